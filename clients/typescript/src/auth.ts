@@ -0,0 +1,84 @@
+/** How long before it expires a client credentials token is renewed, in milliseconds */
+const TOKEN_EXPIRY_MARGIN = 30_000;
+
+/**
+ * Supplies the bearer token sent with each request. The API takes JWTs issued
+ * by the deployment's authentication provider, carrying the caller's tenant,
+ * roles and scopes.
+ */
+export interface TokenSource {
+  token(signal?: AbortSignal): Promise<string>;
+}
+
+/** A fixed bearer token */
+export class StaticToken implements TokenSource {
+  constructor(private readonly value: string) {}
+
+  async token(): Promise<string> {
+    return this.value;
+  }
+}
+
+export interface ClientCredentialsConfig {
+  tokenUrl: string;
+  clientId: string;
+  clientSecret: string;
+  /** Space-separated scopes requested, e.g. "patient:read patient:write" */
+  scope?: string;
+  fetch?: typeof fetch;
+}
+
+/**
+ * Obtains tokens from an OAuth 2.0 token endpoint with the client credentials
+ * grant, authenticating with HTTP Basic, and reuses each until shortly before
+ * it expires. Concurrent callers share one token request.
+ */
+export class ClientCredentials implements TokenSource {
+  private current = "";
+  private expires = 0;
+  private pending?: Promise<string>;
+
+  constructor(private readonly config: ClientCredentialsConfig) {}
+
+  token(signal?: AbortSignal): Promise<string> {
+    if (this.current && Date.now() < this.expires) {
+      return Promise.resolve(this.current);
+    }
+    if (!this.pending) {
+      this.pending = this.request(signal).finally(() => {
+        this.pending = undefined;
+      });
+    }
+    return this.pending;
+  }
+
+  private async request(signal?: AbortSignal): Promise<string> {
+    const form = new URLSearchParams({ grant_type: "client_credentials" });
+    if (this.config.scope) form.set("scope", this.config.scope);
+    const credentials = `${encodeURIComponent(this.config.clientId)}:${encodeURIComponent(this.config.clientSecret)}`;
+
+    const doFetch = this.config.fetch ?? fetch;
+    const response = await doFetch(this.config.tokenUrl, {
+      method: "POST",
+      headers: {
+        "Content-Type": "application/x-www-form-urlencoded",
+        Accept: "application/json",
+        Authorization: `Basic ${btoa(credentials)}`,
+      },
+      body: form.toString(),
+      signal,
+    });
+    if (response.status !== 200) {
+      throw new Error(`token endpoint responded ${response.status}`);
+    }
+
+    const granted = (await response.json()) as { access_token?: string; expires_in?: number };
+    if (!granted.access_token) {
+      throw new Error("token endpoint returned no access token");
+    }
+    const lifetime = Math.max((granted.expires_in ?? 0) * 1000 - TOKEN_EXPIRY_MARGIN, TOKEN_EXPIRY_MARGIN);
+    this.current = granted.access_token;
+    this.expires = Date.now() + lifetime;
+    return this.current;
+  }
+}
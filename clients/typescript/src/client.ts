@@ -0,0 +1,362 @@
+import type { TokenSource } from "./auth.js";
+import { ApiError } from "./errors.js";
+import type {
+  Bundle,
+  Observation,
+  ObservationCreateRequest,
+  ObservationUpdateRequest,
+  OperationOutcome,
+  Patient,
+  PatientCreateRequest,
+  PatientUpdateRequest,
+} from "./types.js";
+
+/** Cap on the wait before a retry, whatever Retry-After asks for, in milliseconds */
+const MAX_RETRY_DELAY = 30_000;
+
+const DEFAULT_BULK_CONCURRENCY = 4;
+
+export interface ClientConfig {
+  /** Root of the API, e.g. https://api.healthcare.example.com, without the /api/v1 path */
+  baseUrl: string;
+  /** Supplies the bearer token of each request */
+  tokens: TokenSource;
+  /** Retries of a request that failed with a network error, 429 or 503; 0 by default */
+  retries?: number;
+  /** Request timeout in milliseconds; 30 seconds by default */
+  timeout?: number;
+  /** fetch implementation; the global fetch by default */
+  fetch?: typeof fetch;
+}
+
+/** Patient search parameters. text runs the fuzzy demographic search instead of family and identifier. */
+export interface PatientSearch {
+  family?: string;
+  identifier?: string;
+  text?: string;
+  limit?: number;
+  offset?: number;
+}
+
+/**
+ * Observation search parameters. text runs the fuzzy search, optionally
+ * limited by subject, instead of the subject and code filters; it needs the
+ * server's Elasticsearch search backend.
+ */
+export interface ObservationSearch {
+  /** Subject reference, e.g. Patient/123e4567-e89b-12d3-a456-426614174000 */
+  subject?: string;
+  /** Either code or system|code, e.g. http://loinc.org|8867-4 */
+  code?: string;
+  text?: string;
+  limit?: number;
+  offset?: number;
+}
+
+/** Downsampling of waveform SampledData in a read observation */
+export interface ObservationRead {
+  maxPoints?: number;
+  /** Minimum time between points, as a Go duration such as 1s or 4ms */
+  resolution?: string;
+}
+
+export interface BulkOptions {
+  /** Creates in flight at once; 4 by default */
+  concurrency?: number;
+  signal?: AbortSignal;
+}
+
+/** Outcome of one create of a bulk call, at the same index as its request */
+export type BulkResult<T> = { resource: T; error?: undefined } | { resource?: undefined; error: unknown };
+
+interface RequestOptions {
+  body?: unknown;
+  signal?: AbortSignal;
+}
+
+/**
+ * Client of the Healthcare API. Every POST carries a fresh Idempotency-Key, so
+ * requests that failed with a network error, 429 or 503 are retried without
+ * creating a resource twice. Error responses are thrown as ApiError.
+ */
+export class Client {
+  private readonly baseUrl: string;
+  private readonly tokens: TokenSource;
+  private readonly retries: number;
+  private readonly timeout: number;
+  private readonly fetch: typeof fetch;
+
+  constructor(config: ClientConfig) {
+    const base = new URL(config.baseUrl);
+    if (base.protocol !== "https:" && base.protocol !== "http:") {
+      throw new Error(`invalid base URL ${config.baseUrl}`);
+    }
+    this.baseUrl = config.baseUrl.replace(/\/+$/, "");
+    this.tokens = config.tokens;
+    this.retries = config.retries ?? 0;
+    this.timeout = config.timeout ?? 30_000;
+    this.fetch = config.fetch ?? fetch.bind(globalThis);
+  }
+
+  /** Creates a patient. Requires the patient:write scope. */
+  createPatient(patient: PatientCreateRequest, signal?: AbortSignal): Promise<Patient> {
+    return this.request("POST", "/api/v1/patients", { body: patient, signal });
+  }
+
+  /** Reads a patient. A deleted patient is a 410 Gone ApiError. */
+  getPatient(id: string, signal?: AbortSignal): Promise<Patient> {
+    return this.request("GET", `/api/v1/patients/${encodeURIComponent(id)}`, { signal });
+  }
+
+  /** Updates the elements of a patient that are set in update */
+  updatePatient(id: string, update: PatientUpdateRequest, signal?: AbortSignal): Promise<Patient> {
+    return this.request("PUT", `/api/v1/patients/${encodeURIComponent(id)}`, { body: update, signal });
+  }
+
+  /** Soft-deletes a patient. Requires the patient:delete scope. */
+  async deletePatient(id: string, signal?: AbortSignal): Promise<void> {
+    await this.request("DELETE", `/api/v1/patients/${encodeURIComponent(id)}`, { signal });
+  }
+
+  /** Returns a page of the patients matching search */
+  searchPatients(search: PatientSearch = {}, signal?: AbortSignal): Promise<Bundle<Patient>> {
+    return this.request("GET", `/api/v1/patients?${patientQuery(search)}`, { signal });
+  }
+
+  /** Yields every patient matching search, following the bundles' next links */
+  eachPatient(search: PatientSearch = {}, signal?: AbortSignal): AsyncGenerator<Patient> {
+    return this.pages<Patient>(`/api/v1/patients?${patientQuery(search)}`, signal);
+  }
+
+  /** Creates an observation. Requires the observation:write scope. */
+  createObservation(observation: ObservationCreateRequest, signal?: AbortSignal): Promise<Observation> {
+    return this.request("POST", "/api/v1/observations", { body: observation, signal });
+  }
+
+  /** Reads an observation. A deleted observation is a 410 Gone ApiError. */
+  getObservation(id: string, read: ObservationRead = {}, signal?: AbortSignal): Promise<Observation> {
+    const query = new URLSearchParams();
+    if (read.maxPoints) query.set("max-points", String(read.maxPoints));
+    if (read.resolution) query.set("resolution", read.resolution);
+    const suffix = query.toString() ? `?${query}` : "";
+    return this.request("GET", `/api/v1/observations/${encodeURIComponent(id)}${suffix}`, { signal });
+  }
+
+  /** Updates the elements of an observation that are set in update */
+  updateObservation(id: string, update: ObservationUpdateRequest, signal?: AbortSignal): Promise<Observation> {
+    return this.request("PUT", `/api/v1/observations/${encodeURIComponent(id)}`, { body: update, signal });
+  }
+
+  /** Soft-deletes an observation. Requires the observation:delete scope. */
+  async deleteObservation(id: string, signal?: AbortSignal): Promise<void> {
+    await this.request("DELETE", `/api/v1/observations/${encodeURIComponent(id)}`, { signal });
+  }
+
+  /** Returns a page of the observations matching search, newest first */
+  searchObservations(search: ObservationSearch = {}, signal?: AbortSignal): Promise<Bundle<Observation>> {
+    return this.request("GET", `/api/v1/observations?${observationQuery(search)}`, { signal });
+  }
+
+  /** Yields every observation matching search, following the bundles' next links */
+  eachObservation(search: ObservationSearch = {}, signal?: AbortSignal): AsyncGenerator<Observation> {
+    return this.pages<Observation>(`/api/v1/observations?${observationQuery(search)}`, signal);
+  }
+
+  /**
+   * Creates each patient, returning a result per request. One failed create
+   * does not stop the others.
+   */
+  createPatients(patients: PatientCreateRequest[], options: BulkOptions = {}): Promise<BulkResult<Patient>[]> {
+    return bulk(patients, options, (patient) => this.createPatient(patient, options.signal));
+  }
+
+  /** Creates each observation, returning a result per request, as createPatients does */
+  createObservations(
+    observations: ObservationCreateRequest[],
+    options: BulkOptions = {},
+  ): Promise<BulkResult<Observation>[]> {
+    return bulk(observations, options, (observation) => this.createObservation(observation, options.signal));
+  }
+
+  private async *pages<T>(path: string, signal?: AbortSignal): AsyncGenerator<T> {
+    let next: string | undefined = path;
+    while (next) {
+      const bundle: Bundle<T> = await this.request("GET", next, { signal });
+      for (const entry of bundle.entry ?? []) {
+        yield entry.resource;
+      }
+      next = bundle.link?.find((link) => link.relation === "next")?.url;
+    }
+  }
+
+  private async request<T>(method: string, path: string, options: RequestOptions = {}): Promise<T> {
+    const body = options.body === undefined ? undefined : JSON.stringify(options.body);
+    // The same key on every attempt makes a retried POST run only once
+    const idempotencyKey = method === "POST" ? crypto.randomUUID() : undefined;
+
+    for (let attempt = 0; ; attempt++) {
+      const deadline = new Deadline(this.timeout, options.signal);
+      try {
+        let response: Response;
+        try {
+          response = await this.send(method, path, body, idempotencyKey, deadline.signal);
+        } catch (err) {
+          if (attempt < this.retries && !options.signal?.aborted) {
+            deadline.clear();
+            await wait(retryDelay(attempt, null), options.signal);
+            continue;
+          }
+          throw err;
+        }
+
+        if ((response.status === 429 || response.status === 503) && attempt < this.retries) {
+          await response.body?.cancel();
+          deadline.clear();
+          await wait(retryDelay(attempt, response.headers.get("Retry-After")), options.signal);
+          continue;
+        }
+        return await decodeResponse<T>(response);
+      } finally {
+        deadline.clear();
+      }
+    }
+  }
+
+  private async send(
+    method: string,
+    path: string,
+    body: string | undefined,
+    idempotencyKey: string | undefined,
+    signal: AbortSignal,
+  ): Promise<Response> {
+    const headers: Record<string, string> = {
+      Authorization: `Bearer ${await this.tokens.token(signal)}`,
+      Accept: "application/fhir+json",
+    };
+    if (body !== undefined) headers["Content-Type"] = "application/fhir+json";
+    if (idempotencyKey) headers["Idempotency-Key"] = idempotencyKey;
+
+    return this.fetch(this.baseUrl + path, { method, headers, body, signal });
+  }
+}
+
+/** Signal aborted by the caller's signal or when a request, body included, takes longer than timeout milliseconds */
+class Deadline {
+  private readonly controller = new AbortController();
+  private readonly timer: ReturnType<typeof setTimeout>;
+  private readonly onAbort = () => this.controller.abort(this.parent?.reason);
+
+  constructor(
+    timeout: number,
+    private readonly parent?: AbortSignal,
+  ) {
+    this.timer = setTimeout(() => this.controller.abort(new Error(`request timed out after ${timeout}ms`)), timeout);
+    if (parent?.aborted) this.onAbort();
+    parent?.addEventListener("abort", this.onAbort, { once: true });
+  }
+
+  get signal(): AbortSignal {
+    return this.controller.signal;
+  }
+
+  clear(): void {
+    clearTimeout(this.timer);
+    this.parent?.removeEventListener("abort", this.onAbort);
+  }
+}
+
+async function decodeResponse<T>(response: Response): Promise<T> {
+  if (response.status >= 400) {
+    let outcome: OperationOutcome | undefined;
+    try {
+      const decoded = await response.json();
+      if (decoded?.resourceType === "OperationOutcome") outcome = decoded;
+    } catch {
+      // Not JSON; the status alone describes the error
+    }
+    throw new ApiError(response.status, outcome);
+  }
+  if (response.status === 204) {
+    return undefined as T;
+  }
+  const text = await response.text();
+  return (text ? JSON.parse(text) : undefined) as T;
+}
+
+function patientQuery(search: PatientSearch): URLSearchParams {
+  const query = new URLSearchParams();
+  if (search.text) {
+    query.set("_query", "smart");
+    query.set("text", search.text);
+  }
+  if (search.family) query.set("family", search.family);
+  if (search.identifier) query.set("identifier", search.identifier);
+  pageQuery(query, search.limit, search.offset);
+  return query;
+}
+
+function observationQuery(search: ObservationSearch): URLSearchParams {
+  const query = new URLSearchParams();
+  if (search.text) {
+    query.set("_query", "smart");
+    query.set("text", search.text);
+  } else if (search.code) {
+    query.set("code", search.code);
+  }
+  if (search.subject) query.set("subject", search.subject);
+  pageQuery(query, search.limit, search.offset);
+  return query;
+}
+
+function pageQuery(query: URLSearchParams, limit?: number, offset?: number): void {
+  if (limit) query.set("limit", String(limit));
+  if (offset) query.set("offset", String(offset));
+}
+
+/** Wait before retry attempt+1: the Retry-After seconds when sent, otherwise a doubling backoff from half a second */
+function retryDelay(attempt: number, retryAfter: string | null): number {
+  let delay = 500 * 2 ** attempt;
+  const seconds = retryAfter === null ? NaN : Number(retryAfter);
+  if (Number.isInteger(seconds) && seconds >= 0) delay = seconds * 1000;
+  return Math.min(delay, MAX_RETRY_DELAY);
+}
+
+function wait(delay: number, signal?: AbortSignal): Promise<void> {
+  return new Promise((resolve, reject) => {
+    if (signal?.aborted) {
+      reject(signal.reason);
+      return;
+    }
+    const onAbort = () => {
+      clearTimeout(timer);
+      reject(signal?.reason);
+    };
+    const timer = setTimeout(() => {
+      signal?.removeEventListener("abort", onAbort);
+      resolve();
+    }, delay);
+    signal?.addEventListener("abort", onAbort, { once: true });
+  });
+}
+
+async function bulk<R, T>(
+  requests: R[],
+  options: BulkOptions,
+  create: (request: R) => Promise<T>,
+): Promise<BulkResult<T>[]> {
+  const results: BulkResult<T>[] = new Array(requests.length);
+  let next = 0;
+  const worker = async () => {
+    while (next < requests.length) {
+      const i = next++;
+      try {
+        results[i] = { resource: await create(requests[i]) };
+      } catch (error) {
+        results[i] = { error };
+      }
+    }
+  };
+  const concurrency = Math.max(1, Math.min(options.concurrency ?? DEFAULT_BULK_CONCURRENCY, requests.length));
+  await Promise.all(Array.from({ length: concurrency }, worker));
+  return results;
+}
@@ -0,0 +1,35 @@
+import type { OperationOutcome } from "./types.js";
+
+/** Coding system of the stable error codes in OperationOutcome issue details */
+export const ERROR_CODE_SYSTEM = "urn:healthcare-api:error-code";
+
+/** An error response of the API */
+export class ApiError extends Error {
+  readonly status: number;
+  /** Stable error code of the response, e.g. PATIENT_NOT_FOUND; empty without an OperationOutcome */
+  readonly code: string;
+  readonly outcome?: OperationOutcome;
+
+  constructor(status: number, outcome?: OperationOutcome) {
+    const issue = outcome?.issue?.[0];
+    const code = issue?.details?.coding?.find((coding) => coding.system === ERROR_CODE_SYSTEM)?.code ?? "";
+    let message = `healthcare API responded ${status}`;
+    if (code) message += ` ${code}`;
+    if (issue?.diagnostics) message += `: ${issue.diagnostics}`;
+    super(message);
+    this.name = "ApiError";
+    this.status = status;
+    this.code = code;
+    this.outcome = outcome;
+  }
+}
+
+/** The API error code of err, e.g. IDENTIFIER_CONFLICT, or "" when err is not an ApiError */
+export function errorCode(err: unknown): string {
+  return err instanceof ApiError ? err.code : "";
+}
+
+/** Whether err is a 404 Not Found or, for a deleted resource, 410 Gone response */
+export function isNotFound(err: unknown): boolean {
+  return err instanceof ApiError && (err.status === 404 || err.status === 410);
+}
@@ -0,0 +1,6 @@
+export { Client } from "./client.js";
+export type { ClientConfig, PatientSearch, ObservationSearch, ObservationRead, BulkOptions, BulkResult } from "./client.js";
+export { StaticToken, ClientCredentials } from "./auth.js";
+export type { TokenSource, ClientCredentialsConfig } from "./auth.js";
+export { ApiError, errorCode, isNotFound, ERROR_CODE_SYSTEM } from "./errors.js";
+export type * from "./types.js";
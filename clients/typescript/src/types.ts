@@ -0,0 +1,314 @@
+// Resource types of the API, mirroring internal/models. Dates and times are
+// ISO 8601 strings.
+
+export interface Coding {
+  system?: string;
+  version?: string;
+  code?: string;
+  display?: string;
+  userSelected?: boolean;
+}
+
+export interface CodeableConcept {
+  coding?: Coding[];
+  text?: string;
+}
+
+export interface Period {
+  start?: string;
+  end?: string;
+}
+
+export interface Reference {
+  reference?: string;
+  type?: string;
+  identifier?: Identifier;
+  display?: string;
+}
+
+export interface Identifier {
+  use?: "usual" | "official" | "temp" | "secondary" | "old";
+  type?: CodeableConcept;
+  system?: string;
+  value?: string;
+  period?: Period;
+  assigner?: Reference;
+}
+
+export interface HumanName {
+  use?: "usual" | "official" | "temp" | "nickname" | "anonymous" | "old" | "maiden";
+  text?: string;
+  family?: string;
+  given?: string[];
+  prefix?: string[];
+  suffix?: string[];
+  period?: Period;
+}
+
+export interface ContactPoint {
+  system?: "phone" | "fax" | "email" | "pager" | "url" | "sms" | "other";
+  value?: string;
+  use?: "home" | "work" | "temp" | "old" | "mobile";
+  rank?: number;
+  period?: Period;
+}
+
+export interface Address {
+  use?: "home" | "work" | "temp" | "old" | "billing";
+  type?: "postal" | "physical" | "both";
+  text?: string;
+  line?: string[];
+  city?: string;
+  district?: string;
+  state?: string;
+  postalCode?: string;
+  country?: string;
+  period?: Period;
+}
+
+export interface Attachment {
+  contentType?: string;
+  language?: string;
+  data?: string;
+  url?: string;
+  size?: number;
+  hash?: string;
+  title?: string;
+  creation?: string;
+}
+
+export interface Quantity {
+  value?: number;
+  comparator?: "<" | "<=" | ">=" | ">" | "ad";
+  unit?: string;
+  system?: string;
+  code?: string;
+}
+
+export interface Range {
+  low?: Quantity;
+  high?: Quantity;
+}
+
+export interface Ratio {
+  numerator?: Quantity;
+  denominator?: Quantity;
+}
+
+export interface SampledData {
+  origin: Quantity;
+  period: number;
+  factor?: number;
+  lowerLimit?: number;
+  upperLimit?: number;
+  dimensions: number;
+  data?: string;
+}
+
+export interface Annotation {
+  authorReference?: Reference;
+  authorString?: string;
+  time?: string;
+  text: string;
+}
+
+export interface Meta {
+  versionId?: string;
+  lastUpdated?: string;
+  source?: string;
+  profile?: string[];
+  security?: Coding[];
+  tag?: Coding[];
+}
+
+export interface Narrative {
+  status: "generated" | "extensions" | "additional" | "empty";
+  div: string;
+}
+
+export interface Extension {
+  url: string;
+  valueString?: string;
+  valueInteger?: number;
+  valueBoolean?: boolean;
+  valueDateTime?: string;
+  valueUrl?: string;
+  valueCodeableConcept?: CodeableConcept;
+  extension?: Extension[];
+}
+
+/** Elements common to the API's resources */
+export interface Resource {
+  id: string;
+  meta?: Meta;
+  implicitRules?: string;
+  language?: string;
+  text?: Narrative;
+  contained?: Resource[];
+  extension?: Extension[];
+  modifierExtension?: Extension[];
+  createdAt: string;
+  updatedAt: string;
+  version: number;
+  deletedAt?: string;
+}
+
+export interface PatientContact {
+  relationship?: CodeableConcept[];
+  name?: HumanName;
+  telecom?: ContactPoint[];
+  address?: Address;
+  gender?: Gender;
+  organization?: Reference;
+  period?: Period;
+}
+
+export interface PatientCommunication {
+  language: CodeableConcept;
+  preferred?: boolean;
+}
+
+export interface PatientLink {
+  other: Reference;
+  type: "replaced-by" | "replaces" | "refer" | "seealso";
+}
+
+export type Gender = "male" | "female" | "other" | "unknown";
+
+export interface PatientCreateRequest {
+  identifier?: Identifier[];
+  active?: boolean;
+  name: HumanName[];
+  telecom?: ContactPoint[];
+  gender?: Gender;
+  birthDate?: string;
+  deceasedBoolean?: boolean;
+  deceasedDateTime?: string;
+  address?: Address[];
+  maritalStatus?: CodeableConcept;
+  multipleBirthBoolean?: boolean;
+  multipleBirthInteger?: number;
+  photo?: Attachment[];
+  contact?: PatientContact[];
+  communication?: PatientCommunication[];
+  generalPractitioner?: Reference[];
+  managingOrganization?: Reference;
+  link?: PatientLink[];
+}
+
+/** Updates the elements that are set */
+export type PatientUpdateRequest = Partial<PatientCreateRequest>;
+
+export interface Patient extends Resource, Partial<PatientCreateRequest> {}
+
+export type ObservationStatus =
+  | "registered"
+  | "preliminary"
+  | "final"
+  | "amended"
+  | "corrected"
+  | "cancelled"
+  | "entered-in-error"
+  | "unknown";
+
+export interface ObservationReferenceRange {
+  low?: Quantity;
+  high?: Quantity;
+  type?: CodeableConcept;
+  appliesTo?: CodeableConcept[];
+  age?: Range;
+  text?: string;
+}
+
+/** The value elements of an observation or component; at most one is set */
+export interface ObservationValue {
+  valueQuantity?: Quantity;
+  valueCodeableConcept?: CodeableConcept;
+  valueString?: string;
+  valueBoolean?: boolean;
+  valueInteger?: number;
+  valueRange?: Range;
+  valueRatio?: Ratio;
+  valueSampledData?: SampledData;
+  valueTime?: string;
+  valueDateTime?: string;
+  valuePeriod?: Period;
+}
+
+export interface ObservationComponent extends ObservationValue {
+  code: CodeableConcept;
+  dataAbsentReason?: CodeableConcept;
+  interpretation?: CodeableConcept[];
+  referenceRange?: ObservationReferenceRange[];
+}
+
+export interface ObservationCreateRequest extends ObservationValue {
+  identifier?: Identifier[];
+  basedOn?: Reference[];
+  partOf?: Reference[];
+  status: ObservationStatus;
+  category?: CodeableConcept[];
+  code: CodeableConcept;
+  subject: Reference;
+  focus?: Reference[];
+  encounter?: Reference;
+  effectiveDateTime?: string;
+  effectivePeriod?: Period;
+  effectiveTiming?: Record<string, unknown>;
+  effectiveInstant?: string;
+  issued?: string;
+  performer?: Reference[];
+  dataAbsentReason?: CodeableConcept;
+  interpretation?: CodeableConcept[];
+  note?: Annotation[];
+  bodySite?: CodeableConcept;
+  method?: CodeableConcept;
+  specimen?: Reference;
+  device?: Reference;
+  referenceRange?: ObservationReferenceRange[];
+  hasMember?: Reference[];
+  derivedFrom?: Reference[];
+  component?: ObservationComponent[];
+}
+
+/** Updates the elements that are set */
+export type ObservationUpdateRequest = Partial<ObservationCreateRequest>;
+
+export interface Observation extends Resource, ObservationCreateRequest {}
+
+export interface BundleLink {
+  relation: string;
+  url: string;
+}
+
+export interface BundleEntry<T> {
+  fullUrl: string;
+  resource: T;
+  search?: { mode: string; score?: number };
+}
+
+/** A page of search results */
+export interface Bundle<T> {
+  resourceType: "Bundle";
+  id: string;
+  type: string;
+  total: number;
+  entry: BundleEntry<T>[] | null;
+  link?: BundleLink[];
+}
+
+export interface OperationOutcomeIssue {
+  severity: "fatal" | "error" | "warning" | "information";
+  code: string;
+  details?: CodeableConcept;
+  diagnostics?: string;
+  location?: string[];
+  expression?: string[];
+}
+
+export interface OperationOutcome {
+  resourceType: "OperationOutcome";
+  id?: string;
+  meta?: Meta;
+  issue: OperationOutcomeIssue[];
+}
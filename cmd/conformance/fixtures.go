@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"healthcare-api/internal/models"
+)
+
+// fixture creates and updates the test resources of a type the API accepts
+// writes of
+type fixture struct {
+	build func(s *suite) (interface{}, error)
+	// created, if set, is called with the response creating the fixture
+	created func(s *suite, resp *response) error
+	// update is the body updating the fixture, and updated checks the
+	// resource returned shows the change
+	update  interface{}
+	updated func(r resource) error
+}
+
+var fixtures = map[string]fixture{
+	"Patient": {
+		build: func(s *suite) (interface{}, error) {
+			var req models.PatientCreateRequest
+			if err := convert(s.generator.Patient(), &req); err != nil {
+				return nil, err
+			}
+			family, system, value := "Conformance"+s.runID, fixtureSystem, s.runID
+			req.Name[0].Family = &family
+			req.Identifier = []models.Identifier{{System: &system, Value: &value}}
+			return &req, nil
+		},
+		created: func(s *suite, resp *response) error {
+			s.patient = &models.Patient{}
+			return json.Unmarshal(resp.raw, s.patient)
+		},
+		update: map[string]interface{}{"active": false},
+		updated: func(r resource) error {
+			if r["active"] != false {
+				return fmt.Errorf("expected active false after the update, got %v", r["active"])
+			}
+			return nil
+		},
+	},
+	"Observation": {
+		build: func(s *suite) (interface{}, error) {
+			if s.patient == nil {
+				return nil, errors.New("no Patient fixture to record the observation for")
+			}
+			var req models.ObservationCreateRequest
+			if err := convert(s.generator.Observations(s.patient, 1)[0], &req); err != nil {
+				return nil, err
+			}
+			return &req, nil
+		},
+		update: map[string]interface{}{"status": "amended"},
+		updated: func(r resource) error {
+			if r["status"] != "amended" {
+				return fmt.Errorf("expected status amended after the update, got %v", r["status"])
+			}
+			return nil
+		},
+	},
+}
+
+// searchValues returns the values of a resource a search parameter matches,
+// the one to search for first
+var searchValues = map[string]func(r resource) []string{
+	"family": func(r resource) []string {
+		return stringFields(objects(r["name"]), "family")
+	},
+	"identifier": func(r resource) []string {
+		return stringFields(objects(r["identifier"]), "value")
+	},
+	"subject": func(r resource) []string {
+		subject, _ := r["subject"].(resource)
+		return stringFields([]resource{subject}, "reference")
+	},
+	"code": func(r resource) []string {
+		code, _ := r["code"].(resource)
+		return tokens(objects(code["coding"]))
+	},
+	"modality": func(r resource) []string {
+		return tokens(objects(r["modality"]))
+	},
+}
+
+// matchesAny reports whether any of values matches want, which string search
+// parameters do as a prefix ignoring case and others exactly
+func matchesAny(paramType string, values []string, want string) bool {
+	for _, value := range values {
+		if paramType == "string" && strings.HasPrefix(strings.ToLower(value), strings.ToLower(want)) || value == want {
+			return true
+		}
+	}
+	return false
+}
+
+// smartText returns the text to search for a resource by: its family name or
+// the text of its code
+func smartText(r resource) string {
+	if families := searchValues["family"](r); len(families) > 0 {
+		return families[0]
+	}
+	code, _ := r["code"].(resource)
+	if text, _ := code["text"].(string); text != "" {
+		return text
+	}
+	for _, coding := range objects(code["coding"]) {
+		if display, _ := coding["display"].(string); display != "" {
+			return display
+		}
+	}
+	return ""
+}
+
+// tokens returns the system|code and code of each coding
+func tokens(codings []resource) []string {
+	var values []string
+	for _, coding := range codings {
+		code, _ := coding["code"].(string)
+		if code == "" {
+			continue
+		}
+		if system, _ := coding["system"].(string); system != "" {
+			values = append(values, system+"|"+code)
+		}
+		values = append(values, code)
+	}
+	return values
+}
+
+func objects(value interface{}) []resource {
+	items, _ := value.([]interface{})
+	result := make([]resource, 0, len(items))
+	for _, item := range items {
+		if object, ok := item.(resource); ok {
+			result = append(result, object)
+		}
+	}
+	return result
+}
+
+// stringFields returns the non-empty string field of each object
+func stringFields(objects []resource, field string) []string {
+	var values []string
+	for _, object := range objects {
+		if value, _ := object[field].(string); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// convert copies a generated resource into the request creating it, whose
+// fields carry the same JSON names
+func convert(generated, req interface{}) error {
+	data, err := json.Marshal(generated)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, req)
+}
@@ -0,0 +1,103 @@
+// Command conformance checks a running instance of the API against the
+// CapabilityStatement it serves at /metadata: every interaction, search
+// parameter and operation the statement claims is exercised, so a claim the
+// routes do not back fails the run.
+//
+// Patients and observations are created as fixtures, with a family name and
+// identifier unique to the run, and deleted again by the delete checks. Types
+// that cannot be written through the API, such as DiagnosticReport, are tested
+// against the first resource their search returns and skipped when there is
+// none. The tenant is the one of the token, taken from -token or
+// HEALTHCARE_API_TOKEN, which needs the read, write and delete scopes of the
+// tested types.
+//
+// Usage:
+//
+//	conformance [-url http://localhost:8080] [-version v1] [-seed 0] [-json]
+//
+// It exits with status 1 when any check fails.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// Result statuses
+const (
+	statusPass = "pass"
+	statusFail = "fail"
+	statusSkip = "skip"
+)
+
+// result is the outcome of one check
+type result struct {
+	Resource string `json:"resource"`
+	Check    string `json:"check"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "root of the API, without the /api/v1 path")
+	token := flag.String("token", os.Getenv("HEALTHCARE_API_TOKEN"), "bearer token (defaults to HEALTHCARE_API_TOKEN)")
+	version := flag.String("version", "v1", "API version to check")
+	seedValue := flag.Int64("seed", 0, "random seed of the fixtures; 0 uses the current time")
+	asJSON := flag.Bool("json", false, "write the results as JSON")
+	flag.Parse()
+
+	if *seedValue == 0 {
+		*seedValue = time.Now().UnixNano()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := newSuite(*baseURL, *version, *token, *seedValue)
+	s.run(ctx)
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(s.results)
+	} else {
+		write(os.Stdout, s.results)
+	}
+
+	counts := map[string]int{}
+	for _, r := range s.results {
+		counts[r.Status]++
+	}
+	log.Printf("%d passed, %d failed, %d skipped", counts[statusPass], counts[statusFail], counts[statusSkip])
+	if counts[statusFail] > 0 {
+		os.Exit(1)
+	}
+}
+
+// write prints the results as a table
+func write(out io.Writer, results []result) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tRESOURCE\tCHECK\tDETAIL")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Status, r.Resource, r.Check, r.Detail)
+	}
+	tw.Flush()
+}
+
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		// Redirects are reported, not followed, as the API sends none
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
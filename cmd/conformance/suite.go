@@ -0,0 +1,561 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/seed"
+)
+
+// fixtureSystem is the identifier system of the patients the suite creates
+const fixtureSystem = "urn:healthcare-api:conformance"
+
+// searchLimit is the page size of searches, which only require the resource
+// under test among the results when they fit on one page
+const searchLimit = 100
+
+// skipped is returned by a check that cannot run, giving the reason
+type skipped string
+
+func (s skipped) Error() string { return string(s) }
+
+// interactions lists the interactions there is a check for
+var interactions = map[string]bool{
+	"create":      true,
+	"read":        true,
+	"update":      true,
+	"delete":      true,
+	"search-type": true,
+}
+
+// resource is a decoded FHIR resource, or any other JSON object
+type resource = map[string]interface{}
+
+// response is a response of the API with its JSON body decoded
+type response struct {
+	status int
+	header http.Header
+	raw    []byte
+	body   resource
+}
+
+type suite struct {
+	http    *http.Client
+	root    string
+	version string
+	token   string
+	// runID makes the family name and identifier of the fixtures unique
+	runID     string
+	generator *seed.Generator
+	results   []result
+	// targets holds the resource of each type the checks read and search for:
+	// a fixture the suite created or the first one a search returned
+	targets map[string]resource
+	created map[string]bool
+	// patient is the Patient fixture observations are recorded for
+	patient *models.Patient
+	// deletes run once every other check is done, last created first
+	deletes []func(ctx context.Context)
+}
+
+func newSuite(root, version, token string, seedValue int64) *suite {
+	rnd := rand.New(rand.NewSource(seedValue))
+	return &suite{
+		http:      newHTTPClient(),
+		root:      strings.TrimRight(root, "/"),
+		version:   version,
+		token:     token,
+		runID:     fmt.Sprintf("%08x", rnd.Uint32()),
+		generator: seed.NewGenerator(seedValue),
+		targets:   make(map[string]resource),
+		created:   make(map[string]bool),
+	}
+}
+
+// check runs test and records its result
+func (s *suite) check(resourceType, name string, test func() error) {
+	r := result{Resource: resourceType, Check: name, Status: statusPass}
+	if err := test(); err != nil {
+		var skip skipped
+		if errors.As(err, &skip) {
+			r.Status = statusSkip
+		} else {
+			r.Status = statusFail
+		}
+		r.Detail = err.Error()
+	}
+	s.results = append(s.results, r)
+}
+
+// run checks every resource type of the statement the server serves
+func (s *suite) run(ctx context.Context) {
+	var statement models.CapabilityStatement
+	s.check("CapabilityStatement", "metadata", func() error { return s.metadata(ctx, &statement) })
+
+	for _, rest := range statement.Rest {
+		if rest.Mode != "server" {
+			continue
+		}
+		for i := range rest.Resource {
+			s.resourceType(ctx, &rest.Resource[i])
+		}
+	}
+
+	for i := len(s.deletes) - 1; i >= 0; i-- {
+		s.deletes[i](ctx)
+	}
+}
+
+// metadata fetches the CapabilityStatement, which must be served without a token
+func (s *suite) metadata(ctx context.Context, statement *models.CapabilityStatement) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.root+"/api/"+s.version+"/metadata", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/fhir+json")
+	resp, err := s.send(req)
+	if err != nil {
+		return err
+	}
+	if err := expect(resp, http.StatusOK); err != nil {
+		return err
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.header.Get("Content-Type")); mediaType != "application/fhir+json" {
+		return fmt.Errorf("expected Content-Type application/fhir+json, got %q", resp.header.Get("Content-Type"))
+	}
+	if err := json.Unmarshal(resp.raw, statement); err != nil {
+		return fmt.Errorf("invalid CapabilityStatement: %v", err)
+	}
+	if statement.ResourceType != "CapabilityStatement" {
+		return fmt.Errorf("expected a CapabilityStatement, got %q", statement.ResourceType)
+	}
+	if !strings.HasPrefix(statement.FHIRVersion, "4.0") {
+		return fmt.Errorf("expected FHIR version 4.0 (R4), got %q", statement.FHIRVersion)
+	}
+	return nil
+}
+
+// resourceType checks every interaction, search parameter and operation a
+// resource type claims. Deletes are queued to run after every other type's
+// checks, as observations refer to the Patient fixture.
+func (s *suite) resourceType(ctx context.Context, r *models.CapabilityResource) {
+	path := r.Path()
+	if path == "" {
+		s.check(r.Type, "path", func() error {
+			return fmt.Errorf("no %s extension giving the path of the type", models.PathExtensionURL)
+		})
+		return
+	}
+
+	for _, interaction := range r.Interaction {
+		if !interactions[interaction.Code] {
+			s.check(r.Type, interaction.Code, func() error { return errors.New("no check for the interaction") })
+		}
+	}
+
+	if r.Supports("create") {
+		s.check(r.Type, "create", func() error { return s.create(ctx, r.Type, path) })
+	}
+	if r.Supports("search-type") {
+		s.check(r.Type, "search-type", func() error { return s.searchType(ctx, r.Type, path) })
+	}
+	if r.Supports("read") {
+		s.check(r.Type, "read", func() error { return s.read(ctx, r.Type, path) })
+	}
+	switch r.ConditionalRead {
+	case "full-support", "not-match", "modified-since":
+		s.check(r.Type, "conditional-read", func() error { return s.conditionalRead(ctx, r.Type, path, r.ConditionalRead) })
+	}
+	if r.Supports("update") {
+		s.check(r.Type, "update", func() error { return s.update(ctx, r.Type, path) })
+	}
+	for _, param := range r.SearchParam {
+		param := param
+		s.check(r.Type, "search "+param.Name, func() error { return s.search(ctx, r.Type, path, param) })
+	}
+	for _, operation := range r.Operation {
+		operation := operation
+		s.check(r.Type, "$"+operation.Name, func() error { return s.operation(ctx, r.Type, path, operation) })
+	}
+	if r.Supports("delete") {
+		s.deletes = append(s.deletes, func(ctx context.Context) {
+			s.check(r.Type, "delete", func() error { return s.delete(ctx, r.Type, path) })
+		})
+	}
+}
+
+// create posts a fixture and makes it the resource under test
+func (s *suite) create(ctx context.Context, resourceType, path string) error {
+	f, ok := fixtures[resourceType]
+	if !ok {
+		return fmt.Errorf("no fixture to create a %s from", resourceType)
+	}
+	body, err := f.build(s)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPost, path, nil, body)
+	if err != nil {
+		return err
+	}
+	if err := expect(resp, http.StatusCreated); err != nil {
+		return err
+	}
+	id, err := resourceID(resp.body, resourceType)
+	if err != nil {
+		return err
+	}
+	if location := resp.header.Get("Location"); location != path+"/"+id {
+		return fmt.Errorf("expected Location %s/%s, got %q", path, id, location)
+	}
+	if resp.header.Get("ETag") == "" {
+		return errors.New("no ETag")
+	}
+
+	s.targets[resourceType] = resp.body
+	s.created[resourceType] = true
+	if f.created != nil {
+		return f.created(s, resp)
+	}
+	return nil
+}
+
+// searchType searches without parameters, taking the first result as the
+// resource under test when the suite created none
+func (s *suite) searchType(ctx context.Context, resourceType, path string) error {
+	resp, err := s.do(ctx, http.MethodGet, path+"?limit=10", nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := expect(resp, http.StatusOK); err != nil {
+		return err
+	}
+	entries, _, err := searchset(resp.body, resourceType)
+	if err != nil {
+		return err
+	}
+	if s.targets[resourceType] == nil && len(entries) > 0 {
+		s.targets[resourceType] = entries[0]
+	}
+	return nil
+}
+
+func (s *suite) read(ctx context.Context, resourceType, path string) error {
+	target, err := s.target(resourceType)
+	if err != nil {
+		return err
+	}
+	id, _ := target["id"].(string)
+	resp, err := s.do(ctx, http.MethodGet, path+"/"+id, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := expect(resp, http.StatusOK); err != nil {
+		return err
+	}
+	got, err := resourceID(resp.body, resourceType)
+	if err != nil {
+		return err
+	}
+	if got != id {
+		return fmt.Errorf("read %s/%s returned %s/%s", resourceType, id, resourceType, got)
+	}
+	return nil
+}
+
+// conditionalRead reads the resource under test again with the validators of a
+// first read, expecting 304 Not Modified for each kind of condition supported
+func (s *suite) conditionalRead(ctx context.Context, resourceType, path, support string) error {
+	target, err := s.target(resourceType)
+	if err != nil {
+		return err
+	}
+	id, _ := target["id"].(string)
+	resp, err := s.do(ctx, http.MethodGet, path+"/"+id, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := expect(resp, http.StatusOK); err != nil {
+		return err
+	}
+
+	conditions := map[string]string{}
+	if support != "modified-since" {
+		conditions["If-None-Match"] = resp.header.Get("ETag")
+	}
+	if support != "not-match" {
+		conditions["If-Modified-Since"] = resp.header.Get("Last-Modified")
+	}
+	for name, value := range conditions {
+		if value == "" {
+			return fmt.Errorf("no validator for %s", name)
+		}
+		resp, err := s.do(ctx, http.MethodGet, path+"/"+id, http.Header{name: {value}}, nil)
+		if err != nil {
+			return err
+		}
+		if resp.status != http.StatusNotModified {
+			return fmt.Errorf("%s: expected 304, got %d", name, resp.status)
+		}
+	}
+	return nil
+}
+
+// update changes the fixture, expecting the change and a new ETag back
+func (s *suite) update(ctx context.Context, resourceType, path string) error {
+	if !s.created[resourceType] {
+		return skipped(fmt.Sprintf("no %s fixture to update", resourceType))
+	}
+	f := fixtures[resourceType]
+	id, _ := s.targets[resourceType]["id"].(string)
+	before, err := s.do(ctx, http.MethodGet, path+"/"+id, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := expect(before, http.StatusOK); err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, path+"/"+id, nil, f.update)
+	if err != nil {
+		return err
+	}
+	if err := expect(resp, http.StatusOK); err != nil {
+		return err
+	}
+	if err := f.updated(resp.body); err != nil {
+		return err
+	}
+	if etag := resp.header.Get("ETag"); etag == "" || etag == before.header.Get("ETag") {
+		return fmt.Errorf("expected a new ETag, got %q", etag)
+	}
+	s.targets[resourceType] = resp.body
+	return nil
+}
+
+// search searches by a parameter's value in the resource under test. Every
+// result must match, and the resource itself must be among them when the
+// results fit on a page.
+func (s *suite) search(ctx context.Context, resourceType, path string, param models.CapabilitySearchParam) error {
+	target, err := s.target(resourceType)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"limit": {fmt.Sprint(searchLimit)}}
+	var values func(resource) []string
+	if param.Name == "_query" {
+		// Only the smart query is defined. A search index may not have the
+		// resource yet, so only the shape of the results is checked.
+		text := smartText(target)
+		if text == "" {
+			return skipped(fmt.Sprintf("the %s under test has no text to search for", resourceType))
+		}
+		query.Set("_query", "smart")
+		query.Set("text", text)
+	} else {
+		var ok bool
+		if values, ok = searchValues[param.Name]; !ok {
+			return fmt.Errorf("no check for the %s search parameter", param.Name)
+		}
+		targetValues := values(target)
+		if len(targetValues) == 0 {
+			return skipped(fmt.Sprintf("the %s under test has no %s", resourceType, param.Name))
+		}
+		query.Set(param.Name, targetValues[0])
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := expect(resp, http.StatusOK); err != nil {
+		return err
+	}
+	entries, total, err := searchset(resp.body, resourceType)
+	if err != nil || values == nil {
+		return err
+	}
+
+	want := query.Get(param.Name)
+	id, _ := target["id"].(string)
+	found := false
+	for _, entry := range entries {
+		if !matchesAny(param.Type, values(entry), want) {
+			return fmt.Errorf("%s/%v does not match %s=%s", resourceType, entry["id"], param.Name, want)
+		}
+		found = found || entry["id"] == id
+	}
+	if !found && total <= len(entries) {
+		return fmt.Errorf("%s/%s is not among the %d results", resourceType, id, len(entries))
+	}
+	return nil
+}
+
+// operation invokes an operation on the resource under test
+func (s *suite) operation(ctx context.Context, resourceType, path string, operation models.CapabilityOperation) error {
+	switch resourceType + "/$" + operation.Name {
+	case "Observation/$trend":
+		target, err := s.target(resourceType)
+		if err != nil {
+			return err
+		}
+		subjects, codes := searchValues["subject"](target), searchValues["code"](target)
+		if len(subjects) == 0 || len(codes) == 0 {
+			return skipped("the Observation under test has no subject or code")
+		}
+		query := url.Values{"patient": {subjects[0]}, "code": {codes[0]}}
+		resp, err := s.do(ctx, http.MethodGet, path+"/$trend?"+query.Encode(), nil, nil)
+		if err != nil {
+			return err
+		}
+		if err := expect(resp, http.StatusOK); err != nil {
+			return err
+		}
+		if resp.body["subject"] != subjects[0] {
+			return fmt.Errorf("expected the trend of %s, got %v", subjects[0], resp.body["subject"])
+		}
+		return nil
+	case "Patient/$merge":
+		return skipped("merging patients cannot be undone, so it is not exercised")
+	}
+	return fmt.Errorf("no check for the $%s operation", operation.Name)
+}
+
+// delete deletes the fixture, which must be gone afterwards
+func (s *suite) delete(ctx context.Context, resourceType, path string) error {
+	if !s.created[resourceType] {
+		return skipped(fmt.Sprintf("no %s fixture to delete", resourceType))
+	}
+	id, _ := s.targets[resourceType]["id"].(string)
+	resp, err := s.do(ctx, http.MethodDelete, path+"/"+id, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := expect(resp, http.StatusNoContent); err != nil {
+		return err
+	}
+	resp, err = s.do(ctx, http.MethodGet, path+"/"+id, nil, nil)
+	if err != nil {
+		return err
+	}
+	if resp.status != http.StatusNotFound && resp.status != http.StatusGone {
+		return fmt.Errorf("read after delete: expected 404 or 410, got %d", resp.status)
+	}
+	return nil
+}
+
+// target returns the resource under test of a type
+func (s *suite) target(resourceType string) (resource, error) {
+	target := s.targets[resourceType]
+	if target == nil {
+		return nil, skipped(fmt.Sprintf("no %s on the server to test against", resourceType))
+	}
+	return target, nil
+}
+
+// do sends an authorized request with body, if not nil, encoded as JSON
+func (s *suite) do(ctx context.Context, method, path string, header http.Header, body interface{}) (*response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.root+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/fhir+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/fhir+json")
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	return s.send(req)
+}
+
+func (s *suite) send(req *http.Request) (*response, error) {
+	req.Header.Set("User-Agent", "healthcare-api-conformance")
+	httpResp, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	resp := &response{status: httpResp.StatusCode, header: httpResp.Header}
+	if resp.raw, err = io.ReadAll(httpResp.Body); err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(resp.raw)) > 0 {
+		if err := json.Unmarshal(resp.raw, &resp.body); err != nil {
+			return nil, fmt.Errorf("%s %s: response %d is not a JSON object: %v", req.Method, req.URL.Path, resp.status, err)
+		}
+	}
+	return resp, nil
+}
+
+// expect returns an error unless the response has status, giving the
+// diagnostics of the OperationOutcome the server sent instead
+func expect(resp *response, status int) error {
+	if resp.status == status {
+		return nil
+	}
+	err := fmt.Errorf("expected %d, got %d", status, resp.status)
+	if issues, ok := resp.body["issue"].([]interface{}); ok && len(issues) > 0 {
+		if issue, ok := issues[0].(resource); ok && issue["diagnostics"] != nil {
+			err = fmt.Errorf("%w: %v", err, issue["diagnostics"])
+		}
+	}
+	return err
+}
+
+// resourceID returns the id of a resource of resourceType
+func resourceID(r resource, resourceType string) (string, error) {
+	if r["resourceType"] != resourceType {
+		return "", fmt.Errorf("expected a %s, got %v", resourceType, r["resourceType"])
+	}
+	id, _ := r["id"].(string)
+	if id == "" {
+		return "", fmt.Errorf("the %s has no id", resourceType)
+	}
+	return id, nil
+}
+
+// searchset returns the resources of a search result Bundle and its total
+func searchset(bundle resource, resourceType string) ([]resource, int, error) {
+	if bundle["resourceType"] != "Bundle" || bundle["type"] != "searchset" {
+		return nil, 0, fmt.Errorf("expected a searchset Bundle, got %v %v", bundle["type"], bundle["resourceType"])
+	}
+	entries, _ := bundle["entry"].([]interface{})
+	resources := make([]resource, 0, len(entries))
+	for _, e := range entries {
+		entry, _ := e.(resource)
+		r, _ := entry["resource"].(resource)
+		if _, err := resourceID(r, resourceType); err != nil {
+			return nil, 0, fmt.Errorf("invalid entry: %v", err)
+		}
+		resources = append(resources, r)
+	}
+	total, ok := bundle["total"].(float64)
+	if !ok {
+		total = float64(len(resources))
+	}
+	return resources, int(total), nil
+}
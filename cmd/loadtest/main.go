@@ -0,0 +1,426 @@
+// Command loadtest drives a mix of reads and writes against a running instance
+// of the API and reports the latency percentiles of each operation, so a
+// performance regression in the repository layer shows up before release.
+//
+// It first creates a pool of synthetic patients with a day of observations
+// each, which the reads and searches pick their targets from, then runs the
+// mix for the given duration: closed-loop, each worker sending its next request
+// as soon as the last one returns, or at a fixed total rate. The tenant is the
+// one of the token, taken from -token or HEALTHCARE_API_TOKEN, which needs the
+// patient and observation read and write scopes.
+//
+// Usage:
+//
+//	loadtest [-url http://localhost:8080] [-duration 30s] [-concurrency 10]
+//	         [-rate 0] [-mix read-patient=4,create-observation=2] [-patients 50]
+//	         [-seed 1] [-max-p99 250ms] [-max-error-rate 0.01] [-json]
+//
+// It exits with status 1 when an operation's p99 latency exceeds -max-p99 or
+// its error rate exceeds -max-error-rate.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/seed"
+	"healthcare-api/pkg/client"
+
+	"github.com/google/uuid"
+)
+
+// defaultMix is a read-heavy mix, as clinical traffic is
+const defaultMix = "read-patient=4,search-patients=2,create-patient=1,read-observation=4,search-observations=2,create-observation=3"
+
+// operation is one kind of request of the mix
+type operation struct {
+	name string
+	run  func(w *worker, ctx context.Context) error
+}
+
+var operations = []operation{
+	{"create-patient", (*worker).createPatient},
+	{"read-patient", (*worker).readPatient},
+	{"search-patients", (*worker).searchPatients},
+	{"create-observation", (*worker).createObservation},
+	{"read-observation", (*worker).readObservation},
+	{"search-observations", (*worker).searchObservations},
+}
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "root of the API, without the /api/v1 path")
+	token := flag.String("token", os.Getenv("HEALTHCARE_API_TOKEN"), "bearer token (defaults to HEALTHCARE_API_TOKEN)")
+	duration := flag.Duration("duration", 30*time.Second, "how long to run the mix, after the warm-up")
+	concurrency := flag.Int("concurrency", 10, "number of requests in flight at once")
+	rate := flag.Float64("rate", 0, "at most this many requests per second in total; 0 sends as fast as the server answers")
+	mix := flag.String("mix", defaultMix, "weights of the operations, as read-patient=4,create-observation=2; one of "+strings.Join(operationNames(), ", "))
+	patients := flag.Int("patients", 50, "patients created before the run for reads and searches to target")
+	seedValue := flag.Int64("seed", 1, "random seed of the generated resources and the choice of operations")
+	maxP99 := flag.Duration("max-p99", 0, "fail when the p99 latency of an operation exceeds this; 0 does not check")
+	maxErrorRate := flag.Float64("max-error-rate", 0.01, "fail when the share of failed requests of an operation exceeds this")
+	asJSON := flag.Bool("json", false, "write the report as JSON")
+	flag.Parse()
+
+	weights, err := parseMix(*mix)
+	if err != nil {
+		log.Fatalf("Invalid mix: %v", err)
+	}
+	if *concurrency < 1 || *patients < 1 || *duration <= 0 || *rate < 0 {
+		log.Fatal("-concurrency and -patients must be at least 1, -duration positive and -rate not negative")
+	}
+
+	c, err := client.New(client.Config{
+		BaseURL: *baseURL,
+		Tokens:  client.StaticToken(*token),
+		// Requests are not retried, so every failure and its latency is counted
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		UserAgent:  "healthcare-api-loadtest",
+	})
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	p := &pool{}
+	workers := make([]*worker, *concurrency)
+	for i := range workers {
+		workers[i] = newWorker(c, p, *seedValue+int64(i), weights)
+	}
+
+	log.Printf("Creating %d patients", *patients)
+	if err := warmUp(ctx, workers, *patients); err != nil {
+		log.Fatalf("Failed to create patients: %v", err)
+	}
+
+	log.Printf("Running for %s with %d workers", *duration, *concurrency)
+	runCtx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+	elapsed := run(runCtx, workers, *rate)
+
+	report := newReport(workers, elapsed)
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		report.write(os.Stdout)
+	}
+
+	for i, op := range operations {
+		for _, w := range workers {
+			if w.firstErrors[i] != nil {
+				log.Printf("%s failed: %v", op.name, w.firstErrors[i])
+				break
+			}
+		}
+	}
+
+	if failures := report.check(*maxP99, *maxErrorRate); len(failures) > 0 {
+		for _, failure := range failures {
+			log.Print(failure)
+		}
+		os.Exit(1)
+	}
+}
+
+// parseMix returns the weight of each operation, in the order of operations
+func parseMix(mix string) ([]float64, error) {
+	named, err := seed.ParseWeights(mix)
+	if err != nil {
+		return nil, err
+	}
+	weights := make([]float64, len(operations))
+	total := 0.0
+	for name, weight := range named {
+		i := operationIndex(name)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown operation %q: must be one of %s", name, strings.Join(operationNames(), ", "))
+		}
+		weights[i] = weight
+		total += weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("at least one operation must have a positive weight")
+	}
+	return weights, nil
+}
+
+func operationNames() []string {
+	names := make([]string, len(operations))
+	for i, op := range operations {
+		names[i] = op.name
+	}
+	return names
+}
+
+func operationIndex(name string) int {
+	for i, op := range operations {
+		if op.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// warmUp creates the patients, each with a day of observations, that the run
+// reads and searches, spreading them over the workers
+func warmUp(ctx context.Context, workers []*worker, patients int) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(workers))
+	for i, w := range workers {
+		n := patients / len(workers)
+		if i < patients%len(workers) {
+			n++
+		}
+		wg.Add(1)
+		go func(w *worker, n int) {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				if err := w.seedPatient(ctx); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w, n)
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// run sends requests from every worker until ctx is done, returning how long
+// it ran. With a rate the workers take turns from a shared ticker.
+func run(ctx context.Context, workers []*worker, rate float64) time.Duration {
+	var ticks <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			for {
+				if ticks != nil {
+					select {
+					case <-ticks:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				w.next(ctx)
+			}
+		}(w)
+	}
+	wg.Wait()
+	return time.Since(start)
+}
+
+// pool holds the resources created so far, for reads and searches to target
+type pool struct {
+	mu           sync.RWMutex
+	patients     []*models.Patient
+	observations []uuid.UUID
+}
+
+func (p *pool) addPatient(patient *models.Patient) {
+	p.mu.Lock()
+	p.patients = append(p.patients, patient)
+	p.mu.Unlock()
+}
+
+func (p *pool) addObservation(id uuid.UUID) {
+	p.mu.Lock()
+	p.observations = append(p.observations, id)
+	p.mu.Unlock()
+}
+
+func (p *pool) patient(rnd *rand.Rand) *models.Patient {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.patients[rnd.Intn(len(p.patients))]
+}
+
+func (p *pool) observation(rnd *rand.Rand) uuid.UUID {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.observations[rnd.Intn(len(p.observations))]
+}
+
+// worker sends one request at a time, recording the latency of each by
+// operation. The generator and random source are its own, as neither is safe
+// for concurrent use.
+type worker struct {
+	client    *client.Client
+	pool      *pool
+	generator *seed.Generator
+	rnd       *rand.Rand
+	// Running total of the operation weights, normalized to 1
+	cumulative []float64
+	latencies  [][]time.Duration
+	errors     []int
+	// The first error of each operation, to tell what failed
+	firstErrors []error
+}
+
+func newWorker(c *client.Client, p *pool, seedValue int64, weights []float64) *worker {
+	w := &worker{
+		client:      c,
+		pool:        p,
+		generator:   seed.NewGenerator(seedValue),
+		rnd:         rand.New(rand.NewSource(seedValue)),
+		cumulative:  make([]float64, len(weights)),
+		latencies:   make([][]time.Duration, len(operations)),
+		errors:      make([]int, len(operations)),
+		firstErrors: make([]error, len(operations)),
+	}
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	sum := 0.0
+	for i, weight := range weights {
+		sum += weight
+		w.cumulative[i] = sum / total
+	}
+	return w
+}
+
+// next sends a request of an operation drawn from the mix. A request cut
+// short by the end of the run is not counted.
+func (w *worker) next(ctx context.Context) {
+	i := w.pick()
+	start := time.Now()
+	err := operations[i].run(w, ctx)
+	latency := time.Since(start)
+	if ctx.Err() != nil {
+		return
+	}
+	w.latencies[i] = append(w.latencies[i], latency)
+	if err != nil {
+		w.errors[i]++
+		if w.firstErrors[i] == nil {
+			w.firstErrors[i] = err
+		}
+	}
+}
+
+func (w *worker) pick() int {
+	x := w.rnd.Float64()
+	for i, c := range w.cumulative {
+		if x < c {
+			return i
+		}
+	}
+	return len(w.cumulative) - 1
+}
+
+// seedPatient creates a patient with a day of observations
+func (w *worker) seedPatient(ctx context.Context) error {
+	patient, err := w.client.CreatePatient(ctx, w.patientRequest())
+	if err != nil {
+		return err
+	}
+	w.pool.addPatient(patient)
+	for _, observation := range w.generator.Observations(patient, 1) {
+		created, err := w.client.CreateObservation(ctx, observationRequest(observation))
+		if err != nil {
+			return err
+		}
+		w.pool.addObservation(created.ID)
+	}
+	return nil
+}
+
+func (w *worker) createPatient(ctx context.Context) error {
+	patient, err := w.client.CreatePatient(ctx, w.patientRequest())
+	if err == nil {
+		w.pool.addPatient(patient)
+	}
+	return err
+}
+
+func (w *worker) readPatient(ctx context.Context) error {
+	_, err := w.client.GetPatient(ctx, w.pool.patient(w.rnd).ID)
+	return err
+}
+
+// searchPatients searches by the first letters of a known family name
+func (w *worker) searchPatients(ctx context.Context) error {
+	family := ""
+	if patient := w.pool.patient(w.rnd); len(patient.Name) > 0 && patient.Name[0].Family != nil {
+		family = *patient.Name[0].Family
+	}
+	if runes := []rune(family); len(runes) > 3 {
+		family = string(runes[:3])
+	}
+	_, err := w.client.SearchPatients(ctx, client.PatientSearch{Family: family, Limit: 20})
+	return err
+}
+
+// createObservation records one of a day's observations of a known patient
+func (w *worker) createObservation(ctx context.Context) error {
+	observations := w.generator.Observations(w.pool.patient(w.rnd), 1)
+	observation := observations[w.rnd.Intn(len(observations))]
+	created, err := w.client.CreateObservation(ctx, observationRequest(observation))
+	if err == nil {
+		w.pool.addObservation(created.ID)
+	}
+	return err
+}
+
+func (w *worker) readObservation(ctx context.Context) error {
+	_, err := w.client.GetObservation(ctx, w.pool.observation(w.rnd), client.ObservationRead{})
+	return err
+}
+
+func (w *worker) searchObservations(ctx context.Context) error {
+	subject := "Patient/" + w.pool.patient(w.rnd).ID.String()
+	_, err := w.client.SearchObservations(ctx, client.ObservationSearch{Subject: subject, Limit: 20})
+	return err
+}
+
+func (w *worker) patientRequest() *models.PatientCreateRequest {
+	var req models.PatientCreateRequest
+	convert(w.generator.Patient(), &req)
+	return &req
+}
+
+func observationRequest(observation *models.Observation) *models.ObservationCreateRequest {
+	var req models.ObservationCreateRequest
+	convert(observation, &req)
+	return &req
+}
+
+// convert copies a generated resource into the request creating it, whose
+// fields carry the same JSON names
+func convert(resource, req interface{}) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		log.Fatalf("Failed to encode generated resource: %v", err)
+	}
+	if err := json.Unmarshal(data, req); err != nil {
+		log.Fatalf("Failed to decode generated resource: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// report summarizes the requests of a run by operation
+type report struct {
+	Duration   float64           `json:"duration_seconds"`
+	Operations []operationReport `json:"operations"`
+	Total      operationReport   `json:"total"`
+}
+
+// operationReport holds the counts and latency percentiles of an operation,
+// in milliseconds
+type operationReport struct {
+	Operation  string  `json:"operation"`
+	Requests   int     `json:"requests"`
+	Errors     int     `json:"errors"`
+	ErrorRate  float64 `json:"error_rate"`
+	Throughput float64 `json:"requests_per_second"`
+	P50        float64 `json:"p50_ms"`
+	P90        float64 `json:"p90_ms"`
+	P95        float64 `json:"p95_ms"`
+	P99        float64 `json:"p99_ms"`
+	Max        float64 `json:"max_ms"`
+}
+
+// newReport merges the latencies recorded by the workers over a run of elapsed
+func newReport(workers []*worker, elapsed time.Duration) *report {
+	r := &report{Duration: elapsed.Seconds()}
+	var all []time.Duration
+	totalErrors := 0
+	for i, op := range operations {
+		var latencies []time.Duration
+		errors := 0
+		for _, w := range workers {
+			latencies = append(latencies, w.latencies[i]...)
+			errors += w.errors[i]
+		}
+		if len(latencies) == 0 {
+			continue
+		}
+		r.Operations = append(r.Operations, summarize(op.name, latencies, errors, elapsed))
+		all = append(all, latencies...)
+		totalErrors += errors
+	}
+	r.Total = summarize("total", all, totalErrors, elapsed)
+	return r
+}
+
+func summarize(name string, latencies []time.Duration, errors int, elapsed time.Duration) operationReport {
+	s := operationReport{Operation: name, Requests: len(latencies), Errors: errors}
+	if len(latencies) == 0 {
+		return s
+	}
+	sortDurations(latencies)
+	s.ErrorRate = float64(errors) / float64(len(latencies))
+	s.Throughput = float64(len(latencies)) / elapsed.Seconds()
+	s.P50 = milliseconds(percentile(latencies, 50))
+	s.P90 = milliseconds(percentile(latencies, 90))
+	s.P95 = milliseconds(percentile(latencies, 95))
+	s.P99 = milliseconds(percentile(latencies, 99))
+	s.Max = milliseconds(latencies[len(latencies)-1])
+	return s
+}
+
+// percentile returns the nearest-rank percentile p of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// write prints the report as a table
+func (r *report) write(out io.Writer) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\trequests\terrors\treq/s\tp50 ms\tp90 ms\tp95 ms\tp99 ms\tmax ms\t")
+	for _, s := range append(r.Operations, r.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t\n",
+			s.Operation, s.Requests, s.Errors, s.Throughput, s.P50, s.P90, s.P95, s.P99, s.Max)
+	}
+	tw.Flush()
+}
+
+// check returns the operations over the thresholds; a maxP99 of 0 is not
+// checked
+func (r *report) check(maxP99 time.Duration, maxErrorRate float64) []string {
+	var failures []string
+	for _, s := range r.Operations {
+		if maxP99 > 0 && s.P99 > milliseconds(maxP99) {
+			failures = append(failures, fmt.Sprintf("%s: p99 latency %.1fms exceeds %s", s.Operation, s.P99, maxP99))
+		}
+		if s.ErrorRate > maxErrorRate {
+			failures = append(failures, fmt.Sprintf("%s: %d of %d requests failed", s.Operation, s.Errors, s.Requests))
+		}
+	}
+	if r.Total.Requests == 0 {
+		failures = append(failures, "no requests completed")
+	}
+	return failures
+}
+
+// sortDurations sorts latencies in place, shortest first
+func sortDurations(d []time.Duration) {
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+}
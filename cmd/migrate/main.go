@@ -0,0 +1,208 @@
+// Command migrate manages the database schema independently of the API server.
+//
+// Usage:
+//
+//	migrate [-path migrations] [-database url] <command> [args]
+//
+// Commands:
+//
+//	up [N]           apply all pending migrations, or the next N
+//	down N | -all    roll back the last N migrations, or every migration
+//	status           show the applied version and pending migrations
+//	force VERSION    record VERSION as applied and clear the dirty flag
+//	create NAME      write an empty NNN_name.up.sql/.down.sql pair
+//
+// The database URL defaults to the one built from the server's DB_* settings.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/database"
+)
+
+func main() {
+	path := flag.String("path", database.MigrationsPath, "directory containing migration files")
+	databaseURL := flag.String("database", "", "database URL (defaults to the DB_* configuration)")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	// create only touches the filesystem
+	if args[0] == "create" {
+		if len(args) != 2 {
+			log.Fatal("usage: migrate create NAME")
+		}
+		up, down, err := database.CreateMigration(*path, args[1])
+		if err != nil {
+			log.Fatalf("Failed to create migration: %v", err)
+		}
+		fmt.Println(up)
+		fmt.Println(down)
+		return
+	}
+
+	switch args[0] {
+	case "up", "down", "status", "force":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// An embedded server is started only when migrating the configured database
+	var provider database.Provider
+	if *databaseURL == "" {
+		*databaseURL = cfg.Database.MigrationURL
+
+		provider, err = database.NewProvider(cfg.Database)
+		if err != nil {
+			log.Fatalf("Failed to configure database driver: %v", err)
+		}
+		if err := provider.Start(); err != nil {
+			log.Fatalf("Failed to start database: %v", err)
+		}
+	}
+
+	code := 0
+	if err := migrateDatabase(*databaseURL, *path, args[0], args[1:]); err != nil {
+		log.Printf("migrate %s: %v", args[0], err)
+		code = 1
+	}
+
+	if provider != nil {
+		provider.Stop()
+	}
+	os.Exit(code)
+}
+
+func migrateDatabase(databaseURL, path, command string, args []string) error {
+	migrator, err := database.NewMigrator(databaseURL, path)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	return run(migrator, command, args)
+}
+
+func run(migrator *database.Migrator, command string, args []string) error {
+	switch command {
+	case "up":
+		steps, err := optionalCount(args)
+		if err != nil {
+			return err
+		}
+		if err := migrator.Up(steps); err != nil {
+			return err
+		}
+		return printStatus(migrator)
+
+	case "down":
+		if len(args) == 1 && args[0] == "-all" {
+			if err := migrator.Down(0, true); err != nil {
+				return err
+			}
+			return printStatus(migrator)
+		}
+		steps, err := optionalCount(args)
+		if err != nil {
+			return err
+		}
+		if steps == 0 {
+			return fmt.Errorf("usage: migrate down N | -all")
+		}
+		if err := migrator.Down(steps, false); err != nil {
+			return err
+		}
+		return printStatus(migrator)
+
+	case "status":
+		return printStatus(migrator)
+
+	case "force":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: migrate force VERSION")
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		if err := migrator.Force(version); err != nil {
+			return err
+		}
+		return printStatus(migrator)
+
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// optionalCount parses an optional positive step count
+func optionalCount(args []string) (int, error) {
+	switch len(args) {
+	case 0:
+		return 0, nil
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid step count %q", args[0])
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("too many arguments")
+	}
+}
+
+func printStatus(migrator *database.Migrator) error {
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	state := "clean"
+	if status.Dirty {
+		state = "dirty"
+	}
+	fmt.Printf("Version: %d (%s)\n", status.Version, state)
+
+	for _, m := range status.Migrations {
+		mark := "pending"
+		if m.Applied {
+			mark = "applied"
+		}
+		fmt.Printf("  %03d %-45s %s\n", m.Version, m.Name, mark)
+	}
+
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: migrate [flags] <command> [args]
+
+Commands:
+  up [N]           apply all pending migrations, or the next N
+  down N | -all    roll back the last N migrations, or every migration
+  status           show the applied version and pending migrations
+  force VERSION    record VERSION as applied and clear the dirty flag
+  create NAME      write an empty up/down migration pair
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
@@ -0,0 +1,255 @@
+// Command seed populates the database with a synthetic dataset: patients with
+// names, addresses and identifiers from a mix of locales, each with a series
+// of correlated vital-sign and laboratory observations. With -out the dataset
+// is written as NDJSON files instead, one resource per line, for loading
+// through the API or into another server.
+//
+// Usage:
+//
+//	seed [-patients 100] [-days 30] [-seed 1] [-tenant default]
+//	     [-locales en-US=3,es-MX=1] [-codes glucose=1,hba1c=0.1] [-out dir]
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/seed"
+)
+
+// batchSize bounds the number of resources sent in a single COPY
+const batchSize = 1000
+
+func main() {
+	patients := flag.Int("patients", 100, "number of patients to create")
+	days := flag.Int("days", 30, "days of observations per patient")
+	seedValue := flag.Int64("seed", 1, "random seed; the same seed produces the same dataset")
+	tenant := flag.String("tenant", "", "tenant to seed (defaults to DEFAULT_TENANT_ID, then \"default\")")
+	localeWeights := flag.String("locales", seed.DefaultLocale, "locales of patients with their weights, as en-US=3,es-MX=1; one of "+strings.Join(seed.Locales(), ", "))
+	codeFrequencies := flag.String("codes", "", "share of days each kind of observation is measured on, overriding the defaults, as glucose=1,hba1c=0.1; one of "+strings.Join(seed.Codes(), ", "))
+	out := flag.String("out", "", "directory to write Patient.ndjson and Observation.ndjson to instead of loading the database")
+	flag.Parse()
+
+	generator, err := newGenerator(*seedValue, *localeWeights, *codeFrequencies)
+	if err != nil {
+		log.Fatalf("Invalid generator options: %v", err)
+	}
+
+	if *out != "" {
+		start := time.Now()
+		patientCount, observationCount, err := write(*out, generator, *patients, *days)
+		if err != nil {
+			log.Fatalf("Failed to write dataset: %v", err)
+		}
+		log.Printf("Wrote %d patients and %d observations to %s in %s",
+			patientCount, observationCount, *out, time.Since(start).Round(time.Millisecond))
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if *tenant == "" {
+		*tenant = cfg.Tenancy.DefaultTenant
+	}
+	if *tenant == "" {
+		*tenant = "default"
+	}
+	if !models.ValidTenantID(*tenant) {
+		log.Fatalf("Invalid tenant id %q", *tenant)
+	}
+
+	provider, err := database.NewProvider(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to configure database driver: %v", err)
+	}
+	if err := provider.Start(); err != nil {
+		log.Fatalf("Failed to start database: %v", err)
+	}
+	defer provider.Stop()
+
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	if cfg.Database.AutoMigrate {
+		if err := database.RunMigrations(cfg.Database.MigrationURL); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	} else if err := database.CheckMigrations(cfg.Database.MigrationURL); err != nil {
+		log.Fatalf("Database schema is not up to date: %v", err)
+	}
+
+	ctx := requestctx.WithTenantID(context.Background(), *tenant)
+	ctx = requestctx.WithUserID(ctx, "seed")
+
+	tenantRepo := repository.NewTenantRepository(db)
+	if _, err := tenantRepo.GetByID(ctx, *tenant); err != nil {
+		log.Fatalf("Tenant %q is not provisioned: %v", *tenant, err)
+	}
+
+	start := time.Now()
+	patientCount, observationCount, err := populate(ctx, db, generator, *patients, *days)
+	if err != nil {
+		log.Fatalf("Failed to seed database: %v", err)
+	}
+
+	log.Printf("Seeded tenant %q with %d patients and %d observations in %s",
+		*tenant, patientCount, observationCount, time.Since(start).Round(time.Millisecond))
+}
+
+// newGenerator returns a generator drawing patients from the weighted locales
+// and observations from the default code distribution with codes applied over it
+func newGenerator(seedValue int64, localeWeights, codes string) (*seed.Generator, error) {
+	generator := seed.NewGenerator(seedValue)
+
+	weights, err := seed.ParseWeights(localeWeights)
+	if err != nil {
+		return nil, err
+	}
+	if err := generator.SetLocales(weights); err != nil {
+		return nil, err
+	}
+
+	overrides, err := seed.ParseWeights(codes)
+	if err != nil {
+		return nil, err
+	}
+	frequencies := make(map[string]float64, len(seed.DefaultCodes))
+	for name, frequency := range seed.DefaultCodes {
+		frequencies[name] = frequency
+	}
+	for name, frequency := range overrides {
+		frequencies[name] = frequency
+	}
+	if err := generator.SetCodes(frequencies); err != nil {
+		return nil, err
+	}
+	return generator, nil
+}
+
+// populate generates and bulk-loads patients with their observations, flushing in batches
+func populate(ctx context.Context, db *database.DB, generator *seed.Generator, patients, days int) (int, int, error) {
+	patientRepo := repository.NewPatientRepository(db)
+	observationRepo := repository.NewObservationRepository(db)
+
+	var patientBatch []*models.Patient
+	var observationBatch []*models.Observation
+	patientCount, observationCount := 0, 0
+
+	flush := func() error {
+		// Patients first, so observations never reference a patient that is not loaded yet
+		n, err := patientRepo.BulkCreate(ctx, patientBatch)
+		if err != nil {
+			return err
+		}
+		patientCount += n
+
+		n, err = observationRepo.BulkCreate(ctx, observationBatch)
+		if err != nil {
+			return err
+		}
+		observationCount += n
+
+		patientBatch, observationBatch = patientBatch[:0], observationBatch[:0]
+		return nil
+	}
+
+	for i := 0; i < patients; i++ {
+		patient := generator.Patient()
+		patientBatch = append(patientBatch, patient)
+		observationBatch = append(observationBatch, generator.Observations(patient, days)...)
+
+		if len(observationBatch) >= batchSize || len(patientBatch) >= batchSize {
+			if err := flush(); err != nil {
+				return patientCount, observationCount, err
+			}
+		}
+	}
+
+	if len(patientBatch) > 0 {
+		if err := flush(); err != nil {
+			return patientCount, observationCount, err
+		}
+	}
+
+	return patientCount, observationCount, nil
+}
+
+// write generates patients with their observations into Patient.ndjson and
+// Observation.ndjson in dir
+func write(dir string, generator *seed.Generator, patients, days int) (int, int, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, 0, err
+	}
+	patientFile, err := newNDJSONFile(filepath.Join(dir, "Patient.ndjson"))
+	if err != nil {
+		return 0, 0, err
+	}
+	observationFile, err := newNDJSONFile(filepath.Join(dir, "Observation.ndjson"))
+	if err != nil {
+		patientFile.Close()
+		return 0, 0, err
+	}
+
+	patientCount, observationCount := 0, 0
+	for i := 0; i < patients && err == nil; i++ {
+		patient := generator.Patient()
+		if err = patientFile.Encode(patient); err != nil {
+			break
+		}
+		patientCount++
+		for _, observation := range generator.Observations(patient, days) {
+			if err = observationFile.Encode(observation); err != nil {
+				break
+			}
+			observationCount++
+		}
+	}
+
+	err = errors.Join(err, patientFile.Close(), observationFile.Close())
+	return patientCount, observationCount, err
+}
+
+// ndjsonFile writes one JSON value per line to a file
+type ndjsonFile struct {
+	*json.Encoder
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func newNDJSONFile(path string) (*ndjsonFile, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(file)
+	return &ndjsonFile{Encoder: json.NewEncoder(writer), file: file, writer: writer}, nil
+}
+
+// Close flushes the lines written and closes the file
+func (f *ndjsonFile) Close() error {
+	if err := f.writer.Flush(); err != nil {
+		f.file.Close()
+		return fmt.Errorf("failed to write %s: %w", f.file.Name(), err)
+	}
+	return f.file.Close()
+}
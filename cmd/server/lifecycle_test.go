@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/features"
+	"healthcare-api/internal/handlers"
+	"healthcare-api/internal/maintenance"
+	"healthcare-api/internal/middleware"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/redact"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/service"
+	"healthcare-api/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// The lifecycle tests drive patients and observations through the router the
+// server serves, against the PostgreSQL server started by testutil.Run. They
+// are skipped with -short.
+
+func TestMain(m *testing.M) {
+	os.Exit(testutil.Run(m))
+}
+
+// api sends requests to a router as a user of one tenant
+type api struct {
+	t      *testing.T
+	router *gin.Engine
+	token  string
+}
+
+// newAPI serves the patient and observation routes of setupRouter from the test
+// database, with the middleware of every API route. The handlers of other routes
+// are left nil, as the tests do not call them.
+func newAPI(t *testing.T, f *testutil.Fixtures) *api {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+	db := testutil.DB(t)
+	logger := testutil.Logger(t)
+
+	tenantMiddleware := middleware.NewTenantMiddleware(service.NewTenantService(f.Tenants, logger), "", logger)
+	deps := routerDeps{
+		patientHandler:     handlers.NewPatientHandler(service.NewPatientService(f.Patients, logger), logger),
+		observationHandler: handlers.NewObservationHandler(service.NewObservationService(f.Observations, logger), logger),
+
+		rateLimiter:           middleware.NewRateLimiter(cfg.RateLimit),
+		corsPolicy:            middleware.NewCORSPolicy(cfg.CORS),
+		featureFlags:          features.New(cfg.Features),
+		maintenanceMode:       maintenance.New(cfg.Maintenance),
+		tenantMiddleware:      tenantMiddleware,
+		idempotencyMiddleware: middleware.NewIdempotencyMiddleware(repository.NewIdempotencyRepository(db), time.Hour, logger),
+		auditMiddleware:       middleware.NewAuditMiddleware(f.DB, redact.New(cfg.RequestLog), middleware.NewLogSampler(cfg.RequestLog), logger),
+	}
+	router, _ := setupRouter(cfg, deps, logger)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &middleware.Claims{
+		UserID:   testutil.UserID,
+		Username: testutil.UserID,
+		TenantID: f.Tenant.ID,
+		Scopes: []string{
+			"patient:read", "patient:write", "patient:delete",
+			"observation:read", "observation:write", "observation:delete",
+		},
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}).SignedString([]byte(cfg.JWT.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &api{t: t, router: router, token: token}
+}
+
+// do sends a request with body encoded as JSON, checks its status and decodes
+// the response into out, when set
+func (a *api) do(method, path string, body interface{}, header http.Header, wantStatus int, out interface{}) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/fhir+json")
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+
+	if w.Code != wantStatus {
+		a.t.Fatalf("%s %s = %d, want %d: %s", method, path, w.Code, wantStatus, w.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			a.t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return w
+}
+
+// convert copies a generated resource into the request creating it
+func convert(t *testing.T, generated, req interface{}) {
+	t.Helper()
+	data, err := json.Marshal(generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, req); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPatientLifecycle(t *testing.T) {
+	f := testutil.NewFixtures(t)
+	a := newAPI(t, f)
+
+	var create models.PatientCreateRequest
+	convert(t, f.NewPatient(), &create)
+	family := "Lifecycle"
+	create.Name[0].Family = &family
+
+	var created models.Patient
+	w := a.do(http.MethodPost, "/api/v1/patients", &create, nil, http.StatusCreated, &created)
+	path := "/api/v1/patients/" + created.ID.String()
+	if location := w.Header().Get("Location"); location != path {
+		t.Errorf("Location = %q, want %q", location, path)
+	}
+	if created.Version != 1 {
+		t.Errorf("created version = %d, want 1", created.Version)
+	}
+
+	var read models.Patient
+	w = a.do(http.MethodGet, path, nil, nil, http.StatusOK, &read)
+	if read.ID != created.ID || len(read.Name) == 0 || read.Name[0].Family == nil || *read.Name[0].Family != family {
+		t.Errorf("read patient %+v, want the one created", read)
+	}
+	a.do(http.MethodGet, path, nil, http.Header{"If-None-Match": {w.Header().Get("ETag")}}, http.StatusNotModified, nil)
+
+	var updated models.Patient
+	a.do(http.MethodPut, path, map[string]interface{}{"active": false}, nil, http.StatusOK, &updated)
+	if updated.Version != 2 || updated.Active == nil || *updated.Active {
+		t.Errorf("updated patient has version %d and active %v, want 2 and false", updated.Version, updated.Active)
+	}
+
+	var found models.PatientListResponse
+	a.do(http.MethodGet, "/api/v1/patients?family=lifecy", nil, nil, http.StatusOK, &found)
+	if found.Total != 1 || len(found.Entry) != 1 || found.Entry[0].Resource.ID != created.ID {
+		t.Errorf("search by family found %d patients, want the one created", found.Total)
+	}
+
+	// Another tenant neither reads nor finds the patient
+	other := newAPI(t, testutil.NewFixtures(t))
+	other.do(http.MethodGet, path, nil, nil, http.StatusNotFound, nil)
+	other.do(http.MethodGet, "/api/v1/patients?family=lifecy", nil, nil, http.StatusOK, &found)
+	if found.Total != 0 {
+		t.Errorf("another tenant found %d patients, want none", found.Total)
+	}
+
+	a.do(http.MethodDelete, path, nil, nil, http.StatusNoContent, nil)
+	a.do(http.MethodGet, path, nil, nil, http.StatusGone, nil)
+	a.do(http.MethodGet, "/api/v1/patients?family=lifecy", nil, nil, http.StatusOK, &found)
+	if found.Total != 0 {
+		t.Errorf("search found %d patients after the delete, want none", found.Total)
+	}
+}
+
+func TestObservationLifecycle(t *testing.T) {
+	f := testutil.NewFixtures(t)
+	a := newAPI(t, f)
+	patient := f.Patient()
+	subject := "Patient/" + patient.ID.String()
+
+	var create models.ObservationCreateRequest
+	convert(t, f.NewVitals(patient, 1)[0], &create)
+
+	var created models.Observation
+	w := a.do(http.MethodPost, "/api/v1/observations", &create, nil, http.StatusCreated, &created)
+	path := "/api/v1/observations/" + created.ID.String()
+	if location := w.Header().Get("Location"); location != path {
+		t.Errorf("Location = %q, want %q", location, path)
+	}
+
+	var read models.Observation
+	a.do(http.MethodGet, path, nil, nil, http.StatusOK, &read)
+	if read.ID != created.ID || read.Subject.Reference == nil || *read.Subject.Reference != subject {
+		t.Errorf("read observation %+v, want the one created about %s", read, subject)
+	}
+
+	var updated models.Observation
+	a.do(http.MethodPut, path, map[string]interface{}{"status": "amended"}, nil, http.StatusOK, &updated)
+	if updated.Status != "amended" || updated.Version != 2 {
+		t.Errorf("updated observation has status %s and version %d, want amended and 2", updated.Status, updated.Version)
+	}
+
+	var found models.ObservationListResponse
+	a.do(http.MethodGet, "/api/v1/observations?subject="+subject, nil, nil, http.StatusOK, &found)
+	if found.Total != 1 || len(found.Entry) != 1 || found.Entry[0].Resource.ID != created.ID {
+		t.Errorf("search by subject found %d observations, want the one created", found.Total)
+	}
+
+	other := newAPI(t, testutil.NewFixtures(t))
+	other.do(http.MethodGet, path, nil, nil, http.StatusNotFound, nil)
+
+	a.do(http.MethodDelete, path, nil, nil, http.StatusNoContent, nil)
+	a.do(http.MethodGet, path, nil, nil, http.StatusGone, nil)
+	a.do(http.MethodGet, "/api/v1/observations?subject="+subject, nil, nil, http.StatusOK, &found)
+	if found.Total != 0 {
+		t.Errorf("search found %d observations after the delete, want none", found.Total)
+	}
+}
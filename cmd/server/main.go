@@ -2,20 +2,42 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"healthcare-api/internal/cache"
 	"healthcare-api/internal/config"
 	"healthcare-api/internal/database"
+	"healthcare-api/internal/empi"
+	"healthcare-api/internal/events"
+	"healthcare-api/internal/features"
+	"healthcare-api/internal/federation"
 	"healthcare-api/internal/handlers"
+	"healthcare-api/internal/health"
+	"healthcare-api/internal/hl7v2"
+	"healthcare-api/internal/httpserver"
+	"healthcare-api/internal/identifier"
+	"healthcare-api/internal/maintenance"
 	"healthcare-api/internal/middleware"
+	"healthcare-api/internal/monitoring"
+	"healthcare-api/internal/notify"
+	"healthcare-api/internal/objectstore"
+	"healthcare-api/internal/redact"
 	"healthcare-api/internal/repository"
+	"healthcare-api/internal/repository/cached"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/scheduler"
+	"healthcare-api/internal/search"
+	"healthcare-api/internal/selftest"
 	"healthcare-api/internal/service"
+	"healthcare-api/internal/shutdown"
+	"healthcare-api/internal/siem"
+	"healthcare-api/internal/terminology"
 	"healthcare-api/internal/worker"
 
 	"github.com/gin-gonic/gin"
@@ -33,29 +55,263 @@ func main() {
 	logger := logrus.New()
 	logger.SetLevel(logrus.Level(cfg.LogLevel))
 	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(requestctx.LogHook{})
+	// Request logging policy; the hook scrubs PHI patterns from every line
+	logPolicy := redact.New(cfg.RequestLog)
+	logger.AddHook(redact.LogHook{Policy: logPolicy})
+
+	// Start the storage provider (a no-op for an external PostgreSQL server)
+	provider, err := database.NewProvider(cfg.Database)
+	if err != nil {
+		logger.Fatalf("Failed to configure database driver: %v", err)
+	}
+	if err := provider.Start(); err != nil {
+		logger.Fatalf("Failed to start database: %v", err)
+	}
 
 	// Initialize database
 	db, err := database.NewConnection(cfg.Database)
 	if err != nil {
 		logger.Fatalf("Failed to connect to database: %v", err)
 	}
-	defer db.Close()
 
-	// Run migrations
-	if err := database.RunMigrations(cfg.Database.URL); err != nil {
-		logger.Fatalf("Failed to run migrations: %v", err)
+	// Run migrations, or only verify the schema when they are rolled out separately
+	if cfg.Database.AutoMigrate {
+		if err := database.RunMigrations(cfg.Database.MigrationURL); err != nil {
+			logger.Fatalf("Failed to run migrations: %v", err)
+		}
+	} else if err := database.CheckMigrations(cfg.Database.MigrationURL); err != nil {
+		logger.Fatalf("Database schema is not up to date: %v", err)
+	}
+
+	// Serve no traffic until the schema, its indexes, the JWT key and a canary
+	// query check out
+	selfTest := selftest.New(cfg.SelfTest, logger)
+	selfTest.Add(selftest.Migrations(db, database.MigrationsPath))
+	selfTest.Add(selftest.Indexes(db, database.MigrationsPath))
+	selfTest.Add(selftest.JWTKey(cfg.JWT.Secret))
+	selfTest.Add(selftest.Canary(db))
+	if err := selfTest.Wait(context.Background()); err != nil {
+		logger.Fatalf("Startup self-test failed: %v", err)
+	}
+
+	// Keep monthly audit log partitions created ahead of time
+	if err := db.StartPartitionMaintenance(context.Background()); err != nil {
+		logger.Fatalf("Failed to create audit log partitions: %v", err)
 	}
 
 	// Initialize repositories
 	patientRepo := repository.NewPatientRepository(db)
 	observationRepo := repository.NewObservationRepository(db)
+	diagnosticReportRepo := repository.NewDiagnosticReportRepository(db)
+	imagingStudyRepo := repository.NewImagingStudyRepository(db)
+	retentionRepo := repository.NewRetentionRepository(db)
+	tenantRepo := repository.NewTenantRepository(db)
+	backupRepo := repository.NewBackupRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	deadJobRepo := repository.NewDeadJobRepository(db)
+	scheduleRepo := repository.NewScheduleRepository(db)
+	jobRepo := repository.NewJobRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	criticalValueRepo := repository.NewCriticalValueRepository(db)
+	empiRepo := repository.NewEMPIRepository(db)
+	duplicateRepo := repository.NewDuplicateRepository(db)
+	cohortRepo := repository.NewCohortRepository(db)
+	statsRepo := repository.NewStatsRepository(db)
+	dataQualityRepo := repository.NewDataQualityRepository(db)
+
+	// Resources read by id are served from the Redis cache when it is enabled
+	resourceCache, err := cache.New(cfg.Cache, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize resource cache: %v", err)
+	}
+	var patientStore repository.PatientStore = patientRepo
+	var observationStore repository.ObservationStore = observationRepo
+	var diagnosticReportStore repository.DiagnosticReportStore = diagnosticReportRepo
+	if resourceCache != nil {
+		patientStore = cached.NewPatientRepository(patientRepo, resourceCache)
+		observationStore = cached.NewObservationRepository(observationRepo, resourceCache)
+		diagnosticReportStore = cached.NewDiagnosticReportRepository(diagnosticReportRepo, resourceCache)
+	}
+
+	// Object storage for backup snapshots and attachments
+	objectStore, err := objectstore.New(cfg.ObjectStore)
+	if err != nil {
+		logger.Fatalf("Failed to initialize object store: %v", err)
+	}
+
+	// Text searches run against the search index when one is configured; it is
+	// kept up to date by the patient_index and observation_process jobs
+	searchIndex, err := search.New(cfg.Search, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize search index: %v", err)
+	}
+
+	// Notifications such as critical result emails and pages are sent through the configured providers
+	mailer, err := notify.NewMailer(cfg.Notifications)
+	if err != nil {
+		logger.Fatalf("Failed to initialize email provider: %v", err)
+	}
+	smsSender, err := notify.NewSMSSender(cfg.Notifications)
+	if err != nil {
+		logger.Fatalf("Failed to initialize SMS provider: %v", err)
+	}
+
+	// Patients are looked up in the EMPI, if one is configured, for their enterprise identifier
+	empiClient, err := empi.New(cfg.EMPI)
+	if err != nil {
+		logger.Fatalf("Failed to initialize EMPI client: %v", err)
+	}
+
+	// Resource change events go to webhooks, to the event transport and to the
+	// EMPI, if configured, through a job per change for each
+	eventPublisher, err := events.New(cfg.Events, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize event publisher: %v", err)
+	}
+	var eventJobTypes []string
+	if cfg.Webhooks.Enabled {
+		eventJobTypes = append(eventJobTypes, service.WebhookDispatchJobType)
+	}
+	if eventPublisher != nil {
+		eventJobTypes = append(eventJobTypes, service.ResourceEventJobType)
+	}
+	if empiClient != nil {
+		eventJobTypes = append(eventJobTypes, service.EMPISyncJobType)
+	}
+	var resourceOutbox service.JobOutbox = outboxRepo
+	if len(eventJobTypes) > 0 {
+		resourceOutbox = service.NewEventOutbox(outboxRepo, eventJobTypes...)
+	}
 
 	// Initialize services
-	patientService := service.NewPatientService(patientRepo, logger)
-	observationService := service.NewObservationService(observationRepo, logger)
+	patientService := service.NewPatientService(patientStore, logger)
+	observationService := service.NewObservationService(observationStore, logger)
+	diagnosticReportService := service.NewDiagnosticReportService(diagnosticReportStore, observationService, logger)
+	// Resource changes queue their indexing, processing, audit and event jobs through the outbox
+	patientService.SetOutbox(resourceOutbox)
+	observationService.SetOutbox(resourceOutbox)
+	diagnosticReportService.SetOutbox(resourceOutbox)
+	// Imaging studies are ingested from DICOM metadata and linked to reports by accession number
+	imagingStudyService := service.NewImagingStudyService(imagingStudyRepo, patientService, diagnosticReportService, cfg.Imaging, logger)
+	imagingStudyService.SetOutbox(resourceOutbox)
+	diagnosticReportService.SetImagingStudies(imagingStudyService)
+
+	// Wearable samples are loaded in batches with COPY, skipping readings already stored
+	wearableService := service.NewWearableService(observationStore, patientService, logger)
+
+	// Early warning scores are computed from the latest vital signs and recorded as observations
+	ewsService := service.NewEWSService(observationStore, patientService, observationService, logger)
+
+	// Measurements schedule the calculation of the observations derived from them, such as BMI
+	derivedObservationService, err := service.NewDerivedObservationService(observationStore, observationService, cfg.Derived, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize derived observations: %v", err)
+	}
+	observationService.SetDerivedObservations(derivedObservationService)
+
+	// Federated resource types are read through from an upstream FHIR server
+	federationProxy, err := federation.New(cfg.Federation)
+	if err != nil {
+		logger.Fatalf("Failed to initialize federation proxy: %v", err)
+	}
+	if federationProxy != nil {
+		if resourceCache != nil {
+			federationProxy.SetCache(resourceCache)
+		} else {
+			federationProxy.SetCache(federation.NewMemoryCache())
+		}
+	}
+	// Attachment contents, such as patient photos, are kept in the object store
+	attachmentService := service.NewAttachmentService(attachmentRepo, objectStore, cfg.Attachments, logger)
+	patientService.SetAttachments(attachmentService)
+	retentionService := service.NewRetentionService(retentionRepo, cfg.Retention, logger)
+	tenantService := service.NewTenantService(tenantRepo, logger)
+	backupService := service.NewBackupService(backupRepo, objectStore, logger)
+	backupService.SetPseudonymKey(cfg.Deidentify.PseudonymKey)
+	if resourceCache != nil {
+		backupService.SetCache(resourceCache)
+	}
+	// Merges re-point a patient's resources in one transaction, around the cached stores
+	patientMergeService := service.NewPatientMergeService(patientRepo, logger)
+	patientMergeService.SetOutbox(resourceOutbox)
+	if resourceCache != nil {
+		patientMergeService.SetCache(resourceCache)
+	}
+	auditService := service.NewAuditService(auditRepo, logger)
+	reindexService := service.NewReindexService(patientRepo, observationRepo, tenantRepo, logger)
+	if searchIndex != nil {
+		patientService.SetSearchIndex(searchIndex)
+		observationService.SetSearchIndex(searchIndex)
+		reindexService.SetSearchIndex(searchIndex)
+	}
+	// National identifiers of patients are checked by the configured plugins, on
+	// API writes and HL7 v2 ADT messages alike
+	identifierValidator, err := identifier.NewValidator(cfg.Identifiers.Validators)
+	if err != nil {
+		logger.Fatalf("Failed to configure identifier validation: %v", err)
+	}
+	// HL7 v2 ORU results are queued through the outbox and stored by hl7_results jobs
+	hl7Service := service.NewHL7Service(patientService, diagnosticReportService, logger)
+	hl7Service.SetIdentifiers(identifierValidator)
+	hl7Service.SetOutbox(outboxRepo)
+	hl7Service.SetMerges(patientMergeService)
+	webhookService := service.NewWebhookService(webhookRepo, cfg.Webhooks, logger)
+	if cfg.Webhooks.Enabled {
+		webhookService.SetOutbox(outboxRepo)
+	}
+	// Critical results and completed backups notify the tenant's recipients through notification_delivery jobs;
+	// critical result pages are released and escalated by notification-escalation jobs
+	notificationService := service.NewNotificationService(notificationRepo, mailer, smsSender, cfg.Notifications, logger)
+	notificationService.SetOutbox(outboxRepo)
+	observationService.SetNotifications(notificationService)
+	backupService.SetNotifications(notificationService)
+	// Critical value rules flag results as they are stored; critical results open tasks acknowledged with their pages
+	criticalValueService := service.NewCriticalValueService(criticalValueRepo, logger)
+	criticalValueService.SetNotifications(notificationService)
+	notificationService.SetCriticalValueTasks(criticalValueRepo)
+	observationService.SetCriticalValues(criticalValueService)
+	// Enterprise identifiers are stored by empi_sync jobs; mismatches are looked up again by empi-reconciliation jobs
+	empiService := service.NewEMPIService(empiRepo, patientService, empiClient, cfg.EMPI, logger)
+	// Probable duplicate patients are found by duplicate-detection jobs and reviewed by registrars
+	duplicateService, err := service.NewDuplicateService(duplicateRepo, cfg.Duplicates, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize duplicate detection: %v", err)
+	}
+
+	// Cohorts are stored criteria, evaluated against the tenant's patients and observations on request
+	cohortService := service.NewCohortService(cohortRepo, logger)
+
+	// Dashboard statistics are grouped counts, cached per tenant for STATS_CACHE_TTL
+	var statsCache service.StatsCache = federation.NewMemoryCache()
+	if resourceCache != nil {
+		statsCache = resourceCache
+	}
+	statsService := service.NewStatsService(statsRepo, statsCache, cfg.Stats, logger)
+	// Data quality issues are flagged in meta.tag on write and reported by scanning the tenant's tables
+	dataQualityService := service.NewDataQualityService(dataQualityRepo, logger)
 
 	// Initialize worker pool
-	workerPool := worker.NewWorkerPool(10, 1000, logger)
+	jobQueue, err := worker.NewQueue(cfg.Worker)
+	if err != nil {
+		logger.Fatalf("Failed to initialize job queue: %v", err)
+	}
+	workerPool := worker.NewWorkerPool(cfg.Worker.Workers, jobQueue, logger)
+	workerPool.SetDeadLetterStore(deadJobRepo)
+	workerPool.SetJobStore(jobRepo)
+	workerPool.SetOutbox(outboxRepo)
+	workerPool.SetTypeConcurrency(cfg.Worker.TypeConcurrency)
+	workerPool.SetDrainTimeout(time.Duration(cfg.Worker.DrainTimeout) * time.Second)
+	metrics := monitoring.NewMetrics()
+	workerPool.SetMetrics(metrics, "default")
+	if resourceCache != nil {
+		resourceCache.SetMetrics(metrics)
+	}
+	deadJobService := service.NewDeadJobService(deadJobRepo, workerPool, logger)
+	jobService := service.NewJobService(jobRepo, logger)
 	
 	// Register job handlers
 	patientIndexHandler := worker.NewPatientIndexHandler(patientService, logger)
@@ -64,36 +320,232 @@ func main() {
 	
 	workerPool.RegisterHandler(patientIndexHandler)
 	workerPool.RegisterHandler(observationProcessHandler)
+	workerPool.RegisterHandler(worker.NewObservationDeriveHandler(derivedObservationService, logger))
 	workerPool.RegisterHandler(auditLogHandler)
+	workerPool.RegisterHandler(worker.NewRetentionHandler(retentionService, logger))
+	workerPool.RegisterHandler(worker.NewBackupHandler(backupService, logger))
+	workerPool.RegisterHandler(worker.NewCacheWarmupHandler(tenantService, logger))
+	workerPool.RegisterHandler(worker.NewJobHistoryCleanupHandler(jobService, webhookService, notificationService, logger))
+	workerPool.RegisterHandler(worker.NewReindexHandler(reindexService, logger))
+	workerPool.RegisterHandler(worker.NewHL7ResultsHandler(hl7Service, logger))
+	if cfg.Webhooks.Enabled {
+		workerPool.RegisterHandler(worker.NewWebhookDispatchHandler(webhookService, logger))
+		workerPool.RegisterHandler(worker.NewWebhookDeliveryHandler(webhookService, logger))
+	}
+	if eventPublisher != nil {
+		workerPool.RegisterHandler(worker.NewResourceEventHandler(eventPublisher, logger))
+	}
+	if mailer != nil || smsSender != nil {
+		workerPool.RegisterHandler(worker.NewNotificationDeliveryHandler(notificationService, logger))
+	}
+	if smsSender != nil {
+		workerPool.RegisterHandler(worker.NewNotificationEscalationHandler(notificationService, logger))
+	}
+	if empiClient != nil {
+		workerPool.RegisterHandler(worker.NewEMPISyncHandler(empiService, logger))
+		workerPool.RegisterHandler(worker.NewEMPIReconciliationHandler(empiService, logger))
+	}
+	workerPool.RegisterHandler(worker.NewDuplicateDetectionHandler(duplicateService, logger))
+
+	// Track the outcome of scheduled runs
+	jobScheduler := scheduler.New(scheduleRepo, workerPool, cfg.Scheduler, logger)
+	workerPool.OnFinished(jobScheduler.JobFinished)
+
+	// Maintenance mode, switched through the admin API for every instance,
+	// refuses writes and pauses the workers
+	maintenanceMode := maintenance.New(cfg.Maintenance)
+	maintenanceService := service.NewMaintenanceService(repository.NewMaintenanceRepository(db), maintenanceMode, cfg.Maintenance, logger)
+	maintenanceService.SetWorkerPool(workerPool)
+	maintenanceService.Start()
 	
 	// Start worker pool
 	workerPool.Start()
-	defer workerPool.Stop()
+
+	// Schedule recurring jobs such as retention runs
+	if cfg.Scheduler.Enabled {
+		schedules, err := scheduler.Configured(cfg)
+		if err != nil {
+			logger.Fatalf("Invalid job schedule: %v", err)
+		}
+		if err := jobScheduler.Sync(context.Background(), schedules); err != nil {
+			logger.Fatalf("Failed to sync job schedules: %v", err)
+		}
+		jobScheduler.Start()
+	}
 
 	// Initialize handlers
 	patientHandler := handlers.NewPatientHandler(patientService, logger)
 	observationHandler := handlers.NewObservationHandler(observationService, logger)
+	retentionHandler := handlers.NewRetentionHandler(retentionService, logger)
+	tenantHandler := handlers.NewTenantHandler(tenantService, logger)
+	backupHandler := handlers.NewBackupHandler(backupService, workerPool, logger)
+	auditHandler := handlers.NewAuditHandler(auditService, logger)
+	deadJobHandler := handlers.NewDeadJobHandler(deadJobService, logger)
+	scheduleHandler := handlers.NewScheduleHandler(jobScheduler, logger)
+	jobHandler := handlers.NewJobHandler(jobService, workerPool, logger)
+	metricsHandler := handlers.NewMetricsHandler(metrics, logger)
+	diagnosticReportHandler := handlers.NewDiagnosticReportHandler(diagnosticReportService, logger)
+	imagingStudyHandler := handlers.NewImagingStudyHandler(imagingStudyService, logger)
+	wearableHandler := handlers.NewWearableHandler(wearableService, logger)
+	ewsHandler := handlers.NewEWSHandler(ewsService, logger)
+	var federationHandler *handlers.FederationHandler
+	metadataHandler := handlers.NewMetadataHandler(searchIndex != nil)
+	if federationProxy != nil {
+		federationHandler = handlers.NewFederationHandler(federationProxy, logger)
+		metadataHandler.SetFederatedTypes(federationHandler.ResourceTypes())
+	}
+	hl7Handler := handlers.NewHL7Handler(hl7Service, logger)
+	webhookHandler := handlers.NewWebhookHandler(webhookService, logger)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, logger)
+	notificationHandler := handlers.NewNotificationHandler(notificationService, logger)
+	criticalValueHandler := handlers.NewCriticalValueHandler(criticalValueService, logger)
+	empiHandler := handlers.NewEMPIHandler(empiService, logger)
+	duplicateHandler := handlers.NewDuplicateHandler(duplicateService, logger)
+	patientMergeHandler := handlers.NewPatientMergeHandler(patientMergeService, logger)
+	cohortHandler := handlers.NewCohortHandler(cohortService, logger)
+	statsHandler := handlers.NewStatsHandler(statsService, logger)
+	dataQualityHandler := handlers.NewDataQualityHandler(dataQualityService, logger)
+	tenantMiddleware := middleware.NewTenantMiddleware(tenantService, cfg.Tenancy.DefaultTenant, logger)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(repository.NewIdempotencyRepository(db), time.Duration(cfg.Server.IdempotencyTTL)*time.Hour, logger)
+	idempotencyMiddleware.Cleanup()
+	auditMiddleware := middleware.NewAuditMiddleware(repository.NewBaseRepository(db), logPolicy, middleware.NewLogSampler(cfg.RequestLog), logger)
+	siemExporter, err := siem.New(cfg.SIEM, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize SIEM exporter: %v", err)
+	}
+	if siemExporter != nil {
+		auditMiddleware.SetExporter(siemExporter)
+	}
+
+	// The log level, rate limits, CORS policy, feature flags and maintenance
+	// mode are reloaded on SIGHUP or through the admin API
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimit)
+	rateLimiter.Cleanup()
+	corsPolicy := middleware.NewCORSPolicy(cfg.CORS)
+	featureFlags := features.New(cfg.Features)
+	configService := service.NewConfigService(cfg, repository.NewBaseRepository(db), logger)
+	configService.SetRateLimiter(rateLimiter)
+	configService.SetCORS(corsPolicy)
+	configService.SetFeatures(featureFlags)
+	configService.SetMaintenance(maintenanceMode)
+	configHandler := handlers.NewConfigHandler(configService, logger)
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceService, logger)
+
+	// /health checks the database, schema, job queue and cache
+	healthChecker := health.NewChecker("1.0.0")
+	healthChecker.RegisterRequired("database", health.Database(db, logger))
+	healthChecker.RegisterRequired("migrations", health.Migrations(db, database.MigrationsPath, logger))
+	healthChecker.Register("worker", health.Worker(workerPool, logger))
+	healthChecker.Register("cache", health.Cache(resourceCache, logger))
+	healthHandler := handlers.NewHealthHandler(healthChecker)
 
 	// Setup router
-	router := setupRouter(cfg, patientHandler, observationHandler, logger)
+	deps := routerDeps{
+		patientHandler:          patientHandler,
+		observationHandler:      observationHandler,
+		retentionHandler:        retentionHandler,
+		tenantHandler:           tenantHandler,
+		backupHandler:           backupHandler,
+		auditHandler:            auditHandler,
+		deadJobHandler:          deadJobHandler,
+		scheduleHandler:         scheduleHandler,
+		jobHandler:              jobHandler,
+		metricsHandler:          metricsHandler,
+		diagnosticReportHandler: diagnosticReportHandler,
+		imagingStudyHandler:     imagingStudyHandler,
+		wearableHandler:         wearableHandler,
+		ewsHandler:              ewsHandler,
+		federationHandler:       federationHandler,
+		hl7Handler:              hl7Handler,
+		webhookHandler:          webhookHandler,
+		attachmentHandler:       attachmentHandler,
+		notificationHandler:     notificationHandler,
+		criticalValueHandler:    criticalValueHandler,
+		empiHandler:             empiHandler,
+		duplicateHandler:        duplicateHandler,
+		patientMergeHandler:     patientMergeHandler,
+		cohortHandler:           cohortHandler,
+		statsHandler:            statsHandler,
+		dataQualityHandler:      dataQualityHandler,
+		configHandler:           configHandler,
+		maintenanceHandler:      maintenanceHandler,
+		healthHandler:           healthHandler,
+		metadataHandler:         metadataHandler,
+
+		identifierValidator:   identifierValidator,
+		rateLimiter:           rateLimiter,
+		corsPolicy:            corsPolicy,
+		featureFlags:          featureFlags,
+		maintenanceMode:       maintenanceMode,
+		tenantMiddleware:      tenantMiddleware,
+		idempotencyMiddleware: idempotencyMiddleware,
+		auditMiddleware:       auditMiddleware,
+	}
+	router, managementRouter := setupRouter(cfg, deps, logger)
+
+	// Setup server, terminating TLS itself when configured to
+	srv, err := httpserver.New(cfg.Server, router, logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure server: %v", err)
+	}
+
+	scheme := "http"
+	if srv.TLS() {
+		scheme = "https"
+	}
+	logger.Infof("Starting Healthcare API server on %s", srv.Addr())
+	logger.Info("API Documentation: https://github.com/your-org/healthcare-api/blob/main/docs/API.md")
+	if cfg.Server.Listen == "" {
+		logger.Infof("Health Check: %s://localhost:%d/health", scheme, cfg.Server.Port)
+	}
+	if err := srv.Start(); err != nil {
+		logger.Fatalf("Failed to start server: %v", err)
+	}
+
+	// Metrics, profiling, health and platform administration on an internal port
+	var managementServer *httpserver.Server
+	if managementRouter != nil {
+		managementServer = httpserver.NewManagement(cfg.Server, managementRouter, logger)
+		if err := managementServer.Start(); err != nil {
+			logger.Fatalf("Failed to start management listener: %v", err)
+		}
+		logger.Infof("Management listener on %s", managementServer.Addr())
+	}
 
-	// Setup server
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
-		Handler:      router,
-		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
-		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
-		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout) * time.Second,
+	// HL7 v2 feeds over MLLP, applied to a single configured tenant
+	var mllpServer *hl7v2.MLLPServer
+	if cfg.HL7.MLLPEnabled {
+		if _, err := tenantService.GetTenant(context.Background(), cfg.HL7.MLLPTenant); err != nil {
+			logger.Fatalf("Invalid MLLP tenant %q: %v", cfg.HL7.MLLPTenant, err)
+		}
+		mllpServer, err = hl7v2.NewMLLPServer(cfg.HL7, func(ctx context.Context, message []byte) []byte {
+			// An error acknowledgement has the sender resend the message later
+			if maintenanceMode.Enabled() {
+				msg, _ := hl7v2.Parse(message)
+				return hl7v2.Ack(msg, hl7v2.AckError, hl7v2.ErrorApplicationInternal, "In maintenance mode, resend later", time.Now())
+			}
+			return hl7Service.Process(ctx, message).Ack
+		}, logger)
+		if err != nil {
+			logger.Fatalf("Failed to configure MLLP listener: %v", err)
+		}
+		if err := mllpServer.Start(); err != nil {
+			logger.Fatalf("Failed to start MLLP listener: %v", err)
+		}
 	}
 
-	// Start server in goroutine
+	// SIGHUP reloads the configuration, e.g. after the .env file is edited, and
+	// the TLS certificate files
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 	go func() {
-		logger.Infof("Starting Healthcare API server on port %d", cfg.Server.Port)
-		logger.Info("API Documentation: https://github.com/your-org/healthcare-api/blob/main/docs/API.md")
-		logger.Info("Health Check: http://localhost:%d/health", cfg.Server.Port)
-		
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatalf("Failed to start server: %v", err)
+		for range reload {
+			if _, err := configService.Reload(context.Background(), "SIGHUP"); err != nil {
+				logger.WithError(err).Error("Configuration not reloaded")
+			}
+			if err := srv.ReloadCertificate(); err != nil {
+				logger.WithError(err).Error("TLS certificate not reloaded")
+			}
 		}
 	}()
 
@@ -104,103 +556,562 @@ func main() {
 
 	logger.Info("Shutting down Healthcare API server...")
 
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	// Graceful shutdown: each stage starts once the one before it is done, so
+	// nothing is closed while requests or jobs may still use it
+	stages := shutdown.New(logger)
+
+	// Stop taking new work: readiness fails so load balancers stop routing
+	// here, and no more scheduled jobs are submitted once a poll in progress
+	// is done
+	stages.Stage("intake", time.Duration(cfg.Shutdown.Delay+cfg.Scheduler.PollInterval)*time.Second)
+	stages.AddFunc(healthHandler.Drain)
+	stages.Add(func(ctx context.Context) error {
+		select {
+		case <-time.After(time.Duration(cfg.Shutdown.Delay) * time.Second):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if cfg.Scheduler.Enabled {
+		stages.AddFunc(jobScheduler.Stop)
+	}
+
+	// Drain the requests and HL7 v2 messages in flight
+	stages.Stage("http", time.Duration(cfg.Shutdown.DrainTimeout)*time.Second)
+	stages.Add(srv.Shutdown)
+	if mllpServer != nil {
+		stages.Add(mllpServer.Shutdown)
+	}
+
+	// Drain the jobs running, including those queued by the last requests. The
+	// pool cancels jobs still running at its drain timeout and puts them back
+	// on the queue.
+	stages.Stage("workers", workerPool.StopTimeout())
+	stages.AddFunc(workerPool.Stop)
 
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.Fatalf("Server forced to shutdown: %v", err)
+	// Flush what the requests and jobs left, then close connections. The
+	// management listener stays up until now, so the drain can be watched.
+	stages.Stage("close", time.Duration(cfg.Shutdown.CloseTimeout)*time.Second)
+	if managementServer != nil {
+		stages.Add(managementServer.Shutdown)
 	}
+	stages.AddFunc(maintenanceService.Stop)
+	// Sent after the server stops, so the access log of the last requests is forwarded
+	if siemExporter != nil {
+		stages.Add(siemExporter.Close)
+	}
+	if eventPublisher != nil {
+		stages.Add(func(context.Context) error { return eventPublisher.Close() })
+	}
+	if resourceCache != nil {
+		stages.Add(func(context.Context) error { return resourceCache.Close() })
+	}
+	stages.Add(func(context.Context) error { return db.Close() })
+	stages.Add(func(context.Context) error { return provider.Stop() })
 
+	if err := stages.Run(); err != nil {
+		logger.WithError(err).Error("Healthcare API server did not shut down cleanly")
+		os.Exit(1)
+	}
 	logger.Info("Healthcare API server exited")
 }
 
-func setupRouter(cfg *config.Config, patientHandler *handlers.PatientHandler, observationHandler *handlers.ObservationHandler, logger *logrus.Logger) *gin.Engine {
+// routerDeps holds the handlers and middleware the routes of setupRouter are
+// served by
+type routerDeps struct {
+	patientHandler          *handlers.PatientHandler
+	observationHandler      *handlers.ObservationHandler
+	retentionHandler        *handlers.RetentionHandler
+	tenantHandler           *handlers.TenantHandler
+	backupHandler           *handlers.BackupHandler
+	auditHandler            *handlers.AuditHandler
+	deadJobHandler          *handlers.DeadJobHandler
+	scheduleHandler         *handlers.ScheduleHandler
+	jobHandler              *handlers.JobHandler
+	metricsHandler          *handlers.MetricsHandler
+	diagnosticReportHandler *handlers.DiagnosticReportHandler
+	imagingStudyHandler     *handlers.ImagingStudyHandler
+	wearableHandler         *handlers.WearableHandler
+	ewsHandler              *handlers.EWSHandler
+	federationHandler       *handlers.FederationHandler
+	hl7Handler              *handlers.HL7Handler
+	webhookHandler          *handlers.WebhookHandler
+	attachmentHandler       *handlers.AttachmentHandler
+	notificationHandler     *handlers.NotificationHandler
+	criticalValueHandler    *handlers.CriticalValueHandler
+	empiHandler             *handlers.EMPIHandler
+	duplicateHandler        *handlers.DuplicateHandler
+	patientMergeHandler     *handlers.PatientMergeHandler
+	cohortHandler           *handlers.CohortHandler
+	statsHandler            *handlers.StatsHandler
+	dataQualityHandler      *handlers.DataQualityHandler
+	configHandler           *handlers.ConfigHandler
+	maintenanceHandler      *handlers.MaintenanceHandler
+	healthHandler           *handlers.HealthHandler
+	metadataHandler         *handlers.MetadataHandler
+
+	// Middleware, and the settings it reads
+	identifierValidator   *identifier.Validator
+	rateLimiter           *middleware.RateLimiter
+	corsPolicy            *middleware.CORSPolicy
+	featureFlags          *features.Flags
+	maintenanceMode       *maintenance.Mode
+	tenantMiddleware      *middleware.TenantMiddleware
+	idempotencyMiddleware *middleware.IdempotencyMiddleware
+	auditMiddleware       *middleware.AuditMiddleware
+}
+
+func setupRouter(cfg *config.Config, deps routerDeps, logger *logrus.Logger) (*gin.Engine, *gin.Engine) {
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	router := gin.New()
+	// ClientIP, used for rate limiting and the access log, trusts forwarding
+	// headers only from the configured proxies
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	router.RemoteIPHeaders = cfg.Server.RemoteIPHeaders
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(cfg.JWT.Secret, logger)
-	rateLimiter := middleware.NewRateLimiter(100.0, 20) // 100 req/min, burst 20
+	deps.rateLimiter.SetTokenVerifier(authMiddleware.VerifyHeader)
+	loadShedder := middleware.NewLoadShedder(cfg.Server.LoadShedding, logger)
 	validationMiddleware := middleware.NewValidationMiddleware()
+	// SNOMED CT codings of observations are checked against the configured value sets
+	snomedValidator, err := terminology.NewSNOMEDValidator(cfg.Terminology)
+	if err != nil {
+		logger.Fatalf("Failed to load SNOMED CT value sets: %v", err)
+	}
+	validationMiddleware.SetSNOMED(snomedValidator)
+	validationMiddleware.SetIdentifiers(deps.identifierValidator)
 
 	// Global middleware
-	router.Use(middleware.Logger(logger))
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Logger(logger, middleware.NewLogSampler(cfg.RequestLog)))
 	router.Use(middleware.Recovery(logger))
-	router.Use(middleware.CORS())
-	router.Use(rateLimiter.RateLimit())
+	router.Use(loadShedder.Shed())
+	router.Use(middleware.RequestTimeout(cfg.Server.RequestTimeouts, logger))
+	router.Use(deps.corsPolicy.CORS())
+	router.Use(deps.rateLimiter.RateLimit())
+	router.Use(middleware.Maintenance(deps.maintenanceMode))
 	router.Use(middleware.Security())
+	router.Use(middleware.ReadYourWrites())
 
 	// Health check endpoint (no auth required)
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":    "healthy",
-			"timestamp": time.Now().UTC(),
-			"version":   "1.0.0",
-			"service":   "healthcare-api",
-		})
-	})
+	router.GET("/health", deps.healthHandler.GetHealth)
+	router.GET("/health/live", deps.healthHandler.GetLiveness)
+	router.GET("/health/ready", deps.healthHandler.GetReadiness)
+
+	// Capability statement (no auth required), for FHIR clients discovering the server
+	router.GET("/api/v1/metadata", middleware.APIVersion("v1"), middleware.ContentType(), deps.metadataHandler.GetCapabilityStatement)
+	if cfg.API.V2Enabled {
+		router.GET("/api/v2/metadata", middleware.APIVersion("v2"), middleware.ContentType(), deps.metadataHandler.GetCapabilityStatement)
+	}
+
+	// Metrics, profiling and platform administration are served on the
+	// management listener when there is one, keeping them off the public port
+	ops := router
+	var management *gin.Engine
+	if cfg.Server.Management.Enabled() {
+		management = gin.New()
+		management.Use(middleware.RequestID())
+		management.Use(middleware.Logger(logger, middleware.NewLogSampler(cfg.RequestLog)))
+		management.Use(middleware.Recovery(logger))
+		management.Use(middleware.Maintenance(deps.maintenanceMode))
+		management.GET("/health", deps.healthHandler.GetHealth)
+		management.GET("/health/live", deps.healthHandler.GetLiveness)
+		management.GET("/health/ready", deps.healthHandler.GetReadiness)
+		// Scraped without a token; the listener is only reachable internally
+		management.GET("/metrics", deps.metricsHandler.GetMetrics)
+
+		if cfg.Server.Management.Pprof {
+			debug := management.Group("/debug/pprof")
+			{
+				debug.GET("/", gin.WrapF(pprof.Index))
+				debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+				debug.GET("/profile", gin.WrapF(pprof.Profile))
+				debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+				debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+				debug.GET("/trace", gin.WrapF(pprof.Trace))
+				// Named profiles, such as heap and goroutine
+				debug.GET("/:profile", gin.WrapF(pprof.Index))
+			}
+		}
+		ops = management
+	} else {
+		// Metrics endpoint, for platform admins only
+		router.GET("/metrics", authMiddleware.RequireAuth(), deps.rateLimiter.LimitSubject(), authMiddleware.RequirePlatformRole("platform_admin"), deps.metricsHandler.GetMetrics)
+	}
 
 	// API documentation endpoint
 	router.GET("/", func(c *gin.Context) {
+		endpoints := gin.H{
+			"health":             "/health",
+			"liveness":           "/health/live",
+			"readiness":          "/health/ready",
+			"metadata":           "/api/v1/metadata",
+			"metrics":            "/metrics",
+			"patients":           "/api/v1/patients",
+			"observations":       "/api/v1/observations",
+			"diagnostic_reports": "/api/v1/diagnostic-reports",
+			"imaging_studies":    "/api/v1/imaging-studies",
+			"audit_events":       "/api/v1/audit-events",
+			"jobs":               "/api/v1/jobs",
+			"attachments":        "/api/v1/attachments",
+		}
+		if management != nil {
+			delete(endpoints, "metrics")
+		}
 		c.JSON(http.StatusOK, gin.H{
 			"service":       "Healthcare API",
 			"version":       "1.0.0",
 			"documentation": "https://github.com/your-org/healthcare-api/blob/main/docs/API.md",
 			"fhir_version":  "R4",
-			"endpoints": gin.H{
-				"health":       "/health",
-				"patients":     "/api/v1/patients",
-				"observations": "/api/v1/observations",
-			},
+			"endpoints":     endpoints,
 		})
 	})
 
-	// API v1 routes with authentication
-	v1 := router.Group("/api/v1")
-	v1.Use(authMiddleware.RequireAuth())
-	{
+	// Resource routes, served by every API version in that version's representation
+	registerResourceRoutes := func(api *gin.RouterGroup) {
 		// Patient routes
-		patients := v1.Group("/patients")
+		patients := api.Group("/patients")
 		patients.Use(authMiddleware.RequireScope("patient:read"))
 		{
 			patients.POST("", 
 				authMiddleware.RequireScope("patient:write"),
 				validationMiddleware.ValidatePatientCreate(),
-				patientHandler.CreatePatient)
-			patients.GET("/:id", patientHandler.GetPatient)
+				deps.patientHandler.CreatePatient)
+			patients.POST("/$merge",
+				authMiddleware.RequireScope("patient:write"),
+				authMiddleware.RequireRole("registrar"),
+				deps.patientMergeHandler.Merge)
+			patients.GET("/:id", deps.patientHandler.GetPatient)
+			patients.GET("/:id/$ews", middleware.RequireFeature(deps.featureFlags, features.EWS), authMiddleware.RequireScope("observation:read"), deps.ewsHandler.GetScores)
+			patients.POST("/:id/$ews", middleware.RequireFeature(deps.featureFlags, features.EWS), authMiddleware.RequireScope("observation:write"), deps.ewsHandler.RecordScores)
 			patients.PUT("/:id", 
 				authMiddleware.RequireScope("patient:write"),
 				validationMiddleware.ValidatePatientUpdate(),
-				patientHandler.UpdatePatient)
+				deps.patientHandler.UpdatePatient)
 			patients.DELETE("/:id", 
 				authMiddleware.RequireScope("patient:delete"),
-				patientHandler.DeletePatient)
-			patients.GET("", patientHandler.ListPatients)
+				deps.patientHandler.DeletePatient)
+			patients.GET("", deps.patientHandler.ListPatients)
 		}
 
 		// Observation routes
-		observations := v1.Group("/observations")
+		observations := api.Group("/observations")
 		observations.Use(authMiddleware.RequireScope("observation:read"))
 		{
 			observations.POST("", 
 				authMiddleware.RequireScope("observation:write"),
 				validationMiddleware.ValidateObservationCreate(),
-				observationHandler.CreateObservation)
-			observations.GET("/:id", observationHandler.GetObservation)
+				deps.observationHandler.CreateObservation)
+			observations.GET("/$trend", deps.observationHandler.Trend)
+			observations.GET("/:id", deps.observationHandler.GetObservation)
 			observations.PUT("/:id", 
 				authMiddleware.RequireScope("observation:write"),
 				validationMiddleware.ValidateObservationUpdate(),
-				observationHandler.UpdateObservation)
+				deps.observationHandler.UpdateObservation)
 			observations.DELETE("/:id", 
 				authMiddleware.RequireScope("observation:delete"),
-				observationHandler.DeleteObservation)
-			observations.GET("", observationHandler.ListObservations)
+				deps.observationHandler.DeleteObservation)
+			observations.GET("", deps.observationHandler.ListObservations)
 		}
+
+		// Diagnostic report routes; reports are created by results feeds, such as
+		// HL7 v2 ORU messages, and group observations, so they share its scope
+		diagnosticReports := api.Group("/diagnostic-reports")
+		diagnosticReports.Use(authMiddleware.RequireScope("observation:read"))
+		{
+			diagnosticReports.GET("/:id", deps.diagnosticReportHandler.GetDiagnosticReport)
+			diagnosticReports.GET("", deps.diagnosticReportHandler.ListDiagnosticReports)
+		}
+
+		// Imaging study routes; studies are created by DICOM metadata ingest and
+		// are read alongside the reports interpreting them, so they share its scope
+		imagingStudies := api.Group("/imaging-studies")
+		imagingStudies.Use(authMiddleware.RequireScope("observation:read"))
+		{
+			imagingStudies.GET("/:id", deps.imagingStudyHandler.GetImagingStudy)
+			imagingStudies.GET("", deps.imagingStudyHandler.ListImagingStudies)
+		}
+	}
+
+	// Middleware shared by every API version, after the version is set
+	apiMiddleware := []gin.HandlerFunc{
+		deps.auditMiddleware.AuditLog(),
+		middleware.ContentType(),
+		authMiddleware.RequireAuth(),
+		deps.rateLimiter.LimitSubject(),
+		deps.tenantMiddleware.RequireTenant(),
+		deps.idempotencyMiddleware.Idempotent(),
+	}
+
+	// API v1 routes with authentication
+	v1 := router.Group("/api/v1")
+	v1.Use(middleware.APIVersion("v1"))
+	v1.Use(apiMiddleware...)
+	{
+		// Resource routes v2 replaces announce their deprecation once it is scheduled
+		successor := ""
+		if cfg.API.V2Enabled {
+			successor = "/api/v2"
+		}
+		resources := v1.Group("")
+		resources.Use(middleware.Deprecated(cfg.API.V1DeprecatedAt, cfg.API.V1Sunset, "/api/v1", successor))
+		registerResourceRoutes(resources)
+
+		// Federated resource types are read through from the upstream FHIR server
+		// under their FHIR type names, e.g. /api/v1/Medication/123
+		if deps.federationHandler != nil {
+			for _, resourceType := range deps.federationHandler.ResourceTypes() {
+				federated := v1.Group("/" + resourceType)
+				federated.Use(authMiddleware.RequireScope("federation:read"))
+				{
+					federated.GET("", deps.federationHandler.Search(resourceType))
+					federated.GET("/:id", deps.federationHandler.Read(resourceType))
+				}
+			}
+		}
+
+		// Audit trail, readable by compliance officers and admins
+		auditEvents := v1.Group("/audit-events")
+		auditEvents.Use(authMiddleware.RequireRole("compliance"))
+		{
+			auditEvents.GET("", deps.auditHandler.ListAuditEvents)
+		}
+
+		// Background job status: tenant admins see their own tenant's jobs, platform admins all jobs
+		jobs := v1.Group("/jobs")
+		jobs.Use(authMiddleware.RequireRole("platform_admin"))
+		{
+			jobs.GET("", deps.jobHandler.ListJobs)
+			jobs.POST("", authMiddleware.RequirePlatformRole("platform_admin"), deps.jobHandler.SubmitJob)
+			jobs.GET("/stats", authMiddleware.RequirePlatformRole("platform_admin"), deps.jobHandler.GetQueueStats)
+			jobs.PUT("/workers", authMiddleware.RequirePlatformRole("platform_admin"), deps.jobHandler.ResizeWorkers)
+			jobs.GET("/results", deps.jobHandler.ListResults)
+			jobs.GET("/:id", deps.jobHandler.GetJob)
+			jobs.GET("/:id/results", deps.jobHandler.GetJobResults)
+			jobs.POST("/:id/cancel", deps.jobHandler.CancelJob)
+		}
+
+		// Admin routes
+		admin := v1.Group("/admin")
+		admin.Use(authMiddleware.RequireRole("admin"))
+		{
+			admin.POST("/patients/:id/restore", deps.patientHandler.RestorePatient)
+			admin.POST("/observations/:id/restore", deps.observationHandler.RestoreObservation)
+			admin.GET("/retention/report", deps.retentionHandler.GetRetentionReport)
+			admin.POST("/retention/run", deps.retentionHandler.RunRetention)
+			admin.POST("/backups", deps.backupHandler.CreateBackup)
+			admin.GET("/backups", deps.backupHandler.ListBackups)
+			admin.GET("/backups/:id", deps.backupHandler.GetBackup)
+			admin.POST("/backups/:id/restore", deps.backupHandler.RestoreBackup)
+		}
+
+		// Webhooks receiving the tenant's resource change events
+		if cfg.Webhooks.Enabled {
+			webhooks := v1.Group("/admin/webhooks")
+			webhooks.Use(authMiddleware.RequireRole("admin"))
+			{
+				webhooks.POST("", deps.webhookHandler.CreateWebhook)
+				webhooks.GET("", deps.webhookHandler.ListWebhooks)
+				webhooks.GET("/:id", deps.webhookHandler.GetWebhook)
+				webhooks.PATCH("/:id", deps.webhookHandler.UpdateWebhook)
+				webhooks.DELETE("/:id", deps.webhookHandler.DeleteWebhook)
+				webhooks.GET("/:id/deliveries", deps.webhookHandler.ListDeliveries)
+				webhooks.POST("/:id/deliveries/:deliveryId/redeliver", deps.webhookHandler.Redeliver)
+			}
+		}
+
+		// Recipients of the tenant's notifications, and the notifications sent to them
+		recipients := v1.Group("/admin/notification-recipients")
+		recipients.Use(authMiddleware.RequireRole("admin"))
+		{
+			recipients.POST("", deps.notificationHandler.CreateRecipient)
+			recipients.GET("", deps.notificationHandler.ListRecipients)
+			recipients.GET("/:id", deps.notificationHandler.GetRecipient)
+			recipients.PATCH("/:id", deps.notificationHandler.UpdateRecipient)
+			recipients.DELETE("/:id", deps.notificationHandler.DeleteRecipient)
+		}
+		notifications := v1.Group("/admin/notifications")
+		notifications.Use(authMiddleware.RequireRole("admin"))
+		{
+			notifications.GET("", deps.notificationHandler.ListNotifications)
+			notifications.GET("/:id", deps.notificationHandler.GetNotification)
+		}
+
+		// Thresholds flagging the tenant's results as critical when they are stored
+		criticalValueRules := v1.Group("/admin/critical-value-rules")
+		criticalValueRules.Use(authMiddleware.RequireRole("admin"))
+		{
+			criticalValueRules.POST("", deps.criticalValueHandler.CreateRule)
+			criticalValueRules.GET("", deps.criticalValueHandler.ListRules)
+			criticalValueRules.GET("/:id", deps.criticalValueHandler.GetRule)
+			criticalValueRules.PATCH("/:id", deps.criticalValueHandler.UpdateRule)
+			criticalValueRules.DELETE("/:id", deps.criticalValueHandler.DeleteRule)
+		}
+
+		// Outcomes of the EMPI lookups of the tenant's patients
+		if cfg.EMPI.Provider != "" && cfg.EMPI.Provider != empi.ProviderNone {
+			empiLinks := v1.Group("/admin/empi/links")
+			empiLinks.Use(authMiddleware.RequireRole("admin"))
+			{
+				empiLinks.GET("", deps.empiHandler.ListLinks)
+				empiLinks.GET("/:id", deps.empiHandler.GetLink)
+				empiLinks.POST("/:id/sync", deps.empiHandler.Sync)
+			}
+		}
+
+		// Probable duplicate patients, confirmed or dismissed by registrars
+		duplicates := v1.Group("/patient-duplicates")
+		duplicates.Use(authMiddleware.RequireRole("registrar"))
+		{
+			duplicates.GET("", deps.duplicateHandler.ListDuplicates)
+			duplicates.GET("/:id", deps.duplicateHandler.GetDuplicate)
+			duplicates.POST("/:id/confirm", deps.duplicateHandler.Confirm)
+			duplicates.POST("/:id/dismiss", deps.duplicateHandler.Dismiss)
+		}
+
+		// Cohorts: stored patient selection criteria, evaluated on request
+		cohorts := v1.Group("/cohorts")
+		cohorts.Use(middleware.RequireFeature(deps.featureFlags, features.Cohorts))
+		cohorts.Use(authMiddleware.RequireScope("cohort:read"))
+		{
+			cohorts.POST("", authMiddleware.RequireScope("cohort:write"), deps.cohortHandler.CreateCohort)
+			cohorts.GET("", deps.cohortHandler.ListCohorts)
+			cohorts.GET("/:id", deps.cohortHandler.GetCohort)
+			cohorts.PUT("/:id", authMiddleware.RequireScope("cohort:write"), deps.cohortHandler.ReplaceCohort)
+			cohorts.DELETE("/:id", authMiddleware.RequireScope("cohort:write"), deps.cohortHandler.DeleteCohort)
+			cohorts.GET("/:id/$evaluate",
+				authMiddleware.RequireScope("patient:read"),
+				authMiddleware.RequireScope("observation:read"),
+				deps.cohortHandler.Evaluate)
+		}
+
+		// Aggregate counts for the operational dashboard
+		v1.GET("/stats",
+			middleware.RequireFeature(deps.featureFlags, features.Stats),
+			authMiddleware.RequireScope("patient:read"),
+			authMiddleware.RequireScope("observation:read"),
+			deps.statsHandler.GetStats)
+
+		// Data quality issues across the tenant's resources
+		v1.GET("/data-quality",
+			middleware.RequireFeature(deps.featureFlags, features.DataQuality),
+			authMiddleware.RequireScope("patient:read"),
+			authMiddleware.RequireScope("observation:read"),
+			deps.dataQualityHandler.GetReport)
+
+		// Clinicians acknowledge the events they are paged about, such as critical results
+		acknowledgements := v1.Group("/notifications")
+		{
+			acknowledgements.POST("/:id/acknowledge", deps.notificationHandler.Acknowledge)
+		}
+		// Critical results awaiting acknowledgement, as FHIR Tasks
+		tasks := v1.Group("/tasks")
+		tasks.Use(authMiddleware.RequireScope("observation:read"))
+		{
+			tasks.GET("", deps.criticalValueHandler.ListTasks)
+			tasks.GET("/:id", deps.criticalValueHandler.GetTask)
+			tasks.POST("/:id/acknowledge", deps.criticalValueHandler.AcknowledgeTask)
+		}
+	}
+
+	// Platform administration, shared by all tenants
+	platform := ops.Group("/api/v1")
+	platform.Use(middleware.APIVersion("v1"))
+	platform.Use(apiMiddleware...)
+	{
+		// Tenant provisioning routes
+		tenants := platform.Group("/admin/tenants")
+		tenants.Use(authMiddleware.RequirePlatformRole("platform_admin"))
+		{
+			tenants.POST("", deps.tenantHandler.CreateTenant)
+			tenants.GET("", deps.tenantHandler.ListTenants)
+			tenants.GET("/:id", deps.tenantHandler.GetTenant)
+			tenants.PATCH("/:id", deps.tenantHandler.UpdateTenant)
+		}
+
+		// Reloads the settings that change without a restart, like SIGHUP
+		platform.POST("/admin/config/reload", authMiddleware.RequirePlatformRole("platform_admin"), deps.configHandler.ReloadConfig)
+
+		// Maintenance mode: reads are served, writes are refused and background jobs pause
+		platform.GET("/admin/maintenance", authMiddleware.RequirePlatformRole("platform_admin"), deps.maintenanceHandler.GetMaintenance)
+		platform.PUT("/admin/maintenance", authMiddleware.RequirePlatformRole("platform_admin"), deps.maintenanceHandler.SetMaintenance)
+
+		// Dead letter queue for background jobs, shared by all tenants
+		deadJobs := platform.Group("/admin/dead-jobs")
+		deadJobs.Use(authMiddleware.RequirePlatformRole("platform_admin"))
+		{
+			deadJobs.GET("", deps.deadJobHandler.ListDeadJobs)
+			deadJobs.DELETE("", deps.deadJobHandler.PurgeDeadJobs)
+			deadJobs.GET("/:id", deps.deadJobHandler.GetDeadJob)
+			deadJobs.DELETE("/:id", deps.deadJobHandler.DeleteDeadJob)
+			deadJobs.POST("/:id/requeue", deps.deadJobHandler.RequeueDeadJob)
+		}
+
+		// Recurring job schedules and their last runs
+		schedules := platform.Group("/admin/schedules")
+		schedules.Use(authMiddleware.RequirePlatformRole("platform_admin"))
+		{
+			schedules.GET("", deps.scheduleHandler.ListSchedules)
+		}
+	}
+
+	// Integration feeds send messages in their own formats rather than FHIR JSON,
+	// so they skip the content type check. ADT messages are applied as upserts and
+	// ORU results are keyed by their control ID, so a resent message does not need
+	// an Idempotency-Key. DICOM metadata is merged into the study of its UID, and
+	// wearable readings are keyed by device and time, so resending either changes
+	// nothing.
+	integrations := router.Group("/api/v1/integrations")
+	integrations.Use(
+		middleware.APIVersion("v1"),
+		deps.auditMiddleware.AuditLog(),
+		authMiddleware.RequireAuth(),
+		deps.rateLimiter.LimitSubject(),
+		deps.tenantMiddleware.RequireTenant(),
+	)
+	{
+		integrations.POST("/hl7v2", authMiddleware.RequireScope("patient:write"), deps.hl7Handler.ReceiveMessage)
+		integrations.POST("/dicom", authMiddleware.RequireScope("observation:write"), deps.imagingStudyHandler.IngestMetadata)
+		integrations.POST("/wearables", authMiddleware.RequireScope("observation:write"), deps.wearableHandler.IngestSamples)
+	}
+
+	// Attachment uploads carry the attachment's own media type rather than JSON,
+	// so they skip the content type check
+	attachments := router.Group("/api/v1/attachments")
+	attachments.Use(
+		middleware.APIVersion("v1"),
+		deps.auditMiddleware.AuditLog(),
+	)
+	{
+		// Signed download links authorize the request themselves
+		attachments.GET("/:id/content", deps.attachmentHandler.DownloadContent)
+
+		authenticated := attachments.Group("")
+		authenticated.Use(
+			authMiddleware.RequireAuth(),
+			deps.rateLimiter.LimitSubject(),
+			deps.tenantMiddleware.RequireTenant(),
+			authMiddleware.RequireScope("attachment:read"),
+		)
+		authenticated.POST("", authMiddleware.RequireScope("attachment:write"), deps.attachmentHandler.UploadAttachment)
+		authenticated.GET("/:id", deps.attachmentHandler.GetAttachment)
+		authenticated.GET("/:id/download", deps.attachmentHandler.GetDownloadLink)
+	}
+
+	// API v2 routes share the services and middleware of v1
+	if cfg.API.V2Enabled {
+		v2 := router.Group("/api/v2")
+		v2.Use(middleware.APIVersion("v2"))
+		v2.Use(apiMiddleware...)
+		registerResourceRoutes(v2)
 	}
 
-	return router
+	return router, management
 }
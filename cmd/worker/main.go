@@ -0,0 +1,293 @@
+// Command worker runs background jobs from the shared job queue without serving
+// HTTP, so job capacity can be scaled independently of the API. It requires the
+// redis queue backend: with the in-memory backend it would never receive jobs.
+//
+// Usage:
+//
+//	WORKER_QUEUE_BACKEND=redis go run ./cmd/worker
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"healthcare-api/internal/cache"
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/empi"
+	"healthcare-api/internal/events"
+	"healthcare-api/internal/maintenance"
+	"healthcare-api/internal/notify"
+	"healthcare-api/internal/objectstore"
+	"healthcare-api/internal/redact"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/repository/cached"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/scheduler"
+	"healthcare-api/internal/search"
+	"healthcare-api/internal/selftest"
+	"healthcare-api/internal/service"
+	"healthcare-api/internal/shutdown"
+	"healthcare-api/internal/worker"
+
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.Level(cfg.LogLevel))
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(requestctx.LogHook{})
+	logger.AddHook(redact.LogHook{Policy: redact.New(cfg.RequestLog)})
+
+	if cfg.Worker.QueueBackend != "redis" {
+		logger.Fatalf("The worker process needs a shared queue: set WORKER_QUEUE_BACKEND=redis (got %q)", cfg.Worker.QueueBackend)
+	}
+	if cfg.Worker.Workers < 1 {
+		logger.Fatalf("WORKER_COUNT must be at least 1 for the worker process")
+	}
+
+	// Start the storage provider (a no-op for an external PostgreSQL server)
+	provider, err := database.NewProvider(cfg.Database)
+	if err != nil {
+		logger.Fatalf("Failed to configure database driver: %v", err)
+	}
+	if err := provider.Start(); err != nil {
+		logger.Fatalf("Failed to start database: %v", err)
+	}
+
+	db, err := database.NewConnection(cfg.Database)
+	if err != nil {
+		logger.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// Migrations are owned by the API server or cmd/migrate
+	if err := database.CheckMigrations(cfg.Database.MigrationURL); err != nil {
+		logger.Fatalf("Database schema is not up to date: %v", err)
+	}
+
+	// Take no jobs until the schema, its indexes and a canary query check out
+	selfTest := selftest.New(cfg.SelfTest, logger)
+	selfTest.Add(selftest.Migrations(db, database.MigrationsPath))
+	selfTest.Add(selftest.Indexes(db, database.MigrationsPath))
+	selfTest.Add(selftest.Canary(db))
+	if err := selfTest.Wait(context.Background()); err != nil {
+		logger.Fatalf("Startup self-test failed: %v", err)
+	}
+
+	objectStore, err := objectstore.New(cfg.ObjectStore)
+	if err != nil {
+		logger.Fatalf("Failed to initialize object store: %v", err)
+	}
+
+	patientRepo := repository.NewPatientRepository(db)
+	observationRepo := repository.NewObservationRepository(db)
+	tenantRepo := repository.NewTenantRepository(db)
+
+	outboxRepo := repository.NewOutboxRepository(db)
+
+	// Writes by jobs must update the cache the API servers read through
+	resourceCache, err := cache.New(cfg.Cache, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize resource cache: %v", err)
+	}
+	var patientStore repository.PatientStore = patientRepo
+	var observationStore repository.ObservationStore = observationRepo
+	var diagnosticReportStore repository.DiagnosticReportStore = repository.NewDiagnosticReportRepository(db)
+	if resourceCache != nil {
+		patientStore = cached.NewPatientRepository(patientStore, resourceCache)
+		observationStore = cached.NewObservationRepository(observationStore, resourceCache)
+		diagnosticReportStore = cached.NewDiagnosticReportRepository(diagnosticReportStore, resourceCache)
+	}
+
+	// The search index, if configured, is written by index jobs and reindex runs
+	searchIndex, err := search.New(cfg.Search, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize search index: %v", err)
+	}
+
+	empiClient, err := empi.New(cfg.EMPI)
+	if err != nil {
+		logger.Fatalf("Failed to initialize EMPI client: %v", err)
+	}
+
+	// Event consumers must match the API servers', which record the jobs
+	eventPublisher, err := events.New(cfg.Events, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize event publisher: %v", err)
+	}
+	var eventJobTypes []string
+	if cfg.Webhooks.Enabled {
+		eventJobTypes = append(eventJobTypes, service.WebhookDispatchJobType)
+	}
+	if eventPublisher != nil {
+		eventJobTypes = append(eventJobTypes, service.ResourceEventJobType)
+	}
+	if empiClient != nil {
+		eventJobTypes = append(eventJobTypes, service.EMPISyncJobType)
+	}
+	var resourceOutbox service.JobOutbox = outboxRepo
+	if len(eventJobTypes) > 0 {
+		resourceOutbox = service.NewEventOutbox(outboxRepo, eventJobTypes...)
+	}
+
+	// Jobs such as hl7_results change resources, which queue their own jobs
+	patientService := service.NewPatientService(patientStore, logger)
+	observationService := service.NewObservationService(observationStore, logger)
+	diagnosticReportService := service.NewDiagnosticReportService(diagnosticReportStore, observationService, logger)
+	patientService.SetOutbox(resourceOutbox)
+	observationService.SetOutbox(resourceOutbox)
+	diagnosticReportService.SetOutbox(resourceOutbox)
+	// Reports stored by hl7_results jobs are linked to the imaging studies of their accession number
+	imagingStudyService := service.NewImagingStudyService(repository.NewImagingStudyRepository(db), patientService, diagnosticReportService, cfg.Imaging, logger)
+	imagingStudyService.SetOutbox(resourceOutbox)
+	diagnosticReportService.SetImagingStudies(imagingStudyService)
+	hl7Service := service.NewHL7Service(patientService, diagnosticReportService, logger)
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(db), cfg.Webhooks, logger)
+	if cfg.Webhooks.Enabled {
+		webhookService.SetOutbox(outboxRepo)
+	}
+	mailer, err := notify.NewMailer(cfg.Notifications)
+	if err != nil {
+		logger.Fatalf("Failed to initialize email provider: %v", err)
+	}
+	smsSender, err := notify.NewSMSSender(cfg.Notifications)
+	if err != nil {
+		logger.Fatalf("Failed to initialize SMS provider: %v", err)
+	}
+	notificationService := service.NewNotificationService(repository.NewNotificationRepository(db), mailer, smsSender, cfg.Notifications, logger)
+	notificationService.SetOutbox(outboxRepo)
+	observationService.SetNotifications(notificationService)
+	retentionService := service.NewRetentionService(repository.NewRetentionRepository(db), cfg.Retention, logger)
+	backupService := service.NewBackupService(repository.NewBackupRepository(db), objectStore, logger)
+	backupService.SetPseudonymKey(cfg.Deidentify.PseudonymKey)
+	if resourceCache != nil {
+		backupService.SetCache(resourceCache)
+	}
+	backupService.SetNotifications(notificationService)
+	criticalValueRepo := repository.NewCriticalValueRepository(db)
+	criticalValueService := service.NewCriticalValueService(criticalValueRepo, logger)
+	criticalValueService.SetNotifications(notificationService)
+	notificationService.SetCriticalValueTasks(criticalValueRepo)
+	observationService.SetCriticalValues(criticalValueService)
+	derivedObservationService, err := service.NewDerivedObservationService(observationStore, observationService, cfg.Derived, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize derived observations: %v", err)
+	}
+	observationService.SetDerivedObservations(derivedObservationService)
+	tenantService := service.NewTenantService(tenantRepo, logger)
+	empiService := service.NewEMPIService(repository.NewEMPIRepository(db), patientService, empiClient, cfg.EMPI, logger)
+	duplicateService, err := service.NewDuplicateService(repository.NewDuplicateRepository(db), cfg.Duplicates, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize duplicate detection: %v", err)
+	}
+	reindexService := service.NewReindexService(patientRepo, observationRepo, tenantRepo, logger)
+	if searchIndex != nil {
+		patientService.SetSearchIndex(searchIndex)
+		observationService.SetSearchIndex(searchIndex)
+		reindexService.SetSearchIndex(searchIndex)
+	}
+	jobRepo := repository.NewJobRepository(db)
+	jobService := service.NewJobService(jobRepo, logger)
+
+	jobQueue, err := worker.NewQueue(cfg.Worker)
+	if err != nil {
+		logger.Fatalf("Failed to initialize job queue: %v", err)
+	}
+	workerPool := worker.NewWorkerPool(cfg.Worker.Workers, jobQueue, logger)
+	workerPool.SetDeadLetterStore(repository.NewDeadJobRepository(db))
+	workerPool.SetJobStore(jobRepo)
+	workerPool.SetOutbox(outboxRepo)
+	workerPool.SetTypeConcurrency(cfg.Worker.TypeConcurrency)
+	workerPool.SetDrainTimeout(time.Duration(cfg.Worker.DrainTimeout) * time.Second)
+
+	workerPool.RegisterHandler(worker.NewPatientIndexHandler(patientService, logger))
+	workerPool.RegisterHandler(worker.NewObservationProcessHandler(observationService, logger))
+	workerPool.RegisterHandler(worker.NewObservationDeriveHandler(derivedObservationService, logger))
+	workerPool.RegisterHandler(worker.NewAuditLogHandler(logger))
+	workerPool.RegisterHandler(worker.NewRetentionHandler(retentionService, logger))
+	workerPool.RegisterHandler(worker.NewBackupHandler(backupService, logger))
+	workerPool.RegisterHandler(worker.NewCacheWarmupHandler(tenantService, logger))
+	workerPool.RegisterHandler(worker.NewJobHistoryCleanupHandler(jobService, webhookService, notificationService, logger))
+	workerPool.RegisterHandler(worker.NewReindexHandler(reindexService, logger))
+	workerPool.RegisterHandler(worker.NewHL7ResultsHandler(hl7Service, logger))
+	if cfg.Webhooks.Enabled {
+		workerPool.RegisterHandler(worker.NewWebhookDispatchHandler(webhookService, logger))
+		workerPool.RegisterHandler(worker.NewWebhookDeliveryHandler(webhookService, logger))
+	}
+	if eventPublisher != nil {
+		workerPool.RegisterHandler(worker.NewResourceEventHandler(eventPublisher, logger))
+	}
+	if mailer != nil || smsSender != nil {
+		workerPool.RegisterHandler(worker.NewNotificationDeliveryHandler(notificationService, logger))
+	}
+	if smsSender != nil {
+		workerPool.RegisterHandler(worker.NewNotificationEscalationHandler(notificationService, logger))
+	}
+	if empiClient != nil {
+		workerPool.RegisterHandler(worker.NewEMPISyncHandler(empiService, logger))
+		workerPool.RegisterHandler(worker.NewEMPIReconciliationHandler(empiService, logger))
+	}
+	workerPool.RegisterHandler(worker.NewDuplicateDetectionHandler(duplicateService, logger))
+
+	// Scheduled jobs are submitted by the API servers; record the outcome of those run here
+	jobScheduler := scheduler.New(repository.NewScheduleRepository(db), workerPool, cfg.Scheduler, logger)
+	workerPool.OnFinished(jobScheduler.JobFinished)
+
+	// Maintenance mode, switched through the API servers, pauses the workers
+	maintenanceMode := maintenance.New(cfg.Maintenance)
+	maintenanceService := service.NewMaintenanceService(repository.NewMaintenanceRepository(db), maintenanceMode, cfg.Maintenance, logger)
+	maintenanceService.SetWorkerPool(workerPool)
+	maintenanceService.Start()
+
+	workerPool.Start()
+	logger.WithField("queue", cfg.Worker.QueueName).Infof("Worker process started with %d workers", cfg.Worker.Workers)
+
+	// SIGHUP reloads the configuration; a worker serves no requests, so only its
+	// log level and maintenance mode apply
+	configService := service.NewConfigService(cfg, repository.NewBaseRepository(db), logger)
+	configService.SetMaintenance(maintenanceMode)
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if _, err := configService.Reload(context.Background(), "SIGHUP"); err != nil {
+				logger.WithError(err).Error("Configuration not reloaded")
+			}
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	// Connections are closed only once the jobs using them are done
+	stages := shutdown.New(logger)
+	stages.Stage("workers", workerPool.StopTimeout())
+	stages.AddFunc(workerPool.Stop)
+	stages.Stage("close", time.Duration(cfg.Shutdown.CloseTimeout)*time.Second)
+	stages.AddFunc(maintenanceService.Stop)
+	if eventPublisher != nil {
+		stages.Add(func(context.Context) error { return eventPublisher.Close() })
+	}
+	if resourceCache != nil {
+		stages.Add(func(context.Context) error { return resourceCache.Close() })
+	}
+	stages.Add(func(context.Context) error { return db.Close() })
+	stages.Add(func(context.Context) error { return provider.Stop() })
+
+	if err := stages.Run(); err != nil {
+		logger.WithError(err).Error("Worker process did not shut down cleanly")
+		os.Exit(1)
+	}
+	logger.Info("Worker process exited")
+}
@@ -0,0 +1,252 @@
+// Package cache keeps resources read by id in Redis, shared by every API and
+// worker process, in front of the PostgreSQL repositories.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/monitoring"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// tombstone marks a resource deleted. A read finding it goes to the database
+// without caching what it finds, so a read racing the delete cannot cache the
+// resource again.
+const tombstone = "-"
+
+// flushBatch bounds the keys scanned and deleted at a time by FlushTenant
+const flushBatch = 500
+
+// storeScript caches a resource unless a newer version is cached. Entries are
+// "<version>\n<resource JSON>". KEYS: entry. ARGV: entry, version, ttl.
+var storeScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	local version = tonumber(string.match(current, '^(%d+)\n'))
+	if version and version > tonumber(ARGV[2]) then
+		return 0
+	end
+end
+redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[3])
+return 1
+`)
+
+// ResourceCache caches resources by tenant, type and id. Cache failures are
+// logged and never fail the caller, who falls back to the database.
+type ResourceCache struct {
+	client  *redis.Client
+	prefix  string
+	ttl     time.Duration
+	metrics *monitoring.Metrics
+	logger  *logrus.Logger
+}
+
+// New connects to the cache's Redis, or returns nil when the cache is disabled
+func New(cfg config.CacheConfig, logger *logrus.Logger) (*ResourceCache, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.TTL <= 0 {
+		return nil, fmt.Errorf("invalid cache TTL %d: must be positive", cfg.TTL)
+	}
+
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to cache redis: %w", err)
+	}
+
+	return &ResourceCache{
+		client: client,
+		prefix: cfg.KeyPrefix,
+		ttl:    time.Duration(cfg.TTL) * time.Second,
+		logger: logger,
+	}, nil
+}
+
+// SetMetrics makes the cache count its hits and misses
+func (c *ResourceCache) SetMetrics(metrics *monitoring.Metrics) {
+	c.metrics = metrics
+}
+
+// Ping checks the cache's Redis can be reached
+func (c *ResourceCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+func (c *ResourceCache) Close() error {
+	return c.client.Close()
+}
+
+// Get reads a cached resource into v, reporting whether it was cached. The
+// second result reports whether a miss may be filled: not after a delete.
+func (c *ResourceCache) Get(ctx context.Context, tenantID, resourceType string, id uuid.UUID, v interface{}) (hit, fill bool) {
+	value, err := c.client.Get(ctx, c.key(tenantID, resourceType, id)).Result()
+	if err == redis.Nil {
+		c.countMiss()
+		return false, true
+	}
+	if err != nil {
+		c.logger.WithContext(ctx).WithError(err).Warn("Failed to read resource cache")
+		return false, false
+	}
+	if value == tombstone {
+		c.countMiss()
+		return false, false
+	}
+
+	_, data, ok := strings.Cut(value, "\n")
+	if !ok || json.Unmarshal([]byte(data), v) != nil {
+		c.logger.WithContext(ctx).WithField("resource_type", resourceType).Warn("Discarding unreadable resource cache entry")
+		c.Evict(ctx, tenantID, resourceType, id)
+		c.countMiss()
+		return false, true
+	}
+	c.countHit()
+	return true, false
+}
+
+// Fill caches a resource read from the database after a miss, unless another
+// process cached it, or deleted it, meanwhile
+func (c *ResourceCache) Fill(ctx context.Context, tenantID, resourceType string, id uuid.UUID, version int, v interface{}) {
+	value, err := entry(version, v)
+	if err == nil {
+		err = c.client.SetNX(ctx, c.key(tenantID, resourceType, id), value, c.ttl).Err()
+	}
+	if err != nil {
+		c.logger.WithContext(ctx).WithError(err).Warn("Failed to fill resource cache")
+	}
+}
+
+// Store caches a resource just written, unless a newer version is cached.
+// When it cannot, the cached copy is evicted so it is not served stale.
+func (c *ResourceCache) Store(ctx context.Context, tenantID, resourceType string, id uuid.UUID, version int, v interface{}) {
+	key := c.key(tenantID, resourceType, id)
+	value, err := entry(version, v)
+	if err == nil {
+		err = storeScript.Run(ctx, c.client, []string{key}, value, version, int(c.ttl.Seconds())).Err()
+	}
+	if err != nil {
+		c.logger.WithContext(ctx).WithError(err).Warn("Failed to update resource cache")
+		c.Evict(ctx, tenantID, resourceType, id)
+	}
+}
+
+// Tombstone replaces the cached copy of a deleted resource with a tombstone
+func (c *ResourceCache) Tombstone(ctx context.Context, tenantID, resourceType string, id uuid.UUID) {
+	if err := c.client.Set(ctx, c.key(tenantID, resourceType, id), tombstone, c.ttl).Err(); err != nil {
+		c.logger.WithContext(ctx).WithError(err).Warn("Failed to mark resource deleted in cache")
+	}
+}
+
+// Evict drops the cached copy of a resource
+func (c *ResourceCache) Evict(ctx context.Context, tenantID, resourceType string, id uuid.UUID) {
+	if err := c.client.Del(ctx, c.key(tenantID, resourceType, id)).Err(); err != nil {
+		c.logger.WithContext(ctx).WithError(err).Warn("Failed to evict resource from cache")
+	}
+}
+
+// FlushTenant drops every cached resource of a tenant, e.g. after its data is
+// replaced wholesale
+func (c *ResourceCache) FlushTenant(ctx context.Context, tenantID string) error {
+	pattern := c.prefix + ":" + escapePattern(tenantID) + ":*"
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, pattern, flushBatch).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan resource cache: %w", err)
+		}
+		if len(keys) > 0 {
+			if err := c.client.Del(ctx, keys...).Err(); err != nil {
+				return fmt.Errorf("failed to flush resource cache: %w", err)
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// GetUpstream reads a cached response of an upstream FHIR server, which is
+// shared by every tenant
+func (c *ResourceCache) GetUpstream(ctx context.Context, key string) ([]byte, bool) {
+	value, err := c.client.Get(ctx, c.upstreamKey(key)).Bytes()
+	if err == redis.Nil {
+		c.countMiss()
+		return nil, false
+	}
+	if err != nil {
+		c.logger.WithContext(ctx).WithError(err).Warn("Failed to read upstream response cache")
+		return nil, false
+	}
+	c.countHit()
+	return value, true
+}
+
+// SetUpstream caches a response of an upstream FHIR server for ttl
+func (c *ResourceCache) SetUpstream(ctx context.Context, key string, data []byte, ttl time.Duration) {
+	if err := c.client.Set(ctx, c.upstreamKey(key), data, ttl).Err(); err != nil {
+		c.logger.WithContext(ctx).WithError(err).Warn("Failed to cache upstream response")
+	}
+}
+
+// key returns the key of a resource: <prefix>:<tenant>:<resource type>:<id>
+func (c *ResourceCache) key(tenantID, resourceType string, id uuid.UUID) string {
+	return c.prefix + ":" + tenantID + ":" + resourceType + ":" + id.String()
+}
+
+// upstreamKey returns the key of an upstream response: <prefix>:_upstream:<key>.
+// Tenant ids cannot start with an underscore, so FlushTenant leaves it alone.
+func (c *ResourceCache) upstreamKey(key string) string {
+	return c.prefix + ":_upstream:" + key
+}
+
+func (c *ResourceCache) countHit() {
+	if c.metrics != nil {
+		c.metrics.IncrementCacheHits()
+	}
+}
+
+func (c *ResourceCache) countMiss() {
+	if c.metrics != nil {
+		c.metrics.IncrementCacheMisses()
+	}
+}
+
+// entry encodes a cache entry
+func entry(version int, v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(version) + "\n" + string(data), nil
+}
+
+// escapePattern escapes the glob characters of a SCAN pattern
+func escapePattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
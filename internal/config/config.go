@@ -1,28 +1,167 @@
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
 	"os"
 	"strconv"
-
-	"github.com/joho/godotenv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Environment string
-	Server      ServerConfig
-	Database    DatabaseConfig
-	JWT         JWTConfig
-	LogLevel    int
+	Environment   string
+	Server        ServerConfig
+	Database      DatabaseConfig
+	JWT           JWTConfig
+	Retention     RetentionConfig
+	Tenancy       TenancyConfig
+	ObjectStore   ObjectStoreConfig
+	Worker        WorkerConfig
+	Scheduler     SchedulerConfig
+	RateLimit     RateLimitConfig
+	CORS          CORSConfig
+	API           APIConfig
+	HL7           HL7Config
+	Events        EventsConfig
+	Webhooks      WebhookConfig
+	Attachments   AttachmentConfig
+	Search        SearchConfig
+	Cache         CacheConfig
+	Notifications NotificationConfig
+	Terminology   TerminologyConfig
+	Identifiers   IdentifierConfig
+	EMPI          EMPIConfig
+	Duplicates    DuplicateConfig
+	Derived       DerivedObservationConfig
+	Stats         StatsConfig
+	Deidentify    DeidentifyConfig
+	Imaging       ImagingConfig
+	Federation    FederationConfig
+	SIEM          SIEMConfig
+	Maintenance   MaintenanceConfig
+	Shutdown      ShutdownConfig
+	SelfTest      SelfTestConfig
+	RequestLog    RequestLogConfig
+	// Feature flags by name, e.g. "stats=false"; a feature without a flag is on
+	Features map[string]bool
+	LogLevel int
 }
 
 type ServerConfig struct {
-	Port         int
+	Port int
+	// Where the API listens instead of Port, for deployments behind a local
+	// reverse proxy: "unix:<path>" for a Unix domain socket, or "systemd" for
+	// the first socket passed by systemd socket activation
+	Listen string
+	// Permissions, in octal, of a Unix domain socket the server creates
+	SocketMode   string
 	ReadTimeout  int
 	WriteTimeout int
 	IdleTimeout  int
+	// Hours a response to a POST with an Idempotency-Key is replayed for retries
+	IdempotencyTTL int
+	// Deadlines applied to each class of request
+	RequestTimeouts RequestTimeoutConfig
+	// Limits on requests handled at once
+	LoadShedding LoadSheddingConfig
+	// Addresses or CIDRs of the load balancers and proxies in front of the
+	// server. The client IP is read from RemoteIPHeaders only on connections from
+	// these, walking X-Forwarded-For from the right past every trusted hop; with
+	// none, the connection's address is the client IP.
+	TrustedProxies  []string
+	RemoteIPHeaders []string
+	// TLS terminated by the server itself
+	TLS TLSConfig
+	// Listener for metrics, profiling, health and platform administration
+	Management ManagementConfig
+}
+
+// ManagementConfig serves the operational endpoints, /metrics, the health
+// probes, Go profiling and the platform admin routes, on a second port bound
+// to an internal address, so they cannot be reached where the API is. Without
+// a port they are served on the API port, except profiling, which needs the
+// management listener.
+type ManagementConfig struct {
+	Port int
+	// Address the listener binds to: loopback by default, or e.g. the pod
+	// address for scraping from inside the cluster
+	Host string
+	// Serve Go runtime profiles under /debug/pprof
+	Pprof bool
+}
+
+// Enabled reports whether the management listener is on
+func (c ManagementConfig) Enabled() bool {
+	return c.Port != 0
+}
+
+// TLSConfig lets the server terminate TLS itself, for deployments without a
+// proxy in front of it. TLS is on when a certificate file or autocert domains
+// are set; otherwise the server speaks plain HTTP.
+type TLSConfig struct {
+	// PEM certificate chain and private key; read again on SIGHUP, so a renewed
+	// certificate is served without a restart
+	CertFile string
+	KeyFile  string
+	// Domains to obtain certificates for from an ACME CA instead of files. The
+	// CA must reach the server on port 443 for TLS-ALPN-01 challenges, or on
+	// RedirectPort, when it is 80, for HTTP-01 challenges.
+	AutocertDomains []string
+	// Contact address registered with the CA
+	AutocertEmail string
+	// Directory certificates and the account key are kept in, so restarts do
+	// not request new ones
+	AutocertCacheDir string
+	// ACME directory of the CA; empty uses Let's Encrypt
+	AutocertDirectoryURL string
+	// Lowest protocol version accepted: "1.2" (default) or "1.3"
+	MinVersion string
+	// Negotiate HTTP/2 with clients that support it
+	HTTP2 bool
+	// Port plain HTTP requests are redirected to HTTPS from; 0 disables
+	RedirectPort int
+}
+
+// Enabled reports whether the server terminates TLS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || len(c.AutocertDomains) > 0
+}
+
+// RequestTimeoutConfig sets the deadline of each class of request in seconds; 0
+// leaves the class without one. Work still running at the deadline, such as a
+// database query, is cancelled.
+type RequestTimeoutConfig struct {
+	// GET of a single resource
+	Read int
+	// GET of a collection, e.g. searches and history
+	Search int
+	// POST, PUT, PATCH and DELETE
+	Write int
+	// Requests under /api/v1/admin, which may run longer
+	Admin int
+}
+
+// LoadSheddingConfig limits the requests in flight. Requests over a limit are
+// rejected with 503 Service Unavailable at once rather than queued, so a burst
+// cannot pile up work the server has no time for.
+type LoadSheddingConfig struct {
+	// Requests in flight across the server; 0 leaves it unlimited
+	MaxInFlight int
+	// Requests in flight per route, keyed by route pattern, e.g.
+	// "/api/v1/observations=50"
+	RouteLimits map[string]int
+	// Seconds clients are told to wait before retrying a rejected request
+	RetryAfter int
 }
 
 type DatabaseConfig struct {
+	// Storage driver: "postgres" (default) or "embedded-postgres"
+	Driver           string
+	EmbeddedDataPath string
+
 	Host     string
 	Port     int
 	User     string
@@ -30,6 +169,20 @@ type DatabaseConfig struct {
 	Name     string
 	SSLMode  string
 	URL      string
+
+	// Connection URL without the statement timeout, for long-running migrations
+	MigrationURL string
+
+	// Apply pending migrations at server start; when false the server only
+	// verifies the schema and migrations are rolled out with cmd/migrate
+	AutoMigrate bool
+
+	// Server-side cap on any single statement, in milliseconds (0 disables)
+	StatementTimeout int
+
+	// Read replicas (host or host:port), sharing the primary's credentials
+	ReplicaHosts []string
+	ReplicaURLs  []string
 }
 
 type JWTConfig struct {
@@ -37,41 +190,820 @@ type JWTConfig struct {
 	Expiration int
 }
 
+// TenancyConfig controls multi-tenant request scoping
+type TenancyConfig struct {
+	// Tenant assigned to tokens without a tenant_id claim; empty rejects such tokens
+	DefaultTenant string
+}
+
+// ObjectStoreConfig selects where blobs such as backup snapshots and
+// attachments are kept
+type ObjectStoreConfig struct {
+	// Backend: "filesystem" (default), "s3" or "gcs"
+	Backend string
+	// Root directory for the filesystem backend
+	Path string
+	// Bucket, credentials and location for the s3 and gcs backends. Endpoint
+	// defaults to the provider's; set it for other S3-compatible services.
+	Bucket          string
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses the bucket in the URL path rather than the host name,
+	// as MinIO and some other S3-compatible services require
+	PathStyle bool
+}
+
+// WorkerConfig controls background job processing
+type WorkerConfig struct {
+	// Number of job workers in this process; 0 only submits jobs for other processes to run
+	Workers int
+	// QueueBackend: "memory" (default, per process) or "redis" (shared between processes)
+	QueueBackend string
+	// Maximum number of waiting jobs
+	QueueSize int
+	// Redis connection URL and list key for the redis backend
+	RedisURL  string
+	QueueName string
+	// Maximum concurrent jobs per job type in this process, e.g. "backup=1,patient_index=20"
+	TypeConcurrency map[string]int
+	// Seconds to wait for running jobs on shutdown before cancelling and requeueing them
+	DrainTimeout int
+	// Seconds a job taken from the redis queue stays leased to its process without a
+	// heartbeat; expired leases are put back on the queue for another process
+	LeaseTimeout int
+	// Days to keep finished jobs and attempt results
+	HistoryDays int
+}
+
+// RateLimitConfig sets request rate limits. Requests with a token are limited per
+// token subject, so clients behind one proxy do not share a bucket; requests
+// without one are limited per client IP.
+type RateLimitConfig struct {
+	// Requests per minute and burst for each client IP without a token
+	AnonymousPerMinute int
+	AnonymousBurst     int
+	// Requests per minute and burst for each authenticated subject
+	PerMinute int
+	Burst     int
+	// Tiers for tokens carrying a scope, e.g. "bulk:write=6000:500"; a token gets
+	// the most generous tier among its scopes
+	ScopeTiers map[string]RateTier
+}
+
+// RateTier is a request rate limit
+type RateTier struct {
+	PerMinute int
+	Burst     int
+}
+
+// CORSConfig is the cross-origin policy for browser clients
+type CORSConfig struct {
+	// Origins allowed to call the API, e.g. "https://app.example.com". "*" allows
+	// any origin, and "https://*.example.com" any subdomain of example.com.
+	// Without a setting, development allows localhost and production allows none.
+	AllowedOrigins []string
+	AllowedMethods []string
+	// Request headers clients may send, and response headers they may read
+	AllowedHeaders []string
+	ExposedHeaders []string
+	// Whether browsers send cookies and Authorization; never with a "*" origin
+	AllowCredentials bool
+	// Seconds browsers may cache a preflight response
+	MaxAge int
+}
+
+// APIConfig controls which API versions are served
+type APIConfig struct {
+	// Serve /api/v2 alongside /api/v1
+	V2Enabled bool
+	// When set, v1 endpoints that v2 replaces announce when they were deprecated
+	// and when they will be removed
+	V1DeprecatedAt time.Time
+	V1Sunset       time.Time
+}
+
+// HL7Config controls HL7 v2 feeds received over MLLP. The HTTP endpoint is
+// always available; MLLP is for senders that cannot use HTTP.
+type HL7Config struct {
+	// Listen for MLLP connections on MLLPPort
+	MLLPEnabled bool
+	MLLPPort    int
+	// MLLP has no authentication, so every message is applied to this tenant and
+	// connections are only accepted from these addresses or CIDRs; with none, any
+	// address may connect
+	MLLPTenant       string
+	MLLPAllowedCIDRs []string
+	// Seconds a connection may stay idle between messages
+	MLLPIdleTimeout int
+}
+
+// EventsConfig selects the transport resource change events are published on
+type EventsConfig struct {
+	// Transport: "none" (default) or "nats"
+	Transport string
+	NATS      NATSConfig
+}
+
+// NATSConfig connects to a NATS server with JetStream enabled. Events are
+// published on "<SubjectPrefix>.<tenant>.<resource type>.<action>", so a stream
+// must capture "<SubjectPrefix>.>"; the API does not create one.
+type NATSConfig struct {
+	// Server URL, nats://host:port or tls://host:port
+	URL           string
+	SubjectPrefix string
+	// Credentials: a token, or a user and password
+	Token    string
+	User     string
+	Password string
+	// Seconds to wait for JetStream to acknowledge an event
+	Timeout int
+}
+
+// WebhookConfig controls delivery of resource change events to the webhooks
+// tenants register
+type WebhookConfig struct {
+	Enabled bool
+	// Accept http:// webhook URLs; https:// is always accepted
+	AllowHTTP bool
+	// Deliver to loopback, private and link-local addresses. Off, deliveries to
+	// such addresses are refused after resolving the host, so webhooks cannot
+	// reach services inside the deployment's network.
+	AllowPrivateNetworks bool
+	// Seconds to wait for an endpoint to respond
+	Timeout int
+}
+
+// AttachmentConfig controls attachment uploads and their download links
+type AttachmentConfig struct {
+	// Largest attachment accepted, in bytes
+	MaxSize int64
+	// Seconds a signed download URL stays valid
+	URLTTL int
+	// Key signing download URLs; derived from the JWT secret when empty
+	URLSecret string
+}
+
+// SearchConfig selects where text searches run. CRUD and the structured search
+// parameters always use PostgreSQL.
+type SearchConfig struct {
+	// Backend: "postgres" (default) or "elasticsearch", which also speaks to OpenSearch
+	Backend       string
+	Elasticsearch ElasticsearchConfig
+}
+
+// ElasticsearchConfig connects to an Elasticsearch or OpenSearch cluster.
+// Resources are indexed in "<IndexPrefix>-patients" and
+// "<IndexPrefix>-observations", created with their mappings on startup.
+type ElasticsearchConfig struct {
+	// Node URLs, tried in turn when one cannot be reached
+	URLs        []string
+	IndexPrefix string
+	// Credentials: an API key, or a username and password
+	APIKey   string
+	Username string
+	Password string
+	// Seconds to wait for a request to the cluster
+	Timeout int
+}
+
+// CacheConfig controls the Redis cache of resources read by id. Writes through
+// the API update or drop the cached copy in every process sharing the cache.
+type CacheConfig struct {
+	Enabled bool
+	// Redis connection URL; defaults to the job queue's
+	RedisURL  string
+	KeyPrefix string
+	// Seconds a resource stays cached
+	TTL int
+}
+
+// NotificationConfig controls the notifications sent to the recipients tenants
+// register, such as emails about critical results
+type NotificationConfig struct {
+	// EmailProvider: "none" (default), which sends no email, or "smtp"
+	EmailProvider string
+	SMTP          SMTPConfig
+	// SMSProvider: "none" (default), which sends no SMS, or "twilio" for
+	// Twilio or a provider with a Twilio-compatible API
+	SMSProvider string
+	Twilio      TwilioConfig
+	// Public URL of the API, e.g. https://api.example.com, used to link to
+	// resources; without it notifications carry resource ids only
+	LinkBaseURL string
+}
+
+// SMTPConfig connects to the mail server notifications are sent through
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Credentials, sent only over TLS; empty sends without authenticating
+	Username string
+	Password string
+	// From is the sender address, e.g. "Healthcare API <noreply@example.com>"
+	From string
+	// TLS: "starttls" (default), "tls" for implicit TLS such as on port 465, or
+	// "none" for a local relay
+	TLS string
+	// Seconds to wait for the server during a send
+	Timeout int
+}
+
+// TwilioConfig connects to the Twilio Messages API SMS pages are sent through
+type TwilioConfig struct {
+	// Base URL of the API; override it for a Twilio-compatible provider
+	APIURL     string
+	AccountSID string
+	AuthToken  string
+	// From is the sending number in E.164 format, e.g. +15005550006; ignored
+	// when MessagingServiceSID is set
+	From                string
+	MessagingServiceSID string
+	// Seconds to wait for the API during a send
+	Timeout int
+}
+
+// TerminologyConfig controls the checks of clinical codes in requests. SNOMED CT
+// codes are always checked to be well-formed concept ids; elements with a value
+// set must also use its concepts.
+type TerminologyConfig struct {
+	// FHIR ValueSet JSON or RF2 simple refset (.txt) files of the SNOMED CT
+	// concepts accepted in Observation.bodySite and Observation.interpretation;
+	// empty accepts any concept
+	SNOMEDBodySiteValueSet       string
+	SNOMEDInterpretationValueSet string
+	// SNOMEDValueSetSeverity: "warning" (default) accepts concepts outside the
+	// value set with a warning, "error" rejects them
+	SNOMEDValueSetSeverity string
+}
+
+// IdentifierConfig selects the checks of country-specific patient identifiers
+type IdentifierConfig struct {
+	// Validators enables identifier plugins, each a plugin name checking its
+	// default system, e.g. "nhs", or name=system to check another; empty
+	// checks no identifiers
+	Validators []string
+}
+
+// EMPIConfig connects to the enterprise master patient index that assigns
+// patients their enterprise identifier. Created and updated patients are looked
+// up by empi_sync jobs, and the identifier found is stored on the patient.
+type EMPIConfig struct {
+	// Provider: "none" (default), "fhir" for an EMPI offering Patient/$match,
+	// or "pixm" for an IHE PIXm patient identifier cross-reference manager
+	Provider string
+	// Base URL of the EMPI's FHIR API
+	URL string
+	// Bearer token sent with every request; empty sends none
+	Token string
+	// EnterpriseSystem is the identifier system of enterprise identifiers,
+	// e.g. urn:oid:2.16.840.1.113883.3.72.5.9.1
+	EnterpriseSystem string
+	// SourceSystem is the system of the local identifier PIXm looks patients up by,
+	// such as the medical record number; used with "pixm" only
+	SourceSystem string
+	// Lowest $match score taken as certain when the EMPI grades no match; used
+	// with "fhir" only
+	MinScore float64
+	// Seconds to wait for a lookup
+	Timeout int
+}
+
+// DuplicateConfig controls the detection of probable duplicate patients by
+// the duplicate-detection job
+type DuplicateConfig struct {
+	// Lowest score, above 0 and at most 1, of a pair recorded for review
+	MinScore float64
+}
+
+// DerivedObservationConfig controls the observations the worker calculates
+// from others recorded in the same encounter
+type DerivedObservationConfig struct {
+	// Calculations run: "bmi" (body mass index) and "bsa" (body surface
+	// area), or "none"
+	Calculations []string
+}
+
+// StatsConfig controls the aggregate statistics of the operational dashboard
+type StatsConfig struct {
+	// Seconds computed statistics are served from the cache
+	CacheTTL int
+}
+
+// DeidentifyConfig controls de-identified exports, backups taken with a
+// de-identification profile
+type DeidentifyConfig struct {
+	// PseudonymKey is the secret pseudonyms and date shifts are derived from.
+	// Exports made with the same key give a resource the same pseudonym; without
+	// one, each export uses a random key.
+	PseudonymKey string
+}
+
+// ImagingConfig controls the ingest of DICOM study metadata from a PACS
+type ImagingConfig struct {
+	// DICOMwebURL is the base URL of the PACS's DICOMweb API, from which WADO-RS
+	// URLs of studies and series are built when the metadata carries none
+	DICOMwebURL string
+	// PatientIDSystem is the identifier system of the DICOM Patient ID, used to
+	// find the patient of a study when the ingest request names none
+	PatientIDSystem string
+}
+
+// FederationConfig proxies reads of resource types the API does not hold, such
+// as medication knowledge, to an upstream FHIR server, so clients use one base
+// URL for both
+type FederationConfig struct {
+	// Base URL of the upstream FHIR server; empty disables federation
+	UpstreamURL string
+	// FHIR resource types read through from the upstream, e.g. Medication
+	ResourceTypes []string
+	// Auth: "none" (default), "bearer" to send Token, or "client_credentials" to
+	// send a token obtained from TokenURL with ClientID and ClientSecret
+	Auth         string
+	Token        string
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// Space-separated scopes requested with client credentials; empty requests none
+	Scope string
+	// Seconds to wait for the upstream
+	Timeout int
+	// Seconds an upstream response stays cached; 0 disables caching
+	CacheTTL int
+}
+
+// SIEMConfig forwards the access log, the record of every API request, to a
+// security information and event management system as it is written
+type SIEMConfig struct {
+	// Transport: "none" (default), "syslog" for RFC 5424 syslog carrying CEF
+	// messages, or "https" for JSON batches POSTed to a collector
+	Transport string
+	// Syslog server, host:port, reached over SyslogNetwork: "udp", "tcp" or "tls"
+	SyslogAddress string
+	SyslogNetwork string
+	// Collector URL and the bearer token sent to it; empty sends none
+	HTTPSURL   string
+	HTTPSToken string
+	// Events held in memory while the SIEM cannot take them; newer events are
+	// dropped when it is full
+	BufferSize int
+	// Events sent at a time, and seconds a partial batch waits before it is sent
+	BatchSize     int
+	FlushInterval int
+	// Seconds to wait for the SIEM to take a batch
+	Timeout int
+}
+
+// MaintenanceConfig controls maintenance mode, in which writes are refused
+// and background jobs pause, e.g. while schema migrations run. It is normally
+// switched through the admin API, which stores it in the database for every
+// instance; Enabled turns it on regardless.
+type MaintenanceConfig struct {
+	Enabled bool
+	// Seconds clients are told to wait before retrying a refused write
+	RetryAfter int
+	// Seconds between reads of the stored maintenance state
+	PollInterval int
+}
+
+// ShutdownConfig sets the stages of a graceful shutdown, which run in order:
+// intake stops, HTTP requests drain, background jobs drain (within
+// WORKER_DRAIN_TIMEOUT), then connections close. Each stage has its own
+// timeout, in seconds, so one overrunning does not take the time of the next.
+type ShutdownConfig struct {
+	// Seconds readiness reports not ready before the server stops accepting
+	// connections, so load balancers stop routing to it first
+	Delay int
+	// Seconds in-flight HTTP requests and MLLP messages get to finish
+	DrainTimeout int
+	// Seconds exporters get to flush and connections to close
+	CloseTimeout int
+}
+
+// SelfTestConfig controls the checks run at startup before any traffic is
+// served: the schema is migrated, its indexes exist, the JWT key works and a
+// canary query succeeds
+type SelfTestConfig struct {
+	// Seconds failing checks are retried for before the process exits; 0 tries
+	// once
+	Timeout int
+	// Seconds between attempts
+	RetryInterval int
+	// Checks whose failure is logged as degraded but does not hold startup
+	// back: migrations, indexes, jwt or canary
+	DegradedAllowed []string
+}
+
+// RequestLogConfig controls what is logged about API requests. Bodies and
+// query strings may hold PHI, so their values are redacted unless their field
+// only describes the request, and strict mode records no body at all.
+type RequestLogConfig struct {
+	// BodyMode: "redacted" (default) records JSON bodies with every value
+	// outside the kept fields redacted; "strict" records only their size
+	BodyMode string
+	// Fields whose values are kept besides the built-in ones, such as status and
+	// code, and built-in ones whose values are redacted anyway
+	KeepFields   []string
+	RedactFields []string
+	// Patterns scrubbed from kept values and from every log line: ssn, email
+	// and phone, all by default, or "none"
+	ScrubPatterns []string
+	// Fraction of successful requests, from 0 to 1, whose log lines are
+	// written; failed and slow requests are always logged. The access log
+	// records every request regardless.
+	SampleRate float64
+	// Milliseconds after which a request is slow
+	SlowThreshold int
+}
+
+// SchedulerConfig controls recurring jobs. Schedules use standard five-field cron
+// expressions or descriptors such as "@daily" and "@every 6h".
+type SchedulerConfig struct {
+	Enabled bool
+	// How often due schedules are checked, in seconds
+	PollInterval int
+	// Schedule for preloading the tenant cache (entries live 30s); empty disables it
+	CacheWarmupSchedule string
+	// Schedule for pruning job history older than Worker.HistoryDays; empty disables it
+	JobHistoryCleanupSchedule string
+	// Schedule for releasing SMS pages held over quiet hours and escalating
+	// unacknowledged ones; used only when an SMS provider is configured
+	NotificationEscalationSchedule string
+	// Schedule for looking up again the patients the EMPI disagreed on or could not
+	// be asked about; used only when an EMPI is configured
+	EMPIReconciliationSchedule string
+	// Schedule for scanning the patients of every tenant for probable duplicates
+	DuplicateDetectionSchedule string
+}
+
+// RetentionConfig controls archival and purging of old audit logs and soft-deleted resources
+type RetentionConfig struct {
+	Enabled       bool
+	DryRun        bool
+	IntervalHours int
+	// Cron schedule for retention runs; defaults to every IntervalHours
+	Schedule            string
+	BatchSize           int
+	AuditLogDays        int
+	DeletedResourceDays int
+
+	// Per-resource-type overrides, in days, keyed by FHIR resource type
+	AuditLogOverrides        map[string]int
+	DeletedResourceOverrides map[string]int
+}
+
+// defaultJWTSecret signs tokens when JWT_SECRET is unset. It is public, so
+// production refuses to run with it.
+const defaultJWTSecret = "your-secret-key"
+
+// Load reads the configuration from the environment and the .env file, if it
+// exists. Every malformed value and missing or invalid setting is reported
+// together in a *ValidationError, rather than falling back to a default.
 func Load() (*Config, error) {
-	// Load .env file if it exists
-	_ = godotenv.Load()
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	problems = nil
+
+	if err := loadEnvFile(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	environment := getEnv("ENVIRONMENT", "development")
 
 	cfg := &Config{
-		Environment: getEnv("ENVIRONMENT", "development"),
+		Environment: environment,
 		Server: ServerConfig{
 			Port:         getEnvAsInt("SERVER_PORT", 8080),
+			Listen:       os.Getenv("SERVER_LISTEN"),
+			SocketMode:   getEnv("SERVER_SOCKET_MODE", "0660"),
 			ReadTimeout:  getEnvAsInt("SERVER_READ_TIMEOUT", 30),
 			WriteTimeout: getEnvAsInt("SERVER_WRITE_TIMEOUT", 30),
 			IdleTimeout:  getEnvAsInt("SERVER_IDLE_TIMEOUT", 120),
+
+			IdempotencyTTL: getEnvAsInt("IDEMPOTENCY_TTL_HOURS", 24),
+			RequestTimeouts: RequestTimeoutConfig{
+				Read:   getEnvAsInt("REQUEST_TIMEOUT_READ", 5),
+				Search: getEnvAsInt("REQUEST_TIMEOUT_SEARCH", 15),
+				Write:  getEnvAsInt("REQUEST_TIMEOUT_WRITE", 10),
+				Admin:  getEnvAsInt("REQUEST_TIMEOUT_ADMIN", 25),
+			},
+			TrustedProxies:  getEnvAsSlice("TRUSTED_PROXIES", nil),
+			RemoteIPHeaders: getEnvAsSlice("REMOTE_IP_HEADERS", []string{"X-Forwarded-For", "X-Real-IP"}),
+			LoadShedding: LoadSheddingConfig{
+				MaxInFlight: getEnvAsInt("LOAD_SHED_MAX_IN_FLIGHT", 0),
+				RouteLimits: getEnvAsIntMap("LOAD_SHED_ROUTE_LIMITS"),
+				RetryAfter:  getEnvAsInt("LOAD_SHED_RETRY_AFTER", 1),
+			},
+			TLS: TLSConfig{
+				CertFile:             os.Getenv("TLS_CERT_FILE"),
+				KeyFile:              os.Getenv("TLS_KEY_FILE"),
+				AutocertDomains:      getEnvAsSlice("TLS_AUTOCERT_DOMAINS", nil),
+				AutocertEmail:        os.Getenv("TLS_AUTOCERT_EMAIL"),
+				AutocertCacheDir:     getEnv("TLS_AUTOCERT_CACHE_DIR", ".data/autocert"),
+				AutocertDirectoryURL: os.Getenv("TLS_AUTOCERT_DIRECTORY_URL"),
+				MinVersion:           getEnv("TLS_MIN_VERSION", "1.2"),
+				HTTP2:                getEnvAsBool("HTTP2_ENABLED", true),
+				RedirectPort:         getEnvAsInt("TLS_REDIRECT_HTTP_PORT", 0),
+			},
+			Management: ManagementConfig{
+				Port:  getEnvAsInt("MANAGEMENT_PORT", 0),
+				Host:  getEnv("MANAGEMENT_HOST", "127.0.0.1"),
+				Pprof: getEnvAsBool("MANAGEMENT_PPROF", false),
+			},
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnvAsInt("DB_PORT", 5432),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", ""),
-			Name:     getEnv("DB_NAME", "rds"),
-			SSLMode:  getEnv("DB_SSL_MODE", "disable"),
+			Driver:           getEnv("DB_DRIVER", "postgres"),
+			EmbeddedDataPath: getEnv("DB_EMBEDDED_DATA_PATH", ".data/postgres"),
+
+			Host:         getEnv("DB_HOST", "localhost"),
+			Port:         getEnvAsInt("DB_PORT", 5432),
+			User:         getEnv("DB_USER", "postgres"),
+			Password:     getEnv("DB_PASSWORD", ""),
+			Name:         getEnv("DB_NAME", "rds"),
+			SSLMode:      getEnv("DB_SSL_MODE", "disable"),
+			ReplicaHosts: getEnvAsSlice("DB_READ_REPLICAS", nil),
+
+			StatementTimeout: getEnvAsInt("DB_STATEMENT_TIMEOUT_MS", 30000),
+			AutoMigrate:      getEnvAsBool("DB_AUTO_MIGRATE", true),
 		},
 		JWT: JWTConfig{
-			Secret:     getEnv("JWT_SECRET", "your-secret-key"),
+			Secret:     getEnv("JWT_SECRET", defaultJWTSecret),
 			Expiration: getEnvAsInt("JWT_EXPIRATION", 3600),
 		},
+		Retention: RetentionConfig{
+			Enabled:                  getEnvAsBool("RETENTION_ENABLED", false),
+			DryRun:                   getEnvAsBool("RETENTION_DRY_RUN", true),
+			IntervalHours:            getEnvAsInt("RETENTION_INTERVAL_HOURS", 24),
+			Schedule:                 os.Getenv("RETENTION_SCHEDULE"),
+			BatchSize:                getEnvAsInt("RETENTION_BATCH_SIZE", 1000),
+			AuditLogDays:             getEnvAsInt("RETENTION_AUDIT_LOG_DAYS", 2190), // 6 years
+			DeletedResourceDays:      getEnvAsInt("RETENTION_DELETED_RESOURCE_DAYS", 365),
+			AuditLogOverrides:        getEnvAsIntMap("RETENTION_AUDIT_LOG_OVERRIDES"),
+			DeletedResourceOverrides: getEnvAsIntMap("RETENTION_DELETED_RESOURCE_OVERRIDES"),
+		},
+		Tenancy: TenancyConfig{
+			DefaultTenant: os.Getenv("DEFAULT_TENANT_ID"),
+		},
+		ObjectStore: ObjectStoreConfig{
+			Backend:         getEnv("OBJECT_STORE_BACKEND", "filesystem"),
+			Path:            getEnv("OBJECT_STORE_PATH", ".data/objects"),
+			Bucket:          os.Getenv("OBJECT_STORE_BUCKET"),
+			Endpoint:        os.Getenv("OBJECT_STORE_ENDPOINT"),
+			Region:          os.Getenv("OBJECT_STORE_REGION"),
+			AccessKeyID:     os.Getenv("OBJECT_STORE_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("OBJECT_STORE_SECRET_ACCESS_KEY"),
+			PathStyle:       getEnvAsBool("OBJECT_STORE_PATH_STYLE", false),
+		},
+		Worker: WorkerConfig{
+			Workers:         getEnvAsInt("WORKER_COUNT", 10),
+			QueueBackend:    getEnv("WORKER_QUEUE_BACKEND", "memory"),
+			QueueSize:       getEnvAsInt("WORKER_QUEUE_SIZE", 1000),
+			RedisURL:        getEnv("REDIS_URL", "redis://localhost:6379/0"),
+			QueueName:       getEnv("WORKER_QUEUE_NAME", "healthcare:jobs"),
+			TypeConcurrency: getEnvAsIntMap("WORKER_TYPE_CONCURRENCY"),
+			DrainTimeout:    getEnvAsInt("WORKER_DRAIN_TIMEOUT", 30),
+			LeaseTimeout:    getEnvAsInt("WORKER_LEASE_TIMEOUT", 60),
+			HistoryDays:     getEnvAsInt("JOB_HISTORY_DAYS", 30),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:                        getEnvAsBool("SCHEDULER_ENABLED", true),
+			PollInterval:                   getEnvAsInt("SCHEDULER_POLL_INTERVAL", 15),
+			CacheWarmupSchedule:            os.Getenv("CACHE_WARMUP_SCHEDULE"),
+			JobHistoryCleanupSchedule:      getEnv("JOB_HISTORY_CLEANUP_SCHEDULE", "@daily"),
+			NotificationEscalationSchedule: getEnv("NOTIFICATION_ESCALATION_SCHEDULE", "@every 1m"),
+			EMPIReconciliationSchedule:     getEnv("EMPI_RECONCILIATION_SCHEDULE", "@hourly"),
+			DuplicateDetectionSchedule:     getEnv("DUPLICATE_DETECTION_SCHEDULE", "@daily"),
+		},
+		RateLimit: RateLimitConfig{
+			AnonymousPerMinute: getEnvAsInt("RATE_LIMIT_ANONYMOUS_PER_MINUTE", 100),
+			AnonymousBurst:     getEnvAsInt("RATE_LIMIT_ANONYMOUS_BURST", 20),
+			PerMinute:          getEnvAsInt("RATE_LIMIT_PER_MINUTE", 1200),
+			Burst:              getEnvAsInt("RATE_LIMIT_BURST", 100),
+			ScopeTiers:         getEnvAsTierMap("RATE_LIMIT_SCOPE_TIERS"),
+		},
+		API: APIConfig{
+			V2Enabled:      getEnvAsBool("API_V2_ENABLED", false),
+			V1DeprecatedAt: getEnvAsDate("API_V1_DEPRECATED_AT"),
+			V1Sunset:       getEnvAsDate("API_V1_SUNSET"),
+		},
+		HL7: HL7Config{
+			MLLPEnabled:      getEnvAsBool("HL7_MLLP_ENABLED", false),
+			MLLPPort:         getEnvAsInt("HL7_MLLP_PORT", 2575),
+			MLLPTenant:       getEnv("HL7_MLLP_TENANT", os.Getenv("DEFAULT_TENANT_ID")),
+			MLLPAllowedCIDRs: getEnvAsSlice("HL7_MLLP_ALLOWED_CIDRS", nil),
+			MLLPIdleTimeout:  getEnvAsInt("HL7_MLLP_IDLE_TIMEOUT", 300),
+		},
+		Events: EventsConfig{
+			Transport: getEnv("EVENTS_TRANSPORT", "none"),
+			NATS: NATSConfig{
+				URL:           getEnv("NATS_URL", "nats://localhost:4222"),
+				SubjectPrefix: getEnv("NATS_SUBJECT_PREFIX", "healthcare.events"),
+				Token:         os.Getenv("NATS_TOKEN"),
+				User:          os.Getenv("NATS_USER"),
+				Password:      os.Getenv("NATS_PASSWORD"),
+				Timeout:       getEnvAsInt("NATS_TIMEOUT", 5),
+			},
+		},
+		Webhooks: WebhookConfig{
+			Enabled:              getEnvAsBool("WEBHOOKS_ENABLED", true),
+			AllowHTTP:            getEnvAsBool("WEBHOOK_ALLOW_HTTP", environment != "production"),
+			AllowPrivateNetworks: getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", environment != "production"),
+			Timeout:              getEnvAsInt("WEBHOOK_TIMEOUT", 10),
+		},
+		Attachments: AttachmentConfig{
+			MaxSize:   int64(getEnvAsInt("ATTACHMENT_MAX_SIZE_MB", 10)) << 20,
+			URLTTL:    getEnvAsInt("ATTACHMENT_URL_TTL", 300),
+			URLSecret: os.Getenv("ATTACHMENT_URL_SECRET"),
+		},
+		Cache: CacheConfig{
+			Enabled:   getEnvAsBool("CACHE_ENABLED", false),
+			RedisURL:  getEnv("CACHE_REDIS_URL", getEnv("REDIS_URL", "redis://localhost:6379/0")),
+			KeyPrefix: getEnv("CACHE_KEY_PREFIX", "healthcare:cache"),
+			TTL:       getEnvAsInt("CACHE_TTL", 300),
+		},
+		Notifications: NotificationConfig{
+			EmailProvider: getEnv("EMAIL_PROVIDER", "none"),
+			SMTP: SMTPConfig{
+				Host:     getEnv("SMTP_HOST", "localhost"),
+				Port:     getEnvAsInt("SMTP_PORT", 587),
+				Username: os.Getenv("SMTP_USERNAME"),
+				Password: os.Getenv("SMTP_PASSWORD"),
+				From:     os.Getenv("SMTP_FROM"),
+				TLS:      getEnv("SMTP_TLS", "starttls"),
+				Timeout:  getEnvAsInt("SMTP_TIMEOUT", 10),
+			},
+			SMSProvider: getEnv("SMS_PROVIDER", "none"),
+			Twilio: TwilioConfig{
+				APIURL:              strings.TrimSuffix(getEnv("TWILIO_API_URL", "https://api.twilio.com"), "/"),
+				AccountSID:          os.Getenv("TWILIO_ACCOUNT_SID"),
+				AuthToken:           os.Getenv("TWILIO_AUTH_TOKEN"),
+				From:                os.Getenv("TWILIO_FROM"),
+				MessagingServiceSID: os.Getenv("TWILIO_MESSAGING_SERVICE_SID"),
+				Timeout:             getEnvAsInt("TWILIO_TIMEOUT", 10),
+			},
+			LinkBaseURL: strings.TrimSuffix(os.Getenv("NOTIFICATION_LINK_BASE_URL"), "/"),
+		},
+		Terminology: TerminologyConfig{
+			SNOMEDBodySiteValueSet:       os.Getenv("SNOMED_BODY_SITE_VALUE_SET"),
+			SNOMEDInterpretationValueSet: os.Getenv("SNOMED_INTERPRETATION_VALUE_SET"),
+			SNOMEDValueSetSeverity:       getEnv("SNOMED_VALUE_SET_SEVERITY", "warning"),
+		},
+		Identifiers: IdentifierConfig{
+			Validators: getEnvAsSlice("IDENTIFIER_VALIDATORS", nil),
+		},
+		EMPI: EMPIConfig{
+			Provider:         getEnv("EMPI_PROVIDER", "none"),
+			URL:              strings.TrimSuffix(os.Getenv("EMPI_URL"), "/"),
+			Token:            os.Getenv("EMPI_TOKEN"),
+			EnterpriseSystem: os.Getenv("EMPI_ENTERPRISE_SYSTEM"),
+			SourceSystem:     os.Getenv("EMPI_SOURCE_SYSTEM"),
+			MinScore:         getEnvAsFloat("EMPI_MIN_SCORE", 0.95),
+			Timeout:          getEnvAsInt("EMPI_TIMEOUT", 10),
+		},
+		Duplicates: DuplicateConfig{
+			MinScore: getEnvAsFloat("DUPLICATE_MIN_SCORE", 0.8),
+		},
+		Derived: DerivedObservationConfig{
+			Calculations: getEnvAsSlice("DERIVED_OBSERVATIONS", []string{"bmi"}),
+		},
+		Stats: StatsConfig{
+			CacheTTL: getEnvAsInt("STATS_CACHE_TTL", 300),
+		},
+		Deidentify: DeidentifyConfig{
+			PseudonymKey: os.Getenv("DEIDENTIFY_PSEUDONYM_KEY"),
+		},
+		Imaging: ImagingConfig{
+			DICOMwebURL:     strings.TrimSuffix(os.Getenv("DICOMWEB_URL"), "/"),
+			PatientIDSystem: os.Getenv("DICOM_PATIENT_ID_SYSTEM"),
+		},
+		Federation: FederationConfig{
+			UpstreamURL:   strings.TrimSuffix(os.Getenv("FEDERATION_UPSTREAM_URL"), "/"),
+			ResourceTypes: getEnvAsSlice("FEDERATION_RESOURCE_TYPES", nil),
+			Auth:          getEnv("FEDERATION_AUTH", "none"),
+			Token:         os.Getenv("FEDERATION_TOKEN"),
+			TokenURL:      os.Getenv("FEDERATION_TOKEN_URL"),
+			ClientID:      os.Getenv("FEDERATION_CLIENT_ID"),
+			ClientSecret:  os.Getenv("FEDERATION_CLIENT_SECRET"),
+			Scope:         os.Getenv("FEDERATION_SCOPE"),
+			Timeout:       getEnvAsInt("FEDERATION_TIMEOUT", 10),
+			CacheTTL:      getEnvAsInt("FEDERATION_CACHE_TTL", 300),
+		},
+		SIEM: SIEMConfig{
+			Transport:     getEnv("SIEM_TRANSPORT", "none"),
+			SyslogAddress: os.Getenv("SIEM_SYSLOG_ADDRESS"),
+			SyslogNetwork: getEnv("SIEM_SYSLOG_NETWORK", "tcp"),
+			HTTPSURL:      os.Getenv("SIEM_HTTPS_URL"),
+			HTTPSToken:    os.Getenv("SIEM_HTTPS_TOKEN"),
+			BufferSize:    getEnvAsInt("SIEM_BUFFER_SIZE", 10000),
+			BatchSize:     getEnvAsInt("SIEM_BATCH_SIZE", 100),
+			FlushInterval: getEnvAsInt("SIEM_FLUSH_INTERVAL", 2),
+			Timeout:       getEnvAsInt("SIEM_TIMEOUT", 10),
+		},
+		Search: SearchConfig{
+			Backend: getEnv("SEARCH_BACKEND", "postgres"),
+			Elasticsearch: ElasticsearchConfig{
+				URLs:        getEnvAsSlice("ELASTICSEARCH_URLS", []string{"http://localhost:9200"}),
+				IndexPrefix: getEnv("ELASTICSEARCH_INDEX_PREFIX", "healthcare"),
+				APIKey:      os.Getenv("ELASTICSEARCH_API_KEY"),
+				Username:    os.Getenv("ELASTICSEARCH_USERNAME"),
+				Password:    os.Getenv("ELASTICSEARCH_PASSWORD"),
+				Timeout:     getEnvAsInt("ELASTICSEARCH_TIMEOUT", 10),
+			},
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   getEnvAsSlice("CORS_ALLOWED_ORIGINS", defaultCORSOrigins(environment)),
+			AllowedMethods:   getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+			AllowedHeaders:   getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "Idempotency-Key", "If-None-Match", "If-Modified-Since", "X-Request-ID"}),
+			ExposedHeaders:   getEnvAsSlice("CORS_EXPOSED_HEADERS", []string{"Content-Length", "Location", "Idempotent-Replayed", "ETag", "Last-Modified", "X-Request-ID", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining"}),
+			AllowCredentials: getEnvAsBool("CORS_ALLOW_CREDENTIALS", true),
+			MaxAge:           getEnvAsInt("CORS_MAX_AGE", 86400),
+		},
+		Maintenance: MaintenanceConfig{
+			Enabled:      getEnvAsBool("MAINTENANCE_MODE", false),
+			RetryAfter:   getEnvAsInt("MAINTENANCE_RETRY_AFTER", 300),
+			PollInterval: getEnvAsInt("MAINTENANCE_POLL_INTERVAL", 10),
+		},
+		Shutdown: ShutdownConfig{
+			Delay:        getEnvAsInt("SHUTDOWN_DELAY", 0),
+			DrainTimeout: getEnvAsInt("SHUTDOWN_DRAIN_TIMEOUT", 30),
+			CloseTimeout: getEnvAsInt("SHUTDOWN_CLOSE_TIMEOUT", 10),
+		},
+		SelfTest: SelfTestConfig{
+			Timeout:         getEnvAsInt("SELFTEST_TIMEOUT", 60),
+			RetryInterval:   getEnvAsInt("SELFTEST_RETRY_INTERVAL", 5),
+			DegradedAllowed: getEnvAsSlice("SELFTEST_DEGRADED_ALLOWED", nil),
+		},
+		RequestLog: RequestLogConfig{
+			BodyMode:      getEnv("LOG_BODY_MODE", "redacted"),
+			KeepFields:    getEnvAsSlice("LOG_KEEP_FIELDS", nil),
+			RedactFields:  getEnvAsSlice("LOG_REDACT_FIELDS", nil),
+			ScrubPatterns: getEnvAsSlice("LOG_SCRUB_PATTERNS", []string{"ssn", "email", "phone"}),
+			SampleRate:    getEnvAsFloat("LOG_SAMPLE_RATE", 1),
+			SlowThreshold: getEnvAsInt("LOG_SLOW_THRESHOLD", 1000),
+		},
+		Features: getEnvAsBoolMap("FEATURE_FLAGS"),
 		LogLevel: getEnvAsInt("LOG_LEVEL", 4), // Info level
 	}
 
+	if cfg.Retention.Schedule == "" {
+		cfg.Retention.Schedule = "@every " + strconv.Itoa(cfg.Retention.IntervalHours) + "h"
+	}
+
+	// Attachment download links get a key of their own, so one leaked with a
+	// link cannot be used to sign tokens
+	if cfg.Attachments.URLSecret == "" {
+		mac := hmac.New(sha256.New, []byte(cfg.JWT.Secret))
+		mac.Write([]byte("attachment download links"))
+		cfg.Attachments.URLSecret = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	// The embedded server always runs locally, without replicas or TLS
+	if cfg.Database.Driver == "embedded-postgres" {
+		cfg.Database.Host = "localhost"
+		cfg.Database.SSLMode = "disable"
+		cfg.Database.ReplicaHosts = nil
+		if cfg.Database.Password == "" {
+			cfg.Database.Password = "postgres"
+		}
+	}
+
 	// Build database URL
 	cfg.Database.URL = buildDatabaseURL(cfg.Database)
+	migration := cfg.Database
+	migration.StatementTimeout = 0
+	cfg.Database.MigrationURL = buildDatabaseURL(migration)
+	for _, host := range cfg.Database.ReplicaHosts {
+		replica := cfg.Database
+		replica.Host = host
+		if h, p, err := net.SplitHostPort(host); err == nil {
+			replica.Host = h
+			replica.Port, _ = strconv.Atoi(p)
+		}
+		cfg.Database.ReplicaURLs = append(cfg.Database.ReplicaURLs, buildDatabaseURL(replica))
+	}
 
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 	return cfg, nil
 }
 
+// defaultCORSOrigins returns the origins allowed when CORS_ALLOWED_ORIGINS is
+// unset: local front ends outside production, none in production
+func defaultCORSOrigins(environment string) []string {
+	if environment == "production" {
+		return nil
+	}
+	return []string{"http://localhost:3000", "https://localhost:3000", "http://127.0.0.1:3000"}
+}
+
 func buildDatabaseURL(db DatabaseConfig) string {
-	return "postgres://" + db.User + ":" + db.Password + "@" + db.Host + ":" + strconv.Itoa(db.Port) + "/" + db.Name + "?sslmode=" + db.SSLMode
+	url := "postgres://" + db.User + ":" + db.Password + "@" + db.Host + ":" + strconv.Itoa(db.Port) + "/" + db.Name + "?sslmode=" + db.SSLMode
+	if db.StatementTimeout > 0 {
+		url += "&statement_timeout=" + strconv.Itoa(db.StatementTimeout)
+	}
+	return url
 }
 
 func getEnv(key, defaultValue string) string {
@@ -83,9 +1015,105 @@ func getEnv(key, defaultValue string) string {
 
 func getEnvAsInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
+		intValue, err := strconv.Atoi(value)
+		if err == nil {
 			return intValue
 		}
+		invalidValue(key, value, "an integer")
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		floatValue, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			return floatValue
+		}
+		invalidValue(key, value, "a number")
+	}
+	return defaultValue
+}
+
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		return values
+	}
+	return defaultValue
+}
+
+// getEnvAsDate parses a YYYY-MM-DD date, as midnight UTC; unset values give the zero time
+func getEnvAsDate(key string) time.Time {
+	if value := os.Getenv(key); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err == nil {
+			return date
+		}
+		invalidValue(key, value, "a YYYY-MM-DD date")
+	}
+	return time.Time{}
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		boolValue, err := strconv.ParseBool(value)
+		if err == nil {
+			return boolValue
+		}
+		invalidValue(key, value, "true or false")
 	}
 	return defaultValue
 }
+
+// getEnvAsBoolMap parses values of the form "stats=false,cohorts=true"
+func getEnvAsBoolMap(key string) map[string]bool {
+	values := make(map[string]bool)
+	for _, item := range getEnvAsSlice(key, nil) {
+		name, value, ok := strings.Cut(item, "=")
+		boolValue, err := strconv.ParseBool(strings.TrimSpace(value))
+		if !ok || err != nil {
+			invalidValue(key, item, "name=true or name=false")
+			continue
+		}
+		values[strings.TrimSpace(name)] = boolValue
+	}
+	return values
+}
+
+// getEnvAsIntMap parses values of the form "Patient=3650,Observation=730"
+func getEnvAsIntMap(key string) map[string]int {
+	values := make(map[string]int)
+	for _, item := range getEnvAsSlice(key, nil) {
+		name, value, ok := strings.Cut(item, "=")
+		intValue, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || err != nil {
+			invalidValue(key, item, "name=integer")
+			continue
+		}
+		values[strings.TrimSpace(name)] = intValue
+	}
+	return values
+}
+
+// getEnvAsTierMap parses "name=perMinute:burst,..." into rate tiers
+func getEnvAsTierMap(key string) map[string]RateTier {
+	tiers := make(map[string]RateTier)
+	for _, item := range getEnvAsSlice(key, nil) {
+		name, value, _ := strings.Cut(item, "=")
+		perMinute, burst, ok := strings.Cut(value, ":")
+		rate, rateErr := strconv.Atoi(strings.TrimSpace(perMinute))
+		size, sizeErr := strconv.Atoi(strings.TrimSpace(burst))
+		if !ok || rateErr != nil || sizeErr != nil {
+			invalidValue(key, item, "name=perMinute:burst")
+			continue
+		}
+		tiers[strings.TrimSpace(name)] = RateTier{PerMinute: rate, Burst: size}
+	}
+	return tiers
+}
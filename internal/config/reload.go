@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+var (
+	envMu sync.Mutex
+	// processEnv are the variables set before the .env file was first read.
+	// They take precedence over the file, on every reload too.
+	processEnv map[string]bool
+	// fileEnv are the variables last set from the .env file
+	fileEnv map[string]bool
+)
+
+// loadEnvFile sets the variables of the .env file, if it exists, that the
+// process environment does not set. Read again, the file's changes apply:
+// variables get their new values and those removed from it are unset.
+func loadEnvFile() error {
+	envMu.Lock()
+	defer envMu.Unlock()
+
+	if processEnv == nil {
+		processEnv = make(map[string]bool)
+		for _, variable := range os.Environ() {
+			name, _, _ := strings.Cut(variable, "=")
+			processEnv[name] = true
+		}
+	}
+
+	values, err := godotenv.Read()
+	var pathErr *fs.PathError
+	switch {
+	case err == nil, errors.Is(err, fs.ErrNotExist):
+	case errors.As(err, &pathErr):
+		return fmt.Errorf("failed to read .env file: %w", err)
+	default:
+		// Parse errors quote the line, which may hold a secret
+		return errors.New("failed to parse .env file")
+	}
+
+	for name := range fileEnv {
+		if _, ok := values[name]; !ok {
+			os.Unsetenv(name)
+		}
+	}
+	fileEnv = make(map[string]bool, len(values))
+	for name, value := range values {
+		if processEnv[name] {
+			continue
+		}
+		os.Setenv(name, value)
+		fileEnv[name] = true
+	}
+	return nil
+}
+
+// ReloadableSettings returns the settings a reload applies, keyed by their
+// environment variable, in the form they are set in
+func (c *Config) ReloadableSettings() map[string]string {
+	tiers := make([]string, 0, len(c.RateLimit.ScopeTiers))
+	for scope, tier := range c.RateLimit.ScopeTiers {
+		tiers = append(tiers, scope+"="+strconv.Itoa(tier.PerMinute)+":"+strconv.Itoa(tier.Burst))
+	}
+	sort.Strings(tiers)
+
+	flags := make([]string, 0, len(c.Features))
+	for name, enabled := range c.Features {
+		flags = append(flags, name+"="+strconv.FormatBool(enabled))
+	}
+	sort.Strings(flags)
+
+	return map[string]string{
+		"LOG_LEVEL":                       strconv.Itoa(c.LogLevel),
+		"RATE_LIMIT_ANONYMOUS_PER_MINUTE": strconv.Itoa(c.RateLimit.AnonymousPerMinute),
+		"RATE_LIMIT_ANONYMOUS_BURST":      strconv.Itoa(c.RateLimit.AnonymousBurst),
+		"RATE_LIMIT_PER_MINUTE":           strconv.Itoa(c.RateLimit.PerMinute),
+		"RATE_LIMIT_BURST":                strconv.Itoa(c.RateLimit.Burst),
+		"RATE_LIMIT_SCOPE_TIERS":          strings.Join(tiers, ","),
+		"CORS_ALLOWED_ORIGINS":            strings.Join(c.CORS.AllowedOrigins, ","),
+		"CORS_ALLOWED_METHODS":            strings.Join(c.CORS.AllowedMethods, ","),
+		"CORS_ALLOWED_HEADERS":            strings.Join(c.CORS.AllowedHeaders, ","),
+		"CORS_EXPOSED_HEADERS":            strings.Join(c.CORS.ExposedHeaders, ","),
+		"CORS_ALLOW_CREDENTIALS":          strconv.FormatBool(c.CORS.AllowCredentials),
+		"CORS_MAX_AGE":                    strconv.Itoa(c.CORS.MaxAge),
+		"FEATURE_FLAGS":                   strings.Join(flags, ","),
+		"MAINTENANCE_MODE":                strconv.FormatBool(c.Maintenance.Enabled),
+		"MAINTENANCE_RETRY_AFTER":         strconv.Itoa(c.Maintenance.RetryAfter),
+	}
+}
@@ -0,0 +1,319 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"healthcare-api/internal/features"
+)
+
+var (
+	// loadMu serializes Load, which collects the problems it finds in problems
+	loadMu   sync.Mutex
+	problems []string
+)
+
+// environments are the values ENVIRONMENT may take
+var environments = []string{"development", "test", "staging", "production"}
+
+// requiredEnv are the variables an environment must set because their
+// defaults are only fit for a development machine. Production's credentials
+// are checked by insecureSettings.
+var requiredEnv = map[string][]string{
+	"staging":    {"DB_HOST", "DB_USER", "DB_PASSWORD", "DB_NAME", "JWT_SECRET"},
+	"production": {"DB_HOST", "DB_USER", "DB_NAME"},
+}
+
+// ValidationError reports every problem found in the configuration, each
+// naming the variable to fix
+type ValidationError struct {
+	Problems []string
+	// Insecure are the settings production refuses to start with
+	Insecure []string
+}
+
+func (e *ValidationError) Error() string {
+	var report []string
+	if len(e.Problems) > 0 {
+		report = append(report, fmt.Sprintf("invalid configuration, %d problem(s):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - ")))
+	}
+	if len(e.Insecure) > 0 {
+		report = append(report, fmt.Sprintf("refusing to run in production with %d insecure setting(s):\n  - %s", len(e.Insecure), strings.Join(e.Insecure, "\n  - ")))
+	}
+	return strings.Join(report, "\n")
+}
+
+// invalidValue records a value of key that could not be parsed
+func invalidValue(key, value, expected string) {
+	problems = append(problems, fmt.Sprintf("%s: %q is not %s", key, value, expected))
+}
+
+// validate checks the loaded configuration, returning a *ValidationError with
+// the problems found by it and while loading
+func (c *Config) validate() error {
+	v := validator{problems: problems}
+
+	v.oneOf("ENVIRONMENT", c.Environment, environments...)
+	for _, key := range requiredEnv[c.Environment] {
+		if strings.HasPrefix(key, "DB_") && c.Database.Driver == "embedded-postgres" {
+			continue
+		}
+		if os.Getenv(key) == "" {
+			v.addf("%s is required in %s", key, c.Environment)
+		}
+	}
+
+	v.port("SERVER_PORT", c.Server.Port)
+	switch listen := c.Server.Listen; {
+	case listen == "", listen == "systemd":
+	case strings.HasPrefix(listen, "unix:"):
+		if strings.TrimPrefix(listen, "unix:") == "" {
+			v.addf("SERVER_LISTEN: %q has no socket path", listen)
+		}
+		if mode, err := strconv.ParseUint(c.Server.SocketMode, 8, 32); err != nil || mode > 0777 {
+			v.addf("SERVER_SOCKET_MODE: %q is not an octal permission such as 0660", c.Server.SocketMode)
+		}
+	default:
+		v.addf("SERVER_LISTEN: %q is not unix:<path> or systemd", listen)
+	}
+	v.min("SERVER_READ_TIMEOUT", c.Server.ReadTimeout, 0)
+	v.min("SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout, 0)
+	v.min("SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout, 0)
+	v.min("IDEMPOTENCY_TTL_HOURS", c.Server.IdempotencyTTL, 1)
+	v.min("REQUEST_TIMEOUT_READ", c.Server.RequestTimeouts.Read, 0)
+	v.min("REQUEST_TIMEOUT_SEARCH", c.Server.RequestTimeouts.Search, 0)
+	v.min("REQUEST_TIMEOUT_WRITE", c.Server.RequestTimeouts.Write, 0)
+	v.min("REQUEST_TIMEOUT_ADMIN", c.Server.RequestTimeouts.Admin, 0)
+	v.min("LOAD_SHED_MAX_IN_FLIGHT", c.Server.LoadShedding.MaxInFlight, 0)
+	v.min("LOAD_SHED_RETRY_AFTER", c.Server.LoadShedding.RetryAfter, 0)
+	if tls := c.Server.TLS; tls.Enabled() {
+		if tls.CertFile != "" && len(tls.AutocertDomains) > 0 {
+			v.addf("TLS_CERT_FILE and TLS_AUTOCERT_DOMAINS cannot both be set")
+		}
+		if tls.CertFile != "" {
+			v.required("TLS_KEY_FILE", tls.KeyFile, "TLS_CERT_FILE")
+		}
+		v.oneOf("TLS_MIN_VERSION", tls.MinVersion, "1.2", "1.3")
+		if tls.RedirectPort != 0 {
+			v.port("TLS_REDIRECT_HTTP_PORT", tls.RedirectPort)
+			if tls.RedirectPort == c.Server.Port {
+				v.addf("TLS_REDIRECT_HTTP_PORT: %d is also SERVER_PORT", tls.RedirectPort)
+			}
+			if c.Server.Listen != "" {
+				v.addf("TLS_REDIRECT_HTTP_PORT redirects to SERVER_PORT, which SERVER_LISTEN replaces")
+			}
+		}
+	} else {
+		if c.Server.TLS.KeyFile != "" {
+			v.required("TLS_CERT_FILE", "", "TLS_KEY_FILE")
+		}
+		if c.Server.TLS.RedirectPort != 0 {
+			v.addf("TLS_REDIRECT_HTTP_PORT needs TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS")
+		}
+	}
+	if management := c.Server.Management; management.Enabled() {
+		v.port("MANAGEMENT_PORT", management.Port)
+		if management.Port == c.Server.Port || management.Port == c.Server.TLS.RedirectPort {
+			v.addf("MANAGEMENT_PORT: %d is also SERVER_PORT or TLS_REDIRECT_HTTP_PORT", management.Port)
+		}
+	} else if management.Pprof {
+		v.addf("MANAGEMENT_PPROF needs MANAGEMENT_PORT")
+	}
+
+	v.oneOf("DB_DRIVER", c.Database.Driver, "postgres", "embedded-postgres")
+	v.port("DB_PORT", c.Database.Port)
+	v.oneOf("DB_SSL_MODE", c.Database.SSLMode, "disable", "require", "verify-ca", "verify-full")
+	v.min("DB_STATEMENT_TIMEOUT_MS", c.Database.StatementTimeout, 0)
+
+	v.min("JWT_EXPIRATION", c.JWT.Expiration, 1)
+
+	v.min("RETENTION_INTERVAL_HOURS", c.Retention.IntervalHours, 1)
+	v.min("RETENTION_BATCH_SIZE", c.Retention.BatchSize, 1)
+	v.min("RETENTION_AUDIT_LOG_DAYS", c.Retention.AuditLogDays, 1)
+	v.min("RETENTION_DELETED_RESOURCE_DAYS", c.Retention.DeletedResourceDays, 1)
+
+	v.oneOf("OBJECT_STORE_BACKEND", c.ObjectStore.Backend, "filesystem", "s3", "gcs")
+	if c.ObjectStore.Backend == "s3" || c.ObjectStore.Backend == "gcs" {
+		v.required("OBJECT_STORE_BUCKET", c.ObjectStore.Bucket, "OBJECT_STORE_BACKEND="+c.ObjectStore.Backend)
+	}
+
+	v.min("WORKER_COUNT", c.Worker.Workers, 0)
+	v.oneOf("WORKER_QUEUE_BACKEND", c.Worker.QueueBackend, "memory", "redis")
+	v.min("WORKER_QUEUE_SIZE", c.Worker.QueueSize, 1)
+	v.min("WORKER_DRAIN_TIMEOUT", c.Worker.DrainTimeout, 0)
+	v.min("WORKER_LEASE_TIMEOUT", c.Worker.LeaseTimeout, 1)
+	v.min("JOB_HISTORY_DAYS", c.Worker.HistoryDays, 1)
+	v.min("SCHEDULER_POLL_INTERVAL", c.Scheduler.PollInterval, 1)
+
+	v.min("RATE_LIMIT_ANONYMOUS_PER_MINUTE", c.RateLimit.AnonymousPerMinute, 1)
+	v.min("RATE_LIMIT_ANONYMOUS_BURST", c.RateLimit.AnonymousBurst, 1)
+	v.min("RATE_LIMIT_PER_MINUTE", c.RateLimit.PerMinute, 1)
+	v.min("RATE_LIMIT_BURST", c.RateLimit.Burst, 1)
+	v.min("CORS_MAX_AGE", c.CORS.MaxAge, 0)
+
+	if c.HL7.MLLPEnabled {
+		v.port("HL7_MLLP_PORT", c.HL7.MLLPPort)
+		v.required("HL7_MLLP_TENANT", c.HL7.MLLPTenant, "HL7_MLLP_ENABLED=true")
+	}
+
+	v.oneOf("EVENTS_TRANSPORT", c.Events.Transport, "none", "nats")
+	v.oneOf("SEARCH_BACKEND", c.Search.Backend, "postgres", "elasticsearch")
+
+	v.oneOf("EMAIL_PROVIDER", c.Notifications.EmailProvider, "none", "smtp")
+	if c.Notifications.EmailProvider == "smtp" {
+		v.port("SMTP_PORT", c.Notifications.SMTP.Port)
+		v.oneOf("SMTP_TLS", c.Notifications.SMTP.TLS, "starttls", "tls", "none")
+		v.required("SMTP_FROM", c.Notifications.SMTP.From, "EMAIL_PROVIDER=smtp")
+	}
+	v.oneOf("SMS_PROVIDER", c.Notifications.SMSProvider, "none", "twilio")
+	if c.Notifications.SMSProvider == "twilio" {
+		v.required("TWILIO_ACCOUNT_SID", c.Notifications.Twilio.AccountSID, "SMS_PROVIDER=twilio")
+		v.required("TWILIO_AUTH_TOKEN", c.Notifications.Twilio.AuthToken, "SMS_PROVIDER=twilio")
+		if c.Notifications.Twilio.From == "" && c.Notifications.Twilio.MessagingServiceSID == "" {
+			v.addf("TWILIO_FROM or TWILIO_MESSAGING_SERVICE_SID is required with SMS_PROVIDER=twilio")
+		}
+	}
+
+	v.oneOf("SNOMED_VALUE_SET_SEVERITY", c.Terminology.SNOMEDValueSetSeverity, "warning", "error")
+
+	v.oneOf("EMPI_PROVIDER", c.EMPI.Provider, "none", "fhir", "pixm")
+	if c.EMPI.Provider == "fhir" || c.EMPI.Provider == "pixm" {
+		v.required("EMPI_URL", c.EMPI.URL, "EMPI_PROVIDER="+c.EMPI.Provider)
+		v.required("EMPI_ENTERPRISE_SYSTEM", c.EMPI.EnterpriseSystem, "EMPI_PROVIDER="+c.EMPI.Provider)
+	}
+	if c.EMPI.Provider == "fhir" && (c.EMPI.MinScore <= 0 || c.EMPI.MinScore > 1) {
+		v.addf("EMPI_MIN_SCORE: %g is not above 0 and at most 1", c.EMPI.MinScore)
+	}
+	if c.EMPI.Provider == "pixm" {
+		v.required("EMPI_SOURCE_SYSTEM", c.EMPI.SourceSystem, "EMPI_PROVIDER=pixm")
+	}
+	if c.Duplicates.MinScore <= 0 || c.Duplicates.MinScore > 1 {
+		v.addf("DUPLICATE_MIN_SCORE: %g is not above 0 and at most 1", c.Duplicates.MinScore)
+	}
+	v.min("STATS_CACHE_TTL", c.Stats.CacheTTL, 0)
+
+	if c.Federation.UpstreamURL != "" {
+		v.oneOf("FEDERATION_AUTH", c.Federation.Auth, "none", "bearer", "client_credentials")
+		switch c.Federation.Auth {
+		case "bearer":
+			v.required("FEDERATION_TOKEN", c.Federation.Token, "FEDERATION_AUTH=bearer")
+		case "client_credentials":
+			v.required("FEDERATION_TOKEN_URL", c.Federation.TokenURL, "FEDERATION_AUTH=client_credentials")
+			v.required("FEDERATION_CLIENT_ID", c.Federation.ClientID, "FEDERATION_AUTH=client_credentials")
+			v.required("FEDERATION_CLIENT_SECRET", c.Federation.ClientSecret, "FEDERATION_AUTH=client_credentials")
+		}
+	}
+
+	v.oneOf("SIEM_TRANSPORT", c.SIEM.Transport, "none", "syslog", "https")
+	switch c.SIEM.Transport {
+	case "syslog":
+		v.required("SIEM_SYSLOG_ADDRESS", c.SIEM.SyslogAddress, "SIEM_TRANSPORT=syslog")
+		v.oneOf("SIEM_SYSLOG_NETWORK", c.SIEM.SyslogNetwork, "udp", "tcp", "tls")
+	case "https":
+		v.required("SIEM_HTTPS_URL", c.SIEM.HTTPSURL, "SIEM_TRANSPORT=https")
+	}
+
+	v.min("MAINTENANCE_RETRY_AFTER", c.Maintenance.RetryAfter, 1)
+	v.min("MAINTENANCE_POLL_INTERVAL", c.Maintenance.PollInterval, 1)
+	v.min("SHUTDOWN_DELAY", c.Shutdown.Delay, 0)
+	v.min("SHUTDOWN_DRAIN_TIMEOUT", c.Shutdown.DrainTimeout, 1)
+	v.min("SHUTDOWN_CLOSE_TIMEOUT", c.Shutdown.CloseTimeout, 1)
+	v.min("SELFTEST_TIMEOUT", c.SelfTest.Timeout, 0)
+	v.min("SELFTEST_RETRY_INTERVAL", c.SelfTest.RetryInterval, 1)
+	for _, name := range c.SelfTest.DegradedAllowed {
+		// The checks of the selftest package
+		v.oneOf("SELFTEST_DEGRADED_ALLOWED", name, "migrations", "indexes", "jwt", "canary")
+	}
+
+	v.oneOf("LOG_BODY_MODE", c.RequestLog.BodyMode, "redacted", "strict")
+	for _, name := range c.RequestLog.ScrubPatterns {
+		// The patterns of the redact package
+		v.oneOf("LOG_SCRUB_PATTERNS", name, "ssn", "email", "phone", "none")
+	}
+	if c.RequestLog.SampleRate < 0 || c.RequestLog.SampleRate > 1 {
+		v.addf("LOG_SAMPLE_RATE: %g is not between 0 and 1", c.RequestLog.SampleRate)
+	}
+	v.min("LOG_SLOW_THRESHOLD", c.RequestLog.SlowThreshold, 1)
+
+	flags := make([]string, 0, len(c.Features))
+	for name := range c.Features {
+		flags = append(flags, name)
+	}
+	sort.Strings(flags)
+	for _, name := range flags {
+		v.oneOf("FEATURE_FLAGS", name, features.Names...)
+	}
+	if c.LogLevel < 0 || c.LogLevel > 6 {
+		v.addf("LOG_LEVEL: %d is not between 0 (panic) and 6 (trace)", c.LogLevel)
+	}
+
+	var insecure []string
+	if c.Environment == "production" {
+		insecure = c.insecureSettings()
+	}
+	if len(v.problems) > 0 || len(insecure) > 0 {
+		return &ValidationError{Problems: v.problems, Insecure: insecure}
+	}
+	return nil
+}
+
+// insecureSettings returns the settings that leave a deployment open to
+// anyone who knows the defaults or can watch its network, each saying why
+func (c *Config) insecureSettings() []string {
+	var insecure []string
+	if c.JWT.Secret == defaultJWTSecret {
+		insecure = append(insecure, fmt.Sprintf("JWT_SECRET is the default %q, so anyone can sign tokens", defaultJWTSecret))
+	}
+	// The embedded server is reached over loopback with a fixed password
+	if c.Database.Driver != "embedded-postgres" {
+		if c.Database.Password == "" {
+			insecure = append(insecure, "DB_PASSWORD is empty")
+		}
+		if c.Database.SSLMode == "disable" {
+			insecure = append(insecure, "DB_SSL_MODE is disable, so database traffic, patient data included, is sent unencrypted")
+		}
+	}
+	return insecure
+}
+
+// validator collects the problems of a configuration
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// oneOf checks that value is one of allowed
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf("%s: %q is not one of %s", key, value, strings.Join(allowed, ", "))
+}
+
+// required checks that value is set, as it must be with condition
+func (v *validator) required(key, value, condition string) {
+	if value == "" {
+		v.addf("%s is required with %s", key, condition)
+	}
+}
+
+func (v *validator) min(key string, value, min int) {
+	if value < min {
+		v.addf("%s: %d is less than %d", key, value, min)
+	}
+}
+
+func (v *validator) port(key string, value int) {
+	if value < 1 || value > 65535 {
+		v.addf("%s: %d is not a port between 1 and 65535", key, value)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sync"
 	"time"
 
 	"healthcare-api/internal/config"
@@ -13,6 +14,10 @@ import (
 
 type DB struct {
 	*sql.DB
+	replicas    []*replica
+	nextReplica uint64
+	done        chan struct{}
+	closeOnce   sync.Once
 }
 
 func NewConnection(cfg config.DatabaseConfig) (*DB, error) {
@@ -32,10 +37,29 @@ func NewConnection(cfg config.DatabaseConfig) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{db}, nil
+	// Open read replicas, if configured
+	replicas, err := openReplicas(cfg.ReplicaURLs)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	conn := &DB{DB: db, replicas: replicas, done: make(chan struct{})}
+	if len(replicas) > 0 {
+		go conn.monitorReplicas(15 * time.Second)
+	}
+
+	return conn, nil
 }
 
+// Close closes the primary and replica pools. It may be called more than once.
 func (db *DB) Close() error {
+	db.closeOnce.Do(func() {
+		close(db.done)
+		for _, r := range db.replicas {
+			r.db.Close()
+		}
+	})
 	return db.DB.Close()
 }
 
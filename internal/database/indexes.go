@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Statements of up migrations that create, drop or move indexes
+var (
+	sqlComment  = regexp.MustCompile(`--[^\n]*`)
+	createIndex = regexp.MustCompile(`(?i)\bCREATE\s+(?:UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(\w+)\s+ON\s+(?:ONLY\s+)?(\w+)`)
+	dropIndex   = regexp.MustCompile(`(?i)\bDROP\s+INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+EXISTS\s+)?(\w+)`)
+	dropTable   = regexp.MustCompile(`(?i)\bDROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(\w+)`)
+	renameTable = regexp.MustCompile(`(?i)\bALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(\w+)\s+RENAME\s+TO\s+(\w+)`)
+)
+
+// ExpectedIndexes returns the names of the indexes that the up migrations in
+// path, up to and including version, leave in the schema. It follows their
+// CREATE INDEX, DROP INDEX, DROP TABLE and ALTER TABLE ... RENAME TO
+// statements; indexes created by dynamic SQL are not seen.
+func ExpectedIndexes(path string, version uint) ([]string, error) {
+	migrations, err := ListMigrations(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// index name -> table
+	indexes := make(map[string]string)
+	for _, m := range migrations {
+		if m.Version > version {
+			break
+		}
+		file := filepath.Join(path, fmt.Sprintf("%03d_%s.up.sql", m.Version, m.Name))
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration: %w", err)
+		}
+		script := sqlComment.ReplaceAllString(string(content), "")
+
+		// Statements apply in the order they appear in the file
+		type statement struct {
+			at    int
+			apply func()
+		}
+		var statements []statement
+		for _, match := range createIndex.FindAllStringSubmatchIndex(script, -1) {
+			name, table := strings.ToLower(script[match[2]:match[3]]), strings.ToLower(script[match[4]:match[5]])
+			statements = append(statements, statement{match[0], func() { indexes[name] = table }})
+		}
+		for _, match := range dropIndex.FindAllStringSubmatchIndex(script, -1) {
+			name := strings.ToLower(script[match[2]:match[3]])
+			statements = append(statements, statement{match[0], func() { delete(indexes, name) }})
+		}
+		for _, match := range dropTable.FindAllStringSubmatchIndex(script, -1) {
+			table := strings.ToLower(script[match[2]:match[3]])
+			statements = append(statements, statement{match[0], func() {
+				for name, on := range indexes {
+					if on == table {
+						delete(indexes, name)
+					}
+				}
+			}})
+		}
+		for _, match := range renameTable.FindAllStringSubmatchIndex(script, -1) {
+			from, to := strings.ToLower(script[match[2]:match[3]]), strings.ToLower(script[match[4]:match[5]])
+			statements = append(statements, statement{match[0], func() {
+				for name, on := range indexes {
+					if on == from {
+						indexes[name] = to
+					}
+				}
+			}})
+		}
+		sort.Slice(statements, func(i, j int) bool { return statements[i].at < statements[j].at })
+		for _, s := range statements {
+			s.apply()
+		}
+	}
+
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// MissingIndexes returns the indexes the applied migrations in path create
+// that do not exist in the current schema, e.g. because they were dropped by
+// hand
+func MissingIndexes(ctx context.Context, db *sql.DB, path string) ([]string, error) {
+	status, err := SchemaStatus(ctx, db, path)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := ExpectedIndexes(path, status.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT indexname FROM pg_indexes WHERE schemaname = current_schema()`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+
+	var missing []string
+	for _, name := range expected {
+		if !existing[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
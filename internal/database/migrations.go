@@ -1,7 +1,16 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -9,30 +18,245 @@ import (
 	_ "github.com/lib/pq"
 )
 
-func RunMigrations(databaseURL string) error {
+// MigrationsPath is the directory migrations are read from, relative to the working directory
+const MigrationsPath = "migrations"
+
+// migrationFile matches "NNN_name.up.sql" / "NNN_name.down.sql"
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a migration available on disk
+type Migration struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// MigrationStatus describes the schema version of a database against the migrations on disk
+type MigrationStatus struct {
+	// Current version, 0 when no migration has been applied
+	Version uint
+	// Dirty is set when a migration failed part-way; it must be fixed by hand and forced
+	Dirty      bool
+	Migrations []Migration
+}
+
+// Pending returns the migrations on disk that have not been applied
+func (s *MigrationStatus) Pending() []Migration {
+	var pending []Migration
+	for _, m := range s.Migrations {
+		if !m.Applied {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// Migrator applies and inspects schema migrations
+type Migrator struct {
+	db   *sql.DB
+	m    *migrate.Migrate
+	path string
+}
+
+// NewMigrator opens a dedicated connection for running migrations from path
+func NewMigrator(databaseURL, path string) (*Migrator, error) {
 	db, err := sql.Open("postgres", databaseURL)
 	if err != nil {
-		return fmt.Errorf("failed to open database for migrations: %w", err)
+		return nil, fmt.Errorf("failed to open database for migrations: %w", err)
 	}
-	defer db.Close()
 
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
-		return fmt.Errorf("failed to create postgres driver: %w", err)
+		db.Close()
+		return nil, fmt.Errorf("failed to create postgres driver: %w", err)
 	}
 
 	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
+		"file://"+filepath.ToSlash(path),
 		"postgres",
 		driver,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
+		db.Close()
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
 	}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+	return &Migrator{db: db, m: m, path: path}, nil
+}
+
+// Close releases the migration connection
+func (mg *Migrator) Close() error {
+	return mg.db.Close()
+}
+
+// Up applies pending migrations; steps <= 0 applies all of them
+func (mg *Migrator) Up(steps int) error {
+	var err error
+	if steps > 0 {
+		err = mg.m.Steps(steps)
+	} else {
+		err = mg.m.Up()
+	}
+	if err != nil && err != migrate.ErrNoChange {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
+	return nil
+}
+
+// Down rolls back steps migrations. Rolling back everything must be asked for explicitly.
+func (mg *Migrator) Down(steps int, all bool) error {
+	var err error
+	switch {
+	case all:
+		err = mg.m.Down()
+	case steps > 0:
+		err = mg.m.Steps(-steps)
+	default:
+		return fmt.Errorf("number of migrations to roll back is required")
+	}
+	if err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+	return nil
+}
+
+// Force sets the recorded version without running any migration and clears the dirty flag
+func (mg *Migrator) Force(version int) error {
+	if err := mg.m.Force(version); err != nil {
+		return fmt.Errorf("failed to force migration version: %w", err)
+	}
+	return nil
+}
 
+// Status reports the applied version and the migrations available on disk
+func (mg *Migrator) Status() (*MigrationStatus, error) {
+	version, dirty, err := mg.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("failed to read migration version: %w", err)
+	}
+
+	return newMigrationStatus(version, dirty, mg.path)
+}
+
+// CheckCurrent returns an error unless every migration on disk has been applied cleanly.
+// A database ahead of the code is accepted so migrations can be rolled out before a deploy.
+func (mg *Migrator) CheckCurrent() error {
+	status, err := mg.Status()
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+// Err returns an error when the schema is dirty or migrations are pending
+func (s *MigrationStatus) Err() error {
+	if s.Dirty {
+		return fmt.Errorf("database schema is dirty at version %d; fix it and run migrate force", s.Version)
+	}
+	if pending := s.Pending(); len(pending) > 0 {
+		return fmt.Errorf("database schema is at version %d, %d migration(s) pending; run migrate up", s.Version, len(pending))
+	}
 	return nil
 }
+
+// SchemaStatus reads the version recorded by the migrator through db, without
+// the dedicated connection of a Migrator, for checks that run often
+func SchemaStatus(ctx context.Context, db *sql.DB, path string) (*MigrationStatus, error) {
+	var version uint
+	var dirty bool
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	return newMigrationStatus(version, dirty, path)
+}
+
+func newMigrationStatus(version uint, dirty bool, path string) (*MigrationStatus, error) {
+	available, err := ListMigrations(path)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MigrationStatus{Version: version, Dirty: dirty}
+	for _, m := range available {
+		m.Applied = m.Version <= version && !(dirty && m.Version == version)
+		status.Migrations = append(status.Migrations, m)
+	}
+
+	return status, nil
+}
+
+// RunMigrations applies all pending migrations
+func RunMigrations(databaseURL string) error {
+	mg, err := NewMigrator(databaseURL, MigrationsPath)
+	if err != nil {
+		return err
+	}
+	defer mg.Close()
+
+	return mg.Up(0)
+}
+
+// CheckMigrations verifies the schema is up to date without changing it
+func CheckMigrations(databaseURL string) error {
+	mg, err := NewMigrator(databaseURL, MigrationsPath)
+	if err != nil {
+		return err
+	}
+	defer mg.Close()
+
+	return mg.CheckCurrent()
+}
+
+// ListMigrations returns the migrations in path, ordered by version
+func ListMigrations(path string) ([]Migration, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil || match[3] != "up" {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: uint(version), Name: match[2]})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// CreateMigration writes an empty up/down pair numbered after the latest migration in path
+func CreateMigration(path, name string) (string, string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", fmt.Errorf("migration name is required")
+	}
+
+	existing, err := ListMigrations(path)
+	if err != nil {
+		return "", "", err
+	}
+	var next uint = 1
+	if len(existing) > 0 {
+		next = existing[len(existing)-1].Version + 1
+	}
+
+	base := filepath.Join(path, fmt.Sprintf("%03d_%s", next, name))
+	up, down := base+".up.sql", base+".down.sql"
+	for _, file := range []string{up, down} {
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create migration file: %w", err)
+		}
+		f.Close()
+	}
+
+	return up, down, nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// auditLogPartitionsAhead is the number of future monthly partitions kept ready
+const auditLogPartitionsAhead = 3
+
+// EnsureAuditLogPartitions creates the monthly audit_logs partitions for the
+// current month and the given number of months ahead
+func (db *DB) EnsureAuditLogPartitions(ctx context.Context, monthsAhead int) error {
+	if _, err := db.ExecContext(ctx, "SELECT ensure_audit_log_partitions($1)", monthsAhead); err != nil {
+		return fmt.Errorf("failed to create audit log partitions: %w", err)
+	}
+	return nil
+}
+
+// DetachAuditLogPartitions detaches monthly audit_logs partitions that end on
+// or before cutoff. Detached tables are left in place for archival and returned by name.
+func (db *DB) DetachAuditLogPartitions(ctx context.Context, cutoff time.Time) ([]string, error) {
+	query := `
+		SELECT child.relname
+		FROM pg_inherits
+		JOIN pg_class parent ON parent.oid = pg_inherits.inhparent
+		JOIN pg_class child ON child.oid = pg_inherits.inhrelid
+		WHERE parent.relname = 'audit_logs'
+			AND child.relname ~ '^audit_logs_[0-9]{4}_[0-9]{2}$'
+		ORDER BY child.relname
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log partitions: %w", err)
+	}
+
+	var expired []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan audit log partition: %w", err)
+		}
+
+		month, err := time.Parse("audit_logs_2006_01", name)
+		if err != nil {
+			continue
+		}
+		if !month.AddDate(0, 1, 0).After(cutoff) {
+			expired = append(expired, name)
+		}
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit log partitions: %w", err)
+	}
+
+	var detached []string
+	for _, name := range expired {
+		stmt := fmt.Sprintf("ALTER TABLE audit_logs DETACH PARTITION %s", pq.QuoteIdentifier(name))
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return detached, fmt.Errorf("failed to detach audit log partition %s: %w", name, err)
+		}
+		detached = append(detached, name)
+	}
+
+	return detached, nil
+}
+
+// maintainAuditLogPartitions keeps future audit_logs partitions created ahead of time
+func (db *DB) maintainAuditLogPartitions(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := db.EnsureAuditLogPartitions(ctx, auditLogPartitionsAhead); err != nil {
+				fmt.Printf("Failed to maintain audit log partitions: %v\n", err)
+			}
+			cancel()
+		case <-db.done:
+			return
+		}
+	}
+}
+
+// StartPartitionMaintenance creates any missing audit_logs partitions and keeps
+// them created ahead of time in the background. It must run after migrations.
+func (db *DB) StartPartitionMaintenance(ctx context.Context) error {
+	if err := db.EnsureAuditLogPartitions(ctx, auditLogPartitionsAhead); err != nil {
+		return err
+	}
+	go db.maintainAuditLogPartitions(24 * time.Hour)
+	return nil
+}
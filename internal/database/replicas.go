@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Querier is the subset of *sql.DB used for read queries
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// replica is a read-only connection pool with health tracking
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+type primaryKey struct{}
+
+// WithPrimary pins all reads made with the returned context to the primary,
+// so a request that writes can read its own writes
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// usesPrimary reports whether ctx has been pinned to the primary
+func usesPrimary(ctx context.Context) bool {
+	pinned, _ := ctx.Value(primaryKey{}).(bool)
+	return pinned
+}
+
+// openReplicas opens connection pools for the configured read replicas.
+// Replicas that fail the initial ping are kept but marked unhealthy.
+func openReplicas(urls []string) ([]*replica, error) {
+	var replicas []*replica
+	for _, url := range urls {
+		db, err := sql.Open("postgres", url)
+		if err != nil {
+			for _, r := range replicas {
+				r.db.Close()
+			}
+			return nil, fmt.Errorf("failed to open read replica connection: %w", err)
+		}
+
+		db.SetMaxOpenConns(100)
+		db.SetMaxIdleConns(25)
+		db.SetConnMaxLifetime(10 * time.Minute)
+		db.SetConnMaxIdleTime(2 * time.Minute)
+
+		r := &replica{db: db}
+		r.healthy.Store(db.Ping() == nil)
+		replicas = append(replicas, r)
+	}
+	return replicas, nil
+}
+
+// Reader returns a connection pool for read-only queries: a healthy replica
+// chosen round-robin, or the primary when the context is pinned or no replica
+// is healthy. A query that fails on the replica with a connection error is
+// retried on the primary, and the replica is marked unhealthy until the next
+// health check finds it reachable.
+func (db *DB) Reader(ctx context.Context) Querier {
+	if len(db.replicas) == 0 || usesPrimary(ctx) {
+		return db.DB
+	}
+
+	start := atomic.AddUint64(&db.nextReplica, 1)
+	for i := 0; i < len(db.replicas); i++ {
+		r := db.replicas[(start+uint64(i))%uint64(len(db.replicas))]
+		if r.healthy.Load() {
+			return &replicaReader{replica: r, primary: db.DB}
+		}
+	}
+
+	return db.DB
+}
+
+// replicaReader runs queries on a replica, falling back to the primary when
+// the replica cannot be reached
+type replicaReader struct {
+	replica *replica
+	primary *sql.DB
+}
+
+func (r *replicaReader) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := r.replica.db.QueryContext(ctx, query, args...)
+	if r.fallBack(ctx, err) {
+		return r.primary.QueryContext(ctx, query, args...)
+	}
+	return rows, err
+}
+
+// QueryRowContext checks the row's query error, which sql.Row otherwise only
+// reports on Scan, so the query can still be retried on the primary
+func (r *replicaReader) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := r.replica.db.QueryRowContext(ctx, query, args...)
+	if r.fallBack(ctx, row.Err()) {
+		return r.primary.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
+
+func (r *replicaReader) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := r.replica.db.ExecContext(ctx, query, args...)
+	if r.fallBack(ctx, err) {
+		return r.primary.ExecContext(ctx, query, args...)
+	}
+	return result, err
+}
+
+// fallBack reports whether a query that failed with err should be retried on
+// the primary, marking the replica unhealthy if so
+func (r *replicaReader) fallBack(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || !IsConnectionError(err) {
+		return false
+	}
+	r.replica.healthy.Store(false)
+	return true
+}
+
+// IsConnectionError reports whether err means the server could not be reached
+// or dropped the connection, rather than rejecting the query: a network error,
+// a broken connection, or a PostgreSQL connection exception (class 08) or
+// shutdown (57P01-57P03)
+func IsConnectionError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := string(pqErr.Code)
+		return strings.HasPrefix(code, "08") || code == "57P01" || code == "57P02" || code == "57P03"
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr)
+}
+
+// monitorReplicas periodically pings replicas and updates their health
+func (db *DB) monitorReplicas(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, r := range db.replicas {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				r.healthy.Store(r.db.PingContext(ctx) == nil)
+				cancel()
+			}
+		case <-db.done:
+			return
+		}
+	}
+}
+
+// ReplicaStats returns the number of configured and healthy replicas
+func (db *DB) ReplicaStats() (configured, healthy int) {
+	for _, r := range db.replicas {
+		if r.healthy.Load() {
+			healthy++
+		}
+	}
+	return len(db.replicas), healthy
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// stubDriver serves a single row naming the server of its DSN, or fails to
+// connect when the DSN is "down"
+type stubDriver struct{}
+
+func (stubDriver) Open(dsn string) (driver.Conn, error) {
+	if dsn == "down" {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	return stubConn(dsn), nil
+}
+
+type stubConn string
+
+func (c stubConn) Prepare(string) (driver.Stmt, error) { return stubStmt(c), nil }
+func (stubConn) Close() error                          { return nil }
+func (stubConn) Begin() (driver.Tx, error)             { return nil, errors.New("not supported") }
+
+type stubStmt string
+
+func (stubStmt) Close() error                               { return nil }
+func (stubStmt) NumInput() int                              { return -1 }
+func (stubStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+func (s stubStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &stubRows{server: string(s)}, nil
+}
+
+type stubRows struct {
+	server string
+	done   bool
+}
+
+func (*stubRows) Columns() []string { return []string{"server"} }
+func (*stubRows) Close() error      { return nil }
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.server
+	return nil
+}
+
+func init() {
+	sql.Register("stub", stubDriver{})
+}
+
+func openStub(t *testing.T, dsn string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("stub", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newStubDB(t *testing.T, replicaDSN string) *DB {
+	r := &replica{db: openStub(t, replicaDSN)}
+	r.healthy.Store(true)
+	return &DB{DB: openStub(t, "primary"), replicas: []*replica{r}, done: make(chan struct{})}
+}
+
+func TestReaderFallsBackToPrimary(t *testing.T) {
+	tests := []struct {
+		name        string
+		replicaDSN  string
+		wantServer  string
+		wantHealthy bool
+	}{
+		{"reachable replica", "replica", "replica", true},
+		{"unreachable replica", "down", "primary", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			db := newStubDB(t, tt.replicaDSN)
+			var server string
+			if err := db.Reader(ctx).QueryRowContext(ctx, "SELECT server").Scan(&server); err != nil {
+				t.Fatalf("QueryRowContext: %v", err)
+			}
+			if server != tt.wantServer {
+				t.Errorf("QueryRowContext ran on %s, want %s", server, tt.wantServer)
+			}
+			if healthy := db.replicas[0].healthy.Load(); healthy != tt.wantHealthy {
+				t.Errorf("replica healthy = %v, want %v", healthy, tt.wantHealthy)
+			}
+
+			db = newStubDB(t, tt.replicaDSN)
+			rows, err := db.Reader(ctx).QueryContext(ctx, "SELECT server")
+			if err != nil {
+				t.Fatalf("QueryContext: %v", err)
+			}
+			defer rows.Close()
+			if !rows.Next() {
+				t.Fatal("QueryContext returned no rows")
+			}
+			if err := rows.Scan(&server); err != nil {
+				t.Fatal(err)
+			}
+			if server != tt.wantServer {
+				t.Errorf("QueryContext ran on %s, want %s", server, tt.wantServer)
+			}
+		})
+	}
+}
+
+func TestReaderUsesPrimaryWhenPinned(t *testing.T) {
+	db := newStubDB(t, "replica")
+	ctx := WithPrimary(context.Background())
+
+	var server string
+	if err := db.Reader(ctx).QueryRowContext(ctx, "SELECT server").Scan(&server); err != nil {
+		t.Fatal(err)
+	}
+	if server != "primary" {
+		t.Errorf("pinned read ran on %s, want primary", server)
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("query: %w", io.ErrUnexpectedEOF), true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{&pq.Error{Code: "08006"}, true},
+		{&pq.Error{Code: "57P01"}, true},
+		{&pq.Error{Code: "42P01"}, false},
+		{&pq.Error{Code: "40001"}, false},
+		{sql.ErrNoRows, false},
+		{context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := IsConnectionError(tt.err); got != tt.want {
+			t.Errorf("IsConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	db := newStubDB(t, "replica")
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
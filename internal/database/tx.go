@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Retry policy for serialization failures and deadlocks
+const (
+	maxTxAttempts  = 5
+	baseTxBackoff  = 20 * time.Millisecond
+	maxTxBackoff   = time.Second
+	minStmtTimeout = 10 * time.Millisecond
+)
+
+// RunInTx runs fn in a transaction. When ctx has a deadline, the remaining time
+// is applied as the transaction's statement_timeout so the server abandons work
+// the caller will no longer wait for. Transactions failing with a serialization
+// failure (40001) or deadlock (40P01) are retried with capped, jittered backoff;
+// fn must therefore be safe to run more than once.
+func (db *DB) RunInTx(ctx context.Context, opts *sql.TxOptions, fn func(*sql.Tx) error) error {
+	var err error
+	for attempt := 0; attempt < maxTxAttempts; attempt++ {
+		if attempt > 0 {
+			if waitErr := sleepBackoff(ctx, attempt); waitErr != nil {
+				return err
+			}
+		}
+
+		err = db.runTxOnce(ctx, opts, fn)
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("transaction failed after %d attempts: %w", maxTxAttempts, err)
+}
+
+// runTxOnce executes a single transaction attempt
+func (db *DB) runTxOnce(ctx context.Context, opts *sql.TxOptions, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if timeout, ok := statementTimeout(ctx); ok {
+		// SET does not accept bind parameters; the value is an integer we formatted ourselves
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+			return fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// statementTimeout returns the time left before the ctx deadline, if any
+func statementTimeout(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+
+	remaining := time.Until(deadline)
+	if remaining < minStmtTimeout {
+		remaining = minStmtTimeout
+	}
+	return remaining, true
+}
+
+// IsRetryable reports whether err is a serialization failure or deadlock
+func IsRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+// sleepBackoff waits for the jittered exponential backoff of the given attempt
+func sleepBackoff(ctx context.Context, attempt int) error {
+	backoff := baseTxBackoff << uint(attempt-1)
+	if backoff > maxTxBackoff {
+		backoff = maxTxBackoff
+	}
+	backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,234 @@
+// Package dedup scores how likely two patient records are to describe the same
+// person, from the similarity of their names, birth dates and identifiers. It
+// is deterministic and runs in memory: callers pick the pairs worth comparing,
+// such as patients sharing a birth date or an identifier value.
+package dedup
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"healthcare-api/internal/models"
+)
+
+// Reasons a pair scored, as recorded with a candidate
+const (
+	// ReasonName: a name is the same, ignoring case, spaces and punctuation
+	ReasonName = "name"
+	// ReasonSimilarName: a name is close, such as a misspelling or swapped
+	// given and family names
+	ReasonSimilarName = "similar-name"
+	// ReasonBirthDate: the birth dates are the same
+	ReasonBirthDate = "birth-date"
+	// ReasonSimilarBirthDate: the birth dates differ by a digit, two adjacent
+	// digits swapped, or the day and month swapped
+	ReasonSimilarBirthDate = "similar-birth-date"
+	// ReasonIdentifier: an identifier value is shared, whatever its system
+	ReasonIdentifier = "identifier"
+)
+
+// Weights of each kind of evidence in a score, summing to 1
+const (
+	nameWeight       = 0.45
+	birthDateWeight  = 0.35
+	identifierWeight = 0.2
+	// genderMismatch scales the score of patients of known, different genders
+	genderMismatch = 0.8
+	// similarName is the lowest name similarity reported as ReasonSimilarName
+	similarName = 0.85
+)
+
+// Match is the outcome of comparing two patients
+type Match struct {
+	// Score, from 0 to 1, is how likely the patients are the same person
+	Score   float64
+	Reasons []string
+}
+
+// Compare scores two patients. Names are compared pairwise, family to family
+// and first given to first given, with Jaro-Winkler similarity; a similar birth
+// date scores half as much as the same one; and patients of known, different
+// genders score a fifth less.
+func Compare(a, b *models.Patient) Match {
+	var match Match
+
+	name := nameSimilarity(a.Name, b.Name)
+	switch {
+	case name == 1:
+		match.Reasons = append(match.Reasons, ReasonName)
+	case name >= similarName:
+		match.Reasons = append(match.Reasons, ReasonSimilarName)
+	}
+
+	birthDate := 0.0
+	if a.BirthDate != nil && b.BirthDate != nil {
+		x, y := a.BirthDate.Format("20060102"), b.BirthDate.Format("20060102")
+		switch {
+		case x == y:
+			birthDate = 1
+			match.Reasons = append(match.Reasons, ReasonBirthDate)
+		case similarDates(x, y):
+			birthDate = 0.5
+			match.Reasons = append(match.Reasons, ReasonSimilarBirthDate)
+		}
+	}
+
+	identifier := 0.0
+	if sharedIdentifier(a.Identifier, b.Identifier) {
+		identifier = 1
+		match.Reasons = append(match.Reasons, ReasonIdentifier)
+	}
+
+	match.Score = nameWeight*name + birthDateWeight*birthDate + identifierWeight*identifier
+	if knownGender(a.Gender) && knownGender(b.Gender) && *a.Gender != *b.Gender {
+		match.Score *= genderMismatch
+	}
+	match.Score = math.Round(match.Score*1000) / 1000
+	return match
+}
+
+// nameSimilarity returns the similarity of the closest pair of names, each
+// averaging its family and first given name, also compared swapped
+func nameSimilarity(a, b []models.HumanName) float64 {
+	best := 0.0
+	for _, x := range a {
+		xFamily, xGiven := nameParts(x)
+		for _, y := range b {
+			yFamily, yGiven := nameParts(y)
+			best = math.Max(best, partsSimilarity(xFamily, xGiven, yFamily, yGiven))
+			best = math.Max(best, partsSimilarity(xFamily, xGiven, yGiven, yFamily))
+		}
+	}
+	return best
+}
+
+func partsSimilarity(xFamily, xGiven, yFamily, yGiven string) float64 {
+	if xFamily == "" || yFamily == "" {
+		return 0
+	}
+	family := jaroWinkler(xFamily, yFamily)
+	if xGiven == "" || yGiven == "" {
+		// Without given names, a family name alone is weak evidence
+		return family / 2
+	}
+	return (family + jaroWinkler(xGiven, yGiven)) / 2
+}
+
+// nameParts returns a name's family and first given name, normalized
+func nameParts(name models.HumanName) (family, given string) {
+	if name.Family != nil {
+		family = normalize(*name.Family)
+	}
+	if len(name.Given) > 0 {
+		given = normalize(name.Given[0])
+	}
+	return family, given
+}
+
+// normalize lowercases s and keeps only its letters and digits
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// similarDates reports whether two dates, formatted YYYYMMDD, differ by one
+// digit, by two adjacent digits swapped, or by the day and month swapped
+func similarDates(x, y string) bool {
+	if x[:4] == y[:4] && x[4:6] == y[6:8] && x[6:8] == y[4:6] {
+		return true
+	}
+	var diffs []int
+	for i := range x {
+		if x[i] != y[i] {
+			diffs = append(diffs, i)
+		}
+	}
+	switch len(diffs) {
+	case 1:
+		return true
+	case 2:
+		i, j := diffs[0], diffs[1]
+		return j == i+1 && x[i] == y[j] && x[j] == y[i]
+	}
+	return false
+}
+
+func sharedIdentifier(a, b []models.Identifier) bool {
+	values := map[string]bool{}
+	for _, identifier := range a {
+		if identifier.Value != nil {
+			if value := normalize(*identifier.Value); value != "" {
+				values[value] = true
+			}
+		}
+	}
+	for _, identifier := range b {
+		if identifier.Value != nil && values[normalize(*identifier.Value)] {
+			return true
+		}
+	}
+	return false
+}
+
+func knownGender(gender *string) bool {
+	return gender != nil && (*gender == "male" || *gender == "female")
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of two strings, from 0 to 1
+func jaroWinkler(a, b string) float64 {
+	x, y := []rune(a), []rune(b)
+	if len(x) == 0 || len(y) == 0 {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	window := max(len(x), len(y))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	xMatched := make([]bool, len(x))
+	yMatched := make([]bool, len(y))
+	matches := 0
+	for i := range x {
+		for j := max(0, i-window); j < min(len(y), i+window+1); j++ {
+			if !yMatched[j] && x[i] == y[j] {
+				xMatched[i], yMatched[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, j := 0, 0
+	for i := range x {
+		if !xMatched[i] {
+			continue
+		}
+		for !yMatched[j] {
+			j++
+		}
+		if x[i] != y[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(x)) + m/float64(len(y)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(x), len(y)) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
@@ -0,0 +1,318 @@
+// Package deidentify transforms patients and observations for release outside
+// the covered entity, following the HIPAA Safe Harbor method: the identifiers
+// it lists are dropped or replaced, dates are shifted and geography is reduced
+// to the state and the first three digits of a US ZIP code.
+//
+// Resource ids and references become pseudonyms derived from a key, so the
+// resources of one patient still refer to each other, and exports made with
+// the same key use the same pseudonyms. Dates are shifted by a whole number of
+// days fixed per patient, keeping the intervals between a patient's events.
+package deidentify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SafeHarbor is the name of the only de-identification profile
+const SafeHarbor = "safe-harbor"
+
+// maxShiftDays bounds the date shift either way
+const maxShiftDays = 365
+
+// maxAge is the oldest age whose birth year is kept; older patients lose their
+// birth date, since Safe Harbor aggregates ages over 89
+const maxAge = 89
+
+// pseudonymized is the security label of de-identified resources
+var pseudonymized = models.Coding{
+	System:  strPtr("http://terminology.hl7.org/CodeSystem/v3-ObservationValue"),
+	Code:    strPtr("PSEUDED"),
+	Display: strPtr("pseudonymized"),
+}
+
+// restrictedZIP3 are the three-digit ZIP code prefixes covering 20,000 people
+// or fewer, which Safe Harbor replaces with 000
+var restrictedZIP3 = map[string]bool{
+	"036": true, "059": true, "063": true, "102": true, "203": true, "556": true,
+	"692": true, "790": true, "821": true, "823": true, "830": true, "831": true,
+	"878": true, "879": true, "884": true, "890": true, "893": true,
+}
+
+// Deidentifier de-identifies the resources of one tenant. It is not modified
+// after New, so it is safe for concurrent use.
+type Deidentifier struct {
+	key      []byte
+	tenantID string
+	now      time.Time
+}
+
+// New returns a Deidentifier for profile, deriving pseudonyms and date shifts
+// from key and the tenant
+func New(profile string, key []byte, tenantID string) (*Deidentifier, error) {
+	if profile != SafeHarbor {
+		return nil, fmt.Errorf("unknown de-identification profile %q", profile)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("de-identification key is empty")
+	}
+	return &Deidentifier{key: key, tenantID: tenantID, now: time.Now().UTC()}, nil
+}
+
+// ValidProfile reports whether profile names a de-identification profile
+func ValidProfile(profile string) bool {
+	return profile == SafeHarbor
+}
+
+// Patient returns a de-identified copy of patient. Names, identifiers,
+// contact details, photos, contacts and care providers are dropped, addresses
+// keep only their state, country and, in the US, a three-digit ZIP code, and
+// the birth date keeps only its year.
+func (d *Deidentifier) Patient(patient *models.Patient) *models.Patient {
+	shift := d.shift("Patient/" + patient.ID.String())
+
+	out := &models.Patient{
+		Resource:             d.resource("Patient", patient.Resource, shift),
+		Active:               patient.Active,
+		Gender:               patient.Gender,
+		DeceasedBoolean:      patient.DeceasedBoolean,
+		DeceasedDateTime:     shiftTime(patient.DeceasedDateTime, shift),
+		MaritalStatus:        patient.MaritalStatus,
+		MultipleBirthBoolean: patient.MultipleBirthBoolean,
+		MultipleBirthInteger: patient.MultipleBirthInteger,
+		Communication:        patient.Communication,
+	}
+
+	if patient.BirthDate != nil && age(*patient.BirthDate, d.now) <= maxAge {
+		year := time.Date(patient.BirthDate.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		out.BirthDate = &year
+	}
+
+	for _, address := range patient.Address {
+		if generalized, ok := generalizeAddress(address); ok {
+			out.Address = append(out.Address, generalized)
+		}
+	}
+
+	for _, link := range patient.Link {
+		if other := d.reference(&link.Other); other != nil {
+			out.Link = append(out.Link, models.PatientLink{Other: *other, Type: link.Type})
+		}
+	}
+
+	return out
+}
+
+// Observation returns a de-identified copy of observation. Identifiers, notes
+// and free-text values are dropped, references become pseudonyms and dates are
+// shifted by those of the subject.
+func (d *Deidentifier) Observation(observation *models.Observation) *models.Observation {
+	var shift time.Duration
+	if observation.Subject.Reference != nil {
+		shift = d.shift(*observation.Subject.Reference)
+	}
+
+	out := &models.Observation{
+		Resource:             d.resource("Observation", observation.Resource, shift),
+		BasedOn:              d.references(observation.BasedOn),
+		PartOf:               d.references(observation.PartOf),
+		Status:               observation.Status,
+		Category:             observation.Category,
+		Code:                 observation.Code,
+		Focus:                d.references(observation.Focus),
+		Encounter:            d.reference(observation.Encounter),
+		EffectiveDateTime:    shiftTime(observation.EffectiveDateTime, shift),
+		EffectivePeriod:      shiftPeriod(observation.EffectivePeriod, shift),
+		EffectiveTiming:      shiftTiming(observation.EffectiveTiming, shift),
+		EffectiveInstant:     shiftTime(observation.EffectiveInstant, shift),
+		Issued:               shiftTime(observation.Issued, shift),
+		Performer:            d.references(observation.Performer),
+		ValueQuantity:        observation.ValueQuantity,
+		ValueCodeableConcept: observation.ValueCodeableConcept,
+		ValueBoolean:         observation.ValueBoolean,
+		ValueInteger:         observation.ValueInteger,
+		ValueRange:           observation.ValueRange,
+		ValueRatio:           observation.ValueRatio,
+		ValueSampledData:     observation.ValueSampledData,
+		ValueTime:            observation.ValueTime,
+		ValueDateTime:        shiftTime(observation.ValueDateTime, shift),
+		ValuePeriod:          shiftPeriod(observation.ValuePeriod, shift),
+		DataAbsentReason:     observation.DataAbsentReason,
+		Interpretation:       observation.Interpretation,
+		BodySite:             observation.BodySite,
+		Method:               observation.Method,
+		Specimen:             d.reference(observation.Specimen),
+		Device:               d.reference(observation.Device),
+		ReferenceRange:       observation.ReferenceRange,
+		HasMember:            d.references(observation.HasMember),
+		DerivedFrom:          d.references(observation.DerivedFrom),
+	}
+	if subject := d.reference(&observation.Subject); subject != nil {
+		out.Subject = *subject
+	}
+
+	for _, component := range observation.Component {
+		component.ValueString = nil
+		component.ValueDateTime = shiftTime(component.ValueDateTime, shift)
+		component.ValuePeriod = shiftPeriod(component.ValuePeriod, shift)
+		out.Component = append(out.Component, component)
+	}
+
+	return out
+}
+
+// Pseudonym returns the id standing for a resource's id in de-identified
+// resources
+func (d *Deidentifier) Pseudonym(resourceType, id string) uuid.UUID {
+	return uuid.NewHash(hmac.New(sha256.New, d.key), uuid.Nil, []byte(d.tenantID+"/"+resourceType+"/"+id), 5)
+}
+
+// resource de-identifies the elements every resource has. The narrative,
+// contained resources and extensions may hold anything, so they are dropped.
+func (d *Deidentifier) resource(resourceType string, resource models.Resource, shift time.Duration) models.Resource {
+	out := models.Resource{
+		ID:            d.Pseudonym(resourceType, resource.ID.String()),
+		ImplicitRules: resource.ImplicitRules,
+		Language:      resource.Language,
+		CreatedAt:     resource.CreatedAt.Add(shift),
+		UpdatedAt:     resource.UpdatedAt.Add(shift),
+		Version:       resource.Version,
+		DeletedAt:     shiftTime(resource.DeletedAt, shift),
+	}
+
+	meta := &models.Meta{Security: []models.Coding{pseudonymized}}
+	if resource.Meta != nil {
+		meta.VersionID = resource.Meta.VersionID
+		meta.LastUpdated = shiftTime(resource.Meta.LastUpdated, shift)
+		meta.Profile = resource.Meta.Profile
+		meta.Tag = resource.Meta.Tag
+		meta.Security = append(append([]models.Coding(nil), resource.Meta.Security...), pseudonymized)
+	}
+	out.Meta = meta
+
+	return out
+}
+
+// reference returns the pseudonymous copy of a relative reference, or nil for
+// a missing, absolute or contained one, which would identify its target
+func (d *Deidentifier) reference(reference *models.Reference) *models.Reference {
+	if reference == nil || reference.Reference == nil {
+		return nil
+	}
+	resourceType, id, ok := strings.Cut(*reference.Reference, "/")
+	if !ok || resourceType == "" || id == "" || strings.Contains(id, "/") {
+		return nil
+	}
+	pseudonym := resourceType + "/" + d.Pseudonym(resourceType, id).String()
+	return &models.Reference{Reference: &pseudonym, Type: reference.Type}
+}
+
+func (d *Deidentifier) references(references []models.Reference) []models.Reference {
+	var out []models.Reference
+	for i := range references {
+		if reference := d.reference(&references[i]); reference != nil {
+			out = append(out, *reference)
+		}
+	}
+	return out
+}
+
+// shift returns the date shift of the resources of a subject: a whole number
+// of days, at most maxShiftDays either way and never zero
+func (d *Deidentifier) shift(subject string) time.Duration {
+	mac := hmac.New(sha256.New, d.key)
+	mac.Write([]byte("shift/" + d.tenantID + "/" + subject))
+	days := int(binary.BigEndian.Uint32(mac.Sum(nil))%(2*maxShiftDays)) - maxShiftDays
+	if days >= 0 {
+		days++
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// generalizeAddress keeps the state and country of an address, and the first
+// three digits of a US ZIP code unless they cover too few people. Addresses
+// left with nothing are dropped.
+func generalizeAddress(address models.Address) (models.Address, bool) {
+	out := models.Address{
+		Use:     address.Use,
+		Type:    address.Type,
+		State:   address.State,
+		Country: address.Country,
+	}
+	if address.PostalCode != nil && usAddress(address) {
+		zip := strings.TrimSpace(*address.PostalCode)
+		if len(zip) >= 3 {
+			zip3 := zip[:3]
+			if restrictedZIP3[zip3] {
+				zip3 = "000"
+			}
+			out.PostalCode = &zip3
+		}
+	}
+	return out, out.State != nil || out.Country != nil || out.PostalCode != nil
+}
+
+// usAddress reports whether an address is in the US, assuming so without a country
+func usAddress(address models.Address) bool {
+	if address.Country == nil {
+		return true
+	}
+	switch strings.ToUpper(strings.TrimSpace(*address.Country)) {
+	case "", "US", "USA", "UNITED STATES", "UNITED STATES OF AMERICA":
+		return true
+	}
+	return false
+}
+
+// age returns the age in whole years on now of someone born on birthDate
+func age(birthDate, now time.Time) int {
+	years := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		years--
+	}
+	return years
+}
+
+func shiftTime(t *time.Time, shift time.Duration) *time.Time {
+	if t == nil {
+		return nil
+	}
+	shifted := t.Add(shift)
+	return &shifted
+}
+
+func shiftPeriod(period *models.Period, shift time.Duration) *models.Period {
+	if period == nil {
+		return nil
+	}
+	return &models.Period{Start: shiftTime(period.Start, shift), End: shiftTime(period.End, shift)}
+}
+
+func shiftTiming(timing *models.Timing, shift time.Duration) *models.Timing {
+	if timing == nil {
+		return nil
+	}
+	out := *timing
+	out.Event = make([]time.Time, len(timing.Event))
+	for i, event := range timing.Event {
+		out.Event[i] = event.Add(shift)
+	}
+	if timing.Repeat != nil {
+		repeat := *timing.Repeat
+		repeat.BoundsPeriod = shiftPeriod(timing.Repeat.BoundsPeriod, shift)
+		out.Repeat = &repeat
+	}
+	return &out
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,182 @@
+// Package dicom reads imaging study metadata in the DICOM JSON model (DICOM
+// PS3.18 Annex F), the encoding of DICOMweb metadata responses: an array of
+// datasets, each an object of attributes keyed by tag.
+package dicom
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of DICOM JSON
+const ContentType = "application/dicom+json"
+
+// MaxMetadataSize is the largest metadata document accepted, enough for the
+// instance metadata of a study of several thousand images
+const MaxMetadataSize = 16 << 20
+
+// Tags of the attributes read, in the eight hex digit form of DICOM JSON
+const (
+	TagSOPClassUID       = "00080016"
+	TagSOPInstanceUID    = "00080018"
+	TagStudyDate         = "00080020"
+	TagStudyTime         = "00080030"
+	TagAccessionNumber   = "00080050"
+	TagModality          = "00080060"
+	TagModalitiesInStudy = "00080061"
+	TagTimezoneOffset    = "00080201"
+	TagStudyDescription  = "00081030"
+	TagSeriesDescription = "0008103E"
+	TagRetrieveURL       = "00081190"
+	TagPatientID         = "00100020"
+	TagIssuerOfPatientID = "00100021"
+	TagStudyInstanceUID  = "0020000D"
+	TagSeriesInstanceUID = "0020000E"
+	TagSeriesNumber      = "00200011"
+	TagInstanceNumber    = "00200013"
+)
+
+// maxUIDLength is the longest a DICOM UID can be
+const maxUIDLength = 64
+
+// ErrInvalidMetadata is wrapped by errors for metadata that cannot be read
+var ErrInvalidMetadata = errors.New("invalid DICOM metadata")
+
+// Attribute is a DICOM JSON attribute. Bulk data is not read.
+type Attribute struct {
+	VR    string            `json:"vr"`
+	Value []json.RawMessage `json:"Value,omitempty"`
+}
+
+// Dataset is a DICOM JSON dataset, its attributes keyed by upper case tag
+type Dataset map[string]Attribute
+
+// Parse reads a DICOM JSON document: an array of datasets, or a single one
+func Parse(data []byte) ([]Dataset, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		data = append(append([]byte{'['}, data...), ']')
+	}
+
+	var raw []map[string]Attribute
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
+	datasets := make([]Dataset, len(raw))
+	for i, attributes := range raw {
+		dataset := make(Dataset, len(attributes))
+		for tag, attribute := range attributes {
+			dataset[strings.ToUpper(tag)] = attribute
+		}
+		datasets[i] = dataset
+	}
+	return datasets, nil
+}
+
+// String returns the first value of an attribute as a string, or "" when it
+// has none. A person name gives its alphabetic representation, and a number
+// its decimal form.
+func (d Dataset) String(tag string) string {
+	attribute, ok := d[tag]
+	if !ok || len(attribute.Value) == 0 {
+		return ""
+	}
+	value := attribute.Value[0]
+
+	if attribute.VR == "PN" {
+		var name map[string]string
+		if json.Unmarshal(value, &name) == nil {
+			return strings.TrimSpace(name["Alphabetic"])
+		}
+	}
+
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	var n json.Number
+	if json.Unmarshal(value, &n) == nil {
+		return n.String()
+	}
+	return ""
+}
+
+// Strings returns every string value of an attribute
+func (d Dataset) Strings(tag string) []string {
+	var values []string
+	for _, value := range d[tag].Value {
+		var s string
+		if json.Unmarshal(value, &s) == nil && strings.TrimSpace(s) != "" {
+			values = append(values, strings.TrimSpace(s))
+		}
+	}
+	return values
+}
+
+// Int returns the first value of an integer string attribute such as an
+// instance number, and whether it has a valid one
+func (d Dataset) Int(tag string) (int, bool) {
+	value := d.String(tag)
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	return n, err == nil
+}
+
+// StudyStarted returns when the study started, from its date and time. DICOM
+// times are local to the modality; they are taken to be UTC unless the dataset
+// gives a timezone offset.
+func (d Dataset) StudyStarted() (*time.Time, error) {
+	date := d.String(TagStudyDate)
+	if date == "" {
+		return nil, nil
+	}
+	clock := d.String(TagStudyTime)
+	// Fractional seconds and trailing components may be left out: HH, HHMM or HHMMSS.FFFFFF
+	if i := strings.IndexByte(clock, '.'); i >= 0 {
+		clock = clock[:i]
+	}
+	clock = (clock + "000000")[:6]
+
+	location := time.UTC
+	if offset := d.String(TagTimezoneOffset); offset != "" {
+		zone, err := time.Parse("-0700", offset)
+		if err != nil {
+			return nil, fmt.Errorf("%w: timezone offset %q is not &ZZXX", ErrInvalidMetadata, offset)
+		}
+		location = zone.Location()
+	}
+
+	started, err := time.ParseInLocation("20060102150405", date+clock, location)
+	if err != nil {
+		return nil, fmt.Errorf("%w: study date %q and time %q are not YYYYMMDD and HHMMSS", ErrInvalidMetadata, date, d.String(TagStudyTime))
+	}
+	started = started.UTC()
+	return &started, nil
+}
+
+// ValidUID reports whether uid is a well-formed DICOM UID: at most 64
+// characters of dot-separated numeric components without leading zeros
+func ValidUID(uid string) bool {
+	if uid == "" || len(uid) > maxUIDLength {
+		return false
+	}
+	for _, component := range strings.Split(uid, ".") {
+		if component == "" || (len(component) > 1 && component[0] == '0') {
+			return false
+		}
+		for _, c := range component {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
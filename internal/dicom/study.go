@@ -0,0 +1,179 @@
+package dicom
+
+import (
+	"fmt"
+	"time"
+)
+
+// Study is the metadata of an imaging study gathered from its datasets
+type Study struct {
+	UID               string
+	AccessionNumber   string
+	PatientID         string
+	IssuerOfPatientID string
+	Description       string
+	Started           *time.Time
+	// Modalities lists the modalities of the study's series, or those a
+	// study-level dataset gives
+	Modalities []string
+	// RetrieveURL is where a DICOMweb server serves the study, when a dataset says
+	RetrieveURL string
+	Series      []*Series
+}
+
+// Series is the metadata of a series of a study
+type Series struct {
+	UID         string
+	Number      *int
+	Modality    string
+	Description string
+	RetrieveURL string
+	Instances   []Instance
+}
+
+// Instance identifies an instance of a series, such as an image
+type Instance struct {
+	UID         string
+	SOPClassUID string
+	Number      *int
+}
+
+// Studies groups datasets into the studies they belong to, in the order first
+// seen. A dataset describes an instance when it has a SOP instance UID, a
+// series when it has only a series UID, and otherwise a study, so instance
+// metadata and study or series search results can both be read. Every dataset
+// needs a study UID, and attributes of a study given differently by two
+// datasets are an error.
+func Studies(datasets []Dataset) ([]*Study, error) {
+	var studies []*Study
+	byUID := map[string]*Study{}
+	series := map[string]*Series{}
+
+	for i, dataset := range datasets {
+		studyUID := dataset.String(TagStudyInstanceUID)
+		if !ValidUID(studyUID) {
+			return nil, fmt.Errorf("%w: dataset %d has no valid study instance UID", ErrInvalidMetadata, i)
+		}
+
+		study, ok := byUID[studyUID]
+		if !ok {
+			study = &Study{UID: studyUID}
+			byUID[studyUID] = study
+			studies = append(studies, study)
+		}
+		if err := study.merge(dataset); err != nil {
+			return nil, fmt.Errorf("dataset %d: %w", i, err)
+		}
+
+		seriesUID := dataset.String(TagSeriesInstanceUID)
+		if seriesUID == "" {
+			study.Modalities = appendNew(study.Modalities, dataset.Strings(TagModalitiesInStudy)...)
+			if url := dataset.String(TagRetrieveURL); url != "" {
+				study.RetrieveURL = url
+			}
+			continue
+		}
+		if !ValidUID(seriesUID) {
+			return nil, fmt.Errorf("%w: dataset %d has an invalid series instance UID %q", ErrInvalidMetadata, i, seriesUID)
+		}
+
+		s, ok := series[seriesUID]
+		if !ok {
+			s = &Series{UID: seriesUID}
+			series[seriesUID] = s
+			study.Series = append(study.Series, s)
+		}
+		if s.Modality == "" {
+			s.Modality = dataset.String(TagModality)
+		}
+		if s.Description == "" {
+			s.Description = dataset.String(TagSeriesDescription)
+		}
+		if s.Number == nil {
+			if n, ok := dataset.Int(TagSeriesNumber); ok {
+				s.Number = &n
+			}
+		}
+		study.Modalities = appendNew(study.Modalities, s.Modality)
+
+		instanceUID := dataset.String(TagSOPInstanceUID)
+		if instanceUID == "" {
+			if url := dataset.String(TagRetrieveURL); url != "" {
+				s.RetrieveURL = url
+			}
+			continue
+		}
+		if !ValidUID(instanceUID) {
+			return nil, fmt.Errorf("%w: dataset %d has an invalid SOP instance UID %q", ErrInvalidMetadata, i, instanceUID)
+		}
+		instance := Instance{UID: instanceUID, SOPClassUID: dataset.String(TagSOPClassUID)}
+		if n, ok := dataset.Int(TagInstanceNumber); ok {
+			instance.Number = &n
+		}
+		s.Instances = appendInstance(s.Instances, instance)
+	}
+	return studies, nil
+}
+
+// merge takes the study-level attributes of dataset, which must agree with
+// those already taken
+func (s *Study) merge(dataset Dataset) error {
+	for _, field := range []struct {
+		name  string
+		value *string
+		tag   string
+	}{
+		{"accession number", &s.AccessionNumber, TagAccessionNumber},
+		{"patient ID", &s.PatientID, TagPatientID},
+		{"issuer of patient ID", &s.IssuerOfPatientID, TagIssuerOfPatientID},
+		{"study description", &s.Description, TagStudyDescription},
+	} {
+		value := dataset.String(field.tag)
+		switch {
+		case value == "":
+		case *field.value == "":
+			*field.value = value
+		case *field.value != value:
+			return fmt.Errorf("%w: study %s has %s %q and %q", ErrInvalidMetadata, s.UID, field.name, *field.value, value)
+		}
+	}
+
+	if s.Started == nil {
+		started, err := dataset.StudyStarted()
+		if err != nil {
+			return err
+		}
+		s.Started = started
+	}
+	return nil
+}
+
+// appendNew appends the values not in values already, skipping empty ones
+func appendNew(values []string, more ...string) []string {
+	for _, value := range more {
+		if value == "" || contains(values, value) {
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// appendInstance appends instance unless the series already has it
+func appendInstance(instances []Instance, instance Instance) []Instance {
+	for _, existing := range instances {
+		if existing.UID == instance.UID {
+			return instances
+		}
+	}
+	return append(instances, instance)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+// Package empi looks patients up in an enterprise master patient index (EMPI),
+// which assigns each person one enterprise identifier across the systems of an
+// organisation.
+package empi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+)
+
+// EMPI providers
+const (
+	ProviderNone = "none"
+	// ProviderFHIR matches patients on demographics with the FHIR Patient/$match operation
+	ProviderFHIR = "fhir"
+	// ProviderPIXm cross-references a local identifier with the IHE PIXm
+	// Patient/$ihe-pix operation
+	ProviderPIXm = "pixm"
+)
+
+// ErrAmbiguousMatch is returned when the EMPI finds more than one person the
+// patient could be
+var ErrAmbiguousMatch = errors.New("EMPI returned more than one certain match")
+
+// Client looks up the enterprise identifier of patients
+type Client interface {
+	// Lookup returns the enterprise identifier the EMPI holds for patient, or ""
+	// when it knows no such person
+	Lookup(ctx context.Context, patient *models.Patient) (string, error)
+}
+
+// New returns the client of the configured provider, or nil when none is configured
+func New(cfg config.EMPIConfig) (Client, error) {
+	switch cfg.Provider {
+	case "", ProviderNone:
+		return nil, nil
+	case ProviderFHIR:
+		return NewMatchClient(cfg)
+	case ProviderPIXm:
+		return NewPIXmClient(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported EMPI provider: %s", cfg.Provider)
+	}
+}
+
+// Permanent reports whether a lookup failed in a way retrying cannot fix, such
+// as a request the EMPI rejects
+func Permanent(err error) bool {
+	if errors.Is(err, ErrAmbiguousMatch) {
+		return true
+	}
+	var empiErr *Error
+	if !errors.As(err, &empiErr) {
+		return false
+	}
+	switch empiErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return empiErr.StatusCode >= 400 && empiErr.StatusCode < 500
+}
+
+// Error is an error response of the EMPI, with the diagnostics of its
+// OperationOutcome when it sent one
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("EMPI responded %d", e.StatusCode)
+	}
+	return fmt.Sprintf("EMPI responded %d: %s", e.StatusCode, e.Message)
+}
+
+// connection holds what both providers need to reach the EMPI
+type connection struct {
+	baseURL          string
+	token            string
+	enterpriseSystem string
+	client           *http.Client
+}
+
+func newConnection(cfg config.EMPIConfig) (connection, error) {
+	base, err := url.Parse(cfg.URL)
+	if err != nil || (base.Scheme != "https" && base.Scheme != "http") || base.Host == "" {
+		return connection{}, fmt.Errorf("invalid EMPI URL %q", cfg.URL)
+	}
+	if cfg.EnterpriseSystem == "" {
+		return connection{}, errors.New("the EMPI enterprise identifier system is required")
+	}
+	if cfg.Timeout <= 0 {
+		return connection{}, fmt.Errorf("invalid EMPI timeout %d: must be positive", cfg.Timeout)
+	}
+	return connection{
+		baseURL:          cfg.URL,
+		token:            cfg.Token,
+		enterpriseSystem: cfg.EnterpriseSystem,
+		client:           &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+	}, nil
+}
+
+// do sends req with the FHIR headers and token, returning the response body of
+// a 2xx response. Other responses are returned as an *Error, so callers can
+// tell a 404 apart.
+func (c connection) do(req *http.Request) ([]byte, error) {
+	req.Header.Set("Accept", "application/fhir+json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach EMPI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read EMPI response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		empiErr := &Error{StatusCode: resp.StatusCode}
+		var outcome models.OperationOutcome
+		if json.Unmarshal(data, &outcome) == nil {
+			for _, issue := range outcome.Issue {
+				if issue.Diagnostics != nil {
+					empiErr.Message = *issue.Diagnostics
+					break
+				}
+			}
+		}
+		return nil, empiErr
+	}
+	return data, nil
+}
+
+// EnterpriseID returns the value of the first identifier of patient in system, or ""
+func EnterpriseID(patient *models.Patient, system string) string {
+	for _, identifier := range patient.Identifier {
+		if identifier.System != nil && *identifier.System == system && identifier.Value != nil {
+			return *identifier.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package empi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+)
+
+// matchGradeExtension grades a $match result: certain, probable, possible or certainly-not
+const matchGradeExtension = "http://hl7.org/fhir/StructureDefinition/match-grade"
+
+// MatchClient looks patients up with the FHIR Patient/$match operation, which
+// matches on demographics and identifiers. Only certain matches count: those
+// graded certain by the EMPI or, without a grade, scoring at least MinScore.
+type MatchClient struct {
+	connection
+	minScore float64
+}
+
+// NewMatchClient validates cfg and returns a client for its EMPI; nothing is
+// sent until the first lookup
+func NewMatchClient(cfg config.EMPIConfig) (*MatchClient, error) {
+	conn, err := newConnection(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MinScore <= 0 || cfg.MinScore > 1 {
+		return nil, fmt.Errorf("invalid EMPI minimum match score %g: must be above 0 and at most 1", cfg.MinScore)
+	}
+	return &MatchClient{connection: conn, minScore: cfg.MinScore}, nil
+}
+
+// matchPatient is the FHIR Patient sent to $match: the demographics of a
+// patient and its identifiers other than the enterprise one, which the EMPI
+// is asked to confirm rather than told
+type matchPatient struct {
+	ResourceType string                `json:"resourceType"`
+	Identifier   []models.Identifier   `json:"identifier,omitempty"`
+	Name         []models.HumanName    `json:"name,omitempty"`
+	Telecom      []models.ContactPoint `json:"telecom,omitempty"`
+	Gender       *string               `json:"gender,omitempty"`
+	BirthDate    string                `json:"birthDate,omitempty"`
+	Address      []models.Address      `json:"address,omitempty"`
+}
+
+// Lookup runs $match for patient
+func (c *MatchClient) Lookup(ctx context.Context, patient *models.Patient) (string, error) {
+	resource := matchPatient{
+		ResourceType: "Patient",
+		Name:         patient.Name,
+		Telecom:      patient.Telecom,
+		Gender:       patient.Gender,
+		Address:      patient.Address,
+	}
+	for _, identifier := range patient.Identifier {
+		if identifier.System == nil || *identifier.System != c.enterpriseSystem {
+			resource.Identifier = append(resource.Identifier, identifier)
+		}
+	}
+	if patient.BirthDate != nil {
+		resource.BirthDate = patient.BirthDate.Format("2006-01-02")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceType": "Parameters",
+		"parameter": []map[string]interface{}{
+			{"name": "resource", "resource": resource},
+			{"name": "onlyCertainMatches", "valueBoolean": true},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/Patient/$match", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/fhir+json")
+
+	data, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	var bundle struct {
+		ResourceType string `json:"resourceType"`
+		Entry        []struct {
+			Resource struct {
+				Identifier []models.Identifier `json:"identifier"`
+			} `json:"resource"`
+			Search struct {
+				Score     *float64 `json:"score"`
+				Extension []struct {
+					URL       string `json:"url"`
+					ValueCode string `json:"valueCode"`
+				} `json:"extension"`
+			} `json:"search"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return "", fmt.Errorf("failed to decode EMPI $match response: %w", err)
+	}
+	if bundle.ResourceType != "Bundle" {
+		return "", fmt.Errorf("EMPI $match returned a %q, not a Bundle", bundle.ResourceType)
+	}
+
+	var enterpriseID string
+	for _, entry := range bundle.Entry {
+		grade := ""
+		for _, extension := range entry.Search.Extension {
+			if extension.URL == matchGradeExtension {
+				grade = extension.ValueCode
+			}
+		}
+		certain := grade == "certain" || (grade == "" && entry.Search.Score != nil && *entry.Search.Score >= c.minScore)
+		if !certain {
+			continue
+		}
+
+		id := EnterpriseID(&models.Patient{Identifier: entry.Resource.Identifier}, c.enterpriseSystem)
+		if id == "" {
+			continue
+		}
+		if enterpriseID != "" && id != enterpriseID {
+			return "", ErrAmbiguousMatch
+		}
+		enterpriseID = id
+	}
+	return enterpriseID, nil
+}
@@ -0,0 +1,90 @@
+package empi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+)
+
+// PIXmClient looks patients up with the IHE PIXm Patient/$ihe-pix operation,
+// which cross-references the patient's identifier in SourceSystem, such as the
+// medical record number, with the enterprise identifier. Patients without an
+// identifier in SourceSystem cannot be looked up and are left unmatched.
+type PIXmClient struct {
+	connection
+	sourceSystem string
+}
+
+// NewPIXmClient validates cfg and returns a client for its cross-reference
+// manager; nothing is sent until the first lookup
+func NewPIXmClient(cfg config.EMPIConfig) (*PIXmClient, error) {
+	conn, err := newConnection(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SourceSystem == "" {
+		return nil, errors.New("the EMPI source identifier system is required for PIXm")
+	}
+	return &PIXmClient{connection: conn, sourceSystem: cfg.SourceSystem}, nil
+}
+
+// Lookup runs $ihe-pix for the patient's source identifier
+func (c *PIXmClient) Lookup(ctx context.Context, patient *models.Patient) (string, error) {
+	source := EnterpriseID(patient, c.sourceSystem)
+	if source == "" {
+		return "", nil
+	}
+
+	query := url.Values{
+		"sourceIdentifier": {c.sourceSystem + "|" + source},
+		"targetSystem":     {c.enterpriseSystem},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/Patient/$ihe-pix?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := c.do(req)
+	if err != nil {
+		// The manager answers 404 for a source identifier it does not know
+		var empiErr *Error
+		if errors.As(err, &empiErr) && empiErr.StatusCode == http.StatusNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var parameters struct {
+		ResourceType string `json:"resourceType"`
+		Parameter    []struct {
+			Name            string             `json:"name"`
+			ValueIdentifier *models.Identifier `json:"valueIdentifier"`
+		} `json:"parameter"`
+	}
+	if err := json.Unmarshal(data, &parameters); err != nil {
+		return "", fmt.Errorf("failed to decode EMPI $ihe-pix response: %w", err)
+	}
+	if parameters.ResourceType != "Parameters" {
+		return "", fmt.Errorf("EMPI $ihe-pix returned a %q, not Parameters", parameters.ResourceType)
+	}
+
+	enterpriseID := ""
+	for _, parameter := range parameters.Parameter {
+		target := parameter.ValueIdentifier
+		if parameter.Name != "targetIdentifier" || target == nil || target.System == nil ||
+			*target.System != c.enterpriseSystem || target.Value == nil {
+			continue
+		}
+		if enterpriseID != "" && *target.Value != enterpriseID {
+			return "", ErrAmbiguousMatch
+		}
+		enterpriseID = *target.Value
+	}
+	return enterpriseID, nil
+}
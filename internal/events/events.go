@@ -0,0 +1,62 @@
+// Package events publishes resource change events to a message transport for
+// downstream consumers such as analytics pipelines and integration engines.
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Supported transports
+const (
+	// TransportNone publishes no events
+	TransportNone = "none"
+	// TransportNATS publishes to NATS JetStream
+	TransportNATS = "nats"
+)
+
+// Event is a stored change to a resource. It carries ids only, never resource
+// contents, so consumers read the resource through the API.
+type Event struct {
+	ID           string    `json:"id"`
+	TenantID     string    `json:"tenantId"`
+	ResourceType string    `json:"resourceType"`
+	ResourceID   string    `json:"resourceId"`
+	Action       string    `json:"action"`
+	UserID       string    `json:"userId,omitempty"`
+	RequestID    string    `json:"requestId,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// Subject returns the subject the event is published on below prefix:
+// <prefix>.<tenant>.<resource type>.<action>, e.g.
+// "healthcare.events.default.patient.create"
+func (e Event) Subject(prefix string) string {
+	return strings.Join([]string{prefix, e.TenantID, strings.ToLower(e.ResourceType), e.Action}, ".")
+}
+
+// Publisher delivers events to a transport
+type Publisher interface {
+	// Publish returns once the transport has durably accepted the event.
+	// Publishing an event again with the same id must not duplicate it.
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
+
+// New returns the publisher selected by cfg.Transport, or nil for TransportNone
+func New(cfg config.EventsConfig, logger *logrus.Logger) (Publisher, error) {
+	switch cfg.Transport {
+	case "", TransportNone:
+		return nil, nil
+	case TransportNATS:
+		return NewNATSPublisher(cfg.NATS, logger)
+	default:
+		return nil, fmt.Errorf("unsupported event transport: %s", cfg.Transport)
+	}
+}
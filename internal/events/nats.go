@@ -0,0 +1,483 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"healthcare-api/internal/config"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNoStream is returned when no JetStream stream captures an event's subject
+var ErrNoStream = errors.New("no JetStream stream captures the event subject")
+
+// errPublisherClosed is returned by Publish after Close
+var errPublisherClosed = errors.New("event publisher is closed")
+
+// NATSPublisher publishes events to NATS JetStream. It speaks the NATS client
+// protocol directly: each event is published with a reply subject on which
+// JetStream acknowledges it once a stream has stored it. The event id is sent
+// as the Nats-Msg-Id header, so a stream drops an event published again within
+// its duplicate window.
+//
+// The connection is opened on the first Publish and opened again after it
+// fails, so a NATS outage only fails the publishes made during it.
+type NATSPublisher struct {
+	addr       string
+	useTLS     bool
+	serverName string
+	prefix     string
+	token      string
+	user       string
+	password   string
+	timeout    time.Duration
+	logger     *logrus.Logger
+
+	mu     sync.Mutex
+	conn   *natsConn
+	closed bool
+}
+
+// NewNATSPublisher creates a publisher for the NATS server at cfg.URL
+func NewNATSPublisher(cfg config.NATSConfig, logger *logrus.Logger) (*NATSPublisher, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS_URL: %w", err)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("invalid NATS_URL %q: scheme must be nats or tls", cfg.URL)
+	}
+	if strings.ContainsAny(cfg.SubjectPrefix, " \t*>") || cfg.SubjectPrefix == "" ||
+		strings.HasPrefix(cfg.SubjectPrefix, ".") || strings.HasSuffix(cfg.SubjectPrefix, ".") {
+		return nil, fmt.Errorf("invalid NATS_SUBJECT_PREFIX %q", cfg.SubjectPrefix)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	p := &NATSPublisher{
+		addr:       addr,
+		useTLS:     u.Scheme == "tls",
+		serverName: u.Hostname(),
+		prefix:     cfg.SubjectPrefix,
+		token:      cfg.Token,
+		user:       cfg.User,
+		password:   cfg.Password,
+		timeout:    time.Duration(cfg.Timeout) * time.Second,
+		logger:     logger,
+	}
+	// Credentials in the URL apply when none are configured separately
+	if u.User != nil && p.user == "" && p.token == "" {
+		if password, ok := u.User.Password(); ok {
+			p.user, p.password = u.User.Username(), password
+		} else {
+			p.token = u.User.Username()
+		}
+	}
+	if p.timeout <= 0 {
+		p.timeout = 5 * time.Second
+	}
+	return p, nil
+}
+
+// jetStreamAck is JetStream's reply to a published message
+type jetStreamAck struct {
+	Stream    string `json:"stream"`
+	Sequence  uint64 `json:"seq"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+	Error     *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error,omitempty"`
+}
+
+// Publish publishes the event and waits for a stream to store it
+func (p *NATSPublisher) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	conn, err := p.connection(ctx)
+	if err != nil {
+		return err
+	}
+
+	subject := event.Subject(p.prefix)
+	reply, err := conn.request(ctx, subject, event.ID, data)
+	if err != nil {
+		return fmt.Errorf("failed to publish event to NATS: %w", err)
+	}
+
+	var ack jetStreamAck
+	if err := json.Unmarshal(reply, &ack); err != nil {
+		return fmt.Errorf("unexpected JetStream reply: %w", err)
+	}
+	if ack.Error != nil {
+		return fmt.Errorf("JetStream rejected event: %s (%d)", ack.Error.Description, ack.Error.Code)
+	}
+	if ack.Stream == "" {
+		return errors.New("unexpected JetStream reply: no stream")
+	}
+
+	p.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"event_id":  event.ID,
+		"subject":   subject,
+		"stream":    ack.Stream,
+		"seq":       ack.Sequence,
+		"duplicate": ack.Duplicate,
+	}).Debug("Event published")
+	return nil
+}
+
+// Close closes the connection; Publish fails afterwards
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.conn != nil {
+		p.conn.fail(errPublisherClosed)
+		p.conn = nil
+	}
+	return nil
+}
+
+// connection returns the open connection, connecting if there is none or it failed
+func (p *NATSPublisher) connection(ctx context.Context) (*natsConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, errPublisherClosed
+	}
+	if p.conn != nil && p.conn.failed() == nil {
+		return p.conn, nil
+	}
+
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS at %s: %w", p.addr, err)
+	}
+	p.conn = conn
+	p.logger.WithField("addr", p.addr).Info("Connected to NATS")
+	return conn, nil
+}
+
+// natsInfo is the part of the server's INFO the publisher uses
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+	Headers     bool `json:"headers"`
+}
+
+// natsConnect is the CONNECT the publisher sends
+type natsConnect struct {
+	Verbose      bool   `json:"verbose"`
+	Pedantic     bool   `json:"pedantic"`
+	TLSRequired  bool   `json:"tls_required"`
+	Name         string `json:"name"`
+	Lang         string `json:"lang"`
+	Version      string `json:"version"`
+	Protocol     int    `json:"protocol"`
+	Headers      bool   `json:"headers"`
+	NoResponders bool   `json:"no_responders"`
+	AuthToken    string `json:"auth_token,omitempty"`
+	User         string `json:"user,omitempty"`
+	Pass         string `json:"pass,omitempty"`
+}
+
+// dial opens a connection: it reads the server's INFO, upgrades to TLS if
+// either side requires it, authenticates with CONNECT and waits for the PONG
+// to the PING after it, then subscribes to the connection's reply inbox
+func (p *NATSPublisher) dial(ctx context.Context) (*natsConn, error) {
+	dialer := net.Dialer{Timeout: p.timeout}
+	raw, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return nil, err
+	}
+	deadline, _ := ctx.Deadline()
+	raw.SetDeadline(deadline)
+
+	var conn net.Conn = raw
+	reader := bufio.NewReader(conn)
+	line, err := readLine(reader)
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		raw.Close()
+		return nil, fmt.Errorf("unexpected greeting %q", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(line[len("INFO "):]), &info); err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("invalid server INFO: %w", err)
+	}
+	if !info.Headers {
+		raw.Close()
+		return nil, errors.New("server does not support message headers, which JetStream publishing needs")
+	}
+
+	useTLS := p.useTLS || info.TLSRequired
+	if useTLS {
+		tlsConn := tls.Client(raw, &tls.Config{ServerName: p.serverName, MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			return nil, fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		conn = tlsConn
+		reader = bufio.NewReader(conn)
+	}
+
+	connect, _ := json.Marshal(natsConnect{
+		TLSRequired:  useTLS,
+		Name:         "healthcare-api",
+		Lang:         "go",
+		Version:      "1.0.0",
+		Protocol:     1,
+		Headers:      true,
+		NoResponders: true,
+		AuthToken:    p.token,
+		User:         p.user,
+		Pass:         p.password,
+	})
+	inbox := "_INBOX." + strings.ReplaceAll(uuid.New().String(), "-", "")
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, fmt.Errorf("server refused connection: %s", strings.TrimSpace(line[len("-ERR"):]))
+		}
+		if line == "PONG" {
+			break
+		}
+	}
+	if _, err := fmt.Fprintf(conn, "SUB %s.* 1\r\n", inbox); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &natsConn{
+		conn:    conn,
+		writer:  bufio.NewWriter(conn),
+		inbox:   inbox,
+		pending: make(map[string]chan natsReply),
+		done:    make(chan struct{}),
+		logger:  p.logger,
+	}
+	go c.readLoop(reader)
+	return c, nil
+}
+
+// natsReply is a message received on the reply inbox
+type natsReply struct {
+	// status is the status code of a message with headers, e.g. "503" when no
+	// stream captures the subject
+	status string
+	data   []byte
+}
+
+// natsConn is an open connection to a NATS server
+type natsConn struct {
+	conn   net.Conn
+	inbox  string
+	logger *logrus.Logger
+
+	writeMu sync.Mutex
+	writer  *bufio.Writer
+
+	mu      sync.Mutex
+	pending map[string]chan natsReply
+	next    uint64
+	err     error
+	done    chan struct{}
+}
+
+// request publishes data on subject and waits for the reply
+func (c *natsConn) request(ctx context.Context, subject, msgID string, data []byte) ([]byte, error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.next++
+	replyTo := c.inbox + "." + strconv.FormatUint(c.next, 10)
+	replies := make(chan natsReply, 1)
+	c.pending[replyTo] = replies
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, replyTo)
+		c.mu.Unlock()
+	}()
+
+	header := "NATS/1.0\r\nNats-Msg-Id: " + msgID + "\r\n\r\n"
+	c.writeMu.Lock()
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetWriteDeadline(deadline)
+	}
+	fmt.Fprintf(c.writer, "HPUB %s %s %d %d\r\n%s", subject, replyTo, len(header), len(header)+len(data), header)
+	c.writer.Write(data)
+	c.writer.WriteString("\r\n")
+	err := c.writer.Flush()
+	c.writeMu.Unlock()
+	if err != nil {
+		c.fail(err)
+		return nil, err
+	}
+
+	select {
+	case reply := <-replies:
+		switch reply.status {
+		case "", "200":
+			return reply.data, nil
+		case "503":
+			return nil, ErrNoStream
+		default:
+			return nil, fmt.Errorf("unexpected reply status %s", reply.status)
+		}
+	case <-c.done:
+		return nil, c.failed()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// readLoop reads server messages until the connection fails, answering the
+// server's PINGs and handing replies to the requests waiting for them
+func (c *natsConn) readLoop(reader *bufio.Reader) {
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		switch {
+		case line == "PING":
+			c.writeMu.Lock()
+			c.writer.WriteString("PONG\r\n")
+			err = c.writer.Flush()
+			c.writeMu.Unlock()
+			if err != nil {
+				c.fail(err)
+				return
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			// The server closes the connection after most errors; treat them all as fatal
+			c.fail(fmt.Errorf("server error: %s", strings.TrimSpace(line[len("-ERR"):])))
+			return
+		case strings.HasPrefix(line, "MSG "), strings.HasPrefix(line, "HMSG "):
+			subject, headerSize, size, err := parseMessageLine(line)
+			if err != nil {
+				c.fail(err)
+				return
+			}
+			body := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, body); err != nil {
+				c.fail(err)
+				return
+			}
+			reply := natsReply{data: body[headerSize:size]}
+			if headerSize > 0 {
+				reply.status = headerStatus(body[:headerSize])
+			}
+			c.mu.Lock()
+			if replies, ok := c.pending[subject]; ok {
+				replies <- reply
+				delete(c.pending, subject)
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+// fail closes the connection, failing the requests waiting on it with err
+func (c *natsConn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.done)
+	c.conn.Close()
+	if !errors.Is(err, errPublisherClosed) {
+		c.logger.WithError(err).Warn("NATS connection failed")
+	}
+}
+
+// failed returns the error the connection failed with, or nil while it is open
+func (c *natsConn) failed() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// parseMessageLine parses "MSG <subject> <sid> [reply-to] <size>" or
+// "HMSG <subject> <sid> [reply-to] <header size> <size>"
+func parseMessageLine(line string) (subject string, headerSize, size int, err error) {
+	fields := strings.Fields(line)
+	headers := fields[0] == "HMSG"
+	minFields := 4
+	if headers {
+		minFields = 5
+	}
+	if len(fields) < minFields || len(fields) > minFields+1 {
+		return "", 0, 0, fmt.Errorf("invalid message line %q", line)
+	}
+
+	size, err = strconv.Atoi(fields[len(fields)-1])
+	if err == nil && headers {
+		headerSize, err = strconv.Atoi(fields[len(fields)-2])
+	}
+	if err != nil || size < 0 || headerSize < 0 || headerSize > size {
+		return "", 0, 0, fmt.Errorf("invalid message line %q", line)
+	}
+	return fields[1], headerSize, size, nil
+}
+
+// headerStatus returns the status code of a header block starting
+// "NATS/1.0 503 No Responders", or "" when it has none
+func headerStatus(header []byte) string {
+	first, _, _ := strings.Cut(string(header), "\r\n")
+	fields := strings.Fields(first)
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// readLine reads a protocol line without its CRLF
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
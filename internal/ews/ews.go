@@ -0,0 +1,208 @@
+// Package ews computes early warning scores from a patient's vital signs: the
+// National Early Warning Score 2 (NEWS2) of the Royal College of Physicians,
+// on its SpO2 scale 1, and the Modified Early Warning Score (MEWS) of Subbe et
+// al. Each vital sign scores points by how far it is from normal, and the
+// total maps to a clinical risk.
+package ews
+
+// Scoring systems
+const (
+	NEWS2 = "NEWS2"
+	MEWS  = "MEWS"
+)
+
+// Systems are the scoring systems, in the order they are reported
+var Systems = []string{NEWS2, MEWS}
+
+// Parameters, the vital signs scored
+const (
+	RespiratoryRate    = "respiratoryRate"
+	OxygenSaturation   = "oxygenSaturation"
+	SupplementalOxygen = "supplementalOxygen"
+	SystolicBP         = "systolicBloodPressure"
+	PulseRate          = "pulseRate"
+	Consciousness      = "consciousness"
+	Temperature        = "temperature"
+)
+
+// Levels of consciousness on the ACVPU scale
+const (
+	Alert        = "A"
+	Confusion    = "C"
+	Voice        = "V"
+	Pain         = "P"
+	Unresponsive = "U"
+)
+
+// Clinical risks
+const (
+	RiskLow       = "low"
+	RiskLowMedium = "low-medium"
+	RiskMedium    = "medium"
+	RiskHigh      = "high"
+)
+
+// Vitals are the vital signs a score is computed from; nil and "" are not measured
+type Vitals struct {
+	// RespiratoryRate in breaths per minute
+	RespiratoryRate *float64
+	// OxygenSaturation in percent
+	OxygenSaturation *float64
+	// OnOxygen is whether the patient breathes supplemental oxygen
+	OnOxygen *bool
+	// SystolicBP in mmHg
+	SystolicBP *float64
+	// PulseRate in beats per minute
+	PulseRate *float64
+	// Consciousness is a level of the ACVPU scale
+	Consciousness string
+	// Temperature in degrees Celsius
+	Temperature *float64
+}
+
+// Score is an early warning score
+type Score struct {
+	System string
+	// Total sums the points of the parameters measured
+	Total int
+	Risk  string
+	// Points holds the points of each parameter measured
+	Points map[string]int
+	// Missing lists the parameters the system needs that were not measured; a
+	// score missing any understates the risk
+	Missing []string
+}
+
+// Complete reports whether every parameter the system needs was measured
+func (s *Score) Complete() bool {
+	return len(s.Missing) == 0
+}
+
+// band scores a value by the first upper bound it does not exceed
+type band struct {
+	max    float64
+	points int
+}
+
+// above is the upper bound of the last band of a table
+const above = 1e308
+
+func points(value float64, bands []band) int {
+	for _, b := range bands {
+		if value <= b.max {
+			return b.points
+		}
+	}
+	return bands[len(bands)-1].points
+}
+
+// NEWS2 tables, from the RCP NEWS2 chart on SpO2 scale 1
+var (
+	news2RespiratoryRate = []band{{8, 3}, {11, 1}, {20, 0}, {24, 2}, {above, 3}}
+	news2Saturation      = []band{{91, 3}, {93, 2}, {95, 1}, {above, 0}}
+	news2SystolicBP      = []band{{90, 3}, {100, 2}, {110, 1}, {219, 0}, {above, 3}}
+	news2PulseRate       = []band{{40, 3}, {50, 1}, {90, 0}, {110, 1}, {130, 2}, {above, 3}}
+	news2Temperature     = []band{{35.0, 3}, {36.0, 1}, {38.0, 0}, {39.0, 1}, {above, 2}}
+)
+
+// MEWS tables
+var (
+	mewsRespiratoryRate = []band{{8, 2}, {14, 0}, {20, 1}, {29, 2}, {above, 3}}
+	mewsSystolicBP      = []band{{70, 3}, {80, 2}, {100, 1}, {199, 0}, {above, 2}}
+	mewsPulseRate       = []band{{40, 2}, {50, 1}, {100, 0}, {110, 1}, {129, 2}, {above, 3}}
+	mewsTemperature     = []band{{34.9, 2}, {38.4, 0}, {above, 2}}
+	mewsConsciousness   = map[string]int{Alert: 0, Confusion: 1, Voice: 1, Pain: 2, Unresponsive: 3}
+)
+
+// Compute computes the score of system from vitals; system is NEWS2 or MEWS
+func Compute(system string, vitals Vitals) *Score {
+	if system == MEWS {
+		return computeMEWS(vitals)
+	}
+	return computeNEWS2(vitals)
+}
+
+// computeNEWS2 scores vitals on NEWS2. The risk is high from 7, medium from 5,
+// low-medium below 5 when a single parameter scores 3, and low otherwise.
+func computeNEWS2(vitals Vitals) *Score {
+	score := &Score{System: NEWS2, Points: map[string]int{}}
+	score.add(RespiratoryRate, vitals.RespiratoryRate, news2RespiratoryRate)
+	score.add(OxygenSaturation, vitals.OxygenSaturation, news2Saturation)
+	if vitals.OnOxygen == nil {
+		score.Missing = append(score.Missing, SupplementalOxygen)
+	} else if *vitals.OnOxygen {
+		score.Points[SupplementalOxygen] = 2
+	} else {
+		score.Points[SupplementalOxygen] = 0
+	}
+	score.add(SystolicBP, vitals.SystolicBP, news2SystolicBP)
+	score.add(PulseRate, vitals.PulseRate, news2PulseRate)
+	switch vitals.Consciousness {
+	case "":
+		score.Missing = append(score.Missing, Consciousness)
+	case Alert:
+		score.Points[Consciousness] = 0
+	default:
+		score.Points[Consciousness] = 3
+	}
+	score.add(Temperature, vitals.Temperature, news2Temperature)
+
+	score.total()
+	redScore := false
+	for _, p := range score.Points {
+		if p == 3 {
+			redScore = true
+		}
+	}
+	switch {
+	case score.Total >= 7:
+		score.Risk = RiskHigh
+	case score.Total >= 5:
+		score.Risk = RiskMedium
+	case redScore:
+		score.Risk = RiskLowMedium
+	default:
+		score.Risk = RiskLow
+	}
+	return score
+}
+
+// computeMEWS scores vitals on MEWS. The risk is high from 5, medium from 3
+// and low otherwise; new confusion scores as responding to voice.
+func computeMEWS(vitals Vitals) *Score {
+	score := &Score{System: MEWS, Points: map[string]int{}}
+	score.add(SystolicBP, vitals.SystolicBP, mewsSystolicBP)
+	score.add(PulseRate, vitals.PulseRate, mewsPulseRate)
+	score.add(RespiratoryRate, vitals.RespiratoryRate, mewsRespiratoryRate)
+	score.add(Temperature, vitals.Temperature, mewsTemperature)
+	if p, ok := mewsConsciousness[vitals.Consciousness]; ok {
+		score.Points[Consciousness] = p
+	} else {
+		score.Missing = append(score.Missing, Consciousness)
+	}
+
+	score.total()
+	switch {
+	case score.Total >= 5:
+		score.Risk = RiskHigh
+	case score.Total >= 3:
+		score.Risk = RiskMedium
+	default:
+		score.Risk = RiskLow
+	}
+	return score
+}
+
+func (s *Score) add(parameter string, value *float64, bands []band) {
+	if value == nil {
+		s.Missing = append(s.Missing, parameter)
+		return
+	}
+	s.Points[parameter] = points(*value, bands)
+}
+
+func (s *Score) total() {
+	for _, p := range s.Points {
+		s.Total += p
+	}
+}
@@ -0,0 +1,50 @@
+// Package features holds the feature flags that turn optional API features
+// off without a deployment. Flags are read from FEATURE_FLAGS and can be
+// replaced while the server runs by a configuration reload.
+package features
+
+import (
+	"sync/atomic"
+)
+
+// Feature names. A feature is on unless its flag is set to false.
+const (
+	// EWS: early warning scores ($ews)
+	EWS = "ews"
+	// Cohorts: stored cohort definitions and their evaluation
+	Cohorts = "cohorts"
+	// Stats: the dashboard statistics
+	Stats = "stats"
+	// DataQuality: the data quality report
+	DataQuality = "data-quality"
+)
+
+// Names are the features that have flags
+var Names = []string{EWS, Cohorts, Stats, DataQuality}
+
+// Flags is a set of feature flags, safe for concurrent use
+type Flags struct {
+	flags atomic.Pointer[map[string]bool]
+}
+
+// New returns the flags set to flags
+func New(flags map[string]bool) *Flags {
+	f := &Flags{}
+	f.Set(flags)
+	return f
+}
+
+// Enabled reports whether a feature is on
+func (f *Flags) Enabled(name string) bool {
+	enabled, ok := (*f.flags.Load())[name]
+	return !ok || enabled
+}
+
+// Set replaces every flag with flags
+func (f *Flags) Set(flags map[string]bool) {
+	copied := make(map[string]bool, len(flags))
+	for name, enabled := range flags {
+		copied[name] = enabled
+	}
+	f.flags.Store(&copied)
+}
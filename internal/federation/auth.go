@@ -0,0 +1,141 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"healthcare-api/internal/config"
+)
+
+// tokenExpiryMargin is how long before it expires a client credentials token is renewed
+const tokenExpiryMargin = 30 * time.Second
+
+// tokenSource supplies the bearer token sent upstream
+type tokenSource interface {
+	token(ctx context.Context) (string, error)
+	// invalidate drops a token the upstream rejected, reporting whether a new
+	// one can be obtained
+	invalidate() bool
+}
+
+func newTokenSource(cfg config.FederationConfig, client *http.Client) (tokenSource, error) {
+	switch cfg.Auth {
+	case "", AuthNone:
+		return nil, nil
+	case AuthBearer:
+		if cfg.Token == "" {
+			return nil, errors.New("federation bearer auth requires a token")
+		}
+		return staticToken(cfg.Token), nil
+	case AuthClientCredentials:
+		tokenURL, err := url.Parse(cfg.TokenURL)
+		if err != nil || (tokenURL.Scheme != "https" && tokenURL.Scheme != "http") || tokenURL.Host == "" {
+			return nil, fmt.Errorf("invalid federation token URL %q", cfg.TokenURL)
+		}
+		if cfg.ClientID == "" || cfg.ClientSecret == "" {
+			return nil, errors.New("federation client credentials auth requires a client ID and secret")
+		}
+		return &clientCredentials{
+			tokenURL:     cfg.TokenURL,
+			clientID:     cfg.ClientID,
+			clientSecret: cfg.ClientSecret,
+			scope:        cfg.Scope,
+			client:       client,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported federation auth: %s", cfg.Auth)
+	}
+}
+
+// staticToken is a fixed bearer token
+type staticToken string
+
+func (t staticToken) token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+func (t staticToken) invalidate() bool {
+	return false
+}
+
+// clientCredentials obtains tokens with the OAuth 2.0 client credentials grant,
+// authenticating with HTTP Basic, and reuses each until shortly before it expires
+type clientCredentials struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scope        string
+	client       *http.Client
+
+	mu      sync.Mutex
+	current string
+	expires time.Time
+}
+
+func (c *clientCredentials) token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current != "" && time.Now().Before(c.expires) {
+		return c.current, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if c.scope != "" {
+		form.Set("scope", c.scope)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request upstream token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read upstream token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint responded %d", resp.StatusCode)
+	}
+
+	var granted struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &granted); err != nil || granted.AccessToken == "" {
+		return "", errors.New("token endpoint returned no access token")
+	}
+
+	// Tokens without an expiry are renewed after the margin, when the upstream
+	// has not rejected them first
+	lifetime := time.Duration(granted.ExpiresIn)*time.Second - tokenExpiryMargin
+	if lifetime < tokenExpiryMargin {
+		lifetime = tokenExpiryMargin
+	}
+	c.current = granted.AccessToken
+	c.expires = time.Now().Add(lifetime)
+	return c.current, nil
+}
+
+func (c *clientCredentials) invalidate() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current = ""
+	return true
+}
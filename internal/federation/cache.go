@@ -0,0 +1,56 @@
+package federation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryCacheEntries bounds the responses a MemoryCache holds
+const memoryCacheEntries = 1000
+
+// MemoryCache caches upstream responses in the process, for deployments
+// without the shared Redis cache. When full, expired entries are dropped, and
+// then every entry if none had expired.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryEntry)}
+}
+
+func (c *MemoryCache) GetUpstream(_ context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.data, true
+}
+
+func (c *MemoryCache) SetUpstream(_ context.Context, key string, data []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= memoryCacheEntries {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= memoryCacheEntries {
+			c.entries = make(map[string]memoryEntry)
+		}
+	}
+	c.entries[key] = memoryEntry{data: data, expires: now.Add(ttl)}
+}
@@ -0,0 +1,254 @@
+// Package federation reads resource types the API does not hold from an
+// upstream FHIR server, so clients reach both through one base URL. Callers'
+// credentials never reach the upstream: requests carry the proxy's own.
+package federation
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"healthcare-api/internal/config"
+)
+
+// Auth modes of the upstream
+const (
+	AuthNone = "none"
+	// AuthBearer sends a fixed bearer token
+	AuthBearer = "bearer"
+	// AuthClientCredentials sends a token obtained with the OAuth 2.0 client
+	// credentials grant, renewed before it expires
+	AuthClientCredentials = "client_credentials"
+)
+
+// maxResponseSize bounds the upstream responses read
+const maxResponseSize = 16 << 20
+
+var (
+	resourceTypePattern = regexp.MustCompile(`^[A-Z][A-Za-z]{1,63}$`)
+	idPattern           = regexp.MustCompile(`^[A-Za-z0-9\-.]{1,64}$`)
+)
+
+// ErrInvalidID is returned for ids FHIR does not allow, which are not sent upstream
+var ErrInvalidID = errors.New("invalid resource id")
+
+// errResponseTooLarge fails upstream responses over maxResponseSize, which
+// would otherwise be relayed and cached cut short
+var errResponseTooLarge = fmt.Errorf("response exceeds %d bytes", maxResponseSize)
+
+// Error is a failure to reach the upstream or a server error it responded
+// with. Timeout is set when it did not answer in time.
+type Error struct {
+	StatusCode int
+	Timeout    bool
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("upstream FHIR server unavailable: %v", e.Err)
+	}
+	return fmt.Sprintf("upstream FHIR server responded %d", e.StatusCode)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Cache keeps upstream responses, shared by the processes using it. Failures
+// are the cache's to log; a miss is read from the upstream.
+type Cache interface {
+	GetUpstream(ctx context.Context, key string) ([]byte, bool)
+	SetUpstream(ctx context.Context, key string, data []byte, ttl time.Duration)
+}
+
+// Response is an upstream response passed on to the client
+type Response struct {
+	StatusCode   int
+	ContentType  string
+	ETag         string
+	LastModified string
+	Body         []byte
+}
+
+// Proxy reads the configured resource types from the upstream. Successful
+// reads and searches are cached; other responses, such as a 404, are passed on
+// uncached.
+type Proxy struct {
+	baseURL string
+	types   []string
+	auth    tokenSource
+	client  *http.Client
+	cache   Cache
+	ttl     time.Duration
+}
+
+// New validates cfg and returns a proxy to its upstream, or nil when federation
+// is disabled; nothing is sent until the first read
+func New(cfg config.FederationConfig) (*Proxy, error) {
+	if cfg.UpstreamURL == "" {
+		return nil, nil
+	}
+	base, err := url.Parse(cfg.UpstreamURL)
+	if err != nil || (base.Scheme != "https" && base.Scheme != "http") || base.Host == "" {
+		return nil, fmt.Errorf("invalid federation upstream URL %q", cfg.UpstreamURL)
+	}
+	if len(cfg.ResourceTypes) == 0 {
+		return nil, errors.New("federation requires at least one resource type")
+	}
+	for _, resourceType := range cfg.ResourceTypes {
+		if !resourceTypePattern.MatchString(resourceType) {
+			return nil, fmt.Errorf("invalid federated resource type %q", resourceType)
+		}
+	}
+	if cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid federation timeout %d: must be positive", cfg.Timeout)
+	}
+	if cfg.CacheTTL < 0 {
+		return nil, fmt.Errorf("invalid federation cache TTL %d: must not be negative", cfg.CacheTTL)
+	}
+
+	client := &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second}
+	auth, err := newTokenSource(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Proxy{
+		baseURL: cfg.UpstreamURL,
+		types:   cfg.ResourceTypes,
+		auth:    auth,
+		client:  client,
+		ttl:     time.Duration(cfg.CacheTTL) * time.Second,
+	}, nil
+}
+
+// SetCache makes the proxy cache successful responses for the configured TTL
+func (p *Proxy) SetCache(cache Cache) {
+	p.cache = cache
+}
+
+// ResourceTypes returns the resource types read through the proxy
+func (p *Proxy) ResourceTypes() []string {
+	return p.types
+}
+
+// Read returns the upstream's response to a read of a resource
+func (p *Proxy) Read(ctx context.Context, resourceType, id string) (*Response, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrInvalidID
+	}
+	return p.get(ctx, resourceType+"/"+id, nil)
+}
+
+// Search returns the upstream's response to a search, with the client's query
+// parameters
+func (p *Proxy) Search(ctx context.Context, resourceType string, query url.Values) (*Response, error) {
+	return p.get(ctx, resourceType, query)
+}
+
+// Rewrite points the upstream URLs of the proxied resource types in body, such
+// as a Bundle's fullUrl and paging links, at base, the proxy's own base path
+func (p *Proxy) Rewrite(body []byte, base string) []byte {
+	for _, resourceType := range p.types {
+		for _, end := range []string{"/", "?", `"`} {
+			body = bytes.ReplaceAll(body, []byte(p.baseURL+"/"+resourceType+end), []byte(base+"/"+resourceType+end))
+		}
+	}
+	return body
+}
+
+// get reads path from the upstream, or from the cache
+func (p *Proxy) get(ctx context.Context, path string, query url.Values) (*Response, error) {
+	target := p.baseURL + "/" + path
+	if encoded := query.Encode(); encoded != "" {
+		target += "?" + encoded
+	}
+
+	key := cacheKey(target)
+	if p.cache != nil && p.ttl > 0 {
+		if data, ok := p.cache.GetUpstream(ctx, key); ok {
+			var cached Response
+			if err := json.Unmarshal(data, &cached); err == nil {
+				return &cached, nil
+			}
+		}
+	}
+
+	resp, err := p.send(ctx, target, false)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK && p.cache != nil && p.ttl > 0 {
+		if data, err := json.Marshal(resp); err == nil {
+			p.cache.SetUpstream(ctx, key, data, p.ttl)
+		}
+	}
+	return resp, nil
+}
+
+// send requests target with the proxy's credentials. A 401 with a token that
+// may have been revoked is retried once with a new one.
+func (p *Proxy) send(ctx context.Context, target string, retried bool) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/fhir+json")
+	if p.auth != nil {
+		token, err := p.auth.token(ctx)
+		if err != nil {
+			return nil, &Error{Err: err}
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpResp, err := p.client.Do(req)
+	if err != nil {
+		return nil, &Error{Timeout: isTimeout(ctx, err), Err: err}
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode == http.StatusUnauthorized && p.auth != nil && !retried && p.auth.invalidate() {
+		io.Copy(io.Discard, io.LimitReader(httpResp.Body, maxResponseSize))
+		return p.send(ctx, target, true)
+	}
+	if httpResp.StatusCode >= 500 {
+		return nil, &Error{StatusCode: httpResp.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, &Error{Timeout: isTimeout(ctx, err), Err: fmt.Errorf("failed to read response: %w", err)}
+	}
+	if len(body) > maxResponseSize {
+		return nil, &Error{StatusCode: httpResp.StatusCode, Err: errResponseTooLarge}
+	}
+	return &Response{
+		StatusCode:   httpResp.StatusCode,
+		ContentType:  httpResp.Header.Get("Content-Type"),
+		ETag:         httpResp.Header.Get("ETag"),
+		LastModified: httpResp.Header.Get("Last-Modified"),
+		Body:         body,
+	}, nil
+}
+
+// cacheKey returns the cache key of an upstream URL
+func cacheKey(target string) string {
+	sum := sha256.Sum256([]byte(target))
+	return hex.EncodeToString(sum[:])
+}
+
+func isTimeout(ctx context.Context, err error) bool {
+	var netErr interface{ Timeout() bool }
+	return errors.Is(ctx.Err(), context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
@@ -0,0 +1,77 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"healthcare-api/internal/config"
+)
+
+// recordingCache counts the responses cached, and never hits
+type recordingCache struct {
+	sets int
+}
+
+func (c *recordingCache) GetUpstream(context.Context, string) ([]byte, bool) {
+	return nil, false
+}
+
+func (c *recordingCache) SetUpstream(context.Context, string, []byte, time.Duration) {
+	c.sets++
+}
+
+func TestProxyResponseSizeLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		wantErr bool
+	}{
+		{"at the limit", maxResponseSize, false},
+		{"over the limit", maxResponseSize + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/fhir+json")
+				w.Write(bytes.Repeat([]byte(" "), tt.size))
+			}))
+			defer upstream.Close()
+
+			proxy, err := New(config.FederationConfig{
+				UpstreamURL:   upstream.URL,
+				ResourceTypes: []string{"Medication"},
+				Timeout:       10,
+				CacheTTL:      60,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cache := &recordingCache{}
+			proxy.SetCache(cache)
+
+			resp, err := proxy.Read(context.Background(), "Medication", "med-1")
+			if !tt.wantErr {
+				if err != nil || len(resp.Body) != tt.size {
+					t.Fatalf("Read = %v, want the %d byte response", err, tt.size)
+				}
+				if cache.sets != 1 {
+					t.Errorf("response cached %d times, want once", cache.sets)
+				}
+				return
+			}
+
+			var upstreamErr *Error
+			if !errors.As(err, &upstreamErr) || upstreamErr.Timeout || !errors.Is(err, errResponseTooLarge) {
+				t.Fatalf("Read = %v, want an upstream Error for the response size", err)
+			}
+			if cache.sets != 0 {
+				t.Errorf("oversized response cached %d times, want never", cache.sets)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type AttachmentHandler struct {
+	service *service.AttachmentService
+	logger  *logrus.Logger
+}
+
+func NewAttachmentHandler(service *service.AttachmentService, logger *logrus.Logger) *AttachmentHandler {
+	return &AttachmentHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// UploadAttachment handles POST /api/v1/attachments. The body is the content
+// itself, sent with its own Content-Type; an optional title query parameter
+// names it. The response is the Attachment to put in a resource.
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	contentType := c.GetHeader("Content-Type")
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, models.NewErrorOutcome(models.ErrorCodeUnsupportedMediaType,
+			"Content-Type must be the media type of the attachment"))
+		return
+	}
+	var title *string
+	if value := c.Query("title"); value != "" {
+		if len(value) > 255 {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "title must be at most 255 characters long"))
+			return
+		}
+		title = &value
+	}
+
+	attachment, err := h.service.Upload(c.Request.Context(), contentType, title, c.Request.Body)
+	if err != nil {
+		h.writeError(c, err, "Failed to upload attachment")
+		return
+	}
+
+	c.Header("Location", *attachment.URL)
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// GetAttachment handles GET /api/v1/attachments/:id, returning the
+// attachment's metadata
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	id, ok := h.attachmentID(c)
+	if !ok {
+		return
+	}
+
+	attachment, err := h.service.GetAttachment(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get attachment")
+		return
+	}
+
+	c.JSON(http.StatusOK, attachment)
+}
+
+// GetDownloadLink handles GET /api/v1/attachments/:id/download, returning a
+// signed link to the content that needs no token until it expires
+func (h *AttachmentHandler) GetDownloadLink(c *gin.Context) {
+	id, ok := h.attachmentID(c)
+	if !ok {
+		return
+	}
+
+	download, err := h.service.DownloadLink(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to create attachment download link")
+		return
+	}
+
+	c.JSON(http.StatusOK, download)
+}
+
+// DownloadContent handles GET /api/v1/attachments/:id/content, serving the
+// content of a signed download link. It is not authenticated; the signature
+// authorizes the request.
+func (h *AttachmentHandler) DownloadContent(c *gin.Context) {
+	id, ok := h.attachmentID(c)
+	if !ok {
+		return
+	}
+
+	attachment, content, err := h.service.OpenLink(c.Request.Context(), c.Query("tenant"), id, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		h.writeError(c, err, "Failed to open attachment download link")
+		return
+	}
+	defer content.Close()
+
+	// Content is served as uploaded, so keep browsers from running it in the API's origin
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+	c.Header("Content-Disposition", disposition)
+	c.Header("Content-Security-Policy", "default-src 'none'; img-src 'self'; sandbox")
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("ETag", strconv.Quote(attachment.Hash))
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, nil)
+}
+
+// attachmentID parses the attachment id in the path, writing an error response on failure
+func (h *AttachmentHandler) attachmentID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid attachment ID format"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeError writes the response for an attachment service error
+func (h *AttachmentHandler) writeError(c *gin.Context, err error, message string) {
+	h.logger.WithError(err).Error(message)
+	switch {
+	case errors.Is(err, models.ErrAttachmentNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeAttachmentNotFound, "Attachment not found"))
+	case errors.Is(err, models.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, models.NewErrorOutcome(models.ErrorCodeAttachmentTooLarge, "Attachment exceeds the size limit"))
+	case errors.Is(err, models.ErrInvalidAttachmentLink):
+		c.JSON(http.StatusForbidden, models.NewErrorOutcome(models.ErrorCodeInvalidAttachmentLink, "Download link is invalid or has expired"))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Request body ended early"))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", message))
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditHandler struct {
+	service *service.AuditService
+	logger  *logrus.Logger
+}
+
+func NewAuditHandler(service *service.AuditService, logger *logrus.Logger) *AuditHandler {
+	return &AuditHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListAuditEvents handles GET /api/v1/audit-events
+//
+// Filters: entity-type (resource type), entity (resource id or Type/id), agent (user id),
+// action (C, R, U, D or CREATE, READ, UPDATE, DELETE, RESTORE; repeatable) and
+// date with ge/gt/le/lt prefixes (repeatable), e.g. date=ge2024-01-01&date=lt2024-02-01.
+func (h *AuditHandler) ListAuditEvents(c *gin.Context) {
+	// Parse query parameters
+	limitStr := c.DefaultQuery("limit", "20")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("limit", limitStr).Error("Invalid limit parameter")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid limit parameter"))
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("offset", offsetStr).Error("Invalid offset parameter")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid offset parameter"))
+		return
+	}
+
+	search, err := parseAuditSearch(c)
+	if err != nil {
+		h.logger.WithError(err).Error("Invalid audit event search")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", err.Error()))
+		return
+	}
+
+	response, err := h.service.ListAuditEvents(c.Request.Context(), search, limit, offset)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list audit events")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list audit events"))
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// parseAuditSearch converts the FHIR-style query parameters into repository filters
+func parseAuditSearch(c *gin.Context) (repository.AuditSearchParams, error) {
+	search := repository.AuditSearchParams{
+		ResourceType: c.Query("entity-type"),
+		UserID:       c.Query("agent"),
+	}
+
+	if entity := c.Query("entity"); entity != "" {
+		// Accept a bare id or a Type/id reference
+		if resourceType, id, ok := strings.Cut(entity, "/"); ok {
+			if search.ResourceType == "" {
+				search.ResourceType = resourceType
+			}
+			entity = id
+		}
+		id, err := uuid.Parse(entity)
+		if err != nil {
+			return search, fmt.Errorf("Invalid entity parameter")
+		}
+		search.ResourceID = &id
+	}
+
+	for _, value := range c.QueryArray("action") {
+		actions := service.AuditActions(value)
+		if len(actions) == 0 {
+			return search, fmt.Errorf("Invalid action parameter: %s", value)
+		}
+		search.Actions = append(search.Actions, actions...)
+	}
+
+	for _, value := range c.QueryArray("date") {
+		if err := applyDateBound(&search, value); err != nil {
+			return search, err
+		}
+	}
+
+	return search, nil
+}
+
+// applyDateBound narrows search by a prefixed date. Dates without a time cover
+// the whole (UTC) day, so le2024-01-31 includes all of January 31st.
+func applyDateBound(search *repository.AuditSearchParams, value string) error {
+	prefix := "eq"
+	if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+		prefix, value = value[:2], value[2:]
+	}
+
+	start, end, err := parseDateRange(value)
+	if err != nil {
+		return fmt.Errorf("Invalid date parameter: %s", value)
+	}
+
+	switch prefix {
+	case "eq":
+		search.From, search.To = laterOf(search.From, start), earlierOf(search.To, end)
+	case "ge":
+		search.From = laterOf(search.From, start)
+	case "gt":
+		search.From = laterOf(search.From, end)
+	case "le":
+		search.To = earlierOf(search.To, end)
+	case "lt":
+		search.To = earlierOf(search.To, start)
+	default:
+		return fmt.Errorf("Unsupported date prefix: %s", prefix)
+	}
+	return nil
+}
+
+// parseDateRange returns the instant range [start, end) denoted by a date or date-time
+func parseDateRange(value string) (time.Time, time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, t.Add(time.Second), nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return t, t.AddDate(0, 0, 1), nil
+}
+
+func laterOf(current *time.Time, t time.Time) *time.Time {
+	if current != nil && current.After(t) {
+		return current
+	}
+	return &t
+}
+
+func earlierOf(current *time.Time, t time.Time) *time.Time {
+	if current != nil && current.Before(t) {
+		return current
+	}
+	return &t
+}
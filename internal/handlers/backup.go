@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"healthcare-api/internal/deidentify"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/service"
+	"healthcare-api/internal/worker"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// backupJobTimeout bounds a single backup or restore; both stream the whole tenant
+const backupJobTimeout = time.Hour
+
+type BackupHandler struct {
+	service *service.BackupService
+	pool    *worker.WorkerPool
+	logger  *logrus.Logger
+}
+
+func NewBackupHandler(service *service.BackupService, pool *worker.WorkerPool, logger *logrus.Logger) *BackupHandler {
+	return &BackupHandler{
+		service: service,
+		pool:    pool,
+		logger:  logger,
+	}
+}
+
+// CreateBackup handles POST /api/v1/admin/backups. With a profile parameter,
+// such as profile=safe-harbor, the snapshot is a de-identified export.
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	profile := c.Query("profile")
+	if profile != "" && !deidentify.ValidProfile(profile) {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid profile parameter: expected "+deidentify.SafeHarbor))
+		return
+	}
+
+	id := service.NewBackupID()
+	if !h.submit(c, worker.BackupActionCreate, id, profile) {
+		return
+	}
+
+	c.Header("Location", "/api/v1/admin/backups/"+id)
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":     id,
+		"status": "accepted",
+	})
+}
+
+// ListBackups handles GET /api/v1/admin/backups
+func (h *BackupHandler) ListBackups(c *gin.Context) {
+	backups, err := h.service.ListBackups(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list backups")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list backups"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"backups": backups,
+		"total":   len(backups),
+	})
+}
+
+// GetBackup handles GET /api/v1/admin/backups/:id
+func (h *BackupHandler) GetBackup(c *gin.Context) {
+	id := c.Param("id")
+
+	backup, err := h.service.GetBackup(c.Request.Context(), id)
+	if err != nil {
+		h.logger.WithError(err).WithField("backup_id", id).Error("Failed to get backup")
+		if errors.Is(err, models.ErrBackupNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeBackupNotFound, "Backup not found or still in progress"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to get backup"))
+		return
+	}
+
+	c.JSON(http.StatusOK, backup)
+}
+
+// RestoreBackup handles POST /api/v1/admin/backups/:id/restore
+func (h *BackupHandler) RestoreBackup(c *gin.Context) {
+	id := c.Param("id")
+
+	// Fail fast on unknown, incomplete or de-identified backups instead of in the background job
+	backup, err := h.service.GetBackup(c.Request.Context(), id)
+	if err != nil {
+		h.logger.WithError(err).WithField("backup_id", id).Error("Failed to get backup for restore")
+		if errors.Is(err, models.ErrBackupNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeBackupNotFound, "Backup not found or still in progress"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to get backup"))
+		return
+	}
+	if backup.Profile != "" {
+		c.JSON(http.StatusConflict, models.NewErrorOutcome(models.ErrorCodeBackupDeidentified, "Backup is a de-identified export and cannot be restored"))
+		return
+	}
+
+	if !h.submit(c, worker.BackupActionRestore, id, "") {
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":     id,
+		"status": "restore accepted",
+	})
+}
+
+// submit queues a backup job for the request's tenant, writing an error response on failure
+func (h *BackupHandler) submit(c *gin.Context, action, backupID, profile string) bool {
+	ctx := c.Request.Context()
+	job := &worker.Job{
+		ID:   uuid.New().String(),
+		Type: "backup",
+		Payload: worker.BackupPayload{
+			Action:   action,
+			BackupID: backupID,
+			TenantID: requestctx.TenantID(ctx),
+			UserID:   requestctx.UserID(ctx),
+			Profile:  profile,
+		},
+		CreatedAt: time.Now().UTC(),
+		Timeout:   backupJobTimeout,
+		Priority:  worker.PriorityLow,
+		TenantID:  requestctx.TenantID(ctx),
+		RequestID: requestctx.RequestID(ctx),
+	}
+	if err := h.pool.SubmitJob(job); err != nil {
+		h.logger.WithError(err).WithField("backup_id", backupID).Error("Failed to submit backup job")
+		c.JSON(http.StatusServiceUnavailable, models.NewOperationOutcome("error", "transient", "Backup queue is unavailable, retry later"))
+		return false
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"job_id":    job.ID,
+		"action":    action,
+		"backup_id": backupID,
+	}).Info("Backup job submitted")
+	return true
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type CohortHandler struct {
+	service *service.CohortService
+	logger  *logrus.Logger
+}
+
+func NewCohortHandler(service *service.CohortService, logger *logrus.Logger) *CohortHandler {
+	return &CohortHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateCohort handles POST /api/v1/cohorts
+func (h *CohortHandler) CreateCohort(c *gin.Context) {
+	var req models.CohortRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind cohort request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+
+	cohort, err := h.service.CreateCohort(c.Request.Context(), &req)
+	if err != nil {
+		h.writeError(c, err, "Failed to create cohort")
+		return
+	}
+
+	c.Header("Location", "/api/v1/cohorts/"+cohort.ID.String())
+	c.JSON(http.StatusCreated, cohort)
+}
+
+// ListCohorts handles GET /api/v1/cohorts
+func (h *CohortHandler) ListCohorts(c *gin.Context) {
+	limit, offset, ok := h.pagination(c)
+	if !ok {
+		return
+	}
+
+	cohorts, pagination, err := h.service.ListCohorts(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.writeError(c, err, "Failed to list cohorts")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":   pagination.Total,
+		"limit":   pagination.Limit,
+		"offset":  pagination.Offset,
+		"cohorts": cohorts,
+	})
+}
+
+// GetCohort handles GET /api/v1/cohorts/:id
+func (h *CohortHandler) GetCohort(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+
+	cohort, err := h.service.GetCohort(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get cohort")
+		return
+	}
+
+	c.JSON(http.StatusOK, cohort)
+}
+
+// ReplaceCohort handles PUT /api/v1/cohorts/:id
+func (h *CohortHandler) ReplaceCohort(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CohortRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind cohort request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+
+	cohort, err := h.service.ReplaceCohort(c.Request.Context(), id, &req)
+	if err != nil {
+		h.writeError(c, err, "Failed to update cohort")
+		return
+	}
+
+	c.JSON(http.StatusOK, cohort)
+}
+
+// DeleteCohort handles DELETE /api/v1/cohorts/:id
+func (h *CohortHandler) DeleteCohort(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCohort(c.Request.Context(), id); err != nil {
+		h.writeError(c, err, "Failed to delete cohort")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Evaluate handles GET /api/v1/cohorts/:id/$evaluate, listing the ids of the
+// patients currently in the cohort, or with _summary=count only their number
+func (h *CohortHandler) Evaluate(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+
+	evaluatedAt := time.Now().UTC()
+	switch c.Query("_summary") {
+	case "":
+	case "count":
+		total, err := h.service.CountMembers(c.Request.Context(), id)
+		if err != nil {
+			h.writeError(c, err, "Failed to evaluate cohort")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"cohortId":    id,
+			"total":       total,
+			"evaluatedAt": evaluatedAt,
+		})
+		return
+	default:
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid _summary parameter: expected count"))
+		return
+	}
+
+	limit, offset, ok := h.pagination(c)
+	if !ok {
+		return
+	}
+
+	ids, pagination, err := h.service.ListMembers(c.Request.Context(), id, limit, offset)
+	if err != nil {
+		h.writeError(c, err, "Failed to evaluate cohort")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cohortId":    id,
+		"total":       pagination.Total,
+		"limit":       pagination.Limit,
+		"offset":      pagination.Offset,
+		"patients":    ids,
+		"evaluatedAt": evaluatedAt,
+	})
+}
+
+// pagination parses the limit and offset parameters, writing an error response on failure
+func (h *CohortHandler) pagination(c *gin.Context) (int, int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid limit parameter"))
+		return 0, 0, false
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid offset parameter"))
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
+
+// pathID parses the id in the path, writing an error response on failure
+func (h *CohortHandler) pathID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid cohort ID format"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeError writes the response for a cohort service error
+func (h *CohortHandler) writeError(c *gin.Context, err error, message string) {
+	h.logger.WithError(err).Error(message)
+	switch {
+	case errors.Is(err, models.ErrCohortNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeCohortNotFound, "Cohort not found"))
+	case errors.Is(err, models.ErrInvalidCohort):
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidCohort, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", message))
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resourceETag returns the strong entity tag of a resource. Every change to a
+// resource increments its version, so the version identifies its representation.
+func resourceETag(resource *models.Resource) string {
+	return `"` + strconv.Itoa(resource.Version) + `"`
+}
+
+// variantETag returns the entity tag of a representation of a resource other
+// than the whole of it, such as one with downsampled data. The variant is part
+// of the tag, so a client holding one representation is never told it is
+// current for another. An empty variant is the whole resource.
+func variantETag(resource *models.Resource, variant string) string {
+	if variant == "" {
+		return resourceETag(resource)
+	}
+	return `"` + strconv.Itoa(resource.Version) + ";" + variant + `"`
+}
+
+// setValidators sets the ETag and Last-Modified headers of a resource response.
+// Responses may only be cached privately and must be revalidated before reuse.
+func setValidators(c *gin.Context, resource *models.Resource) {
+	setVariantValidators(c, resource, "")
+}
+
+// setVariantValidators sets the validators of a response with a variant of the
+// resource
+func setVariantValidators(c *gin.Context, resource *models.Resource, variant string) {
+	c.Header("ETag", variantETag(resource, variant))
+	c.Header("Last-Modified", resource.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "private, no-cache")
+}
+
+// notModified sets the validators of a read response and, when the client's
+// If-None-Match or If-Modified-Since shows its copy is current, responds 304 Not
+// Modified and reports true. If-Modified-Since is ignored when If-None-Match is
+// sent, as RFC 9110 requires.
+func notModified(c *gin.Context, resource *models.Resource) bool {
+	return notModifiedVariant(c, resource, "")
+}
+
+// notModifiedVariant is notModified for a response with a variant of the
+// resource, whose entity tag includes the variant
+func notModifiedVariant(c *gin.Context, resource *models.Resource, variant string) bool {
+	setVariantValidators(c, resource, variant)
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if !etagMatches(match, variantETag(resource, variant)) {
+			return false
+		}
+	} else if since := c.GetHeader("If-Modified-Since"); since != "" {
+		t, err := http.ParseTime(since)
+		// Last-Modified has whole seconds, so compare at that precision
+		if err != nil || resource.UpdatedAt.Truncate(time.Second).After(t) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using the weak
+// comparison the header calls for
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
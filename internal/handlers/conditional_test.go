@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/sampleddata"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNotModifiedVariant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resource := &models.Resource{Version: 3, UpdatedAt: time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)}
+	downsampled := targetVariant(sampleddata.Target{MaxPoints: 500})
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		variant     string
+		wantETag    string
+		want        bool
+	}{
+		{"whole resource, current", `"3"`, "", `"3"`, true},
+		{"whole resource, stale", `"2"`, "", `"3"`, false},
+		{"downsampled, current", `"3;max-points=500"`, downsampled, `"3;max-points=500"`, true},
+		{"downsampled read of a whole copy", `"3"`, downsampled, `"3;max-points=500"`, false},
+		{"whole read of a downsampled copy", `"3;max-points=500"`, "", `"3"`, false},
+		{"other downsampling", `"3;max-points=100"`, downsampled, `"3;max-points=500"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			c.Request.Header.Set("If-None-Match", tt.ifNoneMatch)
+
+			if got := notModifiedVariant(c, resource, tt.variant); got != tt.want {
+				t.Errorf("notModifiedVariant() = %v, want %v", got, tt.want)
+			}
+			if etag := w.Header().Get("ETag"); etag != tt.wantETag {
+				t.Errorf("ETag = %s, want %s", etag, tt.wantETag)
+			}
+		})
+	}
+}
+
+func TestTargetVariant(t *testing.T) {
+	tests := []struct {
+		target sampleddata.Target
+		want   string
+	}{
+		{sampleddata.Target{}, ""},
+		{sampleddata.Target{MaxPoints: 500}, "max-points=500"},
+		{sampleddata.Target{Resolution: 4 * time.Millisecond}, "resolution=4ms"},
+		{sampleddata.Target{MaxPoints: 2, Resolution: time.Second}, "max-points=2;resolution=1s"},
+	}
+	for _, tt := range tests {
+		if got := targetVariant(tt.target); got != tt.want {
+			t.Errorf("targetVariant(%+v) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type ConfigHandler struct {
+	service *service.ConfigService
+	logger  *logrus.Logger
+}
+
+func NewConfigHandler(service *service.ConfigService, logger *logrus.Logger) *ConfigHandler {
+	return &ConfigHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ReloadConfig handles POST /api/v1/admin/config/reload, applying the
+// reloadable settings of the environment and .env file like SIGHUP. An invalid
+// configuration is rejected with 422 and an issue per problem.
+func (h *ConfigHandler) ReloadConfig(c *gin.Context) {
+	reload, err := h.service.Reload(c.Request.Context(), "api")
+	var invalid *config.ValidationError
+	if errors.As(err, &invalid) {
+		var issues []models.OperationOutcomeIssue
+		for i := range invalid.Problems {
+			issues = append(issues, models.OperationOutcomeIssue{Severity: "error", Code: "invalid", Diagnostics: &invalid.Problems[i]})
+		}
+		for i := range invalid.Insecure {
+			issues = append(issues, models.OperationOutcomeIssue{Severity: "error", Code: "security", Diagnostics: &invalid.Insecure[i]})
+		}
+		c.JSON(http.StatusUnprocessableEntity, &models.OperationOutcome{ResourceType: "OperationOutcome", Issue: issues})
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to reload configuration")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to reload configuration"))
+		return
+	}
+
+	c.JSON(http.StatusOK, reload)
+}
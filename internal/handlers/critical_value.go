@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type CriticalValueHandler struct {
+	service *service.CriticalValueService
+	logger  *logrus.Logger
+}
+
+func NewCriticalValueHandler(service *service.CriticalValueService, logger *logrus.Logger) *CriticalValueHandler {
+	return &CriticalValueHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateRule handles POST /api/v1/admin/critical-value-rules
+func (h *CriticalValueHandler) CreateRule(c *gin.Context) {
+	var req models.CriticalValueRuleCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind critical value rule create request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+
+	rule, err := h.service.CreateRule(c.Request.Context(), &req)
+	if err != nil {
+		h.writeError(c, err, "Failed to create critical value rule")
+		return
+	}
+
+	c.Header("Location", "/api/v1/admin/critical-value-rules/"+rule.ID.String())
+	c.JSON(http.StatusCreated, rule)
+}
+
+// ListRules handles GET /api/v1/admin/critical-value-rules
+func (h *CriticalValueHandler) ListRules(c *gin.Context) {
+	rules, err := h.service.ListRules(c.Request.Context())
+	if err != nil {
+		h.writeError(c, err, "Failed to list critical value rules")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": len(rules),
+		"rules": rules,
+	})
+}
+
+// GetRule handles GET /api/v1/admin/critical-value-rules/:id
+func (h *CriticalValueHandler) GetRule(c *gin.Context) {
+	id, ok := h.pathID(c, "Invalid critical value rule ID format")
+	if !ok {
+		return
+	}
+
+	rule, err := h.service.GetRule(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get critical value rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// UpdateRule handles PATCH /api/v1/admin/critical-value-rules/:id
+func (h *CriticalValueHandler) UpdateRule(c *gin.Context) {
+	id, ok := h.pathID(c, "Invalid critical value rule ID format")
+	if !ok {
+		return
+	}
+
+	var req models.CriticalValueRuleUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind critical value rule update request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c.Request.Context(), id, &req)
+	if err != nil {
+		h.writeError(c, err, "Failed to update critical value rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule handles DELETE /api/v1/admin/critical-value-rules/:id
+func (h *CriticalValueHandler) DeleteRule(c *gin.Context) {
+	id, ok := h.pathID(c, "Invalid critical value rule ID format")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRule(c.Request.Context(), id); err != nil {
+		h.writeError(c, err, "Failed to delete critical value rule")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListTasks handles GET /api/v1/tasks, listing critical result tasks most
+// recent first. Supports status, subject and focus filters.
+func (h *CriticalValueHandler) ListTasks(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid limit parameter"))
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid offset parameter"))
+		return
+	}
+
+	filter := repository.TaskFilter{
+		Status:  c.Query("status"),
+		Subject: c.Query("subject"),
+	}
+	switch filter.Status {
+	case "", models.TaskRequested, models.TaskCompleted:
+	default:
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid status parameter: expected requested or completed"))
+		return
+	}
+	if focus := c.Query("focus"); focus != "" {
+		id, err := uuid.Parse(strings.TrimPrefix(focus, "Observation/"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid focus parameter: expected Observation/<id>"))
+			return
+		}
+		filter.ObservationID = &id
+	}
+
+	response, err := h.service.ListTasks(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		h.writeError(c, err, "Failed to list tasks")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTask handles GET /api/v1/tasks/:id
+func (h *CriticalValueHandler) GetTask(c *gin.Context) {
+	id, ok := h.pathID(c, "Invalid task ID format")
+	if !ok {
+		return
+	}
+
+	task, err := h.service.GetTask(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get task")
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// AcknowledgeTask handles POST /api/v1/tasks/:id/acknowledge, recording that
+// the caller has seen the critical result, which completes the task and stops
+// the escalation of its pages
+func (h *CriticalValueHandler) AcknowledgeTask(c *gin.Context) {
+	id, ok := h.pathID(c, "Invalid task ID format")
+	if !ok {
+		return
+	}
+
+	task, err := h.service.AcknowledgeTask(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to acknowledge task")
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// pathID parses the id in the path, writing an error response on failure
+func (h *CriticalValueHandler) pathID(c *gin.Context, message string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, message))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeError writes the response for a critical value service error
+func (h *CriticalValueHandler) writeError(c *gin.Context, err error, message string) {
+	h.logger.WithError(err).Error(message)
+	switch {
+	case errors.Is(err, models.ErrCriticalValueRuleNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeCriticalValueRuleNotFound, "Critical value rule not found"))
+	case errors.Is(err, models.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeTaskNotFound, "Task not found"))
+	case errors.Is(err, models.ErrInvalidCriticalValueRule):
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidCriticalValueRule, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", message))
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxDataQualityResources bounds the resources listed per issue
+const maxDataQualityResources = 100
+
+type DataQualityHandler struct {
+	service *service.DataQualityService
+	logger  *logrus.Logger
+}
+
+func NewDataQualityHandler(service *service.DataQualityService, logger *logrus.Logger) *DataQualityHandler {
+	return &DataQualityHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetReport handles GET /api/v1/data-quality, listing the first resources
+// with each issue (20 by default)
+func (h *DataQualityHandler) GetReport(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > maxDataQualityResources {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid",
+			"Invalid limit parameter: expected a number from 1 to "+strconv.Itoa(maxDataQualityResources)))
+		return
+	}
+
+	report, err := h.service.Report(c.Request.Context(), limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get data quality report")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to get data quality report"))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
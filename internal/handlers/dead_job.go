@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/service"
+	"healthcare-api/internal/worker"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type DeadJobHandler struct {
+	service *service.DeadJobService
+	logger  *logrus.Logger
+}
+
+func NewDeadJobHandler(service *service.DeadJobService, logger *logrus.Logger) *DeadJobHandler {
+	return &DeadJobHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListDeadJobs handles GET /api/v1/admin/dead-jobs
+func (h *DeadJobHandler) ListDeadJobs(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "20")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("limit", limitStr).Error("Invalid limit parameter")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid limit parameter"))
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("offset", offsetStr).Error("Invalid offset parameter")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid offset parameter"))
+		return
+	}
+
+	filter, ok := h.parseFilter(c)
+	if !ok {
+		return
+	}
+
+	jobs, pagination, err := h.service.ListDeadJobs(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list dead jobs")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list dead jobs"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":  pagination.Total,
+		"limit":  pagination.Limit,
+		"offset": pagination.Offset,
+		"jobs":   jobs,
+	})
+}
+
+// GetDeadJob handles GET /api/v1/admin/dead-jobs/:id
+func (h *DeadJobHandler) GetDeadJob(c *gin.Context) {
+	id := c.Param("id")
+
+	job, err := h.service.GetDeadJob(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, id, "Failed to get dead job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// RequeueDeadJob handles POST /api/v1/admin/dead-jobs/:id/requeue
+func (h *DeadJobHandler) RequeueDeadJob(c *gin.Context) {
+	id := c.Param("id")
+
+	job, err := h.service.RequeueDeadJob(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, worker.ErrQueueFull) {
+			h.logger.WithError(err).WithField("job_id", id).Error("Failed to requeue dead job")
+			c.JSON(http.StatusServiceUnavailable, models.NewOperationOutcome("error", "transient", "Job queue is full, retry later"))
+			return
+		}
+		h.respondError(c, err, id, "Failed to requeue dead job")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":       job.ID,
+		"job_type": job.JobType,
+		"status":   "requeued",
+	})
+}
+
+// DeleteDeadJob handles DELETE /api/v1/admin/dead-jobs/:id
+func (h *DeadJobHandler) DeleteDeadJob(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.service.DeleteDeadJob(c.Request.Context(), id); err != nil {
+		h.respondError(c, err, id, "Failed to delete dead job")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// PurgeDeadJobs handles DELETE /api/v1/admin/dead-jobs, optionally filtered by type and failed_before
+func (h *DeadJobHandler) PurgeDeadJobs(c *gin.Context) {
+	filter, ok := h.parseFilter(c)
+	if !ok {
+		return
+	}
+
+	purged, err := h.service.PurgeDeadJobs(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to purge dead jobs")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to purge dead jobs"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+// parseFilter reads the type and failed_before query parameters, writing a 400 response when invalid
+func (h *DeadJobHandler) parseFilter(c *gin.Context) (repository.DeadJobFilter, bool) {
+	filter := repository.DeadJobFilter{JobType: c.Query("type")}
+
+	if value := c.Query("failed_before"); value != "" {
+		before, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.logger.WithError(err).WithField("failed_before", value).Error("Invalid failed_before parameter")
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid failed_before parameter, expected RFC3339"))
+			return filter, false
+		}
+		filter.FailedBefore = &before
+	}
+
+	return filter, true
+}
+
+func (h *DeadJobHandler) respondError(c *gin.Context, err error, id, message string) {
+	h.logger.WithError(err).WithField("job_id", id).Error(message)
+	if errors.Is(err, models.ErrDeadJobNotFound) {
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeDeadJobNotFound, "Dead job not found"))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", message))
+}
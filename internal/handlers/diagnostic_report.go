@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type DiagnosticReportHandler struct {
+	service *service.DiagnosticReportService
+	logger  *logrus.Logger
+}
+
+func NewDiagnosticReportHandler(service *service.DiagnosticReportService, logger *logrus.Logger) *DiagnosticReportHandler {
+	return &DiagnosticReportHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetDiagnosticReport handles GET /api/v1/diagnostic-reports/:id
+func (h *DiagnosticReportHandler) GetDiagnosticReport(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("id", idStr).Error("Invalid diagnostic report ID")
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid diagnostic report ID format"))
+		return
+	}
+
+	report, err := h.service.GetDiagnosticReport(c.Request.Context(), id)
+	if err != nil {
+		h.logger.WithError(err).WithField("id", id).Error("Failed to get diagnostic report")
+		if errors.Is(err, models.ErrResourceDeleted) {
+			c.JSON(http.StatusGone, models.NewOperationOutcome("error", "deleted", "Diagnostic report has been deleted"))
+			return
+		}
+		if errors.Is(err, models.ErrDiagnosticReportNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeDiagnosticReportNotFound, "Diagnostic report not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to retrieve diagnostic report"))
+		return
+	}
+
+	if notModified(c, &report.Resource) {
+		return
+	}
+
+	respond(c, http.StatusOK, report)
+}
+
+// ListDiagnosticReports handles GET /api/v1/diagnostic-reports
+func (h *DiagnosticReportHandler) ListDiagnosticReports(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "20")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("limit", limitStr).Error("Invalid limit parameter")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid limit parameter"))
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("offset", offsetStr).Error("Invalid offset parameter")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid offset parameter"))
+		return
+	}
+
+	search := repository.DiagnosticReportSearchParams{
+		Subject:    c.Query("subject"),
+		Code:       c.Query("code"),
+		Identifier: c.Query("identifier"),
+	}
+
+	response, err := h.service.ListDiagnosticReports(c.Request.Context(), search, limit, offset)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list diagnostic reports")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list diagnostic reports"))
+		return
+	}
+
+	respond(c, http.StatusOK, response)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type DuplicateHandler struct {
+	service *service.DuplicateService
+	logger  *logrus.Logger
+}
+
+func NewDuplicateHandler(service *service.DuplicateService, logger *logrus.Logger) *DuplicateHandler {
+	return &DuplicateHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListDuplicates handles GET /api/v1/patient-duplicates, listing the tenant's
+// probable duplicate patients, highest score first. Supports status and
+// patient filters, e.g. status=pending.
+func (h *DuplicateHandler) ListDuplicates(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid limit parameter"))
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid offset parameter"))
+		return
+	}
+
+	filter := repository.DuplicateFilter{Status: c.Query("status")}
+	if filter.Status != "" && !models.ValidDuplicateStatus(filter.Status) {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid",
+			"Invalid status parameter: expected pending, confirmed or dismissed"))
+		return
+	}
+	if patient := c.Query("patient"); patient != "" {
+		id, err := uuid.Parse(patient)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid patient parameter"))
+			return
+		}
+		filter.PatientID = &id
+	}
+
+	duplicates, pagination, err := h.service.ListDuplicates(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		h.writeError(c, err, "Failed to list patient duplicates")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":      pagination.Total,
+		"limit":      pagination.Limit,
+		"offset":     pagination.Offset,
+		"duplicates": duplicates,
+	})
+}
+
+// GetDuplicate handles GET /api/v1/patient-duplicates/:id
+func (h *DuplicateHandler) GetDuplicate(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+
+	duplicate, err := h.service.GetDuplicate(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get patient duplicate")
+		return
+	}
+
+	c.JSON(http.StatusOK, duplicate)
+}
+
+// Confirm handles POST /api/v1/patient-duplicates/:id/confirm, recording that
+// the patients are the same person
+func (h *DuplicateHandler) Confirm(c *gin.Context) {
+	h.review(c, models.DuplicateConfirmed)
+}
+
+// Dismiss handles POST /api/v1/patient-duplicates/:id/dismiss, recording that
+// the patients are different people, so the pair is not raised again
+func (h *DuplicateHandler) Dismiss(c *gin.Context) {
+	h.review(c, models.DuplicateDismissed)
+}
+
+// review records a decision, with the note of the optional body
+func (h *DuplicateHandler) review(c *gin.Context, status string) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+
+	var req models.PatientDuplicateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.WithError(err).Error("Failed to bind patient duplicate review request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+
+	duplicate, err := h.service.ReviewDuplicate(c.Request.Context(), id, status, req.Note)
+	if err != nil {
+		h.writeError(c, err, "Failed to review patient duplicate")
+		return
+	}
+
+	c.JSON(http.StatusOK, duplicate)
+}
+
+// pathID parses the id in the path, writing an error response on failure
+func (h *DuplicateHandler) pathID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid patient duplicate ID format"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeError writes the response for a duplicate service error
+func (h *DuplicateHandler) writeError(c *gin.Context, err error, message string) {
+	h.logger.WithError(err).Error(message)
+	if errors.Is(err, models.ErrPatientDuplicateNotFound) {
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodePatientDuplicateNotFound, "Patient duplicate not found"))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", message))
+}
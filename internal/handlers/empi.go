@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type EMPIHandler struct {
+	service *service.EMPIService
+	logger  *logrus.Logger
+}
+
+func NewEMPIHandler(service *service.EMPIService, logger *logrus.Logger) *EMPIHandler {
+	return &EMPIHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListLinks handles GET /api/v1/admin/empi/links, listing the outcome of the
+// last EMPI lookup of the tenant's patients, most recently checked first.
+// Supports a status filter, e.g. status=mismatch.
+func (h *EMPIHandler) ListLinks(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid limit parameter"))
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid offset parameter"))
+		return
+	}
+	status := c.Query("status")
+	if status != "" && !models.ValidEMPILinkStatus(status) {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid",
+			"Invalid status parameter: expected linked, unmatched, mismatch, conflict or error"))
+		return
+	}
+
+	links, pagination, err := h.service.ListLinks(c.Request.Context(), status, limit, offset)
+	if err != nil {
+		h.writeError(c, err, "Failed to list EMPI links")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":  pagination.Total,
+		"limit":  pagination.Limit,
+		"offset": pagination.Offset,
+		"links":  links,
+	})
+}
+
+// GetLink handles GET /api/v1/admin/empi/links/:id, the link of a patient
+func (h *EMPIHandler) GetLink(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+
+	link, err := h.service.GetLink(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get EMPI link")
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// Sync handles POST /api/v1/admin/empi/links/:id/sync, looking the patient up
+// in the EMPI now, such as after correcting its demographics or identifiers
+func (h *EMPIHandler) Sync(c *gin.Context) {
+	id, ok := h.pathID(c)
+	if !ok {
+		return
+	}
+
+	link, err := h.service.Sync(c.Request.Context(), id)
+	if err != nil && link != nil {
+		h.logger.WithError(err).WithField("patient_id", id).Error("EMPI lookup failed")
+		c.JSON(http.StatusBadGateway, models.NewOperationOutcome("error", "transient", "EMPI lookup failed: "+*link.LastError))
+		return
+	}
+	if err != nil {
+		h.writeError(c, err, "Failed to sync patient with EMPI")
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// pathID parses the patient id in the path, writing an error response on failure
+func (h *EMPIHandler) pathID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid patient ID format"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// writeError writes the response for an EMPI service error
+func (h *EMPIHandler) writeError(c *gin.Context, err error, message string) {
+	h.logger.WithError(err).Error(message)
+	switch {
+	case errors.Is(err, models.ErrEMPILinkNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeEMPILinkNotFound, "Patient has not been looked up in the EMPI"))
+	case errors.Is(err, models.ErrPatientNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodePatientNotFound, "Patient not found"))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", message))
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"healthcare-api/internal/ews"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type EWSHandler struct {
+	service *service.EWSService
+	logger  *logrus.Logger
+}
+
+func NewEWSHandler(service *service.EWSService, logger *logrus.Logger) *EWSHandler {
+	return &EWSHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetScores handles GET /api/v1/patients/:id/$ews, computing the patient's
+// early warning scores from their latest vital signs. The score parameter,
+// news2 or mews, or both comma-separated, selects the systems; both by default.
+func (h *EWSHandler) GetScores(c *gin.Context) {
+	h.scores(c, false)
+}
+
+// RecordScores handles POST /api/v1/patients/:id/$ews, computing the scores
+// like GetScores and recording the complete ones as observations: 201 when any
+// was recorded.
+func (h *EWSHandler) RecordScores(c *gin.Context) {
+	h.scores(c, true)
+}
+
+func (h *EWSHandler) scores(c *gin.Context, record bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid patient ID format"))
+		return
+	}
+
+	systems := ews.Systems
+	if score := c.Query("score"); score != "" {
+		systems = nil
+		for _, name := range strings.Split(score, ",") {
+			system := strings.ToUpper(strings.TrimSpace(name))
+			if system != ews.NEWS2 && system != ews.MEWS {
+				c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid score parameter: expected news2, mews or both"))
+				return
+			}
+			if !slices.Contains(systems, system) {
+				systems = append(systems, system)
+			}
+		}
+	}
+
+	compute := h.service.Compute
+	if record {
+		compute = h.service.Record
+	}
+	scores, err := compute(c.Request.Context(), id, systems)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to compute early warning scores")
+		switch {
+		case errors.Is(err, models.ErrResourceDeleted):
+			c.JSON(http.StatusGone, models.NewOperationOutcome("error", "deleted", "Patient has been deleted"))
+		case errors.Is(err, models.ErrPatientNotFound):
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodePatientNotFound, "Patient not found"))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to compute early warning scores"))
+		}
+		return
+	}
+
+	status := http.StatusOK
+	for _, score := range scores.Scores {
+		if score.Observation != nil {
+			status = http.StatusCreated
+		}
+	}
+	c.JSON(status, scores)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"healthcare-api/internal/federation"
+	"healthcare-api/internal/middleware"
+	"healthcare-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type FederationHandler struct {
+	proxy  *federation.Proxy
+	logger *logrus.Logger
+}
+
+func NewFederationHandler(proxy *federation.Proxy, logger *logrus.Logger) *FederationHandler {
+	return &FederationHandler{
+		proxy:  proxy,
+		logger: logger,
+	}
+}
+
+// ResourceTypes returns the federated resource types to route
+func (h *FederationHandler) ResourceTypes() []string {
+	return h.proxy.ResourceTypes()
+}
+
+// Read handles GET /api/v1/<resourceType>/:id for a federated resource type
+func (h *FederationHandler) Read(resourceType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resp, err := h.proxy.Read(c.Request.Context(), resourceType, c.Param("id"))
+		if errors.Is(err, federation.ErrInvalidID) {
+			c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid "+resourceType+" ID format"))
+			return
+		}
+		h.relay(c, resourceType, resp, err)
+	}
+}
+
+// Search handles GET /api/v1/<resourceType> for a federated resource type,
+// passing the query parameters on to the upstream
+func (h *FederationHandler) Search(resourceType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resp, err := h.proxy.Search(c.Request.Context(), resourceType, c.Request.URL.Query())
+		h.relay(c, resourceType, resp, err)
+	}
+}
+
+// relay writes the upstream's response with its URLs pointed at this API
+func (h *FederationHandler) relay(c *gin.Context, resourceType string, resp *federation.Response, err error) {
+	if err != nil {
+		h.logger.WithError(err).WithField("resource_type", resourceType).Error("Failed to read from upstream FHIR server")
+		var upstreamErr *federation.Error
+		if errors.As(err, &upstreamErr) && upstreamErr.Timeout {
+			c.JSON(http.StatusGatewayTimeout, models.NewErrorOutcome(models.ErrorCodeUpstreamUnavailable, "The upstream FHIR server did not respond in time"))
+			return
+		}
+		c.JSON(http.StatusBadGateway, models.NewErrorOutcome(models.ErrorCodeUpstreamUnavailable, "The upstream FHIR server is unavailable"))
+		return
+	}
+
+	if resp.ETag != "" {
+		c.Header("ETag", resp.ETag)
+	}
+	if resp.LastModified != "" {
+		c.Header("Last-Modified", resp.LastModified)
+	}
+	contentType := resp.ContentType
+	if contentType == "" {
+		contentType = "application/fhir+json"
+	}
+	c.Data(resp.StatusCode, contentType, h.proxy.Rewrite(resp.Body, "/api/"+middleware.GetAPIVersion(c)))
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"healthcare-api/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker  *health.Checker
+	started  time.Time
+	draining atomic.Bool
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker, started: time.Now()}
+}
+
+// GetHealth handles GET /health, reporting the state of each component. It
+// responds 503 when any component is degraded or unhealthy.
+func (h *HealthHandler) GetHealth(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusHealthy {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
+
+// Drain makes readiness fail from now on, as the server shuts down, so traffic
+// is routed to other instances before it stops accepting connections
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// GetReadiness handles GET /health/ready, for readiness probes. It responds
+// 503 while a component requests cannot be served without, such as the
+// database, is unhealthy, so traffic is routed to other instances until it
+// recovers. Degraded components, such as an unreachable cache, leave the
+// instance ready. Once the server is shutting down it responds 503
+// "shutting_down".
+func (h *HealthHandler) GetReadiness(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+
+	status, state := http.StatusOK, "ready"
+	if !report.Ready {
+		status, state = http.StatusServiceUnavailable, "not_ready"
+	}
+	if h.draining.Load() {
+		status, state = http.StatusServiceUnavailable, "shutting_down"
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{
+		"status":     state,
+		"timestamp":  report.Timestamp,
+		"components": report.Components,
+	})
+}
+
+// GetLiveness handles GET /health/live, for liveness probes. It checks no
+// dependency: restarting the server does not bring back its database, and
+// failing liveness during an outage would only add restarts to it. A server
+// that answers is alive.
+func (h *HealthHandler) GetLiveness(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "alive",
+		"timestamp":      time.Now().UTC(),
+		"uptime_seconds": int64(time.Since(h.started).Seconds()),
+		"goroutines":     runtime.NumGoroutine(),
+	})
+}
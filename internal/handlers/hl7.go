@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"healthcare-api/internal/hl7v2"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// hl7StatusCodes are the HTTP statuses of messages rejected for each error condition
+var hl7StatusCodes = map[hl7v2.ErrorCondition]int{
+	hl7v2.ErrorSegmentSequence:        http.StatusBadRequest,
+	hl7v2.ErrorUnsupportedMessageType: http.StatusBadRequest,
+	hl7v2.ErrorUnsupportedEventCode:   http.StatusBadRequest,
+	hl7v2.ErrorRequiredFieldMissing:   http.StatusUnprocessableEntity,
+	hl7v2.ErrorDataType:               http.StatusUnprocessableEntity,
+	hl7v2.ErrorUnknownKey:             http.StatusUnprocessableEntity,
+	hl7v2.ErrorDuplicateKey:           http.StatusConflict,
+	hl7v2.ErrorApplicationInternal:    http.StatusInternalServerError,
+}
+
+type HL7Handler struct {
+	service *service.HL7Service
+	logger  *logrus.Logger
+}
+
+func NewHL7Handler(service *service.HL7Service, logger *logrus.Logger) *HL7Handler {
+	return &HL7Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ReceiveMessage handles POST /api/v1/integrations/hl7v2. The body is a single
+// ER7-encoded message and the response its ACK, with an HTTP status matching the
+// acknowledgement so HTTP clients need not parse it: 202 for results queued to
+// be stored.
+func (h *HL7Handler) ReceiveMessage(c *gin.Context) {
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, hl7v2.MaxMessageSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Data(http.StatusRequestEntityTooLarge, hl7v2.ContentType,
+				hl7v2.Ack(nil, hl7v2.AckReject, hl7v2.ErrorSegmentSequence, "Message is larger than 1 MiB", time.Now()))
+			return
+		}
+		h.logger.WithError(err).Error("Failed to read HL7 v2 message")
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	result := h.service.Process(c.Request.Context(), data)
+	status := http.StatusOK
+	switch {
+	case result.AckCode != hl7v2.AckAccept:
+		status = hl7StatusCodes[result.Condition]
+	case result.Queued:
+		status = http.StatusAccepted
+	}
+	if result.Created {
+		c.Header("Location", resourcePath(c, "patients", result.PatientID.String()))
+	}
+	c.Data(status, hl7v2.ContentType, result.Ack)
+}
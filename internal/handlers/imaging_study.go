@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"healthcare-api/internal/dicom"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type ImagingStudyHandler struct {
+	service *service.ImagingStudyService
+	logger  *logrus.Logger
+}
+
+func NewImagingStudyHandler(service *service.ImagingStudyService, logger *logrus.Logger) *ImagingStudyHandler {
+	return &ImagingStudyHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// IngestMetadata handles POST /api/v1/integrations/dicom. The body is DICOM
+// JSON study metadata, such as a PACS's WADO-RS metadata response, and the
+// response a Bundle of the studies stored: 201 when one was created. The
+// optional subject parameter names the patient, Patient/<id>; without it the
+// patient is found by the DICOM Patient ID.
+func (h *ImagingStudyHandler) IngestMetadata(c *gin.Context) {
+	mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil || (mediaType != dicom.ContentType && mediaType != "application/json") {
+		c.JSON(http.StatusUnsupportedMediaType, models.NewErrorOutcome(models.ErrorCodeUnsupportedMediaType,
+			"Content-Type must be application/dicom+json or application/json"))
+		return
+	}
+
+	patientID := uuid.Nil
+	if subject := c.Query("subject"); subject != "" {
+		id, err := uuid.Parse(strings.TrimPrefix(subject, "Patient/"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid subject parameter: expected Patient/<id>"))
+			return
+		}
+		patientID = id
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, dicom.MaxMetadataSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.NewOperationOutcome("error", "too-long", "Metadata is larger than 16 MiB"))
+			return
+		}
+		h.logger.WithError(err).Error("Failed to read DICOM metadata")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Failed to read request body"))
+		return
+	}
+
+	datasets, err := dicom.Parse(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidDICOMMetadata, err.Error()))
+		return
+	}
+
+	ingested, err := h.service.Ingest(c.Request.Context(), datasets, patientID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to ingest DICOM metadata")
+		switch {
+		case errors.Is(err, dicom.ErrInvalidMetadata):
+			c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidDICOMMetadata, err.Error()))
+		case errors.Is(err, models.ErrPatientNotFound), errors.Is(err, models.ErrResourceDeleted):
+			c.JSON(http.StatusUnprocessableEntity, models.NewErrorOutcome(models.ErrorCodePatientNotFound, "No patient matches the study: "+err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to ingest DICOM metadata"))
+		}
+		return
+	}
+
+	status := http.StatusOK
+	response := &models.ImagingStudyListResponse{
+		ResourceType: "Bundle",
+		ID:           uuid.New().String(),
+		Type:         "collection",
+		Total:        int64(len(ingested)),
+		Entry:        make([]models.ImagingStudyEntry, len(ingested)),
+	}
+	for i, study := range ingested {
+		response.Entry[i] = models.ImagingStudyEntry{
+			FullURL:  resourcePath(c, "imaging-studies", study.Study.ID.String()),
+			Resource: study.Study,
+		}
+		if study.Created {
+			status = http.StatusCreated
+		}
+	}
+	if len(ingested) == 1 && ingested[0].Created {
+		c.Header("Location", response.Entry[0].FullURL)
+	}
+
+	respond(c, status, response)
+}
+
+// GetImagingStudy handles GET /api/v1/imaging-studies/:id
+func (h *ImagingStudyHandler) GetImagingStudy(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("id", idStr).Error("Invalid imaging study ID")
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid imaging study ID format"))
+		return
+	}
+
+	study, err := h.service.GetImagingStudy(c.Request.Context(), id)
+	if err != nil {
+		h.logger.WithError(err).WithField("id", id).Error("Failed to get imaging study")
+		if errors.Is(err, models.ErrResourceDeleted) {
+			c.JSON(http.StatusGone, models.NewOperationOutcome("error", "deleted", "Imaging study has been deleted"))
+			return
+		}
+		if errors.Is(err, models.ErrImagingStudyNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeImagingStudyNotFound, "Imaging study not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to retrieve imaging study"))
+		return
+	}
+
+	if notModified(c, &study.Resource) {
+		return
+	}
+
+	respond(c, http.StatusOK, study)
+}
+
+// ListImagingStudies handles GET /api/v1/imaging-studies
+func (h *ImagingStudyHandler) ListImagingStudies(c *gin.Context) {
+	limitStr := c.DefaultQuery("limit", "20")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("limit", limitStr).Error("Invalid limit parameter")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid limit parameter"))
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("offset", offsetStr).Error("Invalid offset parameter")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid offset parameter"))
+		return
+	}
+
+	search := repository.ImagingStudySearchParams{
+		Subject:    c.Query("subject"),
+		Identifier: c.Query("identifier"),
+		Modality:   c.Query("modality"),
+	}
+
+	response, err := h.service.ListImagingStudies(c.Request.Context(), search, limit, offset)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list imaging studies")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list imaging studies"))
+		return
+	}
+
+	respond(c, http.StatusOK, response)
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"healthcare-api/internal/middleware"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/service"
+	"healthcare-api/internal/worker"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type JobHandler struct {
+	service *service.JobService
+	pool    *worker.WorkerPool
+	logger  *logrus.Logger
+}
+
+func NewJobHandler(service *service.JobService, pool *worker.WorkerPool, logger *logrus.Logger) *JobHandler {
+	return &JobHandler{
+		service: service,
+		pool:    pool,
+		logger:  logger,
+	}
+}
+
+// SubmitJob handles POST /api/v1/jobs
+func (h *JobHandler) SubmitJob(c *gin.Context) {
+	var req models.JobSubmitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind job submit request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+
+	if !h.pool.HasHandler(req.Type) {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "not-supported", "Unknown job type: "+req.Type))
+		return
+	}
+
+	priority := worker.PriorityNormal
+	if req.Priority != "" {
+		var err error
+		if priority, err = worker.ParsePriority(req.Priority); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", err.Error()))
+			return
+		}
+	}
+
+	job := &worker.Job{
+		ID:         uuid.New().String(),
+		Type:       req.Type,
+		MaxRetries: req.MaxRetries,
+		CreatedAt:  time.Now().UTC(),
+		Timeout:    time.Duration(req.TimeoutSeconds) * time.Second,
+		Priority:   priority,
+		DedupKey:   req.DedupKey,
+		RequestID:  requestctx.RequestID(c.Request.Context()),
+	}
+	if len(req.Payload) > 0 {
+		job.Payload = []byte(req.Payload)
+	}
+	if req.RunAt != nil {
+		job.RunAt = req.RunAt.UTC()
+	} else if req.DelaySeconds > 0 {
+		job.RunAt = job.CreatedAt.Add(time.Duration(req.DelaySeconds) * time.Second)
+	}
+
+	if err := h.pool.SubmitJob(job); err != nil {
+		h.logger.WithError(err).WithField("job_type", req.Type).Error("Failed to submit job")
+		if errors.Is(err, worker.ErrQueueFull) {
+			c.JSON(http.StatusServiceUnavailable, models.NewOperationOutcome("error", "transient", "Job queue is full, retry later"))
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, models.NewOperationOutcome("error", "transient", "Job queue is unavailable, retry later"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+		"user_id":  requestctx.UserID(c.Request.Context()),
+	}).Info("Job submitted")
+
+	c.Header("Location", "/api/v1/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":       job.ID,
+		"jobType":  job.Type,
+		"status":   models.JobStatusQueued,
+		"priority": job.Priority,
+	})
+}
+
+// ListJobs handles GET /api/v1/jobs. Tenant admins see their tenant's jobs;
+// platform admins see all jobs, or one tenant's with the tenant parameter.
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	limit, offset, ok := h.parsePagination(c)
+	if !ok {
+		return
+	}
+
+	filter := repository.JobFilter{
+		TenantID: h.tenantScope(c),
+		JobType:  c.Query("type"),
+		Status:   c.Query("status"),
+	}
+	if filter.TenantID == "" {
+		filter.TenantID = c.Query("tenant")
+	}
+
+	jobs, pagination, err := h.service.ListJobs(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list jobs")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list jobs"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":  pagination.Total,
+		"limit":  pagination.Limit,
+		"offset": pagination.Offset,
+		"jobs":   jobs,
+	})
+}
+
+// GetJob handles GET /api/v1/jobs/:id
+func (h *JobHandler) GetJob(c *gin.Context) {
+	id := c.Param("id")
+
+	job, err := h.service.GetJob(c.Request.Context(), id, h.tenantScope(c))
+	if err != nil {
+		h.logger.WithError(err).WithField("job_id", id).Error("Failed to get job")
+		if errors.Is(err, models.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeJobNotFound, "Job not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to get job"))
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJob handles POST /api/v1/jobs/:id/cancel. A waiting job is skipped and a
+// running job has its context cancelled, wherever it runs.
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	id := c.Param("id")
+
+	job, err := h.service.CancelJob(c.Request.Context(), id, h.tenantScope(c))
+	if err != nil {
+		h.logger.WithError(err).WithField("job_id", id).Error("Failed to cancel job")
+		if errors.Is(err, models.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeJobNotFound, "Job not found"))
+			return
+		}
+		if errors.Is(err, models.ErrJobFinished) {
+			c.JSON(http.StatusConflict, models.NewErrorOutcome(models.ErrorCodeJobFinished, "Job has already finished"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to cancel job"))
+		return
+	}
+
+	// Stop it here at once; other processes notice the status shortly
+	h.pool.CancelJob(id)
+
+	h.logger.WithFields(logrus.Fields{
+		"job_id":  id,
+		"user_id": requestctx.UserID(c.Request.Context()),
+	}).Info("Job cancellation requested")
+
+	c.JSON(http.StatusOK, job)
+}
+
+// ListResults handles GET /api/v1/jobs/results, listing attempt results most
+// recent first. Supports type and success filters, scoped like ListJobs.
+func (h *JobHandler) ListResults(c *gin.Context) {
+	limit, offset, ok := h.parsePagination(c)
+	if !ok {
+		return
+	}
+
+	filter := repository.JobResultFilter{
+		TenantID: h.tenantScope(c),
+		JobType:  c.Query("type"),
+	}
+	if filter.TenantID == "" {
+		filter.TenantID = c.Query("tenant")
+	}
+	if value := c.Query("success"); value != "" {
+		success, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid success parameter"))
+			return
+		}
+		filter.Success = &success
+	}
+
+	results, pagination, err := h.service.ListResults(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list job results")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list job results"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":   pagination.Total,
+		"limit":   pagination.Limit,
+		"offset":  pagination.Offset,
+		"results": results,
+	})
+}
+
+// GetJobResults handles GET /api/v1/jobs/:id/results, listing a job's attempts
+func (h *JobHandler) GetJobResults(c *gin.Context) {
+	id := c.Param("id")
+
+	limit, offset, ok := h.parsePagination(c)
+	if !ok {
+		return
+	}
+
+	results, pagination, err := h.service.GetJobResults(c.Request.Context(), id, h.tenantScope(c), limit, offset)
+	if err != nil {
+		h.logger.WithError(err).WithField("job_id", id).Error("Failed to get job results")
+		if errors.Is(err, models.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeJobNotFound, "Job not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to get job results"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":   pagination.Total,
+		"limit":   pagination.Limit,
+		"offset":  pagination.Offset,
+		"results": results,
+	})
+}
+
+// parsePagination reads the limit and offset query parameters, writing a 400 response when invalid
+func (h *JobHandler) parsePagination(c *gin.Context) (int, int, bool) {
+	limitStr := c.DefaultQuery("limit", "20")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("limit", limitStr).Error("Invalid limit parameter")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid limit parameter"))
+		return 0, 0, false
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("offset", offsetStr).Error("Invalid offset parameter")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid offset parameter"))
+		return 0, 0, false
+	}
+
+	return limit, offset, true
+}
+
+// GetQueueStats handles GET /api/v1/jobs/stats
+func (h *JobHandler) GetQueueStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.pool.GetStats())
+}
+
+// ResizeWorkers handles PUT /api/v1/jobs/workers, growing or shrinking this
+// process's worker pool until it restarts
+func (h *JobHandler) ResizeWorkers(c *gin.Context) {
+	var req models.WorkerResizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+
+	if err := h.pool.Resize(*req.Workers); err != nil {
+		h.logger.WithError(err).Error("Failed to resize worker pool")
+		c.JSON(http.StatusServiceUnavailable, models.NewOperationOutcome("error", "transient", "Worker pool is shutting down"))
+		return
+	}
+
+	c.JSON(http.StatusOK, h.pool.GetStats())
+}
+
+// tenantScope returns the tenant whose jobs the caller may see, or "" for platform admins
+func (h *JobHandler) tenantScope(c *gin.Context) string {
+	_, _, roles, _ := middleware.GetUserFromContext(c)
+	for _, role := range roles {
+		if role == "platform_admin" {
+			return ""
+		}
+	}
+	return requestctx.TenantID(c.Request.Context())
+}
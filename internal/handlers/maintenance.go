@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type MaintenanceHandler struct {
+	service *service.MaintenanceService
+	logger  *logrus.Logger
+}
+
+func NewMaintenanceHandler(service *service.MaintenanceService, logger *logrus.Logger) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetMaintenance handles GET /api/v1/admin/maintenance
+func (h *MaintenanceHandler) GetMaintenance(c *gin.Context) {
+	mode, err := h.service.Get(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get maintenance mode")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to get maintenance mode"))
+		return
+	}
+
+	c.JSON(http.StatusOK, mode)
+}
+
+// SetMaintenance handles PUT /api/v1/admin/maintenance, switching maintenance
+// mode on or off for every instance
+func (h *MaintenanceHandler) SetMaintenance(c *gin.Context) {
+	var req models.MaintenanceModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind maintenance mode request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+
+	mode, err := h.service.Set(c.Request.Context(), &req)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to set maintenance mode")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to set maintenance mode"))
+		return
+	}
+
+	c.JSON(http.StatusOK, mode)
+}
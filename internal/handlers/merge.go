@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type PatientMergeHandler struct {
+	service *service.PatientMergeService
+	logger  *logrus.Logger
+}
+
+func NewPatientMergeHandler(service *service.PatientMergeService, logger *logrus.Logger) *PatientMergeHandler {
+	return &PatientMergeHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Merge handles POST /api/v1/patients/$merge, merging the source patient into
+// the target
+func (h *PatientMergeHandler) Merge(c *gin.Context) {
+	var req models.PatientMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind patient merge request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+
+	sourceID, targetID := uuid.MustParse(req.Source), uuid.MustParse(req.Target)
+	if sourceID == targetID {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Source and target must be different patients"))
+		return
+	}
+
+	result, err := h.service.Merge(c.Request.Context(), sourceID, targetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrPatientNotFound):
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodePatientNotFound, "Patient not found"))
+		case errors.Is(err, models.ErrResourceDeleted):
+			c.JSON(http.StatusGone, models.NewOperationOutcome("error", "deleted", "Patient has been deleted"))
+		case errors.Is(err, models.ErrPatientMerged):
+			c.JSON(http.StatusConflict, models.NewErrorOutcome(models.ErrorCodePatientMerged, "Patient has already been merged into another patient"))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to merge patients"))
+		}
+		return
+	}
+
+	respond(c, http.StatusOK, result)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"healthcare-api/internal/middleware"
+	"healthcare-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetadataHandler serves the CapabilityStatement. The statement is declared
+// here rather than derived from the routes, so it must be kept in step with
+// them; cmd/conformance exercises every interaction and search parameter it
+// claims against a running server.
+type MetadataHandler struct {
+	started time.Time
+	// smartObservationSearch is set when a search index serves _query=smart
+	// on observations, which Postgres alone does not
+	smartObservationSearch bool
+	federatedTypes         []string
+}
+
+func NewMetadataHandler(smartObservationSearch bool) *MetadataHandler {
+	return &MetadataHandler{started: time.Now(), smartObservationSearch: smartObservationSearch}
+}
+
+// SetFederatedTypes declares the resource types read through from the upstream
+// FHIR server, which are served under API v1 only
+func (h *MetadataHandler) SetFederatedTypes(resourceTypes []string) {
+	h.federatedTypes = resourceTypes
+}
+
+// GetCapabilityStatement handles GET /api/v1/metadata, describing the resource
+// types served under the request's API version. It requires no token, so
+// clients can discover the server before authorizing.
+func (h *MetadataHandler) GetCapabilityStatement(c *gin.Context) {
+	version := middleware.GetAPIVersion(c)
+	c.JSON(http.StatusOK, &models.CapabilityStatement{
+		ResourceType: "CapabilityStatement",
+		Status:       "active",
+		Date:         h.started.UTC(),
+		Kind:         "instance",
+		Software:     models.CapabilitySoftware{Name: "Healthcare API", Version: "1.0.0"},
+		FHIRVersion:  "4.0.1",
+		Format:       []string{"json"},
+		Rest: []models.CapabilityStatementRest{
+			{Mode: "server", Resource: h.resources(version)},
+		},
+	})
+}
+
+// resources declares the resource types served under an API version
+func (h *MetadataHandler) resources(version string) []models.CapabilityResource {
+	base := "/api/" + version
+	smartQuery := models.CapabilitySearchParam{
+		Name:          "_query",
+		Type:          "special",
+		Documentation: "smart: fuzzy search of the text parameter",
+	}
+
+	patient := models.CapabilityResource{
+		Type:            "Patient",
+		Extension:       pathExtension(base + "/patients"),
+		Interaction:     interactions("create", "read", "update", "delete", "search-type"),
+		ConditionalRead: "full-support",
+		SearchParam: []models.CapabilitySearchParam{
+			{Name: "family", Type: "string", Documentation: "Prefix of a family name, ignoring case"},
+			{Name: "identifier", Type: "token", Documentation: "Identifier value"},
+			smartQuery,
+		},
+		// $ews is left out: it is enabled per tenant by a feature flag
+		Operation: []models.CapabilityOperation{
+			{Name: "merge", Definition: "http://hl7.org/fhir/OperationDefinition/Patient-merge", Documentation: "Requires the registrar role"},
+		},
+	}
+
+	observation := models.CapabilityResource{
+		Type:            "Observation",
+		Extension:       pathExtension(base + "/observations"),
+		Interaction:     interactions("create", "read", "update", "delete", "search-type"),
+		ConditionalRead: "full-support",
+		SearchParam: []models.CapabilitySearchParam{
+			{Name: "subject", Type: "reference", Documentation: "Patient/<id>"},
+			{Name: "code", Type: "token", Documentation: "code or system|code"},
+		},
+		Operation: []models.CapabilityOperation{
+			{Name: "trend", Definition: "urn:healthcare-api:operation:trend", Documentation: "A patient's results for a code, bucketed for charting"},
+		},
+	}
+	if h.smartObservationSearch {
+		observation.SearchParam = append(observation.SearchParam, smartQuery)
+	}
+
+	resources := []models.CapabilityResource{
+		patient,
+		observation,
+		{
+			Type:            "DiagnosticReport",
+			Extension:       pathExtension(base + "/diagnostic-reports"),
+			Interaction:     interactions("read", "search-type"),
+			ConditionalRead: "full-support",
+			SearchParam: []models.CapabilitySearchParam{
+				{Name: "subject", Type: "reference", Documentation: "Patient/<id>"},
+				{Name: "code", Type: "token", Documentation: "code or system|code"},
+				{Name: "identifier", Type: "token", Documentation: "Identifier value"},
+			},
+		},
+		{
+			Type:            "ImagingStudy",
+			Extension:       pathExtension(base + "/imaging-studies"),
+			Interaction:     interactions("read", "search-type"),
+			ConditionalRead: "full-support",
+			SearchParam: []models.CapabilitySearchParam{
+				{Name: "subject", Type: "reference", Documentation: "Patient/<id>"},
+				{Name: "identifier", Type: "token", Documentation: "Study instance UID or accession number"},
+				{Name: "modality", Type: "token", Documentation: "DICOM modality code, e.g. CT"},
+			},
+		},
+	}
+
+	// Search parameters of federated types are passed to the upstream server
+	// as they are, so none are declared
+	if version == "v1" {
+		for _, resourceType := range h.federatedTypes {
+			resources = append(resources, models.CapabilityResource{
+				Type:        resourceType,
+				Extension:   pathExtension(base + "/" + resourceType),
+				Interaction: interactions("read", "search-type"),
+			})
+		}
+	}
+	return resources
+}
+
+func interactions(codes ...string) []models.CapabilityInteraction {
+	result := make([]models.CapabilityInteraction, len(codes))
+	for i, code := range codes {
+		result[i] = models.CapabilityInteraction{Code: code}
+	}
+	return result
+}
+
+func pathExtension(path string) []models.Extension {
+	return []models.Extension{{URL: models.PathExtensionURL, ValueString: &path}}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"healthcare-api/internal/monitoring"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type MetricsHandler struct {
+	metrics *monitoring.Metrics
+	logger  *logrus.Logger
+}
+
+func NewMetricsHandler(metrics *monitoring.Metrics, logger *logrus.Logger) *MetricsHandler {
+	return &MetricsHandler{
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+// GetMetrics handles GET /metrics
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, h.metrics.GetSnapshot())
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type NotificationHandler struct {
+	service *service.NotificationService
+	logger  *logrus.Logger
+}
+
+func NewNotificationHandler(service *service.NotificationService, logger *logrus.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateRecipient handles POST /api/v1/admin/notification-recipients
+func (h *NotificationHandler) CreateRecipient(c *gin.Context) {
+	var req models.NotificationRecipientCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind notification recipient create request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+	if !h.validKinds(c, req.Kinds) {
+		return
+	}
+
+	recipient, err := h.service.CreateRecipient(c.Request.Context(), &req)
+	if err != nil {
+		h.writeError(c, err, "Failed to create notification recipient")
+		return
+	}
+
+	c.Header("Location", "/api/v1/admin/notification-recipients/"+recipient.ID.String())
+	c.JSON(http.StatusCreated, recipient)
+}
+
+// ListRecipients handles GET /api/v1/admin/notification-recipients
+func (h *NotificationHandler) ListRecipients(c *gin.Context) {
+	recipients, err := h.service.ListRecipients(c.Request.Context())
+	if err != nil {
+		h.writeError(c, err, "Failed to list notification recipients")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":      len(recipients),
+		"recipients": recipients,
+	})
+}
+
+// GetRecipient handles GET /api/v1/admin/notification-recipients/:id
+func (h *NotificationHandler) GetRecipient(c *gin.Context) {
+	id, ok := h.pathID(c, "Invalid notification recipient ID format")
+	if !ok {
+		return
+	}
+
+	recipient, err := h.service.GetRecipient(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get notification recipient")
+		return
+	}
+
+	c.JSON(http.StatusOK, recipient)
+}
+
+// UpdateRecipient handles PATCH /api/v1/admin/notification-recipients/:id
+func (h *NotificationHandler) UpdateRecipient(c *gin.Context) {
+	id, ok := h.pathID(c, "Invalid notification recipient ID format")
+	if !ok {
+		return
+	}
+
+	var req models.NotificationRecipientUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind notification recipient update request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+	if req.Kinds != nil && !h.validKinds(c, *req.Kinds) {
+		return
+	}
+
+	recipient, err := h.service.UpdateRecipient(c.Request.Context(), id, &req)
+	if err != nil {
+		h.writeError(c, err, "Failed to update notification recipient")
+		return
+	}
+
+	c.JSON(http.StatusOK, recipient)
+}
+
+// DeleteRecipient handles DELETE /api/v1/admin/notification-recipients/:id
+func (h *NotificationHandler) DeleteRecipient(c *gin.Context) {
+	id, ok := h.pathID(c, "Invalid notification recipient ID format")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRecipient(c.Request.Context(), id); err != nil {
+		h.writeError(c, err, "Failed to delete notification recipient")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListNotifications handles GET /api/v1/admin/notifications, listing
+// notifications most recent first. Supports kind, status and reference filters.
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid limit parameter"))
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid offset parameter"))
+		return
+	}
+
+	filter := repository.NotificationFilter{
+		Kind:      c.Query("kind"),
+		Status:    c.Query("status"),
+		Reference: c.Query("reference"),
+	}
+	switch filter.Status {
+	case "", models.NotificationHeld, models.NotificationQueued, models.NotificationSent, models.NotificationFailed:
+	default:
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid status parameter: expected held, queued, sent or failed"))
+		return
+	}
+
+	notifications, pagination, err := h.service.ListNotifications(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		h.writeError(c, err, "Failed to list notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":         pagination.Total,
+		"limit":         pagination.Limit,
+		"offset":        pagination.Offset,
+		"notifications": notifications,
+	})
+}
+
+// GetNotification handles GET /api/v1/admin/notifications/:id
+func (h *NotificationHandler) GetNotification(c *gin.Context) {
+	id, ok := h.pathID(c, "Invalid notification ID format")
+	if !ok {
+		return
+	}
+
+	notification, err := h.service.GetNotification(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get notification")
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// Acknowledge handles POST /api/v1/notifications/:id/acknowledge, recording
+// that the caller has seen the event, which stops the escalation of its pages
+func (h *NotificationHandler) Acknowledge(c *gin.Context) {
+	id, ok := h.pathID(c, "Invalid notification ID format")
+	if !ok {
+		return
+	}
+
+	notification, err := h.service.Acknowledge(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to acknowledge notification")
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// pathID parses the id in the path, writing an error response on failure
+func (h *NotificationHandler) pathID(c *gin.Context, message string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, message))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// validKinds checks the notification kinds of a request, writing an error response on failure
+func (h *NotificationHandler) validKinds(c *gin.Context, kinds []string) bool {
+	for _, kind := range kinds {
+		if !models.ValidNotificationKind(kind) {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid",
+				"Invalid notification kind "+strconv.Quote(kind)+": expected critical_result or backup_completed"))
+			return false
+		}
+	}
+	return true
+}
+
+// writeError writes the response for a notification service error
+func (h *NotificationHandler) writeError(c *gin.Context, err error, message string) {
+	h.logger.WithError(err).Error(message)
+	switch {
+	case errors.Is(err, models.ErrNotificationRecipientNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeNotificationRecipientNotFound, "Notification recipient not found"))
+	case errors.Is(err, models.ErrNotificationNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeNotificationNotFound, "Notification not found"))
+	case errors.Is(err, models.ErrInvalidNotificationRecipient):
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidNotificationRecipient, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", message))
+	}
+}
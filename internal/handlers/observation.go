@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"healthcare-api/internal/middleware"
 	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/sampleddata"
 	"healthcare-api/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -26,46 +32,138 @@ func NewObservationHandler(service *service.ObservationService, logger *logrus.L
 
 // CreateObservation handles POST /api/v1/observations
 func (h *ObservationHandler) CreateObservation(c *gin.Context) {
-	var req models.ObservationCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.WithError(err).Error("Failed to bind observation create request")
-		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+	req, ok := middleware.ValidatedRequest[models.ObservationCreateRequest](c)
+	if !ok {
+		h.logger.Error("Route has no observation create validation")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to read request body"))
 		return
 	}
 
-	observation, err := h.service.CreateObservation(c.Request.Context(), &req)
+	observation, err := h.service.CreateObservation(c.Request.Context(), req)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to create observation")
 		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to create observation"))
 		return
 	}
 
-	c.Header("Location", "/api/v1/observations/"+observation.ID.String())
-	c.JSON(http.StatusCreated, observation)
+	c.Header("Location", resourcePath(c, "observations", observation.ID.String()))
+	setValidators(c, &observation.Resource)
+	respondWritten(c, http.StatusCreated, observation)
 }
 
-// GetObservation handles GET /api/v1/observations/:id
+// GetObservation handles GET /api/v1/observations/:id. With max-points or
+// resolution, waveform SampledData is downsampled to that many points or that
+// time between points.
 func (h *ObservationHandler) GetObservation(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", idStr).Error("Invalid observation ID")
-		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid observation ID format"))
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid observation ID format"))
+		return
+	}
+
+	target, err := parseTarget(c.Query("max-points"), c.Query("resolution"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", err.Error()))
 		return
 	}
 
 	observation, err := h.service.GetObservation(c.Request.Context(), id)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", id).Error("Failed to get observation")
-		if err.Error() == "observation not found" {
-			c.JSON(http.StatusNotFound, models.NewOperationOutcome("error", "not-found", "Observation not found"))
+		if errors.Is(err, models.ErrResourceDeleted) {
+			c.JSON(http.StatusGone, models.NewOperationOutcome("error", "deleted", "Observation has been deleted"))
+			return
+		}
+		if errors.Is(err, models.ErrObservationNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeObservationNotFound, "Observation not found"))
 			return
 		}
 		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to retrieve observation"))
 		return
 	}
 
-	c.JSON(http.StatusOK, observation)
+	// A downsampled observation is a different representation of the version,
+	// so it gets its own entity tag
+	if notModifiedVariant(c, &observation.Resource, targetVariant(target)) {
+		return
+	}
+
+	if target != (sampleddata.Target{}) {
+		observation = h.decimate(observation, target)
+	}
+	respond(c, http.StatusOK, observation)
+}
+
+// parseTarget parses the max-points and resolution parameters of a read, either
+// of which may be empty
+func parseTarget(maxPoints, resolution string) (sampleddata.Target, error) {
+	var target sampleddata.Target
+	var err error
+	if maxPoints != "" {
+		if target.MaxPoints, err = strconv.Atoi(maxPoints); err != nil || target.MaxPoints < 2 {
+			return target, errors.New("Invalid max-points parameter: expected a number of at least 2")
+		}
+	}
+	if resolution != "" {
+		if target.Resolution, err = time.ParseDuration(resolution); err != nil || target.Resolution <= 0 {
+			return target, errors.New("Invalid resolution parameter: expected a duration such as 1s or 4ms")
+		}
+	}
+	return target, nil
+}
+
+// targetVariant names the representation of an observation downsampled to
+// target, empty for the whole observation
+func targetVariant(target sampleddata.Target) string {
+	var parts []string
+	if target.MaxPoints > 0 {
+		parts = append(parts, "max-points="+strconv.Itoa(target.MaxPoints))
+	}
+	if target.Resolution > 0 {
+		parts = append(parts, "resolution="+target.Resolution.String())
+	}
+	return strings.Join(parts, ";")
+}
+
+// decimate returns a copy of observation with the SampledData of its value and
+// components downsampled to target, tagged SUBSETTED if any was. Malformed
+// data is returned as stored.
+func (h *ObservationHandler) decimate(observation *models.Observation, target sampleddata.Target) *models.Observation {
+	out := *observation
+	reduced := false
+	apply := func(data *models.SampledData) *models.SampledData {
+		decimated, ok, err := sampleddata.Decimate(data, target)
+		if err != nil {
+			h.logger.WithError(err).WithField("id", observation.ID).Warn("Sampled data not downsampled")
+			return data
+		}
+		reduced = reduced || ok
+		return decimated
+	}
+
+	out.ValueSampledData = apply(observation.ValueSampledData)
+	if observation.Component != nil {
+		out.Component = make([]models.ObservationComponent, len(observation.Component))
+		for i, component := range observation.Component {
+			component.ValueSampledData = apply(component.ValueSampledData)
+			out.Component[i] = component
+		}
+	}
+
+	if reduced {
+		// The FHIR tag of a resource returned with elements left out
+		system, code, display := "http://terminology.hl7.org/CodeSystem/v3-ObservationValue", "SUBSETTED", "subsetted"
+		subsetted := models.Coding{System: &system, Code: &code, Display: &display}
+		meta := models.Meta{}
+		if observation.Meta != nil {
+			meta = *observation.Meta
+		}
+		meta.Tag = append(append([]models.Coding(nil), meta.Tag...), subsetted)
+		out.Meta = &meta
+	}
+	return &out
 }
 
 // UpdateObservation handles PUT /api/v1/observations/:id
@@ -74,29 +172,34 @@ func (h *ObservationHandler) UpdateObservation(c *gin.Context) {
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", idStr).Error("Invalid observation ID")
-		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid observation ID format"))
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid observation ID format"))
 		return
 	}
 
-	var req models.ObservationUpdateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.WithError(err).Error("Failed to bind observation update request")
-		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+	req, ok := middleware.ValidatedRequest[models.ObservationUpdateRequest](c)
+	if !ok {
+		h.logger.Error("Route has no observation update validation")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to read request body"))
 		return
 	}
 
-	observation, err := h.service.UpdateObservation(c.Request.Context(), id, &req)
+	observation, err := h.service.UpdateObservation(c.Request.Context(), id, req)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", id).Error("Failed to update observation")
-		if err.Error() == "observation not found" {
-			c.JSON(http.StatusNotFound, models.NewOperationOutcome("error", "not-found", "Observation not found"))
+		if errors.Is(err, models.ErrResourceDeleted) {
+			c.JSON(http.StatusGone, models.NewOperationOutcome("error", "deleted", "Observation has been deleted"))
+			return
+		}
+		if errors.Is(err, models.ErrObservationNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeObservationNotFound, "Observation not found"))
 			return
 		}
 		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to update observation"))
 		return
 	}
 
-	c.JSON(http.StatusOK, observation)
+	setValidators(c, &observation.Resource)
+	respondWritten(c, http.StatusOK, observation)
 }
 
 // DeleteObservation handles DELETE /api/v1/observations/:id
@@ -105,15 +208,19 @@ func (h *ObservationHandler) DeleteObservation(c *gin.Context) {
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", idStr).Error("Invalid observation ID")
-		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid observation ID format"))
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid observation ID format"))
 		return
 	}
 
 	err = h.service.DeleteObservation(c.Request.Context(), id)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", id).Error("Failed to delete observation")
-		if err.Error() == "observation not found" {
-			c.JSON(http.StatusNotFound, models.NewOperationOutcome("error", "not-found", "Observation not found"))
+		if errors.Is(err, models.ErrResourceDeleted) {
+			c.JSON(http.StatusGone, models.NewOperationOutcome("error", "deleted", "Observation has been deleted"))
+			return
+		}
+		if errors.Is(err, models.ErrObservationNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeObservationNotFound, "Observation not found"))
 			return
 		}
 		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to delete observation"))
@@ -123,6 +230,31 @@ func (h *ObservationHandler) DeleteObservation(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// RestoreObservation handles POST /api/v1/admin/observations/:id/restore
+func (h *ObservationHandler) RestoreObservation(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("id", idStr).Error("Invalid observation ID")
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid observation ID format"))
+		return
+	}
+
+	observation, err := h.service.RestoreObservation(c.Request.Context(), id)
+	if err != nil {
+		h.logger.WithError(err).WithField("id", id).Error("Failed to restore observation")
+		if errors.Is(err, models.ErrObservationNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeObservationNotFound, "No deleted observation with this ID"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to restore observation"))
+		return
+	}
+
+	setValidators(c, &observation.Resource)
+	respond(c, http.StatusOK, observation)
+}
+
 // ListObservations handles GET /api/v1/observations
 func (h *ObservationHandler) ListObservations(c *gin.Context) {
 	// Parse query parameters
@@ -143,12 +275,88 @@ func (h *ObservationHandler) ListObservations(c *gin.Context) {
 		return
 	}
 
-	response, err := h.service.ListObservations(c.Request.Context(), limit, offset)
+	// Named queries
+	switch c.Query("_query") {
+	case "":
+	case "smart":
+		text := c.Query("text")
+		if text == "" {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "required", "The text parameter is required for smart search"))
+			return
+		}
+
+		response, err := h.service.SmartSearchObservations(c.Request.Context(), text, c.Query("subject"), limit, offset)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to search observations")
+			if errors.Is(err, models.ErrUnsupported) {
+				c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "not-supported", "Smart search of observations requires the search backend"))
+				return
+			}
+			c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to search observations"))
+			return
+		}
+
+		respond(c, http.StatusOK, response)
+		return
+	default:
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "not-supported", "Unknown named query: "+c.Query("_query")))
+		return
+	}
+
+	search := repository.ObservationSearchParams{
+		Subject: c.Query("subject"),
+		Code:    c.Query("code"),
+	}
+
+	response, err := h.service.ListObservations(c.Request.Context(), search, limit, offset)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to list observations")
 		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list observations"))
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	respond(c, http.StatusOK, response)
+}
+
+// Trend handles GET /api/v1/observations/$trend, summarizing a patient's
+// results for a code over a period into bucketed avg/min/max points for
+// charting. Requires patient and code; period defaults to 90d and bucket to a
+// width keeping the period to about 200 points.
+func (h *ObservationHandler) Trend(c *gin.Context) {
+	patient := c.Query("patient")
+	code := c.Query("code")
+	if patient == "" || code == "" {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "required", "The patient and code parameters are required"))
+		return
+	}
+	if !strings.Contains(patient, "/") {
+		patient = "Patient/" + patient
+	}
+
+	period, err := service.ParseTrendDuration(c.DefaultQuery("period", "90d"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid period parameter: "+err.Error()))
+		return
+	}
+	var bucket time.Duration
+	if value := c.Query("bucket"); value != "" {
+		if bucket, err = service.ParseTrendDuration(value); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid bucket parameter: "+err.Error()))
+			return
+		}
+		if bucket > period || period/bucket > service.MaxTrendPoints {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid",
+				"Invalid bucket parameter: the period must span between 1 and "+strconv.Itoa(service.MaxTrendPoints)+" buckets"))
+			return
+		}
+	}
+
+	trend, err := h.service.ObservationTrend(c.Request.Context(), patient, code, period, bucket)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to compute observation trend")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to compute observation trend"))
+		return
+	}
+
+	c.JSON(http.StatusOK, trend)
 }
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"healthcare-api/internal/middleware"
 	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
 	"healthcare-api/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -26,22 +29,32 @@ func NewPatientHandler(service *service.PatientService, logger *logrus.Logger) *
 
 // CreatePatient handles POST /api/v1/patients
 func (h *PatientHandler) CreatePatient(c *gin.Context) {
-	var req models.PatientCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.WithError(err).Error("Failed to bind patient create request")
-		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+	req, ok := middleware.ValidatedRequest[models.PatientCreateRequest](c)
+	if !ok {
+		h.logger.Error("Route has no patient create validation")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to read request body"))
 		return
 	}
 
-	patient, err := h.service.CreatePatient(c.Request.Context(), &req)
+	patient, err := h.service.CreatePatient(c.Request.Context(), req)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to create patient")
+		var conflict *models.IdentifierConflictError
+		if errors.As(err, &conflict) {
+			c.JSON(http.StatusConflict, identifierConflictOutcome(conflict))
+			return
+		}
+		if errors.Is(err, models.ErrAttachmentTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.NewErrorOutcome(models.ErrorCodeAttachmentTooLarge, "Patient photo exceeds the attachment size limit"))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to create patient"))
 		return
 	}
 
-	c.Header("Location", "/api/v1/patients/"+patient.ID.String())
-	c.JSON(http.StatusCreated, patient)
+	c.Header("Location", resourcePath(c, "patients", patient.ID.String()))
+	setValidators(c, &patient.Resource)
+	respond(c, http.StatusCreated, patient)
 }
 
 // GetPatient handles GET /api/v1/patients/:id
@@ -50,22 +63,30 @@ func (h *PatientHandler) GetPatient(c *gin.Context) {
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", idStr).Error("Invalid patient ID")
-		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid patient ID format"))
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid patient ID format"))
 		return
 	}
 
 	patient, err := h.service.GetPatient(c.Request.Context(), id)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", id).Error("Failed to get patient")
-		if err.Error() == "patient not found" {
-			c.JSON(http.StatusNotFound, models.NewOperationOutcome("error", "not-found", "Patient not found"))
+		if errors.Is(err, models.ErrResourceDeleted) {
+			c.JSON(http.StatusGone, models.NewOperationOutcome("error", "deleted", "Patient has been deleted"))
+			return
+		}
+		if errors.Is(err, models.ErrPatientNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodePatientNotFound, "Patient not found"))
 			return
 		}
 		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to retrieve patient"))
 		return
 	}
 
-	c.JSON(http.StatusOK, patient)
+	if notModified(c, &patient.Resource) {
+		return
+	}
+
+	respond(c, http.StatusOK, patient)
 }
 
 // UpdatePatient handles PUT /api/v1/patients/:id
@@ -74,29 +95,43 @@ func (h *PatientHandler) UpdatePatient(c *gin.Context) {
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", idStr).Error("Invalid patient ID")
-		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid patient ID format"))
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid patient ID format"))
 		return
 	}
 
-	var req models.PatientUpdateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.WithError(err).Error("Failed to bind patient update request")
-		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+	req, ok := middleware.ValidatedRequest[models.PatientUpdateRequest](c)
+	if !ok {
+		h.logger.Error("Route has no patient update validation")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to read request body"))
 		return
 	}
 
-	patient, err := h.service.UpdatePatient(c.Request.Context(), id, &req)
+	patient, err := h.service.UpdatePatient(c.Request.Context(), id, req)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", id).Error("Failed to update patient")
-		if err.Error() == "patient not found" {
-			c.JSON(http.StatusNotFound, models.NewOperationOutcome("error", "not-found", "Patient not found"))
+		if errors.Is(err, models.ErrResourceDeleted) {
+			c.JSON(http.StatusGone, models.NewOperationOutcome("error", "deleted", "Patient has been deleted"))
+			return
+		}
+		if errors.Is(err, models.ErrPatientNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodePatientNotFound, "Patient not found"))
+			return
+		}
+		var conflict *models.IdentifierConflictError
+		if errors.As(err, &conflict) {
+			c.JSON(http.StatusConflict, identifierConflictOutcome(conflict))
+			return
+		}
+		if errors.Is(err, models.ErrAttachmentTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.NewErrorOutcome(models.ErrorCodeAttachmentTooLarge, "Patient photo exceeds the attachment size limit"))
 			return
 		}
 		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to update patient"))
 		return
 	}
 
-	c.JSON(http.StatusOK, patient)
+	setValidators(c, &patient.Resource)
+	respond(c, http.StatusOK, patient)
 }
 
 // DeletePatient handles DELETE /api/v1/patients/:id
@@ -105,15 +140,19 @@ func (h *PatientHandler) DeletePatient(c *gin.Context) {
 	id, err := uuid.Parse(idStr)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", idStr).Error("Invalid patient ID")
-		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid patient ID format"))
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid patient ID format"))
 		return
 	}
 
 	err = h.service.DeletePatient(c.Request.Context(), id)
 	if err != nil {
 		h.logger.WithError(err).WithField("id", id).Error("Failed to delete patient")
-		if err.Error() == "patient not found" {
-			c.JSON(http.StatusNotFound, models.NewOperationOutcome("error", "not-found", "Patient not found"))
+		if errors.Is(err, models.ErrResourceDeleted) {
+			c.JSON(http.StatusGone, models.NewOperationOutcome("error", "deleted", "Patient has been deleted"))
+			return
+		}
+		if errors.Is(err, models.ErrPatientNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodePatientNotFound, "Patient not found"))
 			return
 		}
 		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to delete patient"))
@@ -123,6 +162,31 @@ func (h *PatientHandler) DeletePatient(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// RestorePatient handles POST /api/v1/admin/patients/:id/restore
+func (h *PatientHandler) RestorePatient(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.logger.WithError(err).WithField("id", idStr).Error("Invalid patient ID")
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid patient ID format"))
+		return
+	}
+
+	patient, err := h.service.RestorePatient(c.Request.Context(), id)
+	if err != nil {
+		h.logger.WithError(err).WithField("id", id).Error("Failed to restore patient")
+		if errors.Is(err, models.ErrPatientNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodePatientNotFound, "No deleted patient with this ID"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to restore patient"))
+		return
+	}
+
+	setValidators(c, &patient.Resource)
+	respond(c, http.StatusOK, patient)
+}
+
 // ListPatients handles GET /api/v1/patients
 func (h *PatientHandler) ListPatients(c *gin.Context) {
 	// Parse query parameters
@@ -143,12 +207,51 @@ func (h *PatientHandler) ListPatients(c *gin.Context) {
 		return
 	}
 
-	response, err := h.service.ListPatients(c.Request.Context(), limit, offset)
+	// Named queries
+	switch c.Query("_query") {
+	case "":
+	case "smart":
+		text := c.Query("text")
+		if text == "" {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "required", "The text parameter is required for smart search"))
+			return
+		}
+
+		response, err := h.service.SmartSearchPatients(c.Request.Context(), text, limit, offset)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to search patients")
+			c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to search patients"))
+			return
+		}
+
+		respond(c, http.StatusOK, response)
+		return
+	default:
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "not-supported", "Unknown named query: "+c.Query("_query")))
+		return
+	}
+
+	search := repository.PatientSearchParams{
+		Family:     c.Query("family"),
+		Identifier: c.Query("identifier"),
+	}
+
+	response, err := h.service.ListPatients(c.Request.Context(), search, limit, offset)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to list patients")
 		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list patients"))
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	respond(c, http.StatusOK, response)
+}
+
+// identifierConflictOutcome describes a duplicate identifier, pointing at the patient that already holds it
+func identifierConflictOutcome(conflict *models.IdentifierConflictError) *models.OperationOutcome {
+	reference := "Patient/" + conflict.ResourceID.String()
+	outcome := models.NewErrorOutcome(models.ErrorCodeIdentifierConflict,
+		"Identifier "+conflict.System+"|"+conflict.Value+" is already assigned to "+reference)
+	outcome.Issue[0].Details.Text = &reference
+	outcome.Issue[0].Expression = []string{"Patient.identifier"}
+	return outcome
 }
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"strings"
+
+	"healthcare-api/internal/middleware"
+	"healthcare-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondWritten writes the response of a create or update: the resource, or,
+// when the client sends "Prefer: return=OperationOutcome", an OperationOutcome
+// carrying the warnings found validating the request
+func respondWritten(c *gin.Context, status int, value interface{}) {
+	if !strings.Contains(c.GetHeader("Prefer"), "return=OperationOutcome") {
+		respond(c, status, value)
+		return
+	}
+
+	issues := middleware.ValidationWarnings(c)
+	if len(issues) == 0 {
+		c.JSON(status, models.NewOperationOutcome("information", "informational", "No issues found"))
+		return
+	}
+	c.JSON(status, &models.OperationOutcome{ResourceType: "OperationOutcome", Issue: issues})
+}
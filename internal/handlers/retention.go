@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type RetentionHandler struct {
+	service *service.RetentionService
+	logger  *logrus.Logger
+}
+
+func NewRetentionHandler(service *service.RetentionService, logger *logrus.Logger) *RetentionHandler {
+	return &RetentionHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetRetentionReport handles GET /api/v1/admin/retention/report
+func (h *RetentionHandler) GetRetentionReport(c *gin.Context) {
+	report, err := h.service.Run(c.Request.Context(), true)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to build retention report")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to build retention report"))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RunRetention handles POST /api/v1/admin/retention/run
+func (h *RetentionHandler) RunRetention(c *gin.Context) {
+	dryRun := false
+	if dryRunStr := c.Query("dry_run"); dryRunStr != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(dryRunStr)
+		if err != nil {
+			h.logger.WithError(err).WithField("dry_run", dryRunStr).Error("Invalid dry_run parameter")
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid dry_run parameter"))
+			return
+		}
+	}
+
+	report, err := h.service.Run(c.Request.Context(), dryRun)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to run retention policies")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to run retention policies"))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type ScheduleHandler struct {
+	scheduler *scheduler.Scheduler
+	logger    *logrus.Logger
+}
+
+func NewScheduleHandler(scheduler *scheduler.Scheduler, logger *logrus.Logger) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduler: scheduler,
+		logger:    logger,
+	}
+}
+
+// ListSchedules handles GET /api/v1/admin/schedules
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	schedules, err := h.scheduler.List(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list job schedules")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list job schedules"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":     len(schedules),
+		"schedules": schedules,
+	})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func FuzzParseAuditSearch(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"entity=Patient/3fa85f64-5717-4562-b3fc-2c963f66afa6&action=read&date=ge2024-01-01&date=lt2024-02-01T00:00:00Z",
+		"entity=3fa85f64-5717-4562-b3fc-2c963f66afa6&entity-type=Observation&agent=user-1",
+		"entity=Patient/not-a-uuid",
+		"action=bogus",
+		"date=xx2024-01-01",
+		"date=eq2024-13-45",
+		"date=%zz",
+	} {
+		f.Add(seed)
+	}
+
+	gin.SetMode(gin.TestMode)
+	f.Fuzz(func(t *testing.T, rawQuery string) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.URL.RawQuery = rawQuery
+
+		search, err := parseAuditSearch(c)
+		if err != nil {
+			return
+		}
+		if search.From != nil && search.To != nil && search.From.After(*search.To) && len(c.QueryArray("date")) < 2 {
+			t.Fatalf("date range %v to %v is reversed for %q", search.From, search.To, rawQuery)
+		}
+	})
+}
+
+func FuzzParseDateRange(f *testing.F) {
+	for _, seed := range []string{"2024-01-31", "2024-01-31T23:59:59Z", "2024-01-31T23:59:59+05:30", "2024-02-30", "", "now"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		start, end, err := parseDateRange(value)
+		if err != nil {
+			return
+		}
+		if !start.Before(end) {
+			t.Fatalf("parseDateRange(%q) = [%v, %v), which is empty", value, start, end)
+		}
+	})
+}
+
+func FuzzParseTarget(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"", ""},
+		{"500", ""},
+		{"", "4ms"},
+		{"2", "1s"},
+		{"1", ""},
+		{"", "-1s"},
+		{"9999999999999999999999", ""},
+		{"", "1.5µs"},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+
+	f.Fuzz(func(t *testing.T, maxPoints, resolution string) {
+		target, err := parseTarget(maxPoints, resolution)
+		if err != nil {
+			return
+		}
+		if maxPoints != "" && target.MaxPoints < 2 || maxPoints == "" && target.MaxPoints != 0 {
+			t.Fatalf("parseTarget(%q) max points = %d", maxPoints, target.MaxPoints)
+		}
+		if resolution != "" && target.Resolution <= 0 || resolution == "" && target.Resolution != 0 {
+			t.Fatalf("parseTarget(%q) resolution = %v", resolution, target.Resolution)
+		}
+
+		// The variant is part of an entity tag, so it must not end the tag or
+		// split an If-None-Match list
+		if variant := targetVariant(target); strings.ContainsAny(variant, "\", ") {
+			t.Fatalf("variant %q of %q, %q cannot be part of an ETag", variant, maxPoints, resolution)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxStatsMonths bounds the months of the monthly counts
+const maxStatsMonths = 60
+
+type StatsHandler struct {
+	service *service.StatsService
+	logger  *logrus.Logger
+}
+
+func NewStatsHandler(service *service.StatsService, logger *logrus.Logger) *StatsHandler {
+	return &StatsHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetStats handles GET /api/v1/stats, the counts behind the operational
+// dashboard, with monthly counts over the last months (12 by default)
+func (h *StatsHandler) GetStats(c *gin.Context) {
+	months, err := strconv.Atoi(c.DefaultQuery("months", "12"))
+	if err != nil || months < 1 || months > maxStatsMonths {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid",
+			"Invalid months parameter: expected a number from 1 to "+strconv.Itoa(maxStatsMonths)))
+		return
+	}
+
+	stats, err := h.service.GetStats(c.Request.Context(), months)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get stats")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to get stats"))
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type TenantHandler struct {
+	service *service.TenantService
+	logger  *logrus.Logger
+}
+
+func NewTenantHandler(service *service.TenantService, logger *logrus.Logger) *TenantHandler {
+	return &TenantHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateTenant handles POST /api/v1/admin/tenants
+func (h *TenantHandler) CreateTenant(c *gin.Context) {
+	var req models.TenantCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind tenant create request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+
+	if !models.ValidTenantID(req.ID) {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Tenant ID must be a lowercase slug (a-z, 0-9, -)"))
+		return
+	}
+
+	tenant, err := h.service.CreateTenant(c.Request.Context(), &req)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to create tenant")
+		if errors.Is(err, models.ErrTenantExists) {
+			c.JSON(http.StatusConflict, models.NewErrorOutcome(models.ErrorCodeTenantExists, "Tenant already exists"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to create tenant"))
+		return
+	}
+
+	c.Header("Location", "/api/v1/admin/tenants/"+tenant.ID)
+	c.JSON(http.StatusCreated, tenant)
+}
+
+// GetTenant handles GET /api/v1/admin/tenants/:id
+func (h *TenantHandler) GetTenant(c *gin.Context) {
+	id := c.Param("id")
+
+	tenant, err := h.service.GetTenant(c.Request.Context(), id)
+	if err != nil {
+		h.logger.WithError(err).WithField("tenant_id", id).Error("Failed to get tenant")
+		if errors.Is(err, models.ErrTenantNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeTenantNotFound, "Tenant not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to get tenant"))
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// UpdateTenant handles PATCH /api/v1/admin/tenants/:id
+func (h *TenantHandler) UpdateTenant(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.TenantUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind tenant update request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+
+	tenant, err := h.service.UpdateTenant(c.Request.Context(), id, &req)
+	if err != nil {
+		h.logger.WithError(err).WithField("tenant_id", id).Error("Failed to update tenant")
+		if errors.Is(err, models.ErrTenantNotFound) {
+			c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeTenantNotFound, "Tenant not found"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to update tenant"))
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// ListTenants handles GET /api/v1/admin/tenants
+func (h *TenantHandler) ListTenants(c *gin.Context) {
+	tenants, err := h.service.ListTenants(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list tenants")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list tenants"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":   len(tenants),
+		"tenants": tenants,
+	})
+}
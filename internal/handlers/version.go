@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"healthcare-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Serializer converts a handler's response into the representation of one API
+// version. A version that changes a resource incompatibly registers its own
+// serializer; versions without one share the handlers' own representation.
+type Serializer func(value interface{}) interface{}
+
+// serializers holds the serializer of each API version that has one
+var serializers = map[string]Serializer{}
+
+// RegisterSerializer sets the serializer of an API version, e.g. "v2". It must
+// be called before the router serves requests.
+func RegisterSerializer(version string, serializer Serializer) {
+	serializers[version] = serializer
+}
+
+// respond writes value as JSON in the representation of the request's API version
+func respond(c *gin.Context, status int, value interface{}) {
+	if serialize, ok := serializers[middleware.GetAPIVersion(c)]; ok {
+		value = serialize(value)
+	}
+	c.JSON(status, value)
+}
+
+// resourcePath returns the path of a resource under the request's API version,
+// e.g. /api/v2/patients/<id>
+func resourcePath(c *gin.Context, collection, id string) string {
+	return "/api/" + middleware.GetAPIVersion(c) + "/" + collection + "/" + id
+}
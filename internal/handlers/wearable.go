@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/service"
+	"healthcare-api/internal/wearable"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type WearableHandler struct {
+	service *service.WearableService
+	logger  *logrus.Logger
+}
+
+func NewWearableHandler(service *service.WearableService, logger *logrus.Logger) *WearableHandler {
+	return &WearableHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// IngestSamples handles POST /api/v1/integrations/wearables. The body is a
+// batch of samples from one device and the subject parameter, Patient/<id>,
+// the patient they were recorded for. The response counts the samples stored
+// and those skipped as already stored: 201 when any was stored.
+func (h *WearableHandler) IngestSamples(c *gin.Context) {
+	mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, models.NewErrorOutcome(models.ErrorCodeUnsupportedMediaType,
+			"Content-Type must be application/json"))
+		return
+	}
+
+	patientID, err := uuid.Parse(strings.TrimPrefix(c.Query("subject"), "Patient/"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid subject parameter: expected Patient/<id>"))
+		return
+	}
+
+	var batch wearable.Batch
+	if err := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, wearable.MaxBatchSize)).Decode(&batch); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.NewOperationOutcome("error", "too-long", "Batch is larger than 8 MiB"))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidWearableData, "Invalid batch: "+err.Error()))
+		return
+	}
+
+	response, err := h.service.Ingest(c.Request.Context(), &batch, patientID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to ingest wearable samples")
+		switch {
+		case errors.Is(err, wearable.ErrInvalidBatch):
+			c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidWearableData, err.Error()))
+		case errors.Is(err, models.ErrPatientNotFound), errors.Is(err, models.ErrResourceDeleted):
+			c.JSON(http.StatusUnprocessableEntity, models.NewErrorOutcome(models.ErrorCodePatientNotFound, "No patient matches the subject"))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to ingest wearable samples"))
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if response.Created > 0 {
+		status = http.StatusCreated
+	}
+	c.JSON(status, response)
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type WebhookHandler struct {
+	service *service.WebhookService
+	logger  *logrus.Logger
+}
+
+func NewWebhookHandler(service *service.WebhookService, logger *logrus.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateWebhook handles POST /api/v1/admin/webhooks. The response is the only
+// one carrying the webhook's signing secret.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.WebhookCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind webhook create request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+	if !h.validEvents(c, req.Events) {
+		return
+	}
+
+	webhook, err := h.service.CreateWebhook(c.Request.Context(), &req)
+	if err != nil {
+		h.writeError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.Header("Location", "/api/v1/admin/webhooks/"+webhook.ID.String())
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListWebhooks handles GET /api/v1/admin/webhooks
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.service.ListWebhooks(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list webhooks")
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", "Failed to list webhooks"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":    len(webhooks),
+		"webhooks": webhooks,
+	})
+}
+
+// GetWebhook handles GET /api/v1/admin/webhooks/:id
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := h.webhookID(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.service.GetWebhook(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook handles PATCH /api/v1/admin/webhooks/:id. With rotateSecret, the
+// response carries the new signing secret.
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := h.webhookID(c)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithError(err).Error("Failed to bind webhook update request")
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid request body: "+err.Error()))
+		return
+	}
+	if req.Events != nil && !h.validEvents(c, *req.Events) {
+		return
+	}
+
+	webhook, err := h.service.UpdateWebhook(c.Request.Context(), id, &req)
+	if err != nil {
+		h.writeError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook handles DELETE /api/v1/admin/webhooks/:id
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := h.webhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWebhook(c.Request.Context(), id); err != nil {
+		h.writeError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /api/v1/admin/webhooks/:id/deliveries, listing
+// delivery attempts most recent first. Supports event_id and success filters.
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := h.webhookID(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid limit parameter"))
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid offset parameter"))
+		return
+	}
+
+	filter := repository.WebhookDeliveryFilter{EventID: c.Query("event_id")}
+	if value := c.Query("success"); value != "" {
+		success, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid success parameter"))
+			return
+		}
+		filter.Success = &success
+	}
+
+	deliveries, pagination, err := h.service.ListDeliveries(c.Request.Context(), id, filter, limit, offset)
+	if err != nil {
+		h.writeError(c, err, "Failed to list webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":      pagination.Total,
+		"limit":      pagination.Limit,
+		"offset":     pagination.Offset,
+		"deliveries": deliveries,
+	})
+}
+
+// Redeliver handles POST /api/v1/admin/webhooks/:id/deliveries/:deliveryId/redeliver,
+// queueing the attempt's event for delivery again
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, ok := h.webhookID(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid delivery ID format"))
+		return
+	}
+
+	eventID, err := h.service.Redeliver(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		h.writeError(c, err, "Failed to queue webhook redelivery")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"eventId": eventID,
+		"status":  "redelivery accepted",
+	})
+}
+
+// webhookID parses the webhook id in the path, writing an error response on failure
+func (h *WebhookHandler) webhookID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidID, "Invalid webhook ID format"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// validEvents checks the event patterns of a request, writing an error response on failure
+func (h *WebhookHandler) validEvents(c *gin.Context, patterns []string) bool {
+	for _, pattern := range patterns {
+		if !models.ValidWebhookEventPattern(pattern) {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid",
+				"Invalid event pattern "+strconv.Quote(pattern)+": expected <resource type>.<action>, e.g. Patient.create or Observation.*"))
+			return false
+		}
+	}
+	return true
+}
+
+// writeError writes the response for a webhook service error
+func (h *WebhookHandler) writeError(c *gin.Context, err error, message string) {
+	h.logger.WithError(err).Error(message)
+	switch {
+	case errors.Is(err, models.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeWebhookNotFound, "Webhook not found"))
+	case errors.Is(err, models.ErrWebhookDeliveryNotFound):
+		c.JSON(http.StatusNotFound, models.NewErrorOutcome(models.ErrorCodeWebhookDeliveryNotFound, "Webhook delivery not found"))
+	case errors.Is(err, models.ErrInvalidWebhookURL):
+		c.JSON(http.StatusBadRequest, models.NewErrorOutcome(models.ErrorCodeInvalidWebhookURL, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception", message))
+	}
+}
@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"healthcare-api/internal/cache"
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/worker"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// queueFullRatio is how full a bounded job queue may get before the
+	// worker component is degraded
+	queueFullRatio = 0.9
+	// failingJobAttempts is how many attempts of a job type may fail in a row
+	// before the worker component is degraded
+	failingJobAttempts = 3
+)
+
+// Database checks connectivity, a read and the connection pool with
+// HealthCheckAdvanced
+func Database(db *database.DB, logger *logrus.Logger) Check {
+	return func(ctx context.Context) Component {
+		stats := db.GetConnectionStats()
+		details := map[string]interface{}{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+			"wait_count":       stats.WaitCount,
+		}
+		// HealthCheckAdvanced bounds itself; run gives up on it at ctx's deadline
+		if err := db.HealthCheckAdvanced(); err != nil {
+			logger.WithError(err).Warn("Database health check failed")
+			return Component{Status: StatusUnhealthy, Error: "database check failed", Details: details}
+		}
+		return Component{Status: StatusHealthy, Details: details}
+	}
+}
+
+// Migrations checks the schema is at the version of the migrations in path.
+// A dirty schema is unhealthy; pending migrations, after a rollback made
+// while the server runs, leave it degraded.
+func Migrations(db *database.DB, path string, logger *logrus.Logger) Check {
+	return func(ctx context.Context) Component {
+		status, err := database.SchemaStatus(ctx, db.DB, path)
+		if err != nil {
+			logger.WithError(err).Warn("Migration health check failed")
+			return Component{Status: StatusUnhealthy, Error: "migration status unavailable"}
+		}
+
+		pending := status.Pending()
+		details := map[string]interface{}{
+			"version": status.Version,
+			"dirty":   status.Dirty,
+			"pending": len(pending),
+		}
+		switch {
+		case status.Dirty:
+			return Component{Status: StatusUnhealthy, Error: fmt.Sprintf("schema is dirty at version %d", status.Version), Details: details}
+		case len(pending) > 0:
+			return Component{Status: StatusDegraded, Error: fmt.Sprintf("%d migration(s) pending", len(pending)), Details: details}
+		}
+		return Component{Status: StatusHealthy, Details: details}
+	}
+}
+
+// Worker checks the job queue can be reached and has room, and that no job
+// type keeps failing
+func Worker(pool *worker.WorkerPool, logger *logrus.Logger) Check {
+	return func(ctx context.Context) Component {
+		queued, err := pool.QueueLen(ctx)
+		if err != nil {
+			logger.WithError(err).Warn("Job queue health check failed")
+			return Component{Status: StatusUnhealthy, Error: "job queue unreachable"}
+		}
+
+		capacity := pool.QueueCapacity()
+		activity := pool.Activity()
+		details := map[string]interface{}{
+			"queued_jobs":    queued,
+			"queue_capacity": capacity,
+			"job_types":      activity,
+		}
+
+		var problems []string
+		if capacity > 0 && float64(queued) >= queueFullRatio*float64(capacity) {
+			problems = append(problems, fmt.Sprintf("job queue %d%% full", queued*100/capacity))
+		}
+		var failing []string
+		for jobType, outcome := range activity {
+			if outcome.ConsecutiveFailures >= failingJobAttempts {
+				failing = append(failing, jobType)
+			}
+		}
+		if len(failing) > 0 {
+			sort.Strings(failing)
+			problems = append(problems, "failing job types: "+strings.Join(failing, ", "))
+		}
+
+		if len(problems) > 0 {
+			return Component{Status: StatusDegraded, Error: strings.Join(problems, "; "), Details: details}
+		}
+		return Component{Status: StatusHealthy, Details: details}
+	}
+}
+
+// Cache checks the resource cache's Redis can be reached. Reads fall back to
+// the database without it, so an unreachable cache is degraded, not unhealthy.
+// A nil cache is disabled.
+func Cache(resourceCache *cache.ResourceCache, logger *logrus.Logger) Check {
+	return func(ctx context.Context) Component {
+		if resourceCache == nil {
+			return Component{Status: StatusHealthy, Details: map[string]interface{}{"backend": "disabled"}}
+		}
+		details := map[string]interface{}{"backend": "redis"}
+		if err := resourceCache.Ping(ctx); err != nil {
+			logger.WithError(err).Warn("Cache health check failed")
+			return Component{Status: StatusDegraded, Error: "cache unreachable", Details: details}
+		}
+		return Component{Status: StatusHealthy, Details: details}
+	}
+}
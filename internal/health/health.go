@@ -0,0 +1,155 @@
+// Package health checks the components the API depends on, such as the
+// database, job queue and cache, and reports their state for /health.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status of a component, or of the whole service: the worst of its components
+type Status string
+
+const (
+	StatusHealthy   Status = "healthy"
+	StatusDegraded  Status = "degraded"
+	StatusUnhealthy Status = "unhealthy"
+)
+
+const (
+	// checkTimeout bounds each component check; a check that takes longer is unhealthy
+	checkTimeout = 5 * time.Second
+	// reportTTL is how long a report is reused, so frequent probes from
+	// several load balancers cost one round of checks
+	reportTTL = 2 * time.Second
+)
+
+// worse reports whether s is worse than other
+func (s Status) worse(other Status) bool {
+	rank := map[Status]int{StatusHealthy: 0, StatusDegraded: 1, StatusUnhealthy: 2}
+	return rank[s] > rank[other]
+}
+
+// Component is the state of one component. Error is a short description fit
+// for an unauthenticated caller; the full error is logged by the check.
+type Component struct {
+	Status Status `json:"status"`
+	// Required components are needed to serve requests at all
+	Required bool                   `json:"required,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	// Duration of the check in milliseconds
+	Duration int64 `json:"duration_ms"`
+}
+
+// Check reports the state of a component. It must return once ctx is done.
+type Check func(ctx context.Context) Component
+
+// Report is the state of the service and of each component
+type Report struct {
+	Status Status `json:"status"`
+	// Ready is set unless a required component is unhealthy
+	Ready      bool                 `json:"-"`
+	Timestamp  time.Time            `json:"timestamp"`
+	Version    string               `json:"version"`
+	Service    string               `json:"service"`
+	Components map[string]Component `json:"components"`
+}
+
+type namedCheck struct {
+	name     string
+	check    Check
+	required bool
+}
+
+// Checker runs the registered component checks
+type Checker struct {
+	version string
+	checks  []namedCheck
+
+	mu       sync.Mutex
+	last     *Report
+	lastTime time.Time
+}
+
+// NewChecker creates a checker for the given service version
+func NewChecker(version string) *Checker {
+	return &Checker{version: version}
+}
+
+// Register adds a component check. Must be called before Check.
+func (c *Checker) Register(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// RegisterRequired adds the check of a component requests cannot be served
+// without, so the service is not ready while it is unhealthy
+func (c *Checker) RegisterRequired(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check, required: true})
+}
+
+// Check runs every component check concurrently, or returns the report of
+// the last run when it is recent
+func (c *Checker) Check(ctx context.Context) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last != nil && time.Since(c.lastTime) < reportTTL {
+		return c.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	components := make([]Component, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			components[i] = run(ctx, check)
+		}(i, check.check)
+	}
+	wg.Wait()
+
+	report := &Report{
+		Status:     StatusHealthy,
+		Ready:      true,
+		Timestamp:  time.Now().UTC(),
+		Version:    c.version,
+		Service:    "healthcare-api",
+		Components: make(map[string]Component, len(c.checks)),
+	}
+	for i, check := range c.checks {
+		component := components[i]
+		component.Required = check.required
+		report.Components[check.name] = component
+		if component.Status.worse(report.Status) {
+			report.Status = component.Status
+		}
+		if check.required && component.Status == StatusUnhealthy {
+			report.Ready = false
+		}
+	}
+
+	c.last = report
+	c.lastTime = time.Now()
+	return report
+}
+
+// run runs check, treating one that outlives ctx as unhealthy
+func run(ctx context.Context, check Check) Component {
+	start := time.Now()
+	done := make(chan Component, 1)
+	go func() { done <- check(ctx) }()
+
+	var component Component
+	select {
+	case component = <-done:
+	case <-ctx.Done():
+		component = Component{Status: StatusUnhealthy, Error: "check timed out"}
+	}
+	component.Duration = time.Since(start).Milliseconds()
+	return component
+}
@@ -0,0 +1,114 @@
+package hl7v2
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AckCode is the acknowledgement code of MSA-1
+type AckCode string
+
+// Acknowledgement codes
+const (
+	// AckAccept means the message was processed
+	AckAccept AckCode = "AA"
+	// AckError means the message could not be processed, e.g. it names an unknown patient
+	AckError AckCode = "AE"
+	// AckReject means the message was not understood, e.g. it is malformed or of
+	// an unsupported type; resending it unchanged will fail again
+	AckReject AckCode = "AR"
+)
+
+// ErrorCondition is an HL7 table 0357 message error condition code, sent in ERR-3
+type ErrorCondition int
+
+// Error conditions reported in acknowledgements
+const (
+	ErrorSegmentSequence        ErrorCondition = 100
+	ErrorRequiredFieldMissing   ErrorCondition = 101
+	ErrorDataType               ErrorCondition = 102
+	ErrorUnsupportedMessageType ErrorCondition = 200
+	ErrorUnsupportedEventCode   ErrorCondition = 201
+	ErrorUnknownKey             ErrorCondition = 204
+	ErrorDuplicateKey           ErrorCondition = 205
+	ErrorApplicationInternal    ErrorCondition = 207
+)
+
+// errorConditionNames are the table 0357 display names of the error conditions
+var errorConditionNames = map[ErrorCondition]string{
+	ErrorSegmentSequence:        "Segment sequence error",
+	ErrorRequiredFieldMissing:   "Required field missing",
+	ErrorDataType:               "Data type error",
+	ErrorUnsupportedMessageType: "Unsupported message type",
+	ErrorUnsupportedEventCode:   "Unsupported event code",
+	ErrorUnknownKey:             "Unknown key identifier",
+	ErrorDuplicateKey:           "Duplicate key identifier",
+	ErrorApplicationInternal:    "Application internal error",
+}
+
+// Ack builds the acknowledgement of original. original may be nil when the
+// message could not be parsed, in which case the acknowledgement has no control
+// ID to echo. condition and text describe the error of AE and AR
+// acknowledgements and are ignored for AA.
+func Ack(original *Message, code AckCode, condition ErrorCondition, text string, now time.Time) []byte {
+	d := DefaultDelimiters
+	var header *Segment
+	if original != nil {
+		d = original.Delimiters
+		header = original.Header()
+	}
+	field := func(n int) string {
+		if header == nil {
+			return ""
+		}
+		raw, _ := header.raw(n)
+		return raw
+	}
+
+	event := ""
+	if original != nil {
+		_, event = original.Type()
+	}
+	version := field(12)
+	if version == "" {
+		version = "2.5.1"
+	}
+	processingID := field(11)
+	if processingID == "" {
+		processingID = "P"
+	}
+
+	sep := string(d.Field)
+	comp := string(d.Component)
+	// Replies go back to the sender, so the sending and receiving application and
+	// facility are swapped
+	msh := []string{
+		"MSH",
+		string([]byte{d.Component, d.Repetition, d.Escape, d.Subcomponent}),
+		field(5), field(6), field(3), field(4),
+		now.UTC().Format("20060102150405") + "+0000",
+		"",
+		"ACK" + comp + Escape(event, d) + comp + "ACK",
+		newControlID(),
+		processingID,
+		version,
+	}
+	segments := []string{
+		strings.Join(msh, sep),
+		strings.Join([]string{"MSA", string(code), field(10), Escape(text, d)}, sep),
+	}
+	if code != AckAccept && condition != 0 {
+		errorCode := strconv.Itoa(int(condition)) + comp + errorConditionNames[condition] + comp + "HL70357"
+		segments = append(segments, strings.Join([]string{"ERR", "", "", errorCode, "E", "", "", "", Escape(text, d)}, sep))
+	}
+	return []byte(strings.Join(segments, "\r") + "\r")
+}
+
+// newControlID returns a message control ID unique to this acknowledgement,
+// within the 20 characters MSH-10 allows
+func newControlID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")[:20]
+}
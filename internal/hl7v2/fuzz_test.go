@@ -0,0 +1,172 @@
+package hl7v2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"healthcare-api/internal/models"
+)
+
+// Seed messages of the fuzz targets; segments end in CR
+var (
+	adtA04 = "MSH|^~\\&|ADT|HOSP|RDS|RDS|20240115130000||ADT^A04|ADT00001|P|2.5.1\r" +
+		"PID|1||12345^^^HOSP&1.2.840.1&ISO^MR~987-65-4321^^^SSA^SS||Doe^John^Q^^Dr~Johnny^^^^^^N||19800115|M|||" +
+		"1 Main St^Apt 2^Springfield^IL^62701^USA^H||^PRN^PH^^1^555^5551234|^WPN^PH^^1^555^5554321||M|||||||||||||20240110|Y\r"
+	oruR01 = "MSH|^~\\&|LAB|HOSP|RDS|RDS|20240115130000||ORU^R01|LAB00042|P|2.5.1\r" +
+		"PID|1||12345^^^HOSP&1.2.840.1&ISO^MR||Doe^John^Q||19800115|M\r" +
+		"OBR|1|ORD123|FIL456|24323-8^Comprehensive metabolic panel^LN|||20240115120000|||||||||||||||20240115125500||CH|F\r" +
+		"NTE|1||Fasting\r" +
+		"OBX|1|NM|2823-3^Potassium^LN||5.9|mmol/L^mmol/L^UCUM|3.5-5.1|H|||F\r" +
+		"NTE|1||Specimen slightly hemolyzed\r" +
+		"OBX|2|SN|2345-7^Glucose^LN||>^200|mg/dL^mg/dL^UCUM|<100|H|||F|||20240115121500\r" +
+		"OBX|3|CWE|882-1^ABO and Rh group^LN||A+^A Rh positive^L|||N|||F\r" +
+		"OBX|4|ST|8251-1^Comment^LN||See note \\T\\ follow up||||||F\r" +
+		"OBX|5|TM|12345-6^Time^LN||0830||||||F\r" +
+		"OBX|6|NM|2951-2^Sodium^LN||||||||F\r"
+)
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		adtA04,
+		oruR01,
+		strings.ReplaceAll(oruR01, "\r", "\n"),
+		"MSH|^~\\&",
+		"MSH#^~\\&#A#B\rPID#1",
+		"MSH|^~\\&|||||||ACK|1|P|2.5.1\rMSA|AA|1",
+		"PID|1",
+		"",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := Parse(data)
+		if err != nil {
+			if !errors.Is(err, ErrMalformed) {
+				t.Fatalf("Parse error %v is not ErrMalformed", err)
+			}
+			return
+		}
+		if msg.Header() == nil {
+			t.Fatal("parsed message has no MSH segment")
+		}
+		msg.Type()
+		msg.ControlID()
+		for _, segment := range msg.Segments {
+			if len(segment.Name) != 3 {
+				t.Fatalf("segment name %q is not 3 characters", segment.Name)
+			}
+			for n := 0; n <= 6; n++ {
+				for _, field := range segment.Repetitions(n) {
+					_ = field.String() + field.Component(1) + field.Subcomponent(1, 2)
+				}
+			}
+		}
+		Ack(msg, AckError, ErrorDataType, "fuzz", fixedTime)
+	})
+}
+
+func FuzzPatientFromPID(f *testing.F) {
+	for _, seed := range []string{
+		adtA04,
+		"MSH|^~\\&|A|B|C|D|20240115||ADT^A08|1|P|2.5.1\rPID|1||^^^^MR||^||2024|X",
+		"MSH|^~\\&|A|B|C|D|20240115||ADT^A08|1|P|2.5.1\rPID|1||||||notadate",
+		"MSH|^~\\&|A|B|C|D|20240115||ADT^A08|1|P|2.5.1\rPID",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := Parse(data)
+		if err != nil {
+			return
+		}
+		pid := msg.Segment("PID")
+		if pid == nil {
+			return
+		}
+		patient, err := PatientFromPID(pid)
+		if err != nil {
+			return
+		}
+		if _, err := json.Marshal(patient); err != nil {
+			t.Fatalf("converted patient does not encode: %v", err)
+		}
+	})
+}
+
+func FuzzOrdersFromORU(f *testing.F) {
+	for _, seed := range []string{
+		oruR01,
+		"MSH|^~\\&|A|B|C|D|20240115||ORU^R01|1|P|2.5.1\rOBX|1|NM|1^A^LN||1",
+		"MSH|^~\\&|A|B|C|D|20240115||ORU^R01|1|P|2.5.1\rOBR|1|||1^A^LN\rOBX|1|NM|1^A^LN||x",
+		"MSH|^~\\&|A|B|C|D|20240115||ORU^R01|1|P|2.5.1\rOBR|1|||1^A^LN\rOBX|1|SN|1^A^LN||^1^:^2",
+		"MSH|^~\\&|A|B|C|D|20240115||ORU^R01|1|P|2.5.1\rOBR|1|||1^A^LN\rOBX|1|ED|1^A^LN||data",
+		"MSH|^~\\&|A|B|C|D|20240115||ORU^R01|1|P|2.5.1\rPV1|1|I|||||||||||||||||V1^^^H&1.2&ISO^VN\rOBR|1|||1^A^LN\rOBX|1|NM|1^A^LN||1",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := Parse(data)
+		if err != nil {
+			return
+		}
+		orders, err := OrdersFromORU(msg, oruSubject())
+		if err != nil {
+			return
+		}
+		if len(orders) == 0 {
+			t.Fatal("OrdersFromORU returned no orders and no error")
+		}
+		for _, order := range orders {
+			if order.Report == nil {
+				t.Fatal("order has no report")
+			}
+			for _, result := range order.Results {
+				if _, err := json.Marshal(result); err != nil {
+					t.Fatalf("converted observation does not encode: %v", err)
+				}
+			}
+		}
+	})
+}
+
+// oruSubject is the patient the results of ORU messages are converted about
+func oruSubject() models.Reference {
+	reference := "Patient/00000000-0000-0000-0000-000000000001"
+	return models.Reference{Reference: &reference}
+}
+
+func FuzzReadFrame(f *testing.F) {
+	f.Add(frame([]byte(adtA04)))
+	f.Add(append([]byte("noise"), frame([]byte(oruR01))...))
+	f.Add([]byte{mllpStartBlock, 'M', mllpEndBlock, 'x', mllpEndBlock, mllpCarriageReturn})
+	f.Add([]byte{mllpStartBlock, 'M', 'S', 'H'})
+	f.Add([]byte{mllpStartBlock, mllpEndBlock})
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		message, err := readFrame(bufio.NewReader(bytes.NewReader(data)))
+		switch {
+		case err == nil && len(message) > MaxMessageSize:
+			t.Fatalf("frame of %d bytes exceeds MaxMessageSize", len(message))
+		case err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, errFrameTooLarge):
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		// A message without an end block reads back as it was framed
+		if bytes.IndexByte(data, mllpEndBlock) >= 0 || len(data) > MaxMessageSize {
+			return
+		}
+		framed, err := readFrame(bufio.NewReader(bytes.NewReader(frame(data))))
+		if err != nil || !bytes.Equal(framed, data) {
+			t.Fatalf("readFrame(frame(%q)) = %q, %v", data, framed, err)
+		}
+	})
+}
@@ -0,0 +1,278 @@
+// Package hl7v2 parses HL7 v2 messages in their pipe-delimited (ER7) encoding,
+// builds acknowledgements and converts segments into FHIR resources: PID into
+// patients, OBR and OBX into diagnostic reports and observations, and PV1 into
+// a reference to the encounter of the results. The API has no Encounter
+// resource, so a PV1 visit is kept only as that reference; the visits of ADT
+// messages are not stored.
+package hl7v2
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of ER7-encoded HL7 v2 messages
+const ContentType = "x-application/hl7-v2+er7"
+
+// ErrMalformed is returned for data that is not an HL7 v2 message
+var ErrMalformed = errors.New("malformed HL7 v2 message")
+
+// Delimiters are the separators of a message, declared in MSH-1 and MSH-2
+type Delimiters struct {
+	Field        byte
+	Component    byte
+	Repetition   byte
+	Escape       byte
+	Subcomponent byte
+}
+
+// DefaultDelimiters are the separators almost every sender uses, |^~\&
+var DefaultDelimiters = Delimiters{Field: '|', Component: '^', Repetition: '~', Escape: '\\', Subcomponent: '&'}
+
+// Message is a parsed HL7 v2 message
+type Message struct {
+	Segments   []*Segment
+	Delimiters Delimiters
+}
+
+// Segment is one line of a message, e.g. PID
+type Segment struct {
+	Name string
+	// fields holds the raw fields; fields[0] is the segment name
+	fields     []string
+	delimiters *Delimiters
+}
+
+// Field is one repetition of a field
+type Field struct {
+	value      string
+	delimiters *Delimiters
+}
+
+// Parse parses an ER7-encoded message. Segments may end in CR, LF or CRLF.
+func Parse(data []byte) (*Message, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\r")
+	text = strings.ReplaceAll(text, "\n", "\r")
+	text = strings.Trim(text, "\r")
+
+	if len(text) < 8 || !strings.HasPrefix(text, "MSH") {
+		return nil, fmt.Errorf("%w: must start with an MSH segment", ErrMalformed)
+	}
+
+	msg := &Message{Delimiters: Delimiters{Field: text[3]}}
+	encoding := strings.SplitN(text[4:], string(text[3]), 2)[0]
+	if len(encoding) < 4 {
+		return nil, fmt.Errorf("%w: MSH-2 must declare the encoding characters", ErrMalformed)
+	}
+	msg.Delimiters.Component = encoding[0]
+	msg.Delimiters.Repetition = encoding[1]
+	msg.Delimiters.Escape = encoding[2]
+	msg.Delimiters.Subcomponent = encoding[3]
+
+	for _, line := range strings.Split(text, "\r") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, string(msg.Delimiters.Field))
+		if len(fields[0]) != 3 {
+			return nil, fmt.Errorf("%w: invalid segment name %q", ErrMalformed, fields[0])
+		}
+		msg.Segments = append(msg.Segments, &Segment{Name: fields[0], fields: fields, delimiters: &msg.Delimiters})
+	}
+	return msg, nil
+}
+
+// Segment returns the first segment named name, or nil
+func (m *Message) Segment(name string) *Segment {
+	for _, segment := range m.Segments {
+		if segment.Name == name {
+			return segment
+		}
+	}
+	return nil
+}
+
+// SegmentsNamed returns every segment named name, in order
+func (m *Message) SegmentsNamed(name string) []*Segment {
+	var segments []*Segment
+	for _, segment := range m.Segments {
+		if segment.Name == name {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// Header returns the MSH segment
+func (m *Message) Header() *Segment {
+	return m.Segment("MSH")
+}
+
+// Type returns the message code and trigger event of MSH-9, e.g. "ADT" and "A01"
+func (m *Message) Type() (code, event string) {
+	header := m.Header()
+	if header == nil {
+		return "", ""
+	}
+	messageType := header.Field(9)
+	return messageType.Component(1), messageType.Component(2)
+}
+
+// ControlID returns MSH-10, which acknowledgements echo
+func (m *Message) ControlID() string {
+	if header := m.Header(); header != nil {
+		return header.Field(10).String()
+	}
+	return ""
+}
+
+// Field returns the first repetition of field n, counting from 1. In MSH, field
+// 1 is the field separator itself.
+func (s *Segment) Field(n int) Field {
+	repetitions := s.Repetitions(n)
+	if len(repetitions) == 0 {
+		return Field{delimiters: s.delimiters}
+	}
+	return repetitions[0]
+}
+
+// Repetitions returns every repetition of field n
+func (s *Segment) Repetitions(n int) []Field {
+	raw, ok := s.raw(n)
+	if !ok || raw == "" {
+		return nil
+	}
+	// MSH-2 holds the repetition separator and is never split
+	if s.Name == "MSH" && n <= 2 {
+		return []Field{{value: raw, delimiters: s.delimiters}}
+	}
+	var fields []Field
+	for _, value := range strings.Split(raw, string(s.delimiters.Repetition)) {
+		fields = append(fields, Field{value: value, delimiters: s.delimiters})
+	}
+	return fields
+}
+
+// raw returns field n as sent
+func (s *Segment) raw(n int) (string, bool) {
+	if s.Name == "MSH" {
+		// MSH-1 is the separator the fields were split on, so MSH-n is fields[n-1]
+		if n == 1 {
+			return string(s.delimiters.Field), true
+		}
+		n--
+	}
+	if n < 1 || n >= len(s.fields) {
+		return "", false
+	}
+	return s.fields[n], true
+}
+
+// String returns the field's unescaped value, with any components left joined
+func (f Field) String() string {
+	return unescape(f.value, f.delimiters)
+}
+
+// IsEmpty reports whether the field has no value
+func (f Field) IsEmpty() bool {
+	return f.value == ""
+}
+
+// Component returns component n of the field, counting from 1, with any
+// subcomponents left joined
+func (f Field) Component(n int) string {
+	return unescape(f.rawComponent(n), f.delimiters)
+}
+
+// Subcomponent returns subcomponent sub of component n
+func (f Field) Subcomponent(n, sub int) string {
+	parts := strings.Split(f.rawComponent(n), string(f.delimiters.Subcomponent))
+	if sub < 1 || sub > len(parts) {
+		return ""
+	}
+	return unescape(parts[sub-1], f.delimiters)
+}
+
+func (f Field) rawComponent(n int) string {
+	if f.delimiters == nil {
+		return ""
+	}
+	parts := strings.Split(f.value, string(f.delimiters.Component))
+	if n < 1 || n > len(parts) {
+		return ""
+	}
+	return parts[n-1]
+}
+
+// unescape replaces the escape sequences of value: \F\, \S\, \T\, \R\ and \E\
+// for the delimiters, \.br\ for a line break and \Xhh..\ for hexadecimal bytes.
+// Other sequences, such as formatting commands, are dropped.
+func unescape(value string, d *Delimiters) string {
+	if d == nil || strings.IndexByte(value, d.Escape) < 0 {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != d.Escape {
+			b.WriteByte(value[i])
+			continue
+		}
+		end := strings.IndexByte(value[i+1:], d.Escape)
+		if end < 0 {
+			b.WriteString(value[i:])
+			break
+		}
+		sequence := value[i+1 : i+1+end]
+		i += end + 1
+
+		switch {
+		case sequence == "F":
+			b.WriteByte(d.Field)
+		case sequence == "S":
+			b.WriteByte(d.Component)
+		case sequence == "T":
+			b.WriteByte(d.Subcomponent)
+		case sequence == "R":
+			b.WriteByte(d.Repetition)
+		case sequence == "E":
+			b.WriteByte(d.Escape)
+		case sequence == ".br":
+			b.WriteByte('\n')
+		case strings.HasPrefix(sequence, "X"):
+			hex := sequence[1:]
+			for j := 0; j+1 < len(hex); j += 2 {
+				if c, err := strconv.ParseUint(hex[j:j+2], 16, 8); err == nil {
+					b.WriteByte(byte(c))
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// Escape escapes the delimiters in value so it can be sent as a field value
+func Escape(value string, d Delimiters) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case d.Escape:
+			b.WriteString(string(d.Escape) + "E" + string(d.Escape))
+		case d.Field:
+			b.WriteString(string(d.Escape) + "F" + string(d.Escape))
+		case d.Component:
+			b.WriteString(string(d.Escape) + "S" + string(d.Escape))
+		case d.Subcomponent:
+			b.WriteString(string(d.Escape) + "T" + string(d.Escape))
+		case d.Repetition:
+			b.WriteString(string(d.Escape) + "R" + string(d.Escape))
+		case '\r', '\n':
+			b.WriteString(string(d.Escape) + ".br" + string(d.Escape))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
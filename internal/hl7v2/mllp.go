@@ -0,0 +1,333 @@
+package hl7v2
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// MLLP frame delimiters: a message is sent as <VT> message <FS><CR>
+const (
+	mllpStartBlock     = 0x0b
+	mllpEndBlock       = 0x1c
+	mllpCarriageReturn = 0x0d
+)
+
+// MaxMessageSize bounds the messages accepted from any transport
+const MaxMessageSize = 1 << 20
+
+// errFrameTooLarge is returned for frames over MaxMessageSize
+var errFrameTooLarge = errors.New("MLLP frame exceeds the maximum message size")
+
+// MessageHandler applies a message and returns its acknowledgement
+type MessageHandler func(ctx context.Context, message []byte) []byte
+
+// MLLPServer receives HL7 v2 messages over the Minimal Lower Layer Protocol, the
+// TCP framing most interface engines and lab systems push with. Each message
+// is acknowledged on the connection it arrived on, in order.
+type MLLPServer struct {
+	addr        string
+	tenantID    string
+	allowed     []*net.IPNet
+	idleTimeout time.Duration
+	handler     MessageHandler
+	logger      *logrus.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closing  bool
+	wg       sync.WaitGroup
+}
+
+// NewMLLPServer creates an MLLP server applying messages with handler
+func NewMLLPServer(cfg config.HL7Config, handler MessageHandler, logger *logrus.Logger) (*MLLPServer, error) {
+	if cfg.MLLPTenant == "" {
+		return nil, errors.New("HL7_MLLP_TENANT is required for the MLLP listener")
+	}
+	allowed, err := parseCIDRs(cfg.MLLPAllowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	return &MLLPServer{
+		addr:        fmt.Sprintf(":%d", cfg.MLLPPort),
+		tenantID:    cfg.MLLPTenant,
+		allowed:     allowed,
+		idleTimeout: time.Duration(cfg.MLLPIdleTimeout) * time.Second,
+		handler:     handler,
+		logger:      logger,
+		conns:       make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// Start listens on the configured port and serves connections in the background
+func (s *MLLPServer) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for MLLP: %w", err)
+	}
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.serve(listener)
+	s.logger.WithField("addr", s.addr).Info("MLLP listener started")
+	return nil
+}
+
+// Shutdown stops accepting connections and waits for messages being processed
+// to be acknowledged, closing idle connections. Connections still busy when ctx
+// is done are closed.
+func (s *MLLPServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	// Wake connections waiting for their next message
+	for conn := range s.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (s *MLLPServer) serve(listener net.Listener) {
+	defer s.wg.Done()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.logger.WithError(err).Error("Failed to accept MLLP connection")
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		if !s.isAllowed(conn.RemoteAddr()) {
+			s.logger.WithField("remote_addr", conn.RemoteAddr().String()).Warn("Rejected MLLP connection from an address not allowed")
+			conn.Close()
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closing {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn reads and acknowledges the messages of one connection until the
+// sender closes it, it stays idle too long or the server shuts down
+func (s *MLLPServer) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	remote := conn.RemoteAddr().String()
+	logger := s.logger.WithField("remote_addr", remote)
+	reader := bufio.NewReader(conn)
+	for {
+		if s.idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.idleTimeout))
+		}
+		s.mu.Lock()
+		closing := s.closing
+		s.mu.Unlock()
+		if closing {
+			return
+		}
+
+		message, err := readFrame(reader)
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case errors.Is(err, io.EOF), errors.As(err, &netErr) && netErr.Timeout(), errors.Is(err, net.ErrClosed):
+			case errors.Is(err, errFrameTooLarge):
+				conn.Write(frame(Ack(nil, AckReject, ErrorSegmentSequence, "Message is larger than 1 MiB", time.Now())))
+				logger.Warn("Closed MLLP connection sending an oversized message")
+			default:
+				logger.WithError(err).Warn("Closed MLLP connection after a read error")
+			}
+			return
+		}
+
+		ctx := requestctx.WithTenantID(context.Background(), s.tenantID)
+		ctx = requestctx.WithRequestID(ctx, uuid.New().String())
+		ctx = requestctx.WithUserID(ctx, "mllp:"+hostOf(conn.RemoteAddr()))
+		ack := s.handle(ctx, logger, message)
+
+		conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+		if _, err := conn.Write(frame(ack)); err != nil {
+			logger.WithError(err).Warn("Failed to send MLLP acknowledgement")
+			return
+		}
+	}
+}
+
+// handle applies a message with the server's handler, recovering a panic so a
+// malformed message cannot take the server down. The sender is told the
+// message was rejected, so it is not resent as though the error were passing.
+func (s *MLLPServer) handle(ctx context.Context, logger *logrus.Entry, message []byte) (ack []byte) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.WithFields(logrus.Fields{
+				"panic": fmt.Sprint(recovered),
+				"stack": string(debug.Stack()),
+			}).Error("HL7 v2 message handler panicked")
+			ack = internalErrorAck(message)
+		}
+	}()
+
+	return s.handler(ctx, message)
+}
+
+// internalErrorAck builds the AR acknowledgement of a message whose handler
+// panicked. The message may be what made it panic, so if parsing or
+// acknowledging it panics too, the acknowledgement is built without it.
+func internalErrorAck(message []byte) (ack []byte) {
+	const text = "Message could not be processed"
+	defer func() {
+		if recover() != nil {
+			ack = Ack(nil, AckReject, ErrorApplicationInternal, text, time.Now())
+		}
+	}()
+
+	original, _ := Parse(message)
+	return Ack(original, AckReject, ErrorApplicationInternal, text, time.Now())
+}
+
+// readFrame reads the next MLLP frame, skipping any bytes before its start block
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == mllpStartBlock {
+			break
+		}
+	}
+
+	var message []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if b == mllpEndBlock {
+			next, err := reader.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if next == mllpCarriageReturn {
+				return message, nil
+			}
+			message = append(message, b, next)
+		} else {
+			message = append(message, b)
+		}
+		if len(message) > MaxMessageSize {
+			return nil, errFrameTooLarge
+		}
+	}
+}
+
+// frame wraps a message in MLLP start and end blocks
+func frame(message []byte) []byte {
+	framed := make([]byte, 0, len(message)+3)
+	framed = append(framed, mllpStartBlock)
+	framed = append(framed, message...)
+	return append(framed, mllpEndBlock, mllpCarriageReturn)
+}
+
+// isAllowed reports whether a connection from addr is accepted
+func (s *MLLPServer) isAllowed(addr net.Addr) bool {
+	if len(s.allowed) == 0 {
+		return true
+	}
+	ip := net.ParseIP(hostOf(addr))
+	for _, network := range s.allowed {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCIDRs parses addresses and CIDRs; a bare address allows only itself
+func parseCIDRs(values []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid MLLP allowed address %q", value)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			value = fmt.Sprintf("%s/%d", value, bits)
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MLLP allowed CIDR %q: %w", value, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// hostOf returns the IP address of addr without its port
+func hostOf(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
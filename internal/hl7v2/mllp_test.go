@@ -0,0 +1,43 @@
+package hl7v2
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var fixedTime = time.Date(2024, 1, 15, 13, 0, 0, 0, time.UTC)
+
+func TestHandleRecoversFromHandlerPanic(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	server := &MLLPServer{
+		handler: func(context.Context, []byte) []byte { panic("handler failed") },
+		logger:  logger,
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		wantMSA string
+	}{
+		{"parsable message", oruR01, "MSA|AR|LAB00042|"},
+		{"unparsable message", "not HL7", "MSA|AR||"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack := string(server.handle(context.Background(), logrus.NewEntry(logger), []byte(tt.message)))
+			if !strings.Contains(ack, "\r"+tt.wantMSA) {
+				t.Errorf("acknowledgement %q has no %q", ack, tt.wantMSA)
+			}
+			if !strings.Contains(ack, "\rERR|||207^") {
+				t.Errorf("acknowledgement %q has no application internal error", ack)
+			}
+		})
+	}
+}
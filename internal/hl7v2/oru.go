@@ -0,0 +1,537 @@
+package hl7v2
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+)
+
+// ErrInvalidValue is wrapped by errors for field values that do not match their
+// data type, such as a non-numeric NM result
+var ErrInvalidValue = errors.New("invalid HL7 v2 field value")
+
+// FHIR code systems used when converting results
+const (
+	observationCategorySystem = "http://terminology.hl7.org/CodeSystem/observation-category"
+	serviceSectionSystem      = "http://terminology.hl7.org/CodeSystem/v2-0074"
+	interpretationSystem      = "http://terminology.hl7.org/CodeSystem/v3-ObservationInterpretation"
+	dataAbsentReasonSystem    = "http://terminology.hl7.org/CodeSystem/data-absent-reason"
+	ucumSystem                = "http://unitsofmeasure.org"
+	// localCodingSystem prefixes coding systems without a FHIR URI, e.g.
+	// urn:healthcare-api:hl7v2:coding-system:99LAB
+	localCodingSystem = "urn:healthcare-api:hl7v2:coding-system:"
+)
+
+// codingSystems maps HL7 table 0396 coding system names to FHIR code system URIs
+var codingSystems = map[string]string{
+	"LN":   "http://loinc.org",
+	"SCT":  "http://snomed.info/sct",
+	"UCUM": ucumSystem,
+	"I10":  "http://hl7.org/fhir/sid/icd-10",
+	"I9C":  "http://hl7.org/fhir/sid/icd-9-cm",
+}
+
+// resultStatuses maps HL7 table 0085 observation result statuses to FHIR observation status
+var resultStatuses = map[string]string{
+	"C": "corrected",
+	"D": "entered-in-error",
+	"F": "final",
+	"I": "registered",
+	"N": "cancelled",
+	"P": "preliminary",
+	"R": "preliminary",
+	"S": "preliminary",
+	"U": "final",
+	"W": "entered-in-error",
+	"X": "cancelled",
+}
+
+// reportStatuses maps HL7 table 0123 result statuses to FHIR diagnostic report status
+var reportStatuses = map[string]string{
+	"A": "partial",
+	"C": "corrected",
+	"F": "final",
+	"I": "registered",
+	"O": "registered",
+	"P": "preliminary",
+	"R": "partial",
+	"S": "registered",
+	"X": "cancelled",
+}
+
+// interpretations are the HL7 table 0078 abnormal flags that are also
+// v3-ObservationInterpretation codes, with their display names
+var interpretations = map[string]string{
+	"L":   "Low",
+	"H":   "High",
+	"LL":  "Critical low",
+	"HH":  "Critical high",
+	"<":   "Off scale low",
+	">":   "Off scale high",
+	"N":   "Normal",
+	"A":   "Abnormal",
+	"AA":  "Critical abnormal",
+	"U":   "Significant change up",
+	"D":   "Significant change down",
+	"B":   "Better",
+	"W":   "Worse",
+	"S":   "Susceptible",
+	"R":   "Resistant",
+	"I":   "Intermediate",
+	"NEG": "Negative",
+	"POS": "Positive",
+	"IND": "Indeterminate",
+}
+
+// structuredComparators are the SN comparators that are also FHIR quantity comparators
+var structuredComparators = map[string]bool{"<": true, "<=": true, ">": true, ">=": true}
+
+// Order is an OBR segment of a result message converted into a diagnostic
+// report, with the OBX results that follow it converted into observations
+type Order struct {
+	Report  *models.DiagnosticReportCreateRequest
+	Results []*models.ObservationCreateRequest
+}
+
+// OrdersFromORU converts the OBR groups of an ORU^R01 message into reports and
+// observations about subject. OBX results carry their value by OBX-2 type,
+// units, reference range, abnormal flags as interpretations, status and time;
+// NTE notes are added to the result they follow, or to the report conclusion
+// when they follow the OBR. A PV1 visit becomes the encounter of the reports
+// and results after it. Other segments, such as ORC and SPM, are ignored.
+func OrdersFromORU(msg *Message, subject models.Reference) ([]*Order, error) {
+	var orders []*Order
+	var current *Order
+	var lastResult *models.ObservationCreateRequest
+	var encounter *models.Reference
+
+	for i, segment := range msg.Segments {
+		position := func(err error) error {
+			return fmt.Errorf("segment %d (%s): %w", i+1, segment.Name, err)
+		}
+
+		switch segment.Name {
+		case "PV1":
+			encounter = EncounterFromPV1(segment)
+		case "OBR":
+			report, err := reportFromOBR(segment, subject)
+			if err != nil {
+				return nil, position(err)
+			}
+			report.Encounter = encounter
+			current = &Order{Report: report}
+			lastResult = nil
+			orders = append(orders, current)
+		case "OBX":
+			if current == nil {
+				return nil, position(errors.New("OBX must follow an OBR segment"))
+			}
+			result, err := observationFromOBX(segment, current.Report)
+			if err != nil {
+				return nil, position(err)
+			}
+			current.Results = append(current.Results, result)
+			lastResult = result
+		case "NTE":
+			text := strings.Join(fieldStrings(segment.Repetitions(3)), "\n")
+			switch {
+			case text == "" || current == nil:
+			case lastResult != nil:
+				lastResult.Note = append(lastResult.Note, models.Annotation{Text: text})
+			case current.Report.Conclusion != nil:
+				conclusion := *current.Report.Conclusion + "\n" + text
+				current.Report.Conclusion = &conclusion
+			default:
+				current.Report.Conclusion = &text
+			}
+		}
+	}
+
+	if len(orders) == 0 {
+		return nil, errors.New("message has no OBR segment")
+	}
+	return orders, nil
+}
+
+// reportFromOBR converts an OBR segment. OBR-2 and OBR-3 become placer and
+// filler identifiers, OBR-4 the code, OBR-7 the effective time, OBR-22 the
+// issued time, OBR-24 the category and OBR-25 the status.
+func reportFromOBR(obr *Segment, subject models.Reference) (*models.DiagnosticReportCreateRequest, error) {
+	code := codeableConcept(obr.Field(4))
+	if code == nil {
+		return nil, errors.New("OBR-4 universal service identifier is required")
+	}
+
+	report := &models.DiagnosticReportCreateRequest{
+		Status:  "unknown",
+		Code:    *code,
+		Subject: subject,
+	}
+	for _, id := range []struct {
+		field    int
+		typeCode string
+	}{{2, "PLAC"}, {3, "FILL"}} {
+		if identifier := entityIdentifier(obr.Field(id.field), id.typeCode); identifier != nil {
+			report.Identifier = append(report.Identifier, *identifier)
+		}
+	}
+
+	if status, ok := reportStatuses[strings.ToUpper(obr.Field(25).String())]; ok {
+		report.Status = status
+	}
+
+	var err error
+	if report.EffectiveDateTime, err = optionalTime(obr.Field(7), "OBR-7"); err != nil {
+		return nil, err
+	}
+	if report.Issued, err = optionalTime(obr.Field(22), "OBR-22"); err != nil {
+		return nil, err
+	}
+
+	if section := obr.Field(24).Component(1); section != "" {
+		report.Category = []models.CodeableConcept{{
+			Coding: []models.Coding{{System: stringPtr(serviceSectionSystem), Code: &section}},
+		}}
+	}
+	return report, nil
+}
+
+// observationFromOBX converts an OBX result of report. OBX-3 becomes the code,
+// OBX-5 the value of the OBX-2 type in OBX-6 units, OBX-7 the reference range,
+// OBX-8 the interpretation, OBX-11 the status and OBX-14 the effective time,
+// defaulting to the report's. It shares the report's encounter.
+func observationFromOBX(obx *Segment, report *models.DiagnosticReportCreateRequest) (*models.ObservationCreateRequest, error) {
+	code := codeableConcept(obx.Field(3))
+	if code == nil {
+		return nil, errors.New("OBX-3 observation identifier is required")
+	}
+
+	observation := &models.ObservationCreateRequest{
+		Status: "unknown",
+		Category: []models.CodeableConcept{{
+			Coding: []models.Coding{{System: stringPtr(observationCategorySystem), Code: stringPtr("laboratory"), Display: stringPtr("Laboratory")}},
+		}},
+		Code:              *code,
+		Subject:           report.Subject,
+		Encounter:         report.Encounter,
+		EffectiveDateTime: report.EffectiveDateTime,
+		Issued:            report.Issued,
+	}
+	if status, ok := resultStatuses[strings.ToUpper(obx.Field(11).String())]; ok {
+		observation.Status = status
+	}
+
+	effective, err := optionalTime(obx.Field(14), "OBX-14")
+	if err != nil {
+		return nil, err
+	}
+	if effective != nil {
+		observation.EffectiveDateTime = effective
+	}
+
+	unit := quantityUnit(obx.Field(6))
+	if err := setValue(observation, obx, unit); err != nil {
+		return nil, err
+	}
+
+	if rng := referenceRange(obx.Field(7).String(), unit); rng != nil {
+		observation.ReferenceRange = []models.ObservationReferenceRange{*rng}
+	}
+
+	for _, flag := range obx.Repetitions(8) {
+		if interpretation := interpretation(flag); interpretation != nil {
+			observation.Interpretation = append(observation.Interpretation, *interpretation)
+		}
+	}
+	return observation, nil
+}
+
+// setValue sets the value of observation from OBX-5 according to the OBX-2 value
+// type. A result without a value gets a data absent reason instead.
+func setValue(observation *models.ObservationCreateRequest, obx *Segment, unit models.Quantity) error {
+	values := obx.Repetitions(5)
+	if len(values) == 0 {
+		reason := "unknown"
+		if observation.Status == "cancelled" {
+			reason = "not-performed"
+		}
+		observation.DataAbsentReason = &models.CodeableConcept{
+			Coding: []models.Coding{{System: stringPtr(dataAbsentReasonSystem), Code: &reason}},
+		}
+		return nil
+	}
+	value := values[0]
+
+	switch valueType := strings.ToUpper(obx.Field(2).String()); valueType {
+	case "NM":
+		number, err := parseNumber(value.String(), "OBX-5")
+		if err != nil {
+			return err
+		}
+		quantity := unit
+		quantity.Value = &number
+		observation.ValueQuantity = &quantity
+	case "SN":
+		return setStructuredNumeric(observation, value, unit)
+	case "ST", "TX", "FT":
+		text := strings.Join(fieldStrings(values), "\n")
+		observation.ValueString = &text
+	case "CE", "CWE", "CNE":
+		observation.ValueCodeableConcept = codeableConcept(value)
+	case "DT", "TS", "DTM":
+		t, err := ParseTime(value.Component(1))
+		if err != nil {
+			return fmt.Errorf("%w: OBX-5: %v", ErrInvalidValue, err)
+		}
+		observation.ValueDateTime = &t
+	case "TM":
+		t, err := parseTimeOfDay(value.String())
+		if err != nil {
+			return err
+		}
+		observation.ValueTime = &t
+	default:
+		return fmt.Errorf("%w OBX-2 value type %q", models.ErrUnsupported, valueType)
+	}
+	return nil
+}
+
+// setStructuredNumeric sets an SN value, comparator^num1^separator^num2: a
+// quantity, such as >^200, a range for "-" or a ratio for ":" and "/". Other
+// forms, such as ^2^+, are kept as text.
+func setStructuredNumeric(observation *models.ObservationCreateRequest, sn Field, unit models.Quantity) error {
+	comparator := sn.Component(1)
+	separator := sn.Component(3)
+
+	switch {
+	case separator == "" && (comparator == "" || comparator == "=" || structuredComparators[comparator]):
+		number, err := parseNumber(sn.Component(2), "OBX-5.2")
+		if err != nil {
+			return err
+		}
+		quantity := unit
+		quantity.Value = &number
+		if structuredComparators[comparator] {
+			quantity.Comparator = &comparator
+		}
+		observation.ValueQuantity = &quantity
+	case separator == "-" && comparator == "":
+		low, err := parseNumber(sn.Component(2), "OBX-5.2")
+		if err != nil {
+			return err
+		}
+		high, err := parseNumber(sn.Component(4), "OBX-5.4")
+		if err != nil {
+			return err
+		}
+		lowQuantity, highQuantity := unit, unit
+		lowQuantity.Value, highQuantity.Value = &low, &high
+		observation.ValueRange = &models.Range{Low: &lowQuantity, High: &highQuantity}
+	case (separator == ":" || separator == "/") && comparator == "":
+		numerator, err := parseNumber(sn.Component(2), "OBX-5.2")
+		if err != nil {
+			return err
+		}
+		denominator, err := parseNumber(sn.Component(4), "OBX-5.4")
+		if err != nil {
+			return err
+		}
+		observation.ValueRatio = &models.Ratio{
+			Numerator:   &models.Quantity{Value: &numerator},
+			Denominator: &models.Quantity{Value: &denominator},
+		}
+	default:
+		text := comparator + sn.Component(2) + separator + sn.Component(4)
+		observation.ValueString = &text
+	}
+	return nil
+}
+
+// quantityUnit converts OBX-6 units into a quantity without a value. UCUM units
+// are carried as a coded unit; others only as unit text.
+func quantityUnit(units Field) models.Quantity {
+	var quantity models.Quantity
+	code, text := units.Component(1), units.Component(2)
+	if text == "" {
+		text = code
+	}
+	if text != "" {
+		quantity.Unit = &text
+	}
+	if strings.EqualFold(units.Component(3), "UCUM") && code != "" {
+		quantity.System = stringPtr(ucumSystem)
+		quantity.Code = &code
+	}
+	return quantity
+}
+
+// referenceRange converts an OBX-7 range, such as "3.5-5.0", ">10" or "<=0.4",
+// into low and high bounds in unit. Ranges in other forms keep only their text.
+func referenceRange(text string, unit models.Quantity) *models.ObservationReferenceRange {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	rng := &models.ObservationReferenceRange{Text: &text}
+	bound := func(value string) *models.Quantity {
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil
+		}
+		quantity := unit
+		quantity.Value = &number
+		return &quantity
+	}
+
+	switch {
+	case strings.HasPrefix(text, ">"):
+		rng.Low = bound(strings.TrimLeft(text, ">="))
+	case strings.HasPrefix(text, "<"):
+		rng.High = bound(strings.TrimLeft(text, "<="))
+	default:
+		// Skip the first character so a negative low bound is not taken as the separator
+		if i := strings.Index(text[1:], "-"); i >= 0 {
+			low, high := bound(text[:i+1]), bound(text[i+2:])
+			if low != nil && high != nil {
+				rng.Low, rng.High = low, high
+			}
+		}
+	}
+	return rng
+}
+
+// interpretation converts an OBX-8 abnormal flag. Flags that are not
+// v3-ObservationInterpretation codes keep only their text.
+func interpretation(flag Field) *models.CodeableConcept {
+	code := strings.TrimSpace(flag.Component(1))
+	if code == "" {
+		return nil
+	}
+	display, ok := interpretations[strings.ToUpper(code)]
+	if !ok {
+		return &models.CodeableConcept{Text: &code}
+	}
+	code = strings.ToUpper(code)
+	return &models.CodeableConcept{
+		Coding: []models.Coding{{System: stringPtr(interpretationSystem), Code: &code, Display: &display}},
+	}
+}
+
+// codeableConcept converts a CE or CWE field: identifier^text^coding system,
+// followed by an alternate identifier, text and coding system
+func codeableConcept(cwe Field) *models.CodeableConcept {
+	concept := &models.CodeableConcept{}
+	for _, first := range []int{1, 4} {
+		code := cwe.Component(first)
+		if code == "" {
+			continue
+		}
+		coding := models.Coding{Code: &code}
+		if display := cwe.Component(first + 1); display != "" {
+			coding.Display = &display
+		}
+		if system := codingSystem(cwe.Component(first + 2)); system != "" {
+			coding.System = &system
+		}
+		concept.Coding = append(concept.Coding, coding)
+	}
+	if text := cwe.Component(2); text != "" {
+		concept.Text = &text
+	}
+	if len(concept.Coding) == 0 && concept.Text == nil {
+		return nil
+	}
+	return concept
+}
+
+// codingSystem returns the URI of an HL7 table 0396 coding system name
+func codingSystem(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+	if system, ok := codingSystems[strings.ToUpper(name)]; ok {
+		return system
+	}
+	if table := strings.TrimPrefix(strings.ToUpper(name), "HL7"); table != strings.ToUpper(name) && len(table) == 4 {
+		return "http://terminology.hl7.org/CodeSystem/v2-" + table
+	}
+	return localCodingSystem + name
+}
+
+// entityIdentifier converts an EI order number, entity^namespace^universal ID^type,
+// into an identifier of the given v2-0203 type
+func entityIdentifier(ei Field, typeCode string) *models.Identifier {
+	value := ei.Component(1)
+	if value == "" {
+		return nil
+	}
+	identifier := &models.Identifier{
+		Value: &value,
+		Type: &models.CodeableConcept{
+			Coding: []models.Coding{{System: stringPtr(identifierTypeSystem), Code: stringPtr(typeCode)}},
+		},
+	}
+	namespace, universalID := ei.Component(2), ei.Component(3)
+	switch {
+	case universalID != "" && strings.EqualFold(ei.Component(4), "ISO"):
+		identifier.System = stringPtr("urn:oid:" + universalID)
+	case universalID != "" && strings.EqualFold(ei.Component(4), "URI"):
+		identifier.System = &universalID
+	case namespace != "":
+		identifier.System = stringPtr(assigningAuthoritySystem + namespace)
+	}
+	return identifier
+}
+
+// optionalTime parses a DTM field that may be empty
+func optionalTime(field Field, name string) (*time.Time, error) {
+	if field.IsEmpty() {
+		return nil, nil
+	}
+	t, err := ParseTime(field.Component(1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidValue, name, err)
+	}
+	return &t, nil
+}
+
+// parseNumber parses an NM value
+func parseNumber(value, name string) (float64, error) {
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %q is not a number", ErrInvalidValue, name, value)
+	}
+	return number, nil
+}
+
+// parseTimeOfDay converts a TM value, HH[MM[SS]], into a FHIR time
+func parseTimeOfDay(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexAny(value, ".+-"); i >= 0 {
+		value = value[:i]
+	}
+	if len(value)%2 != 0 || len(value) < 2 || len(value) > 6 {
+		return "", fmt.Errorf("%w: OBX-5: invalid time %q", ErrInvalidValue, value)
+	}
+	if _, err := strconv.Atoi(value); err != nil {
+		return "", fmt.Errorf("%w: OBX-5: invalid time %q", ErrInvalidValue, value)
+	}
+	value += strings.Repeat("0", 6-len(value))
+	return value[0:2] + ":" + value[2:4] + ":" + value[4:6], nil
+}
+
+// fieldStrings returns the values of fields, skipping empty ones
+func fieldStrings(fields []Field) []string {
+	var values []string
+	for _, field := range fields {
+		if value := field.String(); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,302 @@
+package hl7v2
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+)
+
+// FHIR code systems used when converting HL7 v2 tables
+const (
+	identifierTypeSystem = "http://terminology.hl7.org/CodeSystem/v2-0203"
+	maritalStatusSystem  = "http://terminology.hl7.org/CodeSystem/v3-MaritalStatus"
+	// assigningAuthoritySystem prefixes the namespace of assigning authorities
+	// without a universal ID, e.g. urn:healthcare-api:hl7v2:assigning-authority:HOSP
+	assigningAuthoritySystem = "urn:healthcare-api:hl7v2:assigning-authority:"
+)
+
+// administrativeGenders maps HL7 table 0001 to FHIR administrative gender
+var administrativeGenders = map[string]string{
+	"M": "male",
+	"F": "female",
+	"O": "other",
+	"A": "other",
+	"N": "other",
+	"U": "unknown",
+}
+
+// nameUses maps HL7 table 0200 name types to FHIR name use
+var nameUses = map[string]string{
+	"L": "official",
+	"D": "usual",
+	"A": "usual",
+	"N": "nickname",
+	"M": "maiden",
+	"S": "anonymous",
+	"U": "temp",
+}
+
+// addressUses maps HL7 table 0190 address types to FHIR address use
+var addressUses = map[string]string{
+	"H":  "home",
+	"B":  "work",
+	"O":  "work",
+	"C":  "temp",
+	"BA": "billing",
+}
+
+// phoneUses maps HL7 table 0201 telecommunication use codes to FHIR contact point use
+var phoneUses = map[string]string{
+	"PRN": "home",
+	"ORN": "home",
+	"VHN": "home",
+	"WPN": "work",
+	"PRS": "mobile",
+}
+
+// maritalStatuses are the HL7 table 0002 codes that are also FHIR marital status codes
+var maritalStatuses = map[string]bool{
+	"A": true, "D": true, "I": true, "L": true, "M": true,
+	"P": true, "S": true, "T": true, "U": true, "W": true,
+}
+
+// PatientFromPID converts a PID segment into a patient. PID-3 identifiers, PID-5
+// names, PID-7 birth date, PID-8 sex, PID-11 addresses, PID-13 and PID-14 phone
+// numbers, PID-16 marital status and PID-29/PID-30 death are carried over; other
+// fields are ignored.
+func PatientFromPID(pid *Segment) (*models.PatientCreateRequest, error) {
+	patient := &models.PatientCreateRequest{
+		Identifier: Identifiers(pid.Repetitions(3)),
+	}
+
+	for _, name := range pid.Repetitions(5) {
+		if converted := humanName(name); converted != nil {
+			patient.Name = append(patient.Name, *converted)
+		}
+	}
+
+	if birth := pid.Field(7); !birth.IsEmpty() {
+		birthDate, err := ParseTime(birth.Component(1))
+		if err != nil {
+			return nil, fmt.Errorf("PID-7: %w", err)
+		}
+		patient.BirthDate = &birthDate
+	}
+
+	if gender, ok := administrativeGenders[strings.ToUpper(pid.Field(8).Component(1))]; ok {
+		patient.Gender = &gender
+	}
+
+	for _, address := range pid.Repetitions(11) {
+		if converted := postalAddress(address); converted != nil {
+			patient.Address = append(patient.Address, *converted)
+		}
+	}
+
+	for _, phone := range pid.Repetitions(13) {
+		if converted := contactPoint(phone, "home"); converted != nil {
+			patient.Telecom = append(patient.Telecom, *converted)
+		}
+	}
+	for _, phone := range pid.Repetitions(14) {
+		if converted := contactPoint(phone, "work"); converted != nil {
+			patient.Telecom = append(patient.Telecom, *converted)
+		}
+	}
+
+	if code := strings.ToUpper(pid.Field(16).Component(1)); maritalStatuses[code] {
+		patient.MaritalStatus = &models.CodeableConcept{
+			Coding: []models.Coding{{System: stringPtr(maritalStatusSystem), Code: stringPtr(code)}},
+		}
+		if text := pid.Field(16).Component(2); text != "" {
+			patient.MaritalStatus.Text = &text
+		}
+	}
+
+	if death := pid.Field(29); !death.IsEmpty() {
+		deathTime, err := ParseTime(death.Component(1))
+		if err != nil {
+			return nil, fmt.Errorf("PID-29: %w", err)
+		}
+		patient.DeceasedDateTime = &deathTime
+	} else if indicator := strings.ToUpper(pid.Field(30).String()); indicator == "Y" || indicator == "N" {
+		deceased := indicator == "Y"
+		patient.DeceasedBoolean = &deceased
+	}
+
+	return patient, nil
+}
+
+// Identifiers converts CX identifiers, such as PID-3 or MRG-1, skipping those
+// without an ID. The assigning authority becomes the identifier system: its
+// universal ID as an OID or URI when it has one, its namespace otherwise.
+func Identifiers(fields []Field) []models.Identifier {
+	var identifiers []models.Identifier
+	for _, cx := range fields {
+		value := cx.Component(1)
+		if value == "" {
+			continue
+		}
+		identifier := models.Identifier{Value: &value}
+		if system := identifierSystem(cx); system != "" {
+			identifier.System = &system
+		}
+		if typeCode := cx.Component(5); typeCode != "" {
+			identifier.Type = &models.CodeableConcept{
+				Coding: []models.Coding{{System: stringPtr(identifierTypeSystem), Code: &typeCode}},
+			}
+		}
+		identifiers = append(identifiers, identifier)
+	}
+	return identifiers
+}
+
+// identifierSystem returns the system of a CX identifier's assigning authority
+func identifierSystem(cx Field) string {
+	namespace := cx.Subcomponent(4, 1)
+	universalID := cx.Subcomponent(4, 2)
+	switch strings.ToUpper(cx.Subcomponent(4, 3)) {
+	case "ISO":
+		if universalID != "" {
+			return "urn:oid:" + universalID
+		}
+	case "URI":
+		if universalID != "" {
+			return universalID
+		}
+	}
+	if namespace != "" {
+		return assigningAuthoritySystem + namespace
+	}
+	return ""
+}
+
+// humanName converts an XPN name
+func humanName(xpn Field) *models.HumanName {
+	name := &models.HumanName{}
+	if family := xpn.Subcomponent(1, 1); family != "" {
+		name.Family = &family
+	}
+	for _, n := range []int{2, 3} {
+		if given := xpn.Component(n); given != "" {
+			name.Given = append(name.Given, given)
+		}
+	}
+	if suffix := xpn.Component(4); suffix != "" {
+		name.Suffix = []string{suffix}
+	}
+	if prefix := xpn.Component(5); prefix != "" {
+		name.Prefix = []string{prefix}
+	}
+	if name.Family == nil && len(name.Given) == 0 {
+		return nil
+	}
+	if use, ok := nameUses[strings.ToUpper(xpn.Component(7))]; ok {
+		name.Use = &use
+	}
+	return name
+}
+
+// postalAddress converts an XAD address
+func postalAddress(xad Field) *models.Address {
+	address := &models.Address{}
+	for _, n := range []int{1, 2} {
+		if line := xad.Subcomponent(n, 1); line != "" {
+			address.Line = append(address.Line, line)
+		}
+	}
+	address.City = optional(xad.Component(3))
+	address.State = optional(xad.Component(4))
+	address.PostalCode = optional(xad.Component(5))
+	address.Country = optional(xad.Component(6))
+	if len(address.Line) == 0 && address.City == nil && address.State == nil && address.PostalCode == nil && address.Country == nil {
+		return nil
+	}
+	if use, ok := addressUses[strings.ToUpper(xad.Component(7))]; ok {
+		address.Use = &use
+	}
+	return address
+}
+
+// contactPoint converts an XTN phone number or email address. defaultUse applies
+// when the number carries no use code of its own.
+func contactPoint(xtn Field, defaultUse string) *models.ContactPoint {
+	useCode := strings.ToUpper(xtn.Component(2))
+	equipment := strings.ToUpper(xtn.Component(3))
+
+	if useCode == "NET" || equipment == "INTERNET" || equipment == "X.400" {
+		email := xtn.Component(4)
+		if email == "" {
+			return nil
+		}
+		return &models.ContactPoint{System: stringPtr("email"), Value: &email}
+	}
+
+	number := xtn.Component(1)
+	if number == "" {
+		number = strings.TrimSpace(strings.Join([]string{xtn.Component(5), xtn.Component(6), xtn.Component(7)}, " "))
+	}
+	if number == "" {
+		return nil
+	}
+
+	system := "phone"
+	switch equipment {
+	case "FX":
+		system = "fax"
+	case "BP":
+		system = "pager"
+	}
+	use := defaultUse
+	if mapped, ok := phoneUses[useCode]; ok {
+		use = mapped
+	}
+	if equipment == "CP" {
+		use = "mobile"
+	}
+	return &models.ContactPoint{System: &system, Value: &number, Use: &use}
+}
+
+// ParseTime parses an HL7 DTM timestamp, YYYY[MM[DD[HH[MM[SS[.S+]]]]]][+/-ZZZZ].
+// Timestamps without an offset are taken as UTC.
+func ParseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	offset := ""
+	if i := strings.IndexAny(value, "+-"); i >= 0 {
+		value, offset = value[:i], value[i:]
+	}
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		value = value[:i]
+	}
+
+	layouts := map[int]string{4: "2006", 6: "200601", 8: "20060102", 10: "2006010215", 12: "200601021504", 14: "20060102150405"}
+	layout, ok := layouts[len(value)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid HL7 timestamp %q", value+offset)
+	}
+	if offset != "" {
+		t, err := time.Parse(layout+"-0700", value+offset)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid HL7 timestamp %q", value+offset)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid HL7 timestamp %q", value)
+	}
+	return t, nil
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
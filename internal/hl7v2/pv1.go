@@ -0,0 +1,48 @@
+package hl7v2
+
+import (
+	"regexp"
+	"strings"
+
+	"healthcare-api/internal/models"
+)
+
+// encounterIDPattern matches visit numbers that are valid FHIR ids
+var encounterIDPattern = regexp.MustCompile(`^[A-Za-z0-9\-.]{1,64}$`)
+
+// patientClasses maps HL7 table 0004 patient classes to the display of the
+// FHIR encounter class they correspond to
+var patientClasses = map[string]string{
+	"E": "emergency",
+	"I": "inpatient encounter",
+	"O": "ambulatory",
+	"P": "pre-admission",
+}
+
+// EncounterFromPV1 returns a reference to the visit of a PV1 segment, or nil
+// when it has no PV1-19 visit number. The API has no Encounter resource, so
+// the reference is logical: it carries the visit number as its identifier,
+// with the assigning authority as the system, and is Encounter/<visit number>
+// when the visit number is a valid id. PV1-2, the patient class, becomes its
+// display.
+func EncounterFromPV1(pv1 *Segment) *models.Reference {
+	identifiers := Identifiers([]Field{pv1.Field(19)})
+	if len(identifiers) == 0 {
+		return nil
+	}
+	identifier := identifiers[0]
+	if identifier.Type == nil {
+		identifier.Type = &models.CodeableConcept{
+			Coding: []models.Coding{{System: stringPtr(identifierTypeSystem), Code: stringPtr("VN")}},
+		}
+	}
+
+	encounter := &models.Reference{Type: stringPtr("Encounter"), Identifier: &identifier}
+	if encounterIDPattern.MatchString(*identifier.Value) {
+		encounter.Reference = stringPtr("Encounter/" + *identifier.Value)
+	}
+	if class, ok := patientClasses[strings.ToUpper(pv1.Field(2).Component(1))]; ok {
+		encounter.Display = &class
+	}
+	return encounter
+}
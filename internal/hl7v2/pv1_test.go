@@ -0,0 +1,113 @@
+package hl7v2
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncounterFromPV1(t *testing.T) {
+	tests := []struct {
+		name          string
+		pv1           string
+		wantReference string
+		wantSystem    string
+		wantValue     string
+		wantDisplay   string
+	}{
+		{
+			name:          "inpatient visit",
+			pv1:           "PV1|1|I|W^389^1||||||||||||||||V00123^^^HOSP^VN",
+			wantReference: "Encounter/V00123",
+			wantSystem:    assigningAuthoritySystem + "HOSP",
+			wantValue:     "V00123",
+			wantDisplay:   "inpatient encounter",
+		},
+		{
+			name:        "visit number that is not an id",
+			pv1:         "PV1|1|E|||||||||||||||||V 001/2",
+			wantValue:   "V 001/2",
+			wantDisplay: "emergency",
+		},
+		{
+			name:          "unknown patient class",
+			pv1:           "PV1|1|X|||||||||||||||||V9",
+			wantReference: "Encounter/V9",
+			wantValue:     "V9",
+		},
+		{
+			name: "no visit number",
+			pv1:  "PV1|1|I|W^389^1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Parse([]byte("MSH|^~\\&|ADT|HOSP|RDS|RDS|20240115130000||ADT^A01|1|P|2.5.1\r" + tt.pv1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			encounter := EncounterFromPV1(msg.Segment("PV1"))
+			if tt.wantValue == "" {
+				if encounter != nil {
+					t.Fatalf("EncounterFromPV1 = %+v, want nil", encounter)
+				}
+				return
+			}
+			if encounter == nil || encounter.Identifier == nil {
+				t.Fatalf("EncounterFromPV1 = %+v, want visit %s", encounter, tt.wantValue)
+			}
+			if got := deref(encounter.Reference); got != tt.wantReference {
+				t.Errorf("reference = %q, want %q", got, tt.wantReference)
+			}
+			if got := deref(encounter.Identifier.System); got != tt.wantSystem {
+				t.Errorf("identifier system = %q, want %q", got, tt.wantSystem)
+			}
+			if got := deref(encounter.Identifier.Value); got != tt.wantValue {
+				t.Errorf("identifier value = %q, want %q", got, tt.wantValue)
+			}
+			if got := deref(encounter.Display); got != tt.wantDisplay {
+				t.Errorf("display = %q, want %q", got, tt.wantDisplay)
+			}
+		})
+	}
+}
+
+func TestOrdersFromORUEncounter(t *testing.T) {
+	// The visit applies to the results after the PV1 segment
+	message := strings.Replace(oruR01, "OBR|", "PV1|1|O|||||||||||||||||V00123\rOBR|", 1)
+	msg, err := Parse([]byte(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders, err := OrdersFromORU(msg, oruSubject())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, order := range orders {
+		if got := deref(order.Report.Encounter.Reference); got != "Encounter/V00123" {
+			t.Errorf("report encounter = %q, want Encounter/V00123", got)
+		}
+		for _, result := range order.Results {
+			if result.Encounter != order.Report.Encounter {
+				t.Errorf("result %s has encounter %+v, want the report's", deref(result.Code.Text), result.Encounter)
+			}
+		}
+	}
+
+	msg, err = Parse([]byte(oruR01))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orders, err = OrdersFromORU(msg, oruSubject()); err != nil {
+		t.Fatal(err)
+	}
+	if orders[0].Report.Encounter != nil {
+		t.Errorf("report of a message without PV1 has encounter %+v", orders[0].Report.Encounter)
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
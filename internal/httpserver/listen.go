@@ -0,0 +1,104 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// listenSystemd takes the socket passed by systemd socket activation
+	listenSystemd = "systemd"
+	// unixPrefix precedes the path of a Unix domain socket to listen on
+	unixPrefix = "unix:"
+	// listenFDsStart is the first file descriptor systemd passes sockets on
+	listenFDsStart = 3
+	// unixPeerAddr is the address given to requests over a Unix domain socket,
+	// which come from the local reverse proxy
+	unixPeerAddr = "127.0.0.1:0"
+)
+
+// listen opens the listener of the API: a Unix domain socket, the socket
+// passed by systemd, or a TCP port
+func (s *Server) listen() (net.Listener, error) {
+	switch {
+	case s.listenOn == listenSystemd:
+		return systemdListener()
+	case strings.HasPrefix(s.listenOn, unixPrefix):
+		return unixListener(strings.TrimPrefix(s.listenOn, unixPrefix), s.socketMode)
+	}
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", s.server.Addr, err)
+	}
+	return listener, nil
+}
+
+// unixListener listens on a Unix domain socket at path with permissions mode.
+// A socket left at path by a process that did not shut down cleanly is
+// replaced; any other file there is an error.
+func unixListener(path string, mode fs.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("failed to listen on %s: file exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set permissions of %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+// systemdListener returns the first socket passed by systemd socket
+// activation, as announced by LISTEN_PID and LISTEN_FDS. The variables are
+// unset, so processes started by the server do not take the socket for theirs.
+func systemdListener() (net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	if pid == "" || fds == "" {
+		return nil, errors.New("no socket passed by systemd: LISTEN_PID and LISTEN_FDS are not set")
+	}
+	if pid != strconv.Itoa(os.Getpid()) {
+		return nil, fmt.Errorf("sockets passed by systemd are for process %s", pid)
+	}
+	if n, err := strconv.Atoi(fds); err != nil || n < 1 {
+		return nil, fmt.Errorf("no socket passed by systemd: LISTEN_FDS is %q", fds)
+	}
+
+	file := os.NewFile(listenFDsStart, "systemd-socket")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use socket passed by systemd: %w", err)
+	}
+	return listener, nil
+}
+
+// fromUnixPeer gives requests over a Unix domain socket, which have no peer
+// address, the loopback address, so they are attributed to the local reverse
+// proxy and its forwarding headers are read when it is a trusted proxy
+func fromUnixPeer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := net.SplitHostPort(r.RemoteAddr); err != nil {
+			r.RemoteAddr = unixPeerAddr
+		}
+		next.ServeHTTP(w, r)
+	})
+}
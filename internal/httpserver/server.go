@@ -0,0 +1,239 @@
+// Package httpserver serves the API over plain HTTP, or over TLS with a
+// certificate read from files or obtained from an ACME CA such as Let's
+// Encrypt, for deployments without a proxy in front of the server to
+// terminate TLS. HTTP/2 is negotiated over TLS unless turned off. Behind a
+// local reverse proxy it can listen on a Unix domain socket, or on a socket
+// passed by systemd socket activation, instead of a TCP port.
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Server is the API's HTTP server, with the plain HTTP listener redirecting
+// to it when TLS is on
+type Server struct {
+	server   *http.Server
+	redirect *http.Server
+	logger   *logrus.Logger
+
+	// Where the server listens instead of its port, and the permissions of a
+	// Unix domain socket it creates
+	listenOn   string
+	socketMode fs.FileMode
+
+	// Certificate files, and the certificate last read from them
+	certFile    string
+	keyFile     string
+	certificate atomic.Pointer[tls.Certificate]
+}
+
+// New creates the server handling requests with handler. Certificate files
+// are read before it returns, so a bad certificate stops startup.
+func New(cfg config.ServerConfig, handler http.Handler, logger *logrus.Logger) (*Server, error) {
+	s := &Server{
+		server: &http.Server{
+			Addr:         fmt.Sprintf(":%d", cfg.Port),
+			Handler:      handler,
+			ReadTimeout:  time.Duration(cfg.ReadTimeout) * time.Second,
+			WriteTimeout: time.Duration(cfg.WriteTimeout) * time.Second,
+			IdleTimeout:  time.Duration(cfg.IdleTimeout) * time.Second,
+		},
+		logger:   logger,
+		certFile: cfg.TLS.CertFile,
+		keyFile:  cfg.TLS.KeyFile,
+		listenOn: cfg.Listen,
+	}
+	if cfg.Listen != "" {
+		s.server.Addr = cfg.Listen
+	}
+	if strings.HasPrefix(cfg.Listen, unixPrefix) {
+		mode, err := strconv.ParseUint(cfg.SocketMode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid socket mode %q: %w", cfg.SocketMode, err)
+		}
+		s.socketMode = fs.FileMode(mode)
+	}
+	if !cfg.TLS.Enabled() {
+		return s, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLS.MinVersion == "1.3" {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
+	// Plain HTTP requests are sent to HTTPS; with autocert, HTTP-01
+	// challenges are answered first
+	var redirect http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirectToHTTPS(w, r, cfg.Port)
+	})
+	if len(cfg.TLS.AutocertDomains) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLS.AutocertDomains...),
+			Cache:      autocert.DirCache(cfg.TLS.AutocertCacheDir),
+			Email:      cfg.TLS.AutocertEmail,
+		}
+		if cfg.TLS.AutocertDirectoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: cfg.TLS.AutocertDirectoryURL}
+		}
+		tlsConfig.GetCertificate = manager.GetCertificate
+		// TLS-ALPN-01 challenges are answered on the TLS port
+		tlsConfig.NextProtos = []string{acme.ALPNProto}
+		redirect = manager.HTTPHandler(redirect)
+	} else {
+		if err := s.ReloadCertificate(); err != nil {
+			return nil, err
+		}
+		tlsConfig.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return s.certificate.Load(), nil
+		}
+	}
+
+	// ServeTLS adds h2 to the protocols unless TLSNextProto is set
+	if cfg.TLS.HTTP2 {
+		tlsConfig.NextProtos = append([]string{"h2", "http/1.1"}, tlsConfig.NextProtos...)
+	} else {
+		tlsConfig.NextProtos = append([]string{"http/1.1"}, tlsConfig.NextProtos...)
+		s.server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	s.server.TLSConfig = tlsConfig
+
+	if cfg.TLS.RedirectPort != 0 {
+		s.redirect = &http.Server{
+			Addr:         fmt.Sprintf(":%d", cfg.TLS.RedirectPort),
+			Handler:      redirect,
+			ReadTimeout:  time.Duration(cfg.ReadTimeout) * time.Second,
+			WriteTimeout: time.Duration(cfg.WriteTimeout) * time.Second,
+			IdleTimeout:  time.Duration(cfg.IdleTimeout) * time.Second,
+		}
+	}
+	return s, nil
+}
+
+// NewManagement creates the server of the management listener, which speaks
+// plain HTTP on the internal address of cfg.Management. It has no write
+// timeout, so CPU profiles and traces can run for as long as asked.
+func NewManagement(cfg config.ServerConfig, handler http.Handler, logger *logrus.Logger) *Server {
+	return &Server{
+		server: &http.Server{
+			Addr:        net.JoinHostPort(cfg.Management.Host, strconv.Itoa(cfg.Management.Port)),
+			Handler:     handler,
+			ReadTimeout: time.Duration(cfg.ReadTimeout) * time.Second,
+			IdleTimeout: time.Duration(cfg.IdleTimeout) * time.Second,
+		},
+		logger: logger,
+	}
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.server.Addr
+}
+
+// TLS reports whether the server terminates TLS
+func (s *Server) TLS() bool {
+	return s.server.TLSConfig != nil
+}
+
+// ReloadCertificate reads the certificate files again, so a renewed
+// certificate is served to new connections. It does nothing for a server
+// without certificate files. The certificate in use is kept if the files
+// cannot be read.
+func (s *Server) ReloadCertificate() error {
+	if s.certFile == "" {
+		return nil
+	}
+	certificate, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	s.certificate.Store(&certificate)
+	return nil
+}
+
+// Start listens on the configured ports, or socket, and serves requests in
+// the background
+func (s *Server) Start() error {
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+	if listener.Addr().Network() == "unix" {
+		s.server.Handler = fromUnixPeer(s.server.Handler)
+	}
+	if s.redirect != nil {
+		redirectListener, err := net.Listen("tcp", s.redirect.Addr)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to listen on %s: %w", s.redirect.Addr, err)
+		}
+		go s.serve(s.redirect, redirectListener, false)
+	}
+	go s.serve(s.server, listener, s.TLS())
+	return nil
+}
+
+// Shutdown stops the server and its redirect listener, waiting for requests
+// in progress until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.redirect != nil {
+		if err := s.redirect.Shutdown(ctx); err != nil {
+			s.logger.WithError(err).Warn("HTTP redirect listener forced to shutdown")
+		}
+	}
+	return s.server.Shutdown(ctx)
+}
+
+func (s *Server) serve(server *http.Server, listener net.Listener, useTLS bool) {
+	var err error
+	if useTLS {
+		// The certificate comes from TLSConfig.GetCertificate
+		err = server.ServeTLS(listener, "", "")
+	} else {
+		err = server.Serve(listener)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.WithError(err).WithField("addr", server.Addr).Error("HTTP server stopped")
+	}
+}
+
+// redirectToHTTPS sends a GET or HEAD request to the same URL over HTTPS on
+// port. Other requests are refused rather than redirected, so a client is not
+// told to send its body a second time after already sending it in the clear.
+func redirectToHTTPS(w http.ResponseWriter, r *http.Request, port int) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(models.NewOperationOutcome("error", "security", "HTTPS is required"))
+		return
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if port != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
@@ -0,0 +1,117 @@
+// Package identifier checks the values of country-specific patient identifiers,
+// such as national health and insurance numbers, by the system they are issued
+// under. Each kind of identifier is checked by a plugin; a deployment enables
+// the plugins for its countries, and may register its own.
+package identifier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"healthcare-api/internal/models"
+)
+
+// Plugin checks the values of one kind of identifier
+type Plugin interface {
+	// System is the identifier system the plugin checks unless configured
+	// for another
+	System() string
+	// Check returns why value is not a valid identifier of the kind, or nil
+	Check(value string) error
+}
+
+var (
+	pluginsMu sync.RWMutex
+	plugins   = map[string]Plugin{
+		"nhs": NHSNumber{},
+		"nin": NINumber{},
+		"ssn": SSN{},
+	}
+)
+
+// Register makes a plugin available under name, for plugins built into a
+// deployment's binary. It panics when name is taken.
+func Register(name string, plugin Plugin) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	if _, taken := plugins[name]; taken {
+		panic("identifier: plugin " + name + " registered twice")
+	}
+	plugins[name] = plugin
+}
+
+// Validator checks the identifiers of the systems it has plugins for; those of
+// other systems are not checked
+type Validator struct {
+	// plugins by the system they check
+	plugins map[string]named
+}
+
+type named struct {
+	name   string
+	plugin Plugin
+}
+
+// NewValidator enables the plugins of specs, each a plugin name checking its
+// default system, such as "nhs", or name=system to check another, such as
+// "ssn=urn:oid:2.16.840.1.113883.4.1"
+func NewValidator(specs []string) (*Validator, error) {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+
+	v := &Validator{plugins: map[string]named{}}
+	for _, spec := range specs {
+		name, system, custom := strings.Cut(spec, "=")
+		plugin, ok := plugins[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown identifier plugin %q: expected one of %s", name, strings.Join(sortedNames(), ", "))
+		}
+		if !custom {
+			system = plugin.System()
+		}
+		if system == "" {
+			return nil, fmt.Errorf("identifier plugin %s has no system", name)
+		}
+		if other, taken := v.plugins[system]; taken {
+			return nil, fmt.Errorf("identifier system %s is checked by both %s and %s", system, other.name, name)
+		}
+		v.plugins[system] = named{name: name, plugin: plugin}
+	}
+	return v, nil
+}
+
+// sortedNames lists the plugins; the caller holds pluginsMu
+func sortedNames() []string {
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckIdentifiers returns an error for each of identifiers, found at path such
+// as "identifier", whose value its system's plugin finds invalid. Issue fields
+// are the offending value's path.
+func (v *Validator) CheckIdentifiers(path string, identifiers []models.Identifier) []models.ValidationError {
+	var issues []models.ValidationError
+	for i, identifier := range identifiers {
+		if identifier.System == nil || identifier.Value == nil {
+			continue
+		}
+		checker, ok := v.plugins[*identifier.System]
+		if !ok {
+			continue
+		}
+		if err := checker.plugin.Check(*identifier.Value); err != nil {
+			issues = append(issues, models.ValidationError{
+				Field:   fmt.Sprintf("%s[%d].value", path, i),
+				Message: err.Error(),
+				Value:   *identifier.Value,
+			})
+		}
+	}
+	return issues
+}
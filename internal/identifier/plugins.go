@@ -0,0 +1,109 @@
+package identifier
+
+import (
+	"errors"
+	"strings"
+)
+
+// Identifier systems checked by the built-in plugins by default
+const (
+	NHSNumberSystem = "https://fhir.nhs.uk/Id/nhs-number"
+	NINumberSystem  = "https://fhir.hmrc.gov.uk/Id/national-insurance-number"
+	SSNSystem       = "http://hl7.org/fhir/sid/us-ssn"
+)
+
+// NHSNumber checks NHS numbers of England, Wales and the Isle of Man: ten
+// digits, without spaces, the last a modulus 11 check digit
+type NHSNumber struct{}
+
+func (NHSNumber) System() string {
+	return NHSNumberSystem
+}
+
+func (NHSNumber) Check(value string) error {
+	if len(value) != 10 || !digits(value) {
+		return errors.New("NHS number must be 10 digits")
+	}
+
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(value[i]-'0') * (10 - i)
+	}
+	check := 11 - sum%11
+	if check == 11 {
+		check = 0
+	}
+	if check == 10 || check != int(value[9]-'0') {
+		return errors.New("NHS number check digit is wrong")
+	}
+	return nil
+}
+
+// NINumber checks UK National Insurance numbers: two prefix letters, six
+// digits and a suffix letter from A to D, uppercase without spaces. Prefixes
+// HMRC does not allocate are rejected.
+type NINumber struct{}
+
+func (NINumber) System() string {
+	return NINumberSystem
+}
+
+func (NINumber) Check(value string) error {
+	if len(value) != 9 || !upper(value[:2]) || !digits(value[2:8]) || value[8] < 'A' || value[8] > 'D' {
+		return errors.New("National Insurance number must be 2 letters, 6 digits and a letter from A to D")
+	}
+
+	prefix := value[:2]
+	if strings.ContainsAny(prefix[:1], "DFIQUV") || strings.ContainsAny(prefix[1:], "DFIOQUV") {
+		return errors.New("National Insurance number prefix " + prefix + " is not allocated")
+	}
+	switch prefix {
+	case "BG", "GB", "KN", "NK", "NT", "TN", "ZZ":
+		return errors.New("National Insurance number prefix " + prefix + " is not allocated")
+	}
+	return nil
+}
+
+// SSN checks US Social Security numbers: nine digits, either bare or as
+// AAA-GG-SSSS, whose area, group and serial numbers can be issued
+type SSN struct{}
+
+func (SSN) System() string {
+	return SSNSystem
+}
+
+func (SSN) Check(value string) error {
+	if len(value) == 11 && value[3] == '-' && value[6] == '-' {
+		value = value[:3] + value[4:6] + value[7:]
+	}
+	if len(value) != 9 || !digits(value) {
+		return errors.New("SSN must be 9 digits, optionally as AAA-GG-SSSS")
+	}
+
+	area, group, serial := value[:3], value[3:5], value[5:]
+	if area == "000" || area == "666" || area[0] == '9' {
+		return errors.New("SSN area number " + area + " is never issued")
+	}
+	if group == "00" || serial == "0000" {
+		return errors.New("SSN group and serial numbers must not be zero")
+	}
+	return nil
+}
+
+func digits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func upper(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'A' || s[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
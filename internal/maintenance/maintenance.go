@@ -0,0 +1,88 @@
+// Package maintenance holds the maintenance mode of the deployment, in which
+// reads are served but writes are refused and background jobs pause, e.g.
+// while schema migrations run. It is on when MAINTENANCE_MODE is set or when
+// it was switched on through the admin API, whose stored state every instance
+// reads periodically.
+package maintenance
+
+import (
+	"sync"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+)
+
+// Mode is the maintenance mode of the process, safe for concurrent use
+type Mode struct {
+	mu     sync.RWMutex
+	cfg    config.MaintenanceConfig
+	stored models.MaintenanceMode
+
+	// changing serializes changes, so switches are reported in order
+	changing sync.Mutex
+	onChange []func(enabled bool)
+}
+
+// New returns the maintenance mode set by cfg, with nothing stored yet
+func New(cfg config.MaintenanceConfig) *Mode {
+	return &Mode{cfg: cfg}
+}
+
+// Enabled reports whether maintenance mode is on
+func (m *Mode) Enabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled()
+}
+
+func (m *Mode) enabled() bool {
+	return m.cfg.Enabled || m.stored.Enabled
+}
+
+// Status returns the maintenance mode in effect
+func (m *Mode) Status() *models.MaintenanceMode {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := m.stored
+	status.Enabled = m.enabled()
+	status.Forced = m.cfg.Enabled
+	status.RetryAfter = m.cfg.RetryAfter
+	return &status
+}
+
+// OnChange calls fn whenever maintenance mode is switched on or off. fn must
+// not change the mode.
+func (m *Mode) OnChange(fn func(enabled bool)) {
+	m.changing.Lock()
+	defer m.changing.Unlock()
+	m.onChange = append(m.onChange, fn)
+}
+
+// SetConfig replaces the configured maintenance settings, e.g. on a
+// configuration reload
+func (m *Mode) SetConfig(cfg config.MaintenanceConfig) {
+	m.update(func() { m.cfg = cfg })
+}
+
+// Store replaces the state switched through the admin API
+func (m *Mode) Store(stored *models.MaintenanceMode) {
+	m.update(func() { m.stored = *stored })
+}
+
+func (m *Mode) update(change func()) {
+	m.changing.Lock()
+	defer m.changing.Unlock()
+
+	m.mu.Lock()
+	before := m.enabled()
+	change()
+	after := m.enabled()
+	m.mu.Unlock()
+
+	if after != before {
+		for _, fn := range m.onChange {
+			fn(after)
+		}
+	}
+}
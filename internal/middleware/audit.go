@@ -2,83 +2,147 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
+	"healthcare-api/internal/redact"
 	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/siem"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
+// auditStoreTimeout bounds storing an access log entry after the request finished
+const auditStoreTimeout = 5 * time.Second
+
 // AuditMiddleware logs all API requests for compliance
 type AuditMiddleware struct {
-	repo   *repository.BaseRepository
-	logger *logrus.Logger
+	repo     *repository.BaseRepository
+	policy   *redact.Policy
+	sampler  *LogSampler
+	exporter *siem.Exporter
+	logger   *logrus.Logger
 }
 
-// NewAuditMiddleware creates a new audit middleware
-func NewAuditMiddleware(repo *repository.BaseRepository, logger *logrus.Logger) *AuditMiddleware {
+// NewAuditMiddleware creates a new audit middleware that redacts requests with
+// policy and writes the log lines of those sampler samples
+func NewAuditMiddleware(repo *repository.BaseRepository, policy *redact.Policy, sampler *LogSampler, logger *logrus.Logger) *AuditMiddleware {
 	return &AuditMiddleware{
-		repo:   repo,
-		logger: logger,
+		repo:    repo,
+		policy:  policy,
+		sampler: sampler,
+		logger:  logger,
 	}
 }
 
-// AuditLog middleware logs all requests for healthcare compliance
+// SetExporter makes the middleware forward every access log entry to a SIEM
+func (am *AuditMiddleware) SetExporter(exporter *siem.Exporter) {
+	am.exporter = exporter
+}
+
+// AuditLog middleware records every request in the access log, the HIPAA record
+// of who accessed what. It runs before RequireAuth so rejected requests are
+// recorded too; the user and tenant are read once the request has finished.
+// Request bodies and query values are stored with PHI redacted, and in strict
+// mode bodies are not read at all.
 func (am *AuditMiddleware) AuditLog() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		
-		// Generate request ID
-		requestID := uuid.New().String()
-		c.Set("request_id", requestID)
-		c.Header("X-Request-ID", requestID)
+		requestID := ensureRequestID(c)
 
-		// Capture request body for audit
+		// Capture request body for audit. Only JSON is kept, so other bodies, such
+		// as attachment uploads, are streamed through and recorded by size.
 		var requestBody []byte
-		if c.Request.Body != nil {
+		capture := !am.policy.Strict() && capturesBody(c.Request.Header.Get("Content-Type"))
+		if c.Request.Body != nil && capture {
 			requestBody, _ = io.ReadAll(c.Request.Body)
 			c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
 		}
 
-		// Get user info from context (if authenticated)
-		userID, _ := c.Get("user_id")
-		userIDStr, _ := userID.(string)
-
 		// Process request
 		c.Next()
 
-		// Log audit entry
 		duration := time.Since(start)
-		
-		auditEntry := map[string]interface{}{
-			"request_id":    requestID,
-			"timestamp":     start.UTC(),
-			"method":        c.Request.Method,
-			"path":          c.Request.URL.Path,
-			"query":         c.Request.URL.RawQuery,
-			"status_code":   c.Writer.Status(),
-			"duration_ms":   duration.Milliseconds(),
-			"client_ip":     c.ClientIP(),
-			"user_agent":    c.Request.UserAgent(),
-			"user_id":       userIDStr,
-			"request_size":  len(requestBody),
-			"response_size": c.Writer.Size(),
+		userID, _, _, _ := GetUserFromContext(c)
+		tenantID := requestctx.TenantID(c.Request.Context())
+
+		entry := &repository.AccessLog{
+			RequestID:  &requestID,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			StatusCode: c.Writer.Status(),
+			Duration:   duration,
+			Timestamp:  start.UTC(),
+		}
+		if userID != "" {
+			entry.UserID = &userID
+		}
+		if tenantID != "" {
+			entry.TenantID = &tenantID
+		}
+		if ip := c.ClientIP(); ip != "" {
+			entry.IPAddress = &ip
+		}
+		if userAgent := c.Request.UserAgent(); userAgent != "" {
+			entry.UserAgent = &userAgent
+		}
+		if query := am.policy.Query(c.Request.URL.RawQuery); query != "" {
+			entry.Query = &query
+		}
+		if body := am.policy.Body(requestBody); body != "" {
+			entry.RequestBody = &body
+		} else if am.policy.Strict() && c.Request.ContentLength > 0 {
+			body := redact.Size(c.Request.ContentLength)
+			entry.RequestBody = &body
+		} else if !capture && c.Request.ContentLength > 0 {
+			body := "[" + strconv.FormatInt(c.Request.ContentLength, 10) + " bytes, not JSON]"
+			entry.RequestBody = &body
 		}
 
-		// Log sensitive operations with more detail
-		if c.Request.Method != "GET" {
-			auditEntry["request_body"] = string(requestBody)
+		requestSize := int64(len(requestBody))
+		if !capture && c.Request.ContentLength > 0 {
+			requestSize = c.Request.ContentLength
+		}
+		if am.sampler.Sampled(requestID, entry.StatusCode, duration) {
+			am.logger.WithFields(logrus.Fields{
+				"request_id":    requestID,
+				"method":        entry.Method,
+				"path":          entry.Path,
+				"status_code":   entry.StatusCode,
+				"duration_ms":   duration.Milliseconds(),
+				"user_id":       userID,
+				"tenant_id":     tenantID,
+				"request_size":  requestSize,
+				"response_size": c.Writer.Size(),
+			}).Info("API Request Audit")
 		}
 
-		am.logger.WithFields(auditEntry).Info("API Request Audit")
+		if am.exporter != nil {
+			am.exporter.Export(siem.AccessEvent(entry))
+		}
 
-		// Store in database for compliance (async)
+		// Store in database for compliance, without holding up the response. The
+		// gin context is reused once the request returns, so only ctx is captured.
+		ctx := context.WithoutCancel(c.Request.Context())
 		go func() {
-			// Implementation would store audit log in database
-			// This is important for healthcare compliance (HIPAA, etc.)
+			ctx, cancel := context.WithTimeout(ctx, auditStoreTimeout)
+			defer cancel()
+			if err := am.repo.LogAccess(ctx, entry); err != nil {
+				am.logger.WithContext(ctx).WithError(err).Error("Failed to store access log entry")
+			}
 		}()
 	}
 }
+
+// capturesBody reports whether request bodies of contentType are read into the
+// access log: JSON, and bodies without a type, which may be JSON
+func capturesBody(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	return mediaType == "" || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
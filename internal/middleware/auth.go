@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"healthcare-api/internal/models"
+	"healthcare-api/internal/requestctx"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -31,6 +32,7 @@ type Claims struct {
 	Username string   `json:"username"`
 	Roles    []string `json:"roles"`
 	Scopes   []string `json:"scopes"`
+	TenantID string   `json:"tenant_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -81,11 +83,26 @@ func (a *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		c.Set("username", claims.Username)
 		c.Set("roles", claims.Roles)
 		c.Set("scopes", claims.Scopes)
+		c.Set("tenant_id", claims.TenantID)
+		c.Request = c.Request.WithContext(requestctx.WithUserID(c.Request.Context(), claims.UserID))
 
 		c.Next()
 	}
 }
 
+// VerifyHeader reports whether an Authorization header carries a valid bearer
+// token. It does not set anything on the request.
+func (a *AuthMiddleware) VerifyHeader(authHeader string) bool {
+	tokenParts := strings.Split(authHeader, " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		return false
+	}
+	token, err := jwt.ParseWithClaims(tokenParts[1], &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		return a.jwtSecret, nil
+	})
+	return err == nil && token.Valid
+}
+
 // RequireRole middleware checks if user has required role
 func (a *AuthMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -128,6 +145,27 @@ func (a *AuthMiddleware) RequireRole(requiredRole string) gin.HandlerFunc {
 	}
 }
 
+// RequirePlatformRole checks for a deployment-wide role. Unlike RequireRole,
+// the tenant-level admin role does not satisfy it.
+func (a *AuthMiddleware) RequirePlatformRole(requiredRole string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, _, roles, _ := GetUserFromContext(c)
+		for _, role := range roles {
+			if role == requiredRole {
+				c.Next()
+				return
+			}
+		}
+
+		a.logger.WithFields(logrus.Fields{
+			"required_role": requiredRole,
+			"user_roles":    roles,
+		}).Warn("Insufficient permissions")
+		c.JSON(http.StatusForbidden, models.NewOperationOutcome("error", "security", "Insufficient permissions"))
+		c.Abort()
+	}
+}
+
 // RequireScope middleware checks if user has required scope
 func (a *AuthMiddleware) RequireScope(requiredScope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -171,12 +209,13 @@ func (a *AuthMiddleware) RequireScope(requiredScope string) gin.HandlerFunc {
 }
 
 // GenerateToken generates a JWT token for a user
-func (a *AuthMiddleware) GenerateToken(userID, username string, roles, scopes []string, expiration time.Duration) (string, error) {
+func (a *AuthMiddleware) GenerateToken(userID, username, tenantID string, roles, scopes []string, expiration time.Duration) (string, error) {
 	claims := &Claims{
 		UserID:   userID,
 		Username: username,
 		Roles:    roles,
 		Scopes:   scopes,
+		TenantID: tenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
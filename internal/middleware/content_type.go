@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+
+	"healthcare-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FHIRContentType is the media type of FHIR JSON
+const FHIRContentType = "application/fhir+json"
+
+// acceptedContentTypes are the media types request bodies may be sent as
+var acceptedContentTypes = map[string]bool{
+	FHIRContentType:    true,
+	"application/json": true,
+}
+
+// ContentType middleware rejects request bodies that are not FHIR JSON or plain
+// JSON with 415 Unsupported Media Type, and makes responses application/fhir+json.
+// Handlers setting their own Content-Type keep it.
+func ContentType() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// gin only sets a Content-Type when none is set yet
+		c.Header("Content-Type", FHIRContentType+"; charset=utf-8")
+
+		if hasBody(c.Request) {
+			mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+			if err != nil || !acceptedContentTypes[mediaType] {
+				c.JSON(http.StatusUnsupportedMediaType, models.NewErrorOutcome(models.ErrorCodeUnsupportedMediaType,
+					"Content-Type must be application/fhir+json or application/json"))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// hasBody reports whether a request carries a body
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || (r.ContentLength < 0 && r.Body != nil && r.Body != http.NoBody)
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"healthcare-api/internal/features"
+	"healthcare-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFeature middleware answers 404 Not Found, as if the route did not
+// exist, while the feature's flag is off
+func RequireFeature(flags *features.Flags, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !flags.Enabled(name) {
+			c.JSON(http.StatusNotFound, models.NewOperationOutcome("error", "not-supported", "Feature "+name+" is turned off"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
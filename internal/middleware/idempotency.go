@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client's key for a POST request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// maxIdempotencyKeyLength matches the key column
+	maxIdempotencyKeyLength = 255
+	// idempotencyStaleAfter is how long a request may hold its key before a retry
+	// may take it over, in case the server handling it died
+	idempotencyStaleAfter = 5 * time.Minute
+	// idempotencyStoreTimeout bounds storing a response after the request finished
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// replayedHeaders are the response headers stored with an idempotent response
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", "Last-Modified"}
+
+// IdempotencyMiddleware replays the response of a POST request when a client
+// retries it with the same Idempotency-Key, so a retried create does not create
+// a second resource
+type IdempotencyMiddleware struct {
+	repo   *repository.IdempotencyRepository
+	ttl    time.Duration
+	logger *logrus.Logger
+}
+
+// NewIdempotencyMiddleware creates an idempotency middleware keeping responses for ttl
+func NewIdempotencyMiddleware(repo *repository.IdempotencyRepository, ttl time.Duration, logger *logrus.Logger) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		repo:   repo,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// Idempotent honours the Idempotency-Key header on POST requests. Keys are
+// scoped to the tenant and the authenticated user, so it must run after
+// RequireAuth and RequireTenant. Responses other than 5xx are stored and
+// replayed; after a 5xx the key is freed so a retry runs the request again.
+func (im *IdempotencyMiddleware) Idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Idempotency-Key must be at most 255 characters"))
+			c.Abort()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+		}
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		subject, _, _, _ := GetUserFromContext(c)
+		if subject == "" {
+			subject = c.ClientIP()
+		}
+		logger := im.logger.WithFields(logrus.Fields{
+			"idempotency_key": key,
+			"subject":         subject,
+		})
+
+		ctx := c.Request.Context()
+		record, err := im.repo.Reserve(ctx, subject, key, requestHash, im.ttl, idempotencyStaleAfter)
+		if err != nil {
+			logger.WithError(err).Error("Failed to reserve idempotency key")
+			c.JSON(http.StatusServiceUnavailable, models.NewOperationOutcome("error", "transient", "Idempotency-Key could not be checked, retry later"))
+			c.Abort()
+			return
+		}
+
+		if record != nil {
+			switch {
+			case record.RequestHash != requestHash:
+				c.JSON(http.StatusUnprocessableEntity, models.NewOperationOutcome("error", "invalid", "Idempotency-Key was already used for a different request"))
+			case record.StatusCode == 0:
+				c.JSON(http.StatusConflict, models.NewOperationOutcome("error", "conflict", "A request with this Idempotency-Key is still in progress"))
+			default:
+				logger.Debug("Replaying idempotent response")
+				for name, value := range record.Headers {
+					c.Header(name, value)
+				}
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.StatusCode, record.Headers["Content-Type"], record.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// The client may be gone, but the outcome must still be stored
+		storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyStoreTimeout)
+		defer cancel()
+
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError {
+			if err := im.repo.Release(storeCtx, subject, key); err != nil {
+				logger.WithError(err).Warn("Failed to release idempotency key")
+			}
+			return
+		}
+
+		headers := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := c.Writer.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		if err := im.repo.Complete(storeCtx, subject, key, status, headers, writer.body.Bytes()); err != nil {
+			logger.WithError(err).Error("Failed to store idempotent response")
+		}
+	}
+}
+
+// Cleanup prunes expired idempotency keys every hour
+func (im *IdempotencyMiddleware) Cleanup() {
+	ticker := time.NewTicker(time.Hour)
+	go func() {
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			pruned, err := im.repo.PruneExpired(ctx)
+			cancel()
+			if err != nil {
+				im.logger.WithError(err).Warn("Failed to prune idempotency keys")
+				continue
+			}
+			if pruned > 0 {
+				im.logger.WithField("pruned", pruned).Debug("Pruned expired idempotency keys")
+			}
+		}
+	}()
+}
+
+// capturingWriter keeps a copy of the response body as it is written
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+	"time"
+
+	"healthcare-api/internal/config"
+)
+
+// LogSampler decides which requests the request log lines are written for
+type LogSampler struct {
+	rate float64
+	slow time.Duration
+}
+
+// NewLogSampler returns the sampler set by cfg
+func NewLogSampler(cfg config.RequestLogConfig) *LogSampler {
+	return &LogSampler{
+		rate: cfg.SampleRate,
+		slow: time.Duration(cfg.SlowThreshold) * time.Millisecond,
+	}
+}
+
+// Sampled reports whether the log lines of a request are written. Failed and
+// slow requests always are; of the others, the sample rate is taken by request
+// ID, so every line of a request is written or none is.
+func (s *LogSampler) Sampled(requestID string, status int, latency time.Duration) bool {
+	if status >= http.StatusBadRequest || latency >= s.slow || s.rate >= 1 {
+		return true
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(requestID))
+	return float64(hash.Sum32()) < s.rate*math.MaxUint32
+}
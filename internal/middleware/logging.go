@@ -1,34 +1,44 @@
 package middleware
 
 import (
+	"net/http"
 	"time"
 
+	"healthcare-api/internal/models"
+
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
-// Logger middleware provides structured logging
-func Logger(logger *logrus.Logger) gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		// Generate request ID
-		requestID := uuid.New().String()
-		
+// Logger middleware provides structured logging. Each line carries the request
+// ID set by RequestID, which is assigned here if RequestID has not run. Lines
+// are written for the requests sampler samples.
+func Logger(logger *logrus.Logger, sampler *LogSampler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		requestID := ensureRequestID(c)
+
+		c.Next()
+
+		latency := time.Since(start)
+		if !sampler.Sampled(requestID, c.Writer.Status(), latency) {
+			return
+		}
+
 		// Log structured data
 		logger.WithFields(logrus.Fields{
 			"request_id":   requestID,
-			"timestamp":    param.TimeStamp.Format(time.RFC3339),
-			"status":       param.StatusCode,
-			"latency":      param.Latency,
-			"client_ip":    param.ClientIP,
-			"method":       param.Method,
-			"path":         param.Path,
-			"user_agent":   param.Request.UserAgent(),
-			"error":        param.ErrorMessage,
+			"timestamp":    start.Format(time.RFC3339),
+			"status":       c.Writer.Status(),
+			"latency":      latency,
+			"client_ip":    c.ClientIP(),
+			"method":       c.Request.Method,
+			"path":         path,
+			"user_agent":   c.Request.UserAgent(),
+			"error":        c.Errors.ByType(gin.ErrorTypePrivate).String(),
 		}).Info("HTTP Request")
-
-		return ""
-	})
+	}
 }
 
 // Recovery middleware provides panic recovery with logging
@@ -40,10 +50,10 @@ func Recovery(logger *logrus.Logger) gin.HandlerFunc {
 			"method":     c.Request.Method,
 			"client_ip":  c.ClientIP(),
 			"user_agent": c.Request.UserAgent(),
+			"request_id": c.GetString("request_id"),
 		}).Error("Panic recovered")
 
-		c.JSON(500, gin.H{
-			"error": "Internal server error",
-		})
+		c.JSON(http.StatusInternalServerError, models.NewOperationOutcome("error", "exception",
+			"Internal server error, request ID "+c.GetString("request_id")))
 	})
 }
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"healthcare-api/internal/maintenance"
+	"healthcare-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maintenanceRoutes are the writes served in maintenance mode, the ones that
+// switch it off
+var maintenanceRoutes = map[string]bool{
+	"/api/v1/admin/maintenance":   true,
+	"/api/v1/admin/config/reload": true,
+}
+
+// Maintenance middleware refuses writes with 503 Service Unavailable and
+// Retry-After while maintenance mode is on. Reads, the health routes and the
+// routes that switch maintenance mode off are served as usual.
+func Maintenance(mode *maintenance.Mode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		route := c.FullPath()
+		if probeRoutes[route] || maintenanceRoutes[route] || !mode.Enabled() {
+			c.Next()
+			return
+		}
+
+		status := mode.Status()
+		message := "The API is in maintenance mode and refuses writes, retry later"
+		if status.Message != nil && *status.Message != "" {
+			message = *status.Message
+		}
+		c.Header("Retry-After", strconv.Itoa(status.RetryAfter))
+		c.JSON(http.StatusServiceUnavailable, models.NewErrorOutcome(models.ErrorCodeMaintenanceMode, message))
+		c.Abort()
+	}
+}
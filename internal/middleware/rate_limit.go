@@ -1,35 +1,73 @@
 package middleware
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"healthcare-api/internal/config"
 	"healthcare-api/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
-// RateLimiter implements token bucket rate limiting
+// RateLimiter implements token bucket rate limiting. Requests with a valid token
+// are limited per token subject by LimitSubject; requests without one, or with one
+// that does not verify, are limited per client IP by RateLimit. SetConfig changes
+// the limits while the server runs.
 type RateLimiter struct {
-	limiters map[string]*rate.Limiter
-	mu       sync.RWMutex
-	rate     rate.Limit
-	burst    int
+	limiters   map[string]*rate.Limiter
+	mu         sync.RWMutex
+	anonymous  config.RateTier
+	standard   config.RateTier
+	scopeTiers map[string]config.RateTier
+	// verify reports whether an Authorization header carries a valid token.
+	// Without it every request is limited per client IP.
+	verify func(authHeader string) bool
 }
 
 // NewRateLimiter creates a new rate limiter
-func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
 	return &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rate:     rate.Limit(requestsPerSecond),
-		burst:    burst,
+		limiters:   make(map[string]*rate.Limiter),
+		anonymous:  config.RateTier{PerMinute: cfg.AnonymousPerMinute, Burst: cfg.AnonymousBurst},
+		standard:   config.RateTier{PerMinute: cfg.PerMinute, Burst: cfg.Burst},
+		scopeTiers: cfg.ScopeTiers,
 	}
 }
 
-// getLimiter gets or creates a limiter for a client
-func (rl *RateLimiter) getLimiter(clientID string) *rate.Limiter {
+// SetConfig replaces the limits. Clients start again with a full bucket at
+// their new rate.
+func (rl *RateLimiter) SetConfig(cfg config.RateLimitConfig) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.limiters = make(map[string]*rate.Limiter)
+	rl.anonymous = config.RateTier{PerMinute: cfg.AnonymousPerMinute, Burst: cfg.AnonymousBurst}
+	rl.standard = config.RateTier{PerMinute: cfg.PerMinute, Burst: cfg.Burst}
+	rl.scopeTiers = cfg.ScopeTiers
+}
+
+// SetTokenVerifier sets the check RateLimit uses to tell requests with a valid
+// token, which it leaves to LimitSubject, from those it limits per client IP
+func (rl *RateLimiter) SetTokenVerifier(verify func(authHeader string) bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.verify = verify
+}
+
+// tiers returns the current limits, read together so a concurrent SetConfig
+// is seen whole
+func (rl *RateLimiter) tiers() (anonymous, standard config.RateTier, scopeTiers map[string]config.RateTier) {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.anonymous, rl.standard, rl.scopeTiers
+}
+
+// getLimiter gets or creates the limiter of a client
+func (rl *RateLimiter) getLimiter(clientID string, tier config.RateTier) *rate.Limiter {
 	rl.mu.RLock()
 	limiter, exists := rl.limiters[clientID]
 	rl.mu.RUnlock()
@@ -38,7 +76,7 @@ func (rl *RateLimiter) getLimiter(clientID string) *rate.Limiter {
 		rl.mu.Lock()
 		// Double-check after acquiring write lock
 		if limiter, exists = rl.limiters[clientID]; !exists {
-			limiter = rate.NewLimiter(rl.rate, rl.burst)
+			limiter = rate.NewLimiter(rate.Limit(float64(tier.PerMinute)/60), tier.Burst)
 			rl.limiters[clientID] = limiter
 		}
 		rl.mu.Unlock()
@@ -47,24 +85,75 @@ func (rl *RateLimiter) getLimiter(clientID string) *rate.Limiter {
 	return limiter
 }
 
-// RateLimit middleware applies rate limiting per client IP
+// RateLimit middleware limits requests per client IP, except health probes and
+// requests with a valid token, which are left to LimitSubject. A token that does
+// not verify counts as none, so junk tokens cannot bypass the IP limit.
 func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		clientIP := c.ClientIP()
-		limiter := rl.getLimiter(clientIP)
-
-		if !limiter.Allow() {
-			c.Header("X-RateLimit-Limit", "100")
-			c.Header("X-RateLimit-Remaining", "0")
-			c.Header("X-RateLimit-Reset", time.Now().Add(time.Minute).Format(time.RFC3339))
-			
-			c.JSON(http.StatusTooManyRequests, models.NewOperationOutcome("error", "throttled", "Rate limit exceeded"))
-			c.Abort()
+		if probeRoutes[c.FullPath()] || rl.verified(c.GetHeader("Authorization")) {
+			c.Next()
+			return
+		}
+
+		anonymous, _, _ := rl.tiers()
+		rl.limit(c, "ip:"+c.ClientIP(), anonymous)
+	}
+}
+
+// verified reports whether authHeader carries a token the verifier accepts
+func (rl *RateLimiter) verified(authHeader string) bool {
+	if authHeader == "" {
+		return false
+	}
+	rl.mu.RLock()
+	verify := rl.verify
+	rl.mu.RUnlock()
+	return verify != nil && verify(authHeader)
+}
+
+// LimitSubject middleware limits requests per authenticated subject, at the most
+// generous tier among the token's scopes. It must run after RequireAuth.
+func (rl *RateLimiter) LimitSubject() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		anonymous, standard, scopeTiers := rl.tiers()
+		userID, _, _, scopes := GetUserFromContext(c)
+		if userID == "" {
+			rl.limit(c, "ip:"+c.ClientIP(), anonymous)
 			return
 		}
 
-		c.Next()
+		tier, tierName := standard, ""
+		for _, scope := range scopes {
+			if scopeTier, ok := scopeTiers[scope]; ok && scopeTier.PerMinute > tier.PerMinute {
+				tier, tierName = scopeTier, scope
+			}
+		}
+		// The tier is part of the key so a token with new scopes gets a new bucket
+		rl.limit(c, "sub:"+userID+"|"+tierName, tier)
+	}
+}
+
+// limit takes a token from the client's bucket, or rejects the request when it is empty
+func (rl *RateLimiter) limit(c *gin.Context, clientID string, tier config.RateTier) {
+	limiter := rl.getLimiter(clientID, tier)
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(tier.PerMinute))
+	if !limiter.Allow() {
+		retryAfter := time.Minute
+		if tier.PerMinute > 0 {
+			retryAfter = time.Duration(float64(time.Minute) / float64(tier.PerMinute))
+		}
+		c.Header("X-RateLimit-Remaining", "0")
+		c.Header("X-RateLimit-Reset", time.Now().Add(retryAfter).UTC().Format(time.RFC3339))
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+		c.JSON(http.StatusTooManyRequests, models.NewOperationOutcome("error", "throttled", "Rate limit exceeded"))
+		c.Abort()
+		return
 	}
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(int(limiter.Tokens())))
+
+	c.Next()
 }
 
 // Cleanup removes old limiters to prevent memory leaks
@@ -75,7 +164,7 @@ func (rl *RateLimiter) Cleanup() {
 			rl.mu.Lock()
 			// Remove limiters that haven't been used recently
 			for clientID, limiter := range rl.limiters {
-				if limiter.Tokens() == float64(rl.burst) {
+				if limiter.Tokens() == float64(limiter.Burst()) {
 					delete(rl.limiters, clientID)
 				}
 			}
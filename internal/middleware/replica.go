@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"healthcare-api/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadYourWrites pins every query of a non-read request to the primary database,
+// so reads made while handling a write observe that write
+func ReadYourWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			c.Request = c.Request.WithContext(database.WithPrimary(c.Request.Context()))
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"healthcare-api/internal/requestctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// RequestIDHeader carries the request ID in requests and responses
+	RequestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds a client-supplied request ID, matching the
+	// audit_logs.request_id column
+	maxRequestIDLength = 255
+)
+
+// RequestID middleware gives each request an ID, taken from the X-Request-ID
+// header when a client or proxy sent a usable one and generated otherwise. The
+// ID is stored on the gin context and the request context and echoed in the
+// response header. It must run first so every later log line carries the ID.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		setRequestID(c, requestID)
+
+		c.Next()
+	}
+}
+
+// ensureRequestID returns the ID of the request, assigning one if RequestID has
+// not run
+func ensureRequestID(c *gin.Context) string {
+	if requestID := c.GetString("request_id"); requestID != "" {
+		return requestID
+	}
+
+	requestID := uuid.New().String()
+	setRequestID(c, requestID)
+	return requestID
+}
+
+func setRequestID(c *gin.Context, requestID string) {
+	c.Set("request_id", requestID)
+	c.Header(RequestIDHeader, requestID)
+	c.Request = c.Request.WithContext(requestctx.WithRequestID(c.Request.Context(), requestID))
+}
+
+// validRequestID reports whether a client-supplied request ID may be used. Only
+// printable ASCII without spaces or quotes is accepted, so the ID cannot forge
+// log lines or headers.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		if r <= ' ' || r > '~' || r == '"' || r == '\\' {
+			return false
+		}
+	}
+	return true
+}
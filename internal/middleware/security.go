@@ -2,6 +2,11 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"healthcare-api/internal/config"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,34 +41,64 @@ func Security() gin.HandlerFunc {
 	}
 }
 
-// CORS middleware handles Cross-Origin Resource Sharing
-func CORS() gin.HandlerFunc {
+// CORSPolicy is the cross-origin policy applied by its CORS middleware, which
+// SetConfig replaces while the server runs
+type CORSPolicy struct {
+	policy atomic.Pointer[corsPolicy]
+}
+
+// corsPolicy is a CORS configuration with its header values joined once
+type corsPolicy struct {
+	origins     []string
+	credentials bool
+	methods     string
+	headers     string
+	exposed     string
+	maxAge      string
+}
+
+// NewCORSPolicy creates the policy of cfg
+func NewCORSPolicy(cfg config.CORSConfig) *CORSPolicy {
+	p := &CORSPolicy{}
+	p.SetConfig(cfg)
+	return p
+}
+
+// SetConfig replaces the policy with that of cfg
+func (p *CORSPolicy) SetConfig(cfg config.CORSConfig) {
+	p.policy.Store(&corsPolicy{
+		origins:     cfg.AllowedOrigins,
+		credentials: cfg.AllowCredentials,
+		methods:     strings.Join(cfg.AllowedMethods, ", "),
+		headers:     strings.Join(cfg.AllowedHeaders, ", "),
+		exposed:     strings.Join(cfg.ExposedHeaders, ", "),
+		maxAge:      strconv.Itoa(cfg.MaxAge),
+	})
+}
+
+// CORS middleware handles Cross-Origin Resource Sharing. Requests from an
+// allowed origin get the CORS headers; preflight requests are answered here.
+func (p *CORSPolicy) CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		policy := p.policy.Load()
+		c.Header("Vary", "Origin")
+
 		origin := c.Request.Header.Get("Origin")
-		
-		// In production, you should maintain a whitelist of allowed origins
-		allowedOrigins := []string{
-			"https://localhost:3000",
-			"https://healthcare-app.example.com",
-		}
-		
-		isAllowed := false
-		for _, allowedOrigin := range allowedOrigins {
-			if origin == allowedOrigin {
-				isAllowed = true
-				break
+		if allowed, pattern := matchOrigin(policy.origins, origin); allowed {
+			// Credentials are never allowed for any origin at all
+			if pattern == "*" {
+				c.Header("Access-Control-Allow-Origin", "*")
+			} else {
+				c.Header("Access-Control-Allow-Origin", origin)
+				if policy.credentials {
+					c.Header("Access-Control-Allow-Credentials", "true")
+				}
 			}
+			c.Header("Access-Control-Allow-Methods", policy.methods)
+			c.Header("Access-Control-Allow-Headers", policy.headers)
+			c.Header("Access-Control-Expose-Headers", policy.exposed)
+			c.Header("Access-Control-Max-Age", policy.maxAge)
 		}
-		
-		if isAllowed {
-			c.Header("Access-Control-Allow-Origin", origin)
-		}
-		
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, Location")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Max-Age", "86400")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -74,10 +109,35 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
-// RequestID middleware adds a unique request ID to each request
-func RequestID() gin.HandlerFunc {
-	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
-		c.Header("X-Request-ID", c.GetString("request_id"))
-		c.AbortWithStatus(http.StatusInternalServerError)
-	})
+// matchOrigin reports whether origin is allowed, and by which pattern. A pattern
+// is an exact origin, "*", or an origin whose host starts with "*." to match
+// any subdomain, e.g. "https://*.example.com" matches "https://app.example.com"
+// but not "https://example.com".
+func matchOrigin(patterns []string, origin string) (bool, string) {
+	if origin == "" {
+		return false, ""
+	}
+	origin = strings.ToLower(origin)
+
+	for _, pattern := range patterns {
+		lower := strings.ToLower(pattern)
+		switch wildcard := strings.Index(lower, "://*."); {
+		case lower == "*":
+			return true, pattern
+		case wildcard < 0:
+			if origin == lower {
+				return true, pattern
+			}
+		default:
+			prefix, suffix := lower[:wildcard+3], lower[wildcard+4:]
+			if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+				continue
+			}
+			// The subdomain must not reach into the scheme, port or path
+			if subdomain := origin[len(prefix) : len(origin)-len(suffix)]; !strings.ContainsAny(subdomain, ":/@") {
+				return true, pattern
+			}
+		}
+	}
+	return false, ""
 }
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+// TenantMiddleware resolves the tenant for authenticated requests and applies its rate limit
+type TenantMiddleware struct {
+	tenants       *service.TenantService
+	defaultTenant string
+	limiters      map[string]*rate.Limiter
+	mu            sync.Mutex
+	logger        *logrus.Logger
+}
+
+// NewTenantMiddleware creates a tenant middleware. Tokens without a tenant_id
+// claim are assigned defaultTenant; an empty defaultTenant rejects them.
+func NewTenantMiddleware(tenants *service.TenantService, defaultTenant string, logger *logrus.Logger) *TenantMiddleware {
+	return &TenantMiddleware{
+		tenants:       tenants,
+		defaultTenant: defaultTenant,
+		limiters:      make(map[string]*rate.Limiter),
+		logger:        logger,
+	}
+}
+
+// RequireTenant scopes the request to the tenant named in the token.
+// It must run after RequireAuth.
+func (t *TenantMiddleware) RequireTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.GetString("tenant_id")
+		if tenantID == "" {
+			tenantID = t.defaultTenant
+		}
+		if tenantID == "" {
+			t.logger.Warn("Token has no tenant")
+			c.JSON(http.StatusForbidden, models.NewOperationOutcome("error", "security", "Token is not associated with a tenant"))
+			c.Abort()
+			return
+		}
+
+		tenant, err := t.tenants.ResolveTenant(c.Request.Context(), tenantID)
+		if err != nil {
+			t.logger.WithError(err).WithField("tenant_id", tenantID).Warn("Failed to resolve tenant")
+			c.JSON(http.StatusForbidden, models.NewOperationOutcome("error", "security", "Unknown tenant"))
+			c.Abort()
+			return
+		}
+
+		if !tenant.IsActive() {
+			t.logger.WithField("tenant_id", tenantID).Warn("Request for suspended tenant")
+			c.JSON(http.StatusForbidden, models.NewOperationOutcome("error", "suspended", "Tenant is suspended"))
+			c.Abort()
+			return
+		}
+
+		limiter := t.getLimiter(tenant)
+		if !limiter.Allow() {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(tenant.RateLimitPerMinute))
+			c.Header("X-RateLimit-Remaining", "0")
+			c.JSON(http.StatusTooManyRequests, models.NewOperationOutcome("error", "throttled", "Tenant rate limit exceeded"))
+			c.Abort()
+			return
+		}
+
+		c.Set("tenant_id", tenant.ID)
+		c.Request = c.Request.WithContext(requestctx.WithTenantID(c.Request.Context(), tenant.ID))
+
+		c.Next()
+	}
+}
+
+// getLimiter returns the tenant's limiter, adjusting it when the tenant's limits change
+func (t *TenantMiddleware) getLimiter(tenant *models.Tenant) *rate.Limiter {
+	limit := rate.Limit(float64(tenant.RateLimitPerMinute) / 60)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	limiter, exists := t.limiters[tenant.ID]
+	if !exists {
+		limiter = rate.NewLimiter(limit, tenant.RateLimitBurst)
+		t.limiters[tenant.ID] = limiter
+		return limiter
+	}
+
+	if limiter.Limit() != limit {
+		limiter.SetLimit(limit)
+	}
+	if limiter.Burst() != tenant.RateLimitBurst {
+		limiter.SetBurst(tenant.RateLimitBurst)
+	}
+
+	return limiter
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Request classes with their own deadline
+const (
+	RequestClassRead   = "read"
+	RequestClassSearch = "search"
+	RequestClassWrite  = "write"
+	RequestClassAdmin  = "admin"
+)
+
+// RequestTimeout gives each request a deadline by its class, carried by the
+// request context so database queries and other work still running when it
+// passes are cancelled rather than left to finish for a client that has given up
+func RequestTimeout(cfg config.RequestTimeoutConfig, logger *logrus.Logger) gin.HandlerFunc {
+	timeouts := map[string]time.Duration{
+		RequestClassRead:   time.Duration(cfg.Read) * time.Second,
+		RequestClassSearch: time.Duration(cfg.Search) * time.Second,
+		RequestClassWrite:  time.Duration(cfg.Write) * time.Second,
+		RequestClassAdmin:  time.Duration(cfg.Admin) * time.Second,
+	}
+
+	return func(c *gin.Context) {
+		class := requestClass(c)
+		timeout := timeouts[class]
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			logger.WithFields(logrus.Fields{
+				"method":  c.Request.Method,
+				"path":    c.FullPath(),
+				"class":   class,
+				"timeout": timeout,
+				"status":  c.Writer.Status(),
+			}).Warn("Request exceeded its deadline")
+		}
+	}
+}
+
+// requestClass classifies a request by its method and matched route
+func requestClass(c *gin.Context) string {
+	route := c.FullPath()
+	switch {
+	case strings.HasPrefix(route, "/api/v1/admin/"):
+		return RequestClassAdmin
+	case c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead:
+		return RequestClassWrite
+	case strings.HasSuffix(route, "/:id"):
+		return RequestClassRead
+	default:
+		return RequestClassSearch
+	}
+}
@@ -1,14 +1,26 @@
 package middleware
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
+	"healthcare-api/internal/identifier"
 	"healthcare-api/internal/models"
+	"healthcare-api/internal/terminology"
 	"healthcare-api/internal/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
+// validatedRequestKey is the gin context key of the request body bound and
+// validated by ValidationMiddleware
+const validatedRequestKey = "validated_request"
+
+// validationWarningsKey is the gin context key of the warnings found validating
+// a request body that passed
+const validationWarningsKey = "validation_warnings"
+
 // ValidationMiddleware provides request validation
 type ValidationMiddleware struct {
 	validator *validation.Validator
@@ -21,123 +33,113 @@ func NewValidationMiddleware() *ValidationMiddleware {
 	}
 }
 
+// SetSNOMED checks the SNOMED CT codings of observations with snomed
+func (vm *ValidationMiddleware) SetSNOMED(snomed *terminology.SNOMEDValidator) {
+	vm.validator.SetSNOMED(snomed)
+}
+
+// SetIdentifiers checks the identifiers of patients with identifiers
+func (vm *ValidationMiddleware) SetIdentifiers(identifiers *identifier.Validator) {
+	vm.validator.SetIdentifiers(identifiers)
+}
+
 // ValidatePatientCreate validates patient creation requests
 func (vm *ValidationMiddleware) ValidatePatientCreate() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req models.PatientCreateRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid JSON: "+err.Error()))
-			c.Abort()
-			return
-		}
-
-		if validationErrors := vm.validator.ValidatePatientCreate(&req); validationErrors != nil {
-			outcome := models.NewOperationOutcome("error", "invalid", "Validation failed")
-			for _, validationError := range validationErrors.Errors {
-				outcome.Issue = append(outcome.Issue, models.OperationOutcomeIssue{
-					Severity:    "error",
-					Code:        "invalid",
-					Diagnostics: &validationError.Message,
-					Expression:  []string{validationError.Field},
-				})
-			}
-			c.JSON(http.StatusUnprocessableEntity, outcome)
-			c.Abort()
-			return
-		}
-
-		// Store validated request in context
-		c.Set("validated_request", &req)
-		c.Next()
+		validateBody(c, vm.validator.ValidatePatientCreate)
 	}
 }
 
 // ValidatePatientUpdate validates patient update requests
 func (vm *ValidationMiddleware) ValidatePatientUpdate() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req models.PatientUpdateRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid JSON: "+err.Error()))
-			c.Abort()
-			return
-		}
-
-		if validationErrors := vm.validator.ValidatePatientUpdate(&req); validationErrors != nil {
-			outcome := models.NewOperationOutcome("error", "invalid", "Validation failed")
-			for _, validationError := range validationErrors.Errors {
-				outcome.Issue = append(outcome.Issue, models.OperationOutcomeIssue{
-					Severity:    "error",
-					Code:        "invalid",
-					Diagnostics: &validationError.Message,
-					Expression:  []string{validationError.Field},
-				})
-			}
-			c.JSON(http.StatusUnprocessableEntity, outcome)
-			c.Abort()
-			return
-		}
-
-		c.Set("validated_request", &req)
-		c.Next()
+		validateBody(c, vm.validator.ValidatePatientUpdate)
 	}
 }
 
 // ValidateObservationCreate validates observation creation requests
 func (vm *ValidationMiddleware) ValidateObservationCreate() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req models.ObservationCreateRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid JSON: "+err.Error()))
-			c.Abort()
-			return
-		}
-
-		if validationErrors := vm.validator.ValidateObservationCreate(&req); validationErrors != nil {
-			outcome := models.NewOperationOutcome("error", "invalid", "Validation failed")
-			for _, validationError := range validationErrors.Errors {
-				outcome.Issue = append(outcome.Issue, models.OperationOutcomeIssue{
-					Severity:    "error",
-					Code:        "invalid",
-					Diagnostics: &validationError.Message,
-					Expression:  []string{validationError.Field},
-				})
-			}
-			c.JSON(http.StatusUnprocessableEntity, outcome)
-			c.Abort()
-			return
-		}
-
-		c.Set("validated_request", &req)
-		c.Next()
+		validateBody(c, vm.validator.ValidateObservationCreate)
 	}
 }
 
 // ValidateObservationUpdate validates observation update requests
 func (vm *ValidationMiddleware) ValidateObservationUpdate() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req models.ObservationUpdateRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
+		validateBody(c, vm.validator.ValidateObservationUpdate)
+	}
+}
+
+// ValidatedRequest returns the request body bound and validated by
+// ValidationMiddleware. The middleware consumes the body, so handlers read the
+// request from here rather than binding it again. It reports false when the
+// route has no validation middleware for T.
+func ValidatedRequest[T any](c *gin.Context) (*T, bool) {
+	value, exists := c.Get(validatedRequestKey)
+	if !exists {
+		return nil, false
+	}
+	req, ok := value.(*T)
+	return req, ok
+}
+
+// ValidationWarnings returns the warnings found validating the request body,
+// such as codes outside a value set that is not enforced, as OperationOutcome
+// issues
+func ValidationWarnings(c *gin.Context) []models.OperationOutcomeIssue {
+	value, _ := c.Get(validationWarningsKey)
+	issues, _ := value.([]models.OperationOutcomeIssue)
+	return issues
+}
+
+// validateBody binds the JSON request body to a T, validates it with validate and
+// stores it for ValidatedRequest, or responds 400 or 422 and aborts. Warnings do
+// not fail the request; they are stored for ValidationWarnings, or reported
+// alongside the errors of a failed one.
+func validateBody[T any](c *gin.Context, validate func(*T) *models.ValidationErrors) {
+	var req T
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "required", "Request body is required"))
+		} else {
 			c.JSON(http.StatusBadRequest, models.NewOperationOutcome("error", "invalid", "Invalid JSON: "+err.Error()))
-			c.Abort()
-			return
 		}
+		c.Abort()
+		return
+	}
 
-		if validationErrors := vm.validator.ValidateObservationUpdate(&req); validationErrors != nil {
-			outcome := models.NewOperationOutcome("error", "invalid", "Validation failed")
-			for _, validationError := range validationErrors.Errors {
-				outcome.Issue = append(outcome.Issue, models.OperationOutcomeIssue{
-					Severity:    "error",
-					Code:        "invalid",
-					Diagnostics: &validationError.Message,
-					Expression:  []string{validationError.Field},
-				})
+	var issues []models.OperationOutcomeIssue
+	failed := false
+	if validationErrors := validate(&req); validationErrors != nil {
+		for _, validationError := range validationErrors.Errors {
+			severity := "error"
+			if validationError.Severity == "warning" {
+				severity = "warning"
+			} else {
+				failed = true
 			}
-			c.JSON(http.StatusUnprocessableEntity, outcome)
-			c.Abort()
-			return
+			message := validationError.Message
+			issues = append(issues, models.OperationOutcomeIssue{
+				Severity:    severity,
+				Code:        "invalid",
+				Diagnostics: &message,
+				Expression:  []string{validationError.Field},
+			})
 		}
+	}
+
+	if failed {
+		outcome := models.NewErrorOutcome(models.ErrorCodeValidationFailed, "Validation failed")
+		outcome.Issue = append(outcome.Issue, issues...)
+		c.JSON(http.StatusUnprocessableEntity, outcome)
+		c.Abort()
+		return
+	}
 
-		c.Set("validated_request", &req)
-		c.Next()
+	c.Set(validatedRequestKey, &req)
+	if len(issues) > 0 {
+		c.Set(validationWarningsKey, issues)
 	}
+	c.Next()
 }
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/identifier"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/terminology"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newValidationRouter serves the validated create and update routes of
+// patients and observations, with the identifier plugins and SNOMED CT checks
+// enabled. Each handler responds 200 with the request it was given.
+func newValidationRouter(t testing.TB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	vm := NewValidationMiddleware()
+	identifiers, err := identifier.NewValidator([]string{"nhs", "nin", "ssn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm.SetIdentifiers(identifiers)
+	snomed, err := terminology.NewSNOMEDValidator(config.TerminologyConfig{SNOMEDValueSetSeverity: terminology.SeverityError})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm.SetSNOMED(snomed)
+
+	router := gin.New()
+	router.POST("/patients", vm.ValidatePatientCreate(), echoValidated[models.PatientCreateRequest])
+	router.PUT("/patients", vm.ValidatePatientUpdate(), echoValidated[models.PatientUpdateRequest])
+	router.POST("/observations", vm.ValidateObservationCreate(), echoValidated[models.ObservationCreateRequest])
+	router.PUT("/observations", vm.ValidateObservationUpdate(), echoValidated[models.ObservationUpdateRequest])
+	return router
+}
+
+func echoValidated[T any](c *gin.Context) {
+	req, ok := ValidatedRequest[T](c)
+	if !ok {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// checkValidation sends body to each route of path and checks the response is
+// the validated request or an OperationOutcome
+func checkValidation(t *testing.T, router *gin.Engine, path string, body []byte) {
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		switch w.Code {
+		case http.StatusOK:
+			if !json.Valid(w.Body.Bytes()) {
+				t.Fatalf("%s %s: validated request %q is not JSON", method, path, w.Body.Bytes())
+			}
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+			var outcome models.OperationOutcome
+			if err := json.Unmarshal(w.Body.Bytes(), &outcome); err != nil || outcome.ResourceType != "OperationOutcome" || len(outcome.Issue) == 0 {
+				t.Fatalf("%s %s: %d response %q is not an OperationOutcome", method, path, w.Code, w.Body.Bytes())
+			}
+		default:
+			t.Fatalf("%s %s: unexpected status %d for %q", method, path, w.Code, body)
+		}
+	}
+}
+
+func FuzzValidatePatient(f *testing.F) {
+	for _, seed := range []string{
+		`{"name":[{"family":"Doe","given":["John"]}],"gender":"male","birthDate":"1980-01-15T00:00:00Z"}`,
+		`{"name":[{"use":"official","family":"Doe"}],"identifier":[{"system":"https://fhir.nhs.uk/Id/nhs-number","value":"9434765919"}]}`,
+		`{"name":[{"family":"Doe"}],"identifier":[{"system":"http://hl7.org/fhir/sid/us-ssn","value":"000-00-0000"}]}`,
+		`{"name":[{"family":"Doe"}],"telecom":[{"system":"email","value":"a@b","use":"home"}],"address":[{"use":"home","line":["1 Main"]}]}`,
+		`{"name":[{"use":"bogus"}],"gender":"x"}`,
+		`{"name":[]}`,
+		`{"name":null}`,
+		`{"active":"yes"}`,
+		`[]`,
+		`{`,
+		``,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	router := newValidationRouter(f)
+	f.Fuzz(func(t *testing.T, body []byte) {
+		checkValidation(t, router, "/patients", body)
+	})
+}
+
+func FuzzValidateObservation(f *testing.F) {
+	for _, seed := range []string{
+		`{"status":"final","code":{"coding":[{"system":"http://loinc.org","code":"8867-4"}]},"subject":{"reference":"Patient/1"},"valueQuantity":{"value":72,"unit":"/min"}}`,
+		`{"status":"final","code":{"text":"ECG"},"subject":{"reference":"Patient/1"},"valueSampledData":{"origin":{"value":0},"period":4,"dimensions":1,"data":"1 2 3 E L U"}}`,
+		`{"status":"final","code":{"text":"BP"},"subject":{"reference":"Patient/1"},"component":[{"code":{"text":"sys"},"valueQuantity":{"value":120}}]}`,
+		`{"status":"final","code":{"text":"x"},"subject":{"reference":"Patient/1"},"bodySite":{"coding":[{"system":"http://snomed.info/sct","code":"368209003"}]}}`,
+		`{"status":"final","code":{"text":"x"},"subject":{"reference":"Patient/1"},"interpretation":[{"coding":[{"system":"http://snomed.info/sct","code":"not-a-code"}]}]}`,
+		`{"status":"bogus"}`,
+		`{"status":"final","code":{},"subject":{}}`,
+		`{"effectiveDateTime":"yesterday"}`,
+		`null`,
+		``,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	router := newValidationRouter(f)
+	f.Fuzz(func(t *testing.T, body []byte) {
+		checkValidation(t, router, "/observations", body)
+	})
+}
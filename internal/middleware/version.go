@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiVersionKey is the gin context key of the API version serving a request
+const apiVersionKey = "api_version"
+
+// APIVersion middleware marks the requests of a route group as served by an
+// API version, e.g. "v2", so handlers respond in that version's representation
+func APIVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Next()
+	}
+}
+
+// GetAPIVersion returns the API version serving the request, "v1" when none is set
+func GetAPIVersion(c *gin.Context) string {
+	if version := c.GetString(apiVersionKey); version != "" {
+		return version
+	}
+	return "v1"
+}
+
+// Deprecated middleware announces that the endpoints of a route group are to be
+// removed: the Deprecation header (RFC 9745) gives when they were deprecated,
+// Sunset (RFC 8594) when they stop working, and a successor-version Link the same
+// path under the successor prefix. Zero times and an empty successor are omitted.
+func Deprecated(deprecatedAt, sunset time.Time, prefix, successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !deprecatedAt.IsZero() {
+			c.Header("Deprecation", "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+		}
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if successor != "" && strings.HasPrefix(c.Request.URL.Path, prefix) {
+			c.Header("Link", "<"+successor+strings.TrimPrefix(c.Request.URL.Path, prefix)+`>; rel="successor-version"`)
+		}
+		c.Next()
+	}
+}
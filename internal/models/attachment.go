@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StoredAttachment is an attachment whose content is kept in the object store.
+// Resources refer to it through its URL.
+type StoredAttachment struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	ContentType string    `json:"contentType" db:"content_type"`
+	Size        int64     `json:"size" db:"size"`
+	// Hash is the base64 SHA-1 of the content, as in Attachment.hash
+	Hash      string    `json:"hash" db:"hash"`
+	Title     *string   `json:"title,omitempty" db:"title"`
+	ObjectKey string    `json:"-" db:"object_key"`
+	CreatedBy *string   `json:"createdBy,omitempty" db:"created_by"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// AttachmentDownload is a link to an attachment's content that needs no token
+type AttachmentDownload struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent represents a FHIR R4 AuditEvent resource built from an audit log entry
+type AuditEvent struct {
+	ResourceType string             `json:"resourceType"`
+	ID           uuid.UUID          `json:"id"`
+	Type         Coding             `json:"type"`
+	Subtype      []Coding           `json:"subtype,omitempty"`
+	Action       string             `json:"action,omitempty"`
+	Recorded     time.Time          `json:"recorded"`
+	Outcome      string             `json:"outcome,omitempty"`
+	Agent        []AuditEventAgent  `json:"agent"`
+	Source       AuditEventSource   `json:"source"`
+	Entity       []AuditEventEntity `json:"entity,omitempty"`
+}
+
+// AuditEventAgent identifies who took part in the event
+type AuditEventAgent struct {
+	Who       *Reference         `json:"who,omitempty"`
+	Requestor bool               `json:"requestor"`
+	Network   *AuditEventNetwork `json:"network,omitempty"`
+}
+
+// AuditEventNetwork is the network location an agent acted from
+type AuditEventNetwork struct {
+	Address *string `json:"address,omitempty"`
+	Type    *string `json:"type,omitempty"`
+}
+
+// AuditEventSource identifies the system that recorded the event
+type AuditEventSource struct {
+	Site     *string   `json:"site,omitempty"`
+	Observer Reference `json:"observer"`
+}
+
+// AuditEventEntity is a resource the event was about
+type AuditEventEntity struct {
+	What   *Reference               `json:"what,omitempty"`
+	Type   *Coding                  `json:"type,omitempty"`
+	Detail []AuditEventEntityDetail `json:"detail,omitempty"`
+}
+
+// AuditEventEntityDetail carries additional information about an entity
+type AuditEventEntityDetail struct {
+	Type        string `json:"type"`
+	ValueString string `json:"valueString"`
+}
+
+// AuditEventListResponse represents a searchset bundle of audit events
+type AuditEventListResponse struct {
+	ResourceType string            `json:"resourceType"`
+	ID           string            `json:"id"`
+	Type         string            `json:"type"`
+	Total        int64             `json:"total"`
+	Entry        []AuditEventEntry `json:"entry"`
+	Link         []BundleLink      `json:"link,omitempty"`
+}
+
+// AuditEventEntry represents an audit event entry in a bundle
+type AuditEventEntry struct {
+	FullURL  string       `json:"fullUrl"`
+	Resource *AuditEvent  `json:"resource"`
+	Search   *SearchEntry `json:"search,omitempty"`
+}
@@ -19,6 +19,7 @@ type Resource struct {
 	CreatedAt         time.Time         `json:"createdAt" db:"created_at"`
 	UpdatedAt         time.Time         `json:"updatedAt" db:"updated_at"`
 	Version           int               `json:"version" db:"version"`
+	DeletedAt         *time.Time        `json:"deletedAt,omitempty" db:"deleted_at"`
 }
 
 // Meta contains metadata about a resource
@@ -44,6 +45,7 @@ type Extension struct {
 	ValueInteger       *int        `json:"valueInteger,omitempty"`
 	ValueBoolean       *bool       `json:"valueBoolean,omitempty"`
 	ValueDateTime      *time.Time  `json:"valueDateTime,omitempty"`
+	ValueURL           *string     `json:"valueUrl,omitempty"`
 	ValueCodeableConcept *CodeableConcept `json:"valueCodeableConcept,omitempty"`
 	Extension          []Extension `json:"extension,omitempty"`
 }
@@ -125,7 +127,7 @@ type HumanName struct {
 type Attachment struct {
 	ContentType *string    `json:"contentType,omitempty"`
 	Language    *string    `json:"language,omitempty"`
-	Data        *string    `json:"data,omitempty"`
+	Data        *string    `json:"data,omitempty" validate:"omitempty,base64"`
 	URL         *string    `json:"url,omitempty" validate:"omitempty,uri"`
 	Size        *int       `json:"size,omitempty"`
 	Hash        *string    `json:"hash,omitempty"`
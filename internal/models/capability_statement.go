@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// PathExtensionURL is the extension of a CapabilityStatement resource holding
+// the path it is served under, e.g. /api/v1/patients, as resource types are not
+// served under their FHIR type names
+const PathExtensionURL = "urn:healthcare-api:extension:path"
+
+// CapabilityStatement describes what the server supports: the interactions,
+// search parameters and operations of each resource type it serves
+type CapabilityStatement struct {
+	ResourceType string                    `json:"resourceType"`
+	Status       string                    `json:"status"`
+	Date         time.Time                 `json:"date"`
+	Kind         string                    `json:"kind"`
+	Software     CapabilitySoftware        `json:"software"`
+	FHIRVersion  string                    `json:"fhirVersion"`
+	Format       []string                  `json:"format"`
+	Rest         []CapabilityStatementRest `json:"rest"`
+}
+
+// CapabilitySoftware names the server software
+type CapabilitySoftware struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// CapabilityStatementRest describes the RESTful API of the server
+type CapabilityStatementRest struct {
+	Mode     string               `json:"mode"`
+	Resource []CapabilityResource `json:"resource"`
+}
+
+// CapabilityResource describes a resource type the server serves
+type CapabilityResource struct {
+	Type        string                  `json:"type"`
+	Extension   []Extension             `json:"extension,omitempty"`
+	Interaction []CapabilityInteraction `json:"interaction"`
+	// ConditionalRead is "full-support" when reads honor If-None-Match and
+	// If-Modified-Since, and "not-supported" otherwise
+	ConditionalRead string                  `json:"conditionalRead,omitempty"`
+	SearchParam     []CapabilitySearchParam `json:"searchParam,omitempty"`
+	Operation       []CapabilityOperation   `json:"operation,omitempty"`
+}
+
+// Path returns the path the resource type is served under, or "" without one
+func (r *CapabilityResource) Path() string {
+	for _, extension := range r.Extension {
+		if extension.URL == PathExtensionURL && extension.ValueString != nil {
+			return *extension.ValueString
+		}
+	}
+	return ""
+}
+
+// Supports reports whether the resource type supports an interaction, e.g. "read"
+func (r *CapabilityResource) Supports(interaction string) bool {
+	for _, i := range r.Interaction {
+		if i.Code == interaction {
+			return true
+		}
+	}
+	return false
+}
+
+// CapabilityInteraction is an interaction supported on a resource type, e.g.
+// "read" or "search-type"
+type CapabilityInteraction struct {
+	Code string `json:"code"`
+}
+
+// CapabilitySearchParam is a search parameter supported on a resource type
+type CapabilitySearchParam struct {
+	Name string `json:"name"`
+	// Type is the FHIR search parameter type, e.g. "token" or "reference"
+	Type          string `json:"type"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+// CapabilityOperation is an operation supported on a resource type, e.g. "trend"
+// for Observation/$trend
+type CapabilityOperation struct {
+	Name          string `json:"name"`
+	Definition    string `json:"definition"`
+	Documentation string `json:"documentation,omitempty"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cohort is a stored selection of patients, such as diabetic patients with an
+// HbA1c over 9% in the last six months. Its members are found when it is
+// evaluated, so they follow the data.
+type Cohort struct {
+	ID          uuid.UUID      `json:"id" db:"id"`
+	Name        string         `json:"name" db:"name"`
+	Description *string        `json:"description,omitempty" db:"description"`
+	Criteria    CohortCriteria `json:"criteria" db:"criteria"`
+	CreatedBy   *string        `json:"createdBy,omitempty" db:"created_by"`
+	CreatedAt   time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time      `json:"updatedAt" db:"updated_at"`
+}
+
+// CohortCriteria selects the live patients matching every criterion set
+type CohortCriteria struct {
+	Gender *string `json:"gender,omitempty" binding:"omitempty,oneof=male female other unknown"`
+	// MinAge and MaxAge bound the patients' age in whole years, inclusive
+	MinAge *int  `json:"minAge,omitempty" binding:"omitempty,min=0,max=150"`
+	MaxAge *int  `json:"maxAge,omitempty" binding:"omitempty,min=0,max=150"`
+	Active *bool `json:"active,omitempty"`
+	// Observations are results the patients must, or with exclude must not, have
+	Observations []CohortObservationCriterion `json:"observations,omitempty" binding:"omitempty,max=20,dive"`
+}
+
+// CohortObservationCriterion matches patients by their results of a code,
+// entered-in-error and cancelled ones aside
+type CohortObservationCriterion struct {
+	// Code is a code, or system|code, such as "http://loinc.org|4548-4"
+	Code string `json:"code" binding:"required,max=300"`
+	// Within limits the results to those effective in the last period, a
+	// number of minutes, hours, days or weeks such as "26w"
+	Within string `json:"within,omitempty" binding:"omitempty,max=20"`
+	// Comparator and Value match results whose exact quantity value compares
+	// to Value: gt, ge, lt, le, eq or ne
+	Comparator string   `json:"comparator,omitempty" binding:"omitempty,oneof=gt ge lt le eq ne"`
+	Value      *float64 `json:"value,omitempty"`
+	// Unit is the UCUM code of Value; values in other units are converted.
+	// Without it values are compared in whatever unit they have.
+	Unit string `json:"unit,omitempty" binding:"omitempty,max=50"`
+	// ValueCode matches results with a coded value, a code or system|code,
+	// such as a diagnosis coded with SNOMED CT
+	ValueCode string `json:"valueCode,omitempty" binding:"omitempty,max=300"`
+	// Exclude selects the patients without a matching result instead
+	Exclude bool `json:"exclude,omitempty"`
+}
+
+// CohortRequest represents the request to create or replace a cohort
+type CohortRequest struct {
+	Name        string         `json:"name" binding:"required,max=255"`
+	Description *string        `json:"description,omitempty" binding:"omitempty,max=2000"`
+	Criteria    CohortCriteria `json:"criteria"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ConfigReload is the outcome of a configuration reload
+type ConfigReload struct {
+	ReloadedAt time.Time `json:"reloadedAt"`
+	// Source is what asked for the reload: "api" or the signal, e.g. "SIGHUP"
+	Source string `json:"source"`
+	// Changes are the settings the reload changed, by name
+	Changes []ConfigChange `json:"changes"`
+}
+
+// ConfigChange is a setting changed by a configuration reload, named by its
+// environment variable
+type ConfigChange struct {
+	Setting string `json:"setting"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CriticalValueRule flags observations of a code as critical when their value
+// is below Low or above High, whatever their reference ranges say
+type CriticalValueRule struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	Name   string    `json:"name" db:"name"`
+	System string    `json:"system" db:"code_system"`
+	Code   string    `json:"code" db:"code"`
+	// Unit is the UCUM code of the thresholds; values in other units are converted
+	Unit      string    `json:"unit" db:"unit"`
+	Low       *float64  `json:"low,omitempty" db:"low"`
+	High      *float64  `json:"high,omitempty" db:"high"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// CriticalValueRuleCreateRequest represents the request to add a critical value rule.
+// A rule needs a low threshold, a high threshold or both.
+type CriticalValueRuleCreateRequest struct {
+	Name   string   `json:"name" binding:"required,max=255"`
+	System string   `json:"system" binding:"required,max=255"`
+	Code   string   `json:"code" binding:"required,max=100"`
+	Unit   string   `json:"unit" binding:"required,max=50"`
+	Low    *float64 `json:"low,omitempty" binding:"required_without=High"`
+	High   *float64 `json:"high,omitempty" binding:"required_without=Low"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+// CriticalValueRuleUpdateRequest represents the request to update a critical
+// value rule; clearLow and clearHigh remove a threshold, as long as the other remains
+type CriticalValueRuleUpdateRequest struct {
+	Name      *string  `json:"name,omitempty" binding:"omitempty,max=255"`
+	System    *string  `json:"system,omitempty" binding:"omitempty,min=1,max=255"`
+	Code      *string  `json:"code,omitempty" binding:"omitempty,min=1,max=100"`
+	Unit      *string  `json:"unit,omitempty" binding:"omitempty,min=1,max=50"`
+	Low       *float64 `json:"low,omitempty"`
+	ClearLow  bool     `json:"clearLow,omitempty"`
+	High      *float64 `json:"high,omitempty"`
+	ClearHigh bool     `json:"clearHigh,omitempty"`
+	Active    *bool    `json:"active,omitempty"`
+}
+
+// Task statuses of critical result acknowledgement
+const (
+	// TaskRequested waits for a clinician to acknowledge the result
+	TaskRequested = "requested"
+	// TaskCompleted has been acknowledged
+	TaskCompleted = "completed"
+)
+
+// CriticalValueTask asks a clinician to acknowledge a critical result. It is
+// served as a FHIR Task by ToFHIR.
+type CriticalValueTask struct {
+	ID             uuid.UUID  `db:"id"`
+	ObservationID  uuid.UUID  `db:"observation_id"`
+	Subject        string     `db:"subject"`
+	Interpretation string     `db:"interpretation"`
+	Description    string     `db:"description"`
+	Status         string     `db:"status"`
+	AcknowledgedAt *time.Time `db:"acknowledged_at"`
+	AcknowledgedBy *string    `db:"acknowledged_by"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+}
+
+// Task represents a FHIR R4 Task resource
+type Task struct {
+	ResourceType    string          `json:"resourceType"`
+	ID              uuid.UUID       `json:"id"`
+	Status          string          `json:"status"`
+	Intent          string          `json:"intent"`
+	Priority        string          `json:"priority"`
+	Code            CodeableConcept `json:"code"`
+	Description     string          `json:"description"`
+	ReasonCode      CodeableConcept `json:"reasonCode"`
+	Focus           Reference       `json:"focus"`
+	For             Reference       `json:"for"`
+	AuthoredOn      time.Time       `json:"authoredOn"`
+	LastModified    time.Time       `json:"lastModified"`
+	ExecutionPeriod *Period         `json:"executionPeriod,omitempty"`
+	Owner           *Reference      `json:"owner,omitempty"`
+}
+
+// ToFHIR returns the task as a FHIR Task: requested until acknowledged, then
+// completed and owned by the clinician who acknowledged it
+func (t *CriticalValueTask) ToFHIR() *Task {
+	focus := "Observation/" + t.ObservationID.String()
+	subject := t.Subject
+	text := "Acknowledge critical result"
+	system := "http://terminology.hl7.org/CodeSystem/v3-ObservationInterpretation"
+	interpretation := t.Interpretation
+
+	task := &Task{
+		ResourceType: "Task",
+		ID:           t.ID,
+		Status:       t.Status,
+		Intent:       "order",
+		Priority:     "stat",
+		Code:         CodeableConcept{Text: &text},
+		Description:  t.Description,
+		ReasonCode:   CodeableConcept{Coding: []Coding{{System: &system, Code: &interpretation}}},
+		Focus:        Reference{Reference: &focus},
+		For:          Reference{Reference: &subject},
+		AuthoredOn:   t.CreatedAt,
+		LastModified: t.UpdatedAt,
+	}
+	if t.AcknowledgedAt != nil {
+		start := t.CreatedAt
+		task.ExecutionPeriod = &Period{Start: &start, End: t.AcknowledgedAt}
+	}
+	if t.AcknowledgedBy != nil {
+		task.Owner = &Reference{Identifier: &Identifier{Value: t.AcknowledgedBy}}
+	}
+	return task
+}
+
+// TaskListResponse represents a searchset bundle of tasks
+type TaskListResponse struct {
+	ResourceType string       `json:"resourceType"`
+	ID           string       `json:"id"`
+	Type         string       `json:"type"`
+	Total        int64        `json:"total"`
+	Entry        []TaskEntry  `json:"entry"`
+	Link         []BundleLink `json:"link,omitempty"`
+}
+
+// TaskEntry represents a task entry in a bundle
+type TaskEntry struct {
+	FullURL  string       `json:"fullUrl"`
+	Resource *Task        `json:"resource"`
+	Search   *SearchEntry `json:"search,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// DataQualityReport lists the data quality issues found in a tenant's live
+// resources
+type DataQualityReport struct {
+	GeneratedAt time.Time          `json:"generatedAt"`
+	Issues      []DataQualityIssue `json:"issues"`
+}
+
+// DataQualityIssue counts the resources of a type with an issue, with the
+// references of the first of them in id order
+type DataQualityIssue struct {
+	Code         string   `json:"code"`
+	ResourceType string   `json:"resourceType"`
+	Description  string   `json:"description"`
+	Count        int64    `json:"count"`
+	Resources    []string `json:"resources"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DeadJob is a background job that exhausted its retries and was moved to the dead letter table
+type DeadJob struct {
+	ID         string          `json:"id" db:"id"`
+	JobType    string          `json:"jobType" db:"job_type"`
+	Payload    json.RawMessage `json:"payload,omitempty" db:"payload"`
+	Retries    int             `json:"retries" db:"retries"`
+	MaxRetries int             `json:"maxRetries" db:"max_retries"`
+	Timeout    time.Duration   `json:"-" db:"timeout_ms"`
+	Priority   int             `json:"priority" db:"priority"`
+	Error      string          `json:"error" db:"error"`
+	CreatedAt  time.Time       `json:"createdAt" db:"created_at"`
+	FailedAt   time.Time       `json:"failedAt" db:"failed_at"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+// DiagnosticReport represents a FHIR DiagnosticReport resource: the outcome of
+// a diagnostic order, such as a lab panel, grouping its result observations
+type DiagnosticReport struct {
+	Resource
+
+	Identifier         []Identifier      `json:"identifier,omitempty" db:"identifier"`
+	BasedOn            []Reference       `json:"basedOn,omitempty" db:"based_on"`
+	Status             string            `json:"status" db:"status" validate:"required,oneof=registered partial preliminary final amended corrected appended cancelled entered-in-error unknown"`
+	Category           []CodeableConcept `json:"category,omitempty" db:"category"`
+	Code               CodeableConcept   `json:"code" db:"code" validate:"required"`
+	Subject            Reference         `json:"subject" db:"subject" validate:"required"`
+	Encounter          *Reference        `json:"encounter,omitempty" db:"encounter"`
+	EffectiveDateTime  *time.Time        `json:"effectiveDateTime,omitempty" db:"effective_date_time"`
+	EffectivePeriod    *Period           `json:"effectivePeriod,omitempty" db:"effective_period"`
+	Issued             *time.Time        `json:"issued,omitempty" db:"issued"`
+	Performer          []Reference       `json:"performer,omitempty" db:"performer"`
+	ResultsInterpreter []Reference       `json:"resultsInterpreter,omitempty" db:"results_interpreter"`
+	Specimen           []Reference       `json:"specimen,omitempty" db:"specimen"`
+	Result             []Reference       `json:"result,omitempty" db:"result"`
+	ImagingStudy       []Reference       `json:"imagingStudy,omitempty" db:"imaging_study"`
+	Conclusion         *string           `json:"conclusion,omitempty" db:"conclusion"`
+	ConclusionCode     []CodeableConcept `json:"conclusionCode,omitempty" db:"conclusion_code"`
+}
+
+// DiagnosticReportCreateRequest represents the request to create a diagnostic report
+type DiagnosticReportCreateRequest struct {
+	Identifier         []Identifier      `json:"identifier,omitempty"`
+	BasedOn            []Reference       `json:"basedOn,omitempty"`
+	Status             string            `json:"status" validate:"required,oneof=registered partial preliminary final amended corrected appended cancelled entered-in-error unknown"`
+	Category           []CodeableConcept `json:"category,omitempty"`
+	Code               CodeableConcept   `json:"code" validate:"required"`
+	Subject            Reference         `json:"subject" validate:"required"`
+	Encounter          *Reference        `json:"encounter,omitempty"`
+	EffectiveDateTime  *time.Time        `json:"effectiveDateTime,omitempty"`
+	EffectivePeriod    *Period           `json:"effectivePeriod,omitempty"`
+	Issued             *time.Time        `json:"issued,omitempty"`
+	Performer          []Reference       `json:"performer,omitempty"`
+	ResultsInterpreter []Reference       `json:"resultsInterpreter,omitempty"`
+	Specimen           []Reference       `json:"specimen,omitempty"`
+	Result             []Reference       `json:"result,omitempty"`
+	Conclusion         *string           `json:"conclusion,omitempty"`
+	ConclusionCode     []CodeableConcept `json:"conclusionCode,omitempty"`
+}
+
+// DiagnosticReportListResponse represents the response for listing diagnostic reports
+type DiagnosticReportListResponse struct {
+	ResourceType string                  `json:"resourceType"`
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Total        int64                   `json:"total"`
+	Entry        []DiagnosticReportEntry `json:"entry"`
+	Link         []BundleLink            `json:"link,omitempty"`
+}
+
+// DiagnosticReportEntry represents a diagnostic report entry in a bundle
+type DiagnosticReportEntry struct {
+	FullURL  string            `json:"fullUrl"`
+	Resource *DiagnosticReport `json:"resource"`
+	Search   *SearchEntry      `json:"search,omitempty"`
+}
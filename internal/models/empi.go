@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of an EMPI link
+const (
+	// EMPILinked: the patient holds the enterprise identifier the EMPI returned
+	EMPILinked = "linked"
+	// EMPIUnmatched: the EMPI knows no such person
+	EMPIUnmatched = "unmatched"
+	// EMPIMismatch: the EMPI returned an enterprise identifier other than the
+	// one the patient holds
+	EMPIMismatch = "mismatch"
+	// EMPIConflict: the enterprise identifier the EMPI returned is held by another
+	// patient of the tenant, which is likely a duplicate
+	EMPIConflict = "conflict"
+	// EMPIError: the lookup failed
+	EMPIError = "error"
+)
+
+// ValidEMPILinkStatus reports whether status is an EMPI link status
+func ValidEMPILinkStatus(status string) bool {
+	switch status {
+	case EMPILinked, EMPIUnmatched, EMPIMismatch, EMPIConflict, EMPIError:
+		return true
+	}
+	return false
+}
+
+// EMPILink is the outcome of the last lookup of a patient in the enterprise
+// master patient index
+type EMPILink struct {
+	PatientID uuid.UUID `json:"patientId" db:"patient_id"`
+	Status    string    `json:"status" db:"status"`
+	// EnterpriseID is the enterprise identifier stored on the patient
+	EnterpriseID *string `json:"enterpriseId,omitempty" db:"enterprise_id"`
+	// CandidateID is the enterprise identifier the EMPI returned, for a mismatch or conflict
+	CandidateID          *string    `json:"candidateId,omitempty" db:"candidate_id"`
+	ConflictingPatientID *uuid.UUID `json:"conflictingPatientId,omitempty" db:"conflicting_patient_id"`
+	LastError            *string    `json:"lastError,omitempty" db:"last_error"`
+	// Attempts counts the lookups since the status last changed
+	Attempts  int       `json:"attempts" db:"attempts"`
+	CheckedAt time.Time `json:"checkedAt" db:"checked_at"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	TenantID  string    `json:"-" db:"tenant_id"`
+}
@@ -0,0 +1,170 @@
+package models
+
+// ErrorCodeSystem identifies the API's error codes in OperationOutcome issue
+// details, so clients can match errors without parsing diagnostics
+const ErrorCodeSystem = "urn:healthcare-api:error-code"
+
+// ErrorCode is a stable identifier of an API error. Codes are documented in
+// docs/API.md and are never reused for a different error.
+type ErrorCode string
+
+// Codes of specific errors
+const (
+	ErrorCodePatientNotFound               ErrorCode = "PATIENT_NOT_FOUND"
+	ErrorCodeObservationNotFound           ErrorCode = "OBSERVATION_NOT_FOUND"
+	ErrorCodeDiagnosticReportNotFound      ErrorCode = "DIAGNOSTIC_REPORT_NOT_FOUND"
+	ErrorCodeIdentifierConflict            ErrorCode = "IDENTIFIER_CONFLICT"
+	ErrorCodeTenantNotFound                ErrorCode = "TENANT_NOT_FOUND"
+	ErrorCodeTenantExists                  ErrorCode = "TENANT_EXISTS"
+	ErrorCodeBackupNotFound                ErrorCode = "BACKUP_NOT_FOUND"
+	ErrorCodeJobNotFound                   ErrorCode = "JOB_NOT_FOUND"
+	ErrorCodeJobFinished                   ErrorCode = "JOB_FINISHED"
+	ErrorCodeDeadJobNotFound               ErrorCode = "DEAD_JOB_NOT_FOUND"
+	ErrorCodeWebhookNotFound               ErrorCode = "WEBHOOK_NOT_FOUND"
+	ErrorCodeWebhookDeliveryNotFound       ErrorCode = "WEBHOOK_DELIVERY_NOT_FOUND"
+	ErrorCodeInvalidWebhookURL             ErrorCode = "INVALID_WEBHOOK_URL"
+	ErrorCodeAttachmentNotFound            ErrorCode = "ATTACHMENT_NOT_FOUND"
+	ErrorCodeAttachmentTooLarge            ErrorCode = "ATTACHMENT_TOO_LARGE"
+	ErrorCodeInvalidAttachmentLink         ErrorCode = "INVALID_ATTACHMENT_LINK"
+	ErrorCodeNotificationRecipientNotFound ErrorCode = "NOTIFICATION_RECIPIENT_NOT_FOUND"
+	ErrorCodeNotificationNotFound          ErrorCode = "NOTIFICATION_NOT_FOUND"
+	ErrorCodeInvalidNotificationRecipient  ErrorCode = "INVALID_NOTIFICATION_RECIPIENT"
+	ErrorCodeEMPILinkNotFound              ErrorCode = "EMPI_LINK_NOT_FOUND"
+	ErrorCodeImagingStudyNotFound          ErrorCode = "IMAGING_STUDY_NOT_FOUND"
+	ErrorCodeCriticalValueRuleNotFound     ErrorCode = "CRITICAL_VALUE_RULE_NOT_FOUND"
+	ErrorCodeInvalidCriticalValueRule      ErrorCode = "INVALID_CRITICAL_VALUE_RULE"
+	ErrorCodeTaskNotFound                  ErrorCode = "TASK_NOT_FOUND"
+	ErrorCodePatientDuplicateNotFound      ErrorCode = "PATIENT_DUPLICATE_NOT_FOUND"
+	ErrorCodePatientMerged                 ErrorCode = "PATIENT_MERGED"
+	ErrorCodeCohortNotFound                ErrorCode = "COHORT_NOT_FOUND"
+	ErrorCodeInvalidCohort                 ErrorCode = "INVALID_COHORT"
+	ErrorCodeBackupDeidentified            ErrorCode = "BACKUP_DEIDENTIFIED"
+	ErrorCodeInvalidDICOMMetadata          ErrorCode = "INVALID_DICOM_METADATA"
+	ErrorCodeInvalidWearableData           ErrorCode = "INVALID_WEARABLE_DATA"
+	ErrorCodeUpstreamUnavailable           ErrorCode = "UPSTREAM_UNAVAILABLE"
+	ErrorCodeInvalidID                     ErrorCode = "INVALID_ID"
+	ErrorCodeValidationFailed              ErrorCode = "VALIDATION_FAILED"
+	ErrorCodeUnsupportedMediaType          ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	ErrorCodeOverloaded                    ErrorCode = "SERVER_OVERLOADED"
+	ErrorCodeMaintenanceMode               ErrorCode = "MAINTENANCE_MODE"
+)
+
+// Codes of errors without a specific code, one per FHIR issue type. Every error
+// outcome carries one of these unless a specific code is set.
+const (
+	ErrorCodeInvalidRequest     ErrorCode = "INVALID_REQUEST"
+	ErrorCodeMissingRequired    ErrorCode = "MISSING_REQUIRED"
+	ErrorCodeAccessDenied       ErrorCode = "ACCESS_DENIED"
+	ErrorCodeNotFound           ErrorCode = "NOT_FOUND"
+	ErrorCodeResourceDeleted    ErrorCode = "RESOURCE_DELETED"
+	ErrorCodeConflict           ErrorCode = "CONFLICT"
+	ErrorCodeDuplicate          ErrorCode = "DUPLICATE"
+	ErrorCodeNotSupported       ErrorCode = "NOT_SUPPORTED"
+	ErrorCodeSuspended          ErrorCode = "SUSPENDED"
+	ErrorCodeRateLimited        ErrorCode = "RATE_LIMITED"
+	ErrorCodeServiceUnavailable ErrorCode = "SERVICE_UNAVAILABLE"
+	ErrorCodeInternal           ErrorCode = "INTERNAL_ERROR"
+)
+
+// errorDefinition describes how an error code is reported
+type errorDefinition struct {
+	// IssueCode is the FHIR issue type of outcomes carrying the code
+	IssueCode   string
+	Description string
+}
+
+// errorCatalog lists every error code
+var errorCatalog = map[ErrorCode]errorDefinition{
+	ErrorCodePatientNotFound:               {IssueCode: "not-found", Description: "No patient with the id exists in the tenant"},
+	ErrorCodeObservationNotFound:           {IssueCode: "not-found", Description: "No observation with the id exists in the tenant"},
+	ErrorCodeDiagnosticReportNotFound:      {IssueCode: "not-found", Description: "No diagnostic report with the id exists in the tenant"},
+	ErrorCodeIdentifierConflict:            {IssueCode: "duplicate", Description: "A business identifier is already assigned to another patient"},
+	ErrorCodeTenantNotFound:                {IssueCode: "not-found", Description: "No tenant with the id exists"},
+	ErrorCodeTenantExists:                  {IssueCode: "duplicate", Description: "A tenant with the id already exists"},
+	ErrorCodeBackupNotFound:                {IssueCode: "not-found", Description: "No backup with the id exists for the tenant"},
+	ErrorCodeJobNotFound:                   {IssueCode: "not-found", Description: "No job with the id is visible to the caller"},
+	ErrorCodeJobFinished:                   {IssueCode: "conflict", Description: "The job has already finished and cannot be cancelled"},
+	ErrorCodeDeadJobNotFound:               {IssueCode: "not-found", Description: "No dead job with the id exists"},
+	ErrorCodeWebhookNotFound:               {IssueCode: "not-found", Description: "No webhook with the id exists in the tenant"},
+	ErrorCodeWebhookDeliveryNotFound:       {IssueCode: "not-found", Description: "No delivery with the id exists for the webhook"},
+	ErrorCodeInvalidWebhookURL:             {IssueCode: "invalid", Description: "The webhook URL is not one the server delivers to"},
+	ErrorCodeAttachmentNotFound:            {IssueCode: "not-found", Description: "No attachment with the id exists in the tenant"},
+	ErrorCodeAttachmentTooLarge:            {IssueCode: "too-long", Description: "The attachment exceeds the size limit"},
+	ErrorCodeInvalidAttachmentLink:         {IssueCode: "security", Description: "The attachment download link is invalid or has expired"},
+	ErrorCodeNotificationRecipientNotFound: {IssueCode: "not-found", Description: "No notification recipient with the id exists in the tenant"},
+	ErrorCodeNotificationNotFound:          {IssueCode: "not-found", Description: "No notification with the id exists in the tenant"},
+	ErrorCodeInvalidNotificationRecipient:  {IssueCode: "invalid", Description: "The recipient's quiet hours or escalation cannot be used"},
+	ErrorCodeEMPILinkNotFound:              {IssueCode: "not-found", Description: "The patient has not been looked up in the EMPI"},
+	ErrorCodeImagingStudyNotFound:          {IssueCode: "not-found", Description: "No imaging study with the id exists in the tenant"},
+	ErrorCodeCriticalValueRuleNotFound:     {IssueCode: "not-found", Description: "No critical value rule with the id exists in the tenant"},
+	ErrorCodeInvalidCriticalValueRule:      {IssueCode: "invalid", Description: "The rule's unit is not a UCUM unit or its thresholds are out of order"},
+	ErrorCodeTaskNotFound:                  {IssueCode: "not-found", Description: "No task with the id exists in the tenant"},
+	ErrorCodePatientDuplicateNotFound:      {IssueCode: "not-found", Description: "No duplicate patient candidate with the id exists in the tenant"},
+	ErrorCodePatientMerged:                 {IssueCode: "conflict", Description: "The patient has already been merged into another patient"},
+	ErrorCodeCohortNotFound:                {IssueCode: "not-found", Description: "No cohort with the id exists in the tenant"},
+	ErrorCodeInvalidCohort:                 {IssueCode: "invalid", Description: "The cohort criteria cannot be evaluated"},
+	ErrorCodeBackupDeidentified:            {IssueCode: "conflict", Description: "The backup is a de-identified export and cannot be restored"},
+	ErrorCodeInvalidDICOMMetadata:          {IssueCode: "invalid", Description: "The DICOM JSON metadata is malformed or inconsistent"},
+	ErrorCodeInvalidWearableData:           {IssueCode: "invalid", Description: "A wearable sample has an unsupported type or unit, or an invalid value or time"},
+	ErrorCodeUpstreamUnavailable:           {IssueCode: "transient", Description: "The upstream FHIR server of a federated resource type could not be reached"},
+	ErrorCodeInvalidID:                     {IssueCode: "invalid", Description: "A resource id in the path is malformed"},
+	ErrorCodeValidationFailed:              {IssueCode: "invalid", Description: "The request body failed validation"},
+	ErrorCodeUnsupportedMediaType:          {IssueCode: "not-supported", Description: "The request body is not FHIR JSON or JSON"},
+	ErrorCodeOverloaded:                    {IssueCode: "transient", Description: "Too many requests are in flight; retry after the Retry-After delay"},
+	ErrorCodeMaintenanceMode:               {IssueCode: "transient", Description: "The API is in maintenance mode and refuses writes; retry after the Retry-After delay"},
+
+	ErrorCodeInvalidRequest:     {IssueCode: "invalid", Description: "The request is malformed"},
+	ErrorCodeMissingRequired:    {IssueCode: "required", Description: "A required element is missing"},
+	ErrorCodeAccessDenied:       {IssueCode: "security", Description: "Authentication failed or the caller lacks permission"},
+	ErrorCodeNotFound:           {IssueCode: "not-found", Description: "The resource does not exist"},
+	ErrorCodeResourceDeleted:    {IssueCode: "deleted", Description: "The resource has been deleted"},
+	ErrorCodeConflict:           {IssueCode: "conflict", Description: "The request conflicts with the current state"},
+	ErrorCodeDuplicate:          {IssueCode: "duplicate", Description: "The resource already exists"},
+	ErrorCodeNotSupported:       {IssueCode: "not-supported", Description: "The operation or type is not supported"},
+	ErrorCodeSuspended:          {IssueCode: "suspended", Description: "The tenant is suspended"},
+	ErrorCodeRateLimited:        {IssueCode: "throttled", Description: "The rate limit was exceeded"},
+	ErrorCodeServiceUnavailable: {IssueCode: "transient", Description: "A dependency is unavailable; retry later"},
+	ErrorCodeInternal:           {IssueCode: "exception", Description: "An unexpected server error"},
+}
+
+// genericErrorCodes maps FHIR issue types to the codes used when no specific
+// code is set
+var genericErrorCodes = map[string]ErrorCode{
+	"invalid":       ErrorCodeInvalidRequest,
+	"required":      ErrorCodeMissingRequired,
+	"security":      ErrorCodeAccessDenied,
+	"not-found":     ErrorCodeNotFound,
+	"deleted":       ErrorCodeResourceDeleted,
+	"conflict":      ErrorCodeConflict,
+	"duplicate":     ErrorCodeDuplicate,
+	"not-supported": ErrorCodeNotSupported,
+	"suspended":     ErrorCodeSuspended,
+	"throttled":     ErrorCodeRateLimited,
+	"transient":     ErrorCodeServiceUnavailable,
+	"exception":     ErrorCodeInternal,
+}
+
+// NewErrorOutcome creates an error OperationOutcome carrying code
+func NewErrorOutcome(code ErrorCode, diagnostics string) *OperationOutcome {
+	return NewOperationOutcome("error", errorCatalog[code].IssueCode, diagnostics).WithErrorCode(code)
+}
+
+// WithErrorCode sets the error code of the outcome's first issue
+func (o *OperationOutcome) WithErrorCode(code ErrorCode) *OperationOutcome {
+	if len(o.Issue) == 0 {
+		return o
+	}
+	if o.Issue[0].Details == nil {
+		o.Issue[0].Details = &CodeableConcept{}
+	}
+	o.Issue[0].Details.Coding = []Coding{{
+		System:  stringPtr(ErrorCodeSystem),
+		Code:    stringPtr(string(code)),
+		Display: stringPtr(errorCatalog[code].Description),
+	}}
+	return o
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -1,8 +1,11 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/google/uuid"
 )
 
 // APIError represents a standardized API error response
@@ -59,6 +62,84 @@ var (
 	}
 )
 
+// ErrResourceDeleted is returned when a soft-deleted resource is accessed
+var ErrResourceDeleted = errors.New("resource has been deleted")
+
+// ErrTenantRequired is returned when a tenant-scoped query is made without a tenant in the context
+var ErrTenantRequired = errors.New("tenant is required")
+
+// Errors returned when a looked up record does not exist
+var (
+	ErrPatientNotFound               = errors.New("patient not found")
+	ErrObservationNotFound           = errors.New("observation not found")
+	ErrDiagnosticReportNotFound      = errors.New("diagnostic report not found")
+	ErrTenantNotFound                = errors.New("tenant not found")
+	ErrBackupNotFound                = errors.New("backup not found")
+	ErrJobNotFound                   = errors.New("job not found")
+	ErrDeadJobNotFound               = errors.New("dead job not found")
+	ErrWebhookNotFound               = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound       = errors.New("webhook delivery not found")
+	ErrAttachmentNotFound            = errors.New("attachment not found")
+	ErrNotificationRecipientNotFound = errors.New("notification recipient not found")
+	ErrNotificationNotFound          = errors.New("notification not found")
+	ErrEMPILinkNotFound              = errors.New("EMPI link not found")
+	ErrImagingStudyNotFound          = errors.New("imaging study not found")
+	ErrCriticalValueRuleNotFound     = errors.New("critical value rule not found")
+	ErrTaskNotFound                  = errors.New("task not found")
+	ErrPatientDuplicateNotFound      = errors.New("patient duplicate not found")
+	ErrCohortNotFound                = errors.New("cohort not found")
+)
+
+// ErrBackupDeidentified is returned when restoring a de-identified export,
+// whose resources no longer match the tenant's
+var ErrBackupDeidentified = errors.New("backup is de-identified")
+
+// ErrInvalidWebhookURL is returned for webhook URLs the server will not deliver to
+var ErrInvalidWebhookURL = errors.New("invalid webhook URL")
+
+// ErrInvalidNotificationRecipient is returned for recipients whose paging
+// settings cannot work, such as escalating to themselves
+var ErrInvalidNotificationRecipient = errors.New("invalid notification recipient")
+
+// ErrInvalidCriticalValueRule is returned for rules whose unit is not a UCUM
+// unit or whose thresholds are out of order
+var ErrInvalidCriticalValueRule = errors.New("invalid critical value rule")
+
+// ErrInvalidCohort is returned for cohort criteria that cannot be evaluated,
+// such as a value without a comparator or an unknown unit
+var ErrInvalidCohort = errors.New("invalid cohort criteria")
+
+// ErrAttachmentTooLarge is returned for attachments over the configured size limit
+var ErrAttachmentTooLarge = errors.New("attachment too large")
+
+// ErrInvalidAttachmentLink is returned for download links that are tampered with or expired
+var ErrInvalidAttachmentLink = errors.New("invalid or expired attachment link")
+
+// ErrTenantExists is returned when creating a tenant whose id is taken
+var ErrTenantExists = errors.New("tenant already exists")
+
+// ErrJobFinished is returned when cancelling a job that has already finished
+var ErrJobFinished = errors.New("job already finished")
+
+// ErrPatientMerged is returned when merging a patient that has already been
+// merged into another, either as the source or as the target
+var ErrPatientMerged = errors.New("patient already merged")
+
+// ErrUnsupported is wrapped by errors for input the server cannot handle, such
+// as an unknown resource type; retrying does not help
+var ErrUnsupported = errors.New("unsupported")
+
+// IdentifierConflictError is returned when a business identifier is already assigned to another patient
+type IdentifierConflictError struct {
+	System     string
+	Value      string
+	ResourceID uuid.UUID
+}
+
+func (e *IdentifierConflictError) Error() string {
+	return fmt.Sprintf("identifier %s|%s is already assigned to Patient/%s", e.System, e.Value, e.ResourceID)
+}
+
 // NewAPIError creates a new API error with custom details
 func NewAPIError(code int, message, details string) APIError {
 	return APIError{
@@ -68,11 +149,14 @@ func NewAPIError(code int, message, details string) APIError {
 	}
 }
 
-// ValidationError represents validation errors
+// ValidationError represents validation errors. Warnings, such as a code
+// outside a value set that is not enforced, have Severity "warning" and do not
+// fail the request.
 type ValidationError struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
-	Value   interface{} `json:"value,omitempty"`
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+	Value    interface{} `json:"value,omitempty"`
+	Severity string `json:"severity,omitempty"`
 }
 
 // ValidationErrors represents multiple validation errors
@@ -102,9 +186,10 @@ type OperationOutcomeIssue struct {
 	Expression  []string         `json:"expression,omitempty"`
 }
 
-// NewOperationOutcome creates a new OperationOutcome
+// NewOperationOutcome creates a new OperationOutcome. Error outcomes carry the
+// generic error code of their issue type; use WithErrorCode for a specific one.
 func NewOperationOutcome(severity, code, diagnostics string) *OperationOutcome {
-	return &OperationOutcome{
+	outcome := &OperationOutcome{
 		ResourceType: "OperationOutcome",
 		Issue: []OperationOutcomeIssue{
 			{
@@ -114,4 +199,8 @@ func NewOperationOutcome(severity, code, diagnostics string) *OperationOutcome {
 			},
 		},
 	}
+	if errorCode, ok := genericErrorCodes[code]; ok && (severity == "error" || severity == "fatal") {
+		outcome.WithErrorCode(errorCode)
+	}
+	return outcome
 }
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// EarlyWarningScores are a patient's early warning scores, computed from the
+// latest of their vital signs
+type EarlyWarningScores struct {
+	Subject    string              `json:"subject"`
+	ComputedAt time.Time           `json:"computedAt"`
+	Scores     []EarlyWarningScore `json:"scores"`
+}
+
+// EarlyWarningScore is the score of one system, NEWS2 or MEWS
+type EarlyWarningScore struct {
+	System string `json:"system"`
+	// Total sums the points of the parameters measured
+	Total int    `json:"total"`
+	Risk  string `json:"risk"`
+	// Complete is false when a parameter was not measured, in which case the
+	// total understates the risk
+	Complete   bool           `json:"complete"`
+	Parameters []EWSParameter `json:"parameters"`
+	Missing    []string       `json:"missing,omitempty"`
+	// Observation is the observation the score was recorded as, if it was
+	Observation *Reference `json:"observation,omitempty"`
+}
+
+// EWSParameter is a vital sign scored, with the observation it was read from
+type EWSParameter struct {
+	Name string `json:"name"`
+	// Value is a measurement, in Unit; Text is a level of consciousness on the
+	// ACVPU scale, or "air" or "oxygen" for supplemental oxygen
+	Value *float64 `json:"value,omitempty"`
+	Unit  string   `json:"unit,omitempty"`
+	Text  string   `json:"text,omitempty"`
+	Score int      `json:"score"`
+	// Observation and Effective are absent when the value is Assumed
+	Observation *Reference `json:"observation,omitempty"`
+	Effective   *time.Time `json:"effective,omitempty"`
+	Assumed     bool       `json:"assumed,omitempty"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+)
+
+// Systems of the DICOM codes and identifiers of imaging studies
+const (
+	// DICOMUIDSystem is the identifier system of study instance UIDs, given as urn:oid:<uid>
+	DICOMUIDSystem = "urn:dicom:uid"
+	// DICOMModalitySystem is the DICOM code system of modalities such as CT and MR
+	DICOMModalitySystem = "http://dicom.nema.org/resources/ontology/DCM"
+	// SOPClassSystem is the system of SOP class UIDs, given as urn:oid:<uid>
+	SOPClassSystem = "urn:ietf:rfc:3986"
+	// IdentifierTypeSystem is the HL7 v2-0203 code system of identifier types,
+	// in which ACSN marks accession numbers
+	IdentifierTypeSystem = "http://terminology.hl7.org/CodeSystem/v2-0203"
+)
+
+// WADORSExtensionURL is the extension of a study or series holding the DICOMweb
+// WADO-RS URL its images are retrieved from
+const WADORSExtensionURL = "urn:healthcare-api:extension:wado-rs-url"
+
+// ImagingStudy represents a FHIR ImagingStudy resource: the DICOM metadata of
+// a study held in a PACS, from which its images are retrieved
+type ImagingStudy struct {
+	Resource
+
+	Identifier        []Identifier         `json:"identifier,omitempty" db:"identifier"`
+	Status            string               `json:"status" db:"status" validate:"required,oneof=registered available cancelled entered-in-error unknown"`
+	Modality          []Coding             `json:"modality,omitempty" db:"modality"`
+	Subject           Reference            `json:"subject" db:"subject" validate:"required"`
+	Started           *time.Time           `json:"started,omitempty" db:"started"`
+	NumberOfSeries    int                  `json:"numberOfSeries" db:"number_of_series"`
+	NumberOfInstances int                  `json:"numberOfInstances" db:"number_of_instances"`
+	Description       *string              `json:"description,omitempty" db:"description"`
+	Series            []ImagingStudySeries `json:"series,omitempty" db:"series"`
+}
+
+// ImagingStudySeries is a series of an imaging study
+type ImagingStudySeries struct {
+	Extension         []Extension            `json:"extension,omitempty"`
+	UID               string                 `json:"uid"`
+	Number            *int                   `json:"number,omitempty"`
+	Modality          *Coding                `json:"modality,omitempty"`
+	Description       *string                `json:"description,omitempty"`
+	NumberOfInstances int                    `json:"numberOfInstances"`
+	Instance          []ImagingStudyInstance `json:"instance,omitempty"`
+}
+
+// ImagingStudyInstance is an instance of a series, such as an image
+type ImagingStudyInstance struct {
+	UID      string  `json:"uid"`
+	SOPClass *Coding `json:"sopClass,omitempty"`
+	Number   *int    `json:"number,omitempty"`
+}
+
+// ImagingStudyListResponse represents the response for listing imaging studies
+type ImagingStudyListResponse struct {
+	ResourceType string              `json:"resourceType"`
+	ID           string              `json:"id"`
+	Type         string              `json:"type"`
+	Total        int64               `json:"total"`
+	Entry        []ImagingStudyEntry `json:"entry"`
+	Link         []BundleLink        `json:"link,omitempty"`
+}
+
+// ImagingStudyEntry represents an imaging study entry in a bundle
+type ImagingStudyEntry struct {
+	FullURL  string        `json:"fullUrl"`
+	Resource *ImagingStudy `json:"resource"`
+	Search   *SearchEntry  `json:"search,omitempty"`
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Background job statuses
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusRetrying  = "retrying"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// BackgroundJob is the tracked status of a job submitted to the worker pool
+type BackgroundJob struct {
+	ID       string          `json:"id" db:"id"`
+	TenantID *string         `json:"tenantId,omitempty" db:"tenant_id"`
+	JobType  string          `json:"jobType" db:"job_type"`
+	Status   string          `json:"status" db:"status"`
+	Priority int             `json:"priority" db:"priority"`
+	Payload  json.RawMessage `json:"payload,omitempty" db:"payload"`
+	// RunAt is when a delayed job or retry becomes due
+	RunAt *time.Time `json:"runAt,omitempty" db:"run_at"`
+
+	// Progress is a percentage reported by handlers that support it
+	Progress        int     `json:"progress" db:"progress"`
+	ProgressMessage *string `json:"progressMessage,omitempty" db:"progress_message"`
+
+	Retries    int     `json:"retries" db:"retries"`
+	MaxRetries int     `json:"maxRetries" db:"max_retries"`
+	Error      *string `json:"error,omitempty" db:"error"`
+
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	StartedAt  *time.Time `json:"startedAt,omitempty" db:"started_at"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty" db:"finished_at"`
+	UpdatedAt  time.Time  `json:"updatedAt" db:"updated_at"`
+}
+
+// JobSubmitRequest submits a job through the jobs API
+type JobSubmitRequest struct {
+	Type    string          `json:"type" binding:"required"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// Priority is critical, high, normal (the default) or low
+	Priority       string `json:"priority,omitempty"`
+	MaxRetries     int    `json:"maxRetries,omitempty" binding:"min=0,max=10"`
+	TimeoutSeconds int    `json:"timeoutSeconds,omitempty" binding:"min=0"`
+	// DedupKey collapses the submission into a waiting job with the same key
+	DedupKey string `json:"dedupKey,omitempty" binding:"max=200"`
+	// RunAt or DelaySeconds hold the job back until later
+	RunAt        *time.Time `json:"runAt,omitempty"`
+	DelaySeconds int        `json:"delaySeconds,omitempty" binding:"min=0"`
+}
+
+// WorkerResizeRequest sets the number of workers in a running worker pool
+type WorkerResizeRequest struct {
+	Workers *int `json:"workers" binding:"required,min=0,max=1000"`
+}
+
+// JobResult is the outcome of one attempt at a background job
+type JobResult struct {
+	ID          int64     `json:"id" db:"id"`
+	JobID       string    `json:"jobId" db:"job_id"`
+	TenantID    *string   `json:"tenantId,omitempty" db:"tenant_id"`
+	JobType     string    `json:"jobType" db:"job_type"`
+	Attempt     int       `json:"attempt" db:"attempt"`
+	Success     bool      `json:"success" db:"success"`
+	Error       *string   `json:"error,omitempty" db:"error"`
+	DurationMS  int64     `json:"durationMs" db:"duration_ms"`
+	CompletedAt time.Time `json:"completedAt" db:"completed_at"`
+}
+
+// OutboxEntry is a background job recorded alongside a resource change, waiting
+// to be submitted to the worker pool
+type OutboxEntry struct {
+	ID       string          `json:"id" db:"id"`
+	TenantID *string         `json:"tenantId,omitempty" db:"tenant_id"`
+	JobType  string          `json:"jobType" db:"job_type"`
+	Payload  json.RawMessage `json:"payload,omitempty" db:"payload"`
+	// RequestID is the request that recorded the entry, if any
+	RequestID *string   `json:"requestId,omitempty" db:"request_id"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// PatientIndexPayload is the payload of patient_index jobs
+type PatientIndexPayload struct {
+	PatientID string `json:"patient_id"`
+	Action    string `json:"action"` // create, update, delete, restore
+}
+
+// ObservationProcessPayload is the payload of observation_process jobs
+type ObservationProcessPayload struct {
+	ObservationID string `json:"observation_id"`
+	Action        string `json:"action"` // create, update, delete, restore
+}
+
+// ObservationDerivePayload is the payload of observation_derive jobs: an
+// encounter whose measurements changed and the calculations taking them
+type ObservationDerivePayload struct {
+	Subject      string   `json:"subject"`
+	Encounter    string   `json:"encounter"`
+	Calculations []string `json:"calculations"`
+}
+
+// AuditLogPayload is the payload of audit_log jobs
+type AuditLogPayload struct {
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	Action       string    `json:"action"`
+	UserID       string    `json:"user_id"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// HL7ResultsPayload is the payload of hl7_results jobs: an accepted ORU^R01
+// message and the patient its results are about
+type HL7ResultsPayload struct {
+	PatientID string `json:"patient_id"`
+	Message   string `json:"message"`
+	UserID    string `json:"user_id,omitempty"`
+}
+
+// ResourceEventPayload is the payload of resource_event jobs: a stored change
+// to publish on the configured event transport
+type ResourceEventPayload struct {
+	// EventID stays the same across retries, so the transport can drop duplicates
+	EventID      string    `json:"event_id"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	Action       string    `json:"action"`
+	UserID       string    `json:"user_id,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// WebhookDeliveryPayload is the payload of webhook_delivery jobs: one event for
+// one webhook
+type WebhookDeliveryPayload struct {
+	WebhookID string               `json:"webhook_id"`
+	Event     ResourceEventPayload `json:"event"`
+	// RequestID is the request that made the change, sent in the event
+	RequestID string `json:"request_id,omitempty"`
+	// Redelivery sends the event even if it was delivered before
+	Redelivery bool `json:"redelivery,omitempty"`
+}
+
+// NotificationDeliveryPayload is the payload of notification_delivery jobs:
+// one queued notification to send
+type NotificationDeliveryPayload struct {
+	NotificationID string `json:"notification_id"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job schedule run statuses
+const (
+	ScheduleStatusRunning   = "running"
+	ScheduleStatusSucceeded = "succeeded"
+	ScheduleStatusFailed    = "failed"
+)
+
+// JobSchedule is a recurring background job driven by a cron expression
+type JobSchedule struct {
+	Name    string          `json:"name" db:"name"`
+	JobType string          `json:"jobType" db:"job_type"`
+	Spec    string          `json:"spec" db:"spec"`
+	Payload json.RawMessage `json:"payload,omitempty" db:"payload"`
+	Timeout time.Duration   `json:"-" db:"timeout_ms"`
+	// Priority of submitted jobs, see worker.Priority
+	Priority int    `json:"priority" db:"priority"`
+	Enabled  bool   `json:"enabled" db:"enabled"`
+	Source   string `json:"source" db:"source"`
+
+	NextRunAt      *time.Time `json:"nextRunAt,omitempty" db:"next_run_at"`
+	RunningJobID   *string    `json:"runningJobId,omitempty" db:"running_job_id"`
+	RunningSince   *time.Time `json:"runningSince,omitempty" db:"running_since"`
+	LastRunAt      *time.Time `json:"lastRunAt,omitempty" db:"last_run_at"`
+	LastFinishedAt *time.Time `json:"lastFinishedAt,omitempty" db:"last_finished_at"`
+	LastStatus     *string    `json:"lastStatus,omitempty" db:"last_status"`
+	LastError      *string    `json:"lastError,omitempty" db:"last_error"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// MaintenanceMode is the maintenance mode of the deployment. While it is
+// enabled, reads are served but writes are refused and background jobs pause.
+type MaintenanceMode struct {
+	Enabled bool `json:"enabled"`
+	// Forced is set when MAINTENANCE_MODE turns maintenance mode on, whatever
+	// was set through the API
+	Forced bool `json:"forced"`
+	// Message is shown to clients whose writes are refused
+	Message *string `json:"message,omitempty"`
+	// RetryAfter is the Retry-After seconds of refused writes
+	RetryAfter int        `json:"retryAfter"`
+	UpdatedBy  *string    `json:"updatedBy,omitempty"`
+	UpdatedAt  *time.Time `json:"updatedAt,omitempty"`
+}
+
+// MaintenanceModeRequest switches maintenance mode on or off
+type MaintenanceModeRequest struct {
+	Enabled *bool   `json:"enabled" binding:"required"`
+	Message *string `json:"message,omitempty" binding:"omitempty,max=500"`
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"fmt"
+	"time"
+	// Quiet hours are kept in the recipient's time zone, which must resolve in
+	// images without a zoneinfo database
+	_ "time/tzdata"
+
+	"github.com/google/uuid"
+)
+
+// Notification kinds: the events recipients can subscribe to
+const (
+	// NotificationCriticalResult is sent when an observation is stored with a
+	// critical interpretation
+	NotificationCriticalResult = "critical_result"
+	// NotificationBackupCompleted is sent when a backup of the tenant's data completes
+	NotificationBackupCompleted = "backup_completed"
+)
+
+// notificationKinds are the kinds a recipient can subscribe to
+var notificationKinds = map[string]bool{
+	NotificationCriticalResult:  true,
+	NotificationBackupCompleted: true,
+}
+
+// ValidNotificationKind reports whether kind is a notification kind
+func ValidNotificationKind(kind string) bool {
+	return notificationKinds[kind]
+}
+
+// Notification channels
+const (
+	NotificationChannelEmail = "email"
+	// NotificationChannelSMS pages a recipient's phone
+	NotificationChannelSMS = "sms"
+)
+
+// Notification statuses
+const (
+	// NotificationHeld is an SMS page waiting for its recipient's quiet hours to end
+	NotificationHeld = "held"
+	// NotificationQueued is waiting for its first attempt or a retry
+	NotificationQueued = "queued"
+	NotificationSent   = "sent"
+	// NotificationFailed has been given up on
+	NotificationFailed = "failed"
+)
+
+// criticalInterpretations are the interpretation codes, from the HL7
+// ObservationInterpretation code system and HL7 v2 table 0078, that make a
+// result critical
+var criticalInterpretations = map[string]bool{
+	"HH": true, // critically high
+	"LL": true, // critically low
+	"AA": true, // critically abnormal
+}
+
+// CriticalInterpretation returns the first critical interpretation of an
+// observation, if it has one
+func CriticalInterpretation(observation *Observation) (*Coding, bool) {
+	for _, interpretation := range observation.Interpretation {
+		for i, coding := range interpretation.Coding {
+			if coding.Code != nil && criticalInterpretations[*coding.Code] {
+				return &interpretation.Coding[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// NotificationRecipient is someone the tenant's notifications are sent to: by
+// email, and for critical results also paged by SMS
+type NotificationRecipient struct {
+	ID    uuid.UUID `json:"id" db:"id"`
+	Name  string    `json:"name" db:"name"`
+	Email *string   `json:"email,omitempty" db:"email"`
+	// Phone receives SMS pages, in E.164 format
+	Phone *string `json:"phone,omitempty" db:"phone"`
+	// Kinds lists the notification kinds received; empty receives every kind
+	Kinds      []string    `json:"kinds" db:"kinds"`
+	QuietHours *QuietHours `json:"quietHours,omitempty"`
+	Escalation *Escalation `json:"escalation,omitempty"`
+	Active     bool        `json:"active" db:"active"`
+	CreatedAt  time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+// QuietHours is the daily period a recipient is not paged, such as 22:00 to
+// 07:00. A page due during it goes to the recipient's escalation target, or
+// without one is held until it ends.
+type QuietHours struct {
+	// Start and End are local times formatted "15:04"; a period ending at or
+	// before its start spans midnight
+	Start string `json:"start" binding:"required,datetime=15:04"`
+	End   string `json:"end" binding:"required,datetime=15:04"`
+	// TimeZone is an IANA time zone, e.g. "Europe/Amsterdam"
+	TimeZone string `json:"timeZone" binding:"required,timezone"`
+}
+
+// Validate checks the times and time zone of quiet hours
+func (q *QuietHours) Validate() error {
+	if _, err := time.Parse("15:04", q.Start); err != nil {
+		return fmt.Errorf("invalid quiet hours start %q: expected HH:MM", q.Start)
+	}
+	if _, err := time.Parse("15:04", q.End); err != nil {
+		return fmt.Errorf("invalid quiet hours end %q: expected HH:MM", q.End)
+	}
+	if _, err := time.LoadLocation(q.TimeZone); err != nil {
+		return fmt.Errorf("invalid quiet hours time zone %q", q.TimeZone)
+	}
+	if q.Start == q.End {
+		return fmt.Errorf("quiet hours start and end are both %s", q.Start)
+	}
+	return nil
+}
+
+// During reports whether t falls in the quiet hours and, if so, when they end.
+// Nil or invalid quiet hours are never quiet.
+func (q *QuietHours) During(t time.Time) (bool, time.Time) {
+	if q == nil || q.Validate() != nil {
+		return false, time.Time{}
+	}
+	loc, _ := time.LoadLocation(q.TimeZone)
+	start, _ := time.Parse("15:04", q.Start)
+	end, _ := time.Parse("15:04", q.End)
+
+	local := t.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	at := func(day time.Time, clock time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	}
+
+	// The period that may contain t starts today, or yesterday when it spans midnight
+	for _, from := range []time.Time{day, day.AddDate(0, 0, -1)} {
+		periodStart := at(from, start)
+		periodEnd := at(from, end)
+		if !periodEnd.After(periodStart) {
+			periodEnd = at(from.AddDate(0, 0, 1), end)
+		}
+		if !local.Before(periodStart) && local.Before(periodEnd) {
+			return true, periodEnd.UTC()
+		}
+	}
+	return false, time.Time{}
+}
+
+// Escalation pages another recipient when a page is not acknowledged in time
+type Escalation struct {
+	// RecipientID is paged instead, whatever kinds it receives
+	RecipientID uuid.UUID `json:"recipientId" binding:"required"`
+	// AfterMinutes is how long a page waits for acknowledgement; the target is
+	// paged at once during the recipient's quiet hours
+	AfterMinutes int `json:"afterMinutes" binding:"required,min=1,max=1440"`
+}
+
+// Receives reports whether the recipient receives notifications of kind
+func (r *NotificationRecipient) Receives(kind string) bool {
+	if len(r.Kinds) == 0 {
+		return true
+	}
+	for _, k := range r.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationRecipientCreateRequest represents the request to add a notification recipient
+// A recipient needs an email address, a phone number or both.
+type NotificationRecipientCreateRequest struct {
+	Name       string      `json:"name" binding:"required,max=255"`
+	Email      *string     `json:"email,omitempty" binding:"required_without=Phone,omitempty,email,max=320"`
+	Phone      *string     `json:"phone,omitempty" binding:"required_without=Email,omitempty,e164"`
+	Kinds      []string    `json:"kinds,omitempty" binding:"omitempty,max=20"`
+	QuietHours *QuietHours `json:"quietHours,omitempty"`
+	Escalation *Escalation `json:"escalation,omitempty"`
+	Active     *bool       `json:"active,omitempty"`
+}
+
+// NotificationRecipientUpdateRequest represents the request to update a notification recipient
+// An empty email or phone removes it, as long as the other remains; clearQuietHours
+// and clearEscalation remove those settings.
+type NotificationRecipientUpdateRequest struct {
+	Name            *string     `json:"name,omitempty" binding:"omitempty,max=255"`
+	Email           *string     `json:"email,omitempty" binding:"omitempty,eq=|email,max=320"`
+	Phone           *string     `json:"phone,omitempty" binding:"omitempty,eq=|e164"`
+	Kinds           *[]string   `json:"kinds,omitempty" binding:"omitempty,max=20"`
+	QuietHours      *QuietHours `json:"quietHours,omitempty"`
+	ClearQuietHours bool        `json:"clearQuietHours,omitempty"`
+	Escalation      *Escalation `json:"escalation,omitempty"`
+	ClearEscalation bool        `json:"clearEscalation,omitempty"`
+	Active          *bool       `json:"active,omitempty"`
+}
+
+// Notification is one message to one recipient and the state of its delivery.
+// Its content is rendered when it is queued and kept until it is pruned.
+type Notification struct {
+	ID      uuid.UUID `json:"id" db:"id"`
+	Kind    string    `json:"kind" db:"kind"`
+	Channel string    `json:"channel" db:"channel"`
+	// RecipientID is unset once the recipient is deleted
+	RecipientID *uuid.UUID `json:"recipientId,omitempty" db:"recipient_id"`
+	// Address is where the notification is sent: an email address or, for an
+	// SMS page, a phone number
+	Address string `json:"address" db:"address"`
+	// Reference is what the notification is about, e.g. "Observation/<id>"
+	Reference string `json:"reference" db:"reference"`
+	Subject   string `json:"subject" db:"subject"`
+	// TextBody and HTMLBody are the rendered content, sent as alternatives; an
+	// SMS page sends TextBody only
+	TextBody  string  `json:"-" db:"text_body"`
+	HTMLBody  string  `json:"-" db:"html_body"`
+	Status    string  `json:"status" db:"status"`
+	Attempts  int     `json:"attempts" db:"attempts"`
+	LastError *string `json:"lastError,omitempty" db:"last_error"`
+	// ProviderMessageID is the provider's id of a sent SMS page
+	ProviderMessageID *string `json:"providerMessageId,omitempty" db:"provider_message_id"`
+	// DeliverAfter is when a held page is released
+	DeliverAfter *time.Time `json:"deliverAfter,omitempty" db:"deliver_after"`
+	// EscalateAt is when an unacknowledged page escalates to the recipient's
+	// escalation target, and EscalatedAt when it did
+	EscalateAt  *time.Time `json:"escalateAt,omitempty" db:"escalate_at"`
+	EscalatedAt *time.Time `json:"escalatedAt,omitempty" db:"escalated_at"`
+	// AcknowledgedAt is set on every notification of the event once one of
+	// them is acknowledged, which stops escalation
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty" db:"acknowledged_at"`
+	AcknowledgedBy *string    `json:"acknowledgedBy,omitempty" db:"acknowledged_by"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+	SentAt         *time.Time `json:"sentAt,omitempty" db:"sent_at"`
+
+	// TenantID is set on notifications read across tenants
+	TenantID string `json:"-" db:"tenant_id"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ObservationTrend is a patient's results for a code over a period, bucketed
+// into points for charting. Results in different units are kept apart, one
+// series per unit.
+type ObservationTrend struct {
+	Subject string `json:"subject"`
+	Code    string `json:"code"`
+	// Period and Bucket are durations such as "90d" and "1d"
+	Period string        `json:"period"`
+	Bucket string        `json:"bucket"`
+	Start  time.Time     `json:"start"`
+	End    time.Time     `json:"end"`
+	Series []TrendSeries `json:"series"`
+}
+
+// TrendSeries holds the points of the results in one unit, oldest first; only
+// buckets with results have a point
+type TrendSeries struct {
+	// Unit is the UCUM code of the results, or their unit text without one
+	Unit   string       `json:"unit"`
+	Points []TrendPoint `json:"points"`
+}
+
+// TrendPoint summarizes the results of a bucket, which starts at Start
+type TrendPoint struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+	Avg   float64   `json:"avg"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+}
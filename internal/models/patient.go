@@ -66,7 +66,7 @@ type PatientCreateRequest struct {
 	MaritalStatus           *CodeableConcept  `json:"maritalStatus,omitempty"`
 	MultipleBirthBoolean    *bool             `json:"multipleBirthBoolean,omitempty"`
 	MultipleBirthInteger    *int              `json:"multipleBirthInteger,omitempty"`
-	Photo                   []Attachment      `json:"photo,omitempty"`
+	Photo                   []Attachment      `json:"photo,omitempty" validate:"omitempty,dive"`
 	Contact                 []PatientContact  `json:"contact,omitempty"`
 	Communication           []PatientCommunication `json:"communication,omitempty"`
 	GeneralPractitioner     []Reference       `json:"generalPractitioner,omitempty"`
@@ -88,7 +88,7 @@ type PatientUpdateRequest struct {
 	MaritalStatus           *CodeableConcept  `json:"maritalStatus,omitempty"`
 	MultipleBirthBoolean    *bool             `json:"multipleBirthBoolean,omitempty"`
 	MultipleBirthInteger    *int              `json:"multipleBirthInteger,omitempty"`
-	Photo                   []Attachment      `json:"photo,omitempty"`
+	Photo                   []Attachment      `json:"photo,omitempty" validate:"omitempty,dive"`
 	Contact                 []PatientContact  `json:"contact,omitempty"`
 	Communication           []PatientCommunication `json:"communication,omitempty"`
 	GeneralPractitioner     []Reference       `json:"generalPractitioner,omitempty"`
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of a duplicate patient candidate
+const (
+	// DuplicatePending waits for a registrar's review
+	DuplicatePending = "pending"
+	// DuplicateConfirmed: a registrar confirmed the patients are the same person
+	DuplicateConfirmed = "confirmed"
+	// DuplicateDismissed: a registrar found the patients to be different people;
+	// the pair is not raised again
+	DuplicateDismissed = "dismissed"
+)
+
+// ValidDuplicateStatus reports whether status is a duplicate candidate status
+func ValidDuplicateStatus(status string) bool {
+	switch status {
+	case DuplicatePending, DuplicateConfirmed, DuplicateDismissed:
+		return true
+	}
+	return false
+}
+
+// PatientDuplicate is a pair of patients probably describing the same person,
+// found by the duplicate-detection job. PatientID is the lower of the two ids.
+type PatientDuplicate struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	PatientID   uuid.UUID `json:"patientId" db:"patient_id"`
+	DuplicateID uuid.UUID `json:"duplicateId" db:"duplicate_id"`
+	// Score, from 0 to 1, is how likely the patients are the same person
+	Score float64 `json:"score" db:"score"`
+	// Reasons lists what matched, such as "name", "birth-date" or "identifier"
+	Reasons    []string   `json:"reasons" db:"reasons"`
+	Status     string     `json:"status" db:"status"`
+	DetectedAt time.Time  `json:"detectedAt" db:"detected_at"`
+	ReviewedAt *time.Time `json:"reviewedAt,omitempty" db:"reviewed_at"`
+	ReviewedBy *string    `json:"reviewedBy,omitempty" db:"reviewed_by"`
+	Note       *string    `json:"note,omitempty" db:"note"`
+	CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updatedAt" db:"updated_at"`
+	TenantID   string     `json:"-" db:"tenant_id"`
+}
+
+// PatientDuplicateReviewRequest represents the optional body of confirming or
+// dismissing a duplicate candidate
+type PatientDuplicateReviewRequest struct {
+	Note *string `json:"note,omitempty" binding:"omitempty,max=2000"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// PatientMergeRequest represents the body of a patient $merge: the source
+// patient is merged into the target, which survives
+type PatientMergeRequest struct {
+	Source string `json:"source" binding:"required,uuid"`
+	Target string `json:"target" binding:"required,uuid"`
+}
+
+// PatientMergeResult represents the outcome of a patient merge
+type PatientMergeResult struct {
+	// Source is the merged patient, now inactive and replaced-by the target
+	Source *Patient `json:"source"`
+	// Target is the surviving patient, which replaces the source
+	Target *Patient `json:"target"`
+	// Moved counts the resources re-pointed from the source to the target by
+	// resource type, e.g. {"Observation": 12}
+	Moved map[string]int `json:"moved"`
+}
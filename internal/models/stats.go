@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Stats summarizes a tenant's data for the operational dashboard. It is
+// computed by grouped queries and cached, so it may lag writes by up to the
+// cache TTL given by GeneratedAt.
+type Stats struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Resources counts the live resources of each type
+	Resources []ResourceTypeCount `json:"resources"`
+	// ObservationsByCode counts live observations by their first coding, most
+	// frequent first
+	ObservationsByCode []CodeCount `json:"observationsByCode"`
+	// ObservationsByCategory counts live observations by the first coding of
+	// each category; observations without a category are not counted
+	ObservationsByCategory []CodeCount `json:"observationsByCategory"`
+	// ObservationsByMonth counts live observations by the UTC month they were
+	// effective in, over the months requested
+	ObservationsByMonth []MonthCount `json:"observationsByMonth"`
+	// Registrations counts the live patients by the UTC month they were created
+	// in, over the months requested
+	Registrations []MonthCount `json:"registrations"`
+}
+
+type ResourceTypeCount struct {
+	ResourceType string `json:"resourceType"`
+	Count        int64  `json:"count"`
+}
+
+type CodeCount struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code"`
+	Display string `json:"display,omitempty"`
+	Count   int64  `json:"count"`
+}
+
+// MonthCount is a count for the month starting at Month, in UTC
+type MonthCount struct {
+	Month time.Time `json:"month"`
+	Count int64     `json:"count"`
+}
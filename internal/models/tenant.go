@@ -0,0 +1,51 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// Tenant statuses
+const (
+	TenantStatusActive    = "active"
+	TenantStatusSuspended = "suspended"
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// ValidTenantID reports whether id is a valid tenant identifier (lowercase slug)
+func ValidTenantID(id string) bool {
+	return tenantIDPattern.MatchString(id)
+}
+
+// Tenant represents a clinic or organisation hosted on the deployment
+type Tenant struct {
+	ID                 string    `json:"id" db:"id"`
+	Name               string    `json:"name" db:"name"`
+	Status             string    `json:"status" db:"status"`
+	RateLimitPerMinute int       `json:"rateLimitPerMinute" db:"rate_limit_per_minute"`
+	RateLimitBurst     int       `json:"rateLimitBurst" db:"rate_limit_burst"`
+	CreatedAt          time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt          time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// IsActive reports whether the tenant may serve requests
+func (t *Tenant) IsActive() bool {
+	return t.Status == TenantStatusActive
+}
+
+// TenantCreateRequest represents the request to provision a tenant
+type TenantCreateRequest struct {
+	ID                 string `json:"id" binding:"required,max=63"`
+	Name               string `json:"name" binding:"required,max=255"`
+	RateLimitPerMinute *int   `json:"rateLimitPerMinute,omitempty" binding:"omitempty,min=1"`
+	RateLimitBurst     *int   `json:"rateLimitBurst,omitempty" binding:"omitempty,min=1"`
+}
+
+// TenantUpdateRequest represents the request to update a tenant
+type TenantUpdateRequest struct {
+	Name               *string `json:"name,omitempty" binding:"omitempty,max=255"`
+	Status             *string `json:"status,omitempty" binding:"omitempty,oneof=active suspended"`
+	RateLimitPerMinute *int    `json:"rateLimitPerMinute,omitempty" binding:"omitempty,min=1"`
+	RateLimitBurst     *int    `json:"rateLimitBurst,omitempty" binding:"omitempty,min=1"`
+}
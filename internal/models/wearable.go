@@ -0,0 +1,11 @@
+package models
+
+// WearableIngestResponse is the outcome of ingesting a batch of wearable samples
+type WearableIngestResponse struct {
+	// Received is the number of samples in the batch
+	Received int `json:"received"`
+	// Created is the number of observations stored
+	Created int `json:"created"`
+	// Duplicates is the number of samples already stored, or repeated in the batch
+	Duplicates int `json:"duplicates"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// webhookResourceTypes and webhookActions are the parts of the event patterns a
+// webhook can subscribe to
+var (
+	webhookResourceTypes = map[string]bool{"*": true, "Patient": true, "Observation": true, "DiagnosticReport": true, "ImagingStudy": true}
+	webhookActions       = map[string]bool{"*": true, "create": true, "update": true, "delete": true, "restore": true}
+)
+
+// ValidWebhookEventPattern reports whether pattern is "<resource type>.<action>",
+// with "*" for either part, e.g. "Patient.create" or "Observation.*"
+func ValidWebhookEventPattern(pattern string) bool {
+	resourceType, action, ok := strings.Cut(pattern, ".")
+	return ok && webhookResourceTypes[resourceType] && webhookActions[action]
+}
+
+// Webhook is an endpoint receiving the tenant's resource change events
+type Webhook struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	URL         string    `json:"url" db:"url"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	// Events lists the event patterns delivered; empty delivers every event
+	Events []string `json:"events" db:"events"`
+	Active bool     `json:"active" db:"active"`
+	// Secret signs deliveries. It is returned only when the webhook is created
+	// and when the secret is rotated.
+	Secret    string    `json:"secret,omitempty" db:"secret"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// Matches reports whether the webhook receives events of eventType, e.g. "Patient.create"
+func (w *Webhook) Matches(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	resourceType, action, _ := strings.Cut(eventType, ".")
+	for _, pattern := range w.Events {
+		patternType, patternAction, _ := strings.Cut(pattern, ".")
+		if (patternType == "*" || patternType == resourceType) && (patternAction == "*" || patternAction == action) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookCreateRequest represents the request to register a webhook
+type WebhookCreateRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=2048"`
+	Description *string  `json:"description,omitempty" binding:"omitempty,max=255"`
+	Events      []string `json:"events,omitempty" binding:"omitempty,max=20"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+// WebhookUpdateRequest represents the request to update a webhook
+type WebhookUpdateRequest struct {
+	URL         *string   `json:"url,omitempty" binding:"omitempty,url,max=2048"`
+	Description *string   `json:"description,omitempty" binding:"omitempty,max=255"`
+	Events      *[]string `json:"events,omitempty" binding:"omitempty,max=20"`
+	Active      *bool     `json:"active,omitempty"`
+	// RotateSecret replaces the signing secret; the new one is returned once
+	RotateSecret bool `json:"rotateSecret,omitempty"`
+}
+
+// WebhookDelivery is one attempt to deliver an event to a webhook
+type WebhookDelivery struct {
+	ID        int64     `json:"id" db:"id"`
+	WebhookID uuid.UUID `json:"webhookId" db:"webhook_id"`
+	EventID   string    `json:"eventId" db:"event_id"`
+	EventType string    `json:"eventType" db:"event_type"`
+	// Payload is the request body sent
+	Payload    json.RawMessage `json:"payload" db:"payload"`
+	Attempt    int             `json:"attempt" db:"attempt"`
+	URL        string          `json:"url" db:"url"`
+	Success    bool            `json:"success" db:"success"`
+	StatusCode *int            `json:"statusCode,omitempty" db:"status_code"`
+	// ResponseBody is the start of the endpoint's response, for debugging
+	ResponseBody *string   `json:"responseBody,omitempty" db:"response_body"`
+	Error        *string   `json:"error,omitempty" db:"error"`
+	DurationMS   int64     `json:"durationMs" db:"duration_ms"`
+	DeliveredAt  time.Time `json:"deliveredAt" db:"delivered_at"`
+}
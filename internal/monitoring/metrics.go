@@ -15,20 +15,43 @@ type Metrics struct {
 	cacheHits         int64
 	cacheMisses       int64
 	workerPoolStats   map[string]WorkerPoolMetrics
+	// jobCounters accumulates job attempts per pool and job type
+	jobCounters       map[string]map[string]*jobCounter
 }
 
-// WorkerPoolMetrics represents metrics for a worker pool
+// jobCounter accumulates the attempts of one job type
+type jobCounter struct {
+	processed int64
+	failed    int64
+	duration  time.Duration
+}
+
+// WorkerPoolMetrics represents metrics for a worker pool. Job counts cover
+// every attempt, so a job retried twice counts three times.
 type WorkerPoolMetrics struct {
 	JobsProcessed int64         `json:"jobs_processed"`
 	JobsFailed    int64         `json:"jobs_failed"`
 	AvgDuration   time.Duration `json:"avg_duration"`
 	QueueSize     int           `json:"queue_size"`
+	QueueCapacity int           `json:"queue_capacity"`
+	Workers       int           `json:"workers"`
+	ParkedJobs    int           `json:"parked_jobs"`
+	JobTypes      map[string]JobTypeMetrics `json:"job_types,omitempty"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// JobTypeMetrics represents the attempts of one job type
+type JobTypeMetrics struct {
+	JobsProcessed int64         `json:"jobs_processed"`
+	JobsFailed    int64         `json:"jobs_failed"`
+	AvgDuration   time.Duration `json:"avg_duration"`
 }
 
 // NewMetrics creates a new metrics collector
 func NewMetrics() *Metrics {
 	return &Metrics{
 		workerPoolStats: make(map[string]WorkerPoolMetrics),
+		jobCounters:     make(map[string]map[string]*jobCounter),
 	}
 }
 
@@ -74,13 +97,64 @@ func (m *Metrics) IncrementCacheMisses() {
 	m.cacheMisses++
 }
 
-// UpdateWorkerPoolStats updates worker pool statistics
+// UpdateWorkerPoolStats updates worker pool statistics. Job counts and durations
+// recorded with RecordJob take precedence over those in stats.
 func (m *Metrics) UpdateWorkerPoolStats(poolName string, stats WorkerPoolMetrics) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.workerPoolStats[poolName] = stats
 }
 
+// RecordJob counts one finished job attempt for a worker pool
+func (m *Metrics) RecordJob(poolName, jobType string, success bool, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	counters, ok := m.jobCounters[poolName]
+	if !ok {
+		counters = make(map[string]*jobCounter)
+		m.jobCounters[poolName] = counters
+	}
+	counter, ok := counters[jobType]
+	if !ok {
+		counter = &jobCounter{}
+		counters[jobType] = counter
+	}
+	
+	counter.processed++
+	if !success {
+		counter.failed++
+	}
+	counter.duration += duration
+}
+
+// poolMetrics merges a pool's sampled statistics with its job counters
+func (m *Metrics) poolMetrics(poolName string) WorkerPoolMetrics {
+	stats := m.workerPoolStats[poolName]
+	counters, ok := m.jobCounters[poolName]
+	if !ok {
+		return stats
+	}
+	
+	stats.JobsProcessed, stats.JobsFailed = 0, 0
+	stats.JobTypes = make(map[string]JobTypeMetrics, len(counters))
+	var total time.Duration
+	for jobType, counter := range counters {
+		stats.JobsProcessed += counter.processed
+		stats.JobsFailed += counter.failed
+		total += counter.duration
+		stats.JobTypes[jobType] = JobTypeMetrics{
+			JobsProcessed: counter.processed,
+			JobsFailed:    counter.failed,
+			AvgDuration:   counter.duration / time.Duration(counter.processed),
+		}
+	}
+	if stats.JobsProcessed > 0 {
+		stats.AvgDuration = total / time.Duration(stats.JobsProcessed)
+	}
+	return stats
+}
+
 // GetSnapshot returns a snapshot of current metrics
 func (m *Metrics) GetSnapshot() MetricsSnapshot {
 	m.mu.RLock()
@@ -97,15 +171,23 @@ func (m *Metrics) GetSnapshot() MetricsSnapshot {
 		cacheHitRate = float64(m.cacheHits) / float64(totalCacheRequests)
 	}
 	
+	errorRate := float64(0)
+	if m.requestCount > 0 {
+		errorRate = float64(m.errorCount) / float64(m.requestCount)
+	}
+	
 	workerPoolStats := make(map[string]WorkerPoolMetrics)
-	for k, v := range m.workerPoolStats {
-		workerPoolStats[k] = v
+	for k := range m.workerPoolStats {
+		workerPoolStats[k] = m.poolMetrics(k)
+	}
+	for k := range m.jobCounters {
+		workerPoolStats[k] = m.poolMetrics(k)
 	}
 	
 	return MetricsSnapshot{
 		RequestCount:      m.requestCount,
 		ErrorCount:        m.errorCount,
-		ErrorRate:         float64(m.errorCount) / float64(m.requestCount),
+		ErrorRate:         errorRate,
 		AvgDuration:       avgDuration,
 		ActiveConnections: m.activeConnections,
 		CacheHitRate:      cacheHitRate,
@@ -0,0 +1,101 @@
+// Package notify renders notifications from templates and sends them through
+// the configured providers. Notifications leave the deployment, so templates
+// carry resource ids, links and test names, never patient details.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+
+	"healthcare-api/internal/config"
+)
+
+// Providers
+const (
+	// ProviderNone sends no email or SMS
+	ProviderNone = "none"
+	// ProviderSMTP sends email through an SMTP server
+	ProviderSMTP = "smtp"
+	// ProviderTwilio sends SMS through the Twilio Messages API or a compatible one
+	ProviderTwilio = "twilio"
+)
+
+// Email is a message to one address, with a plain text body and an HTML
+// alternative
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// MessageID identifies the message, so a resend after an unknown outcome
+	// can be recognized as a duplicate by mail clients
+	MessageID string
+}
+
+// Mailer sends email
+type Mailer interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// NewMailer returns the mailer selected by cfg.EmailProvider, or nil for ProviderNone
+func NewMailer(cfg config.NotificationConfig) (Mailer, error) {
+	switch cfg.EmailProvider {
+	case "", ProviderNone:
+		return nil, nil
+	case ProviderSMTP:
+		return NewSMTPMailer(cfg.SMTP)
+	default:
+		return nil, fmt.Errorf("unsupported email provider: %s", cfg.EmailProvider)
+	}
+}
+
+// SMS is a text message to one phone number
+type SMS struct {
+	// To is the phone number in E.164 format
+	To   string
+	Body string
+}
+
+// SMSSender sends SMS, returning the provider's id of the message
+type SMSSender interface {
+	SendSMS(ctx context.Context, sms SMS) (string, error)
+}
+
+// NewSMSSender returns the sender selected by cfg.SMSProvider, or nil for ProviderNone
+func NewSMSSender(cfg config.NotificationConfig) (SMSSender, error) {
+	switch cfg.SMSProvider {
+	case "", ProviderNone:
+		return nil, nil
+	case ProviderTwilio:
+		return NewTwilioSender(cfg.Twilio)
+	default:
+		return nil, fmt.Errorf("unsupported SMS provider: %s", cfg.SMSProvider)
+	}
+}
+
+// Permanent reports whether a send failed in a way retrying cannot fix, such as
+// the mail server rejecting the recipient with a 5xx reply or the SMS provider
+// rejecting the number. Rejected credentials are fixed in the configuration,
+// not the message, so they are not permanent.
+func Permanent(err error) bool {
+	var twilioErr *TwilioError
+	if errors.As(err, &twilioErr) {
+		switch twilioErr.StatusCode {
+		case 401, 403, 429:
+			return false
+		}
+		return twilioErr.StatusCode >= 400 && twilioErr.StatusCode < 500
+	}
+
+	var smtpErr *textproto.Error
+	if !errors.As(err, &smtpErr) {
+		return false
+	}
+	switch smtpErr.Code {
+	case 530, 534, 535:
+		return false
+	}
+	return smtpErr.Code >= 500
+}
@@ -0,0 +1,210 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/config"
+
+	"github.com/google/uuid"
+)
+
+// SMTP TLS modes
+const (
+	SMTPStartTLS = "starttls"
+	SMTPTLS      = "tls"
+	SMTPNoTLS    = "none"
+)
+
+// SMTPMailer sends email through an SMTP server, one connection per message
+type SMTPMailer struct {
+	addr    string
+	host    string
+	from    *mail.Address
+	tlsMode string
+	auth    smtp.Auth
+	timeout time.Duration
+}
+
+// NewSMTPMailer validates cfg and returns a mailer for its server; nothing is
+// sent until the first message
+func NewSMTPMailer(cfg config.SMTPConfig) (*SMTPMailer, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp sender address %q: %w", cfg.From, err)
+	}
+	switch cfg.TLS {
+	case SMTPStartTLS, SMTPTLS, SMTPNoTLS:
+	default:
+		return nil, fmt.Errorf("invalid smtp TLS mode %q: expected starttls, tls or none", cfg.TLS)
+	}
+	if cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid smtp timeout %d: must be positive", cfg.Timeout)
+	}
+
+	mailer := &SMTPMailer{
+		addr:    net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		host:    cfg.Host,
+		from:    from,
+		tlsMode: cfg.TLS,
+		timeout: time.Duration(cfg.Timeout) * time.Second,
+	}
+	if cfg.Username != "" {
+		// PLAIN authentication refuses to send credentials over an unencrypted
+		// connection to anything but localhost
+		mailer.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return mailer, nil
+}
+
+// Send delivers the email to the server. A nil error means the server accepted
+// it for delivery.
+func (m *SMTPMailer) Send(ctx context.Context, email Email) error {
+	to, err := mail.ParseAddress(email.To)
+	if err != nil {
+		return &textproto.Error{Code: 501, Msg: fmt.Sprintf("invalid recipient address %q: %v", email.To, err)}
+	}
+	message, err := m.message(to, email)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	conn, err := m.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	// net/smtp does not take a context, so the deadline bounds the whole exchange
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to greet smtp server: %w", err)
+	}
+	defer client.Close()
+
+	if m.tlsMode == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not offer STARTTLS")
+		}
+		if err := client.StartTLS(m.tlsConfig()); err != nil {
+			return fmt.Errorf("failed to start TLS with smtp server: %w", err)
+		}
+	}
+	if m.auth != nil {
+		if err := client.Auth(m.auth); err != nil {
+			return fmt.Errorf("failed to authenticate with smtp server: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("smtp server refused sender: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("smtp server refused recipient: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp server refused message: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp server refused message: %w", err)
+	}
+
+	// The message is accepted; a failure to say goodbye does not change that
+	client.Quit()
+	return nil
+}
+
+// dial connects to the server, over TLS from the start in SMTPTLS mode
+func (m *SMTPMailer) dial(ctx context.Context) (net.Conn, error) {
+	if m.tlsMode == SMTPTLS {
+		dialer := &tls.Dialer{Config: m.tlsConfig()}
+		return dialer.DialContext(ctx, "tcp", m.addr)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", m.addr)
+}
+
+func (m *SMTPMailer) tlsConfig() *tls.Config {
+	return &tls.Config{ServerName: m.host, MinVersion: tls.VersionTLS12}
+}
+
+// message encodes the email as a multipart/alternative message, its bodies
+// quoted-printable so lines stay within SMTP limits
+func (m *SMTPMailer) message(to *mail.Address, email Email) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	alternatives := []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	}
+	for _, alternative := range alternatives {
+		if alternative.content == "" {
+			continue
+		}
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {alternative.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(part)
+		if _, err := qp.Write([]byte(alternative.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	messageID := email.MessageID
+	if messageID == "" {
+		messageID = uuid.NewString()
+	}
+	_, domain, _ := strings.Cut(m.from.Address, "@")
+
+	var message bytes.Buffer
+	for _, header := range []string{
+		"From: " + m.from.String(),
+		"To: " + to.String(),
+		// Encoding also keeps line breaks in a subject from starting new headers
+		"Subject: " + mime.QEncoding.Encode("utf-8", email.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: <" + messageID + "@" + domain + ">",
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + parts.Boundary(),
+		"Auto-Submitted: auto-generated",
+	} {
+		message.WriteString(header + "\r\n")
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"healthcare-api/internal/models"
+)
+
+// Templates are kept in templates/<kind>.txt, which defines the "subject" and
+// "text" templates, and templates/<kind>.html, the HTML body. Kinds that page
+// by SMS also define an "sms" template in the .txt file.
+//
+//go:embed templates
+var templateFiles embed.FS
+
+// CriticalResult is the data of critical_result notifications
+type CriticalResult struct {
+	TenantID      string
+	ObservationID string
+	// Test is what was observed, e.g. "Potassium [Moles/volume] in Serum or Plasma"
+	Test string
+	// Interpretation is the critical interpretation, e.g. "Critical high"
+	Interpretation string
+	RecordedAt     time.Time
+	// Link leads to the observation in the API, if a link base URL is configured
+	Link string
+}
+
+// BackupCompleted is the data of backup_completed notifications
+type BackupCompleted struct {
+	TenantID     string
+	BackupID     string
+	Patients     int
+	Observations int
+	SnapshotAt   time.Time
+	CompletedAt  time.Time
+	// Link leads to the backup in the API, if a link base URL is configured
+	Link string
+}
+
+// Content is a rendered notification
+type Content struct {
+	Subject string
+	Text    string
+	HTML    string
+	// SMS is the text of an SMS page; empty for kinds that do not page
+	SMS string
+}
+
+type kindTemplates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// templates holds the parsed templates of every notification kind
+var templates = map[string]kindTemplates{
+	models.NotificationCriticalResult:  parseTemplates(models.NotificationCriticalResult),
+	models.NotificationBackupCompleted: parseTemplates(models.NotificationBackupCompleted),
+}
+
+func parseTemplates(kind string) kindTemplates {
+	return kindTemplates{
+		text: texttemplate.Must(texttemplate.New(kind).Option("missingkey=error").ParseFS(templateFiles, "templates/"+kind+".txt")),
+		html: htmltemplate.Must(htmltemplate.New(kind).Option("missingkey=error").ParseFS(templateFiles, "templates/"+kind+".html")),
+	}
+}
+
+// Render renders the notification of kind with data, one of the data types
+// of this package
+func Render(kind string, data interface{}) (*Content, error) {
+	tmpl, ok := templates[kind]
+	if !ok {
+		return nil, fmt.Errorf("no templates for notification kind %q", kind)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", kind, err)
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s text: %w", kind, err)
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, kind+".html", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s HTML: %w", kind, err)
+	}
+	var sms bytes.Buffer
+	if tmpl.text.Lookup("sms") != nil {
+		if err := tmpl.text.ExecuteTemplate(&sms, "sms", data); err != nil {
+			return nil, fmt.Errorf("failed to render %s SMS: %w", kind, err)
+		}
+	}
+
+	return &Content{
+		// A subject is a single line, whatever the data holds
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+		// An SMS is read on a pager screen; line breaks only cost characters
+		SMS: strings.Join(strings.Fields(sms.String()), " "),
+	}, nil
+}
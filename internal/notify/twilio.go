@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/config"
+)
+
+// TwilioSender sends SMS through the Twilio Messages API, or a provider
+// offering the same API
+type TwilioSender struct {
+	endpoint            string
+	accountSID          string
+	authToken           string
+	from                string
+	messagingServiceSID string
+	client              *http.Client
+}
+
+// NewTwilioSender validates cfg and returns a sender for its account; nothing
+// is sent until the first message
+func NewTwilioSender(cfg config.TwilioConfig) (*TwilioSender, error) {
+	base, err := url.Parse(cfg.APIURL)
+	if err != nil || (base.Scheme != "https" && base.Scheme != "http") || base.Host == "" {
+		return nil, fmt.Errorf("invalid Twilio API URL %q", cfg.APIURL)
+	}
+	if cfg.AccountSID == "" || cfg.AuthToken == "" {
+		return nil, errors.New("Twilio account SID and auth token are required")
+	}
+	if cfg.From == "" && cfg.MessagingServiceSID == "" {
+		return nil, errors.New("a Twilio sending number or messaging service SID is required")
+	}
+	if cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid Twilio timeout %d: must be positive", cfg.Timeout)
+	}
+
+	return &TwilioSender{
+		endpoint:            cfg.APIURL + "/2010-04-01/Accounts/" + url.PathEscape(cfg.AccountSID) + "/Messages.json",
+		accountSID:          cfg.AccountSID,
+		authToken:           cfg.AuthToken,
+		from:                cfg.From,
+		messagingServiceSID: cfg.MessagingServiceSID,
+		client:              &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+	}, nil
+}
+
+// SendSMS creates the message, returning its SID. A nil error means the
+// provider accepted it for delivery.
+func (t *TwilioSender) SendSMS(ctx context.Context, sms SMS) (string, error) {
+	form := url.Values{
+		"To":   {sms.To},
+		"Body": {sms.Body},
+	}
+	if t.messagingServiceSID != "" {
+		form.Set("MessagingServiceSid", t.messagingServiceSID)
+	} else {
+		form.Set("From", t.from)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach Twilio: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		twilioErr := &TwilioError{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(data, twilioErr)
+		return "", twilioErr
+	}
+
+	var message struct {
+		SID string `json:"sid"`
+	}
+	if err := json.Unmarshal(data, &message); err != nil {
+		return "", fmt.Errorf("failed to decode Twilio response: %w", err)
+	}
+	return message.SID, nil
+}
+
+// TwilioError is an error response of the Messages API, such as code 21211
+// for an invalid number
+type TwilioError struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *TwilioError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Twilio responded %d", e.StatusCode)
+	}
+	return fmt.Sprintf("Twilio responded %d: %s (code %d)", e.StatusCode, e.Message, e.Code)
+}
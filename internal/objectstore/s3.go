@@ -0,0 +1,406 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/config"
+)
+
+// s3PartSize is the size of the parts larger objects are uploaded in. Objects
+// up to one part are uploaded with a single request. S3 requires parts other
+// than the last to be at least 5 MiB.
+const s3PartSize = 8 << 20
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Store is a Store backed by a bucket of a service speaking the S3 API, such
+// as Amazon S3, Google Cloud Storage with HMAC keys, or MinIO. Requests are
+// signed with AWS Signature Version 4.
+type S3Store struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    *http.Client
+}
+
+// NewS3Store creates a store for cfg.Bucket. Without an endpoint, backend "s3"
+// uses the Amazon S3 endpoint of the region and backend "gcs" the Cloud Storage
+// XML API.
+func NewS3Store(cfg config.ObjectStoreConfig) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("object store bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("object store access key id and secret access key are required")
+	}
+
+	region := cfg.Region
+	endpoint := cfg.Endpoint
+	switch {
+	case cfg.Backend == BackendGCS && region == "":
+		region = "auto"
+	case region == "":
+		region = "us-east-1"
+	}
+	if endpoint == "" {
+		if cfg.Backend == BackendGCS {
+			endpoint = "https://storage.googleapis.com"
+		} else {
+			endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid object store endpoint %q", endpoint)
+	}
+
+	return &S3Store{
+		endpoint:  u,
+		bucket:    cfg.Bucket,
+		region:    region,
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		pathStyle: cfg.PathStyle,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+
+	buf := make([]byte, s3PartSize)
+	n, err := io.ReadFull(&contextReader{ctx: ctx, r: r}, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// The whole object fits in one part
+		resp, err := s.do(ctx, http.MethodPut, key, nil, buf[:n])
+		if err != nil {
+			return fmt.Errorf("failed to store object %s: %w", key, err)
+		}
+		resp.Body.Close()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write object %s: %w", key, err)
+	}
+
+	if err := s.putMultipart(ctx, key, r, buf); err != nil {
+		return fmt.Errorf("failed to store object %s: %w", key, err)
+	}
+	return nil
+}
+
+// putMultipart uploads an object larger than one part. buf holds the first
+// part; the rest is read from r. The upload is aborted on failure, so a partial
+// object never becomes visible.
+func (s *S3Store) putMultipart(ctx context.Context, key string, r io.Reader, buf []byte) error {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := decodeXML(resp, &initiated); err != nil {
+		return fmt.Errorf("failed to start multipart upload: invalid response: %w", err)
+	}
+	if initiated.UploadID == "" {
+		return errors.New("failed to start multipart upload: no upload id in response")
+	}
+	uploadID := initiated.UploadID
+
+	var completed struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}
+	upload := func() error {
+		part := buf
+		for number := 1; ; number++ {
+			query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+			resp, err := s.do(ctx, http.MethodPut, key, query, part)
+			if err != nil {
+				return fmt.Errorf("failed to upload part %d: %w", number, err)
+			}
+			resp.Body.Close()
+			completed.Parts = append(completed.Parts, s3Part{Number: number, ETag: resp.Header.Get("ETag")})
+
+			n, err := io.ReadFull(&contextReader{ctx: ctx, r: r}, buf)
+			if n == 0 && err == io.EOF {
+				return nil
+			}
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return err
+			}
+			part = buf[:n]
+		}
+	}
+	if err := upload(); err != nil {
+		s.abortMultipart(key, uploadID)
+		return err
+	}
+
+	body, err := xml.Marshal(completed)
+	if err != nil {
+		s.abortMultipart(key, uploadID)
+		return err
+	}
+	resp, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		s.abortMultipart(key, uploadID)
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	// Completion may fail after the 200 status has been sent, reported in the body
+	var result struct {
+		XMLName xml.Name
+		s3Error
+	}
+	if err := decodeXML(resp, &result); err != nil {
+		return fmt.Errorf("failed to complete multipart upload: invalid response: %w", err)
+	}
+	if result.XMLName.Local == "Error" {
+		s.abortMultipart(key, uploadID)
+		return fmt.Errorf("failed to complete multipart upload: %w", &result.s3Error)
+	}
+	return nil
+}
+
+// abortMultipart discards the parts of a failed upload. It runs without the
+// request's context, which may be what cancelled the upload.
+func (s *S3Store) abortMultipart(key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if resp, err := s.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil); err == nil {
+		resp.Body.Close()
+	}
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		var s3Err *s3Error
+		if errors.As(err, &s3Err) && s3Err.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open object %s: %w", key, err)
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := decodeXML(resp, &page); err != nil {
+			return nil, fmt.Errorf("failed to list objects: invalid response: %w", err)
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// do sends a signed request for key, or for the bucket when key is empty,
+// returning an *s3Error for responses other than 2xx
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	host := s.endpoint.Host
+	path := strings.TrimSuffix(s.endpoint.EscapedPath(), "/")
+	if s.pathStyle {
+		path += "/" + uriEncode(s.bucket, true)
+	} else {
+		host = s.bucket + "." + host
+	}
+	path += "/" + uriEncode(key, false)
+
+	rawQuery := canonicalQuery(query)
+	target := s.endpoint.Scheme + "://" + host + path
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, path, rawQuery, body, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		s3Err := &s3Error{StatusCode: resp.StatusCode}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		xml.Unmarshal(data, s3Err)
+		return nil, s3Err
+	}
+	return resp, nil
+}
+
+// sign adds AWS Signature Version 4 headers to req. path and rawQuery are the
+// canonical URI and query string the request was built from.
+func (s *S3Store) sign(req *http.Request, path, rawQuery string, body []byte, now time.Time) {
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	amzDate := now.Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "host" || strings.HasPrefix(lower, "x-amz-") || lower == "content-type" || lower == "range" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, rawQuery, canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// s3Part is a completed part of a multipart upload
+type s3Part struct {
+	Number int    `xml:"PartNumber"`
+	ETag   string `xml:"ETag"`
+}
+
+// s3Error is an error response of the S3 API
+type s3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("object store responded %d", e.StatusCode)
+	}
+	return fmt.Sprintf("object store responded %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// decodeXML decodes and closes a response body
+func decodeXML(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+// canonicalQuery encodes query sorted by name, as Signature Version 4 requires
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and slashes
+// unless encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
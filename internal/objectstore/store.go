@@ -0,0 +1,174 @@
+// Package objectstore stores opaque blobs such as backup snapshots by key.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"healthcare-api/internal/config"
+)
+
+// Supported backends
+const (
+	// BackendFilesystem stores objects as files under a local (or mounted) directory
+	BackendFilesystem = "filesystem"
+	// BackendS3 stores objects in an Amazon S3 bucket, or one of another service
+	// speaking the S3 API such as MinIO
+	BackendS3 = "s3"
+	// BackendGCS stores objects in a Google Cloud Storage bucket through its
+	// S3-compatible XML API, authenticated with HMAC keys
+	BackendGCS = "gcs"
+)
+
+// ErrNotFound is returned when a key does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Store is a flat key/value blob store. Keys are slash-separated paths.
+type Store interface {
+	// Put stores the contents of r under key, replacing any existing object.
+	// The object becomes visible only once it has been written completely.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens the object stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// List returns the keys starting with prefix, in lexical order
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// New returns the store selected by cfg.Backend
+func New(cfg config.ObjectStoreConfig) (Store, error) {
+	switch cfg.Backend {
+	case "", BackendFilesystem:
+		return NewFileStore(cfg.Path)
+	case BackendS3, BackendGCS:
+		return NewS3Store(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported object store backend: %s", cfg.Backend)
+	}
+}
+
+// FileStore is a Store backed by a directory
+type FileStore struct {
+	root string
+}
+
+func NewFileStore(root string) (*FileStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create object store directory: %w", err)
+	}
+	return &FileStore{root: root}, nil
+}
+
+// validKey rejects empty keys and keys that are not clean relative paths, which
+// could escape a filesystem root
+func validKey(key string) error {
+	clean := path.Clean("/" + key)
+	if key == "" || clean == "/" || clean != "/"+key {
+		return fmt.Errorf("invalid object key %q", key)
+	}
+	return nil
+}
+
+// path maps key to a file below root
+func (s *FileStore) path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *FileStore) Put(ctx context.Context, key string, r io.Reader) error {
+	file, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o750); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	// Write to a temporary file and rename, so readers never see a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, &contextReader{ctx: ctx, r: r}); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write object %s: %w", key, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync object %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close object %s: %w", key, err)
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to store object %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *FileStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open object %s: %w", key, err)
+	}
+	return f, nil
+}
+
+func (s *FileStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(s.root, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, file)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// contextReader stops a long copy once ctx is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,94 @@
+// Package quality defines the data quality issues the API looks for. Issues a
+// resource has on its own, such as a patient without identifiers, are checked
+// as it is written and flagged in its meta.tag; issues between resources, such
+// as a reference to a deleted patient, can arise without the resource
+// changing, so they are only found by the data quality report.
+package quality
+
+import (
+	"strings"
+
+	"healthcare-api/internal/models"
+)
+
+// TagSystem is the code system of the meta.tag flags of data quality issues
+const TagSystem = "urn:healthcare-api:data-quality"
+
+// Issue codes
+const (
+	// MissingIdentifier: a patient has no identifier with a value
+	MissingIdentifier = "missing-identifier"
+	// MissingUnit: an observation or one of its components has a quantity
+	// value without a unit
+	MissingUnit = "missing-unit"
+	// DanglingSubject: a resource's subject refers to a patient that does not
+	// exist or has been deleted
+	DanglingSubject = "dangling-subject"
+	// DanglingResult: a diagnostic report's result refers to an observation
+	// that does not exist or has been deleted
+	DanglingResult = "dangling-result"
+)
+
+// Descriptions of the issues, used as the display of their tags
+var Descriptions = map[string]string{
+	MissingIdentifier: "Patient has no identifier",
+	MissingUnit:       "Quantity value has no unit",
+	DanglingSubject:   "Subject refers to a missing or deleted patient",
+	DanglingResult:    "Result refers to a missing or deleted observation",
+}
+
+// PatientIssues returns the issues a patient has on its own
+func PatientIssues(patient *models.Patient) []string {
+	for _, identifier := range patient.Identifier {
+		if identifier.Value != nil && strings.TrimSpace(*identifier.Value) != "" {
+			return nil
+		}
+	}
+	return []string{MissingIdentifier}
+}
+
+// ObservationIssues returns the issues an observation has on its own
+func ObservationIssues(observation *models.Observation) []string {
+	if unitless(observation.ValueQuantity) {
+		return []string{MissingUnit}
+	}
+	for _, component := range observation.Component {
+		if unitless(component.ValueQuantity) {
+			return []string{MissingUnit}
+		}
+	}
+	return nil
+}
+
+// Tag replaces the data quality tags of meta with those of issues, returning
+// the meta to store. A nil meta stays nil when there is nothing to flag.
+func Tag(meta *models.Meta, issues []string) *models.Meta {
+	if meta == nil {
+		if len(issues) == 0 {
+			return nil
+		}
+		meta = &models.Meta{}
+	}
+
+	var tags []models.Coding
+	for _, tag := range meta.Tag {
+		if tag.System == nil || *tag.System != TagSystem {
+			tags = append(tags, tag)
+		}
+	}
+	for _, issue := range issues {
+		system, code, display := TagSystem, issue, Descriptions[issue]
+		tags = append(tags, models.Coding{System: &system, Code: &code, Display: &display})
+	}
+	meta.Tag = tags
+	return meta
+}
+
+// unitless reports whether a quantity has a value but neither a unit code
+// nor unit text
+func unitless(quantity *models.Quantity) bool {
+	if quantity == nil || quantity.Value == nil {
+		return false
+	}
+	return (quantity.Code == nil || *quantity.Code == "") && (quantity.Unit == nil || *quantity.Unit == "")
+}
@@ -0,0 +1,202 @@
+// Package redact keeps PHI out of what is logged about requests. A Policy
+// redacts the values of request bodies and query strings except those of
+// fields that only describe the request, such as status and code, and scrubs
+// values that look like identifiers, such as social security numbers, from
+// what it keeps and, through LogHook, from every log line.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"healthcare-api/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Value replaces values that may hold PHI
+const Value = "[REDACTED]"
+
+// BodyModeStrict records request bodies by size only
+const BodyModeStrict = "strict"
+
+// keptFields are the body fields and query parameters whose values are kept by
+// default. They describe what was requested without identifying a patient.
+var keptFields = []string{
+	"resourceType", "id", "status", "system", "code", "use", "unit", "type",
+	"category", "priority", "action", "active", "limit", "offset", "_count",
+	"_sort", "sort", "success", "dry_run", "tenant",
+}
+
+// patterns are the scrub patterns by name, as listed in LOG_SCRUB_PATTERNS
+var patterns = map[string]*regexp.Regexp{
+	"ssn":   regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	"email": regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	"phone": regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)\s?|\b\d{3}[\s.-])\d{3}[\s.-]\d{4}\b`),
+}
+
+// Policy decides what of a request is logged, safe for concurrent use
+type Policy struct {
+	strict bool
+	keep   map[string]bool
+	scrub  []*regexp.Regexp
+}
+
+// New returns the policy set by cfg
+func New(cfg config.RequestLogConfig) *Policy {
+	keep := make(map[string]bool, len(keptFields)+len(cfg.KeepFields))
+	for _, field := range keptFields {
+		keep[field] = true
+	}
+	for _, field := range cfg.KeepFields {
+		keep[field] = true
+	}
+	for _, field := range cfg.RedactFields {
+		delete(keep, field)
+	}
+
+	var scrub []*regexp.Regexp
+	for _, name := range cfg.ScrubPatterns {
+		if pattern, ok := patterns[name]; ok {
+			scrub = append(scrub, pattern)
+		}
+	}
+	return &Policy{strict: cfg.BodyMode == BodyModeStrict, keep: keep, scrub: scrub}
+}
+
+// Strict reports whether request bodies are recorded by size only, so they
+// need not be read
+func (p *Policy) Strict() bool {
+	return p.strict
+}
+
+// Scrub replaces the parts of s that match a scrub pattern
+func (p *Policy) Scrub(s string) string {
+	for _, pattern := range p.scrub {
+		s = pattern.ReplaceAllString(s, Value)
+	}
+	return s
+}
+
+// Query returns the query string with the values of parameters that are not
+// kept redacted, e.g. name=Smith becomes name=[REDACTED]
+func (p *Policy) Query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Value
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		for _, value := range values[key] {
+			if !p.keep[key] {
+				value = Value
+			} else {
+				value = url.QueryEscape(p.Scrub(value))
+			}
+			parts = append(parts, url.QueryEscape(key)+"="+value)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// Body returns a JSON body with the values of fields that are not kept
+// redacted, keeping its structure. Bodies that are not JSON, and every body in
+// strict mode, are replaced by their size.
+func (p *Policy) Body(body []byte) string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return ""
+	}
+	if p.strict {
+		return Size(int64(len(body)))
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return "[" + strconv.Itoa(len(body)) + " bytes, not JSON]"
+	}
+	redacted, err := json.Marshal(p.value("", document))
+	if err != nil {
+		return Value
+	}
+	return string(redacted)
+}
+
+// Size describes a body that is recorded by its size only
+func Size(size int64) string {
+	return "[" + strconv.FormatInt(size, 10) + " bytes]"
+}
+
+// value redacts the scalar values in value, which is the value of field
+func (p *Policy) value(field string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = p.value(key, child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = p.value(field, child)
+		}
+		return v
+	case nil:
+		return nil
+	case string:
+		if p.keep[field] {
+			return p.Scrub(v)
+		}
+		return Value
+	default:
+		if p.keep[field] {
+			return v
+		}
+		return Value
+	}
+}
+
+// LogHook scrubs the message and the string and error fields of every log
+// entry with the patterns of Policy, catching PHI that made it into an error
+// message
+type LogHook struct {
+	Policy *Policy
+}
+
+// Levels reports that the hook applies to every level
+func (LogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire scrubs the entry
+func (h LogHook) Fire(entry *logrus.Entry) error {
+	if len(h.Policy.scrub) == 0 {
+		return nil
+	}
+	entry.Message = h.Policy.Scrub(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = h.Policy.Scrub(v)
+		case error:
+			if scrubbed := h.Policy.Scrub(v.Error()); scrubbed != v.Error() {
+				entry.Data[key] = errors.New(scrubbed)
+			}
+		}
+	}
+	return nil
+}
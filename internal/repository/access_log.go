@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AccessLog is an entry of the HTTP access log: one API request, whether or not
+// it changed data. Unlike AuditLog it is not tied to a resource.
+type AccessLog struct {
+	TenantID   *string
+	RequestID  *string
+	UserID     *string
+	IPAddress  *string
+	UserAgent  *string
+	Method     string
+	Path       string
+	Query      *string
+	StatusCode int
+	Duration   time.Duration
+	// RequestBody is the request body with PHI redacted
+	RequestBody *string
+	Timestamp   time.Time
+}
+
+// LogAccess records an access log entry. Requests rejected before a tenant was
+// resolved, e.g. for a missing token, are recorded without one.
+func (r *BaseRepository) LogAccess(ctx context.Context, log *AccessLog) error {
+	query := `
+		INSERT INTO access_logs (tenant_id, request_id, user_id, ip_address, user_agent, method, path, query, status_code, duration_ms, request_body, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		log.TenantID,
+		log.RequestID,
+		log.UserID,
+		log.IPAddress,
+		log.UserAgent,
+		log.Method,
+		log.Path,
+		log.Query,
+		log.StatusCode,
+		log.Duration.Milliseconds(),
+		log.RequestBody,
+		log.Timestamp,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create access log: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// AttachmentRepository records the attachments stored in the object store
+type AttachmentRepository struct {
+	*BaseRepository
+}
+
+func NewAttachmentRepository(db *database.DB) *AttachmentRepository {
+	return &AttachmentRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+func (r *AttachmentRepository) Create(ctx context.Context, attachment *models.StoredAttachment) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO attachments (id, tenant_id, content_type, size, hash, title, object_key, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at
+	`, attachment.ID, tenantID, attachment.ContentType, attachment.Size, attachment.Hash,
+		attachment.Title, attachment.ObjectKey, attachment.CreatedBy).Scan(&attachment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+	return nil
+}
+
+func (r *AttachmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.StoredAttachment, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := &models.StoredAttachment{}
+	err = r.db.QueryRowContext(ctx, `
+		SELECT id, content_type, size, hash, title, object_key, created_by, created_at
+		FROM attachments WHERE id = $1 AND tenant_id = $2
+	`, id, tenantID).Scan(
+		&attachment.ID,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.Hash,
+		&attachment.Title,
+		&attachment.ObjectKey,
+		&attachment.CreatedBy,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return attachment, nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/database"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// AuditRepository queries the audit trail written by LogAudit
+type AuditRepository struct {
+	*BaseRepository
+}
+
+func NewAuditRepository(db *database.DB) *AuditRepository {
+	return &AuditRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// AuditSearchParams represents supported audit log filters. From is inclusive, To exclusive.
+type AuditSearchParams struct {
+	ResourceType string
+	ResourceID   *uuid.UUID
+	UserID       string
+	Actions      []string
+	From         *time.Time
+	To           *time.Time
+}
+
+// whereClause builds the SQL filter for the search parameters
+func (p AuditSearchParams) whereClause(tenantID string) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{tenantID}
+
+	if p.ResourceType != "" {
+		args = append(args, p.ResourceType)
+		conditions = append(conditions, fmt.Sprintf("resource_type = $%d", len(args)))
+	}
+	if p.ResourceID != nil {
+		args = append(args, *p.ResourceID)
+		conditions = append(conditions, fmt.Sprintf("resource_id = $%d", len(args)))
+	}
+	if p.UserID != "" {
+		args = append(args, p.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if len(p.Actions) > 0 {
+		args = append(args, pq.Array(p.Actions))
+		conditions = append(conditions, fmt.Sprintf("action = ANY($%d)", len(args)))
+	}
+	// Time bounds also let PostgreSQL prune monthly partitions
+	if p.From != nil {
+		args = append(args, *p.From)
+		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d", len(args)))
+	}
+	if p.To != nil {
+		args = append(args, *p.To)
+		conditions = append(conditions, fmt.Sprintf("timestamp < $%d", len(args)))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// List returns matching audit log entries, newest first. Old and new values are
+// not loaded: they hold resource contents and are not part of the audit event view.
+func (r *AuditRepository) List(ctx context.Context, search AuditSearchParams, params PaginationParams) ([]*AuditLog, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	where, args := search.whereClause(tenantID)
+
+	// Get total count
+	countQuery := `SELECT COUNT(*) FROM audit_logs ` + where
+	var total int64
+	err = r.db.Reader(ctx).QueryRowContext(ctx, countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get audit log count: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, resource_type, resource_id, action, user_id, user_agent,
+			   host(ip_address), request_id, timestamp
+		FROM audit_logs
+		%s
+		ORDER BY timestamp DESC, id
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*AuditLog
+	for rows.Next() {
+		log := &AuditLog{}
+		err := rows.Scan(
+			&log.ID,
+			&log.ResourceType,
+			&log.ResourceID,
+			&log.Action,
+			&log.UserID,
+			&log.UserAgent,
+			&log.IPAddress,
+			&log.RequestID,
+			&log.Timestamp,
+		)
+		if err != nil {
+			return nil, PaginationResult{}, fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		logs = append(logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate audit logs: %w", err)
+	}
+
+	return logs, GetPaginationResult(total, params), nil
+}
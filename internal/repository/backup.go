@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+)
+
+// restoreBatchSize bounds the rows buffered before each COPY during a restore
+const restoreBatchSize = 1000
+
+// Restored rows carry their deletion state in addition to the bulk-load columns
+var (
+	patientRestoreColumns     = append(append([]string{}, patientCopyColumns...), "deleted_at")
+	observationRestoreColumns = append(append([]string{}, observationCopyColumns...), "deleted_at")
+)
+
+// BackupRepository reads consistent snapshots of a tenant's resources and rebuilds tenants from them
+type BackupRepository struct {
+	*BaseRepository
+}
+
+func NewBackupRepository(db *database.DB) *BackupRepository {
+	return &BackupRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Snapshot is a point-in-time, read-only view of one tenant's resources
+type Snapshot struct {
+	tx       *sql.Tx
+	tenantID string
+
+	// TakenAt is the database time the snapshot reflects
+	TakenAt time.Time
+}
+
+// Snapshot runs fn in a read-only REPEATABLE READ transaction on the primary,
+// so every read made through the Snapshot sees the same committed state.
+func (r *BackupRepository) Snapshot(ctx context.Context, fn func(*Snapshot) error) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	return r.db.RunInTx(ctx, opts, func(tx *sql.Tx) error {
+		snapshot := &Snapshot{tx: tx, tenantID: tenantID}
+		if err := tx.QueryRowContext(ctx, `SELECT NOW()`).Scan(&snapshot.TakenAt); err != nil {
+			return fmt.Errorf("failed to read snapshot time: %w", err)
+		}
+		return fn(snapshot)
+	})
+}
+
+// EachPatient streams every patient, including soft-deleted ones, in creation order
+func (s *Snapshot) EachPatient(ctx context.Context, fn func(*models.Patient) error) error {
+	query := `
+		SELECT id, identifier, active, name, telecom, gender, birth_date,
+			   deceased_boolean, deceased_date_time, address, marital_status,
+			   multiple_birth_boolean, multiple_birth_integer, photo, contact,
+			   communication, general_practitioner, managing_organization, link,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version, deleted_at
+		FROM patients
+		WHERE tenant_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := s.tx.QueryContext(ctx, query, s.tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to export patients: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var deletedAt *time.Time
+		patient, err := scanPatient(rows, &deletedAt)
+		if err != nil {
+			return err
+		}
+		patient.DeletedAt = deletedAt
+
+		if err := fn(patient); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate patients: %w", err)
+	}
+
+	return nil
+}
+
+// EachObservation streams every observation, including soft-deleted ones, in creation order
+func (s *Snapshot) EachObservation(ctx context.Context, fn func(*models.Observation) error) error {
+	query := `
+		SELECT id, identifier, based_on, part_of, status, category, code, subject,
+			   focus, encounter, effective_date_time, effective_period, effective_timing,
+			   effective_instant, issued, performer, value_quantity, value_codeable_concept,
+			   value_string, value_boolean, value_integer, value_range, value_ratio,
+			   value_sampled_data, value_time, value_date_time, value_period,
+			   data_absent_reason, interpretation, note, body_site, method, specimen,
+			   device, reference_range, has_member, derived_from, component,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version, deleted_at
+		FROM observations
+		WHERE tenant_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := s.tx.QueryContext(ctx, query, s.tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to export observations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var deletedAt *time.Time
+		observation, err := scanObservation(rows, &deletedAt)
+		if err != nil {
+			return err
+		}
+		observation.DeletedAt = deletedAt
+
+		if err := fn(observation); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate observations: %w", err)
+	}
+
+	return nil
+}
+
+// Restorer loads resources into a tenant being rebuilt from a snapshot
+type Restorer struct {
+	tx           *sql.Tx
+	tenantID     string
+	patients     [][]interface{}
+	observations [][]interface{}
+
+	Patients     int
+	Observations int
+}
+
+// Restore replaces all of the tenant's patients and observations with the
+// resources load passes to the Restorer. Everything happens in one transaction,
+// so a failed or aborted load (for example a checksum mismatch) leaves the tenant
+// untouched. Resources keep their ids, versions, timestamps and deletion state.
+func (r *BackupRepository) Restore(ctx context.Context, load func(*Restorer) error) (*Restorer, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var restorer *Restorer
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		restorer = &Restorer{tx: tx, tenantID: tenantID}
+
+		for _, table := range []string{"observations", "patients"} {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE tenant_id = $1`, tenantID); err != nil {
+				return fmt.Errorf("failed to clear %s: %w", table, err)
+			}
+		}
+
+		if err := load(restorer); err != nil {
+			return err
+		}
+		return restorer.flush(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restorer, nil
+}
+
+// AddPatient queues a patient for loading
+func (rs *Restorer) AddPatient(ctx context.Context, patient *models.Patient) error {
+	rs.patients = append(rs.patients, append(patientCopyRow(patient, rs.tenantID), patient.DeletedAt))
+	rs.Patients++
+	if len(rs.patients) >= restoreBatchSize {
+		return rs.flush(ctx)
+	}
+	return nil
+}
+
+// AddObservation queues an observation for loading
+func (rs *Restorer) AddObservation(ctx context.Context, observation *models.Observation) error {
+	rs.observations = append(rs.observations, append(observationCopyRow(observation, rs.tenantID), observation.DeletedAt))
+	rs.Observations++
+	if len(rs.observations) >= restoreBatchSize {
+		return rs.flush(ctx)
+	}
+	return nil
+}
+
+// flush copies the queued rows into their tables
+func (rs *Restorer) flush(ctx context.Context) error {
+	if len(rs.patients) > 0 {
+		if err := copyRows(ctx, rs.tx, "patients", patientRestoreColumns, rs.patients); err != nil {
+			return err
+		}
+		rs.patients = rs.patients[:0]
+	}
+	if len(rs.observations) > 0 {
+		if err := copyRows(ctx, rs.tx, "observations", observationRestoreColumns, rs.observations); err != nil {
+			return err
+		}
+		rs.observations = rs.observations[:0]
+	}
+	return nil
+}
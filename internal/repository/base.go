@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/requestctx"
 
 	"github.com/google/uuid"
 )
@@ -21,6 +23,16 @@ func NewBaseRepository(db *database.DB) *BaseRepository {
 	return &BaseRepository{db: db}
 }
 
+// tenantID returns the tenant that scopes every resource query made with ctx.
+// Queries without a tenant are rejected rather than run unscoped.
+func (r *BaseRepository) tenantID(ctx context.Context) (string, error) {
+	tenantID := requestctx.TenantID(ctx)
+	if tenantID == "" {
+		return "", models.ErrTenantRequired
+	}
+	return tenantID, nil
+}
+
 // AuditLog represents an audit log entry
 type AuditLog struct {
 	ID           uuid.UUID       `json:"id"`
@@ -36,14 +48,28 @@ type AuditLog struct {
 	Timestamp    time.Time       `json:"timestamp"`
 }
 
-// LogAudit creates an audit log entry
+// LogAudit creates an audit log entry. Entries made during a request are tagged
+// with its request ID.
 func (r *BaseRepository) LogAudit(ctx context.Context, log *AuditLog) error {
+	return r.logAudit(ctx, r.db, log)
+}
+
+// logAudit creates an audit log entry through q, such as a transaction
+func (r *BaseRepository) logAudit(ctx context.Context, q database.Querier, log *AuditLog) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+	if requestID := requestctx.RequestID(ctx); log.RequestID == nil && requestID != "" {
+		log.RequestID = &requestID
+	}
+
 	query := `
-		INSERT INTO audit_logs (resource_type, resource_id, action, user_id, user_agent, ip_address, request_id, old_values, new_values)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO audit_logs (resource_type, resource_id, action, user_id, user_agent, ip_address, request_id, old_values, new_values, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err = q.ExecContext(ctx, query,
 		log.ResourceType,
 		log.ResourceID,
 		log.Action,
@@ -53,6 +79,7 @@ func (r *BaseRepository) LogAudit(ctx context.Context, log *AuditLog) error {
 		log.RequestID,
 		log.OldValues,
 		log.NewValues,
+		tenantID,
 	)
 
 	if err != nil {
@@ -62,6 +89,12 @@ func (r *BaseRepository) LogAudit(ctx context.Context, log *AuditLog) error {
 	return nil
 }
 
+// logError reports an error that does not fail the operation. Repositories have
+// no logger, so it is printed, tagged with the request ID for correlation.
+func logError(ctx context.Context, message string, err error) {
+	fmt.Printf("%s: %v (request_id=%s)\n", message, err, requestctx.RequestID(ctx))
+}
+
 // PaginationParams represents pagination parameters
 type PaginationParams struct {
 	Limit  int `json:"limit"`
@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var patientCopyColumns = []string{
+	"id", "identifier", "active", "name", "telecom", "gender", "birth_date",
+	"deceased_boolean", "deceased_date_time", "address", "marital_status",
+	"multiple_birth_boolean", "multiple_birth_integer", "photo", "contact",
+	"communication", "general_practitioner", "managing_organization", "link",
+	"meta", "implicit_rules", "language", "text", "contained", "extension", "modifier_extension",
+	"family_name", "identifier_values", "search_text",
+	"created_at", "updated_at", "version", "tenant_id",
+}
+
+var observationCopyColumns = []string{
+	"id", "identifier", "based_on", "part_of", "status", "category", "code", "subject",
+	"focus", "encounter", "effective_date_time", "effective_period", "effective_timing",
+	"effective_instant", "issued", "performer", "value_quantity", "value_codeable_concept",
+	"value_string", "value_boolean", "value_integer", "value_range", "value_ratio",
+	"value_sampled_data", "value_time", "value_date_time", "value_period",
+	"data_absent_reason", "interpretation", "note", "body_site", "method", "specimen",
+	"device", "reference_range", "has_member", "derived_from", "component",
+	"meta", "implicit_rules", "language", "text", "contained", "extension", "modifier_extension",
+	"code_values", "subject_reference", "effective_date",
+	"created_at", "updated_at", "version", "tenant_id",
+}
+
+var historyCopyColumns = []string{
+	"resource_type", "resource_id", "version", "action", "payload", "actor", "tenant_id",
+}
+
+// BulkCreate inserts patients using COPY FROM instead of row-by-row INSERTs.
+// All rows are loaded in a single transaction along with their history entries.
+func (r *PatientRepository) BulkCreate(ctx context.Context, patients []*models.Patient) (int, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := make([][]interface{}, len(patients))
+	for i, patient := range patients {
+		stampNewResource(&patient.Resource)
+		rows[i] = patientCopyRow(patient, tenantID)
+	}
+
+	history := make([][]interface{}, len(patients))
+	for i, patient := range patients {
+		history[i] = historyRow(ctx, tenantID, "Patient", patient.Resource, patient)
+	}
+
+	if err := r.copyIn(ctx, "patients", patientCopyColumns, rows, history); err != nil {
+		if conflict := r.identifierConflict(ctx, err, patients...); conflict != nil {
+			return 0, conflict
+		}
+		return 0, fmt.Errorf("failed to bulk create patients: %w", err)
+	}
+
+	return len(patients), nil
+}
+
+// BulkCreate inserts observations using COPY FROM instead of row-by-row INSERTs.
+// All rows are loaded in a single transaction along with their history entries.
+func (r *ObservationRepository) BulkCreate(ctx context.Context, observations []*models.Observation) (int, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := make([][]interface{}, len(observations))
+	for i, observation := range observations {
+		stampNewResource(&observation.Resource)
+		rows[i] = observationCopyRow(observation, tenantID)
+	}
+
+	history := make([][]interface{}, len(observations))
+	for i, observation := range observations {
+		history[i] = historyRow(ctx, tenantID, "Observation", observation.Resource, observation)
+	}
+
+	if err := r.copyIn(ctx, "observations", observationCopyColumns, rows, history); err != nil {
+		return 0, fmt.Errorf("failed to bulk create observations: %w", err)
+	}
+
+	return len(observations), nil
+}
+
+// BulkCreateNew inserts the observations whose ids are not stored yet and
+// returns how many it inserted. Rows are copied into a staging table with COPY
+// FROM and moved across with ON CONFLICT DO NOTHING, so callers deriving ids
+// from their input can load batches overlapping ones already stored. History
+// entries are recorded for the inserted observations only.
+func (r *ObservationRepository) BulkCreateNew(ctx context.Context, observations []*models.Observation) (int, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := make([][]interface{}, len(observations))
+	byID := make(map[uuid.UUID]*models.Observation, len(observations))
+	for i, observation := range observations {
+		stampNewResource(&observation.Resource)
+		rows[i] = observationCopyRow(observation, tenantID)
+		byID[observation.ID] = observation
+	}
+
+	columns := strings.Join(observationCopyColumns, ", ")
+	var created int
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE observations_staging (LIKE observations INCLUDING DEFAULTS) ON COMMIT DROP`); err != nil {
+			return fmt.Errorf("failed to create staging table: %w", err)
+		}
+		if err := copyRows(ctx, tx, "observations_staging", observationCopyColumns, rows); err != nil {
+			return err
+		}
+
+		inserted, err := tx.QueryContext(ctx, `
+			INSERT INTO observations (`+columns+`)
+			SELECT `+columns+` FROM observations_staging
+			ON CONFLICT (id) DO NOTHING
+			RETURNING id
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to insert staged observations: %w", err)
+		}
+		defer inserted.Close()
+
+		var history [][]interface{}
+		for inserted.Next() {
+			var id uuid.UUID
+			if err := inserted.Scan(&id); err != nil {
+				return fmt.Errorf("failed to scan inserted observation id: %w", err)
+			}
+			observation := byID[id]
+			history = append(history, historyRow(ctx, tenantID, "Observation", observation.Resource, observation))
+		}
+		if err := inserted.Err(); err != nil {
+			return fmt.Errorf("failed to iterate inserted observations: %w", err)
+		}
+		inserted.Close()
+
+		created = len(history)
+		return copyRows(ctx, tx, "resource_history", historyCopyColumns, history)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to bulk create observations: %w", err)
+	}
+
+	return created, nil
+}
+
+// patientCopyRow returns the values for patientCopyColumns
+func patientCopyRow(patient *models.Patient, tenantID string) []interface{} {
+	cols := ExtractPatientSearchColumns(patient)
+	return []interface{}{
+		patient.ID,
+		jsonText(patient.Identifier),
+		patient.Active,
+		jsonText(patient.Name),
+		jsonText(patient.Telecom),
+		patient.Gender,
+		patient.BirthDate,
+		patient.DeceasedBoolean,
+		patient.DeceasedDateTime,
+		jsonText(patient.Address),
+		jsonText(patient.MaritalStatus),
+		patient.MultipleBirthBoolean,
+		patient.MultipleBirthInteger,
+		jsonText(patient.Photo),
+		jsonText(patient.Contact),
+		jsonText(patient.Communication),
+		jsonText(patient.GeneralPractitioner),
+		jsonText(patient.ManagingOrganization),
+		jsonText(patient.Link),
+		jsonText(patient.Meta),
+		patient.ImplicitRules,
+		patient.Language,
+		jsonText(patient.Text),
+		jsonText(patient.Contained),
+		jsonText(patient.Extension),
+		jsonText(patient.ModifierExtension),
+		cols.FamilyName,
+		pq.Array(cols.IdentifierValues),
+		cols.SearchText,
+		patient.CreatedAt,
+		patient.UpdatedAt,
+		patient.Version,
+		tenantID,
+	}
+}
+
+// observationCopyRow returns the values for observationCopyColumns
+func observationCopyRow(observation *models.Observation, tenantID string) []interface{} {
+	cols := ExtractObservationSearchColumns(observation)
+	return []interface{}{
+		observation.ID,
+		jsonText(observation.Identifier),
+		jsonText(observation.BasedOn),
+		jsonText(observation.PartOf),
+		observation.Status,
+		jsonText(observation.Category),
+		jsonText(observation.Code),
+		jsonText(observation.Subject),
+		jsonText(observation.Focus),
+		jsonText(observation.Encounter),
+		observation.EffectiveDateTime,
+		jsonText(observation.EffectivePeriod),
+		jsonText(observation.EffectiveTiming),
+		observation.EffectiveInstant,
+		observation.Issued,
+		jsonText(observation.Performer),
+		jsonText(observation.ValueQuantity),
+		jsonText(observation.ValueCodeableConcept),
+		observation.ValueString,
+		observation.ValueBoolean,
+		observation.ValueInteger,
+		jsonText(observation.ValueRange),
+		jsonText(observation.ValueRatio),
+		jsonText(observation.ValueSampledData),
+		observation.ValueTime,
+		observation.ValueDateTime,
+		jsonText(observation.ValuePeriod),
+		jsonText(observation.DataAbsentReason),
+		jsonText(observation.Interpretation),
+		jsonText(observation.Note),
+		jsonText(observation.BodySite),
+		jsonText(observation.Method),
+		jsonText(observation.Specimen),
+		jsonText(observation.Device),
+		jsonText(observation.ReferenceRange),
+		jsonText(observation.HasMember),
+		jsonText(observation.DerivedFrom),
+		jsonText(observation.Component),
+		jsonText(observation.Meta),
+		observation.ImplicitRules,
+		observation.Language,
+		jsonText(observation.Text),
+		jsonText(observation.Contained),
+		jsonText(observation.Extension),
+		jsonText(observation.ModifierExtension),
+		pq.Array(cols.CodeValues),
+		cols.SubjectReference,
+		cols.EffectiveDate,
+		observation.CreatedAt,
+		observation.UpdatedAt,
+		observation.Version,
+		tenantID,
+	}
+}
+
+// copyIn streams rows into table with COPY FROM, followed by their history entries, in one transaction
+func (r *BaseRepository) copyIn(ctx context.Context, table string, columns []string, rows, history [][]interface{}) error {
+	return r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if err := copyRows(ctx, tx, table, columns, rows); err != nil {
+			return err
+		}
+		return copyRows(ctx, tx, "resource_history", historyCopyColumns, history)
+	})
+}
+
+// copyRows executes a single COPY FROM STDIN statement within tx
+func copyRows(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]interface{}) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return fmt.Errorf("failed to prepare copy into %s: %w", table, err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("failed to copy row into %s: %w", table, err)
+		}
+	}
+
+	// Flush buffered rows
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to flush copy into %s: %w", table, err)
+	}
+
+	return nil
+}
+
+// stampNewResource fills in bookkeeping fields for resources that are created outside the service layer
+func stampNewResource(resource *models.Resource) {
+	now := time.Now().UTC()
+	if resource.CreatedAt.IsZero() {
+		resource.CreatedAt = now
+	}
+	if resource.UpdatedAt.IsZero() {
+		resource.UpdatedAt = now
+	}
+	if resource.Version == 0 {
+		resource.Version = 1
+	}
+}
+
+// historyRow builds the resource_history COPY row for a newly created resource
+func historyRow(ctx context.Context, tenantID, resourceType string, resource models.Resource, payload interface{}) []interface{} {
+	var actor *string
+	if userID := requestctx.UserID(ctx); userID != "" {
+		actor = &userID
+	}
+	return []interface{}{
+		resourceType,
+		resource.ID,
+		resource.Version,
+		"CREATE",
+		string(mustMarshalJSON(payload)),
+		actor,
+		tenantID,
+	}
+}
+
+// jsonText encodes v as JSON text; COPY sends []byte as bytea, so JSONB columns need a string
+func jsonText(v interface{}) string {
+	return string(toJSON(v))
+}
@@ -0,0 +1,166 @@
+// Package cached wraps the resource stores with a read-through cache of
+// resources read by id. Writes made through the wrappers update or drop the
+// cached copy; writes made around them, such as a backup restore, flush the
+// tenant's cache themselves.
+package cached
+
+import (
+	"context"
+
+	"healthcare-api/internal/cache"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+)
+
+// PatientRepository is a repository.PatientStore reading patients by id through the cache
+type PatientRepository struct {
+	repository.PatientStore
+	cache *cache.ResourceCache
+}
+
+var _ repository.PatientStore = (*PatientRepository)(nil)
+
+func NewPatientRepository(store repository.PatientStore, cache *cache.ResourceCache) *PatientRepository {
+	return &PatientRepository{PatientStore: store, cache: cache}
+}
+
+func (r *PatientRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Patient, error) {
+	return getByID(ctx, r.cache, "Patient", id, r.PatientStore.GetByID, patientVersion)
+}
+
+func (r *PatientRepository) Update(ctx context.Context, patient *models.Patient) error {
+	err := r.PatientStore.Update(ctx, patient)
+	stored(ctx, r.cache, "Patient", patient.ID, patient, patientVersion, err)
+	return err
+}
+
+func (r *PatientRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	err := r.PatientStore.Delete(ctx, id)
+	deleted(ctx, r.cache, "Patient", id, err)
+	return err
+}
+
+func (r *PatientRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Patient, error) {
+	patient, err := r.PatientStore.Restore(ctx, id)
+	stored(ctx, r.cache, "Patient", id, patient, patientVersion, err)
+	return patient, err
+}
+
+func patientVersion(patient *models.Patient) int { return patient.Version }
+
+// ObservationRepository is a repository.ObservationStore reading observations by id through the cache
+type ObservationRepository struct {
+	repository.ObservationStore
+	cache *cache.ResourceCache
+}
+
+var _ repository.ObservationStore = (*ObservationRepository)(nil)
+
+func NewObservationRepository(store repository.ObservationStore, cache *cache.ResourceCache) *ObservationRepository {
+	return &ObservationRepository{ObservationStore: store, cache: cache}
+}
+
+func (r *ObservationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Observation, error) {
+	return getByID(ctx, r.cache, "Observation", id, r.ObservationStore.GetByID, observationVersion)
+}
+
+func (r *ObservationRepository) Update(ctx context.Context, observation *models.Observation) error {
+	err := r.ObservationStore.Update(ctx, observation)
+	stored(ctx, r.cache, "Observation", observation.ID, observation, observationVersion, err)
+	return err
+}
+
+func (r *ObservationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	err := r.ObservationStore.Delete(ctx, id)
+	deleted(ctx, r.cache, "Observation", id, err)
+	return err
+}
+
+func (r *ObservationRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Observation, error) {
+	observation, err := r.ObservationStore.Restore(ctx, id)
+	stored(ctx, r.cache, "Observation", id, observation, observationVersion, err)
+	return observation, err
+}
+
+func observationVersion(observation *models.Observation) int { return observation.Version }
+
+// DiagnosticReportRepository is a repository.DiagnosticReportStore reading
+// diagnostic reports by id through the cache. Reports are not changed once
+// created, so there is nothing to invalidate.
+type DiagnosticReportRepository struct {
+	repository.DiagnosticReportStore
+	cache *cache.ResourceCache
+}
+
+var _ repository.DiagnosticReportStore = (*DiagnosticReportRepository)(nil)
+
+func NewDiagnosticReportRepository(store repository.DiagnosticReportStore, cache *cache.ResourceCache) *DiagnosticReportRepository {
+	return &DiagnosticReportRepository{DiagnosticReportStore: store, cache: cache}
+}
+
+func (r *DiagnosticReportRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.DiagnosticReport, error) {
+	return getByID(ctx, r.cache, "DiagnosticReport", id, r.DiagnosticReportStore.GetByID, diagnosticReportVersion)
+}
+
+func (r *DiagnosticReportRepository) Update(ctx context.Context, report *models.DiagnosticReport) error {
+	err := r.DiagnosticReportStore.Update(ctx, report)
+	stored(ctx, r.cache, "DiagnosticReport", report.ID, report, diagnosticReportVersion, err)
+	return err
+}
+
+func diagnosticReportVersion(report *models.DiagnosticReport) int { return report.Version }
+
+// getByID reads a resource from the cache, or from the store on a miss,
+// caching what the store returns. Errors, such as a deleted resource, are not
+// cached.
+func getByID[T any](ctx context.Context, c *cache.ResourceCache, resourceType string, id uuid.UUID, get func(context.Context, uuid.UUID) (*T, error), version func(*T) int) (*T, error) {
+	tenantID := requestctx.TenantID(ctx)
+	if tenantID == "" {
+		return get(ctx, id)
+	}
+
+	var cached T
+	hit, fill := c.Get(ctx, tenantID, resourceType, id, &cached)
+	if hit {
+		return &cached, nil
+	}
+
+	resource, err := get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if fill {
+		c.Fill(ctx, tenantID, resourceType, id, version(resource), resource)
+	}
+	return resource, nil
+}
+
+// stored caches a resource after a write, or drops its cached copy when the
+// write failed, e.g. on a version conflict with a copy newer than the cached one
+func stored[T any](ctx context.Context, c *cache.ResourceCache, resourceType string, id uuid.UUID, resource *T, version func(*T) int, err error) {
+	tenantID := requestctx.TenantID(ctx)
+	if tenantID == "" {
+		return
+	}
+	if err != nil {
+		c.Evict(ctx, tenantID, resourceType, id)
+		return
+	}
+	c.Store(ctx, tenantID, resourceType, id, version(resource), resource)
+}
+
+// deleted marks a resource deleted in the cache once the delete succeeded
+func deleted(ctx context.Context, c *cache.ResourceCache, resourceType string, id uuid.UUID, err error) {
+	tenantID := requestctx.TenantID(ctx)
+	if tenantID == "" {
+		return
+	}
+	if err != nil {
+		c.Evict(ctx, tenantID, resourceType, id)
+		return
+	}
+	c.Tombstone(ctx, tenantID, resourceType, id)
+}
@@ -0,0 +1,393 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// CohortRepository stores cohort definitions and evaluates them
+type CohortRepository struct {
+	*BaseRepository
+}
+
+func NewCohortRepository(db *database.DB) *CohortRepository {
+	return &CohortRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+const cohortColumns = `id, name, description, criteria, created_by, created_at, updated_at`
+
+func (r *CohortRepository) Create(ctx context.Context, cohort *models.Cohort) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO cohorts (id, tenant_id, name, description, criteria, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, updated_at
+	`, cohort.ID, tenantID, cohort.Name, cohort.Description, toJSON(cohort.Criteria),
+		cohort.CreatedBy).Scan(&cohort.CreatedAt, &cohort.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create cohort: %w", err)
+	}
+	return nil
+}
+
+// Get returns a cohort of the tenant, or models.ErrCohortNotFound
+func (r *CohortRepository) Get(ctx context.Context, id uuid.UUID) (*models.Cohort, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + cohortColumns + ` FROM cohorts WHERE id = $1 AND tenant_id = $2`
+	cohort, err := scanCohort(r.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrCohortNotFound
+		}
+		return nil, err
+	}
+	return cohort, nil
+}
+
+// List returns the tenant's cohorts by name
+func (r *CohortRepository) List(ctx context.Context, params PaginationParams) ([]*models.Cohort, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	var total int64
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM cohorts WHERE tenant_id = $1`, tenantID).Scan(&total)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get cohort count: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+cohortColumns+`
+		FROM cohorts
+		WHERE tenant_id = $1
+		ORDER BY name, id
+		LIMIT $2 OFFSET $3
+	`, tenantID, params.Limit, params.Offset)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list cohorts: %w", err)
+	}
+	defer rows.Close()
+
+	var cohorts []*models.Cohort
+	for rows.Next() {
+		cohort, err := scanCohort(rows)
+		if err != nil {
+			return nil, PaginationResult{}, err
+		}
+		cohorts = append(cohorts, cohort)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate cohorts: %w", err)
+	}
+
+	return cohorts, GetPaginationResult(total, params), nil
+}
+
+// Update replaces the name, description and criteria of a cohort
+func (r *CohortRepository) Update(ctx context.Context, cohort *models.Cohort) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		UPDATE cohorts SET name = $3, description = $4, criteria = $5
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING created_by, created_at, updated_at
+	`, cohort.ID, tenantID, cohort.Name, cohort.Description,
+		toJSON(cohort.Criteria)).Scan(&cohort.CreatedBy, &cohort.CreatedAt, &cohort.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrCohortNotFound
+		}
+		return fmt.Errorf("failed to update cohort: %w", err)
+	}
+	return nil
+}
+
+func (r *CohortRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM cohorts WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete cohort: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.ErrCohortNotFound
+	}
+	return nil
+}
+
+func scanCohort(scanner rowScanner) (*models.Cohort, error) {
+	cohort := &models.Cohort{}
+	err := scanner.Scan(
+		&cohort.ID,
+		&cohort.Name,
+		&cohort.Description,
+		jsonb(&cohort.Criteria),
+		&cohort.CreatedBy,
+		&cohort.CreatedAt,
+		&cohort.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan cohort: %w", err)
+	}
+	return cohort, nil
+}
+
+// CohortQuery is cohort criteria resolved for evaluation: ages as birth date
+// bounds, periods as start times and thresholds in the units results have
+type CohortQuery struct {
+	Gender *string
+	// BornOnOrBefore and BornAfter bound the birth date; patients without one
+	// do not match either
+	BornOnOrBefore *time.Time
+	BornAfter      *time.Time
+	Active         *bool
+	Observations   []CohortObservationQuery
+}
+
+// CohortObservationQuery matches patients having, or with Exclude lacking, a
+// live result of Code
+type CohortObservationQuery struct {
+	// Code is a code, or system|code
+	Code  string
+	Since *time.Time
+	// ValueCoding, when set, is a coding the result's coded value must have
+	ValueCoding *models.Coding
+	// Comparator, when set, compares exact quantity values, of any unit to
+	// Value, or, with Thresholds set, of each unit to its threshold. With
+	// Thresholds empty but not nil, no result matches.
+	Comparator string
+	Value      float64
+	Thresholds map[string]float64
+	Exclude    bool
+}
+
+// cohortOperators maps cohort comparators to SQL operators
+var cohortOperators = map[string]string{
+	"gt": ">",
+	"ge": ">=",
+	"lt": "<",
+	"le": "<=",
+	"eq": "=",
+	"ne": "<>",
+}
+
+// whereClause builds the filter of patients p, with one EXISTS or NOT EXISTS
+// semi-join on observations per observation criterion
+func (q CohortQuery) whereClause(tenantID string) (string, []interface{}, error) {
+	conditions := []string{"p.tenant_id = $1", "p.deleted_at IS NULL"}
+	args := []interface{}{tenantID}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q.Gender != nil {
+		conditions = append(conditions, "p.gender = "+arg(*q.Gender))
+	}
+	if q.BornOnOrBefore != nil {
+		conditions = append(conditions, "p.birth_date <= "+arg(*q.BornOnOrBefore)+"::date")
+	}
+	if q.BornAfter != nil {
+		conditions = append(conditions, "p.birth_date > "+arg(*q.BornAfter)+"::date")
+	}
+	if q.Active != nil {
+		conditions = append(conditions, "COALESCE(p.active, TRUE) = "+arg(*q.Active))
+	}
+
+	for _, observation := range q.Observations {
+		sub := []string{
+			"o.tenant_id = p.tenant_id",
+			"o.subject_reference = 'Patient/' || p.id",
+			"o.deleted_at IS NULL",
+			"o.status NOT IN ('entered-in-error', 'cancelled')",
+			"o.code_values && " + arg(pq.Array([]string{observation.Code})),
+		}
+		if observation.Since != nil {
+			sub = append(sub, "o.effective_date >= "+arg(*observation.Since))
+		}
+		if observation.ValueCoding != nil {
+			contains, err := json.Marshal(models.CodeableConcept{Coding: []models.Coding{*observation.ValueCoding}})
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to encode cohort value coding: %w", err)
+			}
+			sub = append(sub, "o.value_codeable_concept @> "+arg(string(contains))+"::jsonb")
+		}
+		if observation.Comparator != "" {
+			operator, ok := cohortOperators[observation.Comparator]
+			if !ok {
+				return "", nil, fmt.Errorf("%w: unknown comparator %q", models.ErrInvalidCohort, observation.Comparator)
+			}
+			sub = append(sub,
+				"jsonb_typeof(o.value_quantity->'value') = 'number'",
+				"NOT o.value_quantity ? 'comparator'",
+				thresholdCondition(operator, observation, arg))
+		}
+
+		exists := "EXISTS"
+		if observation.Exclude {
+			exists = "NOT EXISTS"
+		}
+		conditions = append(conditions, fmt.Sprintf("%s (SELECT 1 FROM observations o WHERE %s)", exists, strings.Join(sub, " AND ")))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// thresholdCondition compares result values to the criterion's value, or to
+// the threshold of their unit
+func thresholdCondition(operator string, observation CohortObservationQuery, arg func(interface{}) string) string {
+	const value = "(o.value_quantity->>'value')::double precision"
+	if observation.Thresholds == nil {
+		return value + " " + operator + " " + arg(observation.Value)
+	}
+	if len(observation.Thresholds) == 0 {
+		return "FALSE"
+	}
+
+	units := make([]string, 0, len(observation.Thresholds))
+	for unit := range observation.Thresholds {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+
+	alternatives := make([]string, len(units))
+	for i, unit := range units {
+		alternatives[i] = fmt.Sprintf("(COALESCE(o.value_quantity->>'code', o.value_quantity->>'unit', '') = %s AND %s %s %s)",
+			arg(unit), value, operator, arg(observation.Thresholds[unit]))
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// Count returns the number of the tenant's patients matching query
+func (r *CohortRepository) Count(ctx context.Context, query CohortQuery) (int64, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	where, args, err := query.whereClause(tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	err = r.db.Reader(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM patients p `+where, args...).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count cohort members: %w", err)
+	}
+	return total, nil
+}
+
+// Members returns the ids of the tenant's patients matching query, in id order
+func (r *CohortRepository) Members(ctx context.Context, query CohortQuery, params PaginationParams) ([]uuid.UUID, PaginationResult, error) {
+	total, err := r.Count(ctx, query)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+	where, args, err := query.whereClause(tenantID)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, fmt.Sprintf(`
+		SELECT p.id
+		FROM patients p
+		%s
+		ORDER BY p.id
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2), append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list cohort members: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, PaginationResult{}, fmt.Errorf("failed to scan cohort member: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate cohort members: %w", err)
+	}
+
+	return ids, GetPaginationResult(total, params), nil
+}
+
+// ResultUnits returns the distinct units of the tenant's exact quantity
+// results of code: each quantity's code, or its unit text without one
+func (r *CohortRepository) ResultUnits(ctx context.Context, code string) ([]string, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, `
+		SELECT DISTINCT COALESCE(value_quantity->>'code', value_quantity->>'unit', '')
+		FROM observations
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND code_values && $2
+		  AND jsonb_typeof(value_quantity->'value') = 'number'
+	`, tenantID, pq.Array([]string{code}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list result units: %w", err)
+	}
+	defer rows.Close()
+
+	var units []string
+	for rows.Next() {
+		var unit string
+		if err := rows.Scan(&unit); err != nil {
+			return nil, fmt.Errorf("failed to scan result unit: %w", err)
+		}
+		units = append(units, unit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate result units: %w", err)
+	}
+
+	return units, nil
+}
@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// CriticalValueRepository manages the tenant's critical value rules and the
+// acknowledgement tasks of critical results
+type CriticalValueRepository struct {
+	*BaseRepository
+}
+
+func NewCriticalValueRepository(db *database.DB) *CriticalValueRepository {
+	return &CriticalValueRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+const criticalValueRuleColumns = `id, name, code_system, code, unit, low, high, active, created_at, updated_at`
+
+const criticalValueTaskColumns = `id, observation_id, subject, interpretation, description, status,
+			   acknowledged_at, acknowledged_by, created_at, updated_at`
+
+func (r *CriticalValueRepository) CreateRule(ctx context.Context, rule *models.CriticalValueRule) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO critical_value_rules (id, tenant_id, name, code_system, code, unit, low, high, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at, updated_at
+	`, rule.ID, tenantID, rule.Name, rule.System, rule.Code, rule.Unit, rule.Low, rule.High,
+		rule.Active).Scan(&rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create critical value rule: %w", err)
+	}
+	return nil
+}
+
+func (r *CriticalValueRepository) GetRule(ctx context.Context, id uuid.UUID) (*models.CriticalValueRule, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + criticalValueRuleColumns + ` FROM critical_value_rules WHERE id = $1 AND tenant_id = $2`
+	rule, err := scanCriticalValueRule(r.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrCriticalValueRuleNotFound
+		}
+		return nil, err
+	}
+	return rule, nil
+}
+
+// ListRules returns the tenant's critical value rules, oldest first
+func (r *CriticalValueRepository) ListRules(ctx context.Context) ([]*models.CriticalValueRule, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + criticalValueRuleColumns + ` FROM critical_value_rules WHERE tenant_id = $1 ORDER BY created_at, id`
+	return r.listRules(ctx, query, tenantID)
+}
+
+// MatchRules returns the tenant's active rules for any of codes, of any code
+// system, oldest first
+func (r *CriticalValueRepository) MatchRules(ctx context.Context, codes []string) ([]*models.CriticalValueRule, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + criticalValueRuleColumns + ` FROM critical_value_rules
+		WHERE tenant_id = $1 AND active AND code = ANY($2)
+		ORDER BY created_at, id`
+	return r.listRules(ctx, query, tenantID, pq.Array(codes))
+}
+
+func (r *CriticalValueRepository) listRules(ctx context.Context, query string, args ...interface{}) ([]*models.CriticalValueRule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list critical value rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*models.CriticalValueRule
+	for rows.Next() {
+		rule, err := scanCriticalValueRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate critical value rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r *CriticalValueRepository) UpdateRule(ctx context.Context, rule *models.CriticalValueRule) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		UPDATE critical_value_rules SET name = $3, code_system = $4, code = $5, unit = $6, low = $7, high = $8, active = $9
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING updated_at
+	`, rule.ID, tenantID, rule.Name, rule.System, rule.Code, rule.Unit, rule.Low, rule.High,
+		rule.Active).Scan(&rule.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrCriticalValueRuleNotFound
+		}
+		return fmt.Errorf("failed to update critical value rule: %w", err)
+	}
+	return nil
+}
+
+// DeleteRule removes a critical value rule; observations it flagged stay flagged
+func (r *CriticalValueRepository) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM critical_value_rules WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete critical value rule: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return models.ErrCriticalValueRuleNotFound
+	}
+	return nil
+}
+
+// OpenTask records the acknowledgement task of a critical result, reporting
+// whether it is new: an observation has one task, and when it exists already
+// it is read into task instead, with its current status
+func (r *CriticalValueRepository) OpenTask(ctx context.Context, task *models.CriticalValueTask) (bool, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	task.Status = models.TaskRequested
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO critical_value_tasks (id, tenant_id, observation_id, subject, interpretation, description, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (tenant_id, observation_id) DO NOTHING
+		RETURNING created_at, updated_at
+	`, task.ID, tenantID, task.ObservationID, task.Subject, task.Interpretation, task.Description,
+		task.Status).Scan(&task.CreatedAt, &task.UpdatedAt)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("failed to open critical value task: %w", err)
+	}
+
+	query := `SELECT ` + criticalValueTaskColumns + ` FROM critical_value_tasks WHERE tenant_id = $1 AND observation_id = $2`
+	existing, err := scanCriticalValueTask(r.db.QueryRowContext(ctx, query, tenantID, task.ObservationID))
+	if err != nil {
+		return false, err
+	}
+	*task = *existing
+	return false, nil
+}
+
+func (r *CriticalValueRepository) GetTask(ctx context.Context, id uuid.UUID) (*models.CriticalValueTask, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + criticalValueTaskColumns + ` FROM critical_value_tasks WHERE id = $1 AND tenant_id = $2`
+	task, err := scanCriticalValueTask(r.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrTaskNotFound
+		}
+		return nil, err
+	}
+	return task, nil
+}
+
+// CompleteTask marks the task as acknowledged by userID, unless it already is,
+// and returns it
+func (r *CriticalValueRepository) CompleteTask(ctx context.Context, id uuid.UUID, userID string) (*models.CriticalValueTask, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE critical_value_tasks SET status = $3, acknowledged_at = NOW(), acknowledged_by = $4
+		WHERE id = $1 AND tenant_id = $2 AND status = $5
+	`, id, tenantID, models.TaskCompleted, userID, models.TaskRequested)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete critical value task: %w", err)
+	}
+	return r.GetTask(ctx, id)
+}
+
+// CompleteObservationTask marks the task of an observation as acknowledged by
+// userID, unless there is none or it already is
+func (r *CriticalValueRepository) CompleteObservationTask(ctx context.Context, observationID uuid.UUID, userID string) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE critical_value_tasks SET status = $3, acknowledged_at = NOW(), acknowledged_by = $4
+		WHERE observation_id = $1 AND tenant_id = $2 AND status = $5
+	`, observationID, tenantID, models.TaskCompleted, userID, models.TaskRequested)
+	if err != nil {
+		return fmt.Errorf("failed to complete critical value task: %w", err)
+	}
+	return nil
+}
+
+// TaskFilter narrows task listing
+type TaskFilter struct {
+	Status string
+	// Subject is a reference such as "Patient/<id>"
+	Subject string
+	// ObservationID is the observation a task is about
+	ObservationID *uuid.UUID
+}
+
+func (f TaskFilter) whereClause(tenantID string) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{tenantID}
+
+	if f.Status != "" {
+		args = append(args, f.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if f.Subject != "" {
+		args = append(args, f.Subject)
+		conditions = append(conditions, fmt.Sprintf("subject = $%d", len(args)))
+	}
+	if f.ObservationID != nil {
+		args = append(args, *f.ObservationID)
+		conditions = append(conditions, fmt.Sprintf("observation_id = $%d", len(args)))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// ListTasks returns the tenant's critical value tasks, most recent first
+func (r *CriticalValueRepository) ListTasks(ctx context.Context, filter TaskFilter, params PaginationParams) ([]*models.CriticalValueTask, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	where, args := filter.whereClause(tenantID)
+
+	var total int64
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM critical_value_tasks `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get task count: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM critical_value_tasks
+		%s
+		ORDER BY created_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, criticalValueTaskColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []*models.CriticalValueTask
+	for rows.Next() {
+		task, err := scanCriticalValueTask(rows)
+		if err != nil {
+			return nil, PaginationResult{}, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate tasks: %w", err)
+	}
+
+	return tasks, GetPaginationResult(total, params), nil
+}
+
+func scanCriticalValueRule(scanner rowScanner) (*models.CriticalValueRule, error) {
+	rule := &models.CriticalValueRule{}
+	var low, high sql.NullFloat64
+	err := scanner.Scan(
+		&rule.ID,
+		&rule.Name,
+		&rule.System,
+		&rule.Code,
+		&rule.Unit,
+		&low,
+		&high,
+		&rule.Active,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan critical value rule: %w", err)
+	}
+	if low.Valid {
+		rule.Low = &low.Float64
+	}
+	if high.Valid {
+		rule.High = &high.Float64
+	}
+	return rule, nil
+}
+
+func scanCriticalValueTask(scanner rowScanner) (*models.CriticalValueTask, error) {
+	task := &models.CriticalValueTask{}
+	var acknowledgedAt sql.NullTime
+	var acknowledgedBy sql.NullString
+	err := scanner.Scan(
+		&task.ID,
+		&task.ObservationID,
+		&task.Subject,
+		&task.Interpretation,
+		&task.Description,
+		&task.Status,
+		&acknowledgedAt,
+		&acknowledgedBy,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan critical value task: %w", err)
+	}
+	if acknowledgedAt.Valid {
+		task.AcknowledgedAt = &acknowledgedAt.Time
+	}
+	if acknowledgedBy.Valid {
+		task.AcknowledgedBy = &acknowledgedBy.String
+	}
+	return task, nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/quality"
+
+	"github.com/google/uuid"
+)
+
+// dataQualityCheck finds the live rows of a table with an issue. Condition is
+// a predicate on the row, aliased x.
+type dataQualityCheck struct {
+	Issue        string
+	ResourceType string
+	Table        string
+	Condition    string
+}
+
+// danglingSubject matches rows whose subject refers to no live patient of the tenant
+const danglingSubject = `x.subject_reference LIKE 'Patient/%' AND NOT EXISTS (
+		SELECT 1 FROM patients p
+		WHERE p.tenant_id = x.tenant_id AND p.deleted_at IS NULL
+		  AND p.id::text = substr(x.subject_reference, length('Patient/') + 1)
+	)`
+
+// unitlessQuantity matches a quantity, given as a JSONB expression, with a
+// value but neither a unit code nor unit text
+func unitlessQuantity(quantity string) string {
+	return `(jsonb_typeof(` + quantity + `->'value') = 'number'
+		AND COALESCE(` + quantity + `->>'code', '') = '' AND COALESCE(` + quantity + `->>'unit', '') = '')`
+}
+
+// dataQualityChecks are run in order by the data quality report
+var dataQualityChecks = []dataQualityCheck{
+	{
+		Issue:        quality.MissingIdentifier,
+		ResourceType: "Patient",
+		Table:        "patients",
+		Condition:    `NOT EXISTS (SELECT 1 FROM unnest(x.identifier_values) v WHERE btrim(v) <> '')`,
+	},
+	{
+		Issue:        quality.MissingUnit,
+		ResourceType: "Observation",
+		Table:        "observations",
+		Condition: unitlessQuantity("x.value_quantity") + ` OR EXISTS (
+			SELECT 1 FROM jsonb_array_elements(CASE WHEN jsonb_typeof(x.component) = 'array' THEN x.component ELSE '[]'::jsonb END) c
+			WHERE ` + unitlessQuantity("c->'valueQuantity'") + `
+		)`,
+	},
+	{
+		Issue:        quality.DanglingSubject,
+		ResourceType: "Observation",
+		Table:        "observations",
+		Condition:    danglingSubject,
+	},
+	{
+		Issue:        quality.DanglingSubject,
+		ResourceType: "DiagnosticReport",
+		Table:        "diagnostic_reports",
+		Condition:    danglingSubject,
+	},
+	{
+		Issue:        quality.DanglingSubject,
+		ResourceType: "ImagingStudy",
+		Table:        "imaging_studies",
+		Condition:    danglingSubject,
+	},
+	{
+		Issue:        quality.DanglingResult,
+		ResourceType: "DiagnosticReport",
+		Table:        "diagnostic_reports",
+		Condition: `EXISTS (
+			SELECT 1 FROM jsonb_array_elements(CASE WHEN jsonb_typeof(x.result) = 'array' THEN x.result ELSE '[]'::jsonb END) r
+			WHERE r->>'reference' LIKE 'Observation/%' AND NOT EXISTS (
+				SELECT 1 FROM observations o
+				WHERE o.tenant_id = x.tenant_id AND o.deleted_at IS NULL
+				  AND o.id::text = substr(r->>'reference', length('Observation/') + 1)
+			)
+		)`,
+	},
+}
+
+// DataQualityRepository scans a tenant's resources for data quality issues
+type DataQualityRepository struct {
+	*BaseRepository
+}
+
+func NewDataQualityRepository(db *database.DB) *DataQualityRepository {
+	return &DataQualityRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Scan runs every data quality check over the tenant's live resources,
+// returning for each the number of resources with the issue and the
+// references of the first limit of them in id order. Each check is one query,
+// so no resource is loaded to be checked.
+func (r *DataQualityRepository) Scan(ctx context.Context, limit int) ([]models.DataQualityIssue, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make([]models.DataQualityIssue, 0, len(dataQualityChecks))
+	for _, check := range dataQualityChecks {
+		issue, err := r.scan(ctx, tenantID, check, limit)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+func (r *DataQualityRepository) scan(ctx context.Context, tenantID string, check dataQualityCheck, limit int) (models.DataQualityIssue, error) {
+	issue := models.DataQualityIssue{
+		Code:         check.Issue,
+		ResourceType: check.ResourceType,
+		Description:  quality.Descriptions[check.Issue],
+		Resources:    []string{},
+	}
+
+	// The count is taken over every matching row before the limit applies
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, `
+		SELECT x.id, COUNT(*) OVER ()
+		FROM `+check.Table+` x
+		WHERE x.tenant_id = $1 AND x.deleted_at IS NULL AND (`+check.Condition+`)
+		ORDER BY x.id
+		LIMIT $2
+	`, tenantID, limit)
+	if err != nil {
+		return issue, fmt.Errorf("failed to check %s %s: %w", check.ResourceType, check.Issue, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id, &issue.Count); err != nil {
+			return issue, fmt.Errorf("failed to scan %s %s: %w", check.ResourceType, check.Issue, err)
+		}
+		issue.Resources = append(issue.Resources, check.ResourceType+"/"+id.String())
+	}
+	if err := rows.Err(); err != nil {
+		return issue, fmt.Errorf("failed to check %s %s: %w", check.ResourceType, check.Issue, err)
+	}
+	return issue, nil
+}
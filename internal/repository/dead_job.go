@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+)
+
+// DeadJobRepository manages the dead letter table. Jobs are deployment-wide, so
+// queries are not tenant-scoped.
+type DeadJobRepository struct {
+	*BaseRepository
+}
+
+func NewDeadJobRepository(db *database.DB) *DeadJobRepository {
+	return &DeadJobRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+const deadJobColumns = `id, job_type, payload, retries, max_retries, timeout_ms, priority, error, created_at, failed_at`
+
+// DeadJobFilter narrows listing and purging of dead jobs
+type DeadJobFilter struct {
+	JobType string
+	// FailedBefore matches jobs that failed strictly before this time
+	FailedBefore *time.Time
+}
+
+func (f DeadJobFilter) whereClause() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if f.JobType != "" {
+		args = append(args, f.JobType)
+		conditions = append(conditions, fmt.Sprintf("job_type = $%d", len(args)))
+	}
+	if f.FailedBefore != nil {
+		args = append(args, *f.FailedBefore)
+		conditions = append(conditions, fmt.Sprintf("failed_at < $%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Add records a dead job, replacing an earlier entry for the same job id
+func (r *DeadJobRepository) Add(ctx context.Context, job *models.DeadJob) error {
+	query := `
+		INSERT INTO dead_jobs (` + deadJobColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			job_type = EXCLUDED.job_type, payload = EXCLUDED.payload, retries = EXCLUDED.retries,
+			max_retries = EXCLUDED.max_retries, timeout_ms = EXCLUDED.timeout_ms, priority = EXCLUDED.priority,
+			error = EXCLUDED.error, created_at = EXCLUDED.created_at, failed_at = EXCLUDED.failed_at
+		RETURNING failed_at
+	`
+
+	// JSONB parameters must be sent as text
+	var payload interface{}
+	if len(job.Payload) > 0 {
+		payload = string(job.Payload)
+	}
+
+	err := r.db.QueryRowContext(ctx, query,
+		job.ID,
+		job.JobType,
+		payload,
+		job.Retries,
+		job.MaxRetries,
+		job.Timeout.Milliseconds(),
+		job.Priority,
+		job.Error,
+		job.CreatedAt,
+	).Scan(&job.FailedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add dead job: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DeadJobRepository) GetByID(ctx context.Context, id string) (*models.DeadJob, error) {
+	query := `SELECT ` + deadJobColumns + ` FROM dead_jobs WHERE id = $1`
+
+	job, err := scanDeadJob(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrDeadJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get dead job: %w", err)
+	}
+
+	return job, nil
+}
+
+// List returns dead jobs, most recently failed first
+func (r *DeadJobRepository) List(ctx context.Context, filter DeadJobFilter, params PaginationParams) ([]*models.DeadJob, PaginationResult, error) {
+	where, args := filter.whereClause()
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM dead_jobs `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get dead job count: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM dead_jobs
+		%s
+		ORDER BY failed_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, deadJobColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list dead jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.DeadJob
+	for rows.Next() {
+		job, err := scanDeadJob(rows)
+		if err != nil {
+			return nil, PaginationResult{}, fmt.Errorf("failed to scan dead job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate dead jobs: %w", err)
+	}
+
+	return jobs, GetPaginationResult(total, params), nil
+}
+
+func (r *DeadJobRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM dead_jobs WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead job: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.ErrDeadJobNotFound
+	}
+
+	return nil
+}
+
+// Purge deletes every dead job matching filter and returns how many were removed
+func (r *DeadJobRepository) Purge(ctx context.Context, filter DeadJobFilter) (int64, error) {
+	where, args := filter.whereClause()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM dead_jobs `+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge dead jobs: %w", err)
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return purged, nil
+}
+
+func scanDeadJob(scanner rowScanner) (*models.DeadJob, error) {
+	job := &models.DeadJob{}
+	var payload []byte
+	var timeoutMS int64
+	err := scanner.Scan(
+		&job.ID,
+		&job.JobType,
+		&payload,
+		&job.Retries,
+		&job.MaxRetries,
+		&timeoutMS,
+		&job.Priority,
+		&job.Error,
+		&job.CreatedAt,
+		&job.FailedAt,
+	)
+	job.Payload = payload
+	job.Timeout = time.Duration(timeoutMS) * time.Millisecond
+	return job, err
+}
@@ -0,0 +1,343 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type DiagnosticReportRepository struct {
+	*BaseRepository
+}
+
+func NewDiagnosticReportRepository(db *database.DB) *DiagnosticReportRepository {
+	return &DiagnosticReportRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// diagnosticReportColumns is the standard column list read by scanDiagnosticReport
+const diagnosticReportColumns = `id, identifier, based_on, status, category, code, subject, encounter,
+			   effective_date_time, effective_period, issued, performer, results_interpreter,
+			   specimen, result, imaging_study, conclusion, conclusion_code,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version`
+
+func (r *DiagnosticReportRepository) Create(ctx context.Context, report *models.DiagnosticReport) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO diagnostic_reports (
+			id, identifier, based_on, status, category, code, subject, encounter,
+			effective_date_time, effective_period, issued, performer, results_interpreter,
+			specimen, result, imaging_study, conclusion, conclusion_code,
+			meta, implicit_rules, language, text, contained, extension, modifier_extension,
+			code_values, subject_reference, tenant_id
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
+			$17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28
+		) RETURNING created_at, updated_at, version
+	`
+
+	searchColumns := ExtractDiagnosticReportSearchColumns(report)
+
+	args := []interface{}{
+		report.ID,
+		toJSON(report.Identifier),
+		toJSON(report.BasedOn),
+		report.Status,
+		toJSON(report.Category),
+		toJSON(report.Code),
+		toJSON(report.Subject),
+		toJSON(report.Encounter),
+		report.EffectiveDateTime,
+		toJSON(report.EffectivePeriod),
+		report.Issued,
+		toJSON(report.Performer),
+		toJSON(report.ResultsInterpreter),
+		toJSON(report.Specimen),
+		toJSON(report.Result),
+		toJSON(report.ImagingStudy),
+		report.Conclusion,
+		toJSON(report.ConclusionCode),
+		toJSON(report.Meta),
+		report.ImplicitRules,
+		report.Language,
+		toJSON(report.Text),
+		toJSON(report.Contained),
+		toJSON(report.Extension),
+		toJSON(report.ModifierExtension),
+		pq.Array(searchColumns.CodeValues),
+		searchColumns.SubjectReference,
+		tenantID,
+	}
+
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&report.CreatedAt, &report.UpdatedAt, &report.Version); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, report, &AuditLog{
+			ResourceType: "DiagnosticReport",
+			ResourceID:   report.ID,
+			Action:       "CREATE",
+			NewValues:    mustMarshalJSON(report),
+		})
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to create diagnostic report: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DiagnosticReportRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.DiagnosticReport, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + diagnosticReportColumns + `, deleted_at
+		FROM diagnostic_reports WHERE id = $1 AND tenant_id = $2`
+
+	var deletedAt *time.Time
+	report, err := scanDiagnosticReport(r.db.Reader(ctx).QueryRowContext(ctx, query, id, tenantID), &deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrDiagnosticReportNotFound
+		}
+		return nil, fmt.Errorf("failed to get diagnostic report: %w", err)
+	}
+
+	if deletedAt != nil {
+		return nil, models.ErrResourceDeleted
+	}
+
+	return report, nil
+}
+
+func (r *DiagnosticReportRepository) Update(ctx context.Context, report *models.DiagnosticReport) error {
+	ctx = database.WithPrimary(ctx)
+
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// First get the old values for audit
+	oldReport, err := r.GetByID(ctx, report.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE diagnostic_reports SET
+			identifier = $2, based_on = $3, status = $4, category = $5, code = $6,
+			subject = $7, encounter = $8, effective_date_time = $9, effective_period = $10,
+			issued = $11, performer = $12, results_interpreter = $13, specimen = $14,
+			result = $15, imaging_study = $16, conclusion = $17, conclusion_code = $18,
+			meta = $19, implicit_rules = $20, language = $21, text = $22, contained = $23,
+			extension = $24, modifier_extension = $25,
+			code_values = $26, subject_reference = $27
+		WHERE id = $1 AND tenant_id = $28 AND deleted_at IS NULL
+		RETURNING updated_at, version
+	`
+
+	searchColumns := ExtractDiagnosticReportSearchColumns(report)
+
+	args := []interface{}{
+		report.ID,
+		toJSON(report.Identifier),
+		toJSON(report.BasedOn),
+		report.Status,
+		toJSON(report.Category),
+		toJSON(report.Code),
+		toJSON(report.Subject),
+		toJSON(report.Encounter),
+		report.EffectiveDateTime,
+		toJSON(report.EffectivePeriod),
+		report.Issued,
+		toJSON(report.Performer),
+		toJSON(report.ResultsInterpreter),
+		toJSON(report.Specimen),
+		toJSON(report.Result),
+		toJSON(report.ImagingStudy),
+		report.Conclusion,
+		toJSON(report.ConclusionCode),
+		toJSON(report.Meta),
+		report.ImplicitRules,
+		report.Language,
+		toJSON(report.Text),
+		toJSON(report.Contained),
+		toJSON(report.Extension),
+		toJSON(report.ModifierExtension),
+		pq.Array(searchColumns.CodeValues),
+		searchColumns.SubjectReference,
+		tenantID,
+	}
+
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&report.UpdatedAt, &report.Version); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, report, &AuditLog{
+			ResourceType: "DiagnosticReport",
+			ResourceID:   report.ID,
+			Action:       "UPDATE",
+			OldValues:    mustMarshalJSON(oldReport),
+			NewValues:    mustMarshalJSON(report),
+		})
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrDiagnosticReportNotFound
+		}
+		return fmt.Errorf("failed to update diagnostic report: %w", err)
+	}
+
+	return nil
+}
+
+// DiagnosticReportSearchParams represents supported diagnostic report search filters
+type DiagnosticReportSearchParams struct {
+	Subject string `json:"subject,omitempty"`
+	Code    string `json:"code,omitempty"`
+	// Identifier matches the value of any of the report's identifiers
+	Identifier string `json:"identifier,omitempty"`
+}
+
+// whereClause builds the SQL filter for the search parameters using the extracted search columns
+func (p DiagnosticReportSearchParams) whereClause(tenantID string) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1", "deleted_at IS NULL"}
+	args := []interface{}{tenantID}
+
+	if p.Subject != "" {
+		args = append(args, p.Subject)
+		conditions = append(conditions, fmt.Sprintf("subject_reference = $%d", len(args)))
+	}
+	if p.Code != "" {
+		args = append(args, pq.Array([]string{p.Code}))
+		conditions = append(conditions, fmt.Sprintf("code_values @> $%d", len(args)))
+	}
+	if p.Identifier != "" {
+		args = append(args, p.Identifier)
+		conditions = append(conditions, fmt.Sprintf("identifier @> jsonb_build_array(jsonb_build_object('value', $%d::text))", len(args)))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (r *DiagnosticReportRepository) List(ctx context.Context, search DiagnosticReportSearchParams, params PaginationParams) ([]*models.DiagnosticReport, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	where, args := search.whereClause(tenantID)
+
+	countQuery := `SELECT COUNT(*) FROM diagnostic_reports ` + where
+	var total int64
+	if err := r.db.Reader(ctx).QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get diagnostic report count: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM diagnostic_reports
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, diagnosticReportColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list diagnostic reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []*models.DiagnosticReport
+	for rows.Next() {
+		report, err := scanDiagnosticReport(rows)
+		if err != nil {
+			return nil, PaginationResult{}, err
+		}
+		reports = append(reports, report)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate diagnostic reports: %w", err)
+	}
+
+	return reports, GetPaginationResult(total, params), nil
+}
+
+// scanDiagnosticReport scans diagnosticReportColumns, followed by any extra destinations
+func scanDiagnosticReport(scanner rowScanner, extra ...interface{}) (*models.DiagnosticReport, error) {
+	report := &models.DiagnosticReport{}
+
+	dest := []interface{}{
+		&report.ID,
+		jsonb(&report.Identifier),
+		jsonb(&report.BasedOn),
+		&report.Status,
+		jsonb(&report.Category),
+		jsonb(&report.Code),
+		jsonb(&report.Subject),
+		jsonb(&report.Encounter),
+		&report.EffectiveDateTime,
+		jsonb(&report.EffectivePeriod),
+		&report.Issued,
+		jsonb(&report.Performer),
+		jsonb(&report.ResultsInterpreter),
+		jsonb(&report.Specimen),
+		jsonb(&report.Result),
+		jsonb(&report.ImagingStudy),
+		&report.Conclusion,
+		jsonb(&report.ConclusionCode),
+		jsonb(&report.Meta),
+		&report.ImplicitRules,
+		&report.Language,
+		jsonb(&report.Text),
+		jsonb(&report.Contained),
+		jsonb(&report.Extension),
+		jsonb(&report.ModifierExtension),
+		&report.CreatedAt,
+		&report.UpdatedAt,
+		&report.Version,
+	}
+
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return nil, fmt.Errorf("failed to scan diagnostic report: %w", err)
+	}
+
+	return report, nil
+}
+
+// recordChange writes the audit log entry of a change to the diagnostic report and its state
+// after the change, as a new history version, through tx, the transaction of the
+// change, so the change is not committed without them
+func (r *DiagnosticReportRepository) recordChange(ctx context.Context, tx *sql.Tx, report *models.DiagnosticReport, log *AuditLog) error {
+	if err := r.logAudit(ctx, tx, log); err != nil {
+		return err
+	}
+	return r.insertHistory(ctx, tx, &HistoryEntry{
+		ResourceType: "DiagnosticReport",
+		ResourceID:   report.ID,
+		Version:      report.Version,
+		Action:       log.Action,
+		Payload:      mustMarshalJSON(report),
+	})
+}
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DuplicateRepository stores the probable duplicate patients found by the
+// duplicate-detection job and their review
+type DuplicateRepository struct {
+	*BaseRepository
+}
+
+func NewDuplicateRepository(db *database.DB) *DuplicateRepository {
+	return &DuplicateRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+const patientDuplicateColumns = `id, tenant_id, patient_id, duplicate_id, score, reasons, status,
+			   detected_at, reviewed_at, reviewed_by, note, created_at, updated_at`
+
+// DuplicatePair is a pair of live patients of a tenant worth comparing, holding
+// only the demographics compared: identifiers, names, gender and birth date
+type DuplicatePair struct {
+	TenantID string
+	Patient  *models.Patient
+	Other    *models.Patient
+}
+
+// PatientBatch returns, across tenants, up to limit ids of live patients
+// greater than after, in order; the last id is the next batch's after
+func (r *DuplicateRepository) PatientBatch(ctx context.Context, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, `
+		SELECT id FROM patients
+		WHERE deleted_at IS NULL AND id > $1
+		ORDER BY id
+		LIMIT $2
+	`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list patients to compare: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan patient id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate patients to compare: %w", err)
+	}
+
+	return ids, nil
+}
+
+// CandidatePairs pairs each of the patients ids with the live patients of its
+// tenant of a greater id that share an identifier value, or share a birth date
+// or family name and have similar demographics by trigram similarity
+func (r *DuplicateRepository) CandidatePairs(ctx context.Context, ids []uuid.UUID) ([]DuplicatePair, error) {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, `
+		SELECT a.tenant_id,
+			   a.id, a.identifier, a.name, a.gender, a.birth_date,
+			   b.id, b.identifier, b.name, b.gender, b.birth_date
+		FROM patients a
+		JOIN patients b ON b.tenant_id = a.tenant_id AND b.id > a.id AND b.deleted_at IS NULL
+		WHERE a.id = ANY($1::uuid[]) AND a.deleted_at IS NULL
+		  AND (b.identifier_values && a.identifier_values
+			   OR (b.birth_date = a.birth_date AND b.search_text % a.search_text)
+			   OR (b.family_name = a.family_name AND b.search_text % a.search_text))
+		ORDER BY a.id, b.id
+	`, pq.Array(values))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var pairs []DuplicatePair
+	for rows.Next() {
+		pair := DuplicatePair{Patient: &models.Patient{}, Other: &models.Patient{}}
+		err := rows.Scan(
+			&pair.TenantID,
+			&pair.Patient.ID, jsonb(&pair.Patient.Identifier), jsonb(&pair.Patient.Name), &pair.Patient.Gender, &pair.Patient.BirthDate,
+			&pair.Other.ID, jsonb(&pair.Other.Identifier), jsonb(&pair.Other.Name), &pair.Other.Gender, &pair.Other.BirthDate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate candidate: %w", err)
+		}
+		pairs = append(pairs, pair)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate duplicate candidates: %w", err)
+	}
+
+	return pairs, nil
+}
+
+// Record stores a pair found by a scan, reporting whether it is new. A pair
+// found before keeps its status and review, taking the new score and reasons.
+func (r *DuplicateRepository) Record(ctx context.Context, duplicate *models.PatientDuplicate) (bool, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var created bool
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO patient_duplicates (tenant_id, patient_id, duplicate_id, score, reasons, detected_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (patient_id, duplicate_id) DO UPDATE SET
+			score = EXCLUDED.score,
+			reasons = EXCLUDED.reasons,
+			detected_at = EXCLUDED.detected_at
+		WHERE patient_duplicates.tenant_id = EXCLUDED.tenant_id
+		RETURNING id, (xmax = 0)
+	`, tenantID, duplicate.PatientID, duplicate.DuplicateID, duplicate.Score, pq.Array(duplicate.Reasons),
+		duplicate.DetectedAt).Scan(&duplicate.ID, &created)
+	if err != nil {
+		return false, fmt.Errorf("failed to record patient duplicate: %w", err)
+	}
+	duplicate.TenantID = tenantID
+	return created, nil
+}
+
+// RemoveStale deletes, across tenants, the pending pairs last found before
+// before, which a complete scan since no longer finds
+func (r *DuplicateRepository) RemoveStale(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM patient_duplicates WHERE status = $1 AND detected_at < $2
+	`, models.DuplicatePending, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove stale patient duplicates: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Get returns a pair of the tenant, or models.ErrPatientDuplicateNotFound
+func (r *DuplicateRepository) Get(ctx context.Context, id uuid.UUID) (*models.PatientDuplicate, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + patientDuplicateColumns + ` FROM patient_duplicates WHERE id = $1 AND tenant_id = $2`
+	duplicate, err := scanPatientDuplicate(r.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrPatientDuplicateNotFound
+		}
+		return nil, err
+	}
+	return duplicate, nil
+}
+
+// Review records a registrar's decision on a pair, replacing any earlier one
+func (r *DuplicateRepository) Review(ctx context.Context, id uuid.UUID, status, userID string, note *string) (*models.PatientDuplicate, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE patient_duplicates SET status = $3, reviewed_at = NOW(), reviewed_by = NULLIF($4, ''), note = $5
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING ` + patientDuplicateColumns
+	duplicate, err := scanPatientDuplicate(r.db.QueryRowContext(ctx, query, id, tenantID, status, userID, note))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrPatientDuplicateNotFound
+		}
+		return nil, err
+	}
+	return duplicate, nil
+}
+
+// DuplicateFilter narrows duplicate listing
+type DuplicateFilter struct {
+	Status string
+	// PatientID is either patient of a pair
+	PatientID *uuid.UUID
+}
+
+func (f DuplicateFilter) whereClause(tenantID string) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{tenantID}
+
+	if f.Status != "" {
+		args = append(args, f.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if f.PatientID != nil {
+		args = append(args, *f.PatientID)
+		conditions = append(conditions, fmt.Sprintf("(patient_id = $%d OR duplicate_id = $%[1]d)", len(args)))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// List returns the tenant's pairs, highest score first
+func (r *DuplicateRepository) List(ctx context.Context, filter DuplicateFilter, params PaginationParams) ([]*models.PatientDuplicate, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	where, args := filter.whereClause(tenantID)
+
+	var total int64
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM patient_duplicates `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get patient duplicate count: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM patient_duplicates
+		%s
+		ORDER BY score DESC, detected_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, patientDuplicateColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list patient duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	var duplicates []*models.PatientDuplicate
+	for rows.Next() {
+		duplicate, err := scanPatientDuplicate(rows)
+		if err != nil {
+			return nil, PaginationResult{}, err
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate patient duplicates: %w", err)
+	}
+
+	return duplicates, GetPaginationResult(total, params), nil
+}
+
+func scanPatientDuplicate(scanner rowScanner) (*models.PatientDuplicate, error) {
+	duplicate := &models.PatientDuplicate{}
+	err := scanner.Scan(
+		&duplicate.ID,
+		&duplicate.TenantID,
+		&duplicate.PatientID,
+		&duplicate.DuplicateID,
+		&duplicate.Score,
+		pq.Array(&duplicate.Reasons),
+		&duplicate.Status,
+		&duplicate.DetectedAt,
+		&duplicate.ReviewedAt,
+		&duplicate.ReviewedBy,
+		&duplicate.Note,
+		&duplicate.CreatedAt,
+		&duplicate.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan patient duplicate: %w", err)
+	}
+	return duplicate, nil
+}
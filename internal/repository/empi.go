@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// EMPIRepository stores the outcome of the EMPI lookups of the tenant's patients
+type EMPIRepository struct {
+	*BaseRepository
+}
+
+func NewEMPIRepository(db *database.DB) *EMPIRepository {
+	return &EMPIRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+const empiLinkColumns = `patient_id, tenant_id, status, enterprise_id, candidate_id, conflicting_patient_id,
+			   last_error, attempts, checked_at, created_at, updated_at`
+
+// Get returns the link of a patient, or models.ErrEMPILinkNotFound when the
+// patient has not been looked up
+func (r *EMPIRepository) Get(ctx context.Context, patientID uuid.UUID) (*models.EMPILink, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + empiLinkColumns + ` FROM empi_links WHERE patient_id = $1 AND tenant_id = $2`
+	link, err := scanEMPILink(r.db.QueryRowContext(ctx, query, patientID, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrEMPILinkNotFound
+		}
+		return nil, err
+	}
+	return link, nil
+}
+
+// Save records the outcome of a lookup, replacing the previous one. Attempts
+// restart at 1 when the status changes and count up while it stays the same.
+func (r *EMPIRepository) Save(ctx context.Context, link *models.EMPILink) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO empi_links (patient_id, tenant_id, status, enterprise_id, candidate_id,
+			conflicting_patient_id, last_error, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (patient_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			enterprise_id = EXCLUDED.enterprise_id,
+			candidate_id = EXCLUDED.candidate_id,
+			conflicting_patient_id = EXCLUDED.conflicting_patient_id,
+			last_error = EXCLUDED.last_error,
+			checked_at = EXCLUDED.checked_at,
+			attempts = CASE WHEN empi_links.status = EXCLUDED.status THEN empi_links.attempts + 1 ELSE 1 END
+		WHERE empi_links.tenant_id = EXCLUDED.tenant_id
+		RETURNING attempts, created_at, updated_at
+	`, link.PatientID, tenantID, link.Status, link.EnterpriseID, link.CandidateID, link.ConflictingPatientID,
+		link.LastError, link.CheckedAt).Scan(&link.Attempts, &link.CreatedAt, &link.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save EMPI link: %w", err)
+	}
+	link.TenantID = tenantID
+	return nil
+}
+
+// List returns the tenant's links, of one status if status is set, most
+// recently checked first
+func (r *EMPIRepository) List(ctx context.Context, status string, params PaginationParams) ([]*models.EMPILink, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	where := `WHERE tenant_id = $1 AND ($2::text = '' OR status = $2::text)`
+
+	var total int64
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM empi_links `+where, tenantID, status).Scan(&total)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get EMPI link count: %w", err)
+	}
+
+	query := `SELECT ` + empiLinkColumns + ` FROM empi_links ` + where + `
+		ORDER BY checked_at DESC, patient_id
+		LIMIT $3 OFFSET $4`
+	links, err := r.list(ctx, query, tenantID, status, params.Limit, params.Offset)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+	return links, GetPaginationResult(total, params), nil
+}
+
+// DueReconciliation returns, across tenants, up to limit links of patients that
+// are not deleted, not linked and were last checked before cutoff, least
+// recently checked first
+func (r *EMPIRepository) DueReconciliation(ctx context.Context, cutoff time.Time, limit int) ([]*models.EMPILink, error) {
+	query := `SELECT ` + empiLinkColumns + ` FROM empi_links
+		WHERE status <> $1 AND checked_at < $2
+		  AND EXISTS (SELECT 1 FROM patients p WHERE p.id = empi_links.patient_id AND p.deleted_at IS NULL)
+		ORDER BY checked_at
+		LIMIT $3`
+	return r.list(ctx, query, models.EMPILinked, cutoff, limit)
+}
+
+func (r *EMPIRepository) list(ctx context.Context, query string, args ...interface{}) ([]*models.EMPILink, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list EMPI links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []*models.EMPILink
+	for rows.Next() {
+		link, err := scanEMPILink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate EMPI links: %w", err)
+	}
+
+	return links, nil
+}
+
+func scanEMPILink(scanner rowScanner) (*models.EMPILink, error) {
+	link := &models.EMPILink{}
+	err := scanner.Scan(
+		&link.PatientID,
+		&link.TenantID,
+		&link.Status,
+		&link.EnterpriseID,
+		&link.CandidateID,
+		&link.ConflictingPatientID,
+		&link.LastError,
+		&link.Attempts,
+		&link.CheckedAt,
+		&link.CreatedAt,
+		&link.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan EMPI link: %w", err)
+	}
+	return link, nil
+}
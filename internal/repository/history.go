@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+)
+
+// HistoryEntry represents a stored version of a resource
+type HistoryEntry struct {
+	ID           uuid.UUID       `json:"id"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   uuid.UUID       `json:"resource_id"`
+	Version      int             `json:"version"`
+	Action       string          `json:"action"`
+	Payload      json.RawMessage `json:"payload"`
+	Actor        *string         `json:"actor,omitempty"`
+	RecordedAt   time.Time       `json:"recorded_at"`
+}
+
+// RecordHistory stores a resource version in the history table.
+// The actor is taken from the request context when not set explicitly.
+func (r *BaseRepository) RecordHistory(ctx context.Context, entry *HistoryEntry) error {
+	return r.insertHistory(ctx, r.db, entry)
+}
+
+// insertHistory stores a resource version through q, such as a transaction
+func (r *BaseRepository) insertHistory(ctx context.Context, q database.Querier, entry *HistoryEntry) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if entry.Actor == nil {
+		if userID := requestctx.UserID(ctx); userID != "" {
+			entry.Actor = &userID
+		}
+	}
+
+	query := `
+		INSERT INTO resource_history (resource_type, resource_id, version, action, payload, actor, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (resource_type, resource_id, version) DO NOTHING
+	`
+
+	_, err = q.ExecContext(ctx, query,
+		entry.ResourceType,
+		entry.ResourceID,
+		entry.Version,
+		entry.Action,
+		entry.Payload,
+		entry.Actor,
+		tenantID,
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to record resource history: %w", err)
+	}
+
+	return nil
+}
+
+// ListHistory returns all stored versions of a resource, newest first
+func (r *BaseRepository) ListHistory(ctx context.Context, resourceType string, resourceID uuid.UUID) ([]*HistoryEntry, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, resource_type, resource_id, version, action, payload, actor, recorded_at
+		FROM resource_history
+		WHERE resource_type = $1 AND resource_id = $2 AND tenant_id = $3
+		ORDER BY version DESC
+	`
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, resourceType, resourceID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*HistoryEntry
+	for rows.Next() {
+		entry := &HistoryEntry{}
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.ResourceType,
+			&entry.ResourceID,
+			&entry.Version,
+			&entry.Action,
+			&entry.Payload,
+			&entry.Actor,
+			&entry.RecordedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan resource history: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate resource history: %w", err)
+	}
+
+	return entries, nil
+}
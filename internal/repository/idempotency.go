@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/database"
+)
+
+// IdempotencyRecord is the stored outcome of a request sent with an
+// Idempotency-Key. StatusCode is zero while the request is still in progress.
+type IdempotencyRecord struct {
+	Subject     string
+	Key         string
+	RequestHash string
+	StatusCode  int
+	Headers     map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// IdempotencyRepository stores responses by idempotency key, scoped by the
+// request tenant and the client (subject) that sent them
+type IdempotencyRepository struct {
+	*BaseRepository
+}
+
+func NewIdempotencyRepository(db *database.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Reserve claims key for a request with requestHash, to be kept for ttl. It
+// returns nil when the key was claimed, or the record holding it otherwise. A key
+// whose record expired, or whose request has been in progress longer than
+// staleAfter (e.g. its server crashed), is claimed afresh.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, subject, key, requestHash string, ttl, staleAfter time.Duration) (*IdempotencyRecord, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO idempotency_keys (tenant_id, subject, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + make_interval(secs => $5))
+		ON CONFLICT (tenant_id, subject, key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash, status_code = NULL, headers = NULL, body = NULL,
+			created_at = NOW(), expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at <= NOW() - make_interval(secs => $6))
+		RETURNING key
+	`
+
+	// A key released between the insert and the read is tried once more
+	for attempt := 0; attempt < 2; attempt++ {
+		var claimed string
+		err = r.db.QueryRowContext(ctx, query, tenantID, subject, key, requestHash, ttl.Seconds(), staleAfter.Seconds()).Scan(&claimed)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+
+		record, err := r.get(ctx, tenantID, subject, key)
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			return record, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to reserve idempotency key: key is contended")
+}
+
+// get returns the record of key, or nil if there is none
+func (r *IdempotencyRepository) get(ctx context.Context, tenantID, subject, key string) (*IdempotencyRecord, error) {
+	query := `
+		SELECT subject, key, request_hash, COALESCE(status_code, 0), headers, body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE tenant_id = $1 AND subject = $2 AND key = $3
+	`
+
+	record := &IdempotencyRecord{}
+	var headers []byte
+	err := r.db.QueryRowContext(ctx, query, tenantID, subject, key).Scan(
+		&record.Subject, &record.Key, &record.RequestHash, &record.StatusCode,
+		&headers, &record.Body, &record.CreatedAt, &record.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &record.Headers); err != nil {
+			return nil, fmt.Errorf("failed to decode idempotent response headers: %w", err)
+		}
+	}
+	return record, nil
+}
+
+// Complete stores the response of the request holding key
+func (r *IdempotencyRepository) Complete(ctx context.Context, subject, key string, statusCode int, headers map[string]string, body []byte) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotent response headers: %w", err)
+	}
+
+	query := `
+		UPDATE idempotency_keys SET status_code = $4, headers = $5, body = $6
+		WHERE tenant_id = $1 AND subject = $2 AND key = $3
+	`
+	// JSONB parameters must be sent as text
+	if _, err := r.db.ExecContext(ctx, query, tenantID, subject, key, statusCode, string(encoded), body); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release frees key after its request failed, so a retry runs it again
+func (r *IdempotencyRepository) Release(ctx context.Context, subject, key string) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM idempotency_keys WHERE tenant_id = $1 AND subject = $2 AND key = $3 AND status_code IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, tenantID, subject, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PruneExpired deletes expired records of every tenant, returning how many
+func (r *IdempotencyRepository) PruneExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,321 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ImagingStudyRepository struct {
+	*BaseRepository
+}
+
+func NewImagingStudyRepository(db *database.DB) *ImagingStudyRepository {
+	return &ImagingStudyRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// imagingStudyColumns is the standard column list read by scanImagingStudy
+const imagingStudyColumns = `id, identifier, status, modality, subject, started, number_of_series,
+			   number_of_instances, description, series,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version`
+
+func (r *ImagingStudyRepository) Create(ctx context.Context, study *models.ImagingStudy) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO imaging_studies (
+			id, identifier, status, modality, subject, started, number_of_series,
+			number_of_instances, description, series,
+			meta, implicit_rules, language, text, contained, extension, modifier_extension,
+			study_instance_uid, accession_number, modality_codes, subject_reference, tenant_id
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
+			$17, $18, $19, $20, $21, $22
+		) RETURNING created_at, updated_at, version
+	`
+
+	searchColumns := ExtractImagingStudySearchColumns(study)
+
+	args := []interface{}{
+		study.ID,
+		toJSON(study.Identifier),
+		study.Status,
+		toJSON(study.Modality),
+		toJSON(study.Subject),
+		study.Started,
+		study.NumberOfSeries,
+		study.NumberOfInstances,
+		study.Description,
+		toJSON(study.Series),
+		toJSON(study.Meta),
+		study.ImplicitRules,
+		study.Language,
+		toJSON(study.Text),
+		toJSON(study.Contained),
+		toJSON(study.Extension),
+		toJSON(study.ModifierExtension),
+		searchColumns.StudyInstanceUID,
+		searchColumns.AccessionNumber,
+		pq.Array(searchColumns.ModalityCodes),
+		searchColumns.SubjectReference,
+		tenantID,
+	}
+
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&study.CreatedAt, &study.UpdatedAt, &study.Version); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, study, &AuditLog{
+			ResourceType: "ImagingStudy",
+			ResourceID:   study.ID,
+			Action:       "CREATE",
+			NewValues:    mustMarshalJSON(study),
+		})
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to create imaging study: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ImagingStudyRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ImagingStudy, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + imagingStudyColumns + `, deleted_at
+		FROM imaging_studies WHERE id = $1 AND tenant_id = $2`
+
+	var deletedAt *time.Time
+	study, err := scanImagingStudy(r.db.Reader(ctx).QueryRowContext(ctx, query, id, tenantID), &deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrImagingStudyNotFound
+		}
+		return nil, fmt.Errorf("failed to get imaging study: %w", err)
+	}
+
+	if deletedAt != nil {
+		return nil, models.ErrResourceDeleted
+	}
+
+	return study, nil
+}
+
+func (r *ImagingStudyRepository) Update(ctx context.Context, study *models.ImagingStudy) error {
+	ctx = database.WithPrimary(ctx)
+
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// First get the old values for audit
+	oldStudy, err := r.GetByID(ctx, study.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE imaging_studies SET
+			identifier = $2, status = $3, modality = $4, subject = $5, started = $6,
+			number_of_series = $7, number_of_instances = $8, description = $9, series = $10,
+			meta = $11, implicit_rules = $12, language = $13, text = $14, contained = $15,
+			extension = $16, modifier_extension = $17,
+			study_instance_uid = $18, accession_number = $19, modality_codes = $20,
+			subject_reference = $21
+		WHERE id = $1 AND tenant_id = $22 AND deleted_at IS NULL
+		RETURNING updated_at, version
+	`
+
+	searchColumns := ExtractImagingStudySearchColumns(study)
+
+	args := []interface{}{
+		study.ID,
+		toJSON(study.Identifier),
+		study.Status,
+		toJSON(study.Modality),
+		toJSON(study.Subject),
+		study.Started,
+		study.NumberOfSeries,
+		study.NumberOfInstances,
+		study.Description,
+		toJSON(study.Series),
+		toJSON(study.Meta),
+		study.ImplicitRules,
+		study.Language,
+		toJSON(study.Text),
+		toJSON(study.Contained),
+		toJSON(study.Extension),
+		toJSON(study.ModifierExtension),
+		searchColumns.StudyInstanceUID,
+		searchColumns.AccessionNumber,
+		pq.Array(searchColumns.ModalityCodes),
+		searchColumns.SubjectReference,
+		tenantID,
+	}
+
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&study.UpdatedAt, &study.Version); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, study, &AuditLog{
+			ResourceType: "ImagingStudy",
+			ResourceID:   study.ID,
+			Action:       "UPDATE",
+			OldValues:    mustMarshalJSON(oldStudy),
+			NewValues:    mustMarshalJSON(study),
+		})
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrImagingStudyNotFound
+		}
+		return fmt.Errorf("failed to update imaging study: %w", err)
+	}
+
+	return nil
+}
+
+// ImagingStudySearchParams represents supported imaging study search filters
+type ImagingStudySearchParams struct {
+	Subject string `json:"subject,omitempty"`
+	// Identifier matches the study instance UID, with or without its urn:oid:
+	// prefix, or the accession number
+	Identifier string `json:"identifier,omitempty"`
+	Modality   string `json:"modality,omitempty"`
+}
+
+// whereClause builds the SQL filter for the search parameters using the extracted search columns
+func (p ImagingStudySearchParams) whereClause(tenantID string) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1", "deleted_at IS NULL"}
+	args := []interface{}{tenantID}
+
+	if p.Subject != "" {
+		args = append(args, p.Subject)
+		conditions = append(conditions, fmt.Sprintf("subject_reference = $%d", len(args)))
+	}
+	if p.Identifier != "" {
+		args = append(args, strings.TrimPrefix(p.Identifier, "urn:oid:"), p.Identifier)
+		conditions = append(conditions, fmt.Sprintf("(study_instance_uid = $%d OR accession_number = $%d)", len(args)-1, len(args)))
+	}
+	if p.Modality != "" {
+		args = append(args, pq.Array([]string{p.Modality}))
+		conditions = append(conditions, fmt.Sprintf("modality_codes @> $%d", len(args)))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (r *ImagingStudyRepository) List(ctx context.Context, search ImagingStudySearchParams, params PaginationParams) ([]*models.ImagingStudy, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	where, args := search.whereClause(tenantID)
+
+	countQuery := `SELECT COUNT(*) FROM imaging_studies ` + where
+	var total int64
+	if err := r.db.Reader(ctx).QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get imaging study count: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM imaging_studies
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, imagingStudyColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list imaging studies: %w", err)
+	}
+	defer rows.Close()
+
+	var studies []*models.ImagingStudy
+	for rows.Next() {
+		study, err := scanImagingStudy(rows)
+		if err != nil {
+			return nil, PaginationResult{}, err
+		}
+		studies = append(studies, study)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate imaging studies: %w", err)
+	}
+
+	return studies, GetPaginationResult(total, params), nil
+}
+
+// scanImagingStudy scans imagingStudyColumns, followed by any extra destinations
+func scanImagingStudy(scanner rowScanner, extra ...interface{}) (*models.ImagingStudy, error) {
+	study := &models.ImagingStudy{}
+
+	dest := []interface{}{
+		&study.ID,
+		jsonb(&study.Identifier),
+		&study.Status,
+		jsonb(&study.Modality),
+		jsonb(&study.Subject),
+		&study.Started,
+		&study.NumberOfSeries,
+		&study.NumberOfInstances,
+		&study.Description,
+		jsonb(&study.Series),
+		jsonb(&study.Meta),
+		&study.ImplicitRules,
+		&study.Language,
+		jsonb(&study.Text),
+		jsonb(&study.Contained),
+		jsonb(&study.Extension),
+		jsonb(&study.ModifierExtension),
+		&study.CreatedAt,
+		&study.UpdatedAt,
+		&study.Version,
+	}
+
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return nil, fmt.Errorf("failed to scan imaging study: %w", err)
+	}
+
+	return study, nil
+}
+
+// recordChange writes the audit log entry of a change to the imaging study and its state
+// after the change, as a new history version, through tx, the transaction of the
+// change, so the change is not committed without them
+func (r *ImagingStudyRepository) recordChange(ctx context.Context, tx *sql.Tx, study *models.ImagingStudy, log *AuditLog) error {
+	if err := r.logAudit(ctx, tx, log); err != nil {
+		return err
+	}
+	return r.insertHistory(ctx, tx, &HistoryEntry{
+		ResourceType: "ImagingStudy",
+		ResourceID:   study.ID,
+		Version:      study.Version,
+		Action:       log.Action,
+		Payload:      mustMarshalJSON(study),
+	})
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// PatientStore is the storage contract the service layer depends on for patients.
+// PatientRepository is the PostgreSQL implementation; memory.PatientRepository is an in-memory one.
+type PatientStore interface {
+	Create(ctx context.Context, patient *models.Patient) error
+	BulkCreate(ctx context.Context, patients []*models.Patient) (int, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Patient, error)
+	Update(ctx context.Context, patient *models.Patient) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID) (*models.Patient, error)
+	List(ctx context.Context, search PatientSearchParams, params PaginationParams) ([]*models.Patient, PaginationResult, error)
+	SmartSearch(ctx context.Context, text string, params PaginationParams) ([]*PatientMatch, PaginationResult, error)
+	Each(ctx context.Context, search PatientSearchParams, fn func(*models.Patient) error) error
+}
+
+// ObservationStore is the storage contract the service layer depends on for observations
+type ObservationStore interface {
+	Create(ctx context.Context, observation *models.Observation) error
+	BulkCreate(ctx context.Context, observations []*models.Observation) (int, error)
+	// BulkCreateNew inserts the observations whose ids are not stored yet,
+	// returning how many it inserted
+	BulkCreateNew(ctx context.Context, observations []*models.Observation) (int, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Observation, error)
+	Update(ctx context.Context, observation *models.Observation) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	Restore(ctx context.Context, id uuid.UUID) (*models.Observation, error)
+	List(ctx context.Context, search ObservationSearchParams, params PaginationParams) ([]*models.Observation, PaginationResult, error)
+	Each(ctx context.Context, fn func(*models.Observation) error) error
+	Trend(ctx context.Context, query TrendQuery) ([]TrendBucket, error)
+	Latest(ctx context.Context, subject string, codes []string, since time.Time) (*models.Observation, error)
+	LatestInEncounter(ctx context.Context, subject, encounter string, codes []string) (*models.Observation, error)
+}
+
+// DiagnosticReportStore is the storage contract the service layer depends on for diagnostic reports
+type DiagnosticReportStore interface {
+	Create(ctx context.Context, report *models.DiagnosticReport) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.DiagnosticReport, error)
+	Update(ctx context.Context, report *models.DiagnosticReport) error
+	List(ctx context.Context, search DiagnosticReportSearchParams, params PaginationParams) ([]*models.DiagnosticReport, PaginationResult, error)
+}
+
+// ImagingStudyStore is the storage contract the service layer depends on for imaging studies
+type ImagingStudyStore interface {
+	Create(ctx context.Context, study *models.ImagingStudy) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.ImagingStudy, error)
+	Update(ctx context.Context, study *models.ImagingStudy) error
+	List(ctx context.Context, search ImagingStudySearchParams, params PaginationParams) ([]*models.ImagingStudy, PaginationResult, error)
+}
+
+// TenantStore is the storage contract the service layer depends on for tenants
+type TenantStore interface {
+	Create(ctx context.Context, tenant *models.Tenant) error
+	GetByID(ctx context.Context, id string) (*models.Tenant, error)
+	Update(ctx context.Context, tenant *models.Tenant) error
+	List(ctx context.Context) ([]*models.Tenant, error)
+}
+
+// Compile-time checks that the PostgreSQL repositories satisfy the store interfaces
+var (
+	_ PatientStore          = (*PatientRepository)(nil)
+	_ ObservationStore      = (*ObservationRepository)(nil)
+	_ DiagnosticReportStore = (*DiagnosticReportRepository)(nil)
+	_ ImagingStudyStore     = (*ImagingStudyRepository)(nil)
+	_ TenantStore           = (*TenantRepository)(nil)
+)
@@ -0,0 +1,399 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// JobRepository tracks the status of background jobs. Jobs may belong to a tenant
+// or to the whole deployment, so queries are filtered explicitly rather than
+// scoped by the request tenant.
+type JobRepository struct {
+	*BaseRepository
+}
+
+func NewJobRepository(db *database.DB) *JobRepository {
+	return &JobRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+const jobColumns = `id, tenant_id, job_type, status, priority, payload, run_at, progress, progress_message,
+	retries, max_retries, error, created_at, started_at, finished_at, updated_at`
+
+// JobFilter narrows job listing
+type JobFilter struct {
+	// TenantID restricts results to one tenant's jobs when set
+	TenantID string
+	JobType  string
+	Status   string
+}
+
+func (f JobFilter) whereClause() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if f.TenantID != "" {
+		args = append(args, f.TenantID)
+		conditions = append(conditions, fmt.Sprintf("tenant_id = $%d", len(args)))
+	}
+	if f.JobType != "" {
+		args = append(args, f.JobType)
+		conditions = append(conditions, fmt.Sprintf("job_type = $%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Queued records a job as waiting, with status queued or retrying. Resubmissions
+// (retries and requeues) reuse the record, keeping the last error until the next
+// attempt finishes; a cancelled job stays cancelled.
+func (r *JobRepository) Queued(ctx context.Context, job *models.BackgroundJob) error {
+	query := `
+		INSERT INTO jobs (id, tenant_id, job_type, status, priority, payload, run_at, retries, max_retries, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			status = EXCLUDED.status, priority = EXCLUDED.priority, run_at = EXCLUDED.run_at, retries = EXCLUDED.retries,
+			max_retries = EXCLUDED.max_retries, finished_at = NULL, updated_at = NOW()
+		WHERE jobs.status <> 'cancelled'
+	`
+
+	// JSONB parameters must be sent as text
+	var payload interface{}
+	if len(job.Payload) > 0 {
+		payload = string(job.Payload)
+	}
+
+	_, err := r.db.ExecContext(ctx, query,
+		job.ID,
+		job.TenantID,
+		job.JobType,
+		job.Status,
+		job.Priority,
+		payload,
+		job.RunAt,
+		job.Retries,
+		job.MaxRetries,
+		job.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record queued job: %w", err)
+	}
+
+	return nil
+}
+
+// Transition moves a job to status. Starting an attempt resets its progress;
+// jobErr replaces the stored error, which is cleared on success. Cancelled jobs
+// are left as they are.
+func (r *JobRepository) Transition(ctx context.Context, id, status string, jobErr error) error {
+	var errText *string
+	if jobErr != nil {
+		text := jobErr.Error()
+		errText = &text
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE jobs SET
+			status = $2,
+			error = CASE WHEN $2 = 'succeeded' THEN NULL ELSE COALESCE($3, error) END,
+			progress = CASE WHEN $2 = 'running' THEN 0 WHEN $2 = 'succeeded' THEN 100 ELSE progress END,
+			progress_message = CASE WHEN $2 = 'running' THEN NULL ELSE progress_message END,
+			started_at = CASE WHEN $2 = 'running' THEN NOW() ELSE started_at END,
+			finished_at = CASE WHEN $2 IN ('succeeded', 'failed') THEN NOW() END,
+			updated_at = NOW()
+		WHERE id = $1 AND status <> 'cancelled'
+	`, id, status, errText)
+	if err != nil {
+		return fmt.Errorf("failed to update job status: %w", err)
+	}
+
+	return nil
+}
+
+// Progress records the progress of a running job
+func (r *JobRepository) Progress(ctx context.Context, id string, percent int, message string) error {
+	var messageText *string
+	if message != "" {
+		messageText = &message
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE jobs SET progress = $2, progress_message = $3, updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`, id, percent, messageText)
+	if err != nil {
+		return fmt.Errorf("failed to update job progress: %w", err)
+	}
+
+	return nil
+}
+
+// Discard deletes the record of a job that was never queued
+func (r *JobRepository) Discard(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM jobs WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to discard job: %w", err)
+	}
+	return nil
+}
+
+// AddResult records the outcome of one attempt
+func (r *JobRepository) AddResult(ctx context.Context, result *models.JobResult) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO job_results (job_id, tenant_id, job_type, attempt, success, error, duration_ms, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, result.JobID, result.TenantID, result.JobType, result.Attempt, result.Success,
+		result.Error, result.DurationMS, result.CompletedAt).Scan(&result.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record job result: %w", err)
+	}
+	return nil
+}
+
+// JobResultFilter narrows result listing
+type JobResultFilter struct {
+	// TenantID restricts results to one tenant's jobs when set
+	TenantID string
+	JobID    string
+	JobType  string
+	// Success filters by outcome when set
+	Success *bool
+}
+
+func (f JobResultFilter) whereClause() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if f.TenantID != "" {
+		args = append(args, f.TenantID)
+		conditions = append(conditions, fmt.Sprintf("tenant_id = $%d", len(args)))
+	}
+	if f.JobID != "" {
+		args = append(args, f.JobID)
+		conditions = append(conditions, fmt.Sprintf("job_id = $%d", len(args)))
+	}
+	if f.JobType != "" {
+		args = append(args, f.JobType)
+		conditions = append(conditions, fmt.Sprintf("job_type = $%d", len(args)))
+	}
+	if f.Success != nil {
+		args = append(args, *f.Success)
+		conditions = append(conditions, fmt.Sprintf("success = $%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// ListResults returns attempt results, most recent first
+func (r *JobRepository) ListResults(ctx context.Context, filter JobResultFilter, params PaginationParams) ([]*models.JobResult, PaginationResult, error) {
+	where, args := filter.whereClause()
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM job_results `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get job result count: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, job_id, tenant_id, job_type, attempt, success, error, duration_ms, completed_at
+		FROM job_results
+		%s
+		ORDER BY completed_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list job results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.JobResult
+	for rows.Next() {
+		result := &models.JobResult{}
+		err := rows.Scan(
+			&result.ID,
+			&result.JobID,
+			&result.TenantID,
+			&result.JobType,
+			&result.Attempt,
+			&result.Success,
+			&result.Error,
+			&result.DurationMS,
+			&result.CompletedAt,
+		)
+		if err != nil {
+			return nil, PaginationResult{}, fmt.Errorf("failed to scan job result: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate job results: %w", err)
+	}
+
+	return results, GetPaginationResult(total, params), nil
+}
+
+// PruneHistory deletes attempt results, and jobs that finished, before cutoff
+func (r *JobRepository) PruneHistory(ctx context.Context, cutoff time.Time) (results, jobs int64, err error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM job_results WHERE completed_at < $1`, cutoff)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prune job results: %w", err)
+	}
+	if results, err = result.RowsAffected(); err != nil {
+		return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	result, err = r.db.ExecContext(ctx, `
+		DELETE FROM jobs WHERE status IN ('succeeded', 'failed', 'cancelled') AND finished_at < $1
+	`, cutoff)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to prune jobs: %w", err)
+	}
+	if jobs, err = result.RowsAffected(); err != nil {
+		return 0, 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return results, jobs, nil
+}
+
+// Cancel marks a job that has not finished as cancelled, returning whether it was
+func (r *JobRepository) Cancel(ctx context.Context, id string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE jobs SET status = 'cancelled', finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status IN ('queued', 'running', 'retrying')
+	`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel job: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// Cancelled returns which of ids belong to cancelled jobs
+func (r *JobRepository) Cancelled(ctx context.Context, ids []string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id FROM jobs WHERE id = ANY($1) AND status = 'cancelled'
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check cancelled jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var cancelled []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan job id: %w", err)
+		}
+		cancelled = append(cancelled, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check cancelled jobs: %w", err)
+	}
+
+	return cancelled, nil
+}
+
+func (r *JobRepository) GetByID(ctx context.Context, id string) (*models.BackgroundJob, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+
+	job, err := scanJob(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	return job, nil
+}
+
+// List returns jobs, most recently submitted first
+func (r *JobRepository) List(ctx context.Context, filter JobFilter, params PaginationParams) ([]*models.BackgroundJob, PaginationResult, error) {
+	where, args := filter.whereClause()
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get job count: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s FROM jobs
+		%s
+		ORDER BY created_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, jobColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.BackgroundJob
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, PaginationResult{}, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate jobs: %w", err)
+	}
+
+	return jobs, GetPaginationResult(total, params), nil
+}
+
+func scanJob(scanner rowScanner) (*models.BackgroundJob, error) {
+	job := &models.BackgroundJob{}
+	var payload []byte
+	err := scanner.Scan(
+		&job.ID,
+		&job.TenantID,
+		&job.JobType,
+		&job.Status,
+		&job.Priority,
+		&payload,
+		&job.RunAt,
+		&job.Progress,
+		&job.ProgressMessage,
+		&job.Retries,
+		&job.MaxRetries,
+		&job.Error,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+		&job.UpdatedAt,
+	)
+	job.Payload = payload
+	return job, err
+}
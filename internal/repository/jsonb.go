@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonColumn is an sql.Scanner that decodes a JSONB column straight into dest,
+// so rows can be scanned into model fields without intermediate []byte variables
+type jsonColumn struct {
+	dest interface{}
+}
+
+// jsonb wraps a pointer to a model field for scanning from a JSONB column
+func jsonb(dest interface{}) *jsonColumn {
+	return &jsonColumn{dest: dest}
+}
+
+// Scan implements sql.Scanner. SQL NULL and JSON null leave dest at its zero value.
+func (c *jsonColumn) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSON column", src)
+	}
+
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+
+	if err := json.Unmarshal(data, c.dest); err != nil {
+		return fmt.Errorf("failed to decode JSON column into %T: %w", c.dest, err)
+	}
+
+	return nil
+}
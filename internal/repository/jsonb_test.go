@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"healthcare-api/internal/models"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestJSONColumnScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    []models.HumanName
+		wantErr bool
+	}{
+		{name: "SQL NULL", src: nil, want: nil},
+		{name: "JSON null", src: []byte("null"), want: nil},
+		{name: "empty bytes", src: []byte{}, want: nil},
+		{
+			name: "bytes",
+			src:  []byte(`[{"family":"Smith","given":["Jane","Q"]}]`),
+			want: []models.HumanName{{Family: strPtr("Smith"), Given: []string{"Jane", "Q"}}},
+		},
+		{
+			name: "string",
+			src:  `[{"use":"official","family":"Müller"}]`,
+			want: []models.HumanName{{Use: strPtr("official"), Family: strPtr("Müller")}},
+		},
+		{name: "unsupported source type", src: int64(42), wantErr: true},
+		{name: "malformed JSON", src: []byte(`[{"family":`), wantErr: true},
+		{name: "wrong JSON shape", src: []byte(`{"family":"Smith"}`), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []models.HumanName
+			err := jsonb(&got).Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestJSONColumnRoundTrip writes values the way the repositories do, with
+// toJSON, and reads them back with jsonb
+func TestJSONColumnRoundTrip(t *testing.T) {
+	effective := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	value := 72.5
+
+	tests := []struct {
+		name string
+		// value is a pointer to the field written; zero returns a pointer to a
+		// fresh field of the same type to read it back into
+		value interface{}
+		zero  func() interface{}
+	}{
+		{
+			name:  "empty slice",
+			value: &[]models.Identifier{},
+			zero:  func() interface{} { return &[]models.Identifier{} },
+		},
+		{
+			name: "identifiers",
+			value: &[]models.Identifier{
+				{System: strPtr("urn:healthcare-api:mrn"), Value: strPtr("MRN-1")},
+				{Value: strPtr("no system")},
+			},
+			zero: func() interface{} { return &[]models.Identifier{} },
+		},
+		{
+			name: "nested codeable concept",
+			value: &models.CodeableConcept{
+				Coding: []models.Coding{
+					{System: strPtr("http://loinc.org"), Code: strPtr("8867-4"), Display: strPtr("Heart rate")},
+					{System: strPtr("http://snomed.info/sct"), Code: strPtr("364075005")},
+				},
+				Text: strPtr("Heart rate"),
+			},
+			zero: func() interface{} { return &models.CodeableConcept{} },
+		},
+		{
+			name: "components with nested arrays",
+			value: &[]models.ObservationComponent{
+				{
+					Code:          models.CodeableConcept{Coding: []models.Coding{{Code: strPtr("8480-6")}}},
+					ValueQuantity: &models.Quantity{Value: &value, Unit: strPtr("mm[Hg]")},
+				},
+			},
+			zero: func() interface{} { return &[]models.ObservationComponent{} },
+		},
+		{
+			name:  "period",
+			value: &models.Period{Start: &effective},
+			zero:  func() interface{} { return &models.Period{} },
+		},
+		{
+			name:  "map",
+			value: &map[string]interface{}{"nested": map[string]interface{}{"list": []interface{}{"a", 1.5, true}}},
+			zero:  func() interface{} { return &map[string]interface{}{} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.zero()
+			if err := jsonb(got).Scan(toJSON(tt.value)); err != nil {
+				t.Fatalf("Scan(toJSON()) error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.value) {
+				t.Errorf("round trip = %+v, want %+v", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestToJSONNil(t *testing.T) {
+	if got := string(toJSON(nil)); got != "null" {
+		t.Fatalf("toJSON(nil) = %q, want null", got)
+	}
+
+	var got *models.CodeableConcept
+	if err := jsonb(&got).Scan(toJSON(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("Scan(toJSON(nil)) = %+v, want nil", got)
+	}
+}
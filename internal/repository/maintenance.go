@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+)
+
+// MaintenanceRepository stores the maintenance mode switched through the admin
+// API. It is deployment-wide, a single row, so queries are not tenant-scoped.
+type MaintenanceRepository struct {
+	*BaseRepository
+}
+
+func NewMaintenanceRepository(db *database.DB) *MaintenanceRepository {
+	return &MaintenanceRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Get returns the stored maintenance mode. Forced and RetryAfter are not
+// stored, so they are left unset.
+func (r *MaintenanceRepository) Get(ctx context.Context) (*models.MaintenanceMode, error) {
+	mode := &models.MaintenanceMode{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT enabled, message, updated_by, updated_at FROM maintenance_mode WHERE id
+	`).Scan(&mode.Enabled, &mode.Message, &mode.UpdatedBy, &mode.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance mode: %w", err)
+	}
+	return mode, nil
+}
+
+// Set stores whether maintenance mode is enabled, with its message and who
+// switched it, and sets mode.UpdatedAt
+func (r *MaintenanceRepository) Set(ctx context.Context, mode *models.MaintenanceMode) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO maintenance_mode (id, enabled, message, updated_by, updated_at)
+		VALUES (TRUE, $1, $2, $3, NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			message = EXCLUDED.message,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, mode.Enabled, mode.Message, mode.UpdatedBy).Scan(&mode.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set maintenance mode: %w", err)
+	}
+	return nil
+}
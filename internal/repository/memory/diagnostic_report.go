@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// DiagnosticReportRepository is an in-memory repository.DiagnosticReportStore
+type DiagnosticReportRepository struct {
+	reports *table[models.DiagnosticReport]
+}
+
+func NewDiagnosticReportRepository() *DiagnosticReportRepository {
+	return &DiagnosticReportRepository{
+		reports: newTable("diagnostic report", models.ErrDiagnosticReportNotFound, func(r *models.DiagnosticReport) *models.Resource { return &r.Resource }),
+	}
+}
+
+var _ repository.DiagnosticReportStore = (*DiagnosticReportRepository)(nil)
+
+func (r *DiagnosticReportRepository) Create(ctx context.Context, report *models.DiagnosticReport) error {
+	return r.reports.insert(ctx, report)
+}
+
+func (r *DiagnosticReportRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.DiagnosticReport, error) {
+	return r.reports.get(ctx, id)
+}
+
+func (r *DiagnosticReportRepository) Update(ctx context.Context, report *models.DiagnosticReport) error {
+	return r.reports.update(ctx, report)
+}
+
+func (r *DiagnosticReportRepository) List(ctx context.Context, search repository.DiagnosticReportSearchParams, params repository.PaginationParams) ([]*models.DiagnosticReport, repository.PaginationResult, error) {
+	reports, err := r.reports.live(ctx, matchDiagnosticReport(search))
+	if err != nil {
+		return nil, repository.PaginationResult{}, err
+	}
+
+	reports, pagination := paginate(newestFirst(reports), params)
+	return reports, pagination, nil
+}
+
+// matchDiagnosticReport applies the same filters as DiagnosticReportSearchParams.whereClause
+func matchDiagnosticReport(search repository.DiagnosticReportSearchParams) func(*models.DiagnosticReport) bool {
+	return func(report *models.DiagnosticReport) bool {
+		cols := repository.ExtractDiagnosticReportSearchColumns(report)
+		if search.Subject != "" && (cols.SubjectReference == nil || *cols.SubjectReference != search.Subject) {
+			return false
+		}
+		if search.Code != "" && !contains(cols.CodeValues, search.Code) {
+			return false
+		}
+		if search.Identifier != "" && !hasIdentifierValue(report.Identifier, search.Identifier) {
+			return false
+		}
+		return true
+	}
+}
+
+// hasIdentifierValue reports whether any of identifiers has value
+func hasIdentifierValue(identifiers []models.Identifier, value string) bool {
+	for _, identifier := range identifiers {
+		if identifier.Value != nil && *identifier.Value == value {
+			return true
+		}
+	}
+	return false
+}
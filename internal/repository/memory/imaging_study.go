@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ImagingStudyRepository is an in-memory repository.ImagingStudyStore
+type ImagingStudyRepository struct {
+	studies *table[models.ImagingStudy]
+}
+
+func NewImagingStudyRepository() *ImagingStudyRepository {
+	return &ImagingStudyRepository{
+		studies: newTable("imaging study", models.ErrImagingStudyNotFound, func(s *models.ImagingStudy) *models.Resource { return &s.Resource }),
+	}
+}
+
+var _ repository.ImagingStudyStore = (*ImagingStudyRepository)(nil)
+
+func (r *ImagingStudyRepository) Create(ctx context.Context, study *models.ImagingStudy) error {
+	return r.studies.insert(ctx, study)
+}
+
+func (r *ImagingStudyRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ImagingStudy, error) {
+	return r.studies.get(ctx, id)
+}
+
+func (r *ImagingStudyRepository) Update(ctx context.Context, study *models.ImagingStudy) error {
+	return r.studies.update(ctx, study)
+}
+
+func (r *ImagingStudyRepository) List(ctx context.Context, search repository.ImagingStudySearchParams, params repository.PaginationParams) ([]*models.ImagingStudy, repository.PaginationResult, error) {
+	studies, err := r.studies.live(ctx, matchImagingStudy(search))
+	if err != nil {
+		return nil, repository.PaginationResult{}, err
+	}
+
+	studies, pagination := paginate(newestFirst(studies), params)
+	return studies, pagination, nil
+}
+
+// matchImagingStudy applies the same filters as ImagingStudySearchParams.whereClause
+func matchImagingStudy(search repository.ImagingStudySearchParams) func(*models.ImagingStudy) bool {
+	return func(study *models.ImagingStudy) bool {
+		cols := repository.ExtractImagingStudySearchColumns(study)
+		if search.Subject != "" && (cols.SubjectReference == nil || *cols.SubjectReference != search.Subject) {
+			return false
+		}
+		if search.Identifier != "" && cols.StudyInstanceUID != strings.TrimPrefix(search.Identifier, "urn:oid:") &&
+			(cols.AccessionNumber == nil || *cols.AccessionNumber != search.Identifier) {
+			return false
+		}
+		if search.Modality != "" && !contains(cols.ModalityCodes, search.Modality) {
+			return false
+		}
+		return true
+	}
+}
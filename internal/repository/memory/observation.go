@@ -0,0 +1,219 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ObservationRepository is an in-memory repository.ObservationStore
+type ObservationRepository struct {
+	observations *table[models.Observation]
+}
+
+func NewObservationRepository() *ObservationRepository {
+	return &ObservationRepository{
+		observations: newTable("observation", models.ErrObservationNotFound, func(o *models.Observation) *models.Resource { return &o.Resource }),
+	}
+}
+
+var _ repository.ObservationStore = (*ObservationRepository)(nil)
+
+func (r *ObservationRepository) Create(ctx context.Context, observation *models.Observation) error {
+	return r.observations.insert(ctx, observation)
+}
+
+func (r *ObservationRepository) BulkCreate(ctx context.Context, observations []*models.Observation) (int, error) {
+	if err := r.observations.insert(ctx, observations...); err != nil {
+		return 0, err
+	}
+	return len(observations), nil
+}
+
+func (r *ObservationRepository) BulkCreateNew(ctx context.Context, observations []*models.Observation) (int, error) {
+	return r.observations.insertNew(ctx, observations...)
+}
+
+func (r *ObservationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Observation, error) {
+	return r.observations.get(ctx, id)
+}
+
+func (r *ObservationRepository) Update(ctx context.Context, observation *models.Observation) error {
+	return r.observations.update(ctx, observation)
+}
+
+func (r *ObservationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.observations.setDeleted(ctx, id, true)
+	return err
+}
+
+func (r *ObservationRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Observation, error) {
+	return r.observations.setDeleted(ctx, id, false)
+}
+
+func (r *ObservationRepository) List(ctx context.Context, search repository.ObservationSearchParams, params repository.PaginationParams) ([]*models.Observation, repository.PaginationResult, error) {
+	observations, err := r.observations.live(ctx, matchObservation(search))
+	if err != nil {
+		return nil, repository.PaginationResult{}, err
+	}
+
+	observations, pagination := paginate(newestFirst(observations), params)
+	return observations, pagination, nil
+}
+
+func (r *ObservationRepository) Each(ctx context.Context, fn func(*models.Observation) error) error {
+	observations, err := r.observations.live(ctx, matchObservation(repository.ObservationSearchParams{}))
+	if err != nil {
+		return err
+	}
+	return each(observations, fn)
+}
+
+// Trend aggregates like ObservationRepository.Trend
+func (r *ObservationRepository) Trend(ctx context.Context, query repository.TrendQuery) ([]repository.TrendBucket, error) {
+	observations, err := r.observations.live(ctx, matchObservation(repository.ObservationSearchParams{
+		Subject: query.Subject,
+		Code:    query.Code,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		unit  string
+		start int64
+	}
+	sums := map[key]float64{}
+	buckets := map[key]*repository.TrendBucket{}
+	width := int64(query.Bucket / time.Second)
+	for _, observation := range observations {
+		effective := repository.ExtractObservationSearchColumns(observation).EffectiveDate
+		value := observation.ValueQuantity
+		if effective == nil || effective.Before(query.From) || !effective.Before(query.To) ||
+			value == nil || value.Value == nil || value.Comparator != nil {
+			continue
+		}
+
+		unit := ""
+		if value.Code != nil {
+			unit = *value.Code
+		} else if value.Unit != nil {
+			unit = *value.Unit
+		}
+		seconds := effective.Unix()
+		start := seconds - ((seconds%width)+width)%width
+		k := key{unit, start}
+		v := *value.Value
+
+		bucket, ok := buckets[k]
+		if !ok {
+			bucket = &repository.TrendBucket{Unit: unit, Start: time.Unix(start, 0).UTC(), Min: v, Max: v}
+			buckets[k] = bucket
+		}
+		bucket.Count++
+		sums[k] += v
+		bucket.Min = math.Min(bucket.Min, v)
+		bucket.Max = math.Max(bucket.Max, v)
+	}
+
+	result := make([]repository.TrendBucket, 0, len(buckets))
+	for k, bucket := range buckets {
+		bucket.Avg = sums[k] / float64(bucket.Count)
+		result = append(result, *bucket)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Unit != result[j].Unit {
+			return result[i].Unit < result[j].Unit
+		}
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result, nil
+}
+
+// Latest finds the observation like ObservationRepository.Latest
+func (r *ObservationRepository) Latest(ctx context.Context, subject string, codes []string, since time.Time) (*models.Observation, error) {
+	observations, err := r.observations.live(ctx, matchObservation(repository.ObservationSearchParams{Subject: subject}))
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *models.Observation
+	var latestAt time.Time
+	for _, observation := range observations {
+		cols := repository.ExtractObservationSearchColumns(observation)
+		if cols.EffectiveDate == nil || cols.EffectiveDate.Before(since) ||
+			observation.Status == "entered-in-error" || observation.Status == "cancelled" {
+			continue
+		}
+		matches := false
+		for _, code := range codes {
+			matches = matches || contains(cols.CodeValues, code)
+		}
+		// Observations come oldest first, so a later one of the same time wins
+		if matches && (latest == nil || !cols.EffectiveDate.Before(latestAt)) {
+			latest, latestAt = observation, *cols.EffectiveDate
+		}
+	}
+	if latest == nil {
+		return nil, models.ErrObservationNotFound
+	}
+	return latest, nil
+}
+
+// LatestInEncounter finds the observation like ObservationRepository.LatestInEncounter
+func (r *ObservationRepository) LatestInEncounter(ctx context.Context, subject, encounter string, codes []string) (*models.Observation, error) {
+	observations, err := r.observations.live(ctx, matchObservation(repository.ObservationSearchParams{Subject: subject}))
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *models.Observation
+	var latestAt *time.Time
+	for _, observation := range observations {
+		if observation.Encounter == nil || observation.Encounter.Reference == nil || *observation.Encounter.Reference != encounter ||
+			observation.Status == "entered-in-error" || observation.Status == "cancelled" {
+			continue
+		}
+		cols := repository.ExtractObservationSearchColumns(observation)
+		matches := false
+		for _, code := range codes {
+			matches = matches || contains(cols.CodeValues, code)
+		}
+		if !matches {
+			continue
+		}
+		// Observations come oldest first, so a later one of the same time wins,
+		// and one without an effective time only wins over others without
+		if latest == nil || (cols.EffectiveDate == nil && latestAt == nil) ||
+			(cols.EffectiveDate != nil && (latestAt == nil || !cols.EffectiveDate.Before(*latestAt))) {
+			latest, latestAt = observation, cols.EffectiveDate
+		}
+	}
+	if latest == nil {
+		return nil, models.ErrObservationNotFound
+	}
+	return latest, nil
+}
+
+// matchObservation applies the same filters as ObservationSearchParams.whereClause
+func matchObservation(search repository.ObservationSearchParams) func(*models.Observation) bool {
+	return func(observation *models.Observation) bool {
+		cols := repository.ExtractObservationSearchColumns(observation)
+		if search.Subject != "" && (cols.SubjectReference == nil || *cols.SubjectReference != search.Subject) {
+			return false
+		}
+		if search.Code != "" && !contains(cols.CodeValues, search.Code) {
+			return false
+		}
+		if search.IDs != nil && !containsID(search.IDs, observation.ID) {
+			return false
+		}
+		return true
+	}
+}
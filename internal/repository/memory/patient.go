@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// PatientRepository is an in-memory repository.PatientStore
+type PatientRepository struct {
+	patients *table[models.Patient]
+}
+
+func NewPatientRepository() *PatientRepository {
+	patients := newTable("patient", models.ErrPatientNotFound, func(p *models.Patient) *models.Resource { return &p.Resource })
+	patients.check = checkPatientIdentifiers
+	return &PatientRepository{
+		patients: patients,
+	}
+}
+
+var _ repository.PatientStore = (*PatientRepository)(nil)
+
+// checkPatientIdentifiers mirrors the patient_identifiers unique constraint: an identifier
+// may only be held by one patient per tenant, including soft-deleted patients
+func checkPatientIdentifiers(rows map[uuid.UUID]*models.Patient, patients []*models.Patient) error {
+	if conflict := repository.FindBatchIdentifierConflict(patients); conflict != nil {
+		return conflict
+	}
+
+	claimed := make(map[repository.IdentifierKey]uuid.UUID)
+	for _, patient := range patients {
+		for _, key := range repository.PatientIdentifierKeys(patient) {
+			claimed[key] = patient.ID
+		}
+	}
+
+	// A patient being rewritten may keep its own identifiers
+	for id, stored := range rows {
+		for _, key := range repository.PatientIdentifierKeys(stored) {
+			if owner, ok := claimed[key]; ok && owner != id {
+				return &models.IdentifierConflictError{System: key.System, Value: key.Value, ResourceID: id}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *PatientRepository) Create(ctx context.Context, patient *models.Patient) error {
+	return r.patients.insert(ctx, patient)
+}
+
+func (r *PatientRepository) BulkCreate(ctx context.Context, patients []*models.Patient) (int, error) {
+	if err := r.patients.insert(ctx, patients...); err != nil {
+		return 0, err
+	}
+	return len(patients), nil
+}
+
+func (r *PatientRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Patient, error) {
+	return r.patients.get(ctx, id)
+}
+
+func (r *PatientRepository) Update(ctx context.Context, patient *models.Patient) error {
+	return r.patients.update(ctx, patient)
+}
+
+func (r *PatientRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.patients.setDeleted(ctx, id, true)
+	return err
+}
+
+func (r *PatientRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Patient, error) {
+	return r.patients.setDeleted(ctx, id, false)
+}
+
+func (r *PatientRepository) List(ctx context.Context, search repository.PatientSearchParams, params repository.PaginationParams) ([]*models.Patient, repository.PaginationResult, error) {
+	patients, err := r.patients.live(ctx, matchPatient(search))
+	if err != nil {
+		return nil, repository.PaginationResult{}, err
+	}
+
+	patients, pagination := paginate(newestFirst(patients), params)
+	return patients, pagination, nil
+}
+
+// SmartSearch approximates the PostgreSQL ranking: a patient matches when any search
+// term prefixes a word of its demographic text, scored by the fraction of terms matched
+func (r *PatientRepository) SmartSearch(ctx context.Context, text string, params repository.PaginationParams) ([]*repository.PatientMatch, repository.PaginationResult, error) {
+	terms := strings.Fields(strings.ToLower(text))
+	if len(terms) == 0 {
+		return nil, repository.GetPaginationResult(0, params), nil
+	}
+
+	scores := make(map[uuid.UUID]float64)
+	patients, err := r.patients.live(ctx, func(patient *models.Patient) bool {
+		words := strings.Fields(repository.ExtractPatientSearchColumns(patient).SearchText)
+		matched := 0
+		for _, term := range terms {
+			for _, word := range words {
+				if strings.HasPrefix(word, term) {
+					matched++
+					break
+				}
+			}
+		}
+		scores[patient.ID] = float64(matched) / float64(len(terms))
+		return matched > 0
+	})
+	if err != nil {
+		return nil, repository.PaginationResult{}, err
+	}
+
+	// Newest first within equal scores, as in the SQL ORDER BY
+	patients = newestFirst(patients)
+	sort.SliceStable(patients, func(i, j int) bool {
+		return scores[patients[i].ID] > scores[patients[j].ID]
+	})
+
+	matches := make([]*repository.PatientMatch, len(patients))
+	for i, patient := range patients {
+		matches[i] = &repository.PatientMatch{Patient: patient, Score: scores[patient.ID]}
+	}
+
+	matches, pagination := paginate(matches, params)
+	return matches, pagination, nil
+}
+
+func (r *PatientRepository) Each(ctx context.Context, search repository.PatientSearchParams, fn func(*models.Patient) error) error {
+	patients, err := r.patients.live(ctx, matchPatient(search))
+	if err != nil {
+		return err
+	}
+	return each(patients, fn)
+}
+
+// matchPatient applies the same filters as PatientSearchParams.whereClause
+func matchPatient(search repository.PatientSearchParams) func(*models.Patient) bool {
+	family := strings.ToLower(search.Family)
+	return func(patient *models.Patient) bool {
+		cols := repository.ExtractPatientSearchColumns(patient)
+		if family != "" && (cols.FamilyName == nil || !strings.HasPrefix(*cols.FamilyName, family)) {
+			return false
+		}
+		if search.Identifier != "" && !contains(cols.IdentifierValues, search.Identifier) {
+			return false
+		}
+		if search.IDs != nil && !containsID(search.IDs, patient.ID) {
+			return false
+		}
+		return true
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,306 @@
+// Package memory provides in-memory implementations of the repository store
+// interfaces, for unit tests and for running services without a database.
+// Data is kept per tenant and is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+)
+
+// table stores FHIR resources keyed by tenant and id. Values are deep-copied
+// on the way in and out so callers never share state with the store.
+type table[T any] struct {
+	mu       sync.RWMutex
+	rows     map[string]map[uuid.UUID]*T
+	resource func(*T) *models.Resource
+	name     string
+	notFound error
+
+	// check, when set, validates incoming values against the tenant's stored rows
+	// (live and deleted) before a write, like a unique constraint would
+	check func(rows map[uuid.UUID]*T, values []*T) error
+}
+
+func newTable[T any](name string, notFound error, resource func(*T) *models.Resource) *table[T] {
+	return &table[T]{
+		rows:     make(map[string]map[uuid.UUID]*T),
+		resource: resource,
+		name:     name,
+		notFound: notFound,
+	}
+}
+
+// tenantID mirrors BaseRepository.tenantID: unscoped access is rejected
+func tenantID(ctx context.Context) (string, error) {
+	tenantID := requestctx.TenantID(ctx)
+	if tenantID == "" {
+		return "", models.ErrTenantRequired
+	}
+	return tenantID, nil
+}
+
+// insert stores new resources, stamping bookkeeping fields the database would otherwise default
+func (t *table[T]) insert(ctx context.Context, values ...*T) error {
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rows := t.rows[tenantID]
+	if rows == nil {
+		rows = make(map[uuid.UUID]*T)
+		t.rows[tenantID] = rows
+	}
+
+	// Check every id up front so a batch is all-or-nothing, like a transaction
+	for _, value := range values {
+		if _, exists := rows[t.resource(value).ID]; exists {
+			return fmt.Errorf("failed to create %s: duplicate id %s", t.name, t.resource(value).ID)
+		}
+	}
+	if t.check != nil {
+		if err := t.check(rows, values); err != nil {
+			return err
+		}
+	}
+
+	t.put(rows, values)
+	return nil
+}
+
+// insertNew stores the resources whose ids are not stored yet, live or deleted,
+// and returns how many it stored, like INSERT ... ON CONFLICT DO NOTHING
+func (t *table[T]) insertNew(ctx context.Context, values ...*T) (int, error) {
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rows := t.rows[tenantID]
+	if rows == nil {
+		rows = make(map[uuid.UUID]*T)
+		t.rows[tenantID] = rows
+	}
+
+	var fresh []*T
+	seen := make(map[uuid.UUID]bool, len(values))
+	for _, value := range values {
+		id := t.resource(value).ID
+		if _, exists := rows[id]; exists || seen[id] {
+			continue
+		}
+		seen[id] = true
+		fresh = append(fresh, value)
+	}
+	if t.check != nil {
+		if err := t.check(rows, fresh); err != nil {
+			return 0, err
+		}
+	}
+
+	t.put(rows, fresh)
+	return len(fresh), nil
+}
+
+// put stamps the bookkeeping fields the database would otherwise default and stores copies of values
+func (t *table[T]) put(rows map[uuid.UUID]*T, values []*T) {
+	now := time.Now().UTC()
+	for _, value := range values {
+		resource := t.resource(value)
+		if resource.CreatedAt.IsZero() {
+			resource.CreatedAt = now
+		}
+		if resource.UpdatedAt.IsZero() {
+			resource.UpdatedAt = now
+		}
+		if resource.Version == 0 {
+			resource.Version = 1
+		}
+		resource.DeletedAt = nil
+		rows[resource.ID] = clone(value)
+	}
+}
+
+// get returns a copy of a live resource, or ErrResourceDeleted for a soft-deleted one
+func (t *table[T]) get(ctx context.Context, id uuid.UUID) (*T, error) {
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	stored, ok := t.rows[tenantID][id]
+	if !ok {
+		return nil, t.notFound
+	}
+	if t.resource(stored).DeletedAt != nil {
+		return nil, models.ErrResourceDeleted
+	}
+
+	return clone(stored), nil
+}
+
+// update replaces a live resource, bumping its version and updated_at like the database trigger
+func (t *table[T]) update(ctx context.Context, value *T) error {
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	resource := t.resource(value)
+	stored, ok := t.rows[tenantID][resource.ID]
+	if !ok {
+		return t.notFound
+	}
+	if t.resource(stored).DeletedAt != nil {
+		return models.ErrResourceDeleted
+	}
+	if t.check != nil {
+		if err := t.check(t.rows[tenantID], []*T{value}); err != nil {
+			return err
+		}
+	}
+
+	resource.CreatedAt = t.resource(stored).CreatedAt
+	resource.UpdatedAt = time.Now().UTC()
+	resource.Version = t.resource(stored).Version + 1
+	resource.DeletedAt = nil
+	t.rows[tenantID][resource.ID] = clone(value)
+
+	return nil
+}
+
+// setDeleted soft-deletes or restores a resource, returning a copy of the result
+func (t *table[T]) setDeleted(ctx context.Context, id uuid.UUID, deleted bool) (*T, error) {
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stored, ok := t.rows[tenantID][id]
+	if !ok {
+		return nil, t.notFound
+	}
+
+	// Deleting a deleted resource reports it as gone; restoring a live one finds nothing to restore
+	switch isDeleted := t.resource(stored).DeletedAt != nil; {
+	case deleted && isDeleted:
+		return nil, models.ErrResourceDeleted
+	case !deleted && !isDeleted:
+		return nil, t.notFound
+	}
+
+	resource := t.resource(stored)
+	resource.DeletedAt = nil
+	if deleted {
+		now := time.Now().UTC()
+		resource.DeletedAt = &now
+	}
+	resource.UpdatedAt = time.Now().UTC()
+	resource.Version++
+
+	return clone(stored), nil
+}
+
+// live returns copies of the tenant's live resources accepted by match, oldest first
+func (t *table[T]) live(ctx context.Context, match func(*T) bool) ([]*T, error) {
+	tenantID, err := tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.RLock()
+	var values []*T
+	for _, stored := range t.rows[tenantID] {
+		if t.resource(stored).DeletedAt == nil && match(stored) {
+			values = append(values, clone(stored))
+		}
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(values, func(i, j int) bool {
+		a, b := t.resource(values[i]), t.resource(values[j])
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+
+	return values, nil
+}
+
+// newestFirst reverses values from live into the created_at DESC order used by listings
+func newestFirst[T any](values []*T) []*T {
+	for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+		values[i], values[j] = values[j], values[i]
+	}
+	return values
+}
+
+// paginate applies LIMIT/OFFSET semantics to already ordered values
+func paginate[T any](values []*T, params repository.PaginationParams) ([]*T, repository.PaginationResult) {
+	total := len(values)
+
+	start := params.Offset
+	if start > total {
+		start = total
+	}
+	end := start + params.Limit
+	if end > total {
+		end = total
+	}
+
+	return values[start:end], repository.GetPaginationResult(int64(total), params)
+}
+
+// each passes values to fn in order, treating repository.ErrStopIteration as a clean stop
+func each[T any](values []*T, fn func(*T) error) error {
+	for _, value := range values {
+		if err := fn(value); err != nil {
+			if errors.Is(err, repository.ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// clone deep-copies a resource through its JSON representation
+func clone[T any](value *T) *T {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(fmt.Sprintf("memory: failed to copy resource: %v", err))
+	}
+	copied := new(T)
+	if err := json.Unmarshal(data, copied); err != nil {
+		panic(fmt.Sprintf("memory: failed to copy resource: %v", err))
+	}
+	return copied
+}
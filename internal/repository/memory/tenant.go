@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+)
+
+// TenantRepository is an in-memory repository.TenantStore. Tenants are global, so it is not tenant-scoped.
+type TenantRepository struct {
+	mu      sync.RWMutex
+	tenants map[string]models.Tenant
+}
+
+func NewTenantRepository() *TenantRepository {
+	return &TenantRepository{
+		tenants: make(map[string]models.Tenant),
+	}
+}
+
+var _ repository.TenantStore = (*TenantRepository)(nil)
+
+func (r *TenantRepository) Create(ctx context.Context, tenant *models.Tenant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tenants[tenant.ID]; exists {
+		return models.ErrTenantExists
+	}
+
+	now := time.Now().UTC()
+	tenant.CreatedAt = now
+	tenant.UpdatedAt = now
+	r.tenants[tenant.ID] = *tenant
+
+	return nil
+}
+
+func (r *TenantRepository) GetByID(ctx context.Context, id string) (*models.Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant, ok := r.tenants[id]
+	if !ok {
+		return nil, models.ErrTenantNotFound
+	}
+
+	return &tenant, nil
+}
+
+func (r *TenantRepository) Update(ctx context.Context, tenant *models.Tenant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.tenants[tenant.ID]
+	if !ok {
+		return models.ErrTenantNotFound
+	}
+
+	tenant.CreatedAt = stored.CreatedAt
+	tenant.UpdatedAt = time.Now().UTC()
+	r.tenants[tenant.ID] = *tenant
+
+	return nil
+}
+
+func (r *TenantRepository) List(ctx context.Context) ([]*models.Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenants := make([]*models.Tenant, 0, len(r.tenants))
+	for _, tenant := range r.tenants {
+		tenant := tenant
+		tenants = append(tenants, &tenant)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+
+	return tenants, nil
+}
@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+)
+
+// MergeAction is the audit and history action recorded for the changes of a patient merge
+const MergeAction = "MERGE"
+
+// MergedResource is a resource a patient merge re-pointed from the source
+// patient to the target
+type MergedResource struct {
+	ResourceType string
+	ID           uuid.UUID
+}
+
+// PatientMerge is the outcome of merging a source patient into a target
+type PatientMerge struct {
+	Source    *models.Patient
+	Target    *models.Patient
+	Resources []MergedResource
+	// Tasks is the number of critical result tasks re-pointed
+	Tasks int64
+}
+
+// Merge merges the source patient into the target in one transaction. The
+// observations, diagnostic reports, imaging studies and critical result tasks
+// of the source are re-pointed at the target; the source is marked inactive and
+// replaced-by the target, which replaces it; a duplicate candidate pairing the
+// two is confirmed. Every changed resource gets a MERGE history version and
+// audit log entry. Patients already replaced by another return
+// models.ErrPatientMerged.
+func (r *PatientRepository) Merge(ctx context.Context, sourceID, targetID uuid.UUID) (*PatientMerge, error) {
+	ctx = database.WithPrimary(ctx)
+
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var merge *PatientMerge
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		merge = &PatientMerge{}
+
+		source, target, err := lockMergePatients(ctx, tx, tenantID, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+		merge.Source, merge.Target = source, target
+
+		from, to := "Patient/"+sourceID.String(), "Patient/"+targetID.String()
+		oldSubject := mustMarshalJSON(map[string]interface{}{"subject": models.Reference{Reference: &from}})
+		newSubject := mustMarshalJSON(map[string]interface{}{"subject": models.Reference{Reference: &to}})
+
+		observations, err := repointSubjects(ctx, tx, "observations", observationColumns, tenantID, from, to, scanObservation)
+		if err != nil {
+			return err
+		}
+		for _, observation := range observations {
+			if err := r.recordMerged(ctx, tx, merge, "Observation", observation.Resource, observation, oldSubject, newSubject); err != nil {
+				return err
+			}
+		}
+
+		reports, err := repointSubjects(ctx, tx, "diagnostic_reports", diagnosticReportColumns, tenantID, from, to, scanDiagnosticReport)
+		if err != nil {
+			return err
+		}
+		for _, report := range reports {
+			if err := r.recordMerged(ctx, tx, merge, "DiagnosticReport", report.Resource, report, oldSubject, newSubject); err != nil {
+				return err
+			}
+		}
+
+		studies, err := repointSubjects(ctx, tx, "imaging_studies", imagingStudyColumns, tenantID, from, to, scanImagingStudy)
+		if err != nil {
+			return err
+		}
+		for _, study := range studies {
+			if err := r.recordMerged(ctx, tx, merge, "ImagingStudy", study.Resource, study, oldSubject, newSubject); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			UPDATE critical_value_tasks SET subject = $3 WHERE tenant_id = $1 AND subject = $2
+		`, tenantID, from, to)
+		if err != nil {
+			return fmt.Errorf("failed to re-point critical result tasks: %w", err)
+		}
+		if merge.Tasks, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE patient_duplicates SET status = $4, reviewed_at = NOW(), reviewed_by = NULLIF($5, ''),
+				note = COALESCE(note, 'Merged')
+			WHERE tenant_id = $1 AND patient_id = LEAST($2::uuid, $3::uuid) AND duplicate_id = GREATEST($2::uuid, $3::uuid)
+			  AND status <> $4
+		`, tenantID, sourceID, targetID, models.DuplicateConfirmed, requestctx.UserID(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to confirm patient duplicate: %w", err)
+		}
+
+		inactive := false
+		if err := r.updateMergedPatient(ctx, tx, tenantID, source, &inactive, patientLink(to, "replaced-by")); err != nil {
+			return err
+		}
+		return r.updateMergedPatient(ctx, tx, tenantID, target, target.Active, patientLink(from, "replaces"))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return merge, nil
+}
+
+// lockMergePatients reads and locks the source and target patients, in id order
+// so concurrent merges of the same pair cannot deadlock
+func lockMergePatients(ctx context.Context, tx *sql.Tx, tenantID string, sourceID, targetID uuid.UUID) (*models.Patient, *models.Patient, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+patientColumns+`, deleted_at
+		FROM patients
+		WHERE tenant_id = $1 AND id IN ($2, $3)
+		ORDER BY id
+		FOR UPDATE
+	`, tenantID, sourceID, targetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock patients to merge: %w", err)
+	}
+	defer rows.Close()
+
+	var source, target *models.Patient
+	for rows.Next() {
+		var deletedAt *time.Time
+		patient, err := scanPatient(rows, &deletedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if deletedAt != nil {
+			return nil, nil, models.ErrResourceDeleted
+		}
+		if patient.ID == sourceID {
+			source = patient
+		} else {
+			target = patient
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to iterate patients to merge: %w", err)
+	}
+
+	if source == nil || target == nil {
+		return nil, nil, models.ErrPatientNotFound
+	}
+	for _, patient := range []*models.Patient{source, target} {
+		for _, link := range patient.Link {
+			if link.Type == "replaced-by" {
+				return nil, nil, models.ErrPatientMerged
+			}
+		}
+	}
+
+	return source, target, nil
+}
+
+// repointSubjects points the subject of every row of table referring to from,
+// soft-deleted ones included, at to, returning the updated resources
+func repointSubjects[T any](ctx context.Context, tx *sql.Tx, table, columns, tenantID, from, to string, scan func(rowScanner, ...interface{}) (*T, error)) ([]*T, error) {
+	query := fmt.Sprintf(`
+		UPDATE %s SET subject = jsonb_set(subject, '{reference}', to_jsonb($3::text)), subject_reference = $3
+		WHERE tenant_id = $1 AND subject_reference = $2
+		RETURNING %s
+	`, table, columns)
+
+	rows, err := tx.QueryContext(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-point %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var resources []*T
+	for rows.Next() {
+		resource, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate re-pointed %s: %w", table, err)
+	}
+
+	return resources, nil
+}
+
+// recordMerged records the history version and audit log entry of a resource
+// re-pointed by a merge, adding it to the merge's resources
+func (r *PatientRepository) recordMerged(ctx context.Context, tx *sql.Tx, merge *PatientMerge, resourceType string, meta models.Resource, resource interface{}, oldValues, newValues json.RawMessage) error {
+	err := r.insertHistory(ctx, tx, &HistoryEntry{
+		ResourceType: resourceType,
+		ResourceID:   meta.ID,
+		Version:      meta.Version,
+		Action:       MergeAction,
+		Payload:      mustMarshalJSON(resource),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.logAudit(ctx, tx, &AuditLog{
+		ResourceType: resourceType,
+		ResourceID:   meta.ID,
+		Action:       MergeAction,
+		OldValues:    oldValues,
+		NewValues:    newValues,
+	})
+	if err != nil {
+		return err
+	}
+
+	merge.Resources = append(merge.Resources, MergedResource{ResourceType: resourceType, ID: meta.ID})
+	return nil
+}
+
+// updateMergedPatient stores the active flag and an added link of a merged
+// patient, with its history version and audit log entry
+func (r *PatientRepository) updateMergedPatient(ctx context.Context, tx *sql.Tx, tenantID string, patient *models.Patient, active *bool, link models.PatientLink) error {
+	oldValues := mustMarshalJSON(patient)
+	patient.Active = active
+	patient.Link = append(patient.Link, link)
+
+	err := tx.QueryRowContext(ctx, `
+		UPDATE patients SET active = $3, link = $4
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING updated_at, version
+	`, patient.ID, tenantID, patient.Active, toJSON(patient.Link)).Scan(&patient.UpdatedAt, &patient.Version)
+	if err != nil {
+		return fmt.Errorf("failed to update merged patient: %w", err)
+	}
+
+	err = r.insertHistory(ctx, tx, &HistoryEntry{
+		ResourceType: "Patient",
+		ResourceID:   patient.ID,
+		Version:      patient.Version,
+		Action:       MergeAction,
+		Payload:      mustMarshalJSON(patient),
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.logAudit(ctx, tx, &AuditLog{
+		ResourceType: "Patient",
+		ResourceID:   patient.ID,
+		Action:       MergeAction,
+		OldValues:    oldValues,
+		NewValues:    mustMarshalJSON(patient),
+	})
+}
+
+// patientLink returns a link of the given type to the patient reference
+func patientLink(reference, linkType string) models.PatientLink {
+	return models.PatientLink{Other: models.Reference{Reference: &reference}, Type: linkType}
+}
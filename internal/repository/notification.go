@@ -0,0 +1,512 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// NotificationRepository manages the tenant's notification recipients and the
+// notifications queued for them
+type NotificationRepository struct {
+	*BaseRepository
+}
+
+func NewNotificationRepository(db *database.DB) *NotificationRepository {
+	return &NotificationRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+const notificationRecipientColumns = `id, name, email, phone, kinds, quiet_hours_start, quiet_hours_end,
+			   quiet_hours_time_zone, escalate_to, escalate_after_minutes, active, created_at, updated_at`
+
+const notificationColumns = `id, tenant_id, kind, channel, recipient_id, address, reference, subject, text_body, html_body,
+			   status, attempts, last_error, provider_message_id, deliver_after, escalate_at, escalated_at,
+			   acknowledged_at, acknowledged_by, created_at, updated_at, sent_at`
+
+func (r *NotificationRepository) CreateRecipient(ctx context.Context, recipient *models.NotificationRecipient) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	quietStart, quietEnd, timeZone, escalateTo, escalateAfter := recipientPaging(recipient)
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO notification_recipients (id, tenant_id, name, email, phone, kinds, quiet_hours_start,
+			quiet_hours_end, quiet_hours_time_zone, escalate_to, escalate_after_minutes, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING created_at, updated_at
+	`, recipient.ID, tenantID, recipient.Name, recipient.Email, recipient.Phone, pq.Array(recipient.Kinds),
+		quietStart, quietEnd, timeZone, escalateTo, escalateAfter,
+		recipient.Active).Scan(&recipient.CreatedAt, &recipient.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification recipient: %w", err)
+	}
+	return nil
+}
+
+func (r *NotificationRepository) GetRecipient(ctx context.Context, id uuid.UUID) (*models.NotificationRecipient, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + notificationRecipientColumns + ` FROM notification_recipients WHERE id = $1 AND tenant_id = $2`
+	recipient, err := scanNotificationRecipient(r.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNotificationRecipientNotFound
+		}
+		return nil, err
+	}
+	return recipient, nil
+}
+
+// ListRecipients returns the tenant's notification recipients, oldest first;
+// activeOnly skips paused ones
+func (r *NotificationRepository) ListRecipients(ctx context.Context, activeOnly bool) ([]*models.NotificationRecipient, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + notificationRecipientColumns + ` FROM notification_recipients WHERE tenant_id = $1`
+	if activeOnly {
+		query += ` AND active`
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var recipients []*models.NotificationRecipient
+	for rows.Next() {
+		recipient, err := scanNotificationRecipient(rows)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate notification recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+func (r *NotificationRepository) UpdateRecipient(ctx context.Context, recipient *models.NotificationRecipient) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	quietStart, quietEnd, timeZone, escalateTo, escalateAfter := recipientPaging(recipient)
+	err = r.db.QueryRowContext(ctx, `
+		UPDATE notification_recipients SET name = $3, email = $4, phone = $5, kinds = $6,
+			quiet_hours_start = $7, quiet_hours_end = $8, quiet_hours_time_zone = $9,
+			escalate_to = $10, escalate_after_minutes = $11, active = $12
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING updated_at
+	`, recipient.ID, tenantID, recipient.Name, recipient.Email, recipient.Phone, pq.Array(recipient.Kinds),
+		quietStart, quietEnd, timeZone, escalateTo, escalateAfter,
+		recipient.Active).Scan(&recipient.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNotificationRecipientNotFound
+		}
+		return fmt.Errorf("failed to update notification recipient: %w", err)
+	}
+	return nil
+}
+
+// DeleteRecipient removes a notification recipient; its notifications are kept
+func (r *NotificationRepository) DeleteRecipient(ctx context.Context, id uuid.UUID) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM notification_recipients WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification recipient: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return models.ErrNotificationRecipientNotFound
+	}
+	return nil
+}
+
+// Enqueue records a notification, queued or, for a page held over quiet hours,
+// held. A notification of the same event to the same address is recorded
+// once: when one exists, it is read into notification instead, with its
+// current status.
+func (r *NotificationRepository) Enqueue(ctx context.Context, notification *models.Notification) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if notification.Status == "" {
+		notification.Status = models.NotificationQueued
+	}
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO notifications (id, tenant_id, kind, channel, recipient_id, address, reference,
+			subject, text_body, html_body, status, deliver_after, escalate_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (tenant_id, kind, reference, channel, address) DO NOTHING
+		RETURNING created_at, updated_at
+	`, notification.ID, tenantID, notification.Kind, notification.Channel, notification.RecipientID,
+		notification.Address, notification.Reference, notification.Subject, notification.TextBody,
+		notification.HTMLBody, notification.Status, notification.DeliverAfter,
+		notification.EscalateAt).Scan(&notification.CreatedAt, &notification.UpdatedAt)
+	if err == nil {
+		notification.TenantID = tenantID
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to queue notification: %w", err)
+	}
+
+	query := `SELECT ` + notificationColumns + ` FROM notifications
+		WHERE tenant_id = $1 AND kind = $2 AND reference = $3 AND channel = $4 AND address = $5`
+	existing, err := scanNotification(r.db.QueryRowContext(ctx, query, tenantID, notification.Kind,
+		notification.Reference, notification.Channel, notification.Address))
+	if err != nil {
+		return err
+	}
+	*notification = *existing
+	return nil
+}
+
+func (r *NotificationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Notification, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE id = $1 AND tenant_id = $2`
+	notification, err := scanNotification(r.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNotificationNotFound
+		}
+		return nil, err
+	}
+	return notification, nil
+}
+
+// RecordAttempt stores the outcome of a delivery attempt: the notification's
+// status, attempts, last error, provider message id, sent time and escalation time
+func (r *NotificationRepository) RecordAttempt(ctx context.Context, notification *models.Notification) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		UPDATE notifications SET status = $3, attempts = $4, last_error = $5, provider_message_id = $6,
+			sent_at = $7, escalate_at = $8
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING updated_at
+	`, notification.ID, tenantID, notification.Status, notification.Attempts, notification.LastError,
+		notification.ProviderMessageID, notification.SentAt, notification.EscalateAt).Scan(&notification.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNotificationNotFound
+		}
+		return fmt.Errorf("failed to record notification attempt: %w", err)
+	}
+	return nil
+}
+
+// Acknowledge marks every notification of the event notification id belongs to
+// as acknowledged by userID, unless it already is, and returns the notification
+func (r *NotificationRepository) Acknowledge(ctx context.Context, id uuid.UUID, userID string) (*models.Notification, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE notifications n SET acknowledged_at = NOW(), acknowledged_by = $3
+		FROM notifications event
+		WHERE event.id = $1 AND event.tenant_id = $2
+		  AND n.tenant_id = event.tenant_id AND n.kind = event.kind AND n.reference = event.reference
+		  AND n.acknowledged_at IS NULL
+	`, id, tenantID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acknowledge notification: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// AcknowledgeEvent marks every notification of kind about reference as
+// acknowledged by userID, unless it already is
+func (r *NotificationRepository) AcknowledgeEvent(ctx context.Context, kind, reference, userID string) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE notifications SET acknowledged_at = NOW(), acknowledged_by = $4
+		WHERE tenant_id = $1 AND kind = $2 AND reference = $3 AND acknowledged_at IS NULL
+	`, tenantID, kind, reference, userID)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge notifications: %w", err)
+	}
+	return nil
+}
+
+// DueHeld returns, across tenants, up to limit held pages whose quiet hours
+// ended by now and that are not acknowledged, earliest first
+func (r *NotificationRepository) DueHeld(ctx context.Context, now time.Time, limit int) ([]*models.Notification, error) {
+	return r.listAcrossTenants(ctx, `
+		WHERE status = $1 AND deliver_after <= $2 AND acknowledged_at IS NULL
+		ORDER BY deliver_after
+		LIMIT $3
+	`, models.NotificationHeld, now, limit)
+}
+
+// Release queues a held page for delivery
+func (r *NotificationRepository) Release(ctx context.Context, id uuid.UUID) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `UPDATE notifications SET status = $3 WHERE id = $1 AND tenant_id = $2 AND status = $4`,
+		id, tenantID, models.NotificationQueued, models.NotificationHeld)
+	if err != nil {
+		return fmt.Errorf("failed to release notification: %w", err)
+	}
+	return nil
+}
+
+// DueEscalations returns, across tenants, up to limit unacknowledged pages due
+// to escalate by now, earliest first
+func (r *NotificationRepository) DueEscalations(ctx context.Context, now time.Time, limit int) ([]*models.Notification, error) {
+	return r.listAcrossTenants(ctx, `
+		WHERE escalate_at <= $1 AND escalated_at IS NULL AND acknowledged_at IS NULL
+		ORDER BY escalate_at
+		LIMIT $2
+	`, now, limit)
+}
+
+// MarkEscalated records that a page has escalated
+func (r *NotificationRepository) MarkEscalated(ctx context.Context, id uuid.UUID) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `UPDATE notifications SET escalated_at = NOW() WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification escalated: %w", err)
+	}
+	return nil
+}
+
+func (r *NotificationRepository) listAcrossTenants(ctx context.Context, where string, args ...interface{}) ([]*models.Notification, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+notificationColumns+` FROM notifications `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// NotificationFilter narrows notification listing
+type NotificationFilter struct {
+	Kind      string
+	Status    string
+	Reference string
+}
+
+func (f NotificationFilter) whereClause(tenantID string) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1"}
+	args := []interface{}{tenantID}
+
+	if f.Kind != "" {
+		args = append(args, f.Kind)
+		conditions = append(conditions, fmt.Sprintf("kind = $%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if f.Reference != "" {
+		args = append(args, f.Reference)
+		conditions = append(conditions, fmt.Sprintf("reference = $%d", len(args)))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// List returns the tenant's notifications, most recent first
+func (r *NotificationRepository) List(ctx context.Context, filter NotificationFilter, params PaginationParams) ([]*models.Notification, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	where, args := filter.whereClause(tenantID)
+
+	var total int64
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get notification count: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM notifications
+		%s
+		ORDER BY created_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, notificationColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, PaginationResult{}, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate notifications: %w", err)
+	}
+
+	return notifications, GetPaginationResult(total, params), nil
+}
+
+// Prune deletes finished notifications of every tenant queued before cutoff:
+// sent and failed ones, and held pages acknowledged before their release
+func (r *NotificationRepository) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM notifications WHERE created_at < $1 AND status <> $2`,
+		cutoff, models.NotificationQueued)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune notifications: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows, nil
+}
+
+// recipientPaging returns the columns of a recipient's quiet hours and
+// escalation, null when unset
+func recipientPaging(recipient *models.NotificationRecipient) (quietStart, quietEnd, timeZone *string, escalateTo *uuid.UUID, escalateAfter *int) {
+	if q := recipient.QuietHours; q != nil {
+		quietStart, quietEnd, timeZone = &q.Start, &q.End, &q.TimeZone
+	}
+	if e := recipient.Escalation; e != nil {
+		escalateTo, escalateAfter = &e.RecipientID, &e.AfterMinutes
+	}
+	return quietStart, quietEnd, timeZone, escalateTo, escalateAfter
+}
+
+func scanNotificationRecipient(scanner rowScanner) (*models.NotificationRecipient, error) {
+	recipient := &models.NotificationRecipient{}
+	var quietStart, quietEnd, timeZone sql.NullString
+	var escalateTo uuid.NullUUID
+	var escalateAfter sql.NullInt32
+	err := scanner.Scan(
+		&recipient.ID,
+		&recipient.Name,
+		&recipient.Email,
+		&recipient.Phone,
+		pq.Array(&recipient.Kinds),
+		&quietStart,
+		&quietEnd,
+		&timeZone,
+		&escalateTo,
+		&escalateAfter,
+		&recipient.Active,
+		&recipient.CreatedAt,
+		&recipient.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan notification recipient: %w", err)
+	}
+	if quietStart.Valid && quietEnd.Valid && timeZone.Valid {
+		recipient.QuietHours = &models.QuietHours{Start: quietStart.String, End: quietEnd.String, TimeZone: timeZone.String}
+	}
+	if escalateTo.Valid && escalateAfter.Valid {
+		recipient.Escalation = &models.Escalation{RecipientID: escalateTo.UUID, AfterMinutes: int(escalateAfter.Int32)}
+	}
+	return recipient, nil
+}
+
+func scanNotification(scanner rowScanner) (*models.Notification, error) {
+	notification := &models.Notification{}
+	err := scanner.Scan(
+		&notification.ID,
+		&notification.TenantID,
+		&notification.Kind,
+		&notification.Channel,
+		&notification.RecipientID,
+		&notification.Address,
+		&notification.Reference,
+		&notification.Subject,
+		&notification.TextBody,
+		&notification.HTMLBody,
+		&notification.Status,
+		&notification.Attempts,
+		&notification.LastError,
+		&notification.ProviderMessageID,
+		&notification.DeliverAfter,
+		&notification.EscalateAt,
+		&notification.EscalatedAt,
+		&notification.AcknowledgedAt,
+		&notification.AcknowledgedBy,
+		&notification.CreatedAt,
+		&notification.UpdatedAt,
+		&notification.SentAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan notification: %w", err)
+	}
+	return notification, nil
+}
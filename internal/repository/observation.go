@@ -4,12 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"healthcare-api/internal/database"
 	"healthcare-api/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type ObservationRepository struct {
@@ -23,6 +27,11 @@ func NewObservationRepository(db *database.DB) *ObservationRepository {
 }
 
 func (r *ObservationRepository) Create(ctx context.Context, observation *models.Observation) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO observations (
 			id, identifier, based_on, part_of, status, category, code, subject,
@@ -32,15 +41,19 @@ func (r *ObservationRepository) Create(ctx context.Context, observation *models.
 			value_sampled_data, value_time, value_date_time, value_period,
 			data_absent_reason, interpretation, note, body_site, method, specimen,
 			device, reference_range, has_member, derived_from, component,
-			meta, implicit_rules, language, text, contained, extension, modifier_extension
+			meta, implicit_rules, language, text, contained, extension, modifier_extension,
+			code_values, subject_reference, effective_date, tenant_id
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
 			$17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30,
-			$31, $32, $33, $34, $35, $36, $37, $38, $39, $40, $41, $42, $43, $44
+			$31, $32, $33, $34, $35, $36, $37, $38, $39, $40, $41, $42, $43, $44,
+			$45, $46, $47, $48, $49
 		) RETURNING created_at, updated_at, version
 	`
 
-	err := r.db.QueryRowContext(ctx, query,
+	searchColumns := ExtractObservationSearchColumns(observation)
+
+	args := []interface{}{
 		observation.ID,
 		toJSON(observation.Identifier),
 		toJSON(observation.BasedOn),
@@ -86,28 +99,254 @@ func (r *ObservationRepository) Create(ctx context.Context, observation *models.
 		toJSON(observation.Contained),
 		toJSON(observation.Extension),
 		toJSON(observation.ModifierExtension),
-	).Scan(&observation.CreatedAt, &observation.UpdatedAt, &observation.Version)
+		pq.Array(searchColumns.CodeValues),
+		searchColumns.SubjectReference,
+		searchColumns.EffectiveDate,
+		tenantID,
+	}
+
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&observation.CreatedAt, &observation.UpdatedAt, &observation.Version); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, observation, &AuditLog{
+			ResourceType: "Observation",
+			ResourceID:   observation.ID,
+			Action:       "CREATE",
+			NewValues:    mustMarshalJSON(observation),
+		})
+	})
 
 	if err != nil {
 		return fmt.Errorf("failed to create observation: %w", err)
 	}
 
-	// Log audit trail
-	auditLog := &AuditLog{
-		ResourceType: "Observation",
-		ResourceID:   observation.ID,
-		Action:       "CREATE",
-		NewValues:    mustMarshalJSON(observation),
+	return nil
+}
+
+func (r *ObservationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Observation, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, identifier, based_on, part_of, status, category, code, subject,
+			   focus, encounter, effective_date_time, effective_period, effective_timing,
+			   effective_instant, issued, performer, value_quantity, value_codeable_concept,
+			   value_string, value_boolean, value_integer, value_range, value_ratio,
+			   value_sampled_data, value_time, value_date_time, value_period,
+			   data_absent_reason, interpretation, note, body_site, method, specimen,
+			   device, reference_range, has_member, derived_from, component,
+			   meta, implicit_rules, language, text, contained, extension, 
+			   modifier_extension, created_at, updated_at, version, deleted_at
+		FROM observations WHERE id = $1 AND tenant_id = $2
+	`
+
+	var deletedAt *time.Time
+	observation, err := scanObservation(r.db.Reader(ctx).QueryRowContext(ctx, query, id, tenantID), &deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrObservationNotFound
+		}
+		return nil, fmt.Errorf("failed to get observation: %w", err)
+	}
+
+	if deletedAt != nil {
+		return nil, models.ErrResourceDeleted
+	}
+	observation.DeletedAt = deletedAt
+
+	return observation, nil
+}
+
+func (r *ObservationRepository) Update(ctx context.Context, observation *models.Observation) error {
+	ctx = database.WithPrimary(ctx)
+
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
 	}
-	
-	if err := r.LogAudit(ctx, auditLog); err != nil {
-		fmt.Printf("Failed to log audit: %v\n", err)
+
+	// First get the old values for audit
+	oldObservation, err := r.GetByID(ctx, observation.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE observations SET
+			identifier = $2, based_on = $3, part_of = $4, status = $5, category = $6,
+			code = $7, subject = $8, focus = $9, encounter = $10, effective_date_time = $11,
+			effective_period = $12, effective_timing = $13, effective_instant = $14,
+			issued = $15, performer = $16, value_quantity = $17, value_codeable_concept = $18,
+			value_string = $19, value_boolean = $20, value_integer = $21, value_range = $22,
+			value_ratio = $23, value_sampled_data = $24, value_time = $25, value_date_time = $26,
+			value_period = $27, data_absent_reason = $28, interpretation = $29, note = $30,
+			body_site = $31, method = $32, specimen = $33, device = $34, reference_range = $35,
+			has_member = $36, derived_from = $37, component = $38, meta = $39,
+			implicit_rules = $40, language = $41, text = $42, contained = $43,
+			extension = $44, modifier_extension = $45,
+			code_values = $46, subject_reference = $47, effective_date = $48
+		WHERE id = $1 AND tenant_id = $49 AND deleted_at IS NULL
+		RETURNING updated_at, version
+	`
+
+	searchColumns := ExtractObservationSearchColumns(observation)
+
+	args := []interface{}{
+		observation.ID,
+		toJSON(observation.Identifier),
+		toJSON(observation.BasedOn),
+		toJSON(observation.PartOf),
+		observation.Status,
+		toJSON(observation.Category),
+		toJSON(observation.Code),
+		toJSON(observation.Subject),
+		toJSON(observation.Focus),
+		toJSON(observation.Encounter),
+		observation.EffectiveDateTime,
+		toJSON(observation.EffectivePeriod),
+		toJSON(observation.EffectiveTiming),
+		observation.EffectiveInstant,
+		observation.Issued,
+		toJSON(observation.Performer),
+		toJSON(observation.ValueQuantity),
+		toJSON(observation.ValueCodeableConcept),
+		observation.ValueString,
+		observation.ValueBoolean,
+		observation.ValueInteger,
+		toJSON(observation.ValueRange),
+		toJSON(observation.ValueRatio),
+		toJSON(observation.ValueSampledData),
+		observation.ValueTime,
+		observation.ValueDateTime,
+		toJSON(observation.ValuePeriod),
+		toJSON(observation.DataAbsentReason),
+		toJSON(observation.Interpretation),
+		toJSON(observation.Note),
+		toJSON(observation.BodySite),
+		toJSON(observation.Method),
+		toJSON(observation.Specimen),
+		toJSON(observation.Device),
+		toJSON(observation.ReferenceRange),
+		toJSON(observation.HasMember),
+		toJSON(observation.DerivedFrom),
+		toJSON(observation.Component),
+		toJSON(observation.Meta),
+		observation.ImplicitRules,
+		observation.Language,
+		toJSON(observation.Text),
+		toJSON(observation.Contained),
+		toJSON(observation.Extension),
+		toJSON(observation.ModifierExtension),
+		pq.Array(searchColumns.CodeValues),
+		searchColumns.SubjectReference,
+		searchColumns.EffectiveDate,
+		tenantID,
+	}
+
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&observation.UpdatedAt, &observation.Version); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, observation, &AuditLog{
+			ResourceType: "Observation",
+			ResourceID:   observation.ID,
+			Action:       "UPDATE",
+			OldValues:    mustMarshalJSON(oldObservation),
+			NewValues:    mustMarshalJSON(observation),
+		})
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrObservationNotFound
+		}
+		return fmt.Errorf("failed to update observation: %w", err)
 	}
 
 	return nil
 }
 
-func (r *ObservationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Observation, error) {
+func (r *ObservationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx = database.WithPrimary(ctx)
+
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Get the observation for audit log
+	observation, err := r.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE observations SET deleted_at = NOW() WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL RETURNING version, deleted_at`
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, id, tenantID).Scan(&observation.Version, &observation.DeletedAt); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, observation, &AuditLog{
+			ResourceType: "Observation",
+			ResourceID:   id,
+			Action:       "DELETE",
+			OldValues:    mustMarshalJSON(observation),
+		})
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ErrObservationNotFound
+		}
+		return fmt.Errorf("failed to delete observation: %w", err)
+	}
+
+	return nil
+}
+
+// Restore clears the soft delete marker on an observation
+func (r *ObservationRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Observation, error) {
+	ctx = database.WithPrimary(ctx)
+
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE observations SET deleted_at = NULL WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NOT NULL RETURNING ` + observationColumns
+	var observation *models.Observation
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		var err error
+		if observation, err = scanObservation(tx.QueryRowContext(ctx, query, id, tenantID)); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, observation, &AuditLog{
+			ResourceType: "Observation",
+			ResourceID:   id,
+			Action:       "RESTORE",
+			NewValues:    mustMarshalJSON(observation),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrObservationNotFound
+		}
+		return nil, fmt.Errorf("failed to restore observation: %w", err)
+	}
+
+	return observation, nil
+}
+
+// Latest returns the subject's live observation with any of codes effective
+// most recently, at or after since. Observations entered in error or cancelled
+// are skipped. It returns models.ErrObservationNotFound when there is none.
+func (r *ObservationRepository) Latest(ctx context.Context, subject string, codes []string, since time.Time) (*models.Observation, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, identifier, based_on, part_of, status, category, code, subject,
 			   focus, encounter, effective_date_time, effective_period, effective_timing,
@@ -116,98 +355,239 @@ func (r *ObservationRepository) GetByID(ctx context.Context, id uuid.UUID) (*mod
 			   value_sampled_data, value_time, value_date_time, value_period,
 			   data_absent_reason, interpretation, note, body_site, method, specimen,
 			   device, reference_range, has_member, derived_from, component,
-			   meta, implicit_rules, language, text, contained, extension, 
+			   meta, implicit_rules, language, text, contained, extension,
 			   modifier_extension, created_at, updated_at, version
-		FROM observations WHERE id = $1
+		FROM observations
+		WHERE tenant_id = $1 AND deleted_at IS NULL
+		  AND subject_reference = $2 AND code_values && $3 AND effective_date >= $4
+		  AND status NOT IN ('entered-in-error', 'cancelled')
+		ORDER BY effective_date DESC, created_at DESC
+		LIMIT 1
 	`
 
+	observation, err := scanObservation(r.db.Reader(ctx).QueryRowContext(ctx, query, tenantID, subject, pq.Array(codes), since))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrObservationNotFound
+		}
+		return nil, fmt.Errorf("failed to get latest observation: %w", err)
+	}
+	return observation, nil
+}
+
+// LatestInEncounter returns the subject's live observation with any of codes
+// recorded in the encounter, a reference like Encounter/123, that is effective
+// most recently. Observations entered in error or cancelled are skipped. It
+// returns models.ErrObservationNotFound when there is none.
+func (r *ObservationRepository) LatestInEncounter(ctx context.Context, subject, encounter string, codes []string) (*models.Observation, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, identifier, based_on, part_of, status, category, code, subject,
+			   focus, encounter, effective_date_time, effective_period, effective_timing,
+			   effective_instant, issued, performer, value_quantity, value_codeable_concept,
+			   value_string, value_boolean, value_integer, value_range, value_ratio,
+			   value_sampled_data, value_time, value_date_time, value_period,
+			   data_absent_reason, interpretation, note, body_site, method, specimen,
+			   device, reference_range, has_member, derived_from, component,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version
+		FROM observations
+		WHERE tenant_id = $1 AND deleted_at IS NULL
+		  AND subject_reference = $2 AND encounter->>'reference' = $3 AND code_values && $4
+		  AND status NOT IN ('entered-in-error', 'cancelled')
+		ORDER BY effective_date DESC NULLS LAST, created_at DESC
+		LIMIT 1
+	`
+
+	observation, err := scanObservation(r.db.Reader(ctx).QueryRowContext(ctx, query, tenantID, subject, encounter, pq.Array(codes)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrObservationNotFound
+		}
+		return nil, fmt.Errorf("failed to get latest observation in encounter: %w", err)
+	}
+	return observation, nil
+}
+
+// ObservationSearchParams represents supported observation search filters
+type ObservationSearchParams struct {
+	Subject string `json:"subject,omitempty"`
+	Code    string `json:"code,omitempty"`
+	// IDs limits the search to these observations, e.g. the hits of a search index
+	IDs []uuid.UUID `json:"ids,omitempty"`
+}
+
+// whereClause builds the SQL filter for the search parameters using the extracted search columns
+func (p ObservationSearchParams) whereClause(tenantID string) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1", "deleted_at IS NULL"}
+	args := []interface{}{tenantID}
+
+	if p.Subject != "" {
+		args = append(args, p.Subject)
+		conditions = append(conditions, fmt.Sprintf("subject_reference = $%d", len(args)))
+	}
+	if p.Code != "" {
+		args = append(args, pq.Array([]string{p.Code}))
+		conditions = append(conditions, fmt.Sprintf("code_values @> $%d", len(args)))
+	}
+	if p.IDs != nil {
+		ids := make([]string, len(p.IDs))
+		for i, id := range p.IDs {
+			ids[i] = id.String()
+		}
+		args = append(args, pq.Array(ids))
+		conditions = append(conditions, fmt.Sprintf("id = ANY($%d::uuid[])", len(args)))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (r *ObservationRepository) List(ctx context.Context, search ObservationSearchParams, params PaginationParams) ([]*models.Observation, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	where, args := search.whereClause(tenantID)
+
+	// Get total count
+	countQuery := `SELECT COUNT(*) FROM observations ` + where
+	var total int64
+	err = r.db.Reader(ctx).QueryRowContext(ctx, countQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get observation count: %w", err)
+	}
+
+	// Get observations with pagination
+	query := fmt.Sprintf(`
+		SELECT id, identifier, based_on, part_of, status, category, code, subject,
+			   focus, encounter, effective_date_time, effective_period, effective_timing,
+			   effective_instant, issued, performer, value_quantity, value_codeable_concept,
+			   value_string, value_boolean, value_integer, value_range, value_ratio,
+			   value_sampled_data, value_time, value_date_time, value_period,
+			   data_absent_reason, interpretation, note, body_site, method, specimen,
+			   device, reference_range, has_member, derived_from, component,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version
+		FROM observations
+		%s
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list observations: %w", err)
+	}
+	defer rows.Close()
+
+	var observations []*models.Observation
+	for rows.Next() {
+		observation, err := scanObservation(rows)
+		if err != nil {
+			return nil, PaginationResult{}, err
+		}
+
+		observations = append(observations, observation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate observations: %w", err)
+	}
+
+	pagination := GetPaginationResult(total, params)
+	return observations, pagination, nil
+}
+
+// observationColumns is the standard column list read by scanObservation
+const observationColumns = `id, identifier, based_on, part_of, status, category, code, subject,
+			   focus, encounter, effective_date_time, effective_period, effective_timing,
+			   effective_instant, issued, performer, value_quantity, value_codeable_concept,
+			   value_string, value_boolean, value_integer, value_range, value_ratio,
+			   value_sampled_data, value_time, value_date_time, value_period,
+			   data_absent_reason, interpretation, note, body_site, method, specimen,
+			   device, reference_range, has_member, derived_from, component,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version`
+
+// scanObservation scans the standard observation column list, followed by any extra destinations
+func scanObservation(scanner rowScanner, extra ...interface{}) (*models.Observation, error) {
 	observation := &models.Observation{}
-	var identifier, basedOn, partOf, category, code, subject, focus []byte
-	var encounter, effectivePeriod, effectiveTiming, performer []byte
-	var valueQuantity, valueCodeableConcept, valueRange, valueRatio []byte
-	var valueSampledData, valuePeriod, dataAbsentReason, interpretation []byte
-	var note, bodySite, method, specimen, device, referenceRange []byte
-	var hasMember, derivedFrom, component, meta, text, contained []byte
-	var extension, modifierExtension []byte
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+
+	dest := []interface{}{
 		&observation.ID,
-		&identifier,
-		&basedOn,
-		&partOf,
+		jsonb(&observation.Identifier),
+		jsonb(&observation.BasedOn),
+		jsonb(&observation.PartOf),
 		&observation.Status,
-		&category,
-		&code,
-		&subject,
-		&focus,
-		&encounter,
+		jsonb(&observation.Category),
+		jsonb(&observation.Code),
+		jsonb(&observation.Subject),
+		jsonb(&observation.Focus),
+		jsonb(&observation.Encounter),
 		&observation.EffectiveDateTime,
-		&effectivePeriod,
-		&effectiveTiming,
+		jsonb(&observation.EffectivePeriod),
+		jsonb(&observation.EffectiveTiming),
 		&observation.EffectiveInstant,
 		&observation.Issued,
-		&performer,
-		&valueQuantity,
-		&valueCodeableConcept,
+		jsonb(&observation.Performer),
+		jsonb(&observation.ValueQuantity),
+		jsonb(&observation.ValueCodeableConcept),
 		&observation.ValueString,
 		&observation.ValueBoolean,
 		&observation.ValueInteger,
-		&valueRange,
-		&valueRatio,
-		&valueSampledData,
+		jsonb(&observation.ValueRange),
+		jsonb(&observation.ValueRatio),
+		jsonb(&observation.ValueSampledData),
 		&observation.ValueTime,
 		&observation.ValueDateTime,
-		&valuePeriod,
-		&dataAbsentReason,
-		&interpretation,
-		&note,
-		&bodySite,
-		&method,
-		&specimen,
-		&device,
-		&referenceRange,
-		&hasMember,
-		&derivedFrom,
-		&component,
-		&meta,
+		jsonb(&observation.ValuePeriod),
+		jsonb(&observation.DataAbsentReason),
+		jsonb(&observation.Interpretation),
+		jsonb(&observation.Note),
+		jsonb(&observation.BodySite),
+		jsonb(&observation.Method),
+		jsonb(&observation.Specimen),
+		jsonb(&observation.Device),
+		jsonb(&observation.ReferenceRange),
+		jsonb(&observation.HasMember),
+		jsonb(&observation.DerivedFrom),
+		jsonb(&observation.Component),
+		jsonb(&observation.Meta),
 		&observation.ImplicitRules,
 		&observation.Language,
-		&text,
-		&contained,
-		&extension,
-		&modifierExtension,
+		jsonb(&observation.Text),
+		jsonb(&observation.Contained),
+		jsonb(&observation.Extension),
+		jsonb(&observation.ModifierExtension),
 		&observation.CreatedAt,
 		&observation.UpdatedAt,
 		&observation.Version,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("observation not found")
-		}
-		return nil, fmt.Errorf("failed to get observation: %w", err)
 	}
 
-	// Unmarshal JSON fields (implementation would be similar to patient repository)
-	// For brevity, this is left as a placeholder
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return nil, fmt.Errorf("failed to scan observation: %w", err)
+	}
 
 	return observation, nil
 }
 
-func (r *ObservationRepository) Update(ctx context.Context, observation *models.Observation) error {
-	// Implementation similar to patient repository
-	// For brevity, this is left as a placeholder
-	return nil
-}
-
-func (r *ObservationRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	// Implementation similar to patient repository
-	// For brevity, this is left as a placeholder
-	return nil
-}
-
-func (r *ObservationRepository) List(ctx context.Context, params PaginationParams) ([]*models.Observation, PaginationResult, error) {
-	// Implementation similar to patient repository
-	// For brevity, this is left as a placeholder
-	return nil, PaginationResult{}, nil
+// recordChange writes the audit log entry of a change to the observation and its state
+// after the change, as a new history version, through tx, the transaction of the
+// change, so the change is not committed without them
+func (r *ObservationRepository) recordChange(ctx context.Context, tx *sql.Tx, observation *models.Observation, log *AuditLog) error {
+	if err := r.logAudit(ctx, tx, log); err != nil {
+		return err
+	}
+	return r.insertHistory(ctx, tx, &HistoryEntry{
+		ResourceType: "Observation",
+		ResourceID:   observation.ID,
+		Version:      observation.Version,
+		Action:       log.Action,
+		Payload:      mustMarshalJSON(observation),
+	})
 }
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// OutboxRepository stores background jobs recorded alongside resource changes
+// until the worker pool has taken them. Entries are added for the request tenant
+// and dispatched across all tenants.
+type OutboxRepository struct {
+	*BaseRepository
+}
+
+func NewOutboxRepository(db *database.DB) *OutboxRepository {
+	return &OutboxRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Add records a job of jobType for the request tenant, tagged with the request ID
+func (r *OutboxRepository) Add(ctx context.Context, jobType string, payload interface{}) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s outbox payload: %w", jobType, err)
+	}
+
+	var requestID *string
+	if id := requestctx.RequestID(ctx); id != "" {
+		requestID = &id
+	}
+
+	query := `INSERT INTO job_outbox (id, tenant_id, job_type, payload, request_id) VALUES ($1, $2, $3, $4, $5)`
+	// JSONB parameters must be sent as text
+	if _, err := r.db.ExecContext(ctx, query, uuid.New().String(), tenantID, jobType, string(data), requestID); err != nil {
+		return fmt.Errorf("failed to add %s job to outbox: %w", jobType, err)
+	}
+	return nil
+}
+
+// Dispatch hands up to limit of the oldest entries to submit, oldest first, and
+// removes those submitted. Entries are locked while they are submitted, so
+// processes dispatching at once take different entries. It stops at the first
+// submit error, returning it with the number of entries dispatched.
+func (r *OutboxRepository) Dispatch(ctx context.Context, limit int, submit func(*models.OutboxEntry) error) (int, error) {
+	var dispatched int
+	var submitErr error
+	err := r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		dispatched, submitErr = 0, nil
+
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, tenant_id, job_type, payload, request_id, created_at
+			FROM job_outbox
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		`, limit)
+		if err != nil {
+			return fmt.Errorf("failed to read job outbox: %w", err)
+		}
+
+		var entries []*models.OutboxEntry
+		for rows.Next() {
+			entry := &models.OutboxEntry{}
+			var payload []byte
+			if err := rows.Scan(&entry.ID, &entry.TenantID, &entry.JobType, &payload, &entry.RequestID, &entry.CreatedAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan outbox entry: %w", err)
+			}
+			entry.Payload = payload
+			entries = append(entries, entry)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate job outbox: %w", err)
+		}
+
+		var ids []string
+		for _, entry := range entries {
+			if submitErr = submit(entry); submitErr != nil {
+				break
+			}
+			ids = append(ids, entry.ID)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM job_outbox WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+			return fmt.Errorf("failed to remove dispatched outbox entries: %w", err)
+		}
+		dispatched = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return dispatched, submitErr
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode"
 
 	"healthcare-api/internal/database"
 	"healthcare-api/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type PatientRepository struct {
@@ -24,20 +28,28 @@ func NewPatientRepository(db *database.DB) *PatientRepository {
 }
 
 func (r *PatientRepository) Create(ctx context.Context, patient *models.Patient) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
 	query := `
 		INSERT INTO patients (
 			id, identifier, active, name, telecom, gender, birth_date,
 			deceased_boolean, deceased_date_time, address, marital_status,
 			multiple_birth_boolean, multiple_birth_integer, photo, contact,
 			communication, general_practitioner, managing_organization, link,
-			meta, implicit_rules, language, text, contained, extension, modifier_extension
+			meta, implicit_rules, language, text, contained, extension, modifier_extension,
+			family_name, identifier_values, search_text, tenant_id
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
-			$16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26
+			$16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30
 		) RETURNING created_at, updated_at, version
 	`
 
-	err := r.db.QueryRowContext(ctx, query,
+	searchColumns := ExtractPatientSearchColumns(patient)
+
+	args := []interface{}{
 		patient.ID,
 		toJSON(patient.Identifier),
 		patient.Active,
@@ -64,95 +76,75 @@ func (r *PatientRepository) Create(ctx context.Context, patient *models.Patient)
 		toJSON(patient.Contained),
 		toJSON(patient.Extension),
 		toJSON(patient.ModifierExtension),
-	).Scan(&patient.CreatedAt, &patient.UpdatedAt, &patient.Version)
+		searchColumns.FamilyName,
+		pq.Array(searchColumns.IdentifierValues),
+		searchColumns.SearchText,
+		tenantID,
+	}
+
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&patient.CreatedAt, &patient.UpdatedAt, &patient.Version); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, patient, &AuditLog{
+			ResourceType: "Patient",
+			ResourceID:   patient.ID,
+			Action:       "CREATE",
+			NewValues:    mustMarshalJSON(patient),
+		})
+	})
 
 	if err != nil {
+		if conflict := r.identifierConflict(ctx, err, patient); conflict != nil {
+			return conflict
+		}
 		return fmt.Errorf("failed to create patient: %w", err)
 	}
 
-	// Log audit trail
-	auditLog := &AuditLog{
-		ResourceType: "Patient",
-		ResourceID:   patient.ID,
-		Action:       "CREATE",
-		NewValues:    mustMarshalJSON(patient),
-	}
-	
-	if err := r.LogAudit(ctx, auditLog); err != nil {
-		// Log error but don't fail the operation
-		fmt.Printf("Failed to log audit: %v\n", err)
-	}
-
 	return nil
 }
 
 func (r *PatientRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Patient, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT id, identifier, active, name, telecom, gender, birth_date,
 			   deceased_boolean, deceased_date_time, address, marital_status,
 			   multiple_birth_boolean, multiple_birth_integer, photo, contact,
 			   communication, general_practitioner, managing_organization, link,
 			   meta, implicit_rules, language, text, contained, extension, 
-			   modifier_extension, created_at, updated_at, version
-		FROM patients WHERE id = $1
+			   modifier_extension, created_at, updated_at, version, deleted_at
+		FROM patients WHERE id = $1 AND tenant_id = $2
 	`
 
-	patient := &models.Patient{}
-	var identifier, name, telecom, address, maritalStatus, photo, contact []byte
-	var communication, generalPractitioner, link, meta, text, contained []byte
-	var extension, modifierExtension []byte
-	var managingOrganization []byte
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&patient.ID,
-		&identifier,
-		&patient.Active,
-		&name,
-		&telecom,
-		&patient.Gender,
-		&patient.BirthDate,
-		&patient.DeceasedBoolean,
-		&patient.DeceasedDateTime,
-		&address,
-		&maritalStatus,
-		&patient.MultipleBirthBoolean,
-		&patient.MultipleBirthInteger,
-		&photo,
-		&contact,
-		&communication,
-		&generalPractitioner,
-		&managingOrganization,
-		&link,
-		&meta,
-		&patient.ImplicitRules,
-		&patient.Language,
-		&text,
-		&contained,
-		&extension,
-		&modifierExtension,
-		&patient.CreatedAt,
-		&patient.UpdatedAt,
-		&patient.Version,
-	)
-
+	var deletedAt *time.Time
+	patient, err := scanPatient(r.db.Reader(ctx).QueryRowContext(ctx, query, id, tenantID), &deletedAt)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("patient not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrPatientNotFound
 		}
 		return nil, fmt.Errorf("failed to get patient: %w", err)
 	}
 
-	// Unmarshal JSON fields
-	if err := unmarshalJSONFields(patient, identifier, name, telecom, address, maritalStatus,
-		photo, contact, communication, generalPractitioner, managingOrganization, link,
-		meta, text, contained, extension, modifierExtension); err != nil {
-		return nil, err
+	if deletedAt != nil {
+		return nil, models.ErrResourceDeleted
 	}
+	patient.DeletedAt = deletedAt
 
 	return patient, nil
 }
 
 func (r *PatientRepository) Update(ctx context.Context, patient *models.Patient) error {
+	ctx = database.WithPrimary(ctx)
+
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
 	// First get the old values for audit
 	oldPatient, err := r.GetByID(ctx, patient.ID)
 	if err != nil {
@@ -167,12 +159,15 @@ func (r *PatientRepository) Update(ctx context.Context, patient *models.Patient)
 			multiple_birth_integer = $13, photo = $14, contact = $15,
 			communication = $16, general_practitioner = $17, managing_organization = $18,
 			link = $19, meta = $20, implicit_rules = $21, language = $22,
-			text = $23, contained = $24, extension = $25, modifier_extension = $26
-		WHERE id = $1
+			text = $23, contained = $24, extension = $25, modifier_extension = $26,
+			family_name = $27, identifier_values = $28, search_text = $29
+		WHERE id = $1 AND tenant_id = $30 AND deleted_at IS NULL
 		RETURNING updated_at, version
 	`
 
-	err = r.db.QueryRowContext(ctx, query,
+	searchColumns := ExtractPatientSearchColumns(patient)
+
+	args := []interface{}{
 		patient.ID,
 		toJSON(patient.Identifier),
 		patient.Active,
@@ -199,76 +194,155 @@ func (r *PatientRepository) Update(ctx context.Context, patient *models.Patient)
 		toJSON(patient.Contained),
 		toJSON(patient.Extension),
 		toJSON(patient.ModifierExtension),
-	).Scan(&patient.UpdatedAt, &patient.Version)
+		searchColumns.FamilyName,
+		pq.Array(searchColumns.IdentifierValues),
+		searchColumns.SearchText,
+		tenantID,
+	}
+
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&patient.UpdatedAt, &patient.Version); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, patient, &AuditLog{
+			ResourceType: "Patient",
+			ResourceID:   patient.ID,
+			Action:       "UPDATE",
+			OldValues:    mustMarshalJSON(oldPatient),
+			NewValues:    mustMarshalJSON(patient),
+		})
+	})
 
 	if err != nil {
+		if conflict := r.identifierConflict(ctx, err, patient); conflict != nil {
+			return conflict
+		}
 		return fmt.Errorf("failed to update patient: %w", err)
 	}
 
-	// Log audit trail
-	auditLog := &AuditLog{
-		ResourceType: "Patient",
-		ResourceID:   patient.ID,
-		Action:       "UPDATE",
-		OldValues:    mustMarshalJSON(oldPatient),
-		NewValues:    mustMarshalJSON(patient),
-	}
-	
-	if err := r.LogAudit(ctx, auditLog); err != nil {
-		fmt.Printf("Failed to log audit: %v\n", err)
-	}
-
 	return nil
 }
 
 func (r *PatientRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx = database.WithPrimary(ctx)
+
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Get the patient for audit log
 	patient, err := r.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	query := `DELETE FROM patients WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
+	query := `UPDATE patients SET deleted_at = NOW() WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL RETURNING version, deleted_at`
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, id, tenantID).Scan(&patient.Version, &patient.DeletedAt); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, patient, &AuditLog{
+			ResourceType: "Patient",
+			ResourceID:   id,
+			Action:       "DELETE",
+			OldValues:    mustMarshalJSON(patient),
+		})
+	})
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ErrPatientNotFound
+		}
 		return fmt.Errorf("failed to delete patient: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return nil
+}
+
+// Restore clears the soft delete marker on a patient
+func (r *PatientRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Patient, error) {
+	ctx = database.WithPrimary(ctx)
+
+	tenantID, err := r.tenantID(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("patient not found")
+	query := `UPDATE patients SET deleted_at = NULL WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NOT NULL RETURNING ` + patientColumns
+	var patient *models.Patient
+	err = r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		var err error
+		if patient, err = scanPatient(tx.QueryRowContext(ctx, query, id, tenantID)); err != nil {
+			return err
+		}
+		return r.recordChange(ctx, tx, patient, &AuditLog{
+			ResourceType: "Patient",
+			ResourceID:   id,
+			Action:       "RESTORE",
+			NewValues:    mustMarshalJSON(patient),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrPatientNotFound
+		}
+		return nil, fmt.Errorf("failed to restore patient: %w", err)
 	}
 
-	// Log audit trail
-	auditLog := &AuditLog{
-		ResourceType: "Patient",
-		ResourceID:   id,
-		Action:       "DELETE",
-		OldValues:    mustMarshalJSON(patient),
+	return patient, nil
+}
+
+// PatientSearchParams represents supported patient search filters
+type PatientSearchParams struct {
+	Family     string `json:"family,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	// IDs limits the search to these patients, e.g. the hits of a search index
+	IDs []uuid.UUID `json:"ids,omitempty"`
+}
+
+// whereClause builds the SQL filter for the search parameters using the extracted search columns
+func (p PatientSearchParams) whereClause(tenantID string) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1", "deleted_at IS NULL"}
+	args := []interface{}{tenantID}
+
+	if p.Family != "" {
+		args = append(args, strings.ToLower(p.Family)+"%")
+		conditions = append(conditions, fmt.Sprintf("family_name LIKE $%d", len(args)))
 	}
-	
-	if err := r.LogAudit(ctx, auditLog); err != nil {
-		fmt.Printf("Failed to log audit: %v\n", err)
+	if p.Identifier != "" {
+		args = append(args, pq.Array([]string{p.Identifier}))
+		conditions = append(conditions, fmt.Sprintf("identifier_values @> $%d", len(args)))
+	}
+	if p.IDs != nil {
+		ids := make([]string, len(p.IDs))
+		for i, id := range p.IDs {
+			ids[i] = id.String()
+		}
+		args = append(args, pq.Array(ids))
+		conditions = append(conditions, fmt.Sprintf("id = ANY($%d::uuid[])", len(args)))
 	}
 
-	return nil
+	return "WHERE " + strings.Join(conditions, " AND "), args
 }
 
-func (r *PatientRepository) List(ctx context.Context, params PaginationParams) ([]*models.Patient, PaginationResult, error) {
+func (r *PatientRepository) List(ctx context.Context, search PatientSearchParams, params PaginationParams) ([]*models.Patient, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	where, args := search.whereClause(tenantID)
+
 	// Get total count
-	countQuery := `SELECT COUNT(*) FROM patients`
+	countQuery := `SELECT COUNT(*) FROM patients ` + where
 	var total int64
-	err := r.db.QueryRowContext(ctx, countQuery).Scan(&total)
+	err = r.db.Reader(ctx).QueryRowContext(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, PaginationResult{}, fmt.Errorf("failed to get patient count: %w", err)
 	}
 
 	// Get patients with pagination
-	query := `
+	query := fmt.Sprintf(`
 		SELECT id, identifier, active, name, telecom, gender, birth_date,
 			   deceased_boolean, deceased_date_time, address, marital_status,
 			   multiple_birth_boolean, multiple_birth_integer, photo, contact,
@@ -276,11 +350,12 @@ func (r *PatientRepository) List(ctx context.Context, params PaginationParams) (
 			   meta, implicit_rules, language, text, contained, extension, 
 			   modifier_extension, created_at, updated_at, version
 		FROM patients 
+		%s
 		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
 
-	rows, err := r.db.QueryContext(ctx, query, params.Limit, params.Offset)
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
 	if err != nil {
 		return nil, PaginationResult{}, fmt.Errorf("failed to list patients: %w", err)
 	}
@@ -288,52 +363,8 @@ func (r *PatientRepository) List(ctx context.Context, params PaginationParams) (
 
 	var patients []*models.Patient
 	for rows.Next() {
-		patient := &models.Patient{}
-		var identifier, name, telecom, address, maritalStatus, photo, contact []byte
-		var communication, generalPractitioner, link, meta, text, contained []byte
-		var extension, modifierExtension []byte
-		var managingOrganization []byte
-
-		err := rows.Scan(
-			&patient.ID,
-			&identifier,
-			&patient.Active,
-			&name,
-			&telecom,
-			&patient.Gender,
-			&patient.BirthDate,
-			&patient.DeceasedBoolean,
-			&patient.DeceasedDateTime,
-			&address,
-			&maritalStatus,
-			&patient.MultipleBirthBoolean,
-			&patient.MultipleBirthInteger,
-			&photo,
-			&contact,
-			&communication,
-			&generalPractitioner,
-			&managingOrganization,
-			&link,
-			&meta,
-			&patient.ImplicitRules,
-			&patient.Language,
-			&text,
-			&contained,
-			&extension,
-			&modifierExtension,
-			&patient.CreatedAt,
-			&patient.UpdatedAt,
-			&patient.Version,
-		)
-
+		patient, err := scanPatient(rows)
 		if err != nil {
-			return nil, PaginationResult{}, fmt.Errorf("failed to scan patient: %w", err)
-		}
-
-		// Unmarshal JSON fields
-		if err := unmarshalJSONFields(patient, identifier, name, telecom, address, maritalStatus,
-			photo, contact, communication, generalPractitioner, managingOrganization, link,
-			meta, text, contained, extension, modifierExtension); err != nil {
 			return nil, PaginationResult{}, err
 		}
 
@@ -348,6 +379,140 @@ func (r *PatientRepository) List(ctx context.Context, params PaginationParams) (
 	return patients, pagination, nil
 }
 
+// PatientMatch is a patient returned by smart search with its relevance score
+type PatientMatch struct {
+	Patient *models.Patient
+	Score   float64
+}
+
+// SmartSearch performs fuzzy demographic search ranked by full-text and trigram similarity
+func (r *PatientRepository) SmartSearch(ctx context.Context, text string, params PaginationParams) ([]*PatientMatch, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	text = strings.ToLower(strings.TrimSpace(text))
+	tsQuery := prefixTSQuery(text)
+	if tsQuery == "" {
+		return nil, GetPaginationResult(0, params), nil
+	}
+
+	where := `WHERE tenant_id = $3 AND deleted_at IS NULL AND (search_vector @@ to_tsquery('simple', $2) OR $1 <% search_text)`
+
+	// Get total count
+	countQuery := `SELECT COUNT(*) FROM patients ` + where
+	var total int64
+	if err := r.db.Reader(ctx).QueryRowContext(ctx, countQuery, text, tsQuery, tenantID).Scan(&total); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get patient match count: %w", err)
+	}
+
+	query := `
+		SELECT ` + patientColumns + `,
+			   word_similarity($1, search_text) + ts_rank(search_vector, to_tsquery('simple', $2)) AS score
+		FROM patients
+		` + where + `
+		ORDER BY score DESC, created_at DESC
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, text, tsQuery, tenantID, params.Limit, params.Offset)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to search patients: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []*PatientMatch
+	for rows.Next() {
+		match := &PatientMatch{}
+		patient, err := scanPatient(rows, &match.Score)
+		if err != nil {
+			return nil, PaginationResult{}, err
+		}
+		match.Patient = patient
+		matches = append(matches, match)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate patient matches: %w", err)
+	}
+
+	return matches, GetPaginationResult(total, params), nil
+}
+
+// prefixTSQuery converts free text into a prefix-matching tsquery ("jon smth" -> "jon:* & smth:*")
+func prefixTSQuery(text string) string {
+	var terms []string
+	for _, field := range strings.Fields(text) {
+		term := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, field)
+		if term != "" {
+			terms = append(terms, term+":*")
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// patientColumns is the standard column list read by scanPatient
+const patientColumns = `id, identifier, active, name, telecom, gender, birth_date,
+			   deceased_boolean, deceased_date_time, address, marital_status,
+			   multiple_birth_boolean, multiple_birth_integer, photo, contact,
+			   communication, general_practitioner, managing_organization, link,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version`
+
+// scanPatient scans the standard patient column list, followed by any extra destinations
+func scanPatient(scanner rowScanner, extra ...interface{}) (*models.Patient, error) {
+	patient := &models.Patient{}
+
+	dest := []interface{}{
+		&patient.ID,
+		jsonb(&patient.Identifier),
+		&patient.Active,
+		jsonb(&patient.Name),
+		jsonb(&patient.Telecom),
+		&patient.Gender,
+		&patient.BirthDate,
+		&patient.DeceasedBoolean,
+		&patient.DeceasedDateTime,
+		jsonb(&patient.Address),
+		jsonb(&patient.MaritalStatus),
+		&patient.MultipleBirthBoolean,
+		&patient.MultipleBirthInteger,
+		jsonb(&patient.Photo),
+		jsonb(&patient.Contact),
+		jsonb(&patient.Communication),
+		jsonb(&patient.GeneralPractitioner),
+		jsonb(&patient.ManagingOrganization),
+		jsonb(&patient.Link),
+		jsonb(&patient.Meta),
+		&patient.ImplicitRules,
+		&patient.Language,
+		jsonb(&patient.Text),
+		jsonb(&patient.Contained),
+		jsonb(&patient.Extension),
+		jsonb(&patient.ModifierExtension),
+		&patient.CreatedAt,
+		&patient.UpdatedAt,
+		&patient.Version,
+	}
+
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return nil, fmt.Errorf("failed to scan patient: %w", err)
+	}
+
+	return patient, nil
+}
+
 // Helper functions
 func toJSON(v interface{}) []byte {
 	if v == nil {
@@ -362,8 +527,18 @@ func mustMarshalJSON(v interface{}) json.RawMessage {
 	return data
 }
 
-func unmarshalJSONFields(patient *models.Patient, fields ...[]byte) error {
-	// This would unmarshal all the JSON fields - implementation depends on the models
-	// For now, we'll leave this as a placeholder
-	return nil
+// recordChange writes the audit log entry of a change to the patient and its state
+// after the change, as a new history version, through tx, the transaction of the
+// change, so the change is not committed without them
+func (r *PatientRepository) recordChange(ctx context.Context, tx *sql.Tx, patient *models.Patient, log *AuditLog) error {
+	if err := r.logAudit(ctx, tx, log); err != nil {
+		return err
+	}
+	return r.insertHistory(ctx, tx, &HistoryEntry{
+		ResourceType: "Patient",
+		ResourceID:   patient.ID,
+		Version:      patient.Version,
+		Action:       log.Action,
+		Payload:      mustMarshalJSON(patient),
+	})
 }
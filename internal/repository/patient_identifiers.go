@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// patientIdentifierConstraint is the unique constraint on patient_identifiers (tenant_id, system, value)
+const patientIdentifierConstraint = "uq_patient_identifiers_system_value"
+
+// IdentifierKey is a business identifier as stored in patient_identifiers
+type IdentifierKey struct {
+	System string
+	Value  string
+}
+
+// PatientIdentifierKeys returns the distinct identifiers a patient claims. Identifiers
+// without a value are ignored and a missing system is treated as empty, matching the
+// sync_patient_identifiers trigger.
+func PatientIdentifierKeys(patient *models.Patient) []IdentifierKey {
+	seen := make(map[IdentifierKey]bool)
+	var keys []IdentifierKey
+	for _, identifier := range patient.Identifier {
+		if identifier.Value == nil || *identifier.Value == "" {
+			continue
+		}
+		key := IdentifierKey{Value: *identifier.Value}
+		if identifier.System != nil {
+			key.System = *identifier.System
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// FindBatchIdentifierConflict reports the first identifier claimed by two different
+// patients within patients, pointing at the earlier one
+func FindBatchIdentifierConflict(patients []*models.Patient) *models.IdentifierConflictError {
+	owners := make(map[IdentifierKey]uuid.UUID)
+	for _, patient := range patients {
+		for _, key := range PatientIdentifierKeys(patient) {
+			if owner, ok := owners[key]; ok && owner != patient.ID {
+				return &models.IdentifierConflictError{System: key.System, Value: key.Value, ResourceID: owner}
+			}
+			owners[key] = patient.ID
+		}
+	}
+	return nil
+}
+
+// identifierConflict translates a violation of the patient identifier constraint into
+// a models.IdentifierConflictError naming the patient that already holds the identifier.
+// It returns nil when err is not such a violation or the holder can no longer be found.
+func (r *PatientRepository) identifierConflict(ctx context.Context, err error, patients ...*models.Patient) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" || pqErr.Constraint != patientIdentifierConstraint {
+		return nil
+	}
+
+	if conflict := FindBatchIdentifierConflict(patients); conflict != nil {
+		return conflict
+	}
+
+	tenantID, tenantErr := r.tenantID(ctx)
+	if tenantErr != nil {
+		return tenantErr
+	}
+
+	var systems, values, ids []string
+	for _, patient := range patients {
+		ids = append(ids, patient.ID.String())
+		for _, key := range PatientIdentifierKeys(patient) {
+			systems = append(systems, key.System)
+			values = append(values, key.Value)
+		}
+	}
+
+	query := `
+		SELECT pi.system, pi.value, pi.patient_id
+		FROM patient_identifiers pi
+		JOIN unnest($2::text[], $3::text[]) AS k(system, value)
+			ON pi.system = k.system AND pi.value = k.value
+		WHERE pi.tenant_id = $1 AND pi.patient_id <> ALL($4::uuid[])
+		LIMIT 1
+	`
+
+	// The conflicting row was committed by another writer, so read it from the primary
+	conflict := &models.IdentifierConflictError{}
+	lookupErr := r.db.QueryRowContext(ctx, query, tenantID, pq.Array(systems), pq.Array(values), pq.Array(ids)).
+		Scan(&conflict.System, &conflict.Value, &conflict.ResourceID)
+	if lookupErr != nil {
+		logError(ctx, "Failed to look up conflicting patient identifier", lookupErr)
+		return nil
+	}
+
+	return conflict
+}
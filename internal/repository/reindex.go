@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ReindexCursor marks the last row of a reindex page; the zero value starts
+// from the beginning
+type ReindexCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Count returns the number of the tenant's patients, soft-deleted ones included
+func (r *PatientRepository) Count(ctx context.Context) (int, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM patients WHERE tenant_id = $1`, tenantID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count patients: %w", err)
+	}
+	return count, nil
+}
+
+// ReindexPage returns up to limit of the tenant's patients after cursor in
+// creation order, soft-deleted ones included so a restore finds them indexed
+func (r *PatientRepository) ReindexPage(ctx context.Context, after ReindexCursor, limit int) ([]*models.Patient, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, identifier, active, name, telecom, gender, birth_date,
+			   deceased_boolean, deceased_date_time, address, marital_status,
+			   multiple_birth_boolean, multiple_birth_integer, photo, contact,
+			   communication, general_practitioner, managing_organization, link,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version, deleted_at
+		FROM patients
+		WHERE tenant_id = $1 AND (created_at, id) > ($2, $3)
+		ORDER BY created_at, id
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, tenantID, after.CreatedAt, after.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list patients for reindex: %w", err)
+	}
+	defer rows.Close()
+
+	var patients []*models.Patient
+	for rows.Next() {
+		var deletedAt *time.Time
+		patient, err := scanPatient(rows, &deletedAt)
+		if err != nil {
+			return nil, err
+		}
+		patient.DeletedAt = deletedAt
+		patients = append(patients, patient)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate patients: %w", err)
+	}
+
+	return patients, nil
+}
+
+// UpdateSearchColumns recomputes the extracted search columns of patients in one
+// transaction. The resources themselves, their versions and history are untouched.
+func (r *PatientRepository) UpdateSearchColumns(ctx context.Context, patients []*models.Patient) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `
+			UPDATE patients SET family_name = $3, identifier_values = $4, search_text = $5
+			WHERE tenant_id = $1 AND id = $2
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare search column update: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, patient := range patients {
+			cols := ExtractPatientSearchColumns(patient)
+			if _, err := stmt.ExecContext(ctx, tenantID, patient.ID, cols.FamilyName, pq.Array(cols.IdentifierValues), cols.SearchText); err != nil {
+				return fmt.Errorf("failed to update search columns of patient %s: %w", patient.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Count returns the number of the tenant's observations, soft-deleted ones included
+func (r *ObservationRepository) Count(ctx context.Context) (int, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM observations WHERE tenant_id = $1`, tenantID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count observations: %w", err)
+	}
+	return count, nil
+}
+
+// ReindexPage returns up to limit of the tenant's observations after cursor in
+// creation order, soft-deleted ones included
+func (r *ObservationRepository) ReindexPage(ctx context.Context, after ReindexCursor, limit int) ([]*models.Observation, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, identifier, based_on, part_of, status, category, code, subject,
+			   focus, encounter, effective_date_time, effective_period, effective_timing,
+			   effective_instant, issued, performer, value_quantity, value_codeable_concept,
+			   value_string, value_boolean, value_integer, value_range, value_ratio,
+			   value_sampled_data, value_time, value_date_time, value_period,
+			   data_absent_reason, interpretation, note, body_site, method, specimen,
+			   device, reference_range, has_member, derived_from, component,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version, deleted_at
+		FROM observations
+		WHERE tenant_id = $1 AND (created_at, id) > ($2, $3)
+		ORDER BY created_at, id
+		LIMIT $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, tenantID, after.CreatedAt, after.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list observations for reindex: %w", err)
+	}
+	defer rows.Close()
+
+	var observations []*models.Observation
+	for rows.Next() {
+		var deletedAt *time.Time
+		observation, err := scanObservation(rows, &deletedAt)
+		if err != nil {
+			return nil, err
+		}
+		observation.DeletedAt = deletedAt
+		observations = append(observations, observation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate observations: %w", err)
+	}
+
+	return observations, nil
+}
+
+// UpdateSearchColumns recomputes the extracted search columns of observations in
+// one transaction
+func (r *ObservationRepository) UpdateSearchColumns(ctx context.Context, observations []*models.Observation) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `
+			UPDATE observations SET code_values = $3, subject_reference = $4, effective_date = $5
+			WHERE tenant_id = $1 AND id = $2
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare search column update: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, observation := range observations {
+			cols := ExtractObservationSearchColumns(observation)
+			if _, err := stmt.ExecContext(ctx, tenantID, observation.ID, pq.Array(cols.CodeValues), cols.SubjectReference, cols.EffectiveDate); err != nil {
+				return fmt.Errorf("failed to update search columns of observation %s: %w", observation.ID, err)
+			}
+		}
+		return nil
+	})
+}
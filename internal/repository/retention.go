@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+)
+
+// retentionTables maps FHIR resource types to the tables holding soft-deleted rows
+var retentionTables = map[string]string{
+	"Patient":     "patients",
+	"Observation": "observations",
+}
+
+// RetentionResourceTypes returns the resource types covered by retention policies
+func RetentionResourceTypes() []string {
+	return []string{"Patient", "Observation"}
+}
+
+type RetentionRepository struct {
+	*BaseRepository
+}
+
+func NewRetentionRepository(db *database.DB) *RetentionRepository {
+	return &RetentionRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// CountExpiredAuditLogs counts audit log entries for a resource type recorded before cutoff
+func (r *RetentionRepository) CountExpiredAuditLogs(ctx context.Context, resourceType string, cutoff time.Time) (int64, error) {
+	query := `SELECT COUNT(*) FROM audit_logs WHERE resource_type = $1 AND timestamp < $2`
+
+	var count int64
+	if err := r.db.QueryRowContext(ctx, query, resourceType, cutoff).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count expired audit logs: %w", err)
+	}
+
+	return count, nil
+}
+
+// ArchiveAuditLogs moves up to limit audit log entries recorded before cutoff into
+// archived_audit_logs. The copy and the delete happen in a single statement.
+func (r *RetentionRepository) ArchiveAuditLogs(ctx context.Context, resourceType string, cutoff time.Time, limit int) (int64, error) {
+	query := `
+		WITH moved AS (
+			DELETE FROM audit_logs
+			WHERE (id, timestamp) IN (
+				SELECT id, timestamp FROM audit_logs
+				WHERE resource_type = $1 AND timestamp < $2
+				ORDER BY timestamp
+				LIMIT $3
+			)
+			RETURNING id, resource_type, resource_id, action, user_id, user_agent,
+				ip_address, request_id, old_values, new_values, timestamp, tenant_id
+		)
+		INSERT INTO archived_audit_logs (
+			id, resource_type, resource_id, action, user_id, user_agent,
+			ip_address, request_id, old_values, new_values, timestamp, tenant_id
+		)
+		SELECT * FROM moved
+		ON CONFLICT (id, timestamp) DO NOTHING
+	`
+
+	// Archival competes with live writes, so run it with serialization/deadlock retry
+	var archived int64
+	err := r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, resourceType, cutoff, limit)
+		if err != nil {
+			return err
+		}
+		archived, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive audit logs: %w", err)
+	}
+
+	return archived, nil
+}
+
+// CountExpiredDeletedResources counts resources of a type soft-deleted before cutoff
+func (r *RetentionRepository) CountExpiredDeletedResources(ctx context.Context, resourceType string, cutoff time.Time) (int64, error) {
+	table, ok := retentionTables[resourceType]
+	if !ok {
+		return 0, fmt.Errorf("%w resource type: %s", models.ErrUnsupported, resourceType)
+	}
+
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE deleted_at IS NOT NULL AND deleted_at < $1`, table)
+
+	var count int64
+	if err := r.db.QueryRowContext(ctx, query, cutoff).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count expired deleted resources: %w", err)
+	}
+
+	return count, nil
+}
+
+// ArchiveDeletedResources moves up to limit resources soft-deleted before cutoff into
+// archived_resources and purges them from the live table
+func (r *RetentionRepository) ArchiveDeletedResources(ctx context.Context, resourceType string, cutoff time.Time, limit int) (int64, error) {
+	table, ok := retentionTables[resourceType]
+	if !ok {
+		return 0, fmt.Errorf("%w resource type: %s", models.ErrUnsupported, resourceType)
+	}
+
+	query := fmt.Sprintf(`
+		WITH moved AS (
+			DELETE FROM %s
+			WHERE id IN (
+				SELECT id FROM %s
+				WHERE deleted_at IS NOT NULL AND deleted_at < $2
+				ORDER BY deleted_at
+				LIMIT $3
+			)
+			RETURNING *
+		)
+		INSERT INTO archived_resources (resource_type, resource_id, payload, deleted_at, tenant_id)
+		SELECT $1, moved.id, to_jsonb(moved), moved.deleted_at, moved.tenant_id FROM moved
+	`, table, table)
+
+	var archived int64
+	err := r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, resourceType, cutoff, limit)
+		if err != nil {
+			return err
+		}
+		archived, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive deleted resources: %w", err)
+	}
+
+	return archived, nil
+}
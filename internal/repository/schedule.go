@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ScheduleRepository manages recurring job schedules. Schedules are deployment-wide,
+// so queries are not tenant-scoped. Run transitions are conditional updates, so when
+// several instances poll the same schedules only one of them acts on each occurrence.
+type ScheduleRepository struct {
+	*BaseRepository
+}
+
+func NewScheduleRepository(db *database.DB) *ScheduleRepository {
+	return &ScheduleRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+const scheduleColumns = `name, job_type, spec, payload, timeout_ms, priority, enabled, source,
+	next_run_at, running_job_id, running_since, last_run_at, last_finished_at, last_status, last_error`
+
+// SyncConfigured upserts the schedules defined in configuration and disables
+// configuration-sourced schedules that are no longer defined. A changed spec
+// clears next_run_at so the next occurrence is recomputed.
+func (r *ScheduleRepository) SyncConfigured(ctx context.Context, schedules []*models.JobSchedule) error {
+	return r.db.RunInTx(ctx, nil, func(tx *sql.Tx) error {
+		names := make([]string, 0, len(schedules))
+		for _, schedule := range schedules {
+			names = append(names, schedule.Name)
+
+			var payload interface{}
+			if len(schedule.Payload) > 0 {
+				payload = string(schedule.Payload)
+			}
+
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO job_schedules (name, job_type, spec, payload, timeout_ms, priority, enabled, source)
+				VALUES ($1, $2, $3, $4, $5, $6, TRUE, 'config')
+				ON CONFLICT (name) DO UPDATE SET
+					job_type = EXCLUDED.job_type,
+					spec = EXCLUDED.spec,
+					payload = EXCLUDED.payload,
+					timeout_ms = EXCLUDED.timeout_ms,
+					priority = EXCLUDED.priority,
+					enabled = TRUE,
+					source = 'config',
+					next_run_at = CASE WHEN job_schedules.spec = EXCLUDED.spec AND job_schedules.enabled
+						THEN job_schedules.next_run_at END,
+					updated_at = NOW()
+			`, schedule.Name, schedule.JobType, schedule.Spec, payload, schedule.Timeout.Milliseconds(), schedule.Priority)
+			if err != nil {
+				return fmt.Errorf("failed to sync schedule %s: %w", schedule.Name, err)
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			UPDATE job_schedules SET enabled = FALSE, next_run_at = NULL, updated_at = NOW()
+			WHERE source = 'config' AND enabled AND NOT (name = ANY($1))
+		`, pq.Array(names))
+		if err != nil {
+			return fmt.Errorf("failed to disable removed schedules: %w", err)
+		}
+		return nil
+	})
+}
+
+// List returns all schedules ordered by name
+func (r *ScheduleRepository) List(ctx context.Context) ([]*models.JobSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+scheduleColumns+` FROM job_schedules ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []*models.JobSchedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// SetNextRun initialises next_run_at for a schedule that has none yet
+func (r *ScheduleRepository) SetNextRun(ctx context.Context, name string, next time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE job_schedules SET next_run_at = $2
+		WHERE name = $1 AND next_run_at IS NULL
+	`, name, next)
+	if err != nil {
+		return fmt.Errorf("failed to set next run: %w", err)
+	}
+	return nil
+}
+
+// Claim marks a due schedule as running jobID and advances it to next. It fails to
+// claim when another instance got there first or the previous run is still in
+// flight and started less than staleAfter ago.
+func (r *ScheduleRepository) Claim(ctx context.Context, name, jobID string, next time.Time, staleAfter time.Duration) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE job_schedules SET
+			running_job_id = $2, running_since = NOW(), last_run_at = NOW(),
+			next_run_at = $3, last_status = $5, last_error = NULL
+		WHERE name = $1 AND enabled AND next_run_at <= NOW()
+			AND (running_job_id IS NULL OR running_since < NOW() - make_interval(secs => $4))
+	`, name, jobID, next, staleAfter.Seconds(), models.ScheduleStatusRunning)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim schedule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// Skip advances a due schedule to next without running it
+func (r *ScheduleRepository) Skip(ctx context.Context, name string, next time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE job_schedules SET next_run_at = $2
+		WHERE name = $1 AND enabled AND next_run_at <= NOW()
+	`, name, next)
+	if err != nil {
+		return false, fmt.Errorf("failed to skip schedule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// Finish records the outcome of run jobID, if it is still the schedule's current run
+func (r *ScheduleRepository) Finish(ctx context.Context, name, jobID, status string, runErr error) error {
+	var errText *string
+	if runErr != nil {
+		text := runErr.Error()
+		errText = &text
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE job_schedules SET
+			running_job_id = NULL, running_since = NULL, last_finished_at = NOW(),
+			last_status = $3, last_error = $4
+		WHERE name = $1 AND running_job_id = $2
+	`, name, jobID, status, errText)
+	if err != nil {
+		return fmt.Errorf("failed to record schedule run: %w", err)
+	}
+	return nil
+}
+
+func scanSchedule(scanner rowScanner) (*models.JobSchedule, error) {
+	schedule := &models.JobSchedule{}
+	var payload []byte
+	var timeoutMS int64
+	err := scanner.Scan(
+		&schedule.Name,
+		&schedule.JobType,
+		&schedule.Spec,
+		&payload,
+		&timeoutMS,
+		&schedule.Priority,
+		&schedule.Enabled,
+		&schedule.Source,
+		&schedule.NextRunAt,
+		&schedule.RunningJobID,
+		&schedule.RunningSince,
+		&schedule.LastRunAt,
+		&schedule.LastFinishedAt,
+		&schedule.LastStatus,
+		&schedule.LastError,
+	)
+	schedule.Payload = payload
+	schedule.Timeout = time.Duration(timeoutMS) * time.Millisecond
+	return schedule, err
+}
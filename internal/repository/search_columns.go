@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+)
+
+// PatientSearchColumns holds the extracted, indexed columns for a patient
+type PatientSearchColumns struct {
+	FamilyName       *string
+	IdentifierValues []string
+	SearchText       string
+}
+
+// ObservationSearchColumns holds the extracted, indexed columns for an observation
+type ObservationSearchColumns struct {
+	CodeValues       []string
+	SubjectReference *string
+	EffectiveDate    *time.Time
+}
+
+// DiagnosticReportSearchColumns holds the extracted, indexed columns for a diagnostic report
+type DiagnosticReportSearchColumns struct {
+	CodeValues       []string
+	SubjectReference *string
+}
+
+// ImagingStudySearchColumns holds the extracted, indexed columns for an imaging study
+type ImagingStudySearchColumns struct {
+	StudyInstanceUID string
+	AccessionNumber  *string
+	ModalityCodes    []string
+	SubjectReference *string
+}
+
+// ExtractPatientSearchColumns derives the search columns stored alongside a patient
+func ExtractPatientSearchColumns(patient *models.Patient) PatientSearchColumns {
+	cols := PatientSearchColumns{
+		IdentifierValues: []string{},
+	}
+
+	// Prefer the official name, fall back to the first name with a family part
+	for _, name := range patient.Name {
+		if name.Family == nil {
+			continue
+		}
+		if cols.FamilyName == nil || (name.Use != nil && *name.Use == "official") {
+			family := strings.ToLower(*name.Family)
+			cols.FamilyName = &family
+		}
+		if name.Use != nil && *name.Use == "official" {
+			break
+		}
+	}
+
+	for _, identifier := range patient.Identifier {
+		if identifier.Value != nil && *identifier.Value != "" {
+			cols.IdentifierValues = append(cols.IdentifierValues, *identifier.Value)
+		}
+	}
+
+	// Demographic text indexed for full-text and trigram search
+	var terms []string
+	for _, name := range patient.Name {
+		if name.Text != nil {
+			terms = append(terms, *name.Text)
+		}
+		if name.Family != nil {
+			terms = append(terms, *name.Family)
+		}
+		terms = append(terms, name.Given...)
+	}
+	terms = append(terms, cols.IdentifierValues...)
+	for _, address := range patient.Address {
+		if address.City != nil {
+			terms = append(terms, *address.City)
+		}
+		if address.PostalCode != nil {
+			terms = append(terms, *address.PostalCode)
+		}
+		terms = append(terms, address.Line...)
+	}
+	cols.SearchText = strings.ToLower(strings.Join(terms, " "))
+
+	return cols
+}
+
+// ExtractObservationSearchColumns derives the search columns stored alongside an observation
+func ExtractObservationSearchColumns(observation *models.Observation) ObservationSearchColumns {
+	cols := ObservationSearchColumns{
+		CodeValues:       codeValues(observation.Code),
+		SubjectReference: observation.Subject.Reference,
+	}
+
+	switch {
+	case observation.EffectiveDateTime != nil:
+		cols.EffectiveDate = observation.EffectiveDateTime
+	case observation.EffectivePeriod != nil && observation.EffectivePeriod.Start != nil:
+		cols.EffectiveDate = observation.EffectivePeriod.Start
+	case observation.EffectiveInstant != nil:
+		cols.EffectiveDate = observation.EffectiveInstant
+	}
+
+	return cols
+}
+
+// ExtractDiagnosticReportSearchColumns derives the search columns stored alongside a diagnostic report
+func ExtractDiagnosticReportSearchColumns(report *models.DiagnosticReport) DiagnosticReportSearchColumns {
+	return DiagnosticReportSearchColumns{
+		CodeValues:       codeValues(report.Code),
+		SubjectReference: report.Subject.Reference,
+	}
+}
+
+// ExtractImagingStudySearchColumns derives the search columns stored alongside an
+// imaging study: its study instance UID from the urn:dicom:uid identifier, and
+// its accession number from the identifier of type ACSN
+func ExtractImagingStudySearchColumns(study *models.ImagingStudy) ImagingStudySearchColumns {
+	cols := ImagingStudySearchColumns{
+		ModalityCodes:    codeValues(models.CodeableConcept{Coding: study.Modality}),
+		SubjectReference: study.Subject.Reference,
+	}
+
+	for _, identifier := range study.Identifier {
+		if identifier.Value == nil {
+			continue
+		}
+		if identifier.System != nil && *identifier.System == models.DICOMUIDSystem && cols.StudyInstanceUID == "" {
+			cols.StudyInstanceUID = strings.TrimPrefix(*identifier.Value, "urn:oid:")
+		}
+		if identifier.Type != nil && cols.AccessionNumber == nil {
+			for _, coding := range identifier.Type.Coding {
+				if coding.System != nil && *coding.System == models.IdentifierTypeSystem && coding.Code != nil && *coding.Code == "ACSN" {
+					cols.AccessionNumber = identifier.Value
+					break
+				}
+			}
+		}
+	}
+
+	return cols
+}
+
+// codeValues returns the codes of a concept, each both bare and as system|code,
+// for matching the code search parameter
+func codeValues(concept models.CodeableConcept) []string {
+	values := []string{}
+	seen := make(map[string]bool)
+	for _, coding := range concept.Coding {
+		if coding.Code == nil {
+			continue
+		}
+		codes := []string{*coding.Code}
+		if coding.System != nil {
+			codes = append(codes, *coding.System+"|"+*coding.Code)
+		}
+		for _, value := range codes {
+			if !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
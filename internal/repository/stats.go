@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+)
+
+// StatsRepository computes the aggregate statistics of a tenant's data with
+// grouped queries, so no resource is loaded to be counted
+type StatsRepository struct {
+	*BaseRepository
+}
+
+func NewStatsRepository(db *database.DB) *StatsRepository {
+	return &StatsRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// ResourceCounts counts the tenant's live resources of each stored type
+func (r *StatsRepository) ResourceCounts(ctx context.Context) ([]models.ResourceTypeCount, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, `
+		SELECT 'Patient', COUNT(*) FROM patients WHERE tenant_id = $1 AND deleted_at IS NULL
+		UNION ALL
+		SELECT 'Observation', COUNT(*) FROM observations WHERE tenant_id = $1 AND deleted_at IS NULL
+		UNION ALL
+		SELECT 'DiagnosticReport', COUNT(*) FROM diagnostic_reports WHERE tenant_id = $1 AND deleted_at IS NULL
+		UNION ALL
+		SELECT 'ImagingStudy', COUNT(*) FROM imaging_studies WHERE tenant_id = $1 AND deleted_at IS NULL
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count resources: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []models.ResourceTypeCount
+	for rows.Next() {
+		var count models.ResourceTypeCount
+		if err := rows.Scan(&count.ResourceType, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan resource count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count resources: %w", err)
+	}
+	return counts, nil
+}
+
+// ObservationCodes counts the tenant's live observations by the first coding
+// of their code, returning the limit most frequent. Observations coded by text
+// alone are not counted.
+func (r *StatsRepository) ObservationCodes(ctx context.Context, limit int) ([]models.CodeCount, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, `
+		SELECT code->'coding'->0->>'system', code->'coding'->0->>'code',
+			   MAX(code->'coding'->0->>'display'), COUNT(*)
+		FROM observations
+		WHERE tenant_id = $1 AND deleted_at IS NULL
+		  AND code->'coding'->0->>'code' IS NOT NULL
+		GROUP BY 1, 2
+		ORDER BY 4 DESC, 2, 1
+		LIMIT $2
+	`, tenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count observations by code: %w", err)
+	}
+	return scanCodeCounts(rows)
+}
+
+// ObservationCategories counts the tenant's live observations by the first
+// coding of each of their categories
+func (r *StatsRepository) ObservationCategories(ctx context.Context) ([]models.CodeCount, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, `
+		SELECT category.concept->'coding'->0->>'system', category.concept->'coding'->0->>'code',
+			   MAX(category.concept->'coding'->0->>'display'), COUNT(DISTINCT o.id)
+		FROM observations o
+		CROSS JOIN LATERAL jsonb_array_elements(
+			CASE WHEN jsonb_typeof(o.category) = 'array' THEN o.category ELSE '[]'::jsonb END
+		) AS category(concept)
+		WHERE o.tenant_id = $1 AND o.deleted_at IS NULL
+		  AND category.concept->'coding'->0->>'code' IS NOT NULL
+		GROUP BY 1, 2
+		ORDER BY 4 DESC, 2, 1
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count observations by category: %w", err)
+	}
+	return scanCodeCounts(rows)
+}
+
+// ObservationMonths counts the tenant's live observations by the UTC month of
+// their effective date, from the month of since on. Months without
+// observations are left out.
+func (r *StatsRepository) ObservationMonths(ctx context.Context, since time.Time) ([]models.MonthCount, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, `
+		SELECT date_trunc('month', effective_date, 'UTC') AS month, COUNT(*)
+		FROM observations
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND effective_date >= $2
+		GROUP BY month
+		ORDER BY month
+	`, tenantID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count observations by month: %w", err)
+	}
+	return scanMonthCounts(rows)
+}
+
+// PatientRegistrations counts the tenant's live patients by the UTC month they
+// were created in, from the month of since on. Months without registrations
+// are left out.
+func (r *StatsRepository) PatientRegistrations(ctx context.Context, since time.Time) ([]models.MonthCount, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, `
+		SELECT date_trunc('month', created_at, 'UTC') AS month, COUNT(*)
+		FROM patients
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND created_at >= $2
+		GROUP BY month
+		ORDER BY month
+	`, tenantID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count patient registrations: %w", err)
+	}
+	return scanMonthCounts(rows)
+}
+
+func scanCodeCounts(rows *sql.Rows) ([]models.CodeCount, error) {
+	defer rows.Close()
+
+	var counts []models.CodeCount
+	for rows.Next() {
+		var count models.CodeCount
+		var system, display sql.NullString
+		if err := rows.Scan(&system, &count.Code, &display, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan code count: %w", err)
+		}
+		count.System, count.Display = system.String, display.String
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read code counts: %w", err)
+	}
+	return counts, nil
+}
+
+func scanMonthCounts(rows *sql.Rows) ([]models.MonthCount, error) {
+	defer rows.Close()
+
+	var counts []models.MonthCount
+	for rows.Next() {
+		var count models.MonthCount
+		if err := rows.Scan(&count.Month, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan month count: %w", err)
+		}
+		count.Month = count.Month.UTC()
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read month counts: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"healthcare-api/internal/models"
+)
+
+// ErrStopIteration can be returned from an iteration callback to stop early without an error
+var ErrStopIteration = errors.New("stop iteration")
+
+// Each streams every live patient matching search to fn, one row at a time,
+// in creation order. Rows are never materialised as a slice, so memory stays
+// bounded regardless of table size.
+func (r *PatientRepository) Each(ctx context.Context, search PatientSearchParams, fn func(*models.Patient) error) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	where, args := search.whereClause(tenantID)
+
+	query := `
+		SELECT id, identifier, active, name, telecom, gender, birth_date,
+			   deceased_boolean, deceased_date_time, address, marital_status,
+			   multiple_birth_boolean, multiple_birth_integer, photo, contact,
+			   communication, general_practitioner, managing_organization, link,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version
+		FROM patients
+		` + where + `
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to stream patients: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		patient, err := scanPatient(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(patient); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate patients: %w", err)
+	}
+
+	return nil
+}
+
+// Each streams every live observation to fn, one row at a time, in creation order
+func (r *ObservationRepository) Each(ctx context.Context, fn func(*models.Observation) error) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT id, identifier, based_on, part_of, status, category, code, subject,
+			   focus, encounter, effective_date_time, effective_period, effective_timing,
+			   effective_instant, issued, performer, value_quantity, value_codeable_concept,
+			   value_string, value_boolean, value_integer, value_range, value_ratio,
+			   value_sampled_data, value_time, value_date_time, value_period,
+			   data_absent_reason, interpretation, note, body_site, method, specimen,
+			   device, reference_range, has_member, derived_from, component,
+			   meta, implicit_rules, language, text, contained, extension,
+			   modifier_extension, created_at, updated_at, version
+		FROM observations
+		WHERE tenant_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to stream observations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		observation, err := scanObservation(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(observation); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate observations: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// TenantRepository manages tenants. Tenants are global, so queries are not tenant-scoped.
+type TenantRepository struct {
+	*BaseRepository
+}
+
+func NewTenantRepository(db *database.DB) *TenantRepository {
+	return &TenantRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+const tenantColumns = `id, name, status, rate_limit_per_minute, rate_limit_burst, created_at, updated_at`
+
+func (r *TenantRepository) Create(ctx context.Context, tenant *models.Tenant) error {
+	query := `
+		INSERT INTO tenants (id, name, status, rate_limit_per_minute, rate_limit_burst)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		tenant.ID,
+		tenant.Name,
+		tenant.Status,
+		tenant.RateLimitPerMinute,
+		tenant.RateLimitBurst,
+	).Scan(&tenant.CreatedAt, &tenant.UpdatedAt)
+
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return models.ErrTenantExists
+		}
+		return fmt.Errorf("failed to create tenant: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TenantRepository) GetByID(ctx context.Context, id string) (*models.Tenant, error) {
+	query := `SELECT ` + tenantColumns + ` FROM tenants WHERE id = $1`
+
+	tenant, err := scanTenant(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrTenantNotFound
+		}
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	return tenant, nil
+}
+
+func (r *TenantRepository) Update(ctx context.Context, tenant *models.Tenant) error {
+	query := `
+		UPDATE tenants SET
+			name = $2, status = $3, rate_limit_per_minute = $4, rate_limit_burst = $5, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		tenant.ID,
+		tenant.Name,
+		tenant.Status,
+		tenant.RateLimitPerMinute,
+		tenant.RateLimitBurst,
+	).Scan(&tenant.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ErrTenantNotFound
+		}
+		return fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TenantRepository) List(ctx context.Context) ([]*models.Tenant, error) {
+	query := `SELECT ` + tenantColumns + ` FROM tenants ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []*models.Tenant
+	for rows.Next() {
+		tenant, err := scanTenant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, tenant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tenants: %w", err)
+	}
+
+	return tenants, nil
+}
+
+func scanTenant(scanner rowScanner) (*models.Tenant, error) {
+	tenant := &models.Tenant{}
+	err := scanner.Scan(
+		&tenant.ID,
+		&tenant.Name,
+		&tenant.Status,
+		&tenant.RateLimitPerMinute,
+		&tenant.RateLimitBurst,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+	return tenant, err
+}
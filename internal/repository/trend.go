@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TrendQuery selects the results a trend summarizes: the live observations of
+// a subject and code effective in [From, To) with an exact quantity value
+type TrendQuery struct {
+	// Subject is a reference such as "Patient/<id>"
+	Subject string
+	Code    string
+	From    time.Time
+	To      time.Time
+	// Bucket is the width of the buckets, which are aligned to multiples of it
+	// since the Unix epoch, in UTC
+	Bucket time.Duration
+}
+
+// TrendBucket summarizes the results in one unit and bucket
+type TrendBucket struct {
+	// Unit is the quantity's code, or its unit text without one
+	Unit  string
+	Start time.Time
+	Count int
+	Avg   float64
+	Min   float64
+	Max   float64
+}
+
+// Trend aggregates the results selected by query per unit and bucket, ordered
+// by unit and then bucket. Buckets without results are left out.
+func (r *ObservationRepository) Trend(ctx context.Context, query TrendQuery) ([]TrendBucket, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Reader(ctx).QueryContext(ctx, `
+		SELECT unit,
+			   to_timestamp(floor(extract(epoch FROM effective_date) / $6) * $6) AS bucket,
+			   COUNT(*), AVG(value), MIN(value), MAX(value)
+		FROM (
+			SELECT effective_date,
+				   COALESCE(value_quantity->>'code', value_quantity->>'unit', '') AS unit,
+				   (value_quantity->>'value')::double precision AS value
+			FROM observations
+			WHERE tenant_id = $1 AND deleted_at IS NULL
+			  AND subject_reference = $2 AND code_values @> $3
+			  AND effective_date >= $4 AND effective_date < $5
+			  AND jsonb_typeof(value_quantity->'value') = 'number'
+			  AND NOT value_quantity ? 'comparator'
+		) results
+		GROUP BY unit, bucket
+		ORDER BY unit, bucket
+	`, tenantID, query.Subject, pq.Array([]string{query.Code}), query.From, query.To, query.Bucket.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate observation trend: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []TrendBucket
+	for rows.Next() {
+		var bucket TrendBucket
+		if err := rows.Scan(&bucket.Unit, &bucket.Start, &bucket.Count, &bucket.Avg, &bucket.Min, &bucket.Max); err != nil {
+			return nil, fmt.Errorf("failed to scan observation trend: %w", err)
+		}
+		bucket.Start = bucket.Start.UTC()
+		buckets = append(buckets, bucket)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate observation trend: %w", err)
+	}
+
+	return buckets, nil
+}
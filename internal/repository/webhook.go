@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/database"
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// WebhookRepository manages the tenant's webhooks and their delivery log
+type WebhookRepository struct {
+	*BaseRepository
+}
+
+func NewWebhookRepository(db *database.DB) *WebhookRepository {
+	return &WebhookRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+const webhookColumns = `id, url, description, events, active, secret, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, webhook_id, event_id, event_type, payload, attempt, url, success,
+			   status_code, response_body, error, duration_ms, delivered_at`
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (id, tenant_id, url, description, events, secret, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at
+	`, webhook.ID, tenantID, webhook.URL, webhook.Description, pq.Array(webhook.Events),
+		webhook.Secret, webhook.Active).Scan(&webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1 AND tenant_id = $2`
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// List returns the tenant's webhooks, oldest first; activeOnly skips paused ones
+func (r *WebhookRepository) List(ctx context.Context, activeOnly bool) ([]*models.Webhook, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE tenant_id = $1`
+	if activeOnly {
+		query += ` AND active`
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []*models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		UPDATE webhooks SET url = $3, description = $4, events = $5, secret = $6, active = $7
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING updated_at
+	`, webhook.ID, tenantID, webhook.URL, webhook.Description, pq.Array(webhook.Events),
+		webhook.Secret, webhook.Active).Scan(&webhook.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrWebhookNotFound
+		}
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a webhook together with its delivery log
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return models.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// AddDelivery records a delivery attempt
+func (r *WebhookRepository) AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, tenant_id, event_id, event_type, payload, attempt, url,
+			success, status_code, response_body, error, duration_ms, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id
+	`, delivery.WebhookID, tenantID, delivery.EventID, delivery.EventType, string(delivery.Payload),
+		delivery.Attempt, delivery.URL, delivery.Success, delivery.StatusCode, delivery.ResponseBody,
+		delivery.Error, delivery.DurationMS, delivery.DeliveredAt).Scan(&delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// Delivered reports whether the event has been delivered to the webhook successfully
+func (r *WebhookRepository) Delivered(ctx context.Context, webhookID uuid.UUID, eventID string) (bool, error) {
+	var delivered bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM webhook_deliveries WHERE webhook_id = $1 AND event_id = $2 AND success)
+	`, webhookID, eventID).Scan(&delivered)
+	if err != nil {
+		return false, fmt.Errorf("failed to check webhook deliveries: %w", err)
+	}
+	return delivered, nil
+}
+
+// WebhookDeliveryFilter narrows delivery listing
+type WebhookDeliveryFilter struct {
+	EventID string
+	// Success filters by outcome when set
+	Success *bool
+}
+
+func (f WebhookDeliveryFilter) whereClause(tenantID string, webhookID uuid.UUID) (string, []interface{}) {
+	conditions := []string{"tenant_id = $1", "webhook_id = $2"}
+	args := []interface{}{tenantID, webhookID}
+
+	if f.EventID != "" {
+		args = append(args, f.EventID)
+		conditions = append(conditions, fmt.Sprintf("event_id = $%d", len(args)))
+	}
+	if f.Success != nil {
+		args = append(args, *f.Success)
+		conditions = append(conditions, fmt.Sprintf("success = $%d", len(args)))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// ListDeliveries returns a webhook's delivery attempts, most recent first
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, filter WebhookDeliveryFilter, params PaginationParams) ([]*models.WebhookDelivery, PaginationResult, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, PaginationResult{}, err
+	}
+
+	where, args := filter.whereClause(tenantID, webhookID)
+
+	var total int64
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhook_deliveries `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to get webhook delivery count: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webhook_deliveries
+		%s
+		ORDER BY delivered_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, webhookDeliveryColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, PaginationResult{}, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, PaginationResult{}, fmt.Errorf("failed to iterate webhook deliveries: %w", err)
+	}
+
+	return deliveries, GetPaginationResult(total, params), nil
+}
+
+// GetDelivery returns one delivery attempt of a webhook
+func (r *WebhookRepository) GetDelivery(ctx context.Context, webhookID uuid.UUID, id int64) (*models.WebhookDelivery, error) {
+	tenantID, err := r.tenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2 AND tenant_id = $3`
+	delivery, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, id, webhookID, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// PruneDeliveries deletes delivery attempts of every tenant made before cutoff
+func (r *WebhookRepository) PruneDeliveries(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE delivered_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune webhook deliveries: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows, nil
+}
+
+func scanWebhook(scanner rowScanner) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	err := scanner.Scan(
+		&webhook.ID,
+		&webhook.URL,
+		&webhook.Description,
+		pq.Array(&webhook.Events),
+		&webhook.Active,
+		&webhook.Secret,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func scanWebhookDelivery(scanner rowScanner) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	var payload []byte
+	err := scanner.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&payload,
+		&delivery.Attempt,
+		&delivery.URL,
+		&delivery.Success,
+		&delivery.StatusCode,
+		&delivery.ResponseBody,
+		&delivery.Error,
+		&delivery.DurationMS,
+		&delivery.DeliveredAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+	}
+	delivery.Payload = payload
+	return delivery, nil
+}
@@ -0,0 +1,25 @@
+package requestctx
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// LogHook adds the request ID to log entries made with a context carrying one,
+// i.e. through logger.WithContext(ctx)
+type LogHook struct{}
+
+// Levels reports that the hook applies to every level
+func (LogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds the request_id field
+func (LogHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if requestID := RequestID(entry.Context); requestID != "" {
+		entry.Data["request_id"] = requestID
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Package sampleddata downsamples the SampledData of waveform observations,
+// such as ECG strips, so they can be viewed without transferring every raw
+// sample.
+//
+// Decimation is peak-preserving: the samples are split into buckets of
+// consecutive points, and each bucket is replaced by its lowest and highest
+// value of each dimension, in the order they occur. A QRS complex narrower
+// than a bucket therefore keeps its amplitude, which averaging or keeping every
+// nth sample would lose. The result is still evenly spaced SampledData, with
+// two points per bucket and the period adjusted to match.
+package sampleddata
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+)
+
+// ErrMalformed is returned for data that is not a whole number of points of
+// decimals or the E, L and U markers
+var ErrMalformed = errors.New("malformed sampled data")
+
+// Target is how far to decimate. Zero fields set no limit; with both set, the
+// coarser result wins.
+type Target struct {
+	// MaxPoints is the most points to return, at least 2
+	MaxPoints int
+	// Resolution is the least time between returned points
+	Resolution time.Duration
+}
+
+// Decimate returns a copy of data reduced to target, reporting whether it was
+// reduced. Data already within the target is returned as is.
+func Decimate(data *models.SampledData, target Target) (*models.SampledData, bool, error) {
+	if data == nil || data.Data == nil || data.Dimensions < 1 {
+		return data, false, nil
+	}
+	samples := strings.Fields(*data.Data)
+	if len(samples)%data.Dimensions != 0 {
+		return nil, false, fmt.Errorf("%w: %d samples are not a whole number of %d-dimension points", ErrMalformed, len(samples), data.Dimensions)
+	}
+	points := len(samples) / data.Dimensions
+
+	bucket := 0
+	if target.MaxPoints >= 2 && points > target.MaxPoints {
+		bucket = ceilDiv(points, target.MaxPoints/2)
+	}
+	if target.Resolution > 0 && data.Period > 0 {
+		resolution := float64(target.Resolution) / float64(time.Millisecond)
+		if n := int(math.Ceil(2 * resolution / data.Period)); n > bucket {
+			bucket = n
+		}
+	}
+	// Two points per bucket of two or fewer points reduces nothing
+	if bucket <= 2 {
+		return data, false, nil
+	}
+
+	out := make([]string, 0, 2*ceilDiv(points, bucket)*data.Dimensions)
+	first := make([]string, data.Dimensions)
+	second := make([]string, data.Dimensions)
+	for start := 0; start < points; start += bucket {
+		end := start + bucket
+		if end > points {
+			end = points
+		}
+		for dimension := 0; dimension < data.Dimensions; dimension++ {
+			var err error
+			first[dimension], second[dimension], err = extremes(samples, data.Dimensions, dimension, start, end)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+		out = append(out, first...)
+		out = append(out, second...)
+	}
+
+	decimated := *data
+	joined := strings.Join(out, " ")
+	decimated.Data = &joined
+	decimated.Period = data.Period * float64(bucket) / 2
+	return &decimated, true, nil
+}
+
+// extremes returns the lowest and highest samples of a dimension over points
+// start to end, in the order they occur. L, below the lower limit, is lowest
+// and U, above the upper limit, highest; E, an error, is skipped unless the
+// bucket has nothing else.
+func extremes(samples []string, dimensions, dimension, start, end int) (string, string, error) {
+	low, high := -1, -1
+	var lowValue, highValue float64
+	for point := start; point < end; point++ {
+		i := point*dimensions + dimension
+		var value float64
+		switch samples[i] {
+		case "E":
+			continue
+		case "L":
+			value = math.Inf(-1)
+		case "U":
+			value = math.Inf(1)
+		default:
+			var err error
+			if value, err = strconv.ParseFloat(samples[i], 64); err != nil {
+				return "", "", fmt.Errorf("%w: sample %q", ErrMalformed, samples[i])
+			}
+		}
+		if low < 0 || value < lowValue {
+			low, lowValue = i, value
+		}
+		if high < 0 || value > highValue {
+			high, highValue = i, value
+		}
+	}
+
+	if low < 0 {
+		return "E", "E", nil
+	}
+	if high < low {
+		low, high = high, low
+	}
+	return samples[low], samples[high], nil
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
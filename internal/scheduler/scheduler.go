@@ -0,0 +1,295 @@
+// Package scheduler enqueues recurring background jobs from cron schedules kept in
+// the job_schedules table. Every API instance may run a scheduler: due occurrences
+// are claimed with conditional updates, so each one is submitted once, and an
+// occurrence is skipped while the schedule's previous run is still in flight.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/worker"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultRunTimeout applies to schedules without their own timeout, matching the worker default
+	defaultRunTimeout = 30 * time.Second
+	// staleGrace is added to twice the run timeout before an unfinished run is presumed lost
+	staleGrace = time.Minute
+	// finishTimeout bounds recording a run's outcome
+	finishTimeout = 5 * time.Second
+)
+
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ParseSpec validates a cron expression
+func ParseSpec(spec string) (cron.Schedule, error) {
+	schedule, err := parser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	return schedule, nil
+}
+
+// Configured returns the schedules defined by the server configuration
+func Configured(cfg *config.Config) ([]*models.JobSchedule, error) {
+	var schedules []*models.JobSchedule
+
+	if cfg.Retention.Enabled {
+		payload, _ := json.Marshal(worker.RetentionPayload{DryRun: cfg.Retention.DryRun})
+		schedules = append(schedules, &models.JobSchedule{
+			Name:     "retention",
+			JobType:  "retention",
+			Spec:     cfg.Retention.Schedule,
+			Payload:  payload,
+			Timeout:  time.Hour,
+			Priority: int(worker.PriorityLow),
+		})
+	}
+
+	if cfg.Scheduler.CacheWarmupSchedule != "" {
+		schedules = append(schedules, &models.JobSchedule{
+			Name:     "cache-warmup",
+			JobType:  "cache-warmup",
+			Spec:     cfg.Scheduler.CacheWarmupSchedule,
+			Priority: int(worker.PriorityLow),
+		})
+	}
+
+	if cfg.Scheduler.JobHistoryCleanupSchedule != "" {
+		payload, _ := json.Marshal(worker.JobHistoryCleanupPayload{Days: cfg.Worker.HistoryDays})
+		schedules = append(schedules, &models.JobSchedule{
+			Name:     "job-history-cleanup",
+			JobType:  "job-history-cleanup",
+			Spec:     cfg.Scheduler.JobHistoryCleanupSchedule,
+			Payload:  payload,
+			Timeout:  10 * time.Minute,
+			Priority: int(worker.PriorityLow),
+		})
+	}
+
+	smsEnabled := cfg.Notifications.SMSProvider != "" && cfg.Notifications.SMSProvider != "none"
+	if smsEnabled && cfg.Scheduler.NotificationEscalationSchedule != "" {
+		schedules = append(schedules, &models.JobSchedule{
+			Name:     "notification-escalation",
+			JobType:  "notification-escalation",
+			Spec:     cfg.Scheduler.NotificationEscalationSchedule,
+			Timeout:  time.Minute,
+			Priority: int(worker.PriorityCritical),
+		})
+	}
+
+	empiEnabled := cfg.EMPI.Provider != "" && cfg.EMPI.Provider != "none"
+	if empiEnabled && cfg.Scheduler.EMPIReconciliationSchedule != "" {
+		schedules = append(schedules, &models.JobSchedule{
+			Name:     "empi-reconciliation",
+			JobType:  "empi-reconciliation",
+			Spec:     cfg.Scheduler.EMPIReconciliationSchedule,
+			Timeout:  30 * time.Minute,
+			Priority: int(worker.PriorityLow),
+		})
+	}
+
+	if cfg.Scheduler.DuplicateDetectionSchedule != "" {
+		schedules = append(schedules, &models.JobSchedule{
+			Name:     "duplicate-detection",
+			JobType:  "duplicate-detection",
+			Spec:     cfg.Scheduler.DuplicateDetectionSchedule,
+			Timeout:  time.Hour,
+			Priority: int(worker.PriorityLow),
+		})
+	}
+
+	for _, schedule := range schedules {
+		if _, err := ParseSpec(schedule.Spec); err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", schedule.Name, err)
+		}
+	}
+
+	return schedules, nil
+}
+
+// Scheduler submits due scheduled jobs to a worker pool
+type Scheduler struct {
+	repo     *repository.ScheduleRepository
+	pool     *worker.WorkerPool
+	interval time.Duration
+	logger   *logrus.Logger
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func New(repo *repository.ScheduleRepository, pool *worker.WorkerPool, cfg config.SchedulerConfig, logger *logrus.Logger) *Scheduler {
+	interval := time.Duration(cfg.PollInterval) * time.Second
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	return &Scheduler{
+		repo:     repo,
+		pool:     pool,
+		interval: interval,
+		logger:   logger,
+		quit:     make(chan struct{}),
+	}
+}
+
+// Sync stores the configured schedules, disabling configured ones that were removed
+func (s *Scheduler) Sync(ctx context.Context, schedules []*models.JobSchedule) error {
+	if err := s.repo.SyncConfigured(ctx, schedules); err != nil {
+		return err
+	}
+	s.logger.WithField("schedules", len(schedules)).Info("Job schedules synced")
+	return nil
+}
+
+// Start polls for due schedules until Stop is called
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.poll()
+			select {
+			case <-ticker.C:
+			case <-s.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends polling and waits for an in-progress poll to finish
+func (s *Scheduler) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// poll submits every due schedule once
+func (s *Scheduler) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+	defer cancel()
+
+	schedules, err := s.repo.List(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load job schedules")
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, schedule := range schedules {
+		if !schedule.Enabled {
+			continue
+		}
+
+		logger := s.logger.WithFields(logrus.Fields{
+			"schedule": schedule.Name,
+			"job_type": schedule.JobType,
+		})
+
+		spec, err := ParseSpec(schedule.Spec)
+		if err != nil {
+			logger.WithError(err).Error("Skipping job schedule")
+			continue
+		}
+
+		// New or changed schedules start at their next occurrence rather than immediately
+		if schedule.NextRunAt == nil {
+			if err := s.repo.SetNextRun(ctx, schedule.Name, spec.Next(now)); err != nil {
+				logger.WithError(err).Error("Failed to initialise job schedule")
+			}
+			continue
+		}
+		if schedule.NextRunAt.After(now) {
+			continue
+		}
+
+		s.run(ctx, logger, schedule, spec.Next(now))
+	}
+}
+
+// run claims a due occurrence and submits its job, or skips it while the previous run is in flight
+func (s *Scheduler) run(ctx context.Context, logger *logrus.Entry, schedule *models.JobSchedule, next time.Time) {
+	timeout := schedule.Timeout
+	if timeout <= 0 {
+		timeout = defaultRunTimeout
+	}
+
+	job := &worker.Job{
+		ID:        uuid.New().String(),
+		Type:      schedule.JobType,
+		CreatedAt: time.Now().UTC(),
+		Timeout:   schedule.Timeout,
+		Schedule:  schedule.Name,
+		Priority:  worker.Priority(schedule.Priority),
+	}
+	if len(schedule.Payload) > 0 {
+		job.Payload = []byte(schedule.Payload)
+	}
+
+	claimed, err := s.repo.Claim(ctx, schedule.Name, job.ID, next, 2*timeout+staleGrace)
+	if err != nil {
+		logger.WithError(err).Error("Failed to claim job schedule")
+		return
+	}
+
+	if !claimed {
+		// Either another instance claimed it, or the previous run has not finished
+		skipped, err := s.repo.Skip(ctx, schedule.Name, next)
+		if err != nil {
+			logger.WithError(err).Error("Failed to skip job schedule")
+		} else if skipped {
+			logger.WithField("running_job_id", schedule.RunningJobID).Warn("Previous run still in progress, skipping scheduled run")
+		}
+		return
+	}
+
+	if err := s.pool.SubmitJob(job); err != nil {
+		logger.WithError(err).Error("Failed to submit scheduled job")
+		if err := s.repo.Finish(ctx, schedule.Name, job.ID, models.ScheduleStatusFailed, err); err != nil {
+			logger.WithError(err).Error("Failed to record scheduled run")
+		}
+		return
+	}
+
+	logger.WithField("job_id", job.ID).Info("Scheduled job submitted")
+}
+
+// JobFinished records the outcome of scheduled jobs; register it with WorkerPool.OnFinished
+// in every process that runs workers
+func (s *Scheduler) JobFinished(job *worker.Job, jobErr error) {
+	if job.Schedule == "" {
+		return
+	}
+
+	status := models.ScheduleStatusSucceeded
+	if jobErr != nil {
+		status = models.ScheduleStatusFailed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer cancel()
+	if err := s.repo.Finish(ctx, job.Schedule, job.ID, status, jobErr); err != nil {
+		s.logger.WithError(err).WithField("schedule", job.Schedule).Error("Failed to record scheduled run")
+	}
+}
+
+// List returns all schedules with their last-run state
+func (s *Scheduler) List(ctx context.Context) ([]*models.JobSchedule, error) {
+	return s.repo.List(ctx)
+}
@@ -0,0 +1,428 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// setupTimeout bounds creating the indices on startup
+const setupTimeout = 30 * time.Second
+
+// Index mappings. Documents are routed by tenant, so a tenant's searches read
+// one shard; "dynamic": "strict" rejects fields the mappings do not know.
+var (
+	patientMappings = json.RawMessage(`{
+		"dynamic": "strict",
+		"properties": {
+			"tenant_id":  {"type": "keyword"},
+			"active":     {"type": "boolean"},
+			"family":     {"type": "text", "copy_to": "all"},
+			"given":      {"type": "text", "copy_to": "all"},
+			"name":       {"type": "text", "copy_to": "all"},
+			"identifier": {"type": "keyword", "copy_to": "all"},
+			"telecom":    {"type": "keyword", "copy_to": "all"},
+			"address":    {"type": "text", "copy_to": "all"},
+			"gender":     {"type": "keyword"},
+			"birth_date": {"type": "date", "format": "yyyy-MM-dd"},
+			"all":        {"type": "text"}
+		}
+	}`)
+	observationMappings = json.RawMessage(`{
+		"dynamic": "strict",
+		"properties": {
+			"tenant_id":      {"type": "keyword"},
+			"status":         {"type": "keyword"},
+			"subject":        {"type": "keyword"},
+			"code":           {"type": "keyword"},
+			"code_text":      {"type": "text", "copy_to": "all"},
+			"category":       {"type": "text", "copy_to": "all"},
+			"value":          {"type": "text", "copy_to": "all"},
+			"interpretation": {"type": "text", "copy_to": "all"},
+			"note":           {"type": "text", "copy_to": "all"},
+			"effective":      {"type": "date"},
+			"all":            {"type": "text"}
+		}
+	}`)
+)
+
+// ElasticsearchIndex is an Index in an Elasticsearch or OpenSearch cluster,
+// spoken to over the REST API. Documents are indexed with external versioning
+// on the resource version, so jobs indexing the same resource may finish in
+// any order.
+type ElasticsearchIndex struct {
+	urls     []*url.URL
+	next     atomic.Uint32
+	indices  map[string]string // resource type to index name
+	apiKey   string
+	username string
+	password string
+	client   *http.Client
+	logger   *logrus.Logger
+}
+
+// NewElasticsearchIndex creates an index on the cluster at cfg.URLs, creating
+// its indices with their mappings when they do not exist
+func NewElasticsearchIndex(cfg config.ElasticsearchConfig, logger *logrus.Logger) (*ElasticsearchIndex, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, errors.New("at least one Elasticsearch URL is required")
+	}
+	if cfg.IndexPrefix == "" || strings.ToLower(cfg.IndexPrefix) != cfg.IndexPrefix {
+		return nil, fmt.Errorf("invalid Elasticsearch index prefix %q: must be lowercase", cfg.IndexPrefix)
+	}
+
+	index := &ElasticsearchIndex{
+		indices: map[string]string{
+			"Patient":     cfg.IndexPrefix + "-patients",
+			"Observation": cfg.IndexPrefix + "-observations",
+		},
+		apiKey:   cfg.APIKey,
+		username: cfg.Username,
+		password: cfg.Password,
+		client:   &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		logger:   logger,
+	}
+	for _, raw := range cfg.URLs {
+		u, err := url.Parse(strings.TrimSuffix(raw, "/"))
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("invalid Elasticsearch URL %q", raw)
+		}
+		index.urls = append(index.urls, u)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+	if err := index.createIndex(ctx, index.indices["Patient"], patientMappings); err != nil {
+		return nil, err
+	}
+	if err := index.createIndex(ctx, index.indices["Observation"], observationMappings); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (e *ElasticsearchIndex) IndexPatients(ctx context.Context, tenantID string, patients []*models.Patient) error {
+	actions := make([]bulkAction, len(patients))
+	for i, patient := range patients {
+		actions[i] = bulkAction{ID: patient.ID, Version: patient.Version, Deleted: patient.DeletedAt != nil}
+		if !actions[i].Deleted {
+			actions[i].Document = newPatientDocument(tenantID, patient)
+		}
+	}
+	return e.bulk(ctx, e.indices["Patient"], tenantID, actions)
+}
+
+func (e *ElasticsearchIndex) IndexObservations(ctx context.Context, tenantID string, observations []*models.Observation) error {
+	actions := make([]bulkAction, len(observations))
+	for i, observation := range observations {
+		actions[i] = bulkAction{ID: observation.ID, Version: observation.Version, Deleted: observation.DeletedAt != nil}
+		if !actions[i].Deleted {
+			actions[i].Document = newObservationDocument(tenantID, observation)
+		}
+	}
+	return e.bulk(ctx, e.indices["Observation"], tenantID, actions)
+}
+
+func (e *ElasticsearchIndex) Remove(ctx context.Context, tenantID, resourceType string, id uuid.UUID) error {
+	name, ok := e.indices[resourceType]
+	if !ok {
+		return fmt.Errorf("%w search index for %s", models.ErrUnsupported, resourceType)
+	}
+	return e.bulk(ctx, name, tenantID, []bulkAction{{ID: id, Deleted: true}})
+}
+
+// SearchPatients matches every term of the text, allowing typos, against the
+// patient's names, identifiers, telecoms and addresses. Exact identifier and
+// name matches rank first.
+func (e *ElasticsearchIndex) SearchPatients(ctx context.Context, tenantID string, query Query) (*Result, error) {
+	return e.search(ctx, e.indices["Patient"], tenantID, query, []map[string]interface{}{
+		{"multi_match": map[string]interface{}{
+			"query":  query.Text,
+			"fields": []string{"identifier^4", "family^3", "given^2", "name^2", "telecom^2"},
+		}},
+	})
+}
+
+// SearchObservations matches every term of the text, allowing typos, against
+// the observation's code, category, value, interpretation and note text. Code
+// matches rank first.
+func (e *ElasticsearchIndex) SearchObservations(ctx context.Context, tenantID string, query Query) (*Result, error) {
+	return e.search(ctx, e.indices["Observation"], tenantID, query, []map[string]interface{}{
+		{"match": map[string]interface{}{
+			"code_text": map[string]interface{}{"query": query.Text, "boost": 3},
+		}},
+	})
+}
+
+// search runs a text search in one tenant's documents. Every term must match
+// "all"; the should clauses only rank.
+func (e *ElasticsearchIndex) search(ctx context.Context, index, tenantID string, query Query, should []map[string]interface{}) (*Result, error) {
+	filter := []map[string]interface{}{
+		{"term": map[string]interface{}{"tenant_id": tenantID}},
+	}
+	if query.Subject != "" {
+		filter = append(filter, map[string]interface{}{"term": map[string]interface{}{"subject": query.Subject}})
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"from":             query.Offset,
+		"size":             query.Limit,
+		"track_total_hits": true,
+		"_source":          false,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": filter,
+				"must": map[string]interface{}{
+					"match": map[string]interface{}{
+						"all": map[string]interface{}{"query": query.Text, "fuzziness": "AUTO", "operator": "and"},
+					},
+				},
+				"should": should,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := e.do(ctx, http.MethodPost, "/"+index+"/_search", url.Values{"routing": {tenantID}}, "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to search %s: %w", index, responseError(resp))
+	}
+
+	var response struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID    string  `json:"_id"`
+				Score float64 `json:"_score"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+
+	result := &Result{Total: response.Hits.Total.Value, Hits: make([]Hit, 0, len(response.Hits.Hits))}
+	for _, hit := range response.Hits.Hits {
+		id, err := uuid.Parse(hit.ID)
+		if err != nil {
+			continue
+		}
+		result.Hits = append(result.Hits, Hit{ID: id, Score: hit.Score})
+	}
+	return result, nil
+}
+
+// bulkAction indexes or deletes one document
+type bulkAction struct {
+	ID       uuid.UUID
+	Version  int
+	Deleted  bool
+	Document interface{}
+}
+
+// bulk applies actions to one tenant's documents in a single request. Version
+// conflicts, which mean a newer version is already indexed, and deletes of
+// documents not indexed are not failures.
+func (e *ElasticsearchIndex) bulk(ctx context.Context, index, tenantID string, actions []bulkAction) error {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, action := range actions {
+		meta := map[string]interface{}{"_index": index, "_id": action.ID.String(), "routing": tenantID}
+		if action.Deleted {
+			if err := encoder.Encode(map[string]interface{}{"delete": meta}); err != nil {
+				return err
+			}
+			continue
+		}
+		meta["version"] = action.Version
+		meta["version_type"] = "external_gte"
+		if err := encoder.Encode(map[string]interface{}{"index": meta}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(action.Document); err != nil {
+			return err
+		}
+	}
+
+	resp, err := e.do(ctx, http.MethodPost, "/_bulk", nil, "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to index into %s: %w", index, responseError(resp))
+	}
+
+	var response struct {
+		Errors bool                        `json:"errors"`
+		Items  []map[string]bulkItemResult `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if !response.Errors {
+		return nil
+	}
+
+	failed := 0
+	var first *bulkItemResult
+	for _, item := range response.Items {
+		for operation, result := range item {
+			if result.Status == http.StatusConflict || (operation == "delete" && result.Status == http.StatusNotFound) || result.Error == nil {
+				continue
+			}
+			failed++
+			if first == nil {
+				result := result
+				first = &result
+			}
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	return fmt.Errorf("failed to index %d of %d documents into %s: document %s: %s: %s",
+		failed, len(actions), index, first.ID, first.Error.Type, first.Error.Reason)
+}
+
+// bulkItemResult is the outcome of one bulk action
+type bulkItemResult struct {
+	ID     string              `json:"_id"`
+	Status int                 `json:"status"`
+	Error  *elasticsearchError `json:"error"`
+}
+
+// createIndex creates an index with its mappings unless it exists
+func (e *ElasticsearchIndex) createIndex(ctx context.Context, index string, mappings json.RawMessage) error {
+	resp, err := e.do(ctx, http.MethodHead, "/"+index, nil, "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to check index %s: Elasticsearch responded %d", index, resp.StatusCode)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"mappings": mappings})
+	if err != nil {
+		return err
+	}
+	resp, err = e.do(ctx, http.MethodPut, "/"+index, nil, "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := responseError(resp)
+		// Another process created it first
+		var esErr *elasticsearchError
+		if errors.As(err, &esErr) && esErr.Type == "resource_already_exists_exception" {
+			return nil
+		}
+		return fmt.Errorf("failed to create index %s: %w", index, err)
+	}
+
+	e.logger.WithField("index", index).Info("Search index created")
+	return nil
+}
+
+// do sends a request to the cluster, moving on to the next node when one
+// cannot be reached or is unavailable. The caller closes the response body.
+func (e *ElasticsearchIndex) do(ctx context.Context, method, path string, query url.Values, contentType string, body []byte) (*http.Response, error) {
+	start := int(e.next.Add(1))
+	var lastErr error
+	for i := range e.urls {
+		node := e.urls[(start+i)%len(e.urls)]
+		u := *node
+		u.Path += path
+		u.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		switch {
+		case e.apiKey != "":
+			req.Header.Set("Authorization", "ApiKey "+e.apiKey)
+		case e.username != "":
+			req.SetBasicAuth(e.username, e.password)
+		}
+
+		resp, err := e.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == http.StatusServiceUnavailable && i < len(e.urls)-1 {
+			resp.Body.Close()
+			lastErr = fmt.Errorf("Elasticsearch node %s is unavailable", node.Host)
+			continue
+		}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("failed to reach Elasticsearch: %w", lastErr)
+}
+
+// elasticsearchError is the error of a failed request or bulk action
+type elasticsearchError struct {
+	StatusCode int    `json:"-"`
+	Type       string `json:"type"`
+	Reason     string `json:"reason"`
+}
+
+func (e *elasticsearchError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("Elasticsearch responded %d", e.StatusCode)
+	}
+	return fmt.Sprintf("Elasticsearch responded %d: %s: %s", e.StatusCode, e.Type, e.Reason)
+}
+
+// responseError reads the error of a failed request from its response
+func responseError(resp *http.Response) error {
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	err := &elasticsearchError{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &body) == nil && len(body.Error) > 0 {
+		// The error is an object, or a string from some proxies and older versions
+		if json.Unmarshal(body.Error, err) != nil {
+			_ = json.Unmarshal(body.Error, &err.Reason)
+		}
+	}
+	return err
+}
@@ -0,0 +1,185 @@
+// Package search keeps an external full-text index of patients and observations
+// for fuzzy text searches. PostgreSQL stays the system of record: the index
+// holds only what searches match on, and results are read back from the
+// database by id.
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Supported backends
+const (
+	// BackendPostgres runs text searches in PostgreSQL and keeps no external index
+	BackendPostgres = "postgres"
+	// BackendElasticsearch indexes into Elasticsearch or OpenSearch
+	BackendElasticsearch = "elasticsearch"
+)
+
+// Query is a text search within one tenant
+type Query struct {
+	Text string
+	// Subject limits observation searches to one subject reference, e.g. "Patient/<id>"
+	Subject string
+	Offset  int
+	Limit   int
+}
+
+// Hit is a matching resource with its relevance score
+type Hit struct {
+	ID    uuid.UUID
+	Score float64
+}
+
+// Result is a page of hits, best first, with the total number of matches
+type Result struct {
+	Total int64
+	Hits  []Hit
+}
+
+// Index is a full-text index of the resources of every tenant. Documents are
+// versioned with the resource, so indexing an older version than the one
+// already indexed leaves the newer in place.
+type Index interface {
+	// IndexPatients adds or replaces the documents of patients, removing those
+	// of soft-deleted ones
+	IndexPatients(ctx context.Context, tenantID string, patients []*models.Patient) error
+	// IndexObservations adds or replaces the documents of observations,
+	// removing those of soft-deleted ones
+	IndexObservations(ctx context.Context, tenantID string, observations []*models.Observation) error
+	// Remove drops the document of a resource; removing one not indexed succeeds
+	Remove(ctx context.Context, tenantID, resourceType string, id uuid.UUID) error
+	SearchPatients(ctx context.Context, tenantID string, query Query) (*Result, error)
+	SearchObservations(ctx context.Context, tenantID string, query Query) (*Result, error)
+}
+
+// New returns the index selected by cfg.Backend, or nil for BackendPostgres
+func New(cfg config.SearchConfig, logger *logrus.Logger) (Index, error) {
+	switch cfg.Backend {
+	case "", BackendPostgres:
+		return nil, nil
+	case BackendElasticsearch:
+		return NewElasticsearchIndex(cfg.Elasticsearch, logger)
+	default:
+		return nil, fmt.Errorf("unsupported search backend: %s", cfg.Backend)
+	}
+}
+
+// patientDocument is what patients are searched on. Every text field is also
+// copied into "all", which the text search matches against.
+type patientDocument struct {
+	TenantID   string   `json:"tenant_id"`
+	Active     *bool    `json:"active,omitempty"`
+	Family     []string `json:"family,omitempty"`
+	Given      []string `json:"given,omitempty"`
+	Name       []string `json:"name,omitempty"`
+	Identifier []string `json:"identifier,omitempty"`
+	Telecom    []string `json:"telecom,omitempty"`
+	Address    []string `json:"address,omitempty"`
+	Gender     *string  `json:"gender,omitempty"`
+	BirthDate  string   `json:"birth_date,omitempty"`
+}
+
+func newPatientDocument(tenantID string, patient *models.Patient) patientDocument {
+	doc := patientDocument{
+		TenantID:   tenantID,
+		Active:     patient.Active,
+		Identifier: repository.ExtractPatientSearchColumns(patient).IdentifierValues,
+		Gender:     patient.Gender,
+	}
+	for _, name := range patient.Name {
+		if name.Family != nil {
+			doc.Family = append(doc.Family, *name.Family)
+		}
+		doc.Given = append(doc.Given, name.Given...)
+		if name.Text != nil {
+			doc.Name = append(doc.Name, *name.Text)
+		}
+	}
+	for _, telecom := range patient.Telecom {
+		if telecom.Value != nil && *telecom.Value != "" {
+			doc.Telecom = append(doc.Telecom, *telecom.Value)
+		}
+	}
+	for _, address := range patient.Address {
+		doc.Address = append(doc.Address, address.Line...)
+		for _, part := range []*string{address.City, address.State, address.PostalCode} {
+			if part != nil && *part != "" {
+				doc.Address = append(doc.Address, *part)
+			}
+		}
+	}
+	if patient.BirthDate != nil {
+		doc.BirthDate = patient.BirthDate.Format("2006-01-02")
+	}
+	return doc
+}
+
+// observationDocument is what observations are searched on. Every text field
+// is also copied into "all", which the text search matches against.
+type observationDocument struct {
+	TenantID       string   `json:"tenant_id"`
+	Status         string   `json:"status"`
+	Subject        *string  `json:"subject,omitempty"`
+	Code           []string `json:"code,omitempty"`
+	CodeText       []string `json:"code_text,omitempty"`
+	Category       []string `json:"category,omitempty"`
+	Value          []string `json:"value,omitempty"`
+	Interpretation []string `json:"interpretation,omitempty"`
+	Note           []string `json:"note,omitempty"`
+	Effective      string   `json:"effective,omitempty"`
+}
+
+func newObservationDocument(tenantID string, observation *models.Observation) observationDocument {
+	cols := repository.ExtractObservationSearchColumns(observation)
+	doc := observationDocument{
+		TenantID: tenantID,
+		Status:   observation.Status,
+		Subject:  cols.SubjectReference,
+		Code:     cols.CodeValues,
+		CodeText: conceptText(observation.Code),
+	}
+	for _, category := range observation.Category {
+		doc.Category = append(doc.Category, conceptText(category)...)
+	}
+	if observation.ValueString != nil {
+		doc.Value = append(doc.Value, *observation.ValueString)
+	}
+	if observation.ValueCodeableConcept != nil {
+		doc.Value = append(doc.Value, conceptText(*observation.ValueCodeableConcept)...)
+	}
+	for _, interpretation := range observation.Interpretation {
+		doc.Interpretation = append(doc.Interpretation, conceptText(interpretation)...)
+	}
+	for _, note := range observation.Note {
+		doc.Note = append(doc.Note, note.Text)
+	}
+	if cols.EffectiveDate != nil {
+		doc.Effective = cols.EffectiveDate.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return doc
+}
+
+// conceptText returns the human-readable text of a concept: its text and the
+// display of each coding
+func conceptText(concept models.CodeableConcept) []string {
+	var text []string
+	if concept.Text != nil && strings.TrimSpace(*concept.Text) != "" {
+		text = append(text, *concept.Text)
+	}
+	for _, coding := range concept.Coding {
+		if coding.Display != nil && strings.TrimSpace(*coding.Display) != "" {
+			text = append(text, *coding.Display)
+		}
+	}
+	return text
+}
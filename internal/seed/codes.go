@@ -0,0 +1,135 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+)
+
+// reading is the state a day's observations of a patient are built from
+type reading struct {
+	subject   models.Reference
+	p         *profile
+	effective time.Time
+	category  string
+	// day counts the days since the start of the series
+	day   int
+	fever float64
+}
+
+// kind is a kind of observation the generator produces, named in code
+// distributions
+type kind struct {
+	name     string
+	category string
+	build    func(g *Generator, r reading) *models.Observation
+}
+
+// kinds lists the observations in the order they are drawn each day, which
+// keeps datasets reproducible. Height is measured once, at the start of a
+// series.
+var kinds = []kind{
+	{"temperature", categoryVitalSigns, func(g *Generator, r reading) *models.Observation {
+		return g.quantity(r, "8310-5", "Body temperature", round(36.7+g.noise(0.2)+r.fever, 1), "Cel", "Cel")
+	}},
+	{"heart-rate", categoryVitalSigns, func(g *Generator, r reading) *models.Observation {
+		return g.quantity(r, "8867-4", "Heart rate", math.Round(r.p.heartRate+g.noise(4)+r.fever*10), "beats/minute", "/min")
+	}},
+	{"respiratory-rate", categoryVitalSigns, func(g *Generator, r reading) *models.Observation {
+		return g.quantity(r, "9279-1", "Respiratory rate", math.Round(14+g.noise(1.5)+r.fever*3), "breaths/minute", "/min")
+	}},
+	{"oxygen-saturation", categoryVitalSigns, func(g *Generator, r reading) *models.Observation {
+		spO2 := math.Min(100, r.p.spO2+g.noise(0.8)-r.fever*1.5)
+		return g.quantity(r, "59408-5", "Oxygen saturation in Arterial blood by Pulse oximetry", math.Round(spO2), "%", "%")
+	}},
+	{"blood-pressure", categoryVitalSigns, func(g *Generator, r reading) *models.Observation {
+		return g.bloodPressure(r, math.Round(r.p.systolic+g.noise(6)), math.Round(r.p.diastolic+g.noise(4)))
+	}},
+	{"weight", categoryVitalSigns, func(g *Generator, r reading) *models.Observation {
+		return g.quantity(r, "29463-7", "Body weight", round(r.p.weightKg+g.noise(0.3), 1), "kg", "kg")
+	}},
+	{"height", categoryVitalSigns, func(g *Generator, r reading) *models.Observation {
+		return g.quantity(r, "8302-2", "Body height", round(r.p.heightCm, 1), "cm", "cm")
+	}},
+	// Glucose follows the patient's HbA1c through the estimated average
+	// glucose, so the two agree, and rises with fever
+	{"glucose", categoryLaboratory, func(g *Generator, r reading) *models.Observation {
+		glucose := 28.7*r.p.hba1cOn(r.day) - 46.7 + g.noise(r.p.glucoseSpread) + r.fever*8
+		return g.quantity(r, "2339-0", "Glucose [Mass/volume] in Blood", math.Round(math.Max(50, glucose)), "mg/dL", "mg/dL")
+	}},
+	{"hba1c", categoryLaboratory, func(g *Generator, r reading) *models.Observation {
+		return g.quantity(r, "4548-4", "Hemoglobin A1c/Hemoglobin.total in Blood", round(r.p.hba1cOn(r.day)+g.noise(0.1), 1), "%", "%")
+	}},
+	{"cholesterol", categoryLaboratory, func(g *Generator, r reading) *models.Observation {
+		return g.quantity(r, "2093-3", "Cholesterol [Mass/volume] in Serum or Plasma", math.Round(r.p.cholesterol+g.noise(8)), "mg/dL", "mg/dL")
+	}},
+	{"creatinine", categoryLaboratory, func(g *Generator, r reading) *models.Observation {
+		return g.quantity(r, "2160-0", "Creatinine [Mass/volume] in Serum or Plasma", round(math.Max(0.2, r.p.creatinine+g.noise(0.05)), 2), "mg/dL", "mg/dL")
+	}},
+}
+
+// DefaultCodes is the code distribution of the seed command: vital signs every
+// day and laboratory results every week or few
+var DefaultCodes = map[string]float64{
+	"temperature":       1,
+	"heart-rate":        1,
+	"respiratory-rate":  1,
+	"oxygen-saturation": 1,
+	"blood-pressure":    1,
+	"weight":            1,
+	"height":            1,
+	"glucose":           0.15,
+	"hba1c":             0.03,
+	"cholesterol":       0.02,
+	"creatinine":        0.05,
+}
+
+// vitalCodes measures every vital sign every day
+var vitalCodes = func() map[string]float64 {
+	codes := make(map[string]float64)
+	for _, k := range kinds {
+		if k.category == categoryVitalSigns {
+			codes[k.name] = 1
+		}
+	}
+	return codes
+}()
+
+// Codes returns the names of the kinds of observation a code distribution can set
+func Codes() []string {
+	names := make([]string, 0, len(kinds))
+	for _, k := range kinds {
+		names = append(names, k.name)
+	}
+	return names
+}
+
+// frequencies checks a code distribution, giving the share of days on which
+// each kind of observation is measured, and returns it in the order of kinds
+func frequencies(codes map[string]float64) ([]float64, error) {
+	for name, frequency := range codes {
+		if !knownCode(name) {
+			return nil, fmt.Errorf("unknown code %q: must be one of %s", name, strings.Join(Codes(), ", "))
+		}
+		if frequency < 0 || frequency > 1 {
+			return nil, fmt.Errorf("frequency of %s must be between 0 and 1, got %g", name, frequency)
+		}
+	}
+	result := make([]float64, len(kinds))
+	for i, k := range kinds {
+		result[i] = codes[k.name]
+	}
+	return result, nil
+}
+
+func knownCode(name string) bool {
+	for _, k := range kinds {
+		if k.name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,446 @@
+// Package seed generates realistic synthetic patients with longitudinal
+// vital-sign and laboratory observations, for demos, local development and
+// load testing. Patients are drawn from a weighted mix of locales, each with
+// its own names, addresses and phone numbers, and the share of days each kind
+// of observation is measured on is set by a code distribution. Output is
+// deterministic for a given seed.
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	loincSystem = "http://loinc.org"
+	ucumSystem  = "http://unitsofmeasure.org"
+	mrnSystem   = "urn:healthcare-api:mrn"
+
+	observationCategorySystem = "http://terminology.hl7.org/CodeSystem/observation-category"
+	languageSystem            = "urn:ietf:bcp:47"
+
+	categoryVitalSigns = "vital-signs"
+	categoryLaboratory = "laboratory"
+)
+
+var categoryDisplays = map[string]string{
+	categoryVitalSigns: "Vital Signs",
+	categoryLaboratory: "Laboratory",
+}
+
+// emailReplacer spells names with the letters allowed in an email address
+var emailReplacer = strings.NewReplacer(
+	" ", "", "'", "",
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ñ", "n",
+	"ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss", "ı", "i",
+)
+
+// Generator produces demo resources from a seeded random source
+type Generator struct {
+	rnd  *rand.Rand
+	now  time.Time
+	mrns map[string]bool
+
+	// Locales patients are drawn from, with the running total of their weights
+	locales []weightedLocale
+	// Share of days each kind of observation is measured on, in the order of kinds
+	codes []float64
+}
+
+type weightedLocale struct {
+	locale
+	cumulative float64
+}
+
+// NewGenerator returns a generator of patients in DefaultLocale, with
+// observations following DefaultCodes
+func NewGenerator(seed int64) *Generator {
+	codes, _ := frequencies(DefaultCodes)
+	return &Generator{
+		rnd:     rand.New(rand.NewSource(seed)),
+		now:     time.Now().UTC().Truncate(time.Hour),
+		mrns:    make(map[string]bool),
+		locales: []weightedLocale{{locales[DefaultLocale], 1}},
+		codes:   codes,
+	}
+}
+
+// SetLocales sets the locales patients are drawn from, each chosen in
+// proportion to its weight
+func (g *Generator) SetLocales(weights map[string]float64) error {
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		if _, ok := locales[name]; !ok {
+			return fmt.Errorf("unknown locale %q: must be one of %s", name, strings.Join(Locales(), ", "))
+		}
+		names = append(names, name)
+	}
+	// Sorted, so the same weights draw the same patients
+	sort.Strings(names)
+
+	var mix []weightedLocale
+	total := 0.0
+	for _, name := range names {
+		if weights[name] <= 0 {
+			continue
+		}
+		total += weights[name]
+		mix = append(mix, weightedLocale{locales[name], total})
+	}
+	if len(mix) == 0 {
+		return errors.New("at least one locale must have a positive weight")
+	}
+	for i := range mix {
+		mix[i].cumulative /= total
+	}
+	g.locales = mix
+	return nil
+}
+
+// SetCodes sets the code distribution of Observations: for each kind of
+// observation, named as in Codes, the share of days between 0 and 1 it is
+// measured on. Kinds left out are not measured.
+func (g *Generator) SetCodes(codes map[string]float64) error {
+	result, err := frequencies(codes)
+	if err != nil {
+		return err
+	}
+	g.codes = result
+	return nil
+}
+
+// profile holds the per-patient baselines that keep a patient's vitals and
+// laboratory results plausible and correlated
+type profile struct {
+	age          int
+	male         bool
+	hypertensive bool
+	diabetic     bool
+	heightCm     float64
+	weightKg     float64
+	heartRate    float64
+	systolic     float64
+	diastolic    float64
+	spO2         float64
+
+	// HbA1c at the start of the series and its change per day, as treatment
+	// brings it down or control slips
+	hba1c         float64
+	hba1cTrend    float64
+	glucoseSpread float64
+	cholesterol   float64
+	creatinine    float64
+}
+
+// hba1cOn returns the patient's HbA1c day days into the series
+func (p *profile) hba1cOn(day int) float64 {
+	return math.Max(4.5, p.hba1c+p.hba1cTrend*float64(day))
+}
+
+// Patient returns a new patient with a name, identifiers, contact details, an
+// address and a preferred language, all from one of the generator's locales
+func (g *Generator) Patient() *models.Patient {
+	l := g.locale()
+	male := g.rnd.Intn(2) == 0
+	gender := "female"
+	given := g.pick(l.givenNamesFemale)
+	if male {
+		gender = "male"
+		given = g.pick(l.givenNamesMale)
+	}
+	family := g.pick(l.familyNames)
+	if l.doubleFamilyName {
+		family += " " + g.pick(l.familyNames)
+	}
+	nameText := given + " " + family
+
+	birthDate := g.now.AddDate(-(1 + g.rnd.Intn(90)), 0, -g.rnd.Intn(365)).Truncate(24 * time.Hour)
+
+	city := l.cities[g.rnd.Intn(len(l.cities))]
+	line := l.line(1+g.rnd.Intn(250), g.pick(l.streets))
+	postalCode := l.postalCode(g.rnd, city)
+
+	// MRNs are unique per tenant, so never hand out the same one twice
+	mrn := fmt.Sprintf("MRN%08d", g.rnd.Intn(100000000))
+	for g.mrns[mrn] {
+		mrn = fmt.Sprintf("MRN%08d", g.rnd.Intn(100000000))
+	}
+	g.mrns[mrn] = true
+	phone := l.phone(g.rnd)
+	email := emailReplacer.Replace(strings.ToLower(fmt.Sprintf("%s.%s%d@example.org", given, family, g.rnd.Intn(100))))
+
+	return &models.Patient{
+		Resource: models.Resource{ID: g.uuid()},
+		Identifier: []models.Identifier{{
+			Use: ptr("usual"),
+			Type: &models.CodeableConcept{
+				Coding: []models.Coding{{
+					System:  ptr("http://terminology.hl7.org/CodeSystem/v2-0203"),
+					Code:    ptr("MR"),
+					Display: ptr("Medical record number"),
+				}},
+			},
+			System: ptr(mrnSystem),
+			Value:  ptr(mrn),
+		}},
+		Active: ptr(true),
+		Name: []models.HumanName{{
+			Use:    ptr("official"),
+			Text:   ptr(nameText),
+			Family: ptr(family),
+			Given:  []string{given},
+		}},
+		Telecom: []models.ContactPoint{
+			{System: ptr("phone"), Value: ptr(phone), Use: ptr("mobile")},
+			{System: ptr("email"), Value: ptr(email), Use: ptr("home")},
+		},
+		Gender:    ptr(gender),
+		BirthDate: &birthDate,
+		Address: []models.Address{{
+			Use:        ptr("home"),
+			Type:       ptr("physical"),
+			Line:       []string{line},
+			City:       ptr(city.City),
+			State:      ptr(city.State),
+			PostalCode: ptr(postalCode),
+			Country:    ptr(l.country),
+		}},
+		Communication: []models.PatientCommunication{{
+			Language: models.CodeableConcept{
+				Coding: []models.Coding{{System: ptr(languageSystem), Code: ptr(l.language)}},
+			},
+			Preferred: ptr(true),
+		}},
+	}
+}
+
+// locale draws the locale of a patient from the generator's mix
+func (g *Generator) locale() locale {
+	if len(g.locales) == 1 {
+		return g.locales[0].locale
+	}
+	x := g.rnd.Float64()
+	for _, l := range g.locales {
+		if x < l.cumulative {
+			return l.locale
+		}
+	}
+	return g.locales[len(g.locales)-1].locale
+}
+
+// Vitals returns one set of vital signs per day for the last days days, with
+// height measured once, at the start. Readings follow the patient's age, sex
+// and blood pressure baseline, and occasional febrile episodes raise
+// temperature, heart and respiratory rate while lowering SpO2 together.
+func (g *Generator) Vitals(patient *models.Patient, days int) []*models.Observation {
+	codes, _ := frequencies(vitalCodes)
+	return g.series(patient, days, codes)
+}
+
+// Observations returns the patient's observations over the last days days,
+// each kind measured on the share of days the generator's code distribution
+// gives it. Like vital signs, laboratory results follow the patient: glucose
+// and HbA1c agree and drift together over the series, and cholesterol and
+// creatinine follow age and sex.
+func (g *Generator) Observations(patient *models.Patient, days int) []*models.Observation {
+	return g.series(patient, days, g.codes)
+}
+
+// series generates the observations measured each morning over the last days
+// days, with codes giving the share of days each kind is measured on
+func (g *Generator) series(patient *models.Patient, days int, codes []float64) []*models.Observation {
+	p := g.profile(patient)
+	subject := models.Reference{
+		Reference: ptr("Patient/" + patient.ID.String()),
+		Type:      ptr("Patient"),
+	}
+	if len(patient.Name) > 0 {
+		subject.Display = patient.Name[0].Text
+	}
+
+	// Optional fever episode lasting a few days
+	feverStart, feverDays := -1, 0
+	if days > 3 && g.rnd.Float64() < 0.25 {
+		feverDays = 2 + g.rnd.Intn(3)
+		feverStart = g.rnd.Intn(days - 1)
+	}
+
+	var observations []*models.Observation
+	for day := days - 1; day >= 0; day-- {
+		// Morning reading with some jitter
+		effective := g.now.AddDate(0, 0, -day).Truncate(24 * time.Hour).
+			Add(time.Duration(7+g.rnd.Intn(3))*time.Hour + time.Duration(g.rnd.Intn(60))*time.Minute)
+		if latest := g.now.Add(-30 * time.Minute); effective.After(latest) {
+			effective = latest
+		}
+
+		r := reading{subject: subject, p: &p, effective: effective, day: days - 1 - day}
+		if feverStart >= 0 && r.day >= feverStart && r.day < feverStart+feverDays {
+			r.fever = 1.0 + g.rnd.Float64()*1.5
+		}
+
+		for i, k := range kinds {
+			switch {
+			case codes[i] == 0:
+				continue
+			case k.name == "height":
+				if r.day > 0 {
+					continue
+				}
+			case codes[i] < 1 && g.rnd.Float64() >= codes[i]:
+				continue
+			}
+			r.category = k.category
+			observations = append(observations, k.build(g, r))
+		}
+	}
+
+	return observations
+}
+
+// profile derives per-patient baselines from demographics
+func (g *Generator) profile(patient *models.Patient) profile {
+	p := profile{age: 40}
+	if patient.BirthDate != nil {
+		p.age = int(g.now.Sub(*patient.BirthDate).Hours() / 24 / 365.25)
+	}
+	p.male = patient.Gender != nil && *patient.Gender == "male"
+	p.hypertensive = g.rnd.Float64() < 0.1+float64(p.age)/150
+
+	switch {
+	case p.age < 18:
+		// Rough growth curve for children and adolescents
+		p.heightCm = 75 + float64(p.age)*6 + g.noise(5)
+		p.weightKg = 9 + float64(p.age)*2.8 + g.noise(2)
+		p.heartRate = 110 - float64(p.age)*2 + g.noise(5)
+	default:
+		p.heightCm = 163 + g.noise(7)
+		if p.male {
+			p.heightCm += 13
+		}
+		bmi := 22 + g.rnd.Float64()*10
+		p.weightKg = bmi * math.Pow(p.heightCm/100, 2)
+		p.heartRate = 72 + g.noise(6)
+	}
+
+	p.systolic = 105 + float64(p.age)*0.4 + g.noise(5)
+	p.diastolic = 68 + float64(p.age)*0.15 + g.noise(3)
+	if p.hypertensive {
+		p.systolic += 25
+		p.diastolic += 12
+	}
+	p.spO2 = 98 - float64(p.age)/60
+
+	// Diabetes becomes more common with age; treatment brings HbA1c down
+	// over months in most patients while it creeps up in some
+	p.diabetic = p.age >= 18 && g.rnd.Float64() < 0.02+float64(p.age)/400
+	if p.diabetic {
+		p.hba1c = 6.8 + g.rnd.Float64()*2.5
+		p.hba1cTrend = -0.004 + g.rnd.Float64()*0.006
+		p.glucoseSpread = 25
+	} else {
+		p.hba1c = 5.0 + g.rnd.Float64()*0.6 + float64(p.age)*0.004
+		p.glucoseSpread = 8
+	}
+
+	if p.age < 18 {
+		p.cholesterol = 155 + g.noise(15)
+		p.creatinine = 0.3 + float64(p.age)*0.03
+	} else {
+		p.cholesterol = 165 + float64(p.age)*0.6 + g.noise(20)
+		p.creatinine = 0.7 + float64(p.age)*0.002 + g.noise(0.08)
+		if p.male {
+			p.creatinine += 0.2
+		}
+	}
+
+	return p
+}
+
+// quantity builds a single-value observation
+func (g *Generator) quantity(r reading, code, display string, value float64, unit, ucum string) *models.Observation {
+	observation := g.observation(r, code, display)
+	observation.ValueQuantity = quantity(value, unit, ucum)
+	return observation
+}
+
+// bloodPressure builds the blood pressure panel with systolic and diastolic components
+func (g *Generator) bloodPressure(r reading, systolic, diastolic float64) *models.Observation {
+	observation := g.observation(r, "85354-9", "Blood pressure panel with all children optional")
+	observation.Component = []models.ObservationComponent{
+		{Code: loinc("8480-6", "Systolic blood pressure"), ValueQuantity: quantity(systolic, "mmHg", "mm[Hg]")},
+		{Code: loinc("8462-4", "Diastolic blood pressure"), ValueQuantity: quantity(diastolic, "mmHg", "mm[Hg]")},
+	}
+	return observation
+}
+
+func (g *Generator) observation(r reading, code, display string) *models.Observation {
+	effective := r.effective
+	issued := effective.Add(time.Duration(1+g.rnd.Intn(15)) * time.Minute)
+	if r.category == categoryLaboratory {
+		// Laboratory results come back hours after the sample is drawn
+		issued = effective.Add(time.Duration(2+g.rnd.Intn(6))*time.Hour + time.Duration(g.rnd.Intn(60))*time.Minute)
+	}
+	return &models.Observation{
+		Resource: models.Resource{ID: g.uuid()},
+		Status:   "final",
+		Category: []models.CodeableConcept{{
+			Coding: []models.Coding{{
+				System:  ptr(observationCategorySystem),
+				Code:    ptr(r.category),
+				Display: ptr(categoryDisplays[r.category]),
+			}},
+		}},
+		Code:              loinc(code, display),
+		Subject:           r.subject,
+		EffectiveDateTime: &effective,
+		Issued:            &issued,
+	}
+}
+
+func loinc(code, display string) models.CodeableConcept {
+	return models.CodeableConcept{
+		Coding: []models.Coding{{System: ptr(loincSystem), Code: ptr(code), Display: ptr(display)}},
+		Text:   ptr(display),
+	}
+}
+
+func quantity(value float64, unit, ucum string) *models.Quantity {
+	return &models.Quantity{Value: &value, Unit: ptr(unit), System: ptr(ucumSystem), Code: ptr(ucum)}
+}
+
+// uuid draws ids from the seeded source so datasets are reproducible
+func (g *Generator) uuid() uuid.UUID {
+	id, err := uuid.NewRandomFromReader(g.rnd)
+	if err != nil {
+		return uuid.New()
+	}
+	return id
+}
+
+func (g *Generator) pick(values []string) string {
+	return values[g.rnd.Intn(len(values))]
+}
+
+// noise returns normally distributed jitter with the given standard deviation
+func (g *Generator) noise(stddev float64) float64 {
+	return g.rnd.NormFloat64() * stddev
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,181 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is the locale patients are generated in unless others are set
+const DefaultLocale = "en-US"
+
+// locale holds the names, places and formats of the patients generated in it
+type locale struct {
+	language         string
+	country          string
+	givenNamesFemale []string
+	givenNamesMale   []string
+	familyNames      []string
+	// Mexican patients carry their father's and mother's family names
+	doubleFamilyName bool
+	streets          []string
+	cities           []city
+	// line formats the house number and street of an address
+	line       func(number int, street string) string
+	postalCode func(rnd *rand.Rand, c city) string
+	// phone returns a number in a range reserved for fiction where the country has one
+	phone func(rnd *rand.Rand) string
+}
+
+// city is a place with the first characters of its postal codes
+type city struct {
+	City, State, PostalPrefix string
+}
+
+// digits returns postal codes of length digits: the prefix of the city
+// completed with random ones
+func digits(length int) func(rnd *rand.Rand, c city) string {
+	return func(rnd *rand.Rand, c city) string {
+		code := c.PostalPrefix
+		for len(code) < length {
+			code += strconv.Itoa(rnd.Intn(10))
+		}
+		return code
+	}
+}
+
+// ukPostcode returns a postcode of the city's area: a district completing the
+// outward code, then the inward code
+func ukPostcode(rnd *rand.Rand, c city) string {
+	return fmt.Sprintf("%s%d %d%c%c", c.PostalPrefix, 1+rnd.Intn(20), rnd.Intn(10), 'A'+rune(rnd.Intn(26)), 'A'+rune(rnd.Intn(26)))
+}
+
+func numberFirst(number int, street string) string {
+	return fmt.Sprintf("%d %s", number, street)
+}
+
+func streetFirst(number int, street string) string {
+	return fmt.Sprintf("%s %d", street, number)
+}
+
+var locales = map[string]locale{
+	"en-US": {
+		language:         "en-US",
+		country:          "US",
+		givenNamesFemale: []string{"Olivia", "Emma", "Amelia", "Sophia", "Isla", "Mia", "Grace", "Chloe", "Hannah", "Zara", "Aisha", "Maria", "Ingrid", "Mei", "Priya"},
+		givenNamesMale:   []string{"Oliver", "Noah", "George", "Leo", "Arthur", "Jack", "Samuel", "Daniel", "Omar", "Lucas", "Mateo", "Kenji", "Ravi", "Erik", "Tomas"},
+		familyNames:      []string{"Smith", "Jones", "Taylor", "Brown", "Williams", "Wilson", "Johnson", "Davies", "Patel", "Khan", "Nguyen", "Garcia", "Müller", "Kowalski", "O'Brien", "Okafor", "Tanaka", "Rossi", "Larsen", "Cohen"},
+		streets:          []string{"High Street", "Station Road", "Church Lane", "Park Avenue", "Mill Road", "Victoria Street", "Green Lane", "Kings Road", "Queens Drive", "Orchard Way"},
+		cities: []city{
+			{"Springfield", "IL", "627"},
+			{"Portland", "OR", "972"},
+			{"Madison", "WI", "537"},
+			{"Austin", "TX", "787"},
+			{"Burlington", "VT", "054"},
+			{"Boulder", "CO", "803"},
+		},
+		line:       numberFirst,
+		postalCode: digits(5),
+		phone: func(rnd *rand.Rand) string {
+			return fmt.Sprintf("+1-555-%03d-%04d", rnd.Intn(1000), rnd.Intn(10000))
+		},
+	},
+	"en-GB": {
+		language:         "en-GB",
+		country:          "GB",
+		givenNamesFemale: []string{"Olivia", "Amelia", "Isla", "Ava", "Ivy", "Freya", "Lily", "Florence", "Poppy", "Sienna", "Ellie", "Fatima", "Niamh", "Rhiannon", "Anya"},
+		givenNamesMale:   []string{"Muhammad", "Noah", "Oliver", "George", "Arthur", "Leo", "Harry", "Oscar", "Archie", "Henry", "Theodore", "Freddie", "Callum", "Rhys", "Aarav"},
+		familyNames:      []string{"Smith", "Jones", "Williams", "Taylor", "Brown", "Davies", "Evans", "Wilson", "Thomas", "Roberts", "Johnson", "Walker", "Wright", "Robinson", "Thompson", "Hughes", "Ahmed", "Begum", "MacDonald", "Campbell"},
+		streets:          []string{"High Street", "Station Road", "Main Street", "Park Road", "Church Road", "London Road", "Victoria Road", "Manor Road", "Church Street", "Queens Road"},
+		cities: []city{
+			{"Manchester", "Greater Manchester", "M"},
+			{"Leeds", "West Yorkshire", "LS"},
+			{"Bristol", "Bristol", "BS"},
+			{"Cardiff", "Wales", "CF"},
+			{"Glasgow", "Scotland", "G"},
+			{"Norwich", "Norfolk", "NR"},
+		},
+		line:       numberFirst,
+		postalCode: ukPostcode,
+		phone: func(rnd *rand.Rand) string {
+			return fmt.Sprintf("+44 7700 900%03d", rnd.Intn(1000))
+		},
+	},
+	"de-DE": {
+		language:         "de-DE",
+		country:          "DE",
+		givenNamesFemale: []string{"Emilia", "Emma", "Hannah", "Sophia", "Mia", "Lina", "Mila", "Ella", "Lea", "Clara", "Marie", "Frieda", "Ursula", "Sabine", "Elif"},
+		givenNamesMale:   []string{"Noah", "Matteo", "Elias", "Luca", "Leon", "Finn", "Paul", "Emil", "Henry", "Felix", "Jonas", "Lukas", "Jürgen", "Wolfgang", "Mehmet"},
+		familyNames:      []string{"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann", "Schäfer", "Koch", "Bauer", "Richter", "Klein", "Wolf", "Schröder", "Neumann", "Yılmaz", "Nowak"},
+		streets:          []string{"Hauptstraße", "Schulstraße", "Gartenstraße", "Bahnhofstraße", "Dorfstraße", "Bergstraße", "Birkenweg", "Lindenstraße", "Kirchstraße", "Waldstraße"},
+		cities: []city{
+			{"Berlin", "BE", "10"},
+			{"Hamburg", "HH", "20"},
+			{"München", "BY", "80"},
+			{"Köln", "NW", "50"},
+			{"Leipzig", "SN", "04"},
+			{"Freiburg im Breisgau", "BW", "79"},
+		},
+		line:       streetFirst,
+		postalCode: digits(5),
+		phone: func(rnd *rand.Rand) string {
+			return fmt.Sprintf("+49 151 %08d", rnd.Intn(100000000))
+		},
+	},
+	"es-MX": {
+		language:         "es-MX",
+		country:          "MX",
+		givenNamesFemale: []string{"Sofía", "Valentina", "Regina", "María José", "Ximena", "Camila", "Renata", "Fernanda", "Guadalupe", "Daniela", "Victoria", "Isabella", "Lucía", "Andrea", "Itzel"},
+		givenNamesMale:   []string{"Santiago", "Mateo", "Sebastián", "Leonardo", "Emiliano", "Diego", "Miguel Ángel", "Daniel", "Alejandro", "José Luis", "Juan Pablo", "Ángel", "Iker", "Carlos", "Luis"},
+		familyNames:      []string{"Hernández", "García", "Martínez", "López", "González", "Pérez", "Rodríguez", "Sánchez", "Ramírez", "Cruz", "Flores", "Gómez", "Morales", "Vázquez", "Reyes", "Jiménez", "Torres", "Díaz", "Gutiérrez", "Ruiz"},
+		doubleFamilyName: true,
+		streets:          []string{"Avenida Juárez", "Calle Hidalgo", "Calle Morelos", "Avenida Reforma", "Calle Zaragoza", "Calle Allende", "Avenida Insurgentes", "Calle Guerrero", "Calle Independencia", "Calle 5 de Mayo"},
+		cities: []city{
+			{"Ciudad de México", "CMX", "06"},
+			{"Guadalajara", "JAL", "44"},
+			{"Monterrey", "NLE", "64"},
+			{"Puebla", "PUE", "72"},
+			{"Mérida", "YUC", "97"},
+			{"Oaxaca de Juárez", "OAX", "68"},
+		},
+		line:       streetFirst,
+		postalCode: digits(5),
+		phone: func(rnd *rand.Rand) string {
+			return fmt.Sprintf("+52 55 %04d %04d", rnd.Intn(10000), rnd.Intn(10000))
+		},
+	},
+}
+
+// Locales returns the names of the locales patients can be generated in
+func Locales() []string {
+	names := make([]string, 0, len(locales))
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseWeights parses a list of name=weight pairs separated by commas, as in
+// "en-US=3,es-MX=1". A name without a weight has weight 1.
+func ParseWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, found := strings.Cut(item, "=")
+		weight := 1.0
+		if found {
+			var err error
+			if weight, err = strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil || weight < 0 {
+				return nil, fmt.Errorf("invalid weight %q for %s", value, name)
+			}
+		}
+		weights[strings.TrimSpace(name)] = weight
+	}
+	return weights, nil
+}
@@ -0,0 +1,219 @@
+// Package selftest checks at startup that the process can do its work: the
+// schema is migrated, the indexes the migrations create exist, the JWT key
+// signs and verifies tokens and a canary query succeeds. Until every check
+// passes, or fails but is allowed to, the process serves no traffic.
+package selftest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/database"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// checkTimeout bounds each check of an attempt
+const checkTimeout = 10 * time.Second
+
+// Check names, as listed in SELFTEST_DEGRADED_ALLOWED
+const (
+	CheckMigrations = "migrations"
+	CheckIndexes    = "indexes"
+	CheckJWT        = "jwt"
+	CheckCanary     = "canary"
+)
+
+// Result statuses
+const (
+	StatusPassed = "passed"
+	StatusFailed = "failed"
+	// StatusDegraded is a failed check that is allowed to fail
+	StatusDegraded = "degraded"
+)
+
+// Check is a startup check
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of an attempt at the self-test
+type Report struct {
+	// Passed is set when no check failed other than those allowed to
+	Passed  bool     `json:"passed"`
+	Attempt int      `json:"attempt"`
+	Results []Result `json:"checks"`
+}
+
+// SelfTest runs the startup checks
+type SelfTest struct {
+	checks   []Check
+	allowed  map[string]bool
+	timeout  time.Duration
+	interval time.Duration
+	logger   *logrus.Logger
+}
+
+func New(cfg config.SelfTestConfig, logger *logrus.Logger) *SelfTest {
+	allowed := make(map[string]bool, len(cfg.DegradedAllowed))
+	for _, name := range cfg.DegradedAllowed {
+		allowed[name] = true
+	}
+	return &SelfTest{
+		allowed:  allowed,
+		timeout:  time.Duration(cfg.Timeout) * time.Second,
+		interval: time.Duration(cfg.RetryInterval) * time.Second,
+		logger:   logger,
+	}
+}
+
+// Add adds a check to the self-test
+func (t *SelfTest) Add(check Check) {
+	t.checks = append(t.checks, check)
+}
+
+// Wait runs the checks until they pass, logging the report of every attempt.
+// Failing checks are retried every retry interval, so a transient failure does
+// not stop the process; an error is returned once the timeout has passed
+// without a passing attempt.
+func (t *SelfTest) Wait(ctx context.Context) error {
+	deadline := time.Now().Add(t.timeout)
+	for attempt := 1; ; attempt++ {
+		report := t.run(ctx, attempt)
+
+		logger := t.logger.WithFields(logrus.Fields{
+			"passed":  report.Passed,
+			"attempt": report.Attempt,
+			"checks":  report.Results,
+		})
+		if report.Passed {
+			logger.Info("Startup self-test passed")
+			return nil
+		}
+		if !time.Now().Add(t.interval).Before(deadline) {
+			logger.Error("Startup self-test failed")
+			return fmt.Errorf("self-test failed: %s", report.failures())
+		}
+		logger.Warn("Startup self-test failed, retrying")
+
+		select {
+		case <-time.After(t.interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// run runs every check once
+func (t *SelfTest) run(ctx context.Context, attempt int) *Report {
+	report := &Report{Passed: true, Attempt: attempt}
+	for _, check := range t.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		started := time.Now()
+		err := check.Run(checkCtx)
+		cancel()
+
+		result := Result{Name: check.Name, Status: StatusPassed, Duration: time.Since(started).Milliseconds()}
+		if err != nil {
+			result.Error = err.Error()
+			result.Status = StatusFailed
+			if t.allowed[check.Name] {
+				result.Status = StatusDegraded
+			} else {
+				report.Passed = false
+			}
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+// failures describes the checks that failed
+func (r *Report) failures() string {
+	var failed []string
+	for _, result := range r.Results {
+		if result.Status == StatusFailed {
+			failed = append(failed, result.Name+": "+result.Error)
+		}
+	}
+	return strings.Join(failed, "; ")
+}
+
+// Migrations checks every migration in path is applied and the schema is not
+// dirty
+func Migrations(db *database.DB, path string) Check {
+	return Check{Name: CheckMigrations, Run: func(ctx context.Context) error {
+		status, err := database.SchemaStatus(ctx, db.DB, path)
+		if err != nil {
+			return err
+		}
+		return status.Err()
+	}}
+}
+
+// Indexes checks the indexes the applied migrations in path create exist
+func Indexes(db *database.DB, path string) Check {
+	return Check{Name: CheckIndexes, Run: func(ctx context.Context) error {
+		missing, err := database.MissingIndexes(ctx, db.DB, path)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("missing indexes: %s", strings.Join(missing, ", "))
+		}
+		return nil
+	}}
+}
+
+// JWTKey checks that a token signed with secret, the way the API's tokens are
+// signed, verifies
+func JWTKey(secret string) Check {
+	return Check{Name: CheckJWT, Run: func(ctx context.Context) error {
+		if secret == "" {
+			return errors.New("JWT secret is empty")
+		}
+		claims := jwt.RegisteredClaims{
+			Subject:   "selftest",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			return fmt.Errorf("failed to sign token: %w", err)
+		}
+		token, err := jwt.ParseWithClaims(signed, &jwt.RegisteredClaims{}, func(*jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		})
+		if err != nil || !token.Valid {
+			return fmt.Errorf("failed to verify token: %v", err)
+		}
+		return nil
+	}}
+}
+
+// Canary checks a read of the patients table succeeds, exercising the
+// connection, the schema and the role's privileges
+func Canary(db *database.DB) Check {
+	return Check{Name: CheckCanary, Run: func(ctx context.Context) error {
+		var found int
+		err := db.QueryRowContext(ctx, `SELECT 1 FROM patients LIMIT 1`).Scan(&found)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("canary query failed: %w", err)
+		}
+		return nil
+	}}
+}
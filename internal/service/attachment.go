@@ -0,0 +1,219 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/objectstore"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// AttachmentPath is the API path of stored attachments; resources refer to an
+// attachment by this path followed by its id
+const AttachmentPath = "/api/v1/attachments/"
+
+// AttachmentService keeps attachment contents in the object store rather than
+// inline in resources, and issues signed links to download them
+type AttachmentService struct {
+	repo   *repository.AttachmentRepository
+	store  objectstore.Store
+	cfg    config.AttachmentConfig
+	logger *logrus.Logger
+}
+
+func NewAttachmentService(repo *repository.AttachmentRepository, store objectstore.Store, cfg config.AttachmentConfig, logger *logrus.Logger) *AttachmentService {
+	return &AttachmentService{
+		repo:   repo,
+		store:  store,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Upload stores the content read from r, returning the Attachment resources
+// refer to it by. Content over the size limit fails with models.ErrAttachmentTooLarge.
+func (s *AttachmentService) Upload(ctx context.Context, contentType string, title *string, r io.Reader) (*models.Attachment, error) {
+	tenantID := requestctx.TenantID(ctx)
+	if tenantID == "" {
+		return nil, models.ErrTenantRequired
+	}
+
+	id := uuid.New()
+	attachment := &models.StoredAttachment{
+		ID:          id,
+		ContentType: contentType,
+		Title:       title,
+		ObjectKey:   "attachments/" + tenantID + "/" + id.String(),
+	}
+	if userID := requestctx.UserID(ctx); userID != "" {
+		attachment.CreatedBy = &userID
+	}
+
+	hash := sha1.New()
+	content := &sizeLimitReader{r: r, limit: s.cfg.MaxSize}
+	if err := s.store.Put(ctx, attachment.ObjectKey, io.TeeReader(content, hash)); err != nil {
+		if errors.Is(err, models.ErrAttachmentTooLarge) {
+			return nil, models.ErrAttachmentTooLarge
+		}
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to store attachment content")
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	attachment.Size = content.read
+	attachment.Hash = base64.StdEncoding.EncodeToString(hash.Sum(nil))
+
+	// An object stored without its record is never served; it is only left behind
+	if err := s.repo.Create(ctx, attachment); err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("object_key", attachment.ObjectKey).Error("Failed to record attachment")
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"attachment_id": id,
+		"size":          attachment.Size,
+	}).Info("Attachment stored")
+	return attachmentReference(attachment), nil
+}
+
+// Offload moves the inline data of attachments to the object store, leaving
+// each with the URL, size and hash of the stored content instead
+func (s *AttachmentService) Offload(ctx context.Context, attachments []models.Attachment) error {
+	for i := range attachments {
+		attachment := &attachments[i]
+		if attachment.Data == nil {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(*attachment.Data)
+		if err != nil {
+			return fmt.Errorf("%w: attachment data is not base64", models.ErrUnsupported)
+		}
+
+		contentType := "application/octet-stream"
+		if attachment.ContentType != nil {
+			contentType = *attachment.ContentType
+		}
+		stored, err := s.Upload(ctx, contentType, attachment.Title, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+
+		attachment.Data = nil
+		attachment.URL = stored.URL
+		attachment.Size = stored.Size
+		attachment.Hash = stored.Hash
+		if attachment.Creation == nil {
+			attachment.Creation = stored.Creation
+		}
+	}
+	return nil
+}
+
+func (s *AttachmentService) GetAttachment(ctx context.Context, id uuid.UUID) (*models.StoredAttachment, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// DownloadLink returns a link to the attachment's content that is valid for the
+// configured time without a token, for use where headers cannot be set, such
+// as an img element
+func (s *AttachmentService) DownloadLink(ctx context.Context, id uuid.UUID) (*models.AttachmentDownload, error) {
+	attachment, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID := requestctx.TenantID(ctx)
+	expiresAt := time.Now().UTC().Add(time.Duration(s.cfg.URLTTL) * time.Second).Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("tenant", tenantID)
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(tenantID, attachment.ID.String(), expires))
+	return &models.AttachmentDownload{
+		URL:       AttachmentPath + attachment.ID.String() + "/content?" + query.Encode(),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// OpenLink checks a download link's signature and expiry and opens the
+// attachment it refers to. The caller closes the content.
+func (s *AttachmentService) OpenLink(ctx context.Context, tenantID string, id uuid.UUID, expires, signature string) (*models.StoredAttachment, io.ReadCloser, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || tenantID == "" {
+		return nil, nil, models.ErrInvalidAttachmentLink
+	}
+	expected := s.sign(tenantID, id.String(), expires)
+	if !hmac.Equal([]byte(signature), []byte(expected)) || time.Now().Unix() > expiresAt {
+		return nil, nil, models.ErrInvalidAttachmentLink
+	}
+
+	ctx = requestctx.WithTenantID(ctx, tenantID)
+	attachment, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.store.Get(ctx, attachment.ObjectKey)
+	if err != nil {
+		if errors.Is(err, objectstore.ErrNotFound) {
+			s.logger.WithContext(ctx).WithField("attachment_id", id).Error("Attachment content is missing from the object store")
+			return nil, nil, models.ErrAttachmentNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	return attachment, content, nil
+}
+
+// sign returns the signature of a download link
+func (s *AttachmentService) sign(tenantID, id, expires string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.URLSecret))
+	mac.Write([]byte(strings.Join([]string{tenantID, id, expires}, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// attachmentReference returns the Attachment resources carry for a stored attachment
+func attachmentReference(attachment *models.StoredAttachment) *models.Attachment {
+	url := AttachmentPath + attachment.ID.String()
+	size := int(attachment.Size)
+	creation := attachment.CreatedAt
+	return &models.Attachment{
+		ContentType: &attachment.ContentType,
+		URL:         &url,
+		Size:        &size,
+		Hash:        &attachment.Hash,
+		Title:       attachment.Title,
+		Creation:    &creation,
+	}
+}
+
+// sizeLimitReader counts the bytes read, failing with models.ErrAttachmentTooLarge
+// once more than limit have been read
+type sizeLimitReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (r *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return n, models.ErrAttachmentTooLarge
+	}
+	return n, err
+}
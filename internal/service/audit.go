@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	auditEventTypeSystem  = "http://terminology.hl7.org/CodeSystem/audit-event-type"
+	restfulInteractionURL = "http://hl7.org/fhir/restful-interaction"
+	auditEntityTypeSystem = "http://terminology.hl7.org/CodeSystem/audit-entity-type"
+)
+
+// auditActionCodes maps stored audit actions to the FHIR AuditEvent action code and restful interaction
+var auditActionCodes = map[string]struct{ Code, Interaction string }{
+	"CREATE":  {"C", "create"},
+	"READ":    {"R", "read"},
+	"UPDATE":  {"U", "update"},
+	"DELETE":  {"D", "delete"},
+	"RESTORE": {"U", "update"},
+	"MERGE":   {"U", "update"},
+}
+
+// AuditActions returns the stored actions matching a FHIR action code (C, R, U, D)
+// or a stored action name, case-insensitively
+func AuditActions(value string) []string {
+	value = strings.ToUpper(value)
+	if _, ok := auditActionCodes[value]; ok {
+		return []string{value}
+	}
+
+	var actions []string
+	for action, codes := range auditActionCodes {
+		if codes.Code == value {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+type AuditService struct {
+	repo   *repository.AuditRepository
+	logger *logrus.Logger
+}
+
+func NewAuditService(repo *repository.AuditRepository, logger *logrus.Logger) *AuditService {
+	return &AuditService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// ListAuditEvents returns the tenant's audit trail as a bundle of FHIR AuditEvents
+func (s *AuditService) ListAuditEvents(ctx context.Context, search repository.AuditSearchParams, limit, offset int) (*models.AuditEventListResponse, error) {
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"limit":  limit,
+		"offset": offset,
+	}).Info("Listing audit events")
+
+	// Validate and set pagination parameters
+	params := repository.ValidatePaginationParams(limit, offset)
+
+	logs, pagination, err := s.repo.List(ctx, search, params)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list audit events")
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	// Convert to response format
+	entries := make([]models.AuditEventEntry, len(logs))
+	for i, log := range logs {
+		entries[i] = models.AuditEventEntry{
+			FullURL:  fmt.Sprintf("/api/v1/audit-events/%s", log.ID),
+			Resource: toAuditEvent(log),
+			Search: &models.SearchEntry{
+				Mode: "match",
+			},
+		}
+	}
+
+	response := &models.AuditEventListResponse{
+		ResourceType: "Bundle",
+		ID:           uuid.New().String(),
+		Type:         "searchset",
+		Total:        pagination.Total,
+		Entry:        entries,
+	}
+
+	// Carry search filters through to pagination links
+	query := url.Values{}
+	if search.ResourceType != "" {
+		query.Set("entity-type", search.ResourceType)
+	}
+	if search.ResourceID != nil {
+		query.Set("entity", search.ResourceID.String())
+	}
+	if search.UserID != "" {
+		query.Set("agent", search.UserID)
+	}
+	for _, action := range search.Actions {
+		query.Add("action", action)
+	}
+	if search.From != nil {
+		query.Add("date", "ge"+search.From.Format(time.RFC3339))
+	}
+	if search.To != nil {
+		query.Add("date", "lt"+search.To.Format(time.RFC3339))
+	}
+	filters := ""
+	if len(query) > 0 {
+		filters = "&" + query.Encode()
+	}
+
+	// Add pagination links
+	if pagination.HasNext {
+		response.Link = append(response.Link, models.BundleLink{
+			Relation: "next",
+			URL:      fmt.Sprintf("/api/v1/audit-events?limit=%d&offset=%d%s", params.Limit, params.Offset+params.Limit, filters),
+		})
+	}
+
+	if params.Offset > 0 {
+		prevOffset := params.Offset - params.Limit
+		if prevOffset < 0 {
+			prevOffset = 0
+		}
+		response.Link = append(response.Link, models.BundleLink{
+			Relation: "prev",
+			URL:      fmt.Sprintf("/api/v1/audit-events?limit=%d&offset=%d%s", params.Limit, prevOffset, filters),
+		})
+	}
+
+	s.logger.WithContext(ctx).WithField("total", pagination.Total).Info("Audit events listed successfully")
+	return response, nil
+}
+
+// toAuditEvent maps an audit log entry to a FHIR AuditEvent
+func toAuditEvent(log *repository.AuditLog) *models.AuditEvent {
+	codes := auditActionCodes[log.Action]
+	interaction := codes.Interaction
+
+	event := &models.AuditEvent{
+		ResourceType: "AuditEvent",
+		ID:           log.ID,
+		Type: models.Coding{
+			System:  stringPtr(auditEventTypeSystem),
+			Code:    stringPtr("rest"),
+			Display: stringPtr("RESTful Operation"),
+		},
+		Subtype: []models.Coding{{
+			System: stringPtr(restfulInteractionURL),
+			Code:   &interaction,
+		}},
+		Action:   codes.Code,
+		Recorded: log.Timestamp,
+		Outcome:  "0", // only successful operations are audited
+		Source: models.AuditEventSource{
+			Observer: models.Reference{Display: stringPtr("healthcare-api")},
+		},
+	}
+
+	agent := models.AuditEventAgent{Requestor: true}
+	if log.UserID != nil {
+		agent.Who = &models.Reference{Identifier: &models.Identifier{Value: log.UserID}}
+	}
+	if log.IPAddress != nil {
+		agent.Network = &models.AuditEventNetwork{Address: log.IPAddress, Type: stringPtr("2")} // 2 = IP address
+	}
+	event.Agent = []models.AuditEventAgent{agent}
+
+	entity := models.AuditEventEntity{
+		What: &models.Reference{Reference: stringPtr(log.ResourceType + "/" + log.ResourceID.String())},
+		Type: &models.Coding{
+			System: stringPtr(auditEntityTypeSystem),
+			Code:   stringPtr("2"), // system object
+		},
+	}
+	if log.Action == "RESTORE" || log.Action == "MERGE" {
+		entity.Detail = append(entity.Detail, models.AuditEventEntityDetail{Type: "action", ValueString: strings.ToLower(log.Action)})
+	}
+	if log.RequestID != nil {
+		entity.Detail = append(entity.Detail, models.AuditEventEntityDetail{Type: "request-id", ValueString: *log.RequestID})
+	}
+	if log.UserAgent != nil {
+		entity.Detail = append(entity.Detail, models.AuditEventEntityDetail{Type: "user-agent", ValueString: *log.UserAgent})
+	}
+	event.Entity = []models.AuditEventEntity{entity}
+
+	return event
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,457 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/cache"
+	"healthcare-api/internal/deidentify"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/notify"
+	"healthcare-api/internal/objectstore"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BackupFormat identifies the snapshot layout written by this service
+const BackupFormat = "ndjson/v1"
+
+// BackupManifest describes a completed snapshot. It is written last, so a
+// snapshot without a manifest is incomplete and is never listed or restored.
+type BackupManifest struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id"`
+	Format   string `json:"format"`
+	// Profile is the de-identification profile the resources went through,
+	// making the snapshot an export that cannot be restored
+	Profile     string       `json:"profile,omitempty"`
+	SnapshotAt  time.Time    `json:"snapshot_at"`
+	CompletedAt time.Time    `json:"completed_at"`
+	Files       []BackupFile `json:"files"`
+}
+
+// BackupFile is one NDJSON file of a snapshot, one resource per line
+type BackupFile struct {
+	ResourceType string `json:"resource_type"`
+	Key          string `json:"key"`
+	Count        int    `json:"count"`
+	Bytes        int64  `json:"bytes"`
+	SHA256       string `json:"sha256"`
+}
+
+// BackupRestoreResult summarises a restore
+type BackupRestoreResult struct {
+	BackupID     string    `json:"backup_id"`
+	TenantID     string    `json:"tenant_id"`
+	Patients     int       `json:"patients"`
+	Observations int       `json:"observations"`
+	CompletedAt  time.Time `json:"completed_at"`
+}
+
+type BackupService struct {
+	repo          *repository.BackupRepository
+	store         objectstore.Store
+	cache         *cache.ResourceCache
+	notifications *NotificationService
+	pseudonymKey  []byte
+	logger        *logrus.Logger
+}
+
+func NewBackupService(repo *repository.BackupRepository, store objectstore.Store, logger *logrus.Logger) *BackupService {
+	return &BackupService{
+		repo:   repo,
+		store:  store,
+		logger: logger,
+	}
+}
+
+// SetCache makes restores flush the tenant's cached resources, which the
+// restore replaces without going through the cache
+func (s *BackupService) SetCache(cache *cache.ResourceCache) {
+	s.cache = cache
+}
+
+// SetNotifications makes completed backups notify the tenant's recipients
+func (s *BackupService) SetNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// SetPseudonymKey sets the key de-identified exports derive their pseudonyms
+// and date shifts from, so exports share pseudonyms. Without one, each export
+// uses a random key and its pseudonyms are its own.
+func (s *BackupService) SetPseudonymKey(key string) {
+	s.pseudonymKey = []byte(key)
+}
+
+// NewBackupID returns a new snapshot id; ids sort in creation order
+func NewBackupID() string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// ValidBackupID reports whether id has the form produced by NewBackupID
+func ValidBackupID(id string) bool {
+	stamp, suffix, ok := strings.Cut(id, "-")
+	if !ok || len(suffix) != 8 {
+		return false
+	}
+	if _, err := hex.DecodeString(suffix); err != nil {
+		return false
+	}
+	_, err := time.Parse("20060102T150405Z", stamp)
+	return err == nil
+}
+
+func backupPrefix(tenantID string) string {
+	return "backups/" + tenantID + "/"
+}
+
+func backupKey(tenantID, id, name string) string {
+	return backupPrefix(tenantID) + id + "/" + name
+}
+
+// CreateBackup exports the tenant's patients and observations as they were at a
+// single point in time, then writes the manifest with per-file checksums. With
+// a de-identification profile, every resource passes through it on its way
+// into the snapshot, which is then an export rather than a backup.
+func (s *BackupService) CreateBackup(ctx context.Context, id, profile string) (*BackupManifest, error) {
+	tenantID := requestctx.TenantID(ctx)
+	logger := s.logger.WithContext(ctx).WithFields(logrus.Fields{"backup_id": id, "tenant_id": tenantID})
+	logger.WithField("profile", profile).Info("Creating backup")
+
+	transform, err := s.transform(tenantID, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &BackupManifest{ID: id, TenantID: tenantID, Format: BackupFormat, Profile: profile}
+	err = s.repo.Snapshot(ctx, func(snapshot *repository.Snapshot) error {
+		manifest.SnapshotAt = snapshot.TakenAt
+		manifest.Files = nil
+
+		patients, err := s.exportFile(ctx, backupKey(tenantID, id, "Patient.ndjson"), "Patient", func(enc *json.Encoder) (int, error) {
+			count := 0
+			err := snapshot.EachPatient(ctx, func(patient *models.Patient) error {
+				count++
+				return enc.Encode(transform.Patient(patient))
+			})
+			return count, err
+		})
+		if err != nil {
+			return err
+		}
+
+		observations, err := s.exportFile(ctx, backupKey(tenantID, id, "Observation.ndjson"), "Observation", func(enc *json.Encoder) (int, error) {
+			count := 0
+			err := snapshot.EachObservation(ctx, func(observation *models.Observation) error {
+				count++
+				return enc.Encode(transform.Observation(observation))
+			})
+			return count, err
+		})
+		if err != nil {
+			return err
+		}
+
+		manifest.Files = []BackupFile{*patients, *observations}
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to create backup")
+		return nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	manifest.CompletedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode backup manifest: %w", err)
+	}
+	if err := s.store.Put(ctx, backupKey(tenantID, id, "manifest.json"), bytes.NewReader(data)); err != nil {
+		logger.WithError(err).Error("Failed to write backup manifest")
+		return nil, fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+
+	logger.WithField("snapshot_at", manifest.SnapshotAt).Info("Backup created")
+	s.notifyCompleted(ctx, manifest)
+	return manifest, nil
+}
+
+// snapshotTransform is the stage resources pass through on their way into a
+// snapshot
+type snapshotTransform interface {
+	Patient(*models.Patient) *models.Patient
+	Observation(*models.Observation) *models.Observation
+}
+
+// verbatim is the transform of backups, which store resources as they are
+type verbatim struct{}
+
+func (verbatim) Patient(patient *models.Patient) *models.Patient {
+	return patient
+}
+
+func (verbatim) Observation(observation *models.Observation) *models.Observation {
+	return observation
+}
+
+// transform returns the transform of a snapshot taken with profile
+func (s *BackupService) transform(tenantID, profile string) (snapshotTransform, error) {
+	if profile == "" {
+		return verbatim{}, nil
+	}
+
+	key := s.pseudonymKey
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate pseudonym key: %w", err)
+		}
+	}
+	deidentifier, err := deidentify.New(profile, key, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrUnsupported, err)
+	}
+	return deidentifier, nil
+}
+
+// notifyCompleted notifies the tenant's recipients of a completed backup. The
+// backup stands whether or not they can be notified.
+func (s *BackupService) notifyCompleted(ctx context.Context, manifest *BackupManifest) {
+	if s.notifications == nil {
+		return
+	}
+	data := notify.BackupCompleted{
+		TenantID:    manifest.TenantID,
+		BackupID:    manifest.ID,
+		SnapshotAt:  manifest.SnapshotAt,
+		CompletedAt: manifest.CompletedAt,
+		Link:        s.notifications.Link("/api/v1/admin/backups/" + manifest.ID),
+	}
+	for _, file := range manifest.Files {
+		switch file.ResourceType {
+		case "Patient":
+			data.Patients = file.Count
+		case "Observation":
+			data.Observations = file.Count
+		}
+	}
+	if _, err := s.notifications.Notify(ctx, models.NotificationBackupCompleted, "Backup/"+manifest.ID, data); err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("backup_id", manifest.ID).Error("Failed to queue backup notifications")
+	}
+}
+
+// exportFile streams the NDJSON produced by write into the object store, checksumming it on the way
+func (s *BackupService) exportFile(ctx context.Context, key, resourceType string, write func(*json.Encoder) (int, error)) (*BackupFile, error) {
+	file := &BackupFile{ResourceType: resourceType, Key: key}
+	hash := sha256.New()
+	counter := &countingWriter{}
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		count, err := write(json.NewEncoder(io.MultiWriter(pw, hash, counter)))
+		file.Count = count
+		pw.CloseWithError(err)
+		done <- err
+	}()
+
+	putErr := s.store.Put(ctx, key, pr)
+	// Unblock the writer if the store stopped reading early
+	pr.CloseWithError(errors.New("export aborted"))
+	writeErr := <-done
+
+	if writeErr != nil {
+		return nil, writeErr
+	}
+	if putErr != nil {
+		return nil, fmt.Errorf("failed to store %s: %w", key, putErr)
+	}
+
+	file.Bytes = counter.n
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return file, nil
+}
+
+// ListBackups returns the tenant's completed backups, newest first
+func (s *BackupService) ListBackups(ctx context.Context) ([]*BackupManifest, error) {
+	tenantID := requestctx.TenantID(ctx)
+	if tenantID == "" {
+		return nil, models.ErrTenantRequired
+	}
+
+	keys, err := s.store.List(ctx, backupPrefix(tenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var manifests []*BackupManifest
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "/manifest.json") {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(key, backupPrefix(tenantID)), "/manifest.json")
+		manifest, err := s.GetBackup(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].ID > manifests[j].ID })
+	return manifests, nil
+}
+
+// GetBackup returns the manifest of a completed backup
+func (s *BackupService) GetBackup(ctx context.Context, id string) (*BackupManifest, error) {
+	tenantID := requestctx.TenantID(ctx)
+	if tenantID == "" {
+		return nil, models.ErrTenantRequired
+	}
+	if !ValidBackupID(id) {
+		return nil, models.ErrBackupNotFound
+	}
+
+	r, err := s.store.Get(ctx, backupKey(tenantID, id, "manifest.json"))
+	if err != nil {
+		if errors.Is(err, objectstore.ErrNotFound) {
+			return nil, models.ErrBackupNotFound
+		}
+		return nil, fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+	defer r.Close()
+
+	var manifest BackupManifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode backup manifest: %w", err)
+	}
+	if manifest.Format != BackupFormat {
+		return nil, fmt.Errorf("%w backup format %q", models.ErrUnsupported, manifest.Format)
+	}
+
+	return &manifest, nil
+}
+
+// RestoreBackup rebuilds the tenant's patients and observations from a backup.
+// Every file is verified against the manifest's count and checksum while it is
+// loaded; any mismatch rolls the whole restore back.
+func (s *BackupService) RestoreBackup(ctx context.Context, id string) (*BackupRestoreResult, error) {
+	tenantID := requestctx.TenantID(ctx)
+	logger := s.logger.WithContext(ctx).WithFields(logrus.Fields{"backup_id": id, "tenant_id": tenantID})
+	logger.Warn("Restoring tenant from backup")
+
+	manifest, err := s.GetBackup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Profile != "" {
+		return nil, fmt.Errorf("%w: backup %s was de-identified with profile %s", models.ErrBackupDeidentified, id, manifest.Profile)
+	}
+
+	restorer, err := s.repo.Restore(ctx, func(restorer *repository.Restorer) error {
+		for _, file := range manifest.Files {
+			if err := s.restoreFile(ctx, restorer, file); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to restore backup")
+		return nil, fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	if s.cache != nil {
+		if err := s.cache.FlushTenant(ctx, tenantID); err != nil {
+			// Entries left behind expire with the cache TTL
+			logger.WithError(err).Error("Failed to flush resource cache after restore")
+		}
+	}
+
+	result := &BackupRestoreResult{
+		BackupID:     id,
+		TenantID:     tenantID,
+		Patients:     restorer.Patients,
+		Observations: restorer.Observations,
+		CompletedAt:  time.Now().UTC(),
+	}
+	logger.WithFields(logrus.Fields{
+		"patients":     result.Patients,
+		"observations": result.Observations,
+	}).Info("Backup restored")
+
+	return result, nil
+}
+
+// restoreFile decodes one NDJSON file into the restorer and verifies it against the manifest
+func (s *BackupService) restoreFile(ctx context.Context, restorer *repository.Restorer, file BackupFile) error {
+	r, err := s.store.Get(ctx, file.Key)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.Key, err)
+	}
+	defer r.Close()
+
+	hash := sha256.New()
+	dec := json.NewDecoder(io.TeeReader(r, hash))
+
+	count := 0
+	for dec.More() {
+		switch file.ResourceType {
+		case "Patient":
+			var patient models.Patient
+			if err := dec.Decode(&patient); err != nil {
+				return fmt.Errorf("failed to decode %s: %w", file.Key, err)
+			}
+			if err := restorer.AddPatient(ctx, &patient); err != nil {
+				return err
+			}
+		case "Observation":
+			var observation models.Observation
+			if err := dec.Decode(&observation); err != nil {
+				return fmt.Errorf("failed to decode %s: %w", file.Key, err)
+			}
+			if err := restorer.AddObservation(ctx, &observation); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w resource type %q in backup", models.ErrUnsupported, file.ResourceType)
+		}
+		count++
+	}
+
+	// The decoder stops at the last value; drain the rest so the checksum covers the whole file
+	if _, err := io.Copy(io.Discard, io.TeeReader(r, hash)); err != nil {
+		return fmt.Errorf("failed to read %s: %w", file.Key, err)
+	}
+
+	if count != file.Count {
+		return fmt.Errorf("%s holds %d resources, manifest lists %d", file.Key, count, file.Count)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != file.SHA256 {
+		return fmt.Errorf("%s checksum mismatch", file.Key)
+	}
+
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/ucum"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// CohortService stores cohort definitions and evaluates them. A cohort is
+// evaluated by a single query over the tenant's patients, with a semi-join on
+// observations per observation criterion, so members are never loaded to be
+// filtered in memory.
+type CohortService struct {
+	repo   *repository.CohortRepository
+	logger *logrus.Logger
+}
+
+func NewCohortService(repo *repository.CohortRepository, logger *logrus.Logger) *CohortService {
+	return &CohortService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateCohort stores a cohort definition
+func (s *CohortService) CreateCohort(ctx context.Context, req *models.CohortRequest) (*models.Cohort, error) {
+	if err := validateCohortCriteria(&req.Criteria); err != nil {
+		return nil, err
+	}
+
+	cohort := &models.Cohort{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Description: req.Description,
+		Criteria:    req.Criteria,
+	}
+	if userID := requestctx.UserID(ctx); userID != "" {
+		cohort.CreatedBy = &userID
+	}
+
+	if err := s.repo.Create(ctx, cohort); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to create cohort")
+		return nil, fmt.Errorf("failed to create cohort: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithField("cohort_id", cohort.ID).Info("Cohort created")
+	return cohort, nil
+}
+
+func (s *CohortService) GetCohort(ctx context.Context, id uuid.UUID) (*models.Cohort, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// ListCohorts returns the tenant's cohorts by name
+func (s *CohortService) ListCohorts(ctx context.Context, limit, offset int) ([]*models.Cohort, repository.PaginationResult, error) {
+	return s.repo.List(ctx, repository.ValidatePaginationParams(limit, offset))
+}
+
+// ReplaceCohort replaces the definition of a cohort
+func (s *CohortService) ReplaceCohort(ctx context.Context, id uuid.UUID, req *models.CohortRequest) (*models.Cohort, error) {
+	if err := validateCohortCriteria(&req.Criteria); err != nil {
+		return nil, err
+	}
+
+	cohort := &models.Cohort{
+		ID:          id,
+		Name:        req.Name,
+		Description: req.Description,
+		Criteria:    req.Criteria,
+	}
+	if err := s.repo.Update(ctx, cohort); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).WithField("cohort_id", id).Info("Cohort updated")
+	return cohort, nil
+}
+
+func (s *CohortService) DeleteCohort(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.logger.WithContext(ctx).WithField("cohort_id", id).Info("Cohort deleted")
+	return nil
+}
+
+// CountMembers returns the number of patients currently in a cohort
+func (s *CohortService) CountMembers(ctx context.Context, id uuid.UUID) (int64, error) {
+	query, err := s.query(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	total, err := s.repo.Count(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to evaluate cohort: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"cohort_id": id,
+		"total":     total,
+	}).Info("Cohort evaluated")
+	return total, nil
+}
+
+// ListMembers returns a page of the ids of the patients currently in a cohort
+func (s *CohortService) ListMembers(ctx context.Context, id uuid.UUID, limit, offset int) ([]uuid.UUID, repository.PaginationResult, error) {
+	query, err := s.query(ctx, id)
+	if err != nil {
+		return nil, repository.PaginationResult{}, err
+	}
+
+	ids, pagination, err := s.repo.Members(ctx, query, repository.ValidatePaginationParams(limit, offset))
+	if err != nil {
+		return nil, repository.PaginationResult{}, fmt.Errorf("failed to evaluate cohort: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"cohort_id": id,
+		"total":     pagination.Total,
+	}).Info("Cohort evaluated")
+	return ids, pagination, nil
+}
+
+// query resolves the criteria of a cohort as of now: ages become birth date
+// bounds, periods start times, and a value in a unit becomes a threshold for
+// each unit stored results of the code have that it converts to
+func (s *CohortService) query(ctx context.Context, id uuid.UUID) (repository.CohortQuery, error) {
+	cohort, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return repository.CohortQuery{}, err
+	}
+	criteria := cohort.Criteria
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	query := repository.CohortQuery{
+		Gender: criteria.Gender,
+		Active: criteria.Active,
+	}
+	if criteria.MinAge != nil {
+		bound := today.AddDate(-*criteria.MinAge, 0, 0)
+		query.BornOnOrBefore = &bound
+	}
+	if criteria.MaxAge != nil {
+		bound := today.AddDate(-*criteria.MaxAge-1, 0, 0)
+		query.BornAfter = &bound
+	}
+
+	for _, criterion := range criteria.Observations {
+		observation := repository.CohortObservationQuery{
+			Code:       criterion.Code,
+			Comparator: criterion.Comparator,
+			Exclude:    criterion.Exclude,
+		}
+		if criterion.Within != "" {
+			period, err := ParseTrendDuration(criterion.Within)
+			if err != nil {
+				return repository.CohortQuery{}, fmt.Errorf("%w: %v", models.ErrInvalidCohort, err)
+			}
+			since := now.Add(-period)
+			observation.Since = &since
+		}
+		if criterion.ValueCode != "" {
+			observation.ValueCoding = tokenCoding(criterion.ValueCode)
+		}
+		if criterion.Value != nil {
+			observation.Value = *criterion.Value
+		}
+		if criterion.Unit != "" {
+			thresholds, err := s.thresholds(ctx, criterion)
+			if err != nil {
+				return repository.CohortQuery{}, err
+			}
+			observation.Thresholds = thresholds
+		}
+		query.Observations = append(query.Observations, observation)
+	}
+
+	return query, nil
+}
+
+// thresholds converts a criterion's value to each unit the stored results of
+// its code have; units it does not convert to are left out
+func (s *CohortService) thresholds(ctx context.Context, criterion models.CohortObservationCriterion) (map[string]float64, error) {
+	units, err := s.repo.ResultUnits(ctx, criterion.Code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate cohort: %w", err)
+	}
+
+	thresholds := make(map[string]float64, len(units))
+	for _, unit := range units {
+		value, err := ucum.Convert(*criterion.Value, criterion.Unit, unit)
+		if err != nil {
+			continue
+		}
+		thresholds[unit] = value
+	}
+	return thresholds, nil
+}
+
+// validateCohortCriteria checks that criteria can be evaluated, wrapping
+// models.ErrInvalidCohort when they cannot
+func validateCohortCriteria(criteria *models.CohortCriteria) error {
+	if criteria.MinAge != nil && criteria.MaxAge != nil && *criteria.MinAge > *criteria.MaxAge {
+		return fmt.Errorf("%w: minAge must not be above maxAge", models.ErrInvalidCohort)
+	}
+
+	for i, criterion := range criteria.Observations {
+		if tokenCoding(criterion.Code) == nil {
+			return fmt.Errorf("%w: observation %d: invalid code %q", models.ErrInvalidCohort, i, criterion.Code)
+		}
+		if criterion.Within != "" {
+			if _, err := ParseTrendDuration(criterion.Within); err != nil {
+				return fmt.Errorf("%w: observation %d: %v", models.ErrInvalidCohort, i, err)
+			}
+		}
+		if (criterion.Comparator == "") != (criterion.Value == nil) {
+			return fmt.Errorf("%w: observation %d: comparator and value must be given together", models.ErrInvalidCohort, i)
+		}
+		if criterion.Unit != "" {
+			if criterion.Value == nil {
+				return fmt.Errorf("%w: observation %d: unit requires a value", models.ErrInvalidCohort, i)
+			}
+			if _, err := ucum.Parse(criterion.Unit); err != nil {
+				return fmt.Errorf("%w: observation %d: %v", models.ErrInvalidCohort, i, err)
+			}
+		}
+		if criterion.ValueCode != "" {
+			if criterion.Value != nil {
+				return fmt.Errorf("%w: observation %d: a quantity value and a value code cannot both be matched", models.ErrInvalidCohort, i)
+			}
+			if tokenCoding(criterion.ValueCode) == nil {
+				return fmt.Errorf("%w: observation %d: invalid value code %q", models.ErrInvalidCohort, i, criterion.ValueCode)
+			}
+		}
+	}
+	return nil
+}
+
+// tokenCoding parses a code, or system|code, returning nil when the code is empty
+func tokenCoding(token string) *models.Coding {
+	system, code, found := strings.Cut(token, "|")
+	if !found {
+		system, code = "", token
+	}
+	if code == "" {
+		return nil
+	}
+	coding := &models.Coding{Code: &code}
+	if system != "" {
+		coding.System = &system
+	}
+	return coding
+}
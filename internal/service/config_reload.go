@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/features"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// PlatformAuditTenant is the tenant of audit entries about the deployment
+// rather than a tenant's data. It is not a valid tenant id, so it never
+// belongs to a tenant.
+const PlatformAuditTenant = "_platform"
+
+// configurationID is the resource id of the audit entries of configuration
+// changes
+var configurationID = uuid.NewSHA1(uuid.NameSpaceURL, []byte("urn:healthcare-api:configuration"))
+
+// ConfigService reloads the settings that can change while the server runs:
+// the log level, rate limits, CORS policy, feature flags and maintenance mode.
+// Every reload that changes a setting is recorded in the audit log.
+type ConfigService struct {
+	mu          sync.Mutex
+	current     *config.Config
+	load        func() (*config.Config, error)
+	audit       *repository.BaseRepository
+	rateLimiter interface{ SetConfig(config.RateLimitConfig) }
+	cors        interface{ SetConfig(config.CORSConfig) }
+	features    *features.Flags
+	maintenance interface {
+		SetConfig(config.MaintenanceConfig)
+	}
+	logger *logrus.Logger
+}
+
+// NewConfigService creates the service for a server started with cfg. Reloads
+// set the level of logger; the other settings are applied to what is set with
+// SetRateLimiter, SetCORS, SetFeatures and SetMaintenance.
+func NewConfigService(cfg *config.Config, audit *repository.BaseRepository, logger *logrus.Logger) *ConfigService {
+	return &ConfigService{
+		current: cfg,
+		load:    config.Load,
+		audit:   audit,
+		logger:  logger,
+	}
+}
+
+// SetRateLimiter makes reloads apply rate limits to limiter
+func (s *ConfigService) SetRateLimiter(limiter interface{ SetConfig(config.RateLimitConfig) }) {
+	s.rateLimiter = limiter
+}
+
+// SetCORS makes reloads apply the CORS settings to policy
+func (s *ConfigService) SetCORS(policy interface{ SetConfig(config.CORSConfig) }) {
+	s.cors = policy
+}
+
+// SetFeatures makes reloads apply feature flags to flags
+func (s *ConfigService) SetFeatures(flags *features.Flags) {
+	s.features = flags
+}
+
+// SetMaintenance makes reloads apply the maintenance settings to mode
+func (s *ConfigService) SetMaintenance(mode interface {
+	SetConfig(config.MaintenanceConfig)
+}) {
+	s.maintenance = mode
+}
+
+// Reload reads the configuration again, with the changes made to the .env
+// file, and applies the reloadable settings that changed, returning them. An
+// invalid configuration changes nothing. source names what asked for the reload; a
+// reload without a user, such as one on a signal, is audited as
+// "system:<source>".
+func (s *ConfigService) Reload(ctx context.Context, source string) (*models.ConfigReload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, err := s.load()
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to reload configuration")
+		return nil, fmt.Errorf("failed to reload configuration: %w", err)
+	}
+
+	before, after := s.current.ReloadableSettings(), cfg.ReloadableSettings()
+	result := &models.ConfigReload{ReloadedAt: time.Now().UTC(), Source: source, Changes: []models.ConfigChange{}}
+	for setting, value := range after {
+		if before[setting] != value && s.applies(setting) {
+			result.Changes = append(result.Changes, models.ConfigChange{Setting: setting, Old: before[setting], New: value})
+		}
+	}
+	sort.Slice(result.Changes, func(i, j int) bool {
+		return result.Changes[i].Setting < result.Changes[j].Setting
+	})
+	// Only what changed is applied, so rate limit buckets are kept otherwise
+	changed := func(prefix string) bool {
+		for _, change := range result.Changes {
+			if strings.HasPrefix(change.Setting, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if changed("LOG_LEVEL") {
+		s.logger.SetLevel(logrus.Level(cfg.LogLevel))
+	}
+	if changed("RATE_LIMIT_") {
+		s.rateLimiter.SetConfig(cfg.RateLimit)
+	}
+	if changed("CORS_") {
+		s.cors.SetConfig(cfg.CORS)
+	}
+	if changed("FEATURE_FLAGS") {
+		s.features.Set(cfg.Features)
+	}
+	if changed("MAINTENANCE_") {
+		s.maintenance.SetConfig(cfg.Maintenance)
+	}
+	s.current.LogLevel = cfg.LogLevel
+	s.current.RateLimit = cfg.RateLimit
+	s.current.CORS = cfg.CORS
+	s.current.Features = cfg.Features
+	s.current.Maintenance = cfg.Maintenance
+
+	logger := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"source":  source,
+		"changes": len(result.Changes),
+	})
+	if len(result.Changes) == 0 {
+		logger.Info("Configuration reloaded without changes")
+		return result, nil
+	}
+	// The settings are applied already, so a failure to audit is logged
+	if err := s.auditChanges(ctx, source, result.Changes); err != nil {
+		logger.WithError(err).Error("Failed to audit configuration changes")
+	}
+	logger.Info("Configuration reloaded")
+	return result, nil
+}
+
+// applies reports whether the process applies a setting. A worker, for one,
+// has no rate limits or CORS policy, so changes to them are not its to report.
+func (s *ConfigService) applies(setting string) bool {
+	switch {
+	case strings.HasPrefix(setting, "RATE_LIMIT_"):
+		return s.rateLimiter != nil
+	case strings.HasPrefix(setting, "CORS_"):
+		return s.cors != nil
+	case setting == "FEATURE_FLAGS":
+		return s.features != nil
+	case strings.HasPrefix(setting, "MAINTENANCE_"):
+		return s.maintenance != nil
+	}
+	return true
+}
+
+// auditChanges records the changed settings, old and new, in the audit log of
+// PlatformAuditTenant
+func (s *ConfigService) auditChanges(ctx context.Context, source string, changes []models.ConfigChange) error {
+	oldValues := make(map[string]string, len(changes))
+	newValues := make(map[string]string, len(changes))
+	for _, change := range changes {
+		oldValues[change.Setting] = change.Old
+		newValues[change.Setting] = change.New
+	}
+	oldJSON, err := json.Marshal(oldValues)
+	if err != nil {
+		return err
+	}
+	newJSON, err := json.Marshal(newValues)
+	if err != nil {
+		return err
+	}
+
+	userID := requestctx.UserID(ctx)
+	if userID == "" {
+		userID = "system:" + source
+	}
+	return s.audit.LogAudit(requestctx.WithTenantID(ctx, PlatformAuditTenant), &repository.AuditLog{
+		ResourceType: "Configuration",
+		ResourceID:   configurationID,
+		Action:       "UPDATE",
+		UserID:       &userID,
+		OldValues:    oldJSON,
+		NewValues:    newJSON,
+	})
+}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/ucum"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// CriticalValueService manages the tenant's critical value rules, which flag
+// results as critical by per-code thresholds at write time, and the tasks
+// asking clinicians to acknowledge critical results. A critical result, flagged
+// by a rule or sent with a critical interpretation, opens a task and notifies
+// the tenant's recipients; acknowledging either completes both.
+type CriticalValueService struct {
+	repo          *repository.CriticalValueRepository
+	notifications *NotificationService
+	logger        *logrus.Logger
+}
+
+func NewCriticalValueService(repo *repository.CriticalValueRepository, logger *logrus.Logger) *CriticalValueService {
+	return &CriticalValueService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// SetNotifications makes acknowledging a task also acknowledge the
+// notifications of its result
+func (s *CriticalValueService) SetNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// CreateRule adds a critical value rule
+func (s *CriticalValueService) CreateRule(ctx context.Context, req *models.CriticalValueRuleCreateRequest) (*models.CriticalValueRule, error) {
+	rule := &models.CriticalValueRule{
+		ID:     uuid.New(),
+		Name:   req.Name,
+		System: req.System,
+		Code:   req.Code,
+		Unit:   req.Unit,
+		Low:    req.Low,
+		High:   req.High,
+		Active: req.Active == nil || *req.Active,
+	}
+	if err := validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateRule(ctx, rule); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to create critical value rule")
+		return nil, fmt.Errorf("failed to create critical value rule: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithField("rule_id", rule.ID).Info("Critical value rule created")
+	return rule, nil
+}
+
+func (s *CriticalValueService) GetRule(ctx context.Context, id uuid.UUID) (*models.CriticalValueRule, error) {
+	return s.repo.GetRule(ctx, id)
+}
+
+// ListRules returns the tenant's critical value rules
+func (s *CriticalValueService) ListRules(ctx context.Context) ([]*models.CriticalValueRule, error) {
+	rules, err := s.repo.ListRules(ctx)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list critical value rules")
+		return nil, fmt.Errorf("failed to list critical value rules: %w", err)
+	}
+	return rules, nil
+}
+
+// UpdateRule applies the fields set in req
+func (s *CriticalValueService) UpdateRule(ctx context.Context, id uuid.UUID, req *models.CriticalValueRuleUpdateRequest) (*models.CriticalValueRule, error) {
+	rule, err := s.repo.GetRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		rule.Name = *req.Name
+	}
+	if req.System != nil {
+		rule.System = *req.System
+	}
+	if req.Code != nil {
+		rule.Code = *req.Code
+	}
+	if req.Unit != nil {
+		rule.Unit = *req.Unit
+	}
+	if req.ClearLow {
+		rule.Low = nil
+	}
+	if req.Low != nil {
+		rule.Low = req.Low
+	}
+	if req.ClearHigh {
+		rule.High = nil
+	}
+	if req.High != nil {
+		rule.High = req.High
+	}
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+	if err := validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateRule(ctx, rule); err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("rule_id", id).Error("Failed to update critical value rule")
+		return nil, fmt.Errorf("failed to update critical value rule: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithField("rule_id", id).Info("Critical value rule updated")
+	return rule, nil
+}
+
+// DeleteRule removes a critical value rule; results it flagged stay critical
+func (s *CriticalValueService) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.DeleteRule(ctx, id); err != nil {
+		return err
+	}
+	s.logger.WithContext(ctx).WithField("rule_id", id).Info("Critical value rule deleted")
+	return nil
+}
+
+// validateRule checks that a rule has thresholds in a UCUM unit, wrapping
+// models.ErrInvalidCriticalValueRule when it does not
+func validateRule(rule *models.CriticalValueRule) error {
+	if _, err := ucum.Parse(rule.Unit); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidCriticalValueRule, err)
+	}
+	if rule.Low == nil && rule.High == nil {
+		return fmt.Errorf("%w: a low or high threshold is required", models.ErrInvalidCriticalValueRule)
+	}
+	if rule.Low != nil && rule.High != nil && *rule.Low >= *rule.High {
+		return fmt.Errorf("%w: the low threshold must be below the high threshold", models.ErrInvalidCriticalValueRule)
+	}
+	return nil
+}
+
+// Flag applies the tenant's active rules for the observation's code to its
+// quantity value, before the observation is stored. A value below a rule's low
+// threshold, or above its high one, gets the critical interpretation LL or HH,
+// which then opens a task and notifies like one sent with the result. The
+// rules are checked in the order they were added, and the first the value
+// breaches flags it. An observation that is already critical, or whose value
+// is inexact, such as "<5", is left alone, as is a value whose unit cannot be
+// converted to a rule's.
+func (s *CriticalValueService) Flag(ctx context.Context, observation *models.Observation) error {
+	value := observation.ValueQuantity
+	if value == nil || value.Value == nil || value.Comparator != nil {
+		return nil
+	}
+	if _, critical := models.CriticalInterpretation(observation); critical {
+		return nil
+	}
+
+	var codes []string
+	for _, coding := range observation.Code.Coding {
+		if coding.Code != nil {
+			codes = append(codes, *coding.Code)
+		}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	rules, err := s.repo.MatchRules(ctx, codes)
+	if err != nil {
+		return fmt.Errorf("failed to match critical value rules: %w", err)
+	}
+
+	for _, rule := range rules {
+		if !hasCoding(observation.Code, rule.System, rule.Code) {
+			continue
+		}
+		v, err := valueIn(value, rule.Unit)
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+				"rule_id":        rule.ID,
+				"observation_id": observation.ID,
+			}).Warn("Critical value rule skipped: the result's unit cannot be converted")
+			continue
+		}
+
+		var code string
+		switch {
+		case rule.Low != nil && v < *rule.Low:
+			code = "LL"
+		case rule.High != nil && v > *rule.High:
+			code = "HH"
+		default:
+			continue
+		}
+
+		display := interpretationDisplays[code]
+		system := interpretationSystem
+		concept := models.CodeableConcept{
+			Coding: []models.Coding{{System: &system, Code: &code, Display: &display}},
+		}
+		observation.Interpretation = append([]models.CodeableConcept{concept}, observation.Interpretation...)
+
+		s.logger.WithContext(ctx).WithFields(logrus.Fields{
+			"rule_id":        rule.ID,
+			"observation_id": observation.ID,
+			"interpretation": code,
+		}).Info("Observation flagged by critical value rule")
+		return nil
+	}
+	return nil
+}
+
+// hasCoding reports whether concept has a coding of system and code
+func hasCoding(concept models.CodeableConcept, system, code string) bool {
+	for _, coding := range concept.Coding {
+		if coding.Code != nil && *coding.Code == code && coding.System != nil && *coding.System == system {
+			return true
+		}
+	}
+	return false
+}
+
+// valueIn returns a quantity's value in the UCUM unit unit
+func valueIn(quantity *models.Quantity, unit string) (float64, error) {
+	code := ucumCode(quantity)
+	if code == "" {
+		// Without a UCUM code to convert from, the unit must be the same
+		if unitText(quantity) != unit {
+			return 0, fmt.Errorf("%w: %q and %s", ucum.ErrIncompatible, unitText(quantity), unit)
+		}
+		return *quantity.Value, nil
+	}
+	return ucum.Convert(*quantity.Value, code, unit)
+}
+
+// OpenTask opens the task asking a clinician to acknowledge a critical result,
+// reporting whether it is new; a result has one task, however often it is
+// updated
+func (s *CriticalValueService) OpenTask(ctx context.Context, observation *models.Observation, interpretation models.Coding) (bool, error) {
+	code := codingName(interpretation)
+	if interpretation.Code != nil {
+		code = *interpretation.Code
+	}
+	description := conceptName(observation.Code) + " is " + codingName(interpretation)
+	if value := observation.ValueQuantity; value != nil && value.Value != nil {
+		description += ": " + strconv.FormatFloat(*value.Value, 'f', -1, 64)
+		if unit := unitText(value); unit != "" {
+			description += " " + unit
+		}
+	}
+	subject := ""
+	if observation.Subject.Reference != nil {
+		subject = *observation.Subject.Reference
+	}
+
+	task := &models.CriticalValueTask{
+		ID:             uuid.New(),
+		ObservationID:  observation.ID,
+		Subject:        subject,
+		Interpretation: code,
+		Description:    description,
+	}
+	created, err := s.repo.OpenTask(ctx, task)
+	if err != nil {
+		return false, err
+	}
+	if created {
+		s.logger.WithContext(ctx).WithFields(logrus.Fields{
+			"task_id":        task.ID,
+			"observation_id": observation.ID,
+		}).Info("Critical result task opened")
+	}
+	return created, nil
+}
+
+// GetTask returns a critical result task as a FHIR Task
+func (s *CriticalValueService) GetTask(ctx context.Context, id uuid.UUID) (*models.Task, error) {
+	task, err := s.repo.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return task.ToFHIR(), nil
+}
+
+// AcknowledgeTask records that the caller has seen a critical result,
+// completing its task and acknowledging its notifications, which stops the
+// escalation of its pages. Acknowledging a completed task changes nothing.
+func (s *CriticalValueService) AcknowledgeTask(ctx context.Context, id uuid.UUID) (*models.Task, error) {
+	userID := requestctx.UserID(ctx)
+	task, err := s.repo.CompleteTask(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.notifications != nil {
+		reference := "Observation/" + task.ObservationID.String()
+		if err := s.notifications.AcknowledgeEvent(ctx, models.NotificationCriticalResult, reference); err != nil {
+			s.logger.WithContext(ctx).WithError(err).WithField("task_id", id).Error("Failed to acknowledge critical result notifications")
+			return nil, fmt.Errorf("failed to acknowledge critical result notifications: %w", err)
+		}
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"task_id":        id,
+		"observation_id": task.ObservationID,
+	}).Info("Critical result acknowledged")
+	return task.ToFHIR(), nil
+}
+
+// ListTasks returns the tenant's critical result tasks as a bundle of FHIR
+// Tasks, most recent first
+func (s *CriticalValueService) ListTasks(ctx context.Context, filter repository.TaskFilter, limit, offset int) (*models.TaskListResponse, error) {
+	params := repository.ValidatePaginationParams(limit, offset)
+
+	tasks, pagination, err := s.repo.ListTasks(ctx, filter, params)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list tasks")
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	entries := make([]models.TaskEntry, len(tasks))
+	for i, task := range tasks {
+		entries[i] = models.TaskEntry{
+			FullURL:  fmt.Sprintf("/api/v1/tasks/%s", task.ID),
+			Resource: task.ToFHIR(),
+			Search: &models.SearchEntry{
+				Mode: "match",
+			},
+		}
+	}
+
+	response := &models.TaskListResponse{
+		ResourceType: "Bundle",
+		ID:           uuid.New().String(),
+		Type:         "searchset",
+		Total:        pagination.Total,
+		Entry:        entries,
+	}
+
+	// Carry search filters through to pagination links
+	query := url.Values{}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+	if filter.Subject != "" {
+		query.Set("subject", filter.Subject)
+	}
+	if filter.ObservationID != nil {
+		query.Set("focus", "Observation/"+filter.ObservationID.String())
+	}
+	filters := ""
+	if len(query) > 0 {
+		filters = "&" + query.Encode()
+	}
+
+	if pagination.HasNext {
+		response.Link = append(response.Link, models.BundleLink{
+			Relation: "next",
+			URL:      fmt.Sprintf("/api/v1/tasks?limit=%d&offset=%d%s", params.Limit, params.Offset+params.Limit, filters),
+		})
+	}
+
+	if params.Offset > 0 {
+		prevOffset := params.Offset - params.Limit
+		if prevOffset < 0 {
+			prevOffset = 0
+		}
+		response.Link = append(response.Link, models.BundleLink{
+			Relation: "prev",
+			URL:      fmt.Sprintf("/api/v1/tasks?limit=%d&offset=%d%s", params.Limit, prevOffset, filters),
+		})
+	}
+
+	return response, nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DataQualityService reports the data quality issues in a tenant's resources.
+// The issues a resource has on its own are also flagged in its meta.tag as it
+// is written, by the patient and observation services.
+type DataQualityService struct {
+	repo   *repository.DataQualityRepository
+	logger *logrus.Logger
+}
+
+func NewDataQualityService(repo *repository.DataQualityRepository, logger *logrus.Logger) *DataQualityService {
+	return &DataQualityService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Report scans the tenant's live resources, listing up to limit resources per issue
+func (s *DataQualityService) Report(ctx context.Context, limit int) (*models.DataQualityReport, error) {
+	report := &models.DataQualityReport{GeneratedAt: time.Now().UTC()}
+
+	issues, err := s.repo.Scan(ctx, limit)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to scan data quality")
+		return nil, fmt.Errorf("failed to scan data quality: %w", err)
+	}
+	report.Issues = issues
+
+	var total int64
+	for _, issue := range issues {
+		total += issue.Count
+	}
+	s.logger.WithContext(ctx).WithField("issues", total).Info("Data quality report generated")
+	return report, nil
+}
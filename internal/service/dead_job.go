@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// JobRequeuer puts a dead job back on the job queue; worker.WorkerPool implements it
+type JobRequeuer interface {
+	Requeue(dead *models.DeadJob) error
+}
+
+// DeadJobService manages background jobs that failed permanently
+type DeadJobService struct {
+	repo     *repository.DeadJobRepository
+	requeuer JobRequeuer
+	logger   *logrus.Logger
+}
+
+func NewDeadJobService(repo *repository.DeadJobRepository, requeuer JobRequeuer, logger *logrus.Logger) *DeadJobService {
+	return &DeadJobService{
+		repo:     repo,
+		requeuer: requeuer,
+		logger:   logger,
+	}
+}
+
+func (s *DeadJobService) ListDeadJobs(ctx context.Context, filter repository.DeadJobFilter, limit, offset int) ([]*models.DeadJob, repository.PaginationResult, error) {
+	params := repository.ValidatePaginationParams(limit, offset)
+
+	jobs, pagination, err := s.repo.List(ctx, filter, params)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list dead jobs")
+		return nil, repository.PaginationResult{}, fmt.Errorf("failed to list dead jobs: %w", err)
+	}
+
+	return jobs, pagination, nil
+}
+
+func (s *DeadJobService) GetDeadJob(ctx context.Context, id string) (*models.DeadJob, error) {
+	job, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// RequeueDeadJob submits a dead job again and removes it from the dead letter table.
+// The job is submitted first, so a failure to delete can only cause a duplicate entry.
+func (s *DeadJobService) RequeueDeadJob(ctx context.Context, id string) (*models.DeadJob, error) {
+	job, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.requeuer.Requeue(job); err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("job_id", id).Error("Failed to requeue dead job")
+		return nil, fmt.Errorf("failed to requeue dead job: %w", err)
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("job_id", id).Warn("Requeued dead job but failed to remove it")
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":   id,
+		"job_type": job.JobType,
+	}).Info("Dead job requeued")
+	return job, nil
+}
+
+func (s *DeadJobService) DeleteDeadJob(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.WithContext(ctx).WithField("job_id", id).Info("Dead job deleted")
+	return nil
+}
+
+// PurgeDeadJobs deletes every dead job matching filter
+func (s *DeadJobService) PurgeDeadJobs(ctx context.Context, filter repository.DeadJobFilter) (int64, error) {
+	purged, err := s.repo.Purge(ctx, filter)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to purge dead jobs")
+		return 0, fmt.Errorf("failed to purge dead jobs: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithField("purged", purged).Info("Dead jobs purged")
+	return purged, nil
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/ucum"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// DeriveObservationsJobType is the job type calculating the observations
+// derived from the measurements of an encounter
+const DeriveObservationsJobType = "observation_derive"
+
+// derivedObservationIDNamespace is the UUID namespace of the ids of derived
+// observations, one per encounter and calculation
+var derivedObservationIDNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("urn:healthcare-api:derived-observation"))
+
+// calculationInput is a measurement a calculation takes, read from the latest
+// observation of the encounter with one of its LOINC codes, in unit
+type calculationInput struct {
+	codes []string
+	unit  string
+}
+
+// calculation derives an observation from the measurements of an encounter
+type calculation struct {
+	code    string
+	display string
+	unit    string
+	// decimals the result is rounded to
+	decimals  int
+	inputs    []calculationInput
+	calculate func(values []float64) float64
+}
+
+// Body height and weight, measured or stated
+var (
+	bodyHeight = calculationInput{codes: []string{"8302-2", "8306-3", "3137-7", "3138-5"}, unit: "m"}
+	bodyWeight = calculationInput{codes: []string{"29463-7", "3141-9", "3142-7"}, unit: "kg"}
+)
+
+// calculations are the derived observations that can be enabled, by name
+var calculations = map[string]calculation{
+	"bmi": {
+		code:     "39156-5",
+		display:  "Body mass index (BMI) [Ratio]",
+		unit:     "kg/m2",
+		decimals: 1,
+		inputs:   []calculationInput{bodyHeight, bodyWeight},
+		calculate: func(values []float64) float64 {
+			return values[1] / (values[0] * values[0])
+		},
+	},
+	// Body surface area by the Mosteller formula
+	"bsa": {
+		code:     "8277-6",
+		display:  "Body surface area",
+		unit:     "m2",
+		decimals: 2,
+		inputs:   []calculationInput{bodyHeight, bodyWeight},
+		calculate: func(values []float64) float64 {
+			return math.Sqrt(values[0] * 100 * values[1] / 3600)
+		},
+	},
+}
+
+// DerivedObservationService records observations calculated from others of
+// the same encounter, such as a body mass index from a height and a weight.
+// Storing a measurement schedules a job recalculating the observations that
+// take it, so each encounter has one observation per calculation, derived
+// from its latest measurements.
+type DerivedObservationService struct {
+	observations       repository.ObservationStore
+	observationService *ObservationService
+	calculations       []string
+	logger             *logrus.Logger
+}
+
+// NewDerivedObservationService creates the service running the calculations
+// of cfg, recording their results through observationService
+func NewDerivedObservationService(observations repository.ObservationStore, observationService *ObservationService, cfg config.DerivedObservationConfig, logger *logrus.Logger) (*DerivedObservationService, error) {
+	var enabled []string
+	for _, name := range cfg.Calculations {
+		if name == "none" {
+			continue
+		}
+		if _, ok := calculations[name]; !ok {
+			return nil, fmt.Errorf("unknown derived observation %q: expected bmi, bsa or none", name)
+		}
+		enabled = append(enabled, name)
+	}
+	return &DerivedObservationService{
+		observations:       observations,
+		observationService: observationService,
+		calculations:       enabled,
+		logger:             logger,
+	}, nil
+}
+
+// Taking returns the enabled calculations taking observation as an input. It
+// returns none for an observation without a subject or encounter.
+func (s *DerivedObservationService) Taking(observation *models.Observation) []string {
+	if observation.Subject.Reference == nil || observation.Encounter == nil || observation.Encounter.Reference == nil {
+		return nil
+	}
+
+	var taking []string
+	for _, name := range s.calculations {
+	inputs:
+		for _, input := range calculations[name].inputs {
+			for _, code := range input.codes {
+				if hasCoding(observation.Code, loincSystem, code) {
+					taking = append(taking, name)
+					break inputs
+				}
+			}
+		}
+	}
+	return taking
+}
+
+// Derive runs the calculations of payload over the latest measurements of its
+// encounter, creating or updating the encounter's derived observations. A
+// calculation missing a measurement is skipped, as is a derived observation
+// that was deleted. It returns the number of observations written.
+func (s *DerivedObservationService) Derive(ctx context.Context, payload models.ObservationDerivePayload) (int, error) {
+	written := 0
+	for _, name := range payload.Calculations {
+		calc, ok := calculations[name]
+		if !ok {
+			return written, fmt.Errorf("%w: derived observation %q", models.ErrUnsupported, name)
+		}
+		wrote, err := s.derive(ctx, name, calc, payload.Subject, payload.Encounter)
+		if err != nil {
+			return written, fmt.Errorf("failed to derive %s: %w", name, err)
+		}
+		if wrote {
+			written++
+		}
+	}
+	return written, nil
+}
+
+func (s *DerivedObservationService) derive(ctx context.Context, name string, calc calculation, subject, encounter string) (bool, error) {
+	logger := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"calculation": name,
+		"encounter":   encounter,
+	})
+
+	values := make([]float64, len(calc.inputs))
+	var derivedFrom []models.Reference
+	var effective *time.Time
+	for i, input := range calc.inputs {
+		codes := make([]string, len(input.codes))
+		for j, code := range input.codes {
+			codes[j] = loincSystem + "|" + code
+		}
+		observation, err := s.observations.LatestInEncounter(ctx, subject, encounter, codes)
+		if errors.Is(err, models.ErrObservationNotFound) {
+			logger.Debug("Encounter is missing a measurement")
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		reading, ok := readQuantity(input.codes, input.unit)(observation)
+		if !ok || *reading.value <= 0 {
+			logger.WithField("observation_id", observation.ID).Warn("Latest measurement has no value that can be calculated with")
+			return false, nil
+		}
+		values[i] = *reading.value
+
+		reference := "Observation/" + observation.ID.String()
+		derivedFrom = append(derivedFrom, models.Reference{Reference: &reference})
+		if at := repository.ExtractObservationSearchColumns(observation).EffectiveDate; at != nil && (effective == nil || at.After(*effective)) {
+			effective = at
+		}
+	}
+
+	scale := math.Pow(10, float64(calc.decimals))
+	value := math.Round(calc.calculate(values)*scale) / scale
+
+	id := uuid.NewSHA1(derivedObservationIDNamespace, []byte(requestctx.TenantID(ctx)+"\x00"+encounter+"\x00"+name))
+	existing, err := s.observations.GetByID(ctx, id)
+	switch {
+	case errors.Is(err, models.ErrObservationNotFound):
+		if _, err := s.observationService.createObservation(ctx, id, calculationRequest(calc, subject, encounter, value, derivedFrom, effective)); err != nil {
+			return false, err
+		}
+		logger.WithField("observation_id", id).Info("Derived observation created")
+		return true, nil
+	case errors.Is(err, models.ErrResourceDeleted):
+		logger.WithField("observation_id", id).Info("Derived observation was deleted, not recalculating it")
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to check for derived observation: %w", err)
+	}
+
+	if existing.Status == "final" && existing.ValueQuantity != nil && existing.ValueQuantity.Value != nil &&
+		*existing.ValueQuantity.Value == value && sameReferences(existing.DerivedFrom, derivedFrom) {
+		return false, nil
+	}
+	req := calculationRequest(calc, subject, encounter, value, derivedFrom, effective)
+	if _, err := s.observationService.UpdateObservation(ctx, id, &models.ObservationUpdateRequest{
+		Status:            &req.Status,
+		EffectiveDateTime: req.EffectiveDateTime,
+		ValueQuantity:     req.ValueQuantity,
+		DerivedFrom:       req.DerivedFrom,
+	}); err != nil {
+		return false, err
+	}
+	logger.WithField("observation_id", id).Info("Derived observation updated")
+	return true, nil
+}
+
+// calculationRequest returns the request creating the observation of a
+// calculation's result
+func calculationRequest(calc calculation, subject, encounter string, value float64, derivedFrom []models.Reference, effective *time.Time) *models.ObservationCreateRequest {
+	system, code, display := loincSystem, calc.code, calc.display
+	categorySystem, category, categoryName := observationCategorySystem, "vital-signs", "Vital Signs"
+	unitSystem, unit := ucum.System, calc.unit
+
+	return &models.ObservationCreateRequest{
+		Status: "final",
+		Category: []models.CodeableConcept{{
+			Coding: []models.Coding{{System: &categorySystem, Code: &category, Display: &categoryName}},
+		}},
+		Code: models.CodeableConcept{
+			Coding: []models.Coding{{System: &system, Code: &code, Display: &display}},
+			Text:   &display,
+		},
+		Subject:           models.Reference{Reference: &subject},
+		Encounter:         &models.Reference{Reference: &encounter},
+		EffectiveDateTime: effective,
+		ValueQuantity:     &models.Quantity{Value: &value, Unit: &unit, System: &unitSystem, Code: &unit},
+		DerivedFrom:       derivedFrom,
+	}
+}
+
+// sameReferences reports whether two lists of references refer to the same
+// resources in the same order
+func sameReferences(a, b []models.Reference) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if (a[i].Reference == nil) != (b[i].Reference == nil) ||
+			(a[i].Reference != nil && *a[i].Reference != *b[i].Reference) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// reportIDNamespace is the UUID namespace of the ids CreateReportWithResults
+// derives from its keys
+var reportIDNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("urn:healthcare-api:diagnostic-report"))
+
+// DiagnosticReportService stores diagnostic reports together with the result
+// observations they group. Reports are created by result feeds such as HL7 v2
+// ORU messages; the API only reads them.
+type DiagnosticReportService struct {
+	repo           repository.DiagnosticReportStore
+	observations   *ObservationService
+	imagingStudies *ImagingStudyService
+	outbox         JobOutbox
+	logger         *logrus.Logger
+}
+
+// NewDiagnosticReportService creates a diagnostic report service storing result
+// observations through observations
+func NewDiagnosticReportService(repo repository.DiagnosticReportStore, observations *ObservationService, logger *logrus.Logger) *DiagnosticReportService {
+	return &DiagnosticReportService{
+		repo:         repo,
+		observations: observations,
+		logger:       logger,
+	}
+}
+
+// SetOutbox makes the service record background jobs for every stored report
+func (s *DiagnosticReportService) SetOutbox(outbox JobOutbox) {
+	s.outbox = outbox
+}
+
+// SetImagingStudies makes the service link created reports to the imaging
+// studies of their subject whose accession number is one of their identifiers
+func (s *DiagnosticReportService) SetImagingStudies(imagingStudies *ImagingStudyService) {
+	s.imagingStudies = imagingStudies
+}
+
+// CreateReportWithResults stores the observations in results and a report whose
+// result references them, returning the report and whether it was created. The
+// ids are derived from the tenant and key, so calling again with the same key,
+// as a retried job does after a partial failure, creates only what is missing
+// and returns the report stored the first time.
+func (s *DiagnosticReportService) CreateReportWithResults(ctx context.Context, key string, req *models.DiagnosticReportCreateRequest, results []*models.ObservationCreateRequest) (*models.DiagnosticReport, bool, error) {
+	reportID := uuid.NewSHA1(reportIDNamespace, []byte(requestctx.TenantID(ctx)+"\x00"+key))
+	logger := s.logger.WithContext(ctx).WithField("diagnostic_report_id", reportID)
+
+	existing, err := s.repo.GetByID(ctx, reportID)
+	if err == nil {
+		logger.Info("Diagnostic report already stored")
+		return existing, false, nil
+	}
+	if !errors.Is(err, models.ErrDiagnosticReportNotFound) {
+		return nil, false, fmt.Errorf("failed to check for diagnostic report: %w", err)
+	}
+
+	references := make([]models.Reference, 0, len(req.Result)+len(results))
+	references = append(references, req.Result...)
+	for i, result := range results {
+		observationID := uuid.NewSHA1(reportID, []byte(strconv.Itoa(i)))
+		_, err := s.observations.repo.GetByID(ctx, observationID)
+		switch {
+		case errors.Is(err, models.ErrObservationNotFound):
+			if _, err := s.observations.createObservation(ctx, observationID, result); err != nil {
+				return nil, false, err
+			}
+		case err != nil && !errors.Is(err, models.ErrResourceDeleted):
+			return nil, false, fmt.Errorf("failed to check for result observation: %w", err)
+		}
+		reference := "Observation/" + observationID.String()
+		references = append(references, models.Reference{Reference: &reference})
+	}
+
+	now := time.Now().UTC()
+	report := &models.DiagnosticReport{
+		Resource: models.Resource{
+			ID:        reportID,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Version:   1,
+		},
+		Identifier:         req.Identifier,
+		BasedOn:            req.BasedOn,
+		Status:             req.Status,
+		Category:           req.Category,
+		Code:               req.Code,
+		Subject:            req.Subject,
+		Encounter:          req.Encounter,
+		EffectiveDateTime:  req.EffectiveDateTime,
+		EffectivePeriod:    req.EffectivePeriod,
+		Issued:             req.Issued,
+		Performer:          req.Performer,
+		ResultsInterpreter: req.ResultsInterpreter,
+		Specimen:           req.Specimen,
+		Result:             references,
+		Conclusion:         req.Conclusion,
+		ConclusionCode:     req.ConclusionCode,
+	}
+	if err := s.findImagingStudies(ctx, report); err != nil {
+		return nil, false, err
+	}
+
+	if err := s.repo.Create(ctx, report); err != nil {
+		logger.WithError(err).Error("Failed to create diagnostic report")
+		return nil, false, fmt.Errorf("failed to create diagnostic report: %w", err)
+	}
+
+	emitResourceJobs(ctx, s.outbox, s.logger, "DiagnosticReport", report.ID, ActionCreate)
+	logger.WithField("results", len(results)).Info("Diagnostic report created successfully")
+	return report, true, nil
+}
+
+// findImagingStudies sets the imaging studies of a new report: those of its
+// subject whose accession number is the value of one of its identifiers
+func (s *DiagnosticReportService) findImagingStudies(ctx context.Context, report *models.DiagnosticReport) error {
+	if s.imagingStudies == nil || report.Subject.Reference == nil {
+		return nil
+	}
+	for _, identifier := range report.Identifier {
+		if identifier.Value == nil || *identifier.Value == "" {
+			continue
+		}
+		studies, err := s.imagingStudies.FindStudies(ctx, *report.Subject.Reference, *identifier.Value)
+		if err != nil {
+			return err
+		}
+		for _, study := range studies {
+			report.ImagingStudy = withReference(report.ImagingStudy, "ImagingStudy/"+study.ID.String())
+		}
+	}
+	return nil
+}
+
+// LinkImagingStudy adds the study to the imaging studies of the reports of its
+// subject with its accession number as an identifier, for studies received
+// after their reports
+func (s *DiagnosticReportService) LinkImagingStudy(ctx context.Context, study *models.ImagingStudy) error {
+	accession := repository.ExtractImagingStudySearchColumns(study).AccessionNumber
+	if accession == nil || study.Subject.Reference == nil {
+		return nil
+	}
+
+	search := repository.DiagnosticReportSearchParams{Subject: *study.Subject.Reference, Identifier: *accession}
+	reports, _, err := s.repo.List(ctx, search, repository.ValidatePaginationParams(100, 0))
+	if err != nil {
+		return fmt.Errorf("failed to find reports of imaging study: %w", err)
+	}
+
+	reference := "ImagingStudy/" + study.ID.String()
+	for _, report := range reports {
+		linked := withReference(report.ImagingStudy, reference)
+		if len(linked) == len(report.ImagingStudy) {
+			continue
+		}
+		report.ImagingStudy = linked
+		if err := s.repo.Update(ctx, report); err != nil {
+			return fmt.Errorf("failed to link report to imaging study: %w", err)
+		}
+		emitResourceJobs(ctx, s.outbox, s.logger, "DiagnosticReport", report.ID, ActionUpdate)
+		s.logger.WithContext(ctx).WithFields(logrus.Fields{
+			"diagnostic_report_id": report.ID,
+			"imaging_study_id":     study.ID,
+		}).Info("Diagnostic report linked to imaging study")
+	}
+	return nil
+}
+
+// withReference appends a reference to references unless they hold it already
+func withReference(references []models.Reference, reference string) []models.Reference {
+	for _, existing := range references {
+		if existing.Reference != nil && *existing.Reference == reference {
+			return references
+		}
+	}
+	return append(references, models.Reference{Reference: &reference})
+}
+
+func (s *DiagnosticReportService) GetDiagnosticReport(ctx context.Context, id uuid.UUID) (*models.DiagnosticReport, error) {
+	s.logger.WithContext(ctx).WithField("diagnostic_report_id", id).Info("Retrieving diagnostic report")
+
+	report, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("diagnostic_report_id", id).Error("Failed to retrieve diagnostic report")
+		return nil, fmt.Errorf("failed to retrieve diagnostic report: %w", err)
+	}
+
+	return report, nil
+}
+
+func (s *DiagnosticReportService) ListDiagnosticReports(ctx context.Context, search repository.DiagnosticReportSearchParams, limit, offset int) (*models.DiagnosticReportListResponse, error) {
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"limit":  limit,
+		"offset": offset,
+	}).Info("Listing diagnostic reports")
+
+	params := repository.ValidatePaginationParams(limit, offset)
+
+	reports, pagination, err := s.repo.List(ctx, search, params)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list diagnostic reports")
+		return nil, fmt.Errorf("failed to list diagnostic reports: %w", err)
+	}
+
+	entries := make([]models.DiagnosticReportEntry, len(reports))
+	for i, report := range reports {
+		entries[i] = models.DiagnosticReportEntry{
+			FullURL:  fmt.Sprintf("/api/v1/diagnostic-reports/%s", report.ID),
+			Resource: report,
+			Search: &models.SearchEntry{
+				Mode: "match",
+			},
+		}
+	}
+
+	response := &models.DiagnosticReportListResponse{
+		ResourceType: "Bundle",
+		ID:           uuid.New().String(),
+		Type:         "searchset",
+		Total:        pagination.Total,
+		Entry:        entries,
+	}
+
+	// Carry search filters through to pagination links
+	query := url.Values{}
+	if search.Subject != "" {
+		query.Set("subject", search.Subject)
+	}
+	if search.Code != "" {
+		query.Set("code", search.Code)
+	}
+	if search.Identifier != "" {
+		query.Set("identifier", search.Identifier)
+	}
+	filters := ""
+	if len(query) > 0 {
+		filters = "&" + query.Encode()
+	}
+
+	if pagination.HasNext {
+		response.Link = append(response.Link, models.BundleLink{
+			Relation: "next",
+			URL:      fmt.Sprintf("/api/v1/diagnostic-reports?limit=%d&offset=%d%s", params.Limit, params.Offset+params.Limit, filters),
+		})
+	}
+
+	if params.Offset > 0 {
+		prevOffset := params.Offset - params.Limit
+		if prevOffset < 0 {
+			prevOffset = 0
+		}
+		response.Link = append(response.Link, models.BundleLink{
+			Relation: "prev",
+			URL:      fmt.Sprintf("/api/v1/diagnostic-reports?limit=%d&offset=%d%s", params.Limit, prevOffset, filters),
+		})
+	}
+
+	s.logger.WithContext(ctx).WithField("total", pagination.Total).Info("Diagnostic reports listed successfully")
+	return response, nil
+}
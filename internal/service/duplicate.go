@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/dedup"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// duplicateScanBatch is how many patients a scan pairs with the rest at a time
+const duplicateScanBatch = 500
+
+// DuplicateService finds probable duplicate patients and records registrars'
+// review of them. Candidate pairs are chosen by the database, among patients
+// sharing an identifier value, or a birth date or family name with similar
+// demographics, and scored by package dedup.
+type DuplicateService struct {
+	repo     *repository.DuplicateRepository
+	minScore float64
+	logger   *logrus.Logger
+}
+
+// NewDuplicateService creates the service, recording pairs scoring at least
+// cfg.MinScore
+func NewDuplicateService(repo *repository.DuplicateRepository, cfg config.DuplicateConfig, logger *logrus.Logger) (*DuplicateService, error) {
+	if cfg.MinScore <= 0 || cfg.MinScore > 1 {
+		return nil, fmt.Errorf("invalid duplicate minimum score %g: must be above 0 and at most 1", cfg.MinScore)
+	}
+	return &DuplicateService{
+		repo:     repo,
+		minScore: cfg.MinScore,
+		logger:   logger,
+	}, nil
+}
+
+// Detect scans the live patients of every tenant for probable duplicates,
+// recording the pairs scoring at least the minimum score. Pairs found before
+// keep their review; pending pairs no longer found are removed once the scan
+// completes. It returns the patients scanned, the pairs found and how many of
+// them are new.
+func (s *DuplicateService) Detect(ctx context.Context) (scanned, found, created int, err error) {
+	started := time.Now().UTC()
+	after := uuid.Nil
+	for {
+		ids, err := s.repo.PatientBatch(ctx, after, duplicateScanBatch)
+		if err != nil {
+			return scanned, found, created, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		after = ids[len(ids)-1]
+		scanned += len(ids)
+
+		pairs, err := s.repo.CandidatePairs(ctx, ids)
+		if err != nil {
+			return scanned, found, created, err
+		}
+		for _, pair := range pairs {
+			match := dedup.Compare(pair.Patient, pair.Other)
+			if match.Score < s.minScore {
+				continue
+			}
+
+			duplicate := &models.PatientDuplicate{
+				PatientID:   pair.Patient.ID,
+				DuplicateID: pair.Other.ID,
+				Score:       match.Score,
+				Reasons:     match.Reasons,
+				DetectedAt:  started,
+			}
+			isNew, err := s.repo.Record(requestctx.WithTenantID(ctx, pair.TenantID), duplicate)
+			if err != nil {
+				return scanned, found, created, err
+			}
+			found++
+			if isNew {
+				created++
+			}
+		}
+	}
+
+	removed, err := s.repo.RemoveStale(ctx, started)
+	if err != nil {
+		return scanned, found, created, err
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"scanned": scanned,
+		"found":   found,
+		"created": created,
+		"removed": removed,
+	}).Info("Duplicate patient scan completed")
+	return scanned, found, created, nil
+}
+
+// GetDuplicate returns a candidate pair of the tenant
+func (s *DuplicateService) GetDuplicate(ctx context.Context, id uuid.UUID) (*models.PatientDuplicate, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// ListDuplicates returns the tenant's candidate pairs, highest score first
+func (s *DuplicateService) ListDuplicates(ctx context.Context, filter repository.DuplicateFilter, limit, offset int) ([]*models.PatientDuplicate, repository.PaginationResult, error) {
+	return s.repo.List(ctx, filter, repository.ValidatePaginationParams(limit, offset))
+}
+
+// ReviewDuplicate records the caller's decision on a candidate pair, confirmed
+// or dismissed, replacing any earlier one. A dismissed pair stays dismissed
+// when later scans find it again.
+func (s *DuplicateService) ReviewDuplicate(ctx context.Context, id uuid.UUID, status string, note *string) (*models.PatientDuplicate, error) {
+	duplicate, err := s.repo.Review(ctx, id, status, requestctx.UserID(ctx), note)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"duplicate_id": id,
+		"status":       status,
+	}).Info("Patient duplicate reviewed")
+	return duplicate, nil
+}
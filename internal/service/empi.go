@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/empi"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// EMPISyncJobType is the job looking a changed patient up in the EMPI. It
+// receives every resource change event and ignores those of other resources.
+const EMPISyncJobType = "empi_sync"
+
+// empiReconciliationBatch bounds the patients one reconciliation run looks up again
+const empiReconciliationBatch = 100
+
+// EMPIService keeps the enterprise identifier of patients in step with the
+// enterprise master patient index. The EMPI is the authority: an identifier
+// is stored on a patient without one, and replaces a different one once a
+// reconciliation run finds the EMPI still disagrees.
+type EMPIService struct {
+	repo     *repository.EMPIRepository
+	patients *PatientService
+	client   empi.Client
+	system   string
+	logger   *logrus.Logger
+}
+
+// NewEMPIService creates the service; without a client, lookups are disabled
+func NewEMPIService(repo *repository.EMPIRepository, patients *PatientService, client empi.Client, cfg config.EMPIConfig, logger *logrus.Logger) *EMPIService {
+	return &EMPIService{
+		repo:     repo,
+		patients: patients,
+		client:   client,
+		system:   cfg.EnterpriseSystem,
+		logger:   logger,
+	}
+}
+
+// Enabled reports whether an EMPI is configured
+func (s *EMPIService) Enabled() bool {
+	return s.client != nil
+}
+
+// HandleEvent looks up the patient of a Patient create, update or restore
+// event, unless it was looked up since; other events are ignored
+func (s *EMPIService) HandleEvent(ctx context.Context, event models.ResourceEventPayload) error {
+	if !s.Enabled() || event.ResourceType != "Patient" {
+		return nil
+	}
+	switch event.Action {
+	case ActionCreate, ActionUpdate, ActionRestore:
+	default:
+		return nil
+	}
+
+	id, err := uuid.Parse(event.ResourceID)
+	if err != nil {
+		return fmt.Errorf("invalid patient id %q: %w", event.ResourceID, err)
+	}
+
+	// Storing an enterprise identifier is itself an update, whose event comes
+	// before the check recorded after it and is skipped here
+	link, err := s.repo.Get(ctx, id)
+	if err != nil && !errors.Is(err, models.ErrEMPILinkNotFound) {
+		return err
+	}
+	if link != nil && link.CheckedAt.After(event.Timestamp) {
+		return nil
+	}
+
+	_, err = s.sync(ctx, id, nil)
+	return err
+}
+
+// Sync looks a patient up in the EMPI now and returns the outcome, also
+// recorded as its link. A failed lookup returns the error along with the link.
+func (s *EMPIService) Sync(ctx context.Context, id uuid.UUID) (*models.EMPILink, error) {
+	return s.sync(ctx, id, nil)
+}
+
+// sync looks a patient up and records the outcome. A mismatch confirmed by
+// the same candidate as previous replaces the patient's enterprise identifier.
+func (s *EMPIService) sync(ctx context.Context, id uuid.UUID, previous *models.EMPILink) (*models.EMPILink, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("%w: no EMPI is configured", models.ErrUnsupported)
+	}
+
+	patient, err := s.patients.GetPatient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	current := empi.EnterpriseID(patient, s.system)
+	link := &models.EMPILink{PatientID: id, EnterpriseID: optional(current)}
+	logger := s.logger.WithContext(ctx).WithField("patient_id", id)
+
+	found, err := s.client.Lookup(ctx, patient)
+	if err != nil {
+		link.Status = models.EMPIError
+		link.LastError = optional(err.Error())
+		link.CheckedAt = time.Now().UTC()
+		if saveErr := s.repo.Save(ctx, link); saveErr != nil {
+			logger.WithError(saveErr).Error("Failed to record EMPI lookup failure")
+		}
+		return link, fmt.Errorf("failed to look patient up in EMPI: %w", err)
+	}
+
+	confirmed := previous != nil && previous.Status == models.EMPIMismatch &&
+		previous.CandidateID != nil && *previous.CandidateID == found
+	switch {
+	case found == "":
+		link.Status = models.EMPIUnmatched
+	case found == current:
+		link.Status = models.EMPILinked
+	case current == "" || confirmed:
+		_, err := s.patients.ReplaceIdentifier(ctx, id, s.system, found)
+		var conflict *models.IdentifierConflictError
+		switch {
+		case errors.As(err, &conflict):
+			link.Status = models.EMPIConflict
+			link.CandidateID = &found
+			link.ConflictingPatientID = &conflict.ResourceID
+		case err != nil:
+			return nil, err
+		default:
+			link.Status = models.EMPILinked
+			link.EnterpriseID = &found
+			if current != "" {
+				logger.WithFields(logrus.Fields{
+					"previous_id":   current,
+					"enterprise_id": found,
+				}).Warn("Enterprise identifier replaced with the EMPI's")
+			}
+		}
+	default:
+		link.Status = models.EMPIMismatch
+		link.CandidateID = &found
+	}
+
+	// Checked after any identifier update, so its event is skipped
+	link.CheckedAt = time.Now().UTC()
+	if err := s.repo.Save(ctx, link); err != nil {
+		return nil, err
+	}
+	if link.Status == models.EMPIMismatch || link.Status == models.EMPIConflict {
+		logger.WithFields(logrus.Fields{
+			"status":       link.Status,
+			"candidate_id": found,
+		}).Warn("EMPI disagrees on patient's enterprise identifier")
+	}
+	return link, nil
+}
+
+// Reconcile looks up again, in every tenant, the patients whose last lookup
+// did not link them. It stops early when the EMPI cannot be reached, leaving
+// the rest for the next run.
+func (s *EMPIService) Reconcile(ctx context.Context) (checked, linked int, err error) {
+	if !s.Enabled() {
+		return 0, 0, nil
+	}
+
+	links, err := s.repo.DueReconciliation(ctx, time.Now().UTC(), empiReconciliationBatch)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list EMPI links to reconcile: %w", err)
+	}
+	for _, previous := range links {
+		tenantCtx := requestctx.WithTenantID(ctx, previous.TenantID)
+		link, err := s.sync(tenantCtx, previous.PatientID, previous)
+		if errors.Is(err, models.ErrPatientNotFound) {
+			continue
+		}
+		if err != nil && !empi.Permanent(err) {
+			return checked, linked, err
+		}
+		checked++
+		if link != nil && link.Status == models.EMPILinked {
+			linked++
+		}
+	}
+
+	if checked > 0 {
+		s.logger.WithContext(ctx).WithFields(logrus.Fields{
+			"checked": checked,
+			"linked":  linked,
+		}).Info("EMPI links reconciled")
+	}
+	return checked, linked, nil
+}
+
+// GetLink returns the outcome of a patient's last lookup
+func (s *EMPIService) GetLink(ctx context.Context, patientID uuid.UUID) (*models.EMPILink, error) {
+	return s.repo.Get(ctx, patientID)
+}
+
+// ListLinks returns the tenant's links, of one status if status is set
+func (s *EMPIService) ListLinks(ctx context.Context, status string, limit, offset int) ([]*models.EMPILink, repository.PaginationResult, error) {
+	return s.repo.List(ctx, status, repository.ValidatePaginationParams(limit, offset))
+}
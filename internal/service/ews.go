@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/ews"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// EWSWindow is how recent the vital signs an early warning score is computed
+// from must be
+const EWSWindow = 24 * time.Hour
+
+// EWSCodeSystem is the code system of recorded early warning scores and their
+// components, whose codes are the scoring systems and parameters of package ews
+const EWSCodeSystem = "urn:healthcare-api:early-warning-score"
+
+const (
+	loincSystem               = "http://loinc.org"
+	observationCategorySystem = "http://terminology.hl7.org/CodeSystem/observation-category"
+)
+
+// ewsSystemNames are the display names of the scoring systems
+var ewsSystemNames = map[string]string{
+	ews.NEWS2: "National Early Warning Score 2",
+	ews.MEWS:  "Modified Early Warning Score",
+}
+
+// ewsVitalSign is a vital sign and the LOINC codes of the observations it is
+// read from
+type ewsVitalSign struct {
+	parameter string
+	codes     []string
+	read      func(*models.Observation) (*ewsReading, bool)
+}
+
+// ewsReading is the value of a vital sign read from an observation
+type ewsReading struct {
+	value       *float64
+	unit        string
+	text        string
+	observation *models.Observation
+	effective   *time.Time
+	assumed     bool
+}
+
+// ewsVitalSigns are the vital signs scored, in the order they are reported.
+// Systolic pressure is also read from the component of a blood pressure panel.
+var ewsVitalSigns = []ewsVitalSign{
+	{ews.RespiratoryRate, []string{"9279-1"}, readQuantity([]string{"9279-1"}, "/min")},
+	{ews.OxygenSaturation, []string{"59408-5", "2708-6"}, readQuantity([]string{"59408-5", "2708-6"}, "%")},
+	{ews.SupplementalOxygen, []string{"3151-8", "3150-0"}, readSupplementalOxygen},
+	{ews.SystolicBP, []string{"8480-6", "85354-9", "55284-4"}, readQuantity([]string{"8480-6"}, "mm[Hg]")},
+	{ews.PulseRate, []string{"8867-4"}, readQuantity([]string{"8867-4"}, "/min")},
+	{ews.Consciousness, []string{"67775-7"}, readConsciousness},
+	{ews.Temperature, []string{"8310-5", "8331-1"}, readQuantity([]string{"8310-5", "8331-1"}, "Cel")},
+}
+
+// EWSService computes early warning scores from the vital signs recorded for
+// a patient, and records them as observations derived from those vital signs
+type EWSService struct {
+	observations       repository.ObservationStore
+	patients           *PatientService
+	observationService *ObservationService
+	logger             *logrus.Logger
+}
+
+// NewEWSService creates an early warning score service reading vital signs
+// from observations and recording scores through observationService
+func NewEWSService(observations repository.ObservationStore, patients *PatientService, observationService *ObservationService, logger *logrus.Logger) *EWSService {
+	return &EWSService{
+		observations:       observations,
+		patients:           patients,
+		observationService: observationService,
+		logger:             logger,
+	}
+}
+
+// Compute computes the patient's scores on systems from the latest of each
+// vital sign effective in the last EWSWindow. A vital sign without such an
+// observation is missing from the scores, except supplemental oxygen, which is
+// assumed to be air as on the NEWS2 chart.
+func (s *EWSService) Compute(ctx context.Context, patientID uuid.UUID, systems []string) (*models.EarlyWarningScores, error) {
+	if _, err := s.patients.GetPatient(ctx, patientID); err != nil {
+		return nil, err
+	}
+
+	subject := "Patient/" + patientID.String()
+	now := time.Now().UTC()
+	readings, err := s.latestVitalSigns(ctx, subject, now.Add(-EWSWindow))
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to read vital signs")
+		return nil, fmt.Errorf("failed to read vital signs: %w", err)
+	}
+
+	vitals := ews.Vitals{}
+	if r := readings[ews.RespiratoryRate]; r != nil {
+		vitals.RespiratoryRate = r.value
+	}
+	if r := readings[ews.OxygenSaturation]; r != nil {
+		vitals.OxygenSaturation = r.value
+	}
+	if r := readings[ews.SupplementalOxygen]; r != nil {
+		onOxygen := r.text == "oxygen"
+		vitals.OnOxygen = &onOxygen
+	}
+	if r := readings[ews.SystolicBP]; r != nil {
+		vitals.SystolicBP = r.value
+	}
+	if r := readings[ews.PulseRate]; r != nil {
+		vitals.PulseRate = r.value
+	}
+	if r := readings[ews.Consciousness]; r != nil {
+		vitals.Consciousness = r.text
+	}
+	if r := readings[ews.Temperature]; r != nil {
+		vitals.Temperature = r.value
+	}
+
+	scores := &models.EarlyWarningScores{
+		Subject:    subject,
+		ComputedAt: now,
+		Scores:     make([]models.EarlyWarningScore, 0, len(systems)),
+	}
+	for _, system := range systems {
+		score := ews.Compute(system, vitals)
+		result := models.EarlyWarningScore{
+			System:     system,
+			Total:      score.Total,
+			Risk:       score.Risk,
+			Complete:   score.Complete(),
+			Parameters: []models.EWSParameter{},
+			Missing:    score.Missing,
+		}
+		for _, sign := range ewsVitalSigns {
+			points, ok := score.Points[sign.parameter]
+			if !ok {
+				continue
+			}
+			reading := readings[sign.parameter]
+			parameter := models.EWSParameter{
+				Name:      sign.parameter,
+				Value:     reading.value,
+				Unit:      reading.unit,
+				Text:      reading.text,
+				Score:     points,
+				Effective: reading.effective,
+				Assumed:   reading.assumed,
+			}
+			if reading.observation != nil {
+				reference := "Observation/" + reading.observation.ID.String()
+				parameter.Observation = &models.Reference{Reference: &reference}
+			}
+			result.Parameters = append(result.Parameters, parameter)
+		}
+		scores.Scores = append(scores.Scores, result)
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"patient_id":  patientID,
+		"vital_signs": len(readings),
+	}).Info("Early warning scores computed")
+	return scores, nil
+}
+
+// Record computes the patient's scores like Compute and records each complete
+// one as an observation derived from the vital signs it was computed from,
+// effective when the latest of them was. Incomplete scores are returned
+// without being recorded.
+func (s *EWSService) Record(ctx context.Context, patientID uuid.UUID, systems []string) (*models.EarlyWarningScores, error) {
+	scores, err := s.Compute(ctx, patientID, systems)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range scores.Scores {
+		score := &scores.Scores[i]
+		if !score.Complete {
+			continue
+		}
+		observation, err := s.observationService.CreateObservation(ctx, scoreObservation(scores.Subject, score))
+		if err != nil {
+			return nil, fmt.Errorf("failed to record %s score: %w", score.System, err)
+		}
+		reference := "Observation/" + observation.ID.String()
+		score.Observation = &models.Reference{Reference: &reference}
+	}
+	return scores, nil
+}
+
+// scoreObservation returns the request creating the observation of a score
+func scoreObservation(subject string, score *models.EarlyWarningScore) *models.ObservationCreateRequest {
+	system, name := EWSCodeSystem, ewsSystemNames[score.System]
+	code := score.System
+	categorySystem, category, categoryName := observationCategorySystem, "survey", "Survey"
+	risk := score.Risk + " clinical risk"
+	total := score.Total
+
+	req := &models.ObservationCreateRequest{
+		Status: "final",
+		Category: []models.CodeableConcept{{
+			Coding: []models.Coding{{System: &categorySystem, Code: &category, Display: &categoryName}},
+		}},
+		Code: models.CodeableConcept{
+			Coding: []models.Coding{{System: &system, Code: &code, Display: &name}},
+			Text:   &name,
+		},
+		Subject:        models.Reference{Reference: &subject},
+		ValueInteger:   &total,
+		Interpretation: []models.CodeableConcept{{Text: &risk}},
+	}
+	seen := map[string]bool{}
+	for _, parameter := range score.Parameters {
+		parameterCode, points := parameter.Name, parameter.Score
+		req.Component = append(req.Component, models.ObservationComponent{
+			Code:         models.CodeableConcept{Coding: []models.Coding{{System: &system, Code: &parameterCode}}},
+			ValueInteger: &points,
+		})
+		if parameter.Observation == nil || seen[*parameter.Observation.Reference] {
+			continue
+		}
+		seen[*parameter.Observation.Reference] = true
+		req.DerivedFrom = append(req.DerivedFrom, *parameter.Observation)
+		if req.EffectiveDateTime == nil || parameter.Effective.After(*req.EffectiveDateTime) {
+			req.EffectiveDateTime = parameter.Effective
+		}
+	}
+	return req
+}
+
+// latestVitalSigns reads the latest of each vital sign of subject effective
+// since since, keyed by parameter
+func (s *EWSService) latestVitalSigns(ctx context.Context, subject string, since time.Time) (map[string]*ewsReading, error) {
+	readings := map[string]*ewsReading{}
+	for _, sign := range ewsVitalSigns {
+		codes := make([]string, len(sign.codes))
+		for i, code := range sign.codes {
+			codes[i] = loincSystem + "|" + code
+		}
+		observation, err := s.observations.Latest(ctx, subject, codes, since)
+		if errors.Is(err, models.ErrObservationNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		reading, ok := sign.read(observation)
+		if !ok {
+			s.logger.WithContext(ctx).WithFields(logrus.Fields{
+				"observation_id": observation.ID,
+				"parameter":      sign.parameter,
+			}).Warn("Latest vital sign has no value that can be scored")
+			continue
+		}
+		reading.observation = observation
+		reading.effective = repository.ExtractObservationSearchColumns(observation).EffectiveDate
+		readings[sign.parameter] = reading
+	}
+
+	if readings[ews.SupplementalOxygen] == nil {
+		readings[ews.SupplementalOxygen] = &ewsReading{text: "air", assumed: true}
+	}
+	return readings, nil
+}
+
+// readQuantity reads the exact quantity of an observation, or of its
+// component, coded with one of codes, converted to unit
+func readQuantity(codes []string, unit string) func(*models.Observation) (*ewsReading, bool) {
+	return func(observation *models.Observation) (*ewsReading, bool) {
+		quantity := codedQuantity(observation, codes)
+		if quantity == nil || quantity.Value == nil || quantity.Comparator != nil {
+			return nil, false
+		}
+		value, err := valueIn(quantity, unit)
+		if err != nil {
+			return nil, false
+		}
+		return &ewsReading{value: &value, unit: unit}, true
+	}
+}
+
+// codedQuantity returns the quantity of an observation, or of its first
+// component, coded with one of the LOINC codes
+func codedQuantity(observation *models.Observation, codes []string) *models.Quantity {
+	for _, code := range codes {
+		if hasCoding(observation.Code, loincSystem, code) {
+			return observation.ValueQuantity
+		}
+	}
+	for _, component := range observation.Component {
+		for _, code := range codes {
+			if hasCoding(component.Code, loincSystem, code) {
+				return component.ValueQuantity
+			}
+		}
+	}
+	return nil
+}
+
+// readSupplementalOxygen reads whether oxygen is given from an inhaled oxygen
+// flow rate, given when above 0 L/min, or concentration, given when above the
+// 21% of air
+func readSupplementalOxygen(observation *models.Observation) (*ewsReading, bool) {
+	unit, air := "L/min", 0.0
+	if hasCoding(observation.Code, loincSystem, "3150-0") {
+		unit, air = "%", 21
+	}
+	reading, ok := readQuantity([]string{"3151-8", "3150-0"}, unit)(observation)
+	if !ok {
+		return nil, false
+	}
+	reading.text = "air"
+	if *reading.value > air {
+		reading.text = "oxygen"
+	}
+	return reading, true
+}
+
+// acvpuLevels maps the codes, letters and names of the levels of consciousness
+// on the ACVPU and AVPU scales to ACVPU levels
+var acvpuLevels = map[string]string{
+	"a": ews.Alert, "alert": ews.Alert,
+	"c": ews.Confusion, "confusion": ews.Confusion, "new confusion": ews.Confusion, "confused": ews.Confusion,
+	"v": ews.Voice, "voice": ews.Voice, "responds to voice": ews.Voice,
+	"p": ews.Pain, "pain": ews.Pain, "responds to pain": ews.Pain,
+	"u": ews.Unresponsive, "unresponsive": ews.Unresponsive,
+}
+
+// readConsciousness reads the level of consciousness from an observation's
+// coded or text value
+func readConsciousness(observation *models.Observation) (*ewsReading, bool) {
+	var names []string
+	if concept := observation.ValueCodeableConcept; concept != nil {
+		for _, coding := range concept.Coding {
+			if coding.Code != nil {
+				names = append(names, *coding.Code)
+			}
+			if coding.Display != nil {
+				names = append(names, *coding.Display)
+			}
+		}
+		if concept.Text != nil {
+			names = append(names, *concept.Text)
+		}
+	}
+	if observation.ValueString != nil {
+		names = append(names, *observation.ValueString)
+	}
+
+	for _, name := range names {
+		if level, ok := acvpuLevels[strings.ToLower(strings.TrimSpace(name))]; ok {
+			return &ewsReading{text: level}, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,419 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/hl7v2"
+	"healthcare-api/internal/identifier"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/validation"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ADT trigger events the HL7 service accepts
+const (
+	// ADTAdmit is an inpatient admission
+	ADTAdmit = "A01"
+	// ADTRegister is an outpatient registration
+	ADTRegister = "A04"
+	// ADTUpdate is a change to patient information
+	ADTUpdate = "A08"
+	// ADTMerge merges the patient identified in MRG into the one in PID
+	ADTMerge = "A40"
+)
+
+// ORUResult is the ORU trigger event the HL7 service accepts, unsolicited
+// observation results
+const ORUResult = "R01"
+
+// HL7ResultsJobType is the job type that stores the results of accepted ORU messages
+const HL7ResultsJobType = "hl7_results"
+
+// HL7Result is the outcome of processing an HL7 v2 message
+type HL7Result struct {
+	// Ack is the acknowledgement to return to the sender
+	Ack     []byte
+	AckCode hl7v2.AckCode
+	// Err and Condition are why the message was not accepted
+	Err       error
+	Condition hl7v2.ErrorCondition
+	// PatientID is the patient the message created, updated or has results for
+	PatientID uuid.UUID
+	Created   bool
+	// Queued is set when the message was accepted to be applied by a job
+	Queued bool
+}
+
+// HL7Service ingests HL7 v2 messages from interface engines, turning ADT events
+// into patient creates and updates and ORU results into diagnostic reports and
+// observations, recorded in the encounter of their PV1 visit. The PV1 visits of
+// ADT messages are not stored, as there is no Encounter resource to hold them.
+// Every transport, such as the HTTP endpoint, feeds messages through Process.
+type HL7Service struct {
+	patients  *PatientService
+	reports   *DiagnosticReportService
+	merges    *PatientMergeService
+	outbox    JobOutbox
+	validator *validation.Validator
+	logger    *logrus.Logger
+}
+
+// NewHL7Service creates an HL7 service writing patients through patients and
+// results through reports
+func NewHL7Service(patients *PatientService, reports *DiagnosticReportService, logger *logrus.Logger) *HL7Service {
+	return &HL7Service{
+		patients:  patients,
+		reports:   reports,
+		validator: validation.NewValidator(),
+		logger:    logger,
+	}
+}
+
+// SetIdentifiers makes the service check the identifiers of patients in ADT
+// messages, rejecting those with invalid ones as the API does
+func (s *HL7Service) SetIdentifiers(identifiers *identifier.Validator) {
+	s.validator.SetIdentifiers(identifiers)
+}
+
+// SetOutbox makes the service queue ORU results as hl7_results jobs. Without
+// an outbox, ORU messages are rejected.
+func (s *HL7Service) SetOutbox(outbox JobOutbox) {
+	s.outbox = outbox
+}
+
+// SetMerges makes the service apply A40 merges through merges, as the patient
+// $merge operation does. Without it, A40 messages are rejected.
+func (s *HL7Service) SetMerges(merges *PatientMergeService) {
+	s.merges = merges
+}
+
+// Process parses and applies an HL7 v2 message and builds its acknowledgement:
+// AA when it was applied, or queued for ORU results, AR when it is malformed or
+// unsupported and AE when it could not be applied.
+func (s *HL7Service) Process(ctx context.Context, data []byte) *HL7Result {
+	msg, err := hl7v2.Parse(data)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Warn("Rejected malformed HL7 v2 message")
+		return s.reject(nil, hl7v2.AckReject, hl7v2.ErrorSegmentSequence, err)
+	}
+
+	code, event := msg.Type()
+	logger := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"message_type": code + "^" + event,
+		"control_id":   msg.ControlID(),
+	})
+
+	var result *HL7Result
+	switch {
+	case code == "ADT" && (event == ADTAdmit || event == ADTRegister || event == ADTUpdate):
+		result, err = s.upsertPatient(ctx, msg)
+	case code == "ADT" && event == ADTMerge:
+		result, err = s.mergePatients(ctx, msg)
+	case code == "ORU" && event == ORUResult:
+		result, err = s.queueResults(ctx, msg, data)
+	case code == "ADT" || code == "ORU":
+		err = fmt.Errorf("%w %s event %q", models.ErrUnsupported, code, event)
+		logger.Warn("Rejected unsupported HL7 v2 message")
+		return s.reject(msg, hl7v2.AckReject, hl7v2.ErrorUnsupportedEventCode, err)
+	default:
+		err := fmt.Errorf("%w message type %q", models.ErrUnsupported, code)
+		logger.Warn("Rejected unsupported HL7 v2 message")
+		return s.reject(msg, hl7v2.AckReject, hl7v2.ErrorUnsupportedMessageType, err)
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to process HL7 v2 message")
+		return s.reject(msg, hl7v2.AckError, errorCondition(err), err)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"patient_id": result.PatientID,
+		"created":    result.Created,
+		"queued":     result.Queued,
+	}).Info("HL7 v2 message processed")
+	result.AckCode = hl7v2.AckAccept
+	result.Ack = hl7v2.Ack(msg, hl7v2.AckAccept, 0, "", time.Now())
+	return result
+}
+
+// upsertPatient applies A01, A04 and A08: the patient holding one of the PID-3
+// identifiers is updated, or created when there is none
+func (s *HL7Service) upsertPatient(ctx context.Context, msg *hl7v2.Message) (*HL7Result, error) {
+	req, err := s.patientFromMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.findPatient(ctx, req.Identifier)
+	if errors.Is(err, models.ErrPatientNotFound) {
+		patient, err := s.patients.CreatePatient(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return &HL7Result{PatientID: patient.ID, Created: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Senders only list the identifiers they know, so identifiers added by other
+	// systems are kept
+	update := models.PatientUpdateRequest(*req)
+	update.Identifier = mergeIdentifiers(existing.Identifier, req.Identifier)
+	patient, err := s.patients.UpdatePatient(ctx, existing.ID, &update)
+	if err != nil {
+		return nil, err
+	}
+	return &HL7Result{PatientID: patient.ID}, nil
+}
+
+// mergePatients applies A40 as a patient $merge of the patient identified by
+// MRG-1 into the surviving patient identified by PID-3: the resources referring
+// to it are moved to the survivor, and it is deactivated and linked to the
+// survivor, which links back to it. A resent A40 of patients already merged is
+// accepted again.
+func (s *HL7Service) mergePatients(ctx context.Context, msg *hl7v2.Message) (*HL7Result, error) {
+	if s.merges == nil {
+		return nil, errors.New("no merge service to merge patients with")
+	}
+
+	pid, mrg := msg.Segment("PID"), msg.Segment("MRG")
+	if pid == nil || mrg == nil {
+		return nil, missingField("A40 requires PID and MRG segments")
+	}
+
+	survivor, err := s.findPatient(ctx, hl7v2.Identifiers(pid.Repetitions(3)))
+	if err != nil {
+		return nil, fmt.Errorf("surviving patient: %w", err)
+	}
+	merged, err := s.findPatient(ctx, hl7v2.Identifiers(mrg.Repetitions(1)))
+	if err != nil {
+		return nil, fmt.Errorf("merged patient: %w", err)
+	}
+	if merged.ID == survivor.ID || replacedBy(merged, survivor.ID) {
+		return &HL7Result{PatientID: survivor.ID}, nil
+	}
+
+	if _, err := s.merges.Merge(ctx, merged.ID, survivor.ID); err != nil {
+		return nil, err
+	}
+	return &HL7Result{PatientID: survivor.ID}, nil
+}
+
+// queueResults accepts ORU^R01 results about a known patient and records an
+// hl7_results job to store them, so the acknowledgement does not wait for every
+// observation to be written. The results are converted and validated first, so
+// a message the job would fail on is rejected to the sender instead.
+func (s *HL7Service) queueResults(ctx context.Context, msg *hl7v2.Message, data []byte) (*HL7Result, error) {
+	if s.outbox == nil {
+		return nil, errors.New("no job outbox to queue results with")
+	}
+
+	pid := msg.Segment("PID")
+	if pid == nil {
+		return nil, missingField("message has no PID segment")
+	}
+	patient, err := s.findPatient(ctx, hl7v2.Identifiers(pid.Repetitions(3)))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.ordersFromMessage(msg, patient.ID); err != nil {
+		return nil, err
+	}
+
+	payload := models.HL7ResultsPayload{
+		PatientID: patient.ID.String(),
+		Message:   string(data),
+		UserID:    requestctx.UserID(ctx),
+	}
+	if err := s.outbox.Add(ctx, HL7ResultsJobType, payload); err != nil {
+		return nil, err
+	}
+	return &HL7Result{PatientID: patient.ID, Queued: true}, nil
+}
+
+// ApplyResults stores the reports and observations of an ORU^R01 message queued
+// by Process. Each OBR group is stored under a key of the sending application
+// and facility, the message control ID and its position, so a retried job, or
+// the sender resending the message, does not store it twice.
+func (s *HL7Service) ApplyResults(ctx context.Context, payload models.HL7ResultsPayload) ([]*models.DiagnosticReport, error) {
+	patientID, err := uuid.Parse(payload.PatientID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patient id %q: %w", payload.PatientID, err)
+	}
+	msg, err := hl7v2.Parse([]byte(payload.Message))
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.ordersFromMessage(msg, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	header := msg.Header()
+	source := header.Field(3).Component(1) + "|" + header.Field(4).Component(1) + "|" + msg.ControlID()
+	reports := make([]*models.DiagnosticReport, 0, len(orders))
+	for i, order := range orders {
+		key := fmt.Sprintf("hl7v2|%s|%d", source, i+1)
+		report, _, err := s.reports.CreateReportWithResults(ctx, key, order.Report, order.Results)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// ordersFromMessage converts and validates the OBR groups of msg as results
+// about the patient
+func (s *HL7Service) ordersFromMessage(msg *hl7v2.Message, patientID uuid.UUID) ([]*hl7v2.Order, error) {
+	reference := "Patient/" + patientID.String()
+	orders, err := hl7v2.OrdersFromORU(msg, models.Reference{Reference: &reference})
+	if err != nil {
+		if errors.Is(err, hl7v2.ErrInvalidValue) || errors.Is(err, models.ErrUnsupported) {
+			return nil, err
+		}
+		return nil, missingField(err.Error())
+	}
+
+	for _, order := range orders {
+		if validationErrors := s.validator.ValidateDiagnosticReportCreate(order.Report); validationErrors != nil {
+			return nil, validationErrors
+		}
+		for _, result := range order.Results {
+			if validationErrors := s.validator.ValidateObservationCreate(result); validationErrors != nil {
+				return nil, validationErrors
+			}
+		}
+	}
+	return orders, nil
+}
+
+// patientFromMessage converts and validates the PID segment of msg
+func (s *HL7Service) patientFromMessage(msg *hl7v2.Message) (*models.PatientCreateRequest, error) {
+	pid := msg.Segment("PID")
+	if pid == nil {
+		return nil, missingField("message has no PID segment")
+	}
+	req, err := hl7v2.PatientFromPID(pid)
+	if err != nil {
+		return nil, &models.ValidationErrors{Errors: []models.ValidationError{{Field: "PID", Message: err.Error()}}}
+	}
+	if len(req.Identifier) == 0 {
+		return nil, missingField("PID-3 has no patient identifier")
+	}
+	if validationErrors := s.validator.ValidatePatientCreate(req); validationErrors != nil {
+		return nil, validationErrors
+	}
+	return req, nil
+}
+
+// findPatient returns the patient holding the first of identifiers that any
+// patient holds
+func (s *HL7Service) findPatient(ctx context.Context, identifiers []models.Identifier) (*models.Patient, error) {
+	for _, identifier := range identifiers {
+		system := ""
+		if identifier.System != nil {
+			system = *identifier.System
+		}
+		patient, err := s.patients.FindPatientByIdentifier(ctx, system, *identifier.Value)
+		if errors.Is(err, models.ErrPatientNotFound) {
+			continue
+		}
+		return patient, err
+	}
+	return nil, models.ErrPatientNotFound
+}
+
+// reject builds the result of a message that was not applied
+func (s *HL7Service) reject(msg *hl7v2.Message, code hl7v2.AckCode, condition hl7v2.ErrorCondition, err error) *HL7Result {
+	text := err.Error()
+	var validationErrors *models.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		var messages []string
+		for _, validationError := range validationErrors.Errors {
+			if validationError.Field != "" {
+				messages = append(messages, validationError.Field+": "+validationError.Message)
+			} else {
+				messages = append(messages, validationError.Message)
+			}
+		}
+		text = strings.Join(messages, "; ")
+	} else if condition == hl7v2.ErrorApplicationInternal {
+		// Internal errors may describe the database; the sender only needs to retry
+		text = "Message could not be processed, retry later"
+	}
+	return &HL7Result{
+		Ack:       hl7v2.Ack(msg, code, condition, text, time.Now()),
+		AckCode:   code,
+		Err:       err,
+		Condition: condition,
+	}
+}
+
+// errorCondition classifies an error applying a message
+func errorCondition(err error) hl7v2.ErrorCondition {
+	var validationErrors *models.ValidationErrors
+	var conflict *models.IdentifierConflictError
+	switch {
+	case errors.As(err, &validationErrors):
+		return hl7v2.ErrorRequiredFieldMissing
+	case errors.Is(err, hl7v2.ErrInvalidValue), errors.Is(err, models.ErrUnsupported):
+		return hl7v2.ErrorDataType
+	case errors.Is(err, models.ErrPatientNotFound), errors.Is(err, models.ErrPatientMerged), errors.Is(err, models.ErrResourceDeleted):
+		return hl7v2.ErrorUnknownKey
+	case errors.As(err, &conflict):
+		return hl7v2.ErrorDuplicateKey
+	default:
+		return hl7v2.ErrorApplicationInternal
+	}
+}
+
+// missingField reports a required segment or field absent from a message
+func missingField(message string) error {
+	return &models.ValidationErrors{Errors: []models.ValidationError{{Message: message}}}
+}
+
+// mergeIdentifiers returns existing with the identifiers of incoming added or,
+// when they share a system and value, replaced
+func mergeIdentifiers(existing, incoming []models.Identifier) []models.Identifier {
+	key := func(identifier models.Identifier) string {
+		system := ""
+		if identifier.System != nil {
+			system = *identifier.System
+		}
+		value := ""
+		if identifier.Value != nil {
+			value = *identifier.Value
+		}
+		return system + "|" + value
+	}
+
+	replaced := make(map[string]bool, len(incoming))
+	for _, identifier := range incoming {
+		replaced[key(identifier)] = true
+	}
+	merged := make([]models.Identifier, 0, len(existing)+len(incoming))
+	for _, identifier := range existing {
+		if !replaced[key(identifier)] {
+			merged = append(merged, identifier)
+		}
+	}
+	return append(merged, incoming...)
+}
+
+// replacedBy reports whether patient is linked replaced-by the patient with id
+func replacedBy(patient *models.Patient, id uuid.UUID) bool {
+	for _, link := range patient.Link {
+		if link.Type == "replaced-by" && link.Other.Reference != nil && *link.Other.Reference == "Patient/"+id.String() {
+			return true
+		}
+	}
+	return false
+}
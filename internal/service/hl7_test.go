@@ -0,0 +1,81 @@
+package service
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"healthcare-api/internal/hl7v2"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testutil.Run(m))
+}
+
+// adtMessage builds an ADT message of event with segments after its MSH
+func adtMessage(event, controlID string, segments ...string) []byte {
+	msh := "MSH|^~\\&|ADT|HOSP|RDS|RDS|20240115130000||ADT^" + event + "|" + controlID + "|P|2.5.1"
+	return []byte(strings.Join(append([]string{msh}, segments...), "\r"))
+}
+
+func TestHL7MergeMovesResources(t *testing.T) {
+	f := testutil.NewFixtures(t)
+	ctx := f.Context()
+	logger := testutil.Logger(t)
+
+	hl7 := NewHL7Service(NewPatientService(f.Patients, logger), nil, logger)
+	hl7.SetMerges(NewPatientMergeService(f.Patients, logger))
+
+	register := func(controlID, pid string) *models.Patient {
+		t.Helper()
+		result := hl7.Process(ctx, adtMessage(ADTRegister, controlID, pid))
+		if result.AckCode != hl7v2.AckAccept {
+			t.Fatalf("A04 acknowledged %s: %v", result.AckCode, result.Err)
+		}
+		patient, err := f.Patients.GetByID(ctx, result.PatientID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return patient
+	}
+	survivor := register("ADT00001", "PID|1||MRN001^^^HOSP^MR||Doe^Jane||19800115|F")
+	merged := register("ADT00002", "PID|1||MRN002^^^HOSP^MR||Doe^Janet||19800115|F")
+	observations := f.Vitals(merged, 1)
+
+	// A resent A40 is accepted again without merging twice
+	a40 := adtMessage(ADTMerge, "ADT00003", "PID|1||MRN001^^^HOSP^MR||Doe^Jane", "MRG|MRN002^^^HOSP^MR")
+	for _, attempt := range []string{"A40", "resent A40"} {
+		result := hl7.Process(ctx, a40)
+		if result.AckCode != hl7v2.AckAccept || result.PatientID != survivor.ID {
+			t.Fatalf("%s acknowledged %s for patient %s: %v", attempt, result.AckCode, result.PatientID, result.Err)
+		}
+	}
+
+	subject := "Patient/" + survivor.ID.String()
+	for _, observation := range observations {
+		moved, err := f.Observations.GetByID(ctx, observation.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if moved.Subject.Reference == nil || *moved.Subject.Reference != subject {
+			t.Errorf("observation %s is about %v, want %s", moved.ID, moved.Subject.Reference, subject)
+		}
+	}
+
+	retired, err := f.Patients.GetByID(ctx, merged.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retired.Active == nil || *retired.Active || !replacedBy(retired, survivor.ID) {
+		t.Errorf("merged patient has active %v and links %+v, want inactive and replaced-by %s", retired.Active, retired.Link, subject)
+	}
+	kept, err := f.Patients.GetByID(ctx, survivor.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept.Link) != 1 || kept.Link[0].Type != "replaces" {
+		t.Errorf("surviving patient has links %+v, want one replaces link", kept.Link)
+	}
+}
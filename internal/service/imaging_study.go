@@ -0,0 +1,404 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/dicom"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// imagingStudyIDNamespace is the UUID namespace of the ids Ingest derives from
+// study instance UIDs
+var imagingStudyIDNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("urn:healthcare-api:imaging-study"))
+
+// IngestedStudy is an imaging study stored by Ingest
+type IngestedStudy struct {
+	Study *models.ImagingStudy
+	// Created is set for a study stored for the first time
+	Created bool
+}
+
+// ImagingStudyService stores the DICOM metadata of imaging studies held in a
+// PACS, and links them to the diagnostic reports interpreting them so clients
+// can retrieve the images over DICOMweb. Studies are created by the DICOM
+// ingest endpoint; the API only reads them.
+type ImagingStudyService struct {
+	repo            repository.ImagingStudyStore
+	patients        *PatientService
+	reports         *DiagnosticReportService
+	outbox          JobOutbox
+	dicomwebURL     string
+	patientIDSystem string
+	logger          *logrus.Logger
+}
+
+// NewImagingStudyService creates an imaging study service finding the subjects
+// of studies through patients and linking reports through reports
+func NewImagingStudyService(repo repository.ImagingStudyStore, patients *PatientService, reports *DiagnosticReportService, cfg config.ImagingConfig, logger *logrus.Logger) *ImagingStudyService {
+	return &ImagingStudyService{
+		repo:            repo,
+		patients:        patients,
+		reports:         reports,
+		dicomwebURL:     cfg.DICOMwebURL,
+		patientIDSystem: cfg.PatientIDSystem,
+		logger:          logger,
+	}
+}
+
+// SetOutbox makes the service record background jobs for every stored study
+func (s *ImagingStudyService) SetOutbox(outbox JobOutbox) {
+	s.outbox = outbox
+}
+
+// Ingest stores the studies the DICOM JSON datasets describe, creating each
+// study or adding what it lacks, such as series and instances sent after the
+// first, and links each to the reports of its accession number. The studies
+// belong to the patient patientID, or when it is uuid.Nil to the patient whose
+// identifier in the configured system is the study's DICOM Patient ID. Every
+// study's patient is found before any is stored.
+func (s *ImagingStudyService) Ingest(ctx context.Context, datasets []dicom.Dataset, patientID uuid.UUID) ([]IngestedStudy, error) {
+	studies, err := dicom.Studies(datasets)
+	if err != nil {
+		return nil, err
+	}
+	if len(studies) == 0 {
+		return nil, fmt.Errorf("%w: no datasets", dicom.ErrInvalidMetadata)
+	}
+
+	subjects := make([]models.Reference, len(studies))
+	for i, study := range studies {
+		if subjects[i], err = s.subject(ctx, study, patientID); err != nil {
+			return nil, err
+		}
+	}
+
+	ingested := make([]IngestedStudy, len(studies))
+	for i, study := range studies {
+		stored, created, err := s.store(ctx, study, subjects[i])
+		if err != nil {
+			return nil, err
+		}
+		if err := s.reports.LinkImagingStudy(ctx, stored); err != nil {
+			return nil, err
+		}
+		ingested[i] = IngestedStudy{Study: stored, Created: created}
+	}
+	return ingested, nil
+}
+
+// subject returns the reference to the patient of study. Metadata without a
+// patient ID, such as a series sent on its own, keeps the stored study's.
+func (s *ImagingStudyService) subject(ctx context.Context, study *dicom.Study, patientID uuid.UUID) (models.Reference, error) {
+	if patientID == uuid.Nil {
+		if study.PatientID == "" {
+			stored, err := s.repo.GetByID(ctx, s.studyID(ctx, study.UID))
+			if err == nil {
+				return stored.Subject, nil
+			}
+			if !errors.Is(err, models.ErrImagingStudyNotFound) {
+				return models.Reference{}, fmt.Errorf("failed to check for imaging study: %w", err)
+			}
+		}
+		if s.patientIDSystem == "" {
+			return models.Reference{}, fmt.Errorf("%w: a subject is required, as no DICOM patient ID system is configured", dicom.ErrInvalidMetadata)
+		}
+		if study.PatientID == "" {
+			return models.Reference{}, fmt.Errorf("%w: study %s has no patient ID", dicom.ErrInvalidMetadata, study.UID)
+		}
+		patient, err := s.patients.FindPatientByIdentifier(ctx, s.patientIDSystem, study.PatientID)
+		if err != nil {
+			return models.Reference{}, fmt.Errorf("failed to find patient of study %s: %w", study.UID, err)
+		}
+		patientID = patient.ID
+	} else if _, err := s.patients.GetPatient(ctx, patientID); err != nil {
+		return models.Reference{}, err
+	}
+
+	reference := "Patient/" + patientID.String()
+	return models.Reference{Reference: &reference}, nil
+}
+
+// studyID derives the id of a study from the tenant and study instance UID, so
+// a study sent again is found
+func (s *ImagingStudyService) studyID(ctx context.Context, uid string) uuid.UUID {
+	return uuid.NewSHA1(imagingStudyIDNamespace, []byte(requestctx.TenantID(ctx)+"\x00"+uid))
+}
+
+// store creates the study or merges the metadata into the stored one,
+// returning the result and whether it was created
+func (s *ImagingStudyService) store(ctx context.Context, study *dicom.Study, subject models.Reference) (*models.ImagingStudy, bool, error) {
+	id := s.studyID(ctx, study.UID)
+	logger := s.logger.WithContext(ctx).WithField("imaging_study_id", id)
+
+	existing, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, models.ErrImagingStudyNotFound) {
+		now := time.Now().UTC()
+		created := &models.ImagingStudy{
+			Resource: models.Resource{
+				ID:        id,
+				CreatedAt: now,
+				UpdatedAt: now,
+				Version:   1,
+			},
+			Status: "available",
+		}
+		s.merge(created, study, subject)
+		if err := s.repo.Create(ctx, created); err != nil {
+			logger.WithError(err).Error("Failed to create imaging study")
+			return nil, false, fmt.Errorf("failed to create imaging study: %w", err)
+		}
+		emitResourceJobs(ctx, s.outbox, s.logger, "ImagingStudy", id, ActionCreate)
+		logger.WithField("series", created.NumberOfSeries).Info("Imaging study created successfully")
+		return created, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check for imaging study: %w", err)
+	}
+
+	before, _ := json.Marshal(existing)
+	s.merge(existing, study, subject)
+	if after, _ := json.Marshal(existing); bytes.Equal(before, after) {
+		return existing, false, nil
+	}
+	if err := s.repo.Update(ctx, existing); err != nil {
+		logger.WithError(err).Error("Failed to update imaging study")
+		return nil, false, fmt.Errorf("failed to update imaging study: %w", err)
+	}
+	emitResourceJobs(ctx, s.outbox, s.logger, "ImagingStudy", id, ActionUpdate)
+	logger.WithField("series", existing.NumberOfSeries).Info("Imaging study updated successfully")
+	return existing, false, nil
+}
+
+// merge takes the metadata of study into target. Attributes the metadata
+// gives replace stored ones; series and instances are added to those stored.
+func (s *ImagingStudyService) merge(target *models.ImagingStudy, study *dicom.Study, subject models.Reference) {
+	target.Subject = subject
+	accession := ""
+	if stored := repository.ExtractImagingStudySearchColumns(target).AccessionNumber; stored != nil {
+		accession = *stored
+	}
+	if study.AccessionNumber != "" {
+		accession = study.AccessionNumber
+	}
+	target.Identifier = []models.Identifier{{
+		System: stringPtr(models.DICOMUIDSystem),
+		Value:  stringPtr("urn:oid:" + study.UID),
+	}}
+	if accession != "" {
+		target.Identifier = append(target.Identifier, models.Identifier{
+			Type: &models.CodeableConcept{Coding: []models.Coding{{
+				System: stringPtr(models.IdentifierTypeSystem),
+				Code:   stringPtr("ACSN"),
+			}}},
+			Value: stringPtr(accession),
+		})
+	}
+	if study.Description != "" {
+		target.Description = stringPtr(study.Description)
+	}
+	if study.Started != nil {
+		target.Started = study.Started
+	}
+	target.Extension = withWADORSURL(target.Extension, s.wadoURL(study.RetrieveURL, "studies", study.UID))
+
+	for _, series := range study.Series {
+		var stored *models.ImagingStudySeries
+		for i := range target.Series {
+			if target.Series[i].UID == series.UID {
+				stored = &target.Series[i]
+				break
+			}
+		}
+		if stored == nil {
+			target.Series = append(target.Series, models.ImagingStudySeries{UID: series.UID})
+			stored = &target.Series[len(target.Series)-1]
+		}
+
+		if series.Number != nil {
+			stored.Number = series.Number
+		}
+		if series.Modality != "" {
+			stored.Modality = &models.Coding{System: stringPtr(models.DICOMModalitySystem), Code: stringPtr(series.Modality)}
+		}
+		if series.Description != "" {
+			stored.Description = stringPtr(series.Description)
+		}
+		stored.Extension = withWADORSURL(stored.Extension, s.wadoURL(series.RetrieveURL, "studies", study.UID, "series", series.UID))
+
+		for _, instance := range series.Instances {
+			found := false
+			for _, existing := range stored.Instance {
+				if existing.UID == instance.UID {
+					found = true
+					break
+				}
+			}
+			if found {
+				continue
+			}
+			added := models.ImagingStudyInstance{UID: instance.UID, Number: instance.Number}
+			if instance.SOPClassUID != "" {
+				added.SOPClass = &models.Coding{System: stringPtr(models.SOPClassSystem), Code: stringPtr("urn:oid:" + instance.SOPClassUID)}
+			}
+			stored.Instance = append(stored.Instance, added)
+		}
+		stored.NumberOfInstances = len(stored.Instance)
+	}
+
+	// Counts and modalities cover every stored series
+	modalities := append([]string(nil), study.Modalities...)
+	target.NumberOfSeries = len(target.Series)
+	target.NumberOfInstances = 0
+	for _, series := range target.Series {
+		target.NumberOfInstances += series.NumberOfInstances
+		if series.Modality != nil && series.Modality.Code != nil {
+			modalities = append(modalities, *series.Modality.Code)
+		}
+	}
+	for _, coding := range target.Modality {
+		if coding.Code != nil {
+			modalities = append(modalities, *coding.Code)
+		}
+	}
+	target.Modality = nil
+	seen := map[string]bool{}
+	for _, modality := range modalities {
+		if !seen[modality] {
+			seen[modality] = true
+			target.Modality = append(target.Modality, models.Coding{System: stringPtr(models.DICOMModalitySystem), Code: stringPtr(modality)})
+		}
+	}
+}
+
+// wadoURL returns the retrieve URL the metadata gives, or one built on the
+// configured DICOMweb URL from the path segments, or "" without either
+func (s *ImagingStudyService) wadoURL(retrieveURL string, segments ...string) string {
+	if retrieveURL != "" || s.dicomwebURL == "" {
+		return retrieveURL
+	}
+	wado, err := url.JoinPath(s.dicomwebURL, segments...)
+	if err != nil {
+		return ""
+	}
+	return wado
+}
+
+// withWADORSURL sets the WADO-RS URL extension in extensions, leaving them
+// unchanged when wado is empty
+func withWADORSURL(extensions []models.Extension, wado string) []models.Extension {
+	if wado == "" {
+		return extensions
+	}
+	for i := range extensions {
+		if extensions[i].URL == models.WADORSExtensionURL {
+			extensions[i].ValueURL = &wado
+			return extensions
+		}
+	}
+	return append(extensions, models.Extension{URL: models.WADORSExtensionURL, ValueURL: &wado})
+}
+
+func (s *ImagingStudyService) GetImagingStudy(ctx context.Context, id uuid.UUID) (*models.ImagingStudy, error) {
+	s.logger.WithContext(ctx).WithField("imaging_study_id", id).Info("Retrieving imaging study")
+
+	study, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("imaging_study_id", id).Error("Failed to retrieve imaging study")
+		return nil, fmt.Errorf("failed to retrieve imaging study: %w", err)
+	}
+
+	return study, nil
+}
+
+// FindStudies returns the studies of subject with the accession number, for
+// linking them to the reports interpreting them
+func (s *ImagingStudyService) FindStudies(ctx context.Context, subject, accession string) ([]*models.ImagingStudy, error) {
+	studies, _, err := s.repo.List(ctx, repository.ImagingStudySearchParams{Subject: subject, Identifier: accession}, repository.ValidatePaginationParams(100, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find imaging studies: %w", err)
+	}
+	return studies, nil
+}
+
+func (s *ImagingStudyService) ListImagingStudies(ctx context.Context, search repository.ImagingStudySearchParams, limit, offset int) (*models.ImagingStudyListResponse, error) {
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"limit":  limit,
+		"offset": offset,
+	}).Info("Listing imaging studies")
+
+	params := repository.ValidatePaginationParams(limit, offset)
+
+	studies, pagination, err := s.repo.List(ctx, search, params)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list imaging studies")
+		return nil, fmt.Errorf("failed to list imaging studies: %w", err)
+	}
+
+	entries := make([]models.ImagingStudyEntry, len(studies))
+	for i, study := range studies {
+		entries[i] = models.ImagingStudyEntry{
+			FullURL:  fmt.Sprintf("/api/v1/imaging-studies/%s", study.ID),
+			Resource: study,
+			Search: &models.SearchEntry{
+				Mode: "match",
+			},
+		}
+	}
+
+	response := &models.ImagingStudyListResponse{
+		ResourceType: "Bundle",
+		ID:           uuid.New().String(),
+		Type:         "searchset",
+		Total:        pagination.Total,
+		Entry:        entries,
+	}
+
+	// Carry search filters through to pagination links
+	query := url.Values{}
+	if search.Subject != "" {
+		query.Set("subject", search.Subject)
+	}
+	if search.Identifier != "" {
+		query.Set("identifier", search.Identifier)
+	}
+	if search.Modality != "" {
+		query.Set("modality", search.Modality)
+	}
+	filters := ""
+	if len(query) > 0 {
+		filters = "&" + query.Encode()
+	}
+
+	if pagination.HasNext {
+		response.Link = append(response.Link, models.BundleLink{
+			Relation: "next",
+			URL:      fmt.Sprintf("/api/v1/imaging-studies?limit=%d&offset=%d%s", params.Limit, params.Offset+params.Limit, filters),
+		})
+	}
+
+	if params.Offset > 0 {
+		prevOffset := params.Offset - params.Limit
+		if prevOffset < 0 {
+			prevOffset = 0
+		}
+		response.Link = append(response.Link, models.BundleLink{
+			Relation: "prev",
+			URL:      fmt.Sprintf("/api/v1/imaging-studies?limit=%d&offset=%d%s", params.Limit, prevOffset, filters),
+		})
+	}
+
+	s.logger.WithContext(ctx).WithField("total", pagination.Total).Info("Imaging studies listed successfully")
+	return response, nil
+}
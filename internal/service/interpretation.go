@@ -0,0 +1,173 @@
+package service
+
+import (
+	"strings"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/ucum"
+)
+
+// interpretationSystem is the code system of interpretations computed from
+// reference ranges
+const interpretationSystem = "http://terminology.hl7.org/CodeSystem/v3-ObservationInterpretation"
+
+// Interpretations computed from reference ranges, with their display names
+var interpretationDisplays = map[string]string{
+	"LL": "Critical low",
+	"L":  "Low",
+	"N":  "Normal",
+	"H":  "High",
+	"HH": "Critical high",
+}
+
+// interpretObservation computes the interpretation of an observation, and of
+// each of its components, that has a quantity value and reference ranges but no
+// interpretation of its own
+func interpretObservation(observation *models.Observation) {
+	if len(observation.Interpretation) == 0 {
+		if concept := interpret(observation.ValueQuantity, observation.ReferenceRange); concept != nil {
+			observation.Interpretation = []models.CodeableConcept{*concept}
+		}
+	}
+	for i := range observation.Component {
+		component := &observation.Component[i]
+		if len(component.Interpretation) == 0 {
+			if concept := interpret(component.ValueQuantity, component.ReferenceRange); concept != nil {
+				component.Interpretation = []models.CodeableConcept{*concept}
+			}
+		}
+	}
+}
+
+// interpret compares value with the first normal range (one without a type, or
+// typed "normal") and the first range typed "critical", whose bounds are
+// converted to the value's unit. Ranges limited to an age or population are
+// not used, since they may not apply to the subject. It returns nil when the
+// value is inexact, such as "<5", no range applies or a bound's unit cannot be
+// converted.
+func interpret(value *models.Quantity, ranges []models.ObservationReferenceRange) *models.CodeableConcept {
+	if value == nil || value.Value == nil || value.Comparator != nil {
+		return nil
+	}
+
+	var normal, critical *models.ObservationReferenceRange
+	for i := range ranges {
+		rng := &ranges[i]
+		if rng.Age != nil || len(rng.AppliesTo) > 0 {
+			continue
+		}
+		switch rangeMeaning(rng) {
+		case "", "normal":
+			if normal == nil {
+				normal = rng
+			}
+		case "critical":
+			if critical == nil {
+				critical = rng
+			}
+		}
+	}
+
+	v := *value.Value
+	var code string
+	for _, check := range []struct {
+		rng   *models.ObservationReferenceRange
+		code  string
+		below bool
+	}{
+		{critical, "LL", true},
+		{critical, "HH", false},
+		{normal, "L", true},
+		{normal, "H", false},
+	} {
+		if check.rng == nil {
+			continue
+		}
+		bound := check.rng.High
+		if check.below {
+			bound = check.rng.Low
+		}
+		limit, ok, failed := boundIn(bound, value)
+		if failed {
+			return nil
+		}
+		if ok && ((check.below && v < limit) || (!check.below && v > limit)) {
+			code = check.code
+			break
+		}
+	}
+	if code == "" {
+		if normal == nil {
+			return nil
+		}
+		// The value is within the normal range, unless it has no bounds
+		if normal.Low == nil && normal.High == nil {
+			return nil
+		}
+		code = "N"
+	}
+
+	display := interpretationDisplays[code]
+	system := interpretationSystem
+	return &models.CodeableConcept{
+		Coding: []models.Coding{{System: &system, Code: &code, Display: &display}},
+	}
+}
+
+// rangeMeaning returns the lowercased type code or text of a reference range,
+// or "" for one without a type
+func rangeMeaning(rng *models.ObservationReferenceRange) string {
+	if rng.Type == nil {
+		return ""
+	}
+	for _, coding := range rng.Type.Coding {
+		if coding.Code != nil {
+			return strings.ToLower(*coding.Code)
+		}
+	}
+	if rng.Type.Text != nil {
+		return strings.ToLower(*rng.Type.Text)
+	}
+	return ""
+}
+
+// boundIn returns a range bound in the unit of value. ok is false for a
+// missing bound; failed is true when its unit cannot be converted.
+func boundIn(bound, value *models.Quantity) (limit float64, ok, failed bool) {
+	if bound == nil || bound.Value == nil {
+		return 0, false, false
+	}
+
+	boundCode, valueCode := ucumCode(bound), ucumCode(value)
+	if boundCode == "" || valueCode == "" {
+		// Without UCUM codes to convert between, the units must be the same
+		if unitText(bound) != unitText(value) {
+			return 0, false, true
+		}
+		return *bound.Value, true, false
+	}
+	converted, err := ucum.Convert(*bound.Value, boundCode, valueCode)
+	if err != nil {
+		return 0, false, true
+	}
+	return converted, true, false
+}
+
+// ucumCode returns the UCUM code of a quantity's unit, or ""
+func ucumCode(quantity *models.Quantity) string {
+	if quantity.Code != nil && (quantity.System == nil || *quantity.System == ucum.System) {
+		return *quantity.Code
+	}
+	return ""
+}
+
+// unitText returns a quantity's unit as written, falling back to its code
+func unitText(quantity *models.Quantity) string {
+	if quantity.Unit != nil {
+		return *quantity.Unit
+	}
+	if quantity.Code != nil {
+		return *quantity.Code
+	}
+	return ""
+}
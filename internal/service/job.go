@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// JobService reads the status of background jobs. Jobs are submitted through
+// worker.WorkerPool, which records their status.
+type JobService struct {
+	repo   *repository.JobRepository
+	logger *logrus.Logger
+}
+
+func NewJobService(repo *repository.JobRepository, logger *logrus.Logger) *JobService {
+	return &JobService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *JobService) ListJobs(ctx context.Context, filter repository.JobFilter, limit, offset int) ([]*models.BackgroundJob, repository.PaginationResult, error) {
+	params := repository.ValidatePaginationParams(limit, offset)
+
+	jobs, pagination, err := s.repo.List(ctx, filter, params)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list jobs")
+		return nil, repository.PaginationResult{}, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	return jobs, pagination, nil
+}
+
+// GetJob returns a job. When tenantID is set, jobs of other tenants and
+// deployment-wide jobs are reported as not found.
+func (s *JobService) GetJob(ctx context.Context, id, tenantID string) (*models.BackgroundJob, error) {
+	job, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if tenantID != "" && (job.TenantID == nil || *job.TenantID != tenantID) {
+		return nil, models.ErrJobNotFound
+	}
+
+	return job, nil
+}
+
+// CancelJob marks a job that has not finished as cancelled, subject to the same
+// tenant check as GetJob. Workers skip the job if it is still waiting, and cancel
+// it if it is running.
+func (s *JobService) CancelJob(ctx context.Context, id, tenantID string) (*models.BackgroundJob, error) {
+	if _, err := s.GetJob(ctx, id, tenantID); err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.repo.Cancel(ctx, id)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("job_id", id).Error("Failed to cancel job")
+		return nil, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	if !cancelled {
+		return nil, models.ErrJobFinished
+	}
+
+	s.logger.WithContext(ctx).WithField("job_id", id).Info("Job cancelled")
+	return s.repo.GetByID(ctx, id)
+}
+
+// ListResults returns attempt results, most recent first
+func (s *JobService) ListResults(ctx context.Context, filter repository.JobResultFilter, limit, offset int) ([]*models.JobResult, repository.PaginationResult, error) {
+	params := repository.ValidatePaginationParams(limit, offset)
+
+	results, pagination, err := s.repo.ListResults(ctx, filter, params)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list job results")
+		return nil, repository.PaginationResult{}, fmt.Errorf("failed to list job results: %w", err)
+	}
+
+	return results, pagination, nil
+}
+
+// GetJobResults returns the attempt results of one job, subject to the same
+// tenant check as GetJob
+func (s *JobService) GetJobResults(ctx context.Context, id, tenantID string, limit, offset int) ([]*models.JobResult, repository.PaginationResult, error) {
+	if _, err := s.GetJob(ctx, id, tenantID); err != nil {
+		return nil, repository.PaginationResult{}, err
+	}
+	return s.ListResults(ctx, repository.JobResultFilter{JobID: id}, limit, offset)
+}
+
+// PruneHistory deletes attempt results and finished jobs older than retention
+func (s *JobService) PruneHistory(ctx context.Context, retention time.Duration) error {
+	cutoff := time.Now().UTC().Add(-retention)
+
+	results, jobs, err := s.repo.PruneHistory(ctx, cutoff)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to prune job history")
+		return fmt.Errorf("failed to prune job history: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"cutoff":  cutoff,
+		"results": results,
+		"jobs":    jobs,
+	}).Info("Job history pruned")
+	return nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/maintenance"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// maintenanceModeID is the resource id of the audit entries of maintenance mode
+// switches
+var maintenanceModeID = uuid.NewSHA1(uuid.NameSpaceURL, []byte("urn:healthcare-api:maintenance-mode"))
+
+// MaintenanceService switches maintenance mode through the admin API and keeps
+// the process's mode in step with the state stored for every instance. Every
+// switch is recorded in the audit log.
+type MaintenanceService struct {
+	repo     *repository.MaintenanceRepository
+	mode     *maintenance.Mode
+	interval time.Duration
+	logger   *logrus.Logger
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewMaintenanceService creates the service of mode, which it logs the
+// switches of
+func NewMaintenanceService(repo *repository.MaintenanceRepository, mode *maintenance.Mode, cfg config.MaintenanceConfig, logger *logrus.Logger) *MaintenanceService {
+	mode.OnChange(func(enabled bool) {
+		if enabled {
+			logger.Warn("Maintenance mode on: writes are refused and background jobs pause")
+		} else {
+			logger.Info("Maintenance mode off")
+		}
+	})
+
+	return &MaintenanceService{
+		repo:     repo,
+		mode:     mode,
+		interval: time.Duration(cfg.PollInterval) * time.Second,
+		logger:   logger,
+		quit:     make(chan struct{}),
+	}
+}
+
+// SetWorkerPool makes maintenance mode pause pool, which takes no jobs while
+// it is on
+func (s *MaintenanceService) SetWorkerPool(pool interface {
+	Pause()
+	Resume()
+}) {
+	s.mode.OnChange(func(enabled bool) {
+		if enabled {
+			pool.Pause()
+		} else {
+			pool.Resume()
+		}
+	})
+	if s.mode.Enabled() {
+		pool.Pause()
+	}
+}
+
+// Get returns the maintenance mode in effect, read again from the database
+func (s *MaintenanceService) Get(ctx context.Context) (*models.MaintenanceMode, error) {
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return s.mode.Status(), nil
+}
+
+// Set switches maintenance mode on or off for every instance. Other instances
+// follow within the poll interval. Switching it off leaves it on while
+// MAINTENANCE_MODE is set.
+func (s *MaintenanceService) Set(ctx context.Context, req *models.MaintenanceModeRequest) (*models.MaintenanceMode, error) {
+	previous, err := s.repo.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &models.MaintenanceMode{Enabled: *req.Enabled, Message: req.Message}
+	if userID := requestctx.UserID(ctx); userID != "" {
+		stored.UpdatedBy = &userID
+	}
+	if err := s.repo.Set(ctx, stored); err != nil {
+		return nil, err
+	}
+	s.mode.Store(stored)
+
+	// The switch is made already, so a failure to audit is logged
+	if err := s.audit(ctx, previous, stored); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to audit maintenance mode switch")
+	}
+	s.logger.WithContext(ctx).WithField("enabled", stored.Enabled).Info("Maintenance mode switched")
+	return s.mode.Status(), nil
+}
+
+// Start reads the stored maintenance mode, then again every poll interval until
+// Stop is called
+func (s *MaintenanceService) Start() {
+	if err := s.refresh(context.Background()); err != nil {
+		s.logger.WithError(err).Error("Failed to read maintenance mode")
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.refresh(context.Background()); err != nil {
+					// The last state read stays in effect
+					s.logger.WithError(err).Warn("Failed to read maintenance mode")
+				}
+			case <-s.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends polling
+func (s *MaintenanceService) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// refresh applies the stored maintenance mode to the process
+func (s *MaintenanceService) refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.interval)
+	defer cancel()
+
+	stored, err := s.repo.Get(ctx)
+	if err != nil {
+		return err
+	}
+	s.mode.Store(stored)
+	return nil
+}
+
+// audit records a maintenance mode switch in the audit log of
+// PlatformAuditTenant
+func (s *MaintenanceService) audit(ctx context.Context, previous, stored *models.MaintenanceMode) error {
+	oldJSON, err := json.Marshal(map[string]interface{}{"enabled": previous.Enabled, "message": previous.Message})
+	if err != nil {
+		return err
+	}
+	newJSON, err := json.Marshal(map[string]interface{}{"enabled": stored.Enabled, "message": stored.Message})
+	if err != nil {
+		return err
+	}
+
+	return s.repo.LogAudit(requestctx.WithTenantID(ctx, PlatformAuditTenant), &repository.AuditLog{
+		ResourceType: "MaintenanceMode",
+		ResourceID:   maintenanceModeID,
+		Action:       "UPDATE",
+		UserID:       stored.UpdatedBy,
+		OldValues:    oldJSON,
+		NewValues:    newJSON,
+	})
+}
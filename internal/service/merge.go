@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"healthcare-api/internal/cache"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// PatientMergeService merges duplicate patients: everything referring to the
+// merged patient is moved to the surviving one in a single transaction
+type PatientMergeService struct {
+	repo   *repository.PatientRepository
+	outbox JobOutbox
+	cache  *cache.ResourceCache
+	logger *logrus.Logger
+}
+
+func NewPatientMergeService(repo *repository.PatientRepository, logger *logrus.Logger) *PatientMergeService {
+	return &PatientMergeService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// SetOutbox makes the service record background jobs for every resource a merge changes
+func (s *PatientMergeService) SetOutbox(outbox JobOutbox) {
+	s.outbox = outbox
+}
+
+// SetCache makes merges drop the cached copies of the resources they change,
+// which the merge writes without going through the cache
+func (s *PatientMergeService) SetCache(cache *cache.ResourceCache) {
+	s.cache = cache
+}
+
+// Merge merges the source patient into the target, which must be a different
+// patient. The source's observations, diagnostic reports, imaging studies and
+// critical result tasks are re-pointed at the target, the source is marked
+// inactive and linked replaced-by the target, and the target is linked as
+// replacing it. A patient already replaced by another cannot take part.
+func (s *PatientMergeService) Merge(ctx context.Context, sourceID, targetID uuid.UUID) (*models.PatientMergeResult, error) {
+	logger := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"source_patient_id": sourceID,
+		"target_patient_id": targetID,
+	})
+	logger.Info("Merging patients")
+
+	merge, err := s.repo.Merge(ctx, sourceID, targetID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to merge patients")
+		return nil, fmt.Errorf("failed to merge patients: %w", err)
+	}
+
+	changed := append([]repository.MergedResource{
+		{ResourceType: "Patient", ID: sourceID},
+		{ResourceType: "Patient", ID: targetID},
+	}, merge.Resources...)
+	for _, resource := range changed {
+		if s.cache != nil {
+			s.cache.Evict(ctx, requestctx.TenantID(ctx), resource.ResourceType, resource.ID)
+		}
+		emitResourceJobs(ctx, s.outbox, s.logger, resource.ResourceType, resource.ID, ActionUpdate)
+	}
+
+	result := &models.PatientMergeResult{
+		Source: merge.Source,
+		Target: merge.Target,
+		Moved:  map[string]int{},
+	}
+	for _, resource := range merge.Resources {
+		result.Moved[resource.ResourceType]++
+	}
+	if merge.Tasks > 0 {
+		result.Moved["Task"] = int(merge.Tasks)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"resources": len(merge.Resources),
+		"tasks":     merge.Tasks,
+	}).Info("Patients merged successfully")
+	return result, nil
+}
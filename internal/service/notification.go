@@ -0,0 +1,644 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/notify"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// NotificationDeliveryJobType is the job sending one queued notification
+const NotificationDeliveryJobType = "notification_delivery"
+
+// maxNotificationError bounds the error kept on a notification
+const maxNotificationError = 1024
+
+// escalationBatch bounds the held pages released, and the pages escalated, per run
+const escalationBatch = 500
+
+// escalatedPrefix starts the text of a page sent to an escalation target
+const escalatedPrefix = "ESCALATED: "
+
+// NotificationService manages the tenant's notification recipients and sends
+// them notifications about events such as critical results. Notify queues a
+// notification per recipient, rendered from its kind's templates, and a
+// delivery job for each; Deliver sends it and tracks its status.
+//
+// Kinds with an SMS template, such as critical results, also page recipients
+// with a phone number. A page to a recipient in its quiet hours goes to its
+// escalation target instead, or is held until they end, and a page not
+// acknowledged in time escalates; RunEscalations does both on a schedule.
+type NotificationService struct {
+	repo          *repository.NotificationRepository
+	outbox        JobOutbox
+	mailer        notify.Mailer
+	sms           notify.SMSSender
+	criticalTasks *repository.CriticalValueRepository
+	cfg           config.NotificationConfig
+	logger        *logrus.Logger
+}
+
+func NewNotificationService(repo *repository.NotificationRepository, mailer notify.Mailer, sms notify.SMSSender, cfg config.NotificationConfig, logger *logrus.Logger) *NotificationService {
+	return &NotificationService{
+		repo:   repo,
+		mailer: mailer,
+		sms:    sms,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// SetOutbox makes the service record delivery jobs through outbox
+func (s *NotificationService) SetOutbox(outbox JobOutbox) {
+	s.outbox = outbox
+}
+
+// SetCriticalValueTasks makes acknowledging a critical result notification
+// also complete the result's acknowledgement task
+func (s *NotificationService) SetCriticalValueTasks(tasks *repository.CriticalValueRepository) {
+	s.criticalTasks = tasks
+}
+
+// Enabled reports whether notifications are sent: a provider is configured and
+// delivery jobs can be recorded
+func (s *NotificationService) Enabled() bool {
+	return (s.mailer != nil || s.sms != nil) && s.outbox != nil
+}
+
+// CreateRecipient adds a notification recipient
+func (s *NotificationService) CreateRecipient(ctx context.Context, req *models.NotificationRecipientCreateRequest) (*models.NotificationRecipient, error) {
+	recipient := &models.NotificationRecipient{
+		ID:         uuid.New(),
+		Name:       req.Name,
+		Email:      req.Email,
+		Phone:      req.Phone,
+		Kinds:      req.Kinds,
+		QuietHours: req.QuietHours,
+		Escalation: req.Escalation,
+		Active:     req.Active == nil || *req.Active,
+	}
+	if recipient.Kinds == nil {
+		recipient.Kinds = []string{}
+	}
+	if err := s.validateRecipient(ctx, recipient); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateRecipient(ctx, recipient); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to create notification recipient")
+		return nil, fmt.Errorf("failed to create notification recipient: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithField("recipient_id", recipient.ID).Info("Notification recipient created")
+	return recipient, nil
+}
+
+func (s *NotificationService) GetRecipient(ctx context.Context, id uuid.UUID) (*models.NotificationRecipient, error) {
+	return s.repo.GetRecipient(ctx, id)
+}
+
+// ListRecipients returns the tenant's notification recipients
+func (s *NotificationService) ListRecipients(ctx context.Context) ([]*models.NotificationRecipient, error) {
+	recipients, err := s.repo.ListRecipients(ctx, false)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list notification recipients")
+		return nil, fmt.Errorf("failed to list notification recipients: %w", err)
+	}
+	return recipients, nil
+}
+
+// UpdateRecipient applies the fields set in req
+func (s *NotificationService) UpdateRecipient(ctx context.Context, id uuid.UUID, req *models.NotificationRecipientUpdateRequest) (*models.NotificationRecipient, error) {
+	recipient, err := s.repo.GetRecipient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		recipient.Name = *req.Name
+	}
+	if req.Email != nil {
+		recipient.Email = optional(*req.Email)
+	}
+	if req.Phone != nil {
+		recipient.Phone = optional(*req.Phone)
+	}
+	if req.Kinds != nil {
+		recipient.Kinds = *req.Kinds
+	}
+	if req.ClearQuietHours {
+		recipient.QuietHours = nil
+	}
+	if req.QuietHours != nil {
+		recipient.QuietHours = req.QuietHours
+	}
+	if req.ClearEscalation {
+		recipient.Escalation = nil
+	}
+	if req.Escalation != nil {
+		recipient.Escalation = req.Escalation
+	}
+	if req.Active != nil {
+		recipient.Active = *req.Active
+	}
+	if err := s.validateRecipient(ctx, recipient); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateRecipient(ctx, recipient); err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("recipient_id", id).Error("Failed to update notification recipient")
+		return nil, fmt.Errorf("failed to update notification recipient: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithField("recipient_id", id).Info("Notification recipient updated")
+	return recipient, nil
+}
+
+// validateRecipient checks that a recipient can be reached and that its quiet
+// hours and escalation target are usable, wrapping
+// models.ErrInvalidNotificationRecipient when they are not
+func (s *NotificationService) validateRecipient(ctx context.Context, recipient *models.NotificationRecipient) error {
+	if recipient.Email == nil && recipient.Phone == nil {
+		return fmt.Errorf("%w: an email address or phone number is required", models.ErrInvalidNotificationRecipient)
+	}
+	if recipient.QuietHours != nil {
+		if err := recipient.QuietHours.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", models.ErrInvalidNotificationRecipient, err)
+		}
+	}
+
+	escalation := recipient.Escalation
+	if escalation == nil {
+		return nil
+	}
+	if escalation.RecipientID == recipient.ID {
+		return fmt.Errorf("%w: a recipient cannot escalate to itself", models.ErrInvalidNotificationRecipient)
+	}
+	target, err := s.repo.GetRecipient(ctx, escalation.RecipientID)
+	if errors.Is(err, models.ErrNotificationRecipientNotFound) {
+		return fmt.Errorf("%w: escalation recipient %s does not exist", models.ErrInvalidNotificationRecipient, escalation.RecipientID)
+	}
+	if err != nil {
+		return err
+	}
+	if target.Phone == nil {
+		return fmt.Errorf("%w: escalation recipient %s has no phone number", models.ErrInvalidNotificationRecipient, escalation.RecipientID)
+	}
+	return nil
+}
+
+// DeleteRecipient removes a notification recipient; notifications already
+// queued for it are still sent, and recipients escalating to it no longer escalate
+func (s *NotificationService) DeleteRecipient(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.DeleteRecipient(ctx, id); err != nil {
+		return err
+	}
+	s.logger.WithContext(ctx).WithField("recipient_id", id).Info("Notification recipient deleted")
+	return nil
+}
+
+// GetNotification returns a notification and its delivery status
+func (s *NotificationService) GetNotification(ctx context.Context, id uuid.UUID) (*models.Notification, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Acknowledge records that the caller has seen the event of a notification,
+// such as a critical result, which stops the escalation of its pages and
+// completes a critical result's acknowledgement task
+func (s *NotificationService) Acknowledge(ctx context.Context, id uuid.UUID) (*models.Notification, error) {
+	userID := requestctx.UserID(ctx)
+	notification, err := s.repo.Acknowledge(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if notification.Kind == models.NotificationCriticalResult && s.criticalTasks != nil {
+		if observationID, ok := observationReference(notification.Reference); ok {
+			if err := s.criticalTasks.CompleteObservationTask(ctx, observationID, userID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"notification_id": id,
+		"kind":            notification.Kind,
+		"reference":       notification.Reference,
+	}).Info("Notification acknowledged")
+	return notification, nil
+}
+
+// AcknowledgeEvent acknowledges every notification of kind about reference,
+// e.g. "Observation/<id>", for the caller
+func (s *NotificationService) AcknowledgeEvent(ctx context.Context, kind, reference string) error {
+	return s.repo.AcknowledgeEvent(ctx, kind, reference, requestctx.UserID(ctx))
+}
+
+// ListNotifications returns the tenant's notifications, most recent first
+func (s *NotificationService) ListNotifications(ctx context.Context, filter repository.NotificationFilter, limit, offset int) ([]*models.Notification, repository.PaginationResult, error) {
+	params := repository.ValidatePaginationParams(limit, offset)
+	notifications, pagination, err := s.repo.List(ctx, filter, params)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list notifications")
+		return nil, repository.PaginationResult{}, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	return notifications, pagination, nil
+}
+
+// Link returns the URL of an API path, such as "/api/v1/observations/<id>", or
+// "" when no link base URL is configured
+func (s *NotificationService) Link(path string) string {
+	if s.cfg.LinkBaseURL == "" {
+		return ""
+	}
+	return s.cfg.LinkBaseURL + path
+}
+
+// Notify queues a notification of kind about reference, e.g.
+// "Observation/<id>", for each active recipient receiving kind, rendered with
+// data, returning how many were queued: an email to recipients with an email
+// address and, for kinds with an SMS template, a page to recipients with a
+// phone number. Each event notifies an address once: notifying again only
+// queues delivery jobs for notifications still queued, which Deliver skips once
+// they are sent. Channels without a provider are skipped.
+func (s *NotificationService) Notify(ctx context.Context, kind, reference string, data interface{}) (int, error) {
+	if !s.Enabled() {
+		return 0, nil
+	}
+	recipients, err := s.repo.ListRecipients(ctx, true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list notification recipients: %w", err)
+	}
+
+	var content *notify.Content
+	now := time.Now().UTC()
+	queued := 0
+	for _, recipient := range recipients {
+		if !recipient.Receives(kind) {
+			continue
+		}
+		if content == nil {
+			if content, err = notify.Render(kind, data); err != nil {
+				return queued, err
+			}
+		}
+
+		if recipient.Email != nil && s.mailer != nil {
+			recipientID := recipient.ID
+			ok, err := s.enqueue(ctx, &models.Notification{
+				ID:          uuid.New(),
+				Kind:        kind,
+				Channel:     models.NotificationChannelEmail,
+				RecipientID: &recipientID,
+				Address:     *recipient.Email,
+				Reference:   reference,
+				Subject:     content.Subject,
+				TextBody:    content.Text,
+				HTMLBody:    content.HTML,
+			})
+			if err != nil {
+				return queued, err
+			}
+			if ok {
+				queued++
+			}
+		}
+
+		if recipient.Phone != nil && content.SMS != "" && s.sms != nil {
+			p := page{kind: kind, reference: reference, subject: content.Subject, text: content.SMS}
+			paged, err := s.page(ctx, p, recipient, now, map[uuid.UUID]bool{})
+			if err != nil {
+				return queued, err
+			}
+			queued += paged
+		}
+	}
+
+	if queued > 0 {
+		s.logger.WithContext(ctx).WithFields(logrus.Fields{
+			"kind":          kind,
+			"reference":     reference,
+			"notifications": queued,
+		}).Info("Notifications queued")
+	}
+	return queued, nil
+}
+
+// page is an SMS page about an event
+type page struct {
+	kind      string
+	reference string
+	subject   string
+	text      string
+}
+
+// page queues an SMS page to recipient, returning how many pages were queued.
+// A recipient that cannot be paged now, because it is in its quiet hours,
+// paused or has no phone, is passed over for its escalation target; without
+// one, a page in quiet hours is held until they end and is otherwise dropped.
+// seen holds the recipients already passed over, so escalation loops end.
+func (s *NotificationService) page(ctx context.Context, p page, recipient *models.NotificationRecipient, now time.Time, seen map[uuid.UUID]bool) (int, error) {
+	logger := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"kind":         p.kind,
+		"reference":    p.reference,
+		"recipient_id": recipient.ID,
+	})
+	if seen[recipient.ID] {
+		logger.Warn("Escalation loops back to a recipient already passed over; page dropped")
+		return 0, nil
+	}
+	seen[recipient.ID] = true
+
+	available := recipient.Active && recipient.Phone != nil
+	quiet, quietUntil := recipient.QuietHours.During(now)
+	if !available || quiet {
+		if escalation := recipient.Escalation; escalation != nil {
+			target, err := s.repo.GetRecipient(ctx, escalation.RecipientID)
+			if err == nil {
+				logger.WithField("escalation_recipient_id", target.ID).Info("Recipient unavailable; paging escalation recipient")
+				return s.page(ctx, p, target, now, seen)
+			}
+			if !errors.Is(err, models.ErrNotificationRecipientNotFound) {
+				return 0, err
+			}
+		}
+		if !available {
+			logger.Warn("Recipient unavailable and has no escalation recipient; page dropped")
+			return 0, nil
+		}
+	}
+
+	recipientID := recipient.ID
+	notification := &models.Notification{
+		ID:          uuid.New(),
+		Kind:        p.kind,
+		Channel:     models.NotificationChannelSMS,
+		RecipientID: &recipientID,
+		Address:     *recipient.Phone,
+		Reference:   p.reference,
+		Subject:     p.subject,
+		TextBody:    p.text,
+	}
+	// The acknowledgement deadline runs from when the page is sent
+	sendAt := now
+	if quiet {
+		notification.Status = models.NotificationHeld
+		notification.DeliverAfter = &quietUntil
+		sendAt = quietUntil
+	}
+	if escalation := recipient.Escalation; escalation != nil {
+		escalateAt := sendAt.Add(time.Duration(escalation.AfterMinutes) * time.Minute)
+		notification.EscalateAt = &escalateAt
+	}
+
+	ok, err := s.enqueue(ctx, notification)
+	if err != nil || !ok {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// enqueue records a notification and queues its delivery job, reporting
+// whether a job was queued: none is for a held page, or a notification of the
+// event to the address that was already sent or given up on
+func (s *NotificationService) enqueue(ctx context.Context, notification *models.Notification) (bool, error) {
+	if err := s.repo.Enqueue(ctx, notification); err != nil {
+		return false, err
+	}
+	if notification.Status != models.NotificationQueued {
+		return false, nil
+	}
+	err := s.outbox.Add(ctx, NotificationDeliveryJobType, models.NotificationDeliveryPayload{
+		NotificationID: notification.ID.String(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to queue notification delivery: %w", err)
+	}
+	return true, nil
+}
+
+// RunEscalations releases the held pages of every tenant whose quiet hours
+// have ended and escalates pages not acknowledged in time, or given up on, to
+// their recipient's escalation target. Without an SMS provider, it does nothing.
+func (s *NotificationService) RunEscalations(ctx context.Context) (released, escalated int, err error) {
+	if s.sms == nil || s.outbox == nil {
+		return 0, 0, nil
+	}
+	now := time.Now().UTC()
+
+	held, err := s.repo.DueHeld(ctx, now, escalationBatch)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list held notifications: %w", err)
+	}
+	for _, notification := range held {
+		tenantCtx := requestctx.WithTenantID(ctx, notification.TenantID)
+		// The job is queued first: Deliver sends held pages that are due, so a
+		// failed release leaves nothing unsent
+		err := s.outbox.Add(tenantCtx, NotificationDeliveryJobType, models.NotificationDeliveryPayload{
+			NotificationID: notification.ID.String(),
+		})
+		if err != nil {
+			return released, escalated, fmt.Errorf("failed to queue notification delivery: %w", err)
+		}
+		if err := s.repo.Release(tenantCtx, notification.ID); err != nil {
+			return released, escalated, err
+		}
+		released++
+	}
+
+	due, err := s.repo.DueEscalations(ctx, now, escalationBatch)
+	if err != nil {
+		return released, escalated, fmt.Errorf("failed to list notifications due to escalate: %w", err)
+	}
+	for _, notification := range due {
+		tenantCtx := requestctx.WithTenantID(ctx, notification.TenantID)
+		if err := s.escalate(tenantCtx, notification, now); err != nil {
+			return released, escalated, err
+		}
+		escalated++
+	}
+
+	if released > 0 || escalated > 0 {
+		s.logger.WithContext(ctx).WithFields(logrus.Fields{
+			"released":  released,
+			"escalated": escalated,
+		}).Info("Notification escalations run")
+	}
+	return released, escalated, nil
+}
+
+// escalate pages the escalation target of the recipient of an unacknowledged
+// page and marks the page escalated. A page whose recipient was deleted, or
+// no longer escalates, is marked escalated without paging anyone.
+func (s *NotificationService) escalate(ctx context.Context, notification *models.Notification, now time.Time) error {
+	logger := s.logger.WithContext(ctx).WithField("notification_id", notification.ID)
+
+	var recipient *models.NotificationRecipient
+	if notification.RecipientID != nil {
+		var err error
+		recipient, err = s.repo.GetRecipient(ctx, *notification.RecipientID)
+		if err != nil && !errors.Is(err, models.ErrNotificationRecipientNotFound) {
+			return err
+		}
+	}
+
+	var target *models.NotificationRecipient
+	if recipient != nil && recipient.Escalation != nil {
+		var err error
+		target, err = s.repo.GetRecipient(ctx, recipient.Escalation.RecipientID)
+		if err != nil && !errors.Is(err, models.ErrNotificationRecipientNotFound) {
+			return err
+		}
+	}
+
+	if target == nil {
+		logger.Warn("Page not acknowledged but its recipient no longer escalates")
+	} else {
+		text := notification.TextBody
+		if !strings.HasPrefix(text, escalatedPrefix) {
+			text = escalatedPrefix + text
+		}
+		p := page{kind: notification.Kind, reference: notification.Reference, subject: notification.Subject, text: text}
+		if _, err := s.page(ctx, p, target, now, map[uuid.UUID]bool{recipient.ID: true}); err != nil {
+			return err
+		}
+		logger.WithFields(logrus.Fields{
+			"recipient_id":            recipient.ID,
+			"escalation_recipient_id": target.ID,
+		}).Info("Unacknowledged page escalated")
+	}
+
+	return s.repo.MarkEscalated(ctx, notification.ID)
+}
+
+// Deliver sends a queued notification, or a held page that is due, and records
+// the attempt, returning an error when it was not sent. Notifications already
+// sent or given up on are skipped. A failed attempt leaves the notification
+// queued for a retry unless final is set or the failure is permanent, which
+// marks it failed; a page given up on escalates at once.
+func (s *NotificationService) Deliver(ctx context.Context, id uuid.UUID, final bool) error {
+	logger := s.logger.WithContext(ctx).WithField("notification_id", id)
+
+	notification, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, models.ErrNotificationNotFound) {
+		logger.Info("Skipped delivery of a pruned notification")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	due := notification.Status == models.NotificationQueued ||
+		(notification.Status == models.NotificationHeld && notification.DeliverAfter != nil && !notification.DeliverAfter.After(now))
+	if !due {
+		logger.WithField("status", notification.Status).Info("Skipped delivery of a finished or held notification")
+		return nil
+	}
+
+	var sendErr error
+	switch notification.Channel {
+	case models.NotificationChannelSMS:
+		if s.sms == nil {
+			return fmt.Errorf("%w: no SMS provider is configured", models.ErrUnsupported)
+		}
+		var messageID string
+		messageID, sendErr = s.sms.SendSMS(ctx, notify.SMS{
+			To:   notification.Address,
+			Body: notification.TextBody,
+		})
+		if messageID != "" {
+			notification.ProviderMessageID = &messageID
+		}
+	default:
+		if s.mailer == nil {
+			return fmt.Errorf("%w: no email provider is configured", models.ErrUnsupported)
+		}
+		sendErr = s.mailer.Send(ctx, notify.Email{
+			To:        notification.Address,
+			Subject:   notification.Subject,
+			Text:      notification.TextBody,
+			HTML:      notification.HTMLBody,
+			MessageID: notification.ID.String(),
+		})
+	}
+
+	notification.Attempts++
+	if sendErr == nil {
+		notification.Status = models.NotificationSent
+		notification.SentAt = &now
+		notification.LastError = nil
+	} else {
+		message := sendErr.Error()
+		if len(message) > maxNotificationError {
+			message = message[:maxNotificationError]
+		}
+		notification.LastError = &message
+		if final || notify.Permanent(sendErr) {
+			notification.Status = models.NotificationFailed
+			if notification.EscalateAt != nil && notification.EscalatedAt == nil {
+				notification.EscalateAt = &now
+			}
+		}
+	}
+	if err := s.repo.RecordAttempt(ctx, notification); err != nil {
+		logger.WithError(err).Error("Failed to record notification attempt")
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"kind":     notification.Kind,
+		"attempts": notification.Attempts,
+	})
+	if sendErr != nil {
+		logger.WithError(sendErr).WithField("status", notification.Status).Warn("Notification delivery failed")
+		return sendErr
+	}
+	logger.Info("Notification sent")
+	return nil
+}
+
+// Prune deletes finished notifications of every tenant older than retention
+func (s *NotificationService) Prune(ctx context.Context, retention time.Duration) error {
+	cutoff := time.Now().UTC().Add(-retention)
+
+	notifications, err := s.repo.Prune(ctx, cutoff)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to prune notifications")
+		return fmt.Errorf("failed to prune notifications: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"cutoff":        cutoff,
+		"notifications": notifications,
+	}).Info("Notifications pruned")
+	return nil
+}
+
+// observationReference parses the id of an "Observation/<id>" reference
+func observationReference(reference string) (uuid.UUID, bool) {
+	id, ok := strings.CutPrefix(reference, "Observation/")
+	if !ok {
+		return uuid.Nil, false
+	}
+	observationID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return observationID, true
+}
+
+// optional returns nil for an empty value, which clears an optional field
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -2,33 +2,75 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
 	"healthcare-api/internal/models"
+	"healthcare-api/internal/notify"
+	"healthcare-api/internal/quality"
 	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/search"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
 type ObservationService struct {
-	repo   *repository.ObservationRepository
-	logger *logrus.Logger
+	repo          repository.ObservationStore
+	outbox        JobOutbox
+	index         search.Index
+	notifications *NotificationService
+	critical      *CriticalValueService
+	derived       *DerivedObservationService
+	logger        *logrus.Logger
 }
 
-func NewObservationService(repo *repository.ObservationRepository, logger *logrus.Logger) *ObservationService {
+func NewObservationService(repo repository.ObservationStore, logger *logrus.Logger) *ObservationService {
 	return &ObservationService{
 		repo:   repo,
 		logger: logger,
 	}
 }
 
+// SetOutbox makes the service record background jobs for every stored change
+func (s *ObservationService) SetOutbox(outbox JobOutbox) {
+	s.outbox = outbox
+}
+
+// SetSearchIndex enables smart searches of observations against an external
+// search index, which IndexObservation keeps up to date
+func (s *ObservationService) SetSearchIndex(index search.Index) {
+	s.index = index
+}
+
+// SetNotifications makes AlertCriticalResult notify the tenant's recipients
+func (s *ObservationService) SetNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// SetCriticalValues makes stored observations subject to the tenant's critical
+// value rules, and AlertCriticalResult open acknowledgement tasks
+func (s *ObservationService) SetCriticalValues(critical *CriticalValueService) {
+	s.critical = critical
+}
+
+// SetDerivedObservations makes stored measurements schedule the calculation
+// of the observations derived from them
+func (s *ObservationService) SetDerivedObservations(derived *DerivedObservationService) {
+	s.derived = derived
+}
+
 func (s *ObservationService) CreateObservation(ctx context.Context, req *models.ObservationCreateRequest) (*models.Observation, error) {
-	s.logger.WithContext(ctx).Info("Creating new observation")
+	return s.createObservation(ctx, uuid.New(), req)
+}
 
-	// Generate UUID for new observation
-	observationID := uuid.New()
+// createObservation creates an observation with the given id, which callers
+// deriving ids from their input use to make creates repeatable
+func (s *ObservationService) createObservation(ctx context.Context, observationID uuid.UUID, req *models.ObservationCreateRequest) (*models.Observation, error) {
+	s.logger.WithContext(ctx).Info("Creating new observation")
 
 	// Convert request to observation model
 	observation := &models.Observation{
@@ -77,12 +119,22 @@ func (s *ObservationService) CreateObservation(ctx context.Context, req *models.
 		Component:            req.Component,
 	}
 
+	// Results sent without an interpretation get one from their reference
+	// ranges, which also lets critical values raise notifications
+	interpretObservation(observation)
+	if err := s.flagCritical(ctx, observation); err != nil {
+		return nil, err
+	}
+	observation.Meta = quality.Tag(observation.Meta, quality.ObservationIssues(observation))
+
 	// Create observation in repository
 	if err := s.repo.Create(ctx, observation); err != nil {
 		s.logger.WithContext(ctx).WithError(err).Error("Failed to create observation")
 		return nil, fmt.Errorf("failed to create observation: %w", err)
 	}
 
+	emitResourceJobs(ctx, s.outbox, s.logger, "Observation", observation.ID, ActionCreate)
+	s.scheduleDerivation(ctx, observation)
 	s.logger.WithContext(ctx).WithField("observation_id", observation.ID).Info("Observation created successfully")
 	return observation, nil
 }
@@ -221,16 +273,45 @@ func (s *ObservationService) UpdateObservation(ctx context.Context, id uuid.UUID
 		existingObservation.Component = req.Component
 	}
 
+	if err := s.flagCritical(ctx, existingObservation); err != nil {
+		return nil, err
+	}
+	existingObservation.Meta = quality.Tag(existingObservation.Meta, quality.ObservationIssues(existingObservation))
+
 	// Update in repository
 	if err := s.repo.Update(ctx, existingObservation); err != nil {
 		s.logger.WithContext(ctx).WithError(err).WithField("observation_id", id).Error("Failed to update observation")
 		return nil, fmt.Errorf("failed to update observation: %w", err)
 	}
 
+	emitResourceJobs(ctx, s.outbox, s.logger, "Observation", id, ActionUpdate)
+	s.scheduleDerivation(ctx, existingObservation)
 	s.logger.WithContext(ctx).WithField("observation_id", id).Info("Observation updated successfully")
 	return existingObservation, nil
 }
 
+// scheduleDerivation records the job recalculating the observations derived
+// from the measurements of a stored observation's encounter, if the
+// observation is one of them. The change is already stored, so a failure is
+// logged rather than returned.
+func (s *ObservationService) scheduleDerivation(ctx context.Context, observation *models.Observation) {
+	if s.derived == nil || s.outbox == nil {
+		return
+	}
+	taking := s.derived.Taking(observation)
+	if len(taking) == 0 {
+		return
+	}
+	payload := models.ObservationDerivePayload{
+		Subject:      *observation.Subject.Reference,
+		Encounter:    *observation.Encounter.Reference,
+		Calculations: taking,
+	}
+	if err := s.outbox.Add(ctx, DeriveObservationsJobType, payload); err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("observation_id", observation.ID).Error("Failed to record derived observation job")
+	}
+}
+
 func (s *ObservationService) DeleteObservation(ctx context.Context, id uuid.UUID) error {
 	s.logger.WithContext(ctx).WithField("observation_id", id).Info("Deleting observation")
 
@@ -239,11 +320,28 @@ func (s *ObservationService) DeleteObservation(ctx context.Context, id uuid.UUID
 		return fmt.Errorf("failed to delete observation: %w", err)
 	}
 
+	emitResourceJobs(ctx, s.outbox, s.logger, "Observation", id, ActionDelete)
 	s.logger.WithContext(ctx).WithField("observation_id", id).Info("Observation deleted successfully")
 	return nil
 }
 
-func (s *ObservationService) ListObservations(ctx context.Context, limit, offset int) (*models.ObservationListResponse, error) {
+// RestoreObservation restores a soft-deleted observation
+func (s *ObservationService) RestoreObservation(ctx context.Context, id uuid.UUID) (*models.Observation, error) {
+	s.logger.WithContext(ctx).WithField("observation_id", id).Info("Restoring observation")
+
+	observation, err := s.repo.Restore(ctx, id)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("observation_id", id).Error("Failed to restore observation")
+		return nil, fmt.Errorf("failed to restore observation: %w", err)
+	}
+
+	emitResourceJobs(ctx, s.outbox, s.logger, "Observation", id, ActionRestore)
+	s.scheduleDerivation(ctx, observation)
+	s.logger.WithContext(ctx).WithField("observation_id", id).Info("Observation restored successfully")
+	return observation, nil
+}
+
+func (s *ObservationService) ListObservations(ctx context.Context, search repository.ObservationSearchParams, limit, offset int) (*models.ObservationListResponse, error) {
 	s.logger.WithContext(ctx).WithFields(logrus.Fields{
 		"limit":  limit,
 		"offset": offset,
@@ -252,7 +350,7 @@ func (s *ObservationService) ListObservations(ctx context.Context, limit, offset
 	// Validate and set pagination parameters
 	params := repository.ValidatePaginationParams(limit, offset)
 
-	observations, pagination, err := s.repo.List(ctx, params)
+	observations, pagination, err := s.repo.List(ctx, search, params)
 	if err != nil {
 		s.logger.WithContext(ctx).WithError(err).Error("Failed to list observations")
 		return nil, fmt.Errorf("failed to list observations: %w", err)
@@ -278,11 +376,24 @@ func (s *ObservationService) ListObservations(ctx context.Context, limit, offset
 		Entry:        entries,
 	}
 
+	// Carry search filters through to pagination links
+	query := url.Values{}
+	if search.Subject != "" {
+		query.Set("subject", search.Subject)
+	}
+	if search.Code != "" {
+		query.Set("code", search.Code)
+	}
+	filters := ""
+	if len(query) > 0 {
+		filters = "&" + query.Encode()
+	}
+
 	// Add pagination links
 	if pagination.HasNext {
 		response.Link = append(response.Link, models.BundleLink{
 			Relation: "next",
-			URL:      fmt.Sprintf("/api/v1/observations?limit=%d&offset=%d", params.Limit, params.Offset+params.Limit),
+			URL:      fmt.Sprintf("/api/v1/observations?limit=%d&offset=%d%s", params.Limit, params.Offset+params.Limit, filters),
 		})
 	}
 
@@ -293,10 +404,190 @@ func (s *ObservationService) ListObservations(ctx context.Context, limit, offset
 		}
 		response.Link = append(response.Link, models.BundleLink{
 			Relation: "prev",
-			URL:      fmt.Sprintf("/api/v1/observations?limit=%d&offset=%d", params.Limit, prevOffset),
+			URL:      fmt.Sprintf("/api/v1/observations?limit=%d&offset=%d%s", params.Limit, prevOffset, filters),
 		})
 	}
 
 	s.logger.WithContext(ctx).WithField("total", pagination.Total).Info("Observations listed successfully")
 	return response, nil
 }
+
+// SmartSearchObservations performs fuzzy text search of observations ranked by
+// relevance, optionally of one subject. It needs the search index; without
+// one it fails with models.ErrUnsupported.
+func (s *ObservationService) SmartSearchObservations(ctx context.Context, text, subject string, limit, offset int) (*models.ObservationListResponse, error) {
+	if s.index == nil {
+		return nil, fmt.Errorf("%w smart search of observations without a search index", models.ErrUnsupported)
+	}
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"limit":  limit,
+		"offset": offset,
+	}).Info("Smart searching observations")
+
+	params := repository.ValidatePaginationParams(limit, offset)
+
+	result, err := s.index.SearchObservations(ctx, requestctx.TenantID(ctx), search.Query{Text: text, Subject: subject, Offset: params.Offset, Limit: params.Limit})
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to search observations")
+		return nil, fmt.Errorf("failed to search observations: %w", err)
+	}
+	pagination := repository.GetPaginationResult(result.Total, params)
+
+	// Hits the index has but the repository no longer does, not yet reindexed
+	// after a delete, are left out
+	entries := []models.ObservationEntry{}
+	if len(result.Hits) > 0 {
+		ids := make([]uuid.UUID, len(result.Hits))
+		for i, hit := range result.Hits {
+			ids[i] = hit.ID
+		}
+		observations, _, err := s.repo.List(ctx, repository.ObservationSearchParams{IDs: ids}, repository.PaginationParams{Limit: len(ids)})
+		if err != nil {
+			s.logger.WithContext(ctx).WithError(err).Error("Failed to read observation search hits")
+			return nil, fmt.Errorf("failed to search observations: %w", err)
+		}
+		byID := make(map[uuid.UUID]*models.Observation, len(observations))
+		for _, observation := range observations {
+			byID[observation.ID] = observation
+		}
+		for _, hit := range result.Hits {
+			observation, ok := byID[hit.ID]
+			if !ok {
+				continue
+			}
+			score := hit.Score
+			entries = append(entries, models.ObservationEntry{
+				FullURL:  fmt.Sprintf("/api/v1/observations/%s", observation.ID),
+				Resource: observation,
+				Search: &models.SearchEntry{
+					Mode:  "match",
+					Score: &score,
+				},
+			})
+		}
+	}
+
+	response := &models.ObservationListResponse{
+		ResourceType: "Bundle",
+		ID:           uuid.New().String(),
+		Type:         "searchset",
+		Total:        pagination.Total,
+		Entry:        entries,
+	}
+
+	query := url.Values{"_query": {"smart"}, "text": {text}}
+	if subject != "" {
+		query.Set("subject", subject)
+	}
+	if pagination.HasNext {
+		response.Link = append(response.Link, models.BundleLink{
+			Relation: "next",
+			URL:      fmt.Sprintf("/api/v1/observations?limit=%d&offset=%d&%s", params.Limit, params.Offset+params.Limit, query.Encode()),
+		})
+	}
+
+	s.logger.WithContext(ctx).WithField("total", pagination.Total).Info("Observation search completed")
+	return response, nil
+}
+
+// IndexObservation brings the observation's search index document in line
+// with the stored observation, removing it once the observation is deleted. It
+// does nothing without a search index.
+func (s *ObservationService) IndexObservation(ctx context.Context, id uuid.UUID) error {
+	if s.index == nil {
+		return nil
+	}
+	tenantID := requestctx.TenantID(ctx)
+
+	observation, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, models.ErrObservationNotFound) || errors.Is(err, models.ErrResourceDeleted) {
+		return s.index.Remove(ctx, tenantID, "Observation", id)
+	}
+	if err != nil {
+		return err
+	}
+	return s.index.IndexObservations(ctx, tenantID, []*models.Observation{observation})
+}
+
+// flagCritical applies the tenant's critical value rules to an observation
+// about to be stored
+func (s *ObservationService) flagCritical(ctx context.Context, observation *models.Observation) error {
+	if s.critical == nil {
+		return nil
+	}
+	if err := s.critical.Flag(ctx, observation); err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("observation_id", observation.ID).Error("Failed to apply critical value rules")
+		return fmt.Errorf("failed to apply critical value rules: %w", err)
+	}
+	return nil
+}
+
+// AlertCriticalResult opens a task for a clinician to acknowledge the
+// observation, and notifies the tenant's recipients, when it has a critical
+// interpretation. An observation alerts once, however often it is updated.
+// Without critical values or notifications, it skips that part.
+func (s *ObservationService) AlertCriticalResult(ctx context.Context, id uuid.UUID) error {
+	notifying := s.notifications != nil && s.notifications.Enabled()
+	if s.critical == nil && !notifying {
+		return nil
+	}
+
+	observation, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, models.ErrObservationNotFound) || errors.Is(err, models.ErrResourceDeleted) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	interpretation, ok := models.CriticalInterpretation(observation)
+	if !ok {
+		return nil
+	}
+
+	if s.critical != nil {
+		if _, err := s.critical.OpenTask(ctx, observation, *interpretation); err != nil {
+			return fmt.Errorf("failed to open critical result task: %w", err)
+		}
+	}
+	if !notifying {
+		return nil
+	}
+
+	recordedAt := observation.CreatedAt
+	if observation.Issued != nil {
+		recordedAt = *observation.Issued
+	}
+	_, err = s.notifications.Notify(ctx, models.NotificationCriticalResult, "Observation/"+id.String(), notify.CriticalResult{
+		TenantID:       requestctx.TenantID(ctx),
+		ObservationID:  id.String(),
+		Test:           conceptName(observation.Code),
+		Interpretation: codingName(*interpretation),
+		RecordedAt:     recordedAt,
+		Link:           s.notifications.Link("/api/v1/observations/" + id.String()),
+	})
+	return err
+}
+
+// conceptName returns a concept's text, or the name of its first coding
+func conceptName(concept models.CodeableConcept) string {
+	if concept.Text != nil && *concept.Text != "" {
+		return *concept.Text
+	}
+	for _, coding := range concept.Coding {
+		if name := codingName(coding); name != "" {
+			return name
+		}
+	}
+	return "Unnamed test"
+}
+
+// codingName returns a coding's display, or its code
+func codingName(coding models.Coding) string {
+	if coding.Display != nil && *coding.Display != "" {
+		return *coding.Display
+	}
+	if coding.Code != nil {
+		return *coding.Code
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Resource change actions carried by the jobs a change emits
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+)
+
+// JobOutbox records background jobs for the worker pool to pick up.
+// repository.OutboxRepository is the PostgreSQL implementation.
+type JobOutbox interface {
+	Add(ctx context.Context, jobType string, payload interface{}) error
+}
+
+// ResourceEventJobType is the job publishing a resource change event
+const ResourceEventJobType = "resource_event"
+
+// EventOutbox is a JobOutbox whose resource changes are also handed to event
+// consumers, such as the event transport and webhooks, through a job per change
+// for each consumer
+type EventOutbox struct {
+	JobOutbox
+	jobTypes []string
+}
+
+// NewEventOutbox wraps outbox so resource changes also record a job of each of
+// jobTypes, all with the same event
+func NewEventOutbox(outbox JobOutbox, jobTypes ...string) *EventOutbox {
+	return &EventOutbox{JobOutbox: outbox, jobTypes: jobTypes}
+}
+
+// emitResourceJobs records the jobs that follow a stored change to a resource:
+// its processing job (patient_index or observation_process), if its type has
+// one, an audit_log job and, with an EventOutbox, its event jobs.
+// The change is already stored, so failures are logged rather than returned.
+func emitResourceJobs(ctx context.Context, outbox JobOutbox, logger *logrus.Logger, resourceType string, id uuid.UUID, action string) {
+	if outbox == nil {
+		return
+	}
+
+	var jobType string
+	var payload interface{}
+	switch resourceType {
+	case "Patient":
+		jobType = "patient_index"
+		payload = models.PatientIndexPayload{PatientID: id.String(), Action: action}
+	case "Observation":
+		jobType = "observation_process"
+		payload = models.ObservationProcessPayload{ObservationID: id.String(), Action: action}
+	}
+
+	add := func(jobType string, payload interface{}) {
+		if err := outbox.Add(ctx, jobType, payload); err != nil {
+			logger.WithContext(ctx).WithError(err).WithFields(logrus.Fields{
+				"resource_type": resourceType,
+				"resource_id":   id,
+				"job_type":      jobType,
+			}).Error("Failed to record job for resource change")
+		}
+	}
+	if jobType != "" {
+		add(jobType, payload)
+	}
+	now := time.Now().UTC()
+	add("audit_log", models.AuditLogPayload{
+		ResourceType: resourceType,
+		ResourceID:   id.String(),
+		Action:       action,
+		UserID:       requestctx.UserID(ctx),
+		Timestamp:    now,
+	})
+	if eventOutbox, ok := outbox.(*EventOutbox); ok {
+		event := models.ResourceEventPayload{
+			EventID:      uuid.New().String(),
+			ResourceType: resourceType,
+			ResourceID:   id.String(),
+			Action:       action,
+			UserID:       requestctx.UserID(ctx),
+			Timestamp:    now,
+		}
+		for _, jobType := range eventOutbox.jobTypes {
+			add(jobType, event)
+		}
+	}
+}
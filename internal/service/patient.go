@@ -2,28 +2,52 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
 	"healthcare-api/internal/models"
+	"healthcare-api/internal/quality"
 	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/search"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
 type PatientService struct {
-	repo   *repository.PatientRepository
-	logger *logrus.Logger
+	repo        repository.PatientStore
+	outbox      JobOutbox
+	attachments *AttachmentService
+	index       search.Index
+	logger      *logrus.Logger
 }
 
-func NewPatientService(repo *repository.PatientRepository, logger *logrus.Logger) *PatientService {
+func NewPatientService(repo repository.PatientStore, logger *logrus.Logger) *PatientService {
 	return &PatientService{
 		repo:   repo,
 		logger: logger,
 	}
 }
 
+// SetOutbox makes the service record background jobs for every stored change
+func (s *PatientService) SetOutbox(outbox JobOutbox) {
+	s.outbox = outbox
+}
+
+// SetAttachments makes the service move inline photo data to the object store
+func (s *PatientService) SetAttachments(attachments *AttachmentService) {
+	s.attachments = attachments
+}
+
+// SetSearchIndex makes smart searches run against an external search index,
+// which IndexPatient keeps up to date
+func (s *PatientService) SetSearchIndex(index search.Index) {
+	s.index = index
+}
+
 func (s *PatientService) CreatePatient(ctx context.Context, req *models.PatientCreateRequest) (*models.Patient, error) {
 	s.logger.WithContext(ctx).Info("Creating new patient")
 
@@ -64,12 +88,19 @@ func (s *PatientService) CreatePatient(ctx context.Context, req *models.PatientC
 		patient.Active = &active
 	}
 
+	if err := s.offloadPhotos(ctx, patient.Photo); err != nil {
+		return nil, err
+	}
+
+	patient.Meta = quality.Tag(patient.Meta, quality.PatientIssues(patient))
+
 	// Create patient in repository
 	if err := s.repo.Create(ctx, patient); err != nil {
 		s.logger.WithContext(ctx).WithError(err).Error("Failed to create patient")
 		return nil, fmt.Errorf("failed to create patient: %w", err)
 	}
 
+	emitResourceJobs(ctx, s.outbox, s.logger, "Patient", patient.ID, ActionCreate)
 	s.logger.WithContext(ctx).WithField("patient_id", patient.ID).Info("Patient created successfully")
 	return patient, nil
 }
@@ -86,6 +117,54 @@ func (s *PatientService) GetPatient(ctx context.Context, id uuid.UUID) (*models.
 	return patient, nil
 }
 
+// FindPatientByIdentifier returns the patient holding the business identifier
+// system|value, or models.ErrPatientNotFound. Identifiers are unique per tenant.
+func (s *PatientService) FindPatientByIdentifier(ctx context.Context, system, value string) (*models.Patient, error) {
+	// The identifier search matches values only, so candidates are narrowed by system here
+	patients, _, err := s.repo.List(ctx, repository.PatientSearchParams{Identifier: value}, repository.ValidatePaginationParams(100, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find patient by identifier: %w", err)
+	}
+	for _, patient := range patients {
+		for _, key := range repository.PatientIdentifierKeys(patient) {
+			if key.System == system && key.Value == value {
+				return patient, nil
+			}
+		}
+	}
+	return nil, models.ErrPatientNotFound
+}
+
+// ReplaceIdentifier sets the patient's identifier in system to value, replacing
+// any it holds in system, such as an enterprise identifier assigned by an EMPI.
+// It fails with a models.IdentifierConflictError when another patient holds it.
+func (s *PatientService) ReplaceIdentifier(ctx context.Context, id uuid.UUID, system, value string) (*models.Patient, error) {
+	patient, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing patient: %w", err)
+	}
+
+	identifiers := []models.Identifier{{System: &system, Value: &value}}
+	for _, identifier := range patient.Identifier {
+		if identifier.System == nil || *identifier.System != system {
+			identifiers = append(identifiers, identifier)
+		}
+	}
+	patient.Identifier = identifiers
+	patient.Meta = quality.Tag(patient.Meta, quality.PatientIssues(patient))
+
+	if err := s.repo.Update(ctx, patient); err != nil {
+		return nil, fmt.Errorf("failed to update patient: %w", err)
+	}
+
+	emitResourceJobs(ctx, s.outbox, s.logger, "Patient", id, ActionUpdate)
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"patient_id": id,
+		"system":     system,
+	}).Info("Patient identifier replaced")
+	return patient, nil
+}
+
 func (s *PatientService) UpdatePatient(ctx context.Context, id uuid.UUID, req *models.PatientUpdateRequest) (*models.Patient, error) {
 	s.logger.WithContext(ctx).WithField("patient_id", id).Info("Updating patient")
 
@@ -133,6 +212,9 @@ func (s *PatientService) UpdatePatient(ctx context.Context, id uuid.UUID, req *m
 		existingPatient.MultipleBirthInteger = req.MultipleBirthInteger
 	}
 	if req.Photo != nil {
+		if err := s.offloadPhotos(ctx, req.Photo); err != nil {
+			return nil, err
+		}
 		existingPatient.Photo = req.Photo
 	}
 	if req.Contact != nil {
@@ -151,12 +233,15 @@ func (s *PatientService) UpdatePatient(ctx context.Context, id uuid.UUID, req *m
 		existingPatient.Link = req.Link
 	}
 
+	existingPatient.Meta = quality.Tag(existingPatient.Meta, quality.PatientIssues(existingPatient))
+
 	// Update in repository
 	if err := s.repo.Update(ctx, existingPatient); err != nil {
 		s.logger.WithContext(ctx).WithError(err).WithField("patient_id", id).Error("Failed to update patient")
 		return nil, fmt.Errorf("failed to update patient: %w", err)
 	}
 
+	emitResourceJobs(ctx, s.outbox, s.logger, "Patient", id, ActionUpdate)
 	s.logger.WithContext(ctx).WithField("patient_id", id).Info("Patient updated successfully")
 	return existingPatient, nil
 }
@@ -169,11 +254,27 @@ func (s *PatientService) DeletePatient(ctx context.Context, id uuid.UUID) error
 		return fmt.Errorf("failed to delete patient: %w", err)
 	}
 
+	emitResourceJobs(ctx, s.outbox, s.logger, "Patient", id, ActionDelete)
 	s.logger.WithContext(ctx).WithField("patient_id", id).Info("Patient deleted successfully")
 	return nil
 }
 
-func (s *PatientService) ListPatients(ctx context.Context, limit, offset int) (*models.PatientListResponse, error) {
+// RestorePatient restores a soft-deleted patient
+func (s *PatientService) RestorePatient(ctx context.Context, id uuid.UUID) (*models.Patient, error) {
+	s.logger.WithContext(ctx).WithField("patient_id", id).Info("Restoring patient")
+
+	patient, err := s.repo.Restore(ctx, id)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("patient_id", id).Error("Failed to restore patient")
+		return nil, fmt.Errorf("failed to restore patient: %w", err)
+	}
+
+	emitResourceJobs(ctx, s.outbox, s.logger, "Patient", id, ActionRestore)
+	s.logger.WithContext(ctx).WithField("patient_id", id).Info("Patient restored successfully")
+	return patient, nil
+}
+
+func (s *PatientService) ListPatients(ctx context.Context, search repository.PatientSearchParams, limit, offset int) (*models.PatientListResponse, error) {
 	s.logger.WithContext(ctx).WithFields(logrus.Fields{
 		"limit":  limit,
 		"offset": offset,
@@ -182,7 +283,7 @@ func (s *PatientService) ListPatients(ctx context.Context, limit, offset int) (*
 	// Validate and set pagination parameters
 	params := repository.ValidatePaginationParams(limit, offset)
 
-	patients, pagination, err := s.repo.List(ctx, params)
+	patients, pagination, err := s.repo.List(ctx, search, params)
 	if err != nil {
 		s.logger.WithContext(ctx).WithError(err).Error("Failed to list patients")
 		return nil, fmt.Errorf("failed to list patients: %w", err)
@@ -208,11 +309,24 @@ func (s *PatientService) ListPatients(ctx context.Context, limit, offset int) (*
 		Entry:        entries,
 	}
 
+	// Carry search filters through to pagination links
+	query := url.Values{}
+	if search.Family != "" {
+		query.Set("family", search.Family)
+	}
+	if search.Identifier != "" {
+		query.Set("identifier", search.Identifier)
+	}
+	filters := ""
+	if len(query) > 0 {
+		filters = "&" + query.Encode()
+	}
+
 	// Add pagination links
 	if pagination.HasNext {
 		response.Link = append(response.Link, models.BundleLink{
 			Relation: "next",
-			URL:      fmt.Sprintf("/api/v1/patients?limit=%d&offset=%d", params.Limit, params.Offset+params.Limit),
+			URL:      fmt.Sprintf("/api/v1/patients?limit=%d&offset=%d%s", params.Limit, params.Offset+params.Limit, filters),
 		})
 	}
 
@@ -223,10 +337,131 @@ func (s *PatientService) ListPatients(ctx context.Context, limit, offset int) (*
 		}
 		response.Link = append(response.Link, models.BundleLink{
 			Relation: "prev",
-			URL:      fmt.Sprintf("/api/v1/patients?limit=%d&offset=%d", params.Limit, prevOffset),
+			URL:      fmt.Sprintf("/api/v1/patients?limit=%d&offset=%d%s", params.Limit, prevOffset, filters),
 		})
 	}
 
 	s.logger.WithContext(ctx).WithField("total", pagination.Total).Info("Patients listed successfully")
 	return response, nil
 }
+
+// SmartSearchPatients performs fuzzy demographic lookup ranked by relevance
+func (s *PatientService) SmartSearchPatients(ctx context.Context, text string, limit, offset int) (*models.PatientListResponse, error) {
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"limit":  limit,
+		"offset": offset,
+	}).Info("Smart searching patients")
+
+	params := repository.ValidatePaginationParams(limit, offset)
+
+	var matches []*repository.PatientMatch
+	var pagination repository.PaginationResult
+	var err error
+	if s.index != nil {
+		matches, pagination, err = s.searchIndex(ctx, text, params)
+	} else {
+		matches, pagination, err = s.repo.SmartSearch(ctx, text, params)
+	}
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to search patients")
+		return nil, fmt.Errorf("failed to search patients: %w", err)
+	}
+
+	entries := make([]models.PatientEntry, len(matches))
+	for i, match := range matches {
+		score := match.Score
+		entries[i] = models.PatientEntry{
+			FullURL:  fmt.Sprintf("/api/v1/patients/%s", match.Patient.ID),
+			Resource: match.Patient,
+			Search: &models.SearchEntry{
+				Mode:  "match",
+				Score: &score,
+			},
+		}
+	}
+
+	response := &models.PatientListResponse{
+		ResourceType: "Bundle",
+		ID:           uuid.New().String(),
+		Type:         "searchset",
+		Total:        pagination.Total,
+		Entry:        entries,
+	}
+
+	filters := "&" + url.Values{"_query": {"smart"}, "text": {text}}.Encode()
+	if pagination.HasNext {
+		response.Link = append(response.Link, models.BundleLink{
+			Relation: "next",
+			URL:      fmt.Sprintf("/api/v1/patients?limit=%d&offset=%d%s", params.Limit, params.Offset+params.Limit, filters),
+		})
+	}
+
+	s.logger.WithContext(ctx).WithField("total", pagination.Total).Info("Patient search completed")
+	return response, nil
+}
+
+// searchIndex runs a smart search against the search index, reading the
+// matching patients from the repository. Hits the index has but the
+// repository no longer does, not yet reindexed after a delete, are left out.
+func (s *PatientService) searchIndex(ctx context.Context, text string, params repository.PaginationParams) ([]*repository.PatientMatch, repository.PaginationResult, error) {
+	result, err := s.index.SearchPatients(ctx, requestctx.TenantID(ctx), search.Query{Text: text, Offset: params.Offset, Limit: params.Limit})
+	if err != nil {
+		return nil, repository.PaginationResult{}, err
+	}
+	pagination := repository.GetPaginationResult(result.Total, params)
+	if len(result.Hits) == 0 {
+		return nil, pagination, nil
+	}
+
+	ids := make([]uuid.UUID, len(result.Hits))
+	for i, hit := range result.Hits {
+		ids[i] = hit.ID
+	}
+	patients, _, err := s.repo.List(ctx, repository.PatientSearchParams{IDs: ids}, repository.PaginationParams{Limit: len(ids)})
+	if err != nil {
+		return nil, repository.PaginationResult{}, err
+	}
+	byID := make(map[uuid.UUID]*models.Patient, len(patients))
+	for _, patient := range patients {
+		byID[patient.ID] = patient
+	}
+
+	matches := make([]*repository.PatientMatch, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		if patient, ok := byID[hit.ID]; ok {
+			matches = append(matches, &repository.PatientMatch{Patient: patient, Score: hit.Score})
+		}
+	}
+	return matches, pagination, nil
+}
+
+// IndexPatient brings the patient's search index document in line with the
+// stored patient, removing it once the patient is deleted. It does nothing
+// without a search index.
+func (s *PatientService) IndexPatient(ctx context.Context, id uuid.UUID) error {
+	if s.index == nil {
+		return nil
+	}
+	tenantID := requestctx.TenantID(ctx)
+
+	patient, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, models.ErrPatientNotFound) || errors.Is(err, models.ErrResourceDeleted) {
+		return s.index.Remove(ctx, tenantID, "Patient", id)
+	}
+	if err != nil {
+		return err
+	}
+	return s.index.IndexPatients(ctx, tenantID, []*models.Patient{patient})
+}
+
+// offloadPhotos moves inline photo data to the object store, when attachments are configured
+func (s *PatientService) offloadPhotos(ctx context.Context, photos []models.Attachment) error {
+	if s.attachments == nil {
+		return nil
+	}
+	if err := s.attachments.Offload(ctx, photos); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to store patient photo")
+		return fmt.Errorf("failed to store patient photo: %w", err)
+	}
+	return nil
+}
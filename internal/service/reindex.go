@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/concurrent"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/search"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// reindexPageSize is how many rows are read at a time
+	reindexPageSize = 1000
+	// reindexBatchSize is how many rows are updated per transaction
+	reindexBatchSize = 100
+	// reindexWorkers bounds the transactions of one page running at once
+	reindexWorkers = 4
+	// reindexBatchTimeout bounds a single batch transaction
+	reindexBatchTimeout = time.Minute
+)
+
+// ReindexResourceTypes lists the resource types with extracted search columns
+var ReindexResourceTypes = []string{"Patient", "Observation"}
+
+// ReindexReport summarises a reindex run
+type ReindexReport struct {
+	ResourceTypes []string  `json:"resource_types"`
+	Tenants       int       `json:"tenants"`
+	Patients      int       `json:"patients"`
+	Observations  int       `json:"observations"`
+	StartedAt     time.Time `json:"started_at"`
+	CompletedAt   time.Time `json:"completed_at"`
+}
+
+// ReindexProgress is told how many rows are done out of the total after each page
+type ReindexProgress func(done, total int)
+
+// ReindexService rebuilds the search columns extracted from stored resources,
+// e.g. after the extraction rules change, and the search index when there is one
+type ReindexService struct {
+	patients     *repository.PatientRepository
+	observations *repository.ObservationRepository
+	tenants      *repository.TenantRepository
+	index        search.Index
+	logger       *logrus.Logger
+}
+
+func NewReindexService(patients *repository.PatientRepository, observations *repository.ObservationRepository, tenants *repository.TenantRepository, logger *logrus.Logger) *ReindexService {
+	return &ReindexService{
+		patients:     patients,
+		observations: observations,
+		tenants:      tenants,
+		logger:       logger,
+	}
+}
+
+// SetSearchIndex makes reindex runs also write every resource to the search
+// index, e.g. to fill a new index from existing data
+func (s *ReindexService) SetSearchIndex(index search.Index) {
+	s.index = index
+}
+
+// Reindex rebuilds the search columns of resourceTypes (all when empty) for one
+// tenant, or for every tenant when tenantID is empty. Rows are updated in place
+// without new versions, so it is safe to run again after a failure.
+func (s *ReindexService) Reindex(ctx context.Context, tenantID string, resourceTypes []string, progress ReindexProgress) (*ReindexReport, error) {
+	if len(resourceTypes) == 0 {
+		resourceTypes = ReindexResourceTypes
+	}
+	for _, resourceType := range resourceTypes {
+		if resourceType != "Patient" && resourceType != "Observation" {
+			return nil, fmt.Errorf("%w reindex resource type %q", models.ErrUnsupported, resourceType)
+		}
+	}
+
+	tenantIDs := []string{tenantID}
+	if tenantID == "" {
+		tenants, err := s.tenants.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tenants: %w", err)
+		}
+		tenantIDs = tenantIDs[:0]
+		for _, tenant := range tenants {
+			tenantIDs = append(tenantIDs, tenant.ID)
+		}
+	}
+
+	logger := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"resource_types": resourceTypes,
+		"tenants":        len(tenantIDs),
+	})
+	logger.Info("Reindexing search columns")
+
+	report := &ReindexReport{
+		ResourceTypes: resourceTypes,
+		Tenants:       len(tenantIDs),
+		StartedAt:     time.Now().UTC(),
+	}
+
+	// Count first so progress can be reported as a share of the whole run
+	total := 0
+	for _, id := range tenantIDs {
+		tenantCtx := requestctx.WithTenantID(ctx, id)
+		for _, resourceType := range resourceTypes {
+			count, err := s.count(tenantCtx, resourceType)
+			if err != nil {
+				return nil, err
+			}
+			total += count
+		}
+	}
+
+	done := 0
+	advance := func(n int) {
+		done += n
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+
+	for _, id := range tenantIDs {
+		tenantCtx := requestctx.WithTenantID(ctx, id)
+		for _, resourceType := range resourceTypes {
+			var err error
+			switch resourceType {
+			case "Patient":
+				var count int
+				count, err = reindexPages(tenantCtx, s.logger, s.patients.ReindexPage, s.updatePatients, func(patient *models.Patient) repository.ReindexCursor {
+					return repository.ReindexCursor{CreatedAt: patient.CreatedAt, ID: patient.ID}
+				}, advance)
+				report.Patients += count
+			case "Observation":
+				var count int
+				count, err = reindexPages(tenantCtx, s.logger, s.observations.ReindexPage, s.updateObservations, func(observation *models.Observation) repository.ReindexCursor {
+					return repository.ReindexCursor{CreatedAt: observation.CreatedAt, ID: observation.ID}
+				}, advance)
+				report.Observations += count
+			}
+			if err != nil {
+				logger.WithError(err).WithField("tenant_id", id).Error("Failed to reindex search columns")
+				return nil, fmt.Errorf("failed to reindex %s for tenant %s: %w", resourceType, id, err)
+			}
+		}
+	}
+
+	report.CompletedAt = time.Now().UTC()
+	logger.WithFields(logrus.Fields{
+		"patients":     report.Patients,
+		"observations": report.Observations,
+	}).Info("Search columns reindexed")
+	return report, nil
+}
+
+// updatePatients rebuilds the search columns of a batch of patients and their
+// search index documents
+func (s *ReindexService) updatePatients(ctx context.Context, patients []*models.Patient) error {
+	if err := s.patients.UpdateSearchColumns(ctx, patients); err != nil {
+		return err
+	}
+	if s.index == nil {
+		return nil
+	}
+	return s.index.IndexPatients(ctx, requestctx.TenantID(ctx), patients)
+}
+
+// updateObservations rebuilds the search columns of a batch of observations
+// and their search index documents
+func (s *ReindexService) updateObservations(ctx context.Context, observations []*models.Observation) error {
+	if err := s.observations.UpdateSearchColumns(ctx, observations); err != nil {
+		return err
+	}
+	if s.index == nil {
+		return nil
+	}
+	return s.index.IndexObservations(ctx, requestctx.TenantID(ctx), observations)
+}
+
+// count returns the number of rows of resourceType in the context's tenant
+func (s *ReindexService) count(ctx context.Context, resourceType string) (int, error) {
+	if resourceType == "Patient" {
+		return s.patients.Count(ctx)
+	}
+	return s.observations.Count(ctx)
+}
+
+// reindexPages walks every row a page at a time, updating each page in concurrent
+// batches, and returns the number of rows updated
+func reindexPages[T any](
+	ctx context.Context,
+	logger *logrus.Logger,
+	page func(ctx context.Context, after repository.ReindexCursor, limit int) ([]T, error),
+	update func(ctx context.Context, batch []T) error,
+	cursorOf func(T) repository.ReindexCursor,
+	advance func(n int),
+) (int, error) {
+	processor := concurrent.NewBatchProcessor[T](reindexBatchSize, reindexWorkers, reindexBatchTimeout, update, logger)
+
+	var cursor repository.ReindexCursor
+	count := 0
+	for {
+		items, err := page(ctx, cursor, reindexPageSize)
+		if err != nil {
+			return count, err
+		}
+		if len(items) == 0 {
+			return count, nil
+		}
+
+		if err := processor.Process(ctx, items); err != nil {
+			return count, err
+		}
+		count += len(items)
+		advance(len(items))
+
+		if len(items) < reindexPageSize {
+			return count, nil
+		}
+		cursor = cursorOf(items[len(items)-1])
+	}
+}
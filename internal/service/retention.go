@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RetentionReport summarises a retention run
+type RetentionReport struct {
+	DryRun      bool                  `json:"dry_run"`
+	StartedAt   time.Time             `json:"started_at"`
+	CompletedAt time.Time             `json:"completed_at"`
+	Items       []RetentionReportItem `json:"items"`
+}
+
+// RetentionReportItem covers one policy target for one resource type
+type RetentionReportItem struct {
+	Target        string    `json:"target"` // audit_logs or deleted_resources
+	ResourceType  string    `json:"resource_type"`
+	RetentionDays int       `json:"retention_days"`
+	Cutoff        time.Time `json:"cutoff"`
+	Eligible      int64     `json:"eligible"`
+	Archived      int64     `json:"archived"`
+}
+
+type RetentionService struct {
+	repo   *repository.RetentionRepository
+	cfg    config.RetentionConfig
+	logger *logrus.Logger
+}
+
+func NewRetentionService(repo *repository.RetentionRepository, cfg config.RetentionConfig, logger *logrus.Logger) *RetentionService {
+	return &RetentionService{
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// DefaultDryRun reports whether scheduled runs only report without purging
+func (s *RetentionService) DefaultDryRun() bool {
+	return s.cfg.DryRun
+}
+
+// Run applies the retention policies. Eligible rows are archived, then purged
+// from the live tables; in dry-run mode only the eligible counts are reported.
+func (s *RetentionService) Run(ctx context.Context, dryRun bool) (*RetentionReport, error) {
+	logger := s.logger.WithContext(ctx).WithField("dry_run", dryRun)
+	logger.Info("Running retention policies")
+
+	now := time.Now().UTC()
+	report := &RetentionReport{
+		DryRun:    dryRun,
+		StartedAt: now,
+		Items:     []RetentionReportItem{},
+	}
+
+	for _, resourceType := range repository.RetentionResourceTypes() {
+		days := retentionDays(s.cfg.AuditLogDays, s.cfg.AuditLogOverrides, resourceType)
+		item, err := s.apply(ctx, "audit_logs", resourceType, days, now, dryRun,
+			s.repo.CountExpiredAuditLogs, s.repo.ArchiveAuditLogs)
+		if err != nil {
+			return nil, err
+		}
+		report.Items = append(report.Items, item)
+
+		days = retentionDays(s.cfg.DeletedResourceDays, s.cfg.DeletedResourceOverrides, resourceType)
+		item, err = s.apply(ctx, "deleted_resources", resourceType, days, now, dryRun,
+			s.repo.CountExpiredDeletedResources, s.repo.ArchiveDeletedResources)
+		if err != nil {
+			return nil, err
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	report.CompletedAt = time.Now().UTC()
+
+	for _, item := range report.Items {
+		logger.WithFields(logrus.Fields{
+			"target":        item.Target,
+			"resource_type": item.ResourceType,
+			"eligible":      item.Eligible,
+			"archived":      item.Archived,
+		}).Info("Retention policy applied")
+	}
+
+	return report, nil
+}
+
+// apply counts and, unless dryRun, archives rows in batches for one target and resource type
+func (s *RetentionService) apply(
+	ctx context.Context,
+	target, resourceType string,
+	days int,
+	now time.Time,
+	dryRun bool,
+	count func(context.Context, string, time.Time) (int64, error),
+	archive func(context.Context, string, time.Time, int) (int64, error),
+) (RetentionReportItem, error) {
+	item := RetentionReportItem{
+		Target:        target,
+		ResourceType:  resourceType,
+		RetentionDays: days,
+	}
+
+	// A non-positive retention period keeps data forever
+	if days <= 0 {
+		return item, nil
+	}
+	item.Cutoff = now.AddDate(0, 0, -days)
+
+	eligible, err := count(ctx, resourceType, item.Cutoff)
+	if err != nil {
+		return item, fmt.Errorf("failed to apply %s retention for %s: %w", target, resourceType, err)
+	}
+	item.Eligible = eligible
+
+	if dryRun || eligible == 0 {
+		return item, nil
+	}
+
+	batchSize := s.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	for {
+		archived, err := archive(ctx, resourceType, item.Cutoff, batchSize)
+		if err != nil {
+			return item, fmt.Errorf("failed to apply %s retention for %s: %w", target, resourceType, err)
+		}
+		item.Archived += archived
+		if archived < int64(batchSize) {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return item, err
+		}
+	}
+
+	return item, nil
+}
+
+// retentionDays returns the override for resourceType if one is configured
+func retentionDays(defaultDays int, overrides map[string]int, resourceType string) int {
+	if days, ok := overrides[resourceType]; ok {
+		return days
+	}
+	return defaultDays
+}
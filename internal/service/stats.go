@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/sirupsen/logrus"
+)
+
+// statsTopCodes bounds the observation codes counted in the statistics
+const statsTopCodes = 50
+
+// StatsCache holds computed statistics until their TTL passes. The shared
+// Redis cache and federation.MemoryCache both implement it.
+type StatsCache interface {
+	GetUpstream(ctx context.Context, key string) ([]byte, bool)
+	SetUpstream(ctx context.Context, key string, data []byte, ttl time.Duration)
+}
+
+// StatsService computes the aggregate statistics of the operational
+// dashboard. Every count is a grouped query, and the result is cached per
+// tenant and period, so a dashboard polling it does not rescan the tables.
+type StatsService struct {
+	repo   *repository.StatsRepository
+	cache  StatsCache
+	ttl    time.Duration
+	logger *logrus.Logger
+}
+
+func NewStatsService(repo *repository.StatsRepository, cache StatsCache, cfg config.StatsConfig, logger *logrus.Logger) *StatsService {
+	return &StatsService{
+		repo:   repo,
+		cache:  cache,
+		ttl:    time.Duration(cfg.CacheTTL) * time.Second,
+		logger: logger,
+	}
+}
+
+// GetStats returns the statistics of the tenant, with the monthly counts
+// covering the current UTC month and the months-1 before it
+func (s *StatsService) GetStats(ctx context.Context, months int) (*models.Stats, error) {
+	key := "stats:" + requestctx.TenantID(ctx) + ":" + strconv.Itoa(months)
+	if s.ttl > 0 {
+		if data, ok := s.cache.GetUpstream(ctx, key); ok {
+			var stats models.Stats
+			if err := json.Unmarshal(data, &stats); err == nil {
+				return &stats, nil
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1-months, 0)
+
+	stats := &models.Stats{GeneratedAt: now}
+	var err error
+	if stats.Resources, err = s.repo.ResourceCounts(ctx); err != nil {
+		return nil, err
+	}
+	if stats.ObservationsByCode, err = s.repo.ObservationCodes(ctx, statsTopCodes); err != nil {
+		return nil, err
+	}
+	if stats.ObservationsByCategory, err = s.repo.ObservationCategories(ctx); err != nil {
+		return nil, err
+	}
+	observations, err := s.repo.ObservationMonths(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	stats.ObservationsByMonth = fillMonths(since, months, observations)
+	registrations, err := s.repo.PatientRegistrations(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	stats.Registrations = fillMonths(since, months, registrations)
+
+	if stats.ObservationsByCode == nil {
+		stats.ObservationsByCode = []models.CodeCount{}
+	}
+	if stats.ObservationsByCategory == nil {
+		stats.ObservationsByCategory = []models.CodeCount{}
+	}
+
+	if s.ttl > 0 {
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode stats: %w", err)
+		}
+		s.cache.SetUpstream(ctx, key, data, s.ttl)
+	}
+
+	s.logger.WithContext(ctx).WithField("months", months).Debug("Stats computed")
+	return stats, nil
+}
+
+// fillMonths returns a count for each of the months from since, which is the
+// start of a month, taking zero for the months missing from counts
+func fillMonths(since time.Time, months int, counts []models.MonthCount) []models.MonthCount {
+	byMonth := make(map[int64]int64, len(counts))
+	for _, count := range counts {
+		byMonth[count.Month.Unix()] = count.Count
+	}
+
+	filled := make([]models.MonthCount, months)
+	for i := range filled {
+		month := since.AddDate(0, i, 0)
+		filled[i] = models.MonthCount{Month: month, Count: byMonth[month.Unix()]}
+	}
+	return filled
+}
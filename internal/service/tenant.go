@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"healthcare-api/internal/concurrent"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Default per-tenant rate limits for newly provisioned tenants
+const (
+	defaultTenantRateLimitPerMinute = 6000
+	defaultTenantRateLimitBurst     = 100
+)
+
+type TenantService struct {
+	repo   repository.TenantStore
+	cache  *concurrent.ConcurrentCache[string, *models.Tenant]
+	logger *logrus.Logger
+}
+
+func NewTenantService(repo repository.TenantStore, logger *logrus.Logger) *TenantService {
+	return &TenantService{
+		repo:   repo,
+		cache:  concurrent.NewConcurrentCache[string, *models.Tenant](30 * time.Second),
+		logger: logger,
+	}
+}
+
+func (s *TenantService) CreateTenant(ctx context.Context, req *models.TenantCreateRequest) (*models.Tenant, error) {
+	s.logger.WithContext(ctx).WithField("tenant_id", req.ID).Info("Provisioning tenant")
+
+	tenant := &models.Tenant{
+		ID:                 req.ID,
+		Name:               req.Name,
+		Status:             models.TenantStatusActive,
+		RateLimitPerMinute: defaultTenantRateLimitPerMinute,
+		RateLimitBurst:     defaultTenantRateLimitBurst,
+	}
+	if req.RateLimitPerMinute != nil {
+		tenant.RateLimitPerMinute = *req.RateLimitPerMinute
+	}
+	if req.RateLimitBurst != nil {
+		tenant.RateLimitBurst = *req.RateLimitBurst
+	}
+
+	if err := s.repo.Create(ctx, tenant); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to create tenant")
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+
+	return tenant, nil
+}
+
+func (s *TenantService) GetTenant(ctx context.Context, id string) (*models.Tenant, error) {
+	tenant, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	return tenant, nil
+}
+
+// ResolveTenant returns the tenant for an incoming request, served from a short-lived cache
+func (s *TenantService) ResolveTenant(ctx context.Context, id string) (*models.Tenant, error) {
+	if tenant, ok := s.cache.Get(id); ok {
+		return tenant, nil
+	}
+
+	tenant, err := s.GetTenant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Set(id, tenant)
+	return tenant, nil
+}
+
+func (s *TenantService) UpdateTenant(ctx context.Context, id string, req *models.TenantUpdateRequest) (*models.Tenant, error) {
+	s.logger.WithContext(ctx).WithField("tenant_id", id).Info("Updating tenant")
+
+	tenant, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	if req.Name != nil {
+		tenant.Name = *req.Name
+	}
+	if req.Status != nil {
+		tenant.Status = *req.Status
+	}
+	if req.RateLimitPerMinute != nil {
+		tenant.RateLimitPerMinute = *req.RateLimitPerMinute
+	}
+	if req.RateLimitBurst != nil {
+		tenant.RateLimitBurst = *req.RateLimitBurst
+	}
+
+	if err := s.repo.Update(ctx, tenant); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to update tenant")
+		return nil, fmt.Errorf("failed to update tenant: %w", err)
+	}
+
+	s.cache.Delete(id)
+	return tenant, nil
+}
+
+// WarmCache loads every tenant into the resolution cache so the first requests
+// after startup or cache expiry do not each hit the database
+func (s *TenantService) WarmCache(ctx context.Context) (int, error) {
+	tenants, err := s.repo.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	for _, tenant := range tenants {
+		s.cache.Set(tenant.ID, tenant)
+	}
+
+	return len(tenants), nil
+}
+
+func (s *TenantService) ListTenants(ctx context.Context) ([]*models.Tenant, error) {
+	tenants, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	return tenants, nil
+}
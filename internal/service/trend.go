@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Limits of observation trends
+const (
+	// MaxTrendPeriod bounds how far back a trend reaches
+	MaxTrendPeriod = 10 * 365 * 24 * time.Hour
+	// MaxTrendPoints bounds the buckets of a trend's period
+	MaxTrendPoints = 1000
+)
+
+// trendBuckets are the bucket widths a trend picks from when none is given,
+// narrowest first
+var trendBuckets = []time.Duration{
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+}
+
+// defaultTrendPoints is the most buckets a trend's period gets when no bucket is given
+const defaultTrendPoints = 200
+
+// ObservationTrend summarizes a subject's results for a code over the period
+// up to now, into buckets of width bucket computed by the database. A zero
+// bucket picks the narrowest of an hour, 6 hours, a day, a week or 30 days
+// that keeps the period to 200 buckets.
+func (s *ObservationService) ObservationTrend(ctx context.Context, subject, code string, period, bucket time.Duration) (*models.ObservationTrend, error) {
+	if bucket == 0 {
+		bucket = trendBucket(period)
+	}
+	end := time.Now().UTC()
+	start := end.Add(-period)
+
+	buckets, err := s.repo.Trend(ctx, repository.TrendQuery{
+		Subject: subject,
+		Code:    code,
+		From:    start,
+		To:      end,
+		Bucket:  bucket,
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to compute observation trend")
+		return nil, fmt.Errorf("failed to compute observation trend: %w", err)
+	}
+
+	trend := &models.ObservationTrend{
+		Subject: subject,
+		Code:    code,
+		Period:  FormatTrendDuration(period),
+		Bucket:  FormatTrendDuration(bucket),
+		Start:   start,
+		End:     end,
+		Series:  []models.TrendSeries{},
+	}
+	for _, b := range buckets {
+		last := len(trend.Series) - 1
+		if last < 0 || trend.Series[last].Unit != b.Unit {
+			trend.Series = append(trend.Series, models.TrendSeries{Unit: b.Unit})
+			last++
+		}
+		trend.Series[last].Points = append(trend.Series[last].Points, models.TrendPoint{
+			Start: b.Start,
+			Count: b.Count,
+			Avg:   b.Avg,
+			Min:   b.Min,
+			Max:   b.Max,
+		})
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"code":    code,
+		"buckets": len(buckets),
+	}).Info("Observation trend computed")
+	return trend, nil
+}
+
+// trendBucket returns the bucket width for a period when none is given
+func trendBucket(period time.Duration) time.Duration {
+	for _, bucket := range trendBuckets {
+		if period/bucket <= defaultTrendPoints {
+			return bucket
+		}
+	}
+	return trendBuckets[len(trendBuckets)-1]
+}
+
+// trendUnits are the units of trend durations, largest first
+var trendUnits = []struct {
+	suffix string
+	unit   time.Duration
+}{
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+}
+
+// ParseTrendDuration parses a trend period or bucket: a positive whole number
+// of minutes, hours, days or weeks, such as "15m", "6h", "90d" or "4w"
+func ParseTrendDuration(value string) (time.Duration, error) {
+	for _, u := range trendUnits {
+		digits, ok := strings.CutSuffix(value, u.suffix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil || n <= 0 || digits[0] == '+' {
+			break
+		}
+		if int64(n) > int64(MaxTrendPeriod/u.unit) {
+			return 0, fmt.Errorf("duration %q is too long", value)
+		}
+		return time.Duration(n) * u.unit, nil
+	}
+	return 0, fmt.Errorf("invalid duration %q: expected a number of minutes, hours, days or weeks, such as 90d", value)
+}
+
+// FormatTrendDuration formats a duration as ParseTrendDuration reads it, in
+// the largest unit it is a whole number of
+func FormatTrendDuration(d time.Duration) string {
+	for _, u := range trendUnits {
+		if d%u.unit == 0 {
+			return strconv.FormatInt(int64(d/u.unit), 10) + u.suffix
+		}
+	}
+	return d.String()
+}
@@ -0,0 +1,23 @@
+package service
+
+import "testing"
+
+func FuzzParseTrendDuration(f *testing.F) {
+	for _, seed := range []string{"90d", "15m", "6h", "4w", "0d", "-1d", "+1d", "1y", "d", "99999999999999w", ""} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		d, err := ParseTrendDuration(value)
+		if err != nil {
+			return
+		}
+		if d <= 0 || d > MaxTrendPeriod {
+			t.Fatalf("ParseTrendDuration(%q) = %v, outside (0, %v]", value, d, MaxTrendPeriod)
+		}
+		formatted := FormatTrendDuration(d)
+		if again, err := ParseTrendDuration(formatted); err != nil || again != d {
+			t.Fatalf("ParseTrendDuration(FormatTrendDuration(%v)) = %v, %v", d, again, err)
+		}
+	})
+}
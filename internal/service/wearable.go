@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/wearable"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// wearableObservationIDNamespace is the UUID namespace of the ids Ingest
+// derives from wearable readings
+var wearableObservationIDNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("urn:healthcare-api:wearable-observation"))
+
+// WearableService ingests batches of samples from consumer wearables as
+// observations. A batch is loaded with COPY in one transaction rather than an
+// insert per reading, and each reading's id is derived from its device, code
+// and start time, so samples sent again are skipped.
+//
+// Readings are stored without the background jobs of observations created
+// through the API: they are not sent to webhooks, event consumers or the search
+// index, and do not trigger critical result notifications.
+type WearableService struct {
+	observations repository.ObservationStore
+	patients     *PatientService
+	logger       *logrus.Logger
+}
+
+// NewWearableService creates a wearable service storing observations in
+// observations about patients found through patients
+func NewWearableService(observations repository.ObservationStore, patients *PatientService, logger *logrus.Logger) *WearableService {
+	return &WearableService{
+		observations: observations,
+		patients:     patients,
+		logger:       logger,
+	}
+}
+
+// Ingest stores the batch's samples as observations about the patient
+// patientID, skipping those already stored. A batch with a sample that cannot
+// be converted is rejected whole, with an error wrapping
+// wearable.ErrInvalidBatch.
+func (s *WearableService) Ingest(ctx context.Context, batch *wearable.Batch, patientID uuid.UUID) (*models.WearableIngestResponse, error) {
+	if _, err := s.patients.GetPatient(ctx, patientID); err != nil {
+		return nil, err
+	}
+
+	reference := "Patient/" + patientID.String()
+	readings, err := wearable.Readings(batch, models.Reference{Reference: &reference})
+	if err != nil {
+		return nil, err
+	}
+
+	observations := make([]*models.Observation, 0, len(readings))
+	seen := make(map[uuid.UUID]bool, len(readings))
+	for _, reading := range readings {
+		id := uuid.NewSHA1(wearableObservationIDNamespace, []byte(requestctx.TenantID(ctx)+"\x00"+reading.Key))
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		reading.Observation.ID = id
+		observations = append(observations, reading.Observation)
+	}
+
+	created, err := s.observations.BulkCreateNew(ctx, observations)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to store wearable observations")
+		return nil, fmt.Errorf("failed to store wearable observations: %w", err)
+	}
+
+	response := &models.WearableIngestResponse{
+		Received:   len(readings),
+		Created:    created,
+		Duplicates: len(readings) - created,
+	}
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"patient_id": patientID,
+		"received":   response.Received,
+		"created":    response.Created,
+	}).Info("Wearable samples ingested")
+	return response, nil
+}
@@ -0,0 +1,483 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/events"
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Webhook job types: a dispatch job per resource change finds the webhooks that
+// receive it and records a delivery job for each
+const (
+	WebhookDispatchJobType = "webhook_dispatch"
+	WebhookDeliveryJobType = "webhook_delivery"
+)
+
+// webhookSecretPrefix marks signing secrets, as in the Standard Webhooks specification
+const webhookSecretPrefix = "whsec_"
+
+// maxWebhookResponseBody bounds the part of a response kept in the delivery log
+const maxWebhookResponseBody = 1024
+
+// errPrivateAddress is returned when a webhook host resolves to a private address
+var errPrivateAddress = errors.New("webhook host resolves to a private or loopback address")
+
+// WebhookService manages the tenant's webhooks and delivers resource change
+// events to them. Deliveries are signed following the Standard Webhooks
+// specification, so receivers can verify them with its libraries.
+type WebhookService struct {
+	repo   *repository.WebhookRepository
+	outbox JobOutbox
+	cfg    config.WebhookConfig
+	client *http.Client
+	logger *logrus.Logger
+}
+
+func NewWebhookService(repo *repository.WebhookRepository, cfg config.WebhookConfig, logger *logrus.Logger) *WebhookService {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	dialer := &net.Dialer{Timeout: timeout}
+	if !cfg.AllowPrivateNetworks {
+		// Checked on the resolved address, so a public name pointing inside is refused too
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	return &WebhookService{
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				MaxIdleConnsPerHost:   4,
+				IdleConnTimeout:       90 * time.Second,
+			},
+			// A redirect could lead anywhere; endpoints must be registered as they are
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// SetOutbox makes the service record delivery jobs through outbox
+func (s *WebhookService) SetOutbox(outbox JobOutbox) {
+	s.outbox = outbox
+}
+
+// CreateWebhook registers a webhook with a new signing secret, returned only here
+func (s *WebhookService) CreateWebhook(ctx context.Context, req *models.WebhookCreateRequest) (*models.Webhook, error) {
+	if err := s.validateURL(req.URL); err != nil {
+		return nil, err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &models.Webhook{
+		ID:          uuid.New(),
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Active:      req.Active == nil || *req.Active,
+		Secret:      secret,
+	}
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+
+	if err := s.repo.Create(ctx, webhook); err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to create webhook")
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithField("webhook_id", webhook.ID).Info("Webhook created")
+	return webhook, nil
+}
+
+// GetWebhook returns a webhook without its secret
+func (s *WebhookService) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	webhook, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// ListWebhooks returns the tenant's webhooks without their secrets
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	webhooks, err := s.repo.List(ctx, false)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to list webhooks")
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook applies the fields set in req. The secret is returned only when
+// it is rotated.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id uuid.UUID, req *models.WebhookUpdateRequest) (*models.Webhook, error) {
+	webhook, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if err := s.validateURL(*req.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *req.URL
+	}
+	if req.Description != nil {
+		webhook.Description = req.Description
+	}
+	if req.Events != nil {
+		webhook.Events = *req.Events
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if req.RotateSecret {
+		if webhook.Secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Update(ctx, webhook); err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("webhook_id", id).Error("Failed to update webhook")
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"webhook_id":     id,
+		"secret_rotated": req.RotateSecret,
+	}).Info("Webhook updated")
+	if !req.RotateSecret {
+		webhook.Secret = ""
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log; deliveries still queued are dropped
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.logger.WithContext(ctx).WithField("webhook_id", id).Info("Webhook deleted")
+	return nil
+}
+
+// ListDeliveries returns a webhook's delivery attempts, most recent first
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID uuid.UUID, filter repository.WebhookDeliveryFilter, limit, offset int) ([]*models.WebhookDelivery, repository.PaginationResult, error) {
+	if _, err := s.repo.GetByID(ctx, webhookID); err != nil {
+		return nil, repository.PaginationResult{}, err
+	}
+
+	params := repository.ValidatePaginationParams(limit, offset)
+	deliveries, pagination, err := s.repo.ListDeliveries(ctx, webhookID, filter, params)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).WithField("webhook_id", webhookID).Error("Failed to list webhook deliveries")
+		return nil, repository.PaginationResult{}, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, pagination, nil
+}
+
+// Redeliver queues the event of an earlier delivery attempt for delivery again,
+// whether or not it was delivered, returning the event id
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID uuid.UUID, deliveryID int64) (string, error) {
+	if s.outbox == nil {
+		return "", errors.New("webhook deliveries are not enabled")
+	}
+	delivery, err := s.repo.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return "", err
+	}
+
+	var event events.Event
+	if err := json.Unmarshal(delivery.Payload, &event); err != nil {
+		return "", fmt.Errorf("failed to decode delivered event: %w", err)
+	}
+
+	err = s.outbox.Add(ctx, WebhookDeliveryJobType, models.WebhookDeliveryPayload{
+		WebhookID:  webhookID.String(),
+		Event:      eventPayload(event),
+		RequestID:  event.RequestID,
+		Redelivery: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to queue redelivery: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"webhook_id": webhookID,
+		"event_id":   event.ID,
+	}).Info("Webhook redelivery queued")
+	return event.ID, nil
+}
+
+// Dispatch records a delivery job for each active webhook of the tenant that
+// receives the event, returning how many were recorded. Dispatching an event
+// again records its deliveries again; Deliver skips those already made.
+func (s *WebhookService) Dispatch(ctx context.Context, event models.ResourceEventPayload) (int, error) {
+	if s.outbox == nil {
+		return 0, errors.New("webhook deliveries are not enabled")
+	}
+	webhooks, err := s.repo.List(ctx, true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	eventType := event.ResourceType + "." + event.Action
+	dispatched := 0
+	for _, webhook := range webhooks {
+		if !webhook.Matches(eventType) {
+			continue
+		}
+		err := s.outbox.Add(ctx, WebhookDeliveryJobType, models.WebhookDeliveryPayload{
+			WebhookID: webhook.ID.String(),
+			Event:     event,
+			RequestID: requestctx.RequestID(ctx),
+		})
+		if err != nil {
+			return dispatched, fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+		dispatched++
+	}
+	return dispatched, nil
+}
+
+// Deliver posts the event to the webhook and records the attempt, returning an
+// error when the endpoint did not accept it. Events for webhooks that have been
+// deleted or paused, and events already delivered, are skipped.
+func (s *WebhookService) Deliver(ctx context.Context, payload models.WebhookDeliveryPayload, attempt int) error {
+	webhookID, err := uuid.Parse(payload.WebhookID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid webhook id %q", models.ErrUnsupported, payload.WebhookID)
+	}
+	logger := s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"webhook_id": webhookID,
+		"event_id":   payload.Event.EventID,
+		"attempt":    attempt,
+	})
+
+	webhook, err := s.repo.GetByID(ctx, webhookID)
+	if errors.Is(err, models.ErrWebhookNotFound) {
+		logger.Info("Skipped delivery to a deleted webhook")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !webhook.Active {
+		logger.Info("Skipped delivery to a paused webhook")
+		return nil
+	}
+	if !payload.Redelivery {
+		delivered, err := s.repo.Delivered(ctx, webhookID, payload.Event.EventID)
+		if err != nil {
+			return err
+		}
+		if delivered {
+			logger.Info("Skipped delivery of an event already delivered")
+			return nil
+		}
+	}
+
+	body, err := json.Marshal(events.Event{
+		ID:           payload.Event.EventID,
+		TenantID:     requestctx.TenantID(ctx),
+		ResourceType: payload.Event.ResourceType,
+		ResourceID:   payload.Event.ResourceID,
+		Action:       payload.Event.Action,
+		UserID:       payload.Event.UserID,
+		RequestID:    payload.RequestID,
+		Time:         payload.Event.Timestamp,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	delivery := &models.WebhookDelivery{
+		WebhookID:   webhookID,
+		EventID:     payload.Event.EventID,
+		EventType:   payload.Event.ResourceType + "." + payload.Event.Action,
+		Payload:     body,
+		Attempt:     attempt,
+		URL:         webhook.URL,
+		DeliveredAt: time.Now().UTC(),
+	}
+	deliverErr := s.post(ctx, webhook, delivery)
+	delivery.DurationMS = time.Since(delivery.DeliveredAt).Milliseconds()
+	delivery.Success = deliverErr == nil
+	if deliverErr != nil {
+		message := deliverErr.Error()
+		delivery.Error = &message
+	}
+
+	if err := s.repo.AddDelivery(ctx, delivery); err != nil {
+		logger.WithError(err).Error("Failed to record webhook delivery")
+	}
+
+	if deliverErr != nil {
+		logger.WithError(deliverErr).Warn("Webhook delivery failed")
+		return deliverErr
+	}
+	logger.WithField("status_code", *delivery.StatusCode).Info("Webhook delivered")
+	return nil
+}
+
+// post sends the delivery's payload, signed, and reads the response into it
+func (s *WebhookService) post(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := signWebhook(webhook.Secret, delivery.EventID, timestamp, delivery.Payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "healthcare-api-webhooks/1.0")
+	req.Header.Set("webhook-id", delivery.EventID)
+	req.Header.Set("webhook-timestamp", timestamp)
+	req.Header.Set("webhook-signature", signature)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	delivery.StatusCode = &resp.StatusCode
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+	if len(head) > 0 {
+		text := strings.ToValidUTF8(string(head), "")
+		delivery.ResponseBody = &text
+	}
+	// Drain a little more so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook endpoint responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// PruneDeliveries deletes delivery attempts of every tenant older than retention
+func (s *WebhookService) PruneDeliveries(ctx context.Context, retention time.Duration) error {
+	cutoff := time.Now().UTC().Add(-retention)
+
+	deliveries, err := s.repo.PruneDeliveries(ctx, cutoff)
+	if err != nil {
+		s.logger.WithContext(ctx).WithError(err).Error("Failed to prune webhook deliveries")
+		return fmt.Errorf("failed to prune webhook deliveries: %w", err)
+	}
+
+	s.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"cutoff":     cutoff,
+		"deliveries": deliveries,
+	}).Info("Webhook deliveries pruned")
+	return nil
+}
+
+// validateURL checks that the server will deliver to rawURL under its configuration
+func (s *WebhookService) validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: must be an absolute URL", models.ErrInvalidWebhookURL)
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && s.cfg.AllowHTTP:
+	default:
+		return fmt.Errorf("%w: scheme must be https", models.ErrInvalidWebhookURL)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: must not contain credentials", models.ErrInvalidWebhookURL)
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !s.cfg.AllowPrivateNetworks && !publicIP(ip) {
+		return fmt.Errorf("%w: %v", models.ErrInvalidWebhookURL, errPrivateAddress)
+	}
+	return nil
+}
+
+// signWebhook signs a delivery following the Standard Webhooks specification:
+// "v1," and the base64 HMAC-SHA256 of "<id>.<timestamp>.<body>", keyed with
+// the decoded secret
+func signWebhook(secret, id, timestamp string, body []byte) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, webhookSecretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid webhook secret: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// newWebhookSecret returns a random 256-bit signing secret
+func newWebhookSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return webhookSecretPrefix + base64.StdEncoding.EncodeToString(key), nil
+}
+
+// publicIP reports whether ip is a globally routable unicast address
+func publicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
+
+// eventPayload converts a delivered event back into the payload it was built from
+func eventPayload(event events.Event) models.ResourceEventPayload {
+	return models.ResourceEventPayload{
+		EventID:      event.ID,
+		ResourceType: event.ResourceType,
+		ResourceID:   event.ResourceID,
+		Action:       event.Action,
+		UserID:       event.UserID,
+		Timestamp:    event.Time,
+	}
+}
@@ -0,0 +1,103 @@
+// Package shutdown runs the graceful shutdown of a process as a sequence of
+// stages, such as stopping intake, draining requests, draining background jobs
+// and closing connections. Each stage starts when the one before it has
+// finished, or when that one's timeout has passed, so dependencies are closed
+// only after what uses them and a stuck stage cannot hold up the rest.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sequence is an ordered list of shutdown stages
+type Sequence struct {
+	stages []stage
+	logger *logrus.Logger
+}
+
+type stage struct {
+	name    string
+	timeout time.Duration
+	steps   []func(ctx context.Context) error
+}
+
+// New creates an empty shutdown sequence
+func New(logger *logrus.Logger) *Sequence {
+	return &Sequence{logger: logger}
+}
+
+// Stage appends a stage that gets timeout to finish
+func (s *Sequence) Stage(name string, timeout time.Duration) {
+	s.stages = append(s.stages, stage{name: name, timeout: timeout})
+}
+
+// Add appends a step to the last stage. The steps of a stage run in the order
+// they were added, with the context of the stage, which is done at its
+// timeout.
+func (s *Sequence) Add(step func(ctx context.Context) error) {
+	if len(s.stages) == 0 {
+		panic("shutdown: step added before any stage")
+	}
+	last := &s.stages[len(s.stages)-1]
+	last.steps = append(last.steps, step)
+}
+
+// AddFunc appends a step that cannot fail or be cut short, such as a Stop
+// method bounded by its own timeout
+func (s *Sequence) AddFunc(step func()) {
+	s.Add(func(context.Context) error {
+		step()
+		return nil
+	})
+}
+
+// Run runs the stages in order and returns the errors of the steps that failed
+// or did not finish in time. A step still running at its stage's timeout is
+// left running, and the next stage starts.
+func (s *Sequence) Run() error {
+	var errs []error
+	for _, stage := range s.stages {
+		logger := s.logger.WithFields(logrus.Fields{
+			"stage":   stage.name,
+			"timeout": stage.timeout,
+		})
+		logger.Info("Shutdown stage started")
+		started := time.Now()
+
+		if err := stage.run(); err != nil {
+			logger.WithError(err).Error("Shutdown stage did not finish cleanly")
+			errs = append(errs, fmt.Errorf("%s: %w", stage.name, err))
+			continue
+		}
+		logger.WithField("duration", time.Since(started)).Info("Shutdown stage finished")
+	}
+	return errors.Join(errs...)
+}
+
+func (st stage) run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), st.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		var errs []error
+		for _, step := range st.steps {
+			if err := step(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		done <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", st.timeout)
+	}
+}
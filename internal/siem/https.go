@@ -0,0 +1,75 @@
+package siem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"healthcare-api/internal/config"
+)
+
+// HTTPSSender POSTs each batch to a collector as a JSON array of events. A 2xx
+// response takes the batch; other 4xx responses reject it, except those a
+// retry may get past: 401 and 403, until the token is fixed, 408 and 429.
+type HTTPSSender struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPSSender validates cfg and returns a sender to its collector
+func NewHTTPSSender(cfg config.SIEMConfig) (*HTTPSSender, error) {
+	collector, err := url.Parse(cfg.HTTPSURL)
+	if err != nil || collector.Scheme != "https" || collector.Host == "" {
+		return nil, fmt.Errorf("invalid SIEM collector URL %q: must be https", cfg.HTTPSURL)
+	}
+	return &HTTPSSender{
+		url:    cfg.HTTPSURL,
+		token:  cfg.HTTPSToken,
+		client: &http.Client{},
+	}, nil
+}
+
+func (s *HTTPSSender) Send(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach SIEM collector: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden,
+		resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("SIEM collector responded %d", resp.StatusCode)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return fmt.Errorf("%w: collector responded %d", ErrRejected, resp.StatusCode)
+	default:
+		return fmt.Errorf("SIEM collector responded %d", resp.StatusCode)
+	}
+}
+
+func (s *HTTPSSender) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
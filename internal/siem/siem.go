@@ -0,0 +1,268 @@
+// Package siem forwards the access log to a security information and event
+// management system, so security operations can watch API access in their own
+// tools instead of querying the database. Events are buffered in memory and
+// sent in batches, retried until the SIEM takes them.
+package siem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Supported transports
+const (
+	// TransportNone forwards nothing
+	TransportNone = "none"
+	// TransportSyslog sends RFC 5424 syslog messages carrying CEF events
+	TransportSyslog = "syslog"
+	// TransportHTTPS POSTs batches of JSON events to a collector
+	TransportHTTPS = "https"
+)
+
+// Retry delays of a batch the SIEM did not take, doubling from the first to the last
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// Event is an access log entry as sent to the SIEM. The request body is left
+// out; the query carries PHI redacted as in the access log.
+type Event struct {
+	Time       time.Time `json:"time"`
+	TenantID   string    `json:"tenantId,omitempty"`
+	RequestID  string    `json:"requestId,omitempty"`
+	UserID     string    `json:"userId,omitempty"`
+	IPAddress  string    `json:"ipAddress,omitempty"`
+	UserAgent  string    `json:"userAgent,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	StatusCode int       `json:"statusCode"`
+	DurationMS int64     `json:"durationMs"`
+}
+
+// AccessEvent returns the event of an access log entry
+func AccessEvent(entry *repository.AccessLog) Event {
+	event := Event{
+		Time:       entry.Timestamp,
+		Method:     entry.Method,
+		Path:       entry.Path,
+		StatusCode: entry.StatusCode,
+		DurationMS: entry.Duration.Milliseconds(),
+	}
+	for _, field := range []struct {
+		dest  *string
+		value *string
+	}{
+		{&event.TenantID, entry.TenantID},
+		{&event.RequestID, entry.RequestID},
+		{&event.UserID, entry.UserID},
+		{&event.IPAddress, entry.IPAddress},
+		{&event.UserAgent, entry.UserAgent},
+		{&event.Query, entry.Query},
+	} {
+		if field.value != nil {
+			*field.dest = *field.value
+		}
+	}
+	return event
+}
+
+// Sender delivers batches of events to a SIEM
+type Sender interface {
+	// Send returns once the SIEM has taken every event. Errors wrapping
+	// ErrRejected are not retried.
+	Send(ctx context.Context, events []Event) error
+	Close() error
+}
+
+// ErrRejected is wrapped by errors for batches the SIEM refused, which sending
+// again will not change
+var ErrRejected = errors.New("SIEM rejected events")
+
+// Exporter buffers events and sends them to the SIEM in the background. When
+// the SIEM cannot take them and the buffer fills, newer events are dropped and
+// counted in the log; the access log in the database stays complete.
+type Exporter struct {
+	sender        Sender
+	batchSize     int
+	flushInterval time.Duration
+	timeout       time.Duration
+	logger        *logrus.Logger
+
+	mu      sync.RWMutex
+	closed  bool
+	buffer  chan Event
+	dropped atomic.Int64
+
+	// stop abandons the batch being retried once Close gives up waiting
+	stop   context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New validates cfg and starts an exporter to the SIEM of cfg.Transport, or
+// returns nil for TransportNone
+func New(cfg config.SIEMConfig, logger *logrus.Logger) (*Exporter, error) {
+	var sender Sender
+	var err error
+	switch cfg.Transport {
+	case "", TransportNone:
+		return nil, nil
+	case TransportSyslog:
+		sender, err = NewSyslogSender(cfg)
+	case TransportHTTPS:
+		sender, err = NewHTTPSSender(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported SIEM transport: %s", cfg.Transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.BufferSize <= 0 || cfg.BatchSize <= 0 || cfg.BatchSize > cfg.BufferSize {
+		return nil, fmt.Errorf("invalid SIEM buffer size %d and batch size %d: both must be positive, the batch no larger", cfg.BufferSize, cfg.BatchSize)
+	}
+	if cfg.FlushInterval <= 0 {
+		return nil, fmt.Errorf("invalid SIEM flush interval %d: must be positive", cfg.FlushInterval)
+	}
+	if cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid SIEM timeout %d: must be positive", cfg.Timeout)
+	}
+
+	return NewExporter(sender, cfg, logger), nil
+}
+
+// NewExporter starts an exporter sending through sender
+func NewExporter(sender Sender, cfg config.SIEMConfig, logger *logrus.Logger) *Exporter {
+	stop, cancel := context.WithCancel(context.Background())
+	e := &Exporter{
+		sender:        sender,
+		batchSize:     cfg.BatchSize,
+		flushInterval: time.Duration(cfg.FlushInterval) * time.Second,
+		timeout:       time.Duration(cfg.Timeout) * time.Second,
+		logger:        logger,
+		buffer:        make(chan Event, cfg.BufferSize),
+		stop:          stop,
+		cancel:        cancel,
+		done:          make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Export queues an event without waiting, dropping it when the buffer is full
+func (e *Exporter) Export(event Event) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.buffer <- event:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// Close sends the buffered events and closes the sender. Events still unsent
+// when ctx is done are abandoned.
+func (e *Exporter) Close(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.buffer)
+	}
+	e.mu.Unlock()
+
+	var err error
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		e.cancel()
+		<-e.done
+		err = fmt.Errorf("abandoned unsent SIEM events: %w", ctx.Err())
+	}
+	e.cancel()
+	if closeErr := e.sender.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// run collects events into batches, sending each when full or when the flush
+// interval passes
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, e.batchSize)
+	for {
+		select {
+		case event, ok := <-e.buffer:
+			if !ok {
+				e.deliver(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) < e.batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		e.deliver(batch)
+		batch = batch[:0]
+	}
+}
+
+// deliver sends batch, retrying with growing delays until the SIEM takes it,
+// rejects it or the exporter is stopped
+func (e *Exporter) deliver(batch []Event) {
+	if dropped := e.dropped.Swap(0); dropped > 0 {
+		e.logger.WithField("dropped", dropped).Warn("SIEM export buffer full; dropped access log events")
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	delay := minRetryDelay
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(e.stop, e.timeout)
+		err := e.sender.Send(ctx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		logger := e.logger.WithError(err).WithFields(logrus.Fields{"events": len(batch), "attempt": attempt})
+		if errors.Is(err, ErrRejected) {
+			logger.Error("SIEM rejected access log events; dropping them")
+			return
+		}
+		logger.Warn("Failed to send access log events to SIEM; retrying")
+
+		select {
+		case <-time.After(delay):
+		case <-e.stop.Done():
+			e.logger.WithField("events", len(batch)).Error("Abandoned access log events unsent to SIEM")
+			return
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package siem
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"healthcare-api/internal/config"
+)
+
+// CEF header fields identifying the API as the event source
+const (
+	cefVendor  = "healthcare-api"
+	cefProduct = "healthcare-api"
+	cefVersion = "1.0.0"
+)
+
+// syslogFacility is the log audit facility (13) of RFC 5424
+const syslogFacility = 13
+
+// SyslogSender sends each event as an RFC 5424 syslog message carrying a CEF
+// event. Over TCP and TLS, messages are framed by octet counting (RFC 6587)
+// on one connection, opened again after a failure; over UDP each is a datagram.
+type SyslogSender struct {
+	address  string
+	network  string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSender validates cfg and returns a sender to its syslog server;
+// nothing is sent until the first batch
+func NewSyslogSender(cfg config.SIEMConfig) (*SyslogSender, error) {
+	if _, _, err := net.SplitHostPort(cfg.SyslogAddress); err != nil {
+		return nil, fmt.Errorf("invalid SIEM syslog address %q: %w", cfg.SyslogAddress, err)
+	}
+	switch cfg.SyslogNetwork {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("unsupported SIEM syslog network: %s", cfg.SyslogNetwork)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &SyslogSender{
+		address:  cfg.SyslogAddress,
+		network:  cfg.SyslogNetwork,
+		hostname: hostname,
+	}, nil
+}
+
+func (s *SyslogSender) Send(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog server: %w", err)
+		}
+		s.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	}
+
+	for _, event := range events {
+		message := s.message(event)
+		if s.network != "udp" {
+			message = strconv.Itoa(len(message)) + " " + message
+		}
+		if _, err := s.conn.Write([]byte(message)); err != nil {
+			// Whether the server took part of the batch is unknown, so the whole
+			// batch is sent again on a new connection
+			s.conn.Close()
+			s.conn = nil
+			return fmt.Errorf("failed to write to syslog server: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *SyslogSender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SyslogSender) dial(ctx context.Context) (net.Conn, error) {
+	if s.network == "tls" {
+		dialer := &tls.Dialer{Config: &tls.Config{MinVersion: tls.VersionTLS12}}
+		return dialer.DialContext(ctx, "tcp", s.address)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, s.network, s.address)
+}
+
+// message formats event as an RFC 5424 message: the header, no structured data
+// and the CEF event
+func (s *SyslogSender) message(event Event) string {
+	severity, _, _ := classify(event.StatusCode)
+	return fmt.Sprintf("<%d>1 %s %s %s %d access - %s",
+		syslogFacility*8+severity,
+		event.Time.UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		cefProduct,
+		os.Getpid(),
+		CEF(event),
+	)
+}
+
+// classify returns the syslog severity, CEF severity and name of a request's outcome
+func classify(statusCode int) (syslogSeverity, cefSeverity int, name string) {
+	switch {
+	case statusCode == 401 || statusCode == 403:
+		return 5, 7, "Access denied"
+	case statusCode >= 500:
+		return 3, 6, "Request failed"
+	case statusCode >= 400:
+		return 4, 4, "Request rejected"
+	default:
+		return 6, 3, "API request"
+	}
+}
+
+// CEF formats event as an ArcSight Common Event Format event
+func CEF(event Event) string {
+	_, severity, name := classify(event.StatusCode)
+
+	request := event.Path
+	if event.Query != "" {
+		request += "?" + event.Query
+	}
+	extension := []string{
+		"rt=" + strconv.FormatInt(event.Time.UnixMilli(), 10),
+		"requestMethod=" + cefValue(event.Method),
+		"request=" + cefValue(request),
+		"outcome=" + strconv.Itoa(event.StatusCode),
+		"cn1Label=durationMs",
+		"cn1=" + strconv.FormatInt(event.DurationMS, 10),
+	}
+	for _, field := range []struct{ key, value string }{
+		{"src", event.IPAddress},
+		{"suser", event.UserID},
+		{"requestClientApplication", event.UserAgent},
+		{"externalId", event.RequestID},
+	} {
+		if field.value != "" {
+			extension = append(extension, field.key+"="+cefValue(field.value))
+		}
+	}
+	if event.TenantID != "" {
+		extension = append(extension, "cs1Label=tenantId", "cs1="+cefValue(event.TenantID))
+	}
+
+	return strings.Join([]string{
+		"CEF:0",
+		cefHeader(cefVendor),
+		cefHeader(cefProduct),
+		cefHeader(cefVersion),
+		strconv.Itoa(event.StatusCode),
+		cefHeader(name),
+		strconv.Itoa(severity),
+		strings.Join(extension, " "),
+	}, "|")
+}
+
+var (
+	cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", " ", "\r", " ")
+	cefValueEscaper  = strings.NewReplacer(`\`, `\\`, "=", `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+func cefHeader(s string) string {
+	return cefHeaderEscaper.Replace(s)
+}
+
+func cefValue(s string) string {
+	return cefValueEscaper.Replace(s)
+}
@@ -0,0 +1,181 @@
+// Package terminology checks the codings of resources against code systems and
+// value sets loaded at startup.
+package terminology
+
+import (
+	"fmt"
+	"strings"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/models"
+)
+
+// SNOMEDSystem is the SNOMED CT code system URI
+const SNOMEDSystem = "http://snomed.info/sct"
+
+// Severities of codings outside their element's value set
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Elements bound to a SNOMED CT value set
+const (
+	ElementBodySite       = "bodySite"
+	ElementInterpretation = "interpretation"
+)
+
+// SNOMEDValidator checks the SNOMED CT codings of coded elements: every code
+// must be a well-formed concept id, and elements bound to a value set must use
+// its concepts. Codings of other systems are not checked.
+type SNOMEDValidator struct {
+	// valueSets holds the value set of each bound element
+	valueSets map[string]*ValueSet
+	// severity of codings outside their element's value set
+	severity string
+}
+
+// NewSNOMEDValidator loads the value sets configured in cfg. Without any, it
+// only checks that codes are well-formed.
+func NewSNOMEDValidator(cfg config.TerminologyConfig) (*SNOMEDValidator, error) {
+	switch cfg.SNOMEDValueSetSeverity {
+	case SeverityError, SeverityWarning:
+	default:
+		return nil, fmt.Errorf("invalid SNOMED CT value set severity %q: expected error or warning", cfg.SNOMEDValueSetSeverity)
+	}
+
+	v := &SNOMEDValidator{valueSets: map[string]*ValueSet{}, severity: cfg.SNOMEDValueSetSeverity}
+	for element, path := range map[string]string{
+		ElementBodySite:       cfg.SNOMEDBodySiteValueSet,
+		ElementInterpretation: cfg.SNOMEDInterpretationValueSet,
+	} {
+		if path == "" {
+			continue
+		}
+		valueSet, err := LoadValueSet(path, SNOMEDSystem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s value set: %w", element, err)
+		}
+		v.valueSets[element] = valueSet
+	}
+	return v, nil
+}
+
+// ValueSet returns the value set bound to element, or nil
+func (v *SNOMEDValidator) ValueSet(element string) *ValueSet {
+	return v.valueSets[element]
+}
+
+// CheckConcept checks the SNOMED CT codings of a concept of element, found at
+// path such as "interpretation[0]", returning an error for each malformed
+// code, an issue of the configured severity for each code outside the
+// element's value set, and a warning for each display the value set disagrees
+// with. Issue fields are the offending coding's path.
+func (v *SNOMEDValidator) CheckConcept(element, path string, concept *models.CodeableConcept) []models.ValidationError {
+	if concept == nil {
+		return nil
+	}
+
+	valueSet := v.valueSets[element]
+	var issues []models.ValidationError
+	for i, coding := range concept.Coding {
+		if coding.System == nil || *coding.System != SNOMEDSystem || coding.Code == nil {
+			continue
+		}
+		codingPath := fmt.Sprintf("%s.coding[%d]", path, i)
+		code := *coding.Code
+
+		if !ValidConceptID(code) {
+			issues = append(issues, models.ValidationError{
+				Field:   codingPath + ".code",
+				Message: fmt.Sprintf("%q is not a valid SNOMED CT concept id", code),
+				Value:   code,
+			})
+			continue
+		}
+		if valueSet == nil {
+			continue
+		}
+
+		display, ok := valueSet.Contains(code)
+		if !ok {
+			issue := models.ValidationError{
+				Field:   codingPath + ".code",
+				Message: fmt.Sprintf("SNOMED CT concept %s is not in the %s value set %s", code, element, valueSet.Name),
+				Value:   code,
+			}
+			if v.severity == SeverityWarning {
+				issue.Severity = SeverityWarning
+			}
+			issues = append(issues, issue)
+			continue
+		}
+		if display != "" && coding.Display != nil && !strings.EqualFold(*coding.Display, display) {
+			issues = append(issues, models.ValidationError{
+				Field:    codingPath + ".display",
+				Message:  fmt.Sprintf("Display %q of SNOMED CT concept %s does not match %q", *coding.Display, code, display),
+				Value:    *coding.Display,
+				Severity: SeverityWarning,
+			})
+		}
+	}
+	return issues
+}
+
+// ValidConceptID reports whether code is a well-formed SNOMED CT concept id:
+// 6 to 18 digits without a leading zero, a concept partition identifier and a
+// valid Verhoeff check digit. It does not tell whether the concept exists.
+func ValidConceptID(code string) bool {
+	if len(code) < 6 || len(code) > 18 || code[0] == '0' {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	// The partition identifier is the two digits before the check digit: 00
+	// for concepts in the international release, 10 for extension concepts
+	switch code[len(code)-3 : len(code)-1] {
+	case "00", "10":
+	default:
+		return false
+	}
+	return verhoeffValid(code)
+}
+
+// Verhoeff dihedral group multiplication and permutation tables
+var (
+	verhoeffD = [10][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+		{2, 3, 4, 0, 1, 7, 8, 9, 5, 6},
+		{3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+		{4, 0, 1, 2, 3, 9, 5, 6, 7, 8},
+		{5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+		{6, 5, 9, 8, 7, 1, 0, 4, 3, 2},
+		{7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+		{8, 7, 6, 5, 9, 3, 2, 1, 0, 4},
+		{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+	verhoeffP = [8][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+		{5, 8, 0, 3, 7, 9, 6, 1, 4, 2},
+		{8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+		{9, 4, 5, 3, 1, 2, 6, 8, 7, 0},
+		{4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+		{2, 7, 9, 3, 8, 0, 6, 4, 1, 5},
+		{7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+	}
+)
+
+// verhoeffValid reports whether the last digit of digits is its Verhoeff check digit
+func verhoeffValid(digits string) bool {
+	check := 0
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		check = verhoeffD[check][verhoeffP[i%8][digit]]
+	}
+	return check == 0
+}
@@ -0,0 +1,174 @@
+package terminology
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ValueSet is an expanded set of codes from one code system, with their displays
+type ValueSet struct {
+	// Name identifies the value set in messages: its URL, name or file name
+	Name   string
+	System string
+	codes  map[string]string
+}
+
+// Contains reports whether code is in the value set and returns its display
+func (v *ValueSet) Contains(code string) (string, bool) {
+	display, ok := v.codes[code]
+	return display, ok
+}
+
+// Len returns the number of codes in the value set
+func (v *ValueSet) Len() int {
+	return len(v.codes)
+}
+
+// LoadValueSet reads the codes of system from a file: a FHIR ValueSet in JSON,
+// its codes listed in expansion.contains or compose.include concepts, or, for
+// files ending in .txt or .tsv, an RF2 simple refset whose active members are
+// the codes. Codes of other systems in a FHIR ValueSet are ignored.
+func LoadValueSet(path, system string) (*ValueSet, error) {
+	var valueSet *ValueSet
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt", ".tsv":
+		valueSet, err = loadRefset(path, system)
+	default:
+		valueSet, err = loadFHIRValueSet(path, system)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if valueSet.Len() == 0 {
+		return nil, fmt.Errorf("value set %s has no %s codes", path, system)
+	}
+	return valueSet, nil
+}
+
+// valueSetContains is an entry of a FHIR ValueSet expansion, possibly nesting more
+type valueSetContains struct {
+	System   string             `json:"system"`
+	Code     string             `json:"code"`
+	Display  string             `json:"display"`
+	Abstract bool               `json:"abstract"`
+	Inactive bool               `json:"inactive"`
+	Contains []valueSetContains `json:"contains"`
+}
+
+func loadFHIRValueSet(path, system string) (*ValueSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read value set: %w", err)
+	}
+
+	var resource struct {
+		ResourceType string `json:"resourceType"`
+		URL          string `json:"url"`
+		Name         string `json:"name"`
+		Compose      *struct {
+			Include []struct {
+				System  string `json:"system"`
+				Concept []struct {
+					Code    string `json:"code"`
+					Display string `json:"display"`
+				} `json:"concept"`
+			} `json:"include"`
+		} `json:"compose"`
+		Expansion *struct {
+			Contains []valueSetContains `json:"contains"`
+		} `json:"expansion"`
+	}
+	if err := json.Unmarshal(data, &resource); err != nil {
+		return nil, fmt.Errorf("failed to parse value set %s: %w", path, err)
+	}
+	if resource.ResourceType != "ValueSet" {
+		return nil, fmt.Errorf("%s is a %q, not a ValueSet", path, resource.ResourceType)
+	}
+
+	valueSet := &ValueSet{Name: resource.URL, System: system, codes: map[string]string{}}
+	if valueSet.Name == "" {
+		valueSet.Name = resource.Name
+	}
+	if valueSet.Name == "" {
+		valueSet.Name = filepath.Base(path)
+	}
+
+	// An expansion is the complete list; compose concepts are used without one,
+	// as filters such as is-a need the code system to evaluate
+	if resource.Expansion != nil {
+		var add func(entries []valueSetContains)
+		add = func(entries []valueSetContains) {
+			for _, entry := range entries {
+				if entry.System == system && entry.Code != "" && !entry.Abstract && !entry.Inactive {
+					valueSet.codes[entry.Code] = entry.Display
+				}
+				add(entry.Contains)
+			}
+		}
+		add(resource.Expansion.Contains)
+	} else if resource.Compose != nil {
+		for _, include := range resource.Compose.Include {
+			if include.System != system {
+				continue
+			}
+			for _, concept := range include.Concept {
+				valueSet.codes[concept.Code] = concept.Display
+			}
+		}
+	}
+	return valueSet, nil
+}
+
+// loadRefset reads an RF2 simple refset: tab-separated id, effectiveTime,
+// active, moduleId, refsetId and referencedComponentId columns after a header
+// row. Refsets carry no displays.
+func loadRefset(path, system string) (*ValueSet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read value set: %w", err)
+	}
+	defer file.Close()
+
+	// A full release holds every version of a member; the latest one counts
+	type member struct {
+		effectiveTime string
+		active        bool
+	}
+	members := map[string]member{}
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		columns := strings.Split(strings.TrimRight(scanner.Text(), "\r"), "\t")
+		if line == 1 {
+			if len(columns) < 6 || columns[2] != "active" || columns[5] != "referencedComponentId" {
+				return nil, fmt.Errorf("%s is not an RF2 simple refset: unexpected header", path)
+			}
+			continue
+		}
+		if len(columns) < 6 {
+			return nil, fmt.Errorf("%s line %d: expected 6 columns, found %d", path, line, len(columns))
+		}
+		code, effectiveTime := columns[5], columns[1]
+		if latest, ok := members[code]; !ok || effectiveTime >= latest.effectiveTime {
+			members[code] = member{effectiveTime: effectiveTime, active: columns[2] == "1"}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read value set %s: %w", path, err)
+	}
+
+	valueSet := &ValueSet{Name: filepath.Base(path), System: system, codes: map[string]string{}}
+	for code, member := range members {
+		if member.active {
+			valueSet.codes[code] = ""
+		}
+	}
+	return valueSet, nil
+}
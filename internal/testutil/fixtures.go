@@ -0,0 +1,122 @@
+package testutil
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"testing"
+
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/repository"
+	"healthcare-api/internal/requestctx"
+	"healthcare-api/internal/seed"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// UserID is the user fixtures and the contexts of tests act as
+const UserID = "test"
+
+// Fixtures creates the resources a test starts from, in a tenant of the test's
+// own
+type Fixtures struct {
+	tb        testing.TB
+	ctx       context.Context
+	generator *seed.Generator
+
+	DB           *repository.BaseRepository
+	Tenants      *repository.TenantRepository
+	Patients     *repository.PatientRepository
+	Observations *repository.ObservationRepository
+	Tenant       *models.Tenant
+}
+
+// NewFixtures provisions a tenant for the test. The data generated is the same
+// on every run of the test, as the generator is seeded with its name.
+func NewFixtures(tb testing.TB) *Fixtures {
+	tb.Helper()
+	db := DB(tb)
+
+	seedValue := fnv.New64a()
+	seedValue.Write([]byte(tb.Name()))
+
+	f := &Fixtures{
+		tb:           tb,
+		generator:    seed.NewGenerator(int64(seedValue.Sum64())),
+		DB:           repository.NewBaseRepository(db),
+		Tenants:      repository.NewTenantRepository(db),
+		Patients:     repository.NewPatientRepository(db),
+		Observations: repository.NewObservationRepository(db),
+		Tenant: &models.Tenant{
+			ID:                 "test-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:16],
+			Name:               tb.Name(),
+			Status:             models.TenantStatusActive,
+			RateLimitPerMinute: 6000,
+			RateLimitBurst:     100,
+		},
+	}
+	if err := f.Tenants.Create(context.Background(), f.Tenant); err != nil {
+		tb.Fatalf("failed to provision tenant: %v", err)
+	}
+	f.ctx = requestctx.WithUserID(requestctx.WithTenantID(context.Background(), f.Tenant.ID), UserID)
+	return f
+}
+
+// Context returns a context acting in the test's tenant as UserID
+func (f *Fixtures) Context() context.Context {
+	return f.ctx
+}
+
+// NewPatient returns a generated patient without storing it
+func (f *Fixtures) NewPatient() *models.Patient {
+	return f.generator.Patient()
+}
+
+// Patient stores a generated patient
+func (f *Fixtures) Patient() *models.Patient {
+	f.tb.Helper()
+	patient := f.generator.Patient()
+	if err := f.Patients.Create(f.ctx, patient); err != nil {
+		f.tb.Fatalf("failed to create patient: %v", err)
+	}
+	return patient
+}
+
+// NewVitals returns a day of vital signs of patient for each of the last days
+// days, without storing them
+func (f *Fixtures) NewVitals(patient *models.Patient, days int) []*models.Observation {
+	return f.generator.Vitals(patient, days)
+}
+
+// Vitals stores a day of vital signs of patient for each of the last days days
+func (f *Fixtures) Vitals(patient *models.Patient, days int) []*models.Observation {
+	f.tb.Helper()
+	observations := f.generator.Vitals(patient, days)
+	for _, observation := range observations {
+		if err := f.Observations.Create(f.ctx, observation); err != nil {
+			f.tb.Fatalf("failed to create observation: %v", err)
+		}
+	}
+	return observations
+}
+
+// Logger returns a logger writing to the test's log, for the services under
+// test
+func Logger(tb testing.TB) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(testWriter{tb})
+	logger.SetLevel(logrus.DebugLevel)
+	return logger
+}
+
+// testWriter writes to the log of a test
+type testWriter struct {
+	tb testing.TB
+}
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.tb.Helper()
+	w.tb.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
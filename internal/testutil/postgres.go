@@ -0,0 +1,201 @@
+// Package testutil runs integration tests against a real PostgreSQL server. Run,
+// called from a package's TestMain, starts a private server with the
+// embedded-postgres driver and applies every migration; each test then works
+// in a tenant of its own, with fixtures built from the seed generator, so tests
+// share the server without seeing one another's data.
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(testutil.Run(m))
+//	}
+//
+//	func TestPatientLifecycle(t *testing.T) {
+//		f := testutil.NewFixtures(t)
+//		patient := f.Patient()
+//		...
+//	}
+//
+// With -short no server is started and the tests that need one are skipped.
+// They are skipped too when the server cannot be started, as when its binaries
+// are not cached yet and cannot be downloaded.
+//
+// The server is the one the embedded-postgres database driver runs, rather than
+// a testcontainers container: it needs no Docker daemon, which CI runners and
+// developer machines do not all have, it is a dependency already, and once its
+// binaries are cached the tests run offline. The tests also run against the
+// same PostgreSQL the embedded driver serves in development.
+package testutil
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"healthcare-api/internal/config"
+	"healthcare-api/internal/database"
+)
+
+// ErrUnavailable is returned by StartPostgres when the server cannot be
+// started, as when its binaries can be neither found in the cache nor downloaded
+var ErrUnavailable = errors.New("PostgreSQL server unavailable")
+
+var (
+	// shared is the database of the package's tests, set by Run
+	shared *database.DB
+	// unavailable is why Run started no server, if it could not
+	unavailable error
+)
+
+// Postgres is a migrated PostgreSQL server started for tests
+type Postgres struct {
+	DB     *database.DB
+	Config config.DatabaseConfig
+
+	provider database.Provider
+	dir      string
+}
+
+// Run starts a PostgreSQL server, runs the tests of the package against it and
+// stops it, returning the exit code for os.Exit. With -short, or when the server
+// is unavailable, the tests run without one.
+func Run(m *testing.M) int {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	if testing.Short() {
+		return m.Run()
+	}
+
+	pg, err := StartPostgres()
+	if errors.Is(err, ErrUnavailable) {
+		fmt.Fprintf(os.Stderr, "testutil: %v; skipping integration tests\n", err)
+		unavailable = err
+		return m.Run()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "testutil: %v\n", err)
+		return 1
+	}
+	shared = pg.DB
+	code := m.Run()
+	if err := pg.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "testutil: %v\n", err)
+	}
+	return code
+}
+
+// DB returns the database started by Run, skipping the test when there is
+// none, as with -short
+func DB(tb testing.TB) *database.DB {
+	tb.Helper()
+	if unavailable != nil {
+		tb.Skipf("integration test: %v", unavailable)
+	}
+	if shared == nil {
+		tb.Skip("integration test: needs the PostgreSQL server started by testutil.Run, and is skipped with -short")
+	}
+	return shared
+}
+
+// StartPostgres starts an embedded PostgreSQL server on a free port, with its
+// data in a temporary directory, and applies every migration
+func StartPostgres() (*Postgres, error) {
+	dir, err := os.MkdirTemp("", "healthcare-api-test-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	cfg := config.DatabaseConfig{
+		Driver:           database.DriverEmbedded,
+		EmbeddedDataPath: filepath.Join(dir, "postgres"),
+		Host:             "localhost",
+		Port:             port,
+		User:             "postgres",
+		Password:         "postgres",
+		Name:             "healthcare_test",
+		SSLMode:          "disable",
+	}
+	cfg.URL = fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name, cfg.SSLMode)
+	cfg.MigrationURL = cfg.URL
+
+	pg := &Postgres{Config: cfg, dir: dir}
+	if pg.provider, err = database.NewProvider(cfg); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if err := pg.provider.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	if err := migrate(cfg.MigrationURL); err != nil {
+		pg.Stop()
+		return nil, err
+	}
+	if pg.DB, err = database.NewConnection(cfg); err != nil {
+		pg.Stop()
+		return nil, err
+	}
+	return pg, nil
+}
+
+// Stop closes the connection, stops the server and removes its data
+func (pg *Postgres) Stop() error {
+	var errs []error
+	if pg.DB != nil {
+		errs = append(errs, pg.DB.Close())
+	}
+	errs = append(errs, pg.provider.Stop(), os.RemoveAll(pg.dir))
+	return errors.Join(errs...)
+}
+
+// migrate applies the migrations of the module, found from the directory the
+// tests run in, which is that of their package
+func migrate(databaseURL string) error {
+	path, err := migrationsPath()
+	if err != nil {
+		return err
+	}
+	mg, err := database.NewMigrator(databaseURL, path)
+	if err != nil {
+		return err
+	}
+	defer mg.Close()
+	return mg.Up(0)
+}
+
+// migrationsPath returns the migrations directory next to the module's go.mod
+func migrationsPath() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return filepath.Join(dir, database.MigrationsPath), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("migrations not found: no go.mod above the working directory")
+		}
+		dir = parent
+	}
+}
+
+// freePort returns a TCP port nothing listens on
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,369 @@
+// Package ucum converts quantities between units given as UCUM codes, the
+// units of FHIR quantities with system http://unitsofmeasure.org. It covers the
+// units of clinical results and vital signs: SI base and derived units with
+// their prefixes, liters, time, pressure, temperature, percent, powers of ten,
+// customary units of length and mass, and annotations such as {cells}.
+// Arbitrary units such as [iU] convert only to themselves with a prefix.
+package ucum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// System is the UCUM code system URI
+const System = "http://unitsofmeasure.org"
+
+// ErrIncompatible is wrapped by errors converting between units of different
+// kinds, such as mass and volume
+var ErrIncompatible = errors.New("units are not commensurable")
+
+// Unit is a parsed UCUM unit: a factor of the product of base units raised
+// to their exponents
+type Unit struct {
+	factor float64
+	dims   map[string]int
+}
+
+// atom is a unit symbol and its value in base units
+type atom struct {
+	factor float64
+	dims   map[string]int
+	// metric atoms take prefixes
+	metric bool
+}
+
+// Base units of the supported dimensions. Each arbitrary unit is a base unit
+// of its own.
+const (
+	dimLength      = "m"
+	dimMass        = "g"
+	dimTime        = "s"
+	dimAmount      = "mol"
+	dimTemperature = "K"
+	dimCharge      = "C"
+	dimLuminosity  = "cd"
+)
+
+func base(dim string) map[string]int {
+	return map[string]int{dim: 1}
+}
+
+var atoms = map[string]atom{
+	// SI base units
+	"m":   {1, base(dimLength), true},
+	"g":   {1, base(dimMass), true},
+	"s":   {1, base(dimTime), true},
+	"mol": {1, base(dimAmount), true},
+	"K":   {1, base(dimTemperature), true},
+	"C":   {1, base(dimCharge), true},
+	"cd":  {1, base(dimLuminosity), true},
+	// Derived and accepted units
+	"L":      {1e-3, map[string]int{dimLength: 3}, true},
+	"l":      {1e-3, map[string]int{dimLength: 3}, true},
+	"Pa":     {1e3, map[string]int{dimMass: 1, dimLength: -1, dimTime: -2}, true},
+	"bar":    {1e8, map[string]int{dimMass: 1, dimLength: -1, dimTime: -2}, true},
+	"J":      {1e3, map[string]int{dimMass: 1, dimLength: 2, dimTime: -2}, true},
+	"cal":    {4.184e3, map[string]int{dimMass: 1, dimLength: 2, dimTime: -2}, true},
+	"W":      {1e3, map[string]int{dimMass: 1, dimLength: 2, dimTime: -3}, true},
+	"Hz":     {1, map[string]int{dimTime: -1}, true},
+	"kat":    {1, map[string]int{dimAmount: 1, dimTime: -1}, true},
+	"U":      {1e-6 / 60, map[string]int{dimAmount: 1, dimTime: -1}, true},
+	"eq":     {1, base(dimAmount), true},
+	"osm":    {1, base(dimAmount), true},
+	"t":      {1e6, base(dimMass), true},
+	"m[Hg]":  {133.322e6, map[string]int{dimMass: 1, dimLength: -1, dimTime: -2}, true},
+	"m[H2O]": {9.80665e6, map[string]int{dimMass: 1, dimLength: -1, dimTime: -2}, true},
+	// Time
+	"min": {60, base(dimTime), false},
+	"h":   {3600, base(dimTime), false},
+	"d":   {86400, base(dimTime), false},
+	"wk":  {604800, base(dimTime), false},
+	"mo":  {2629800, base(dimTime), false},
+	"a":   {31557600, base(dimTime), false},
+	// Dimensionless
+	"%":      {1e-2, nil, false},
+	"[ppth]": {1e-3, nil, false},
+	"[ppm]":  {1e-6, nil, false},
+	"[ppb]":  {1e-9, nil, false},
+	// Customary units
+	"[in_i]":  {0.0254, base(dimLength), false},
+	"[ft_i]":  {0.3048, base(dimLength), false},
+	"[lb_av]": {453.59237, base(dimMass), false},
+	"[oz_av]": {28.349523125, base(dimMass), false},
+	"[gr]":    {0.06479891, base(dimMass), false},
+	"[mi_i]":  {1609.344, base(dimLength), false},
+	// Arbitrary units
+	"[iU]":    {1, base("[iU]"), true},
+	"[IU]":    {1, base("[iU]"), true},
+	"[arb'U]": {1, base("[arb'U]"), false},
+	"[pH]":    {1, base("[pH]"), false},
+}
+
+var prefixes = map[string]float64{
+	"Y": 1e24, "Z": 1e21, "E": 1e18, "P": 1e15, "T": 1e12, "G": 1e9, "M": 1e6,
+	"k": 1e3, "h": 1e2, "da": 1e1, "d": 1e-1, "c": 1e-2, "m": 1e-3, "u": 1e-6,
+	"n": 1e-9, "p": 1e-12, "f": 1e-15, "a": 1e-18, "z": 1e-21, "y": 1e-24,
+}
+
+// Temperature scales, which convert with an offset and only as the whole unit
+var scales = map[string]struct{ factor, offset float64 }{
+	"K":      {1, 0},
+	"Cel":    {1, 273.15},
+	"[degF]": {5.0 / 9, 255.37222222222222},
+}
+
+// Convert converts value from unit from to unit to
+func Convert(value float64, from, to string) (float64, error) {
+	if from == to {
+		return value, nil
+	}
+	fromScale, fromTemperature := scales[from]
+	toScale, toTemperature := scales[to]
+	switch {
+	case fromTemperature && toTemperature:
+		kelvin := value*fromScale.factor + fromScale.offset
+		return (kelvin - toScale.offset) / toScale.factor, nil
+	case fromTemperature && fromScale.offset != 0, toTemperature && toScale.offset != 0:
+		return 0, fmt.Errorf("%w: %s and %s", ErrIncompatible, from, to)
+	}
+
+	fromUnit, err := Parse(from)
+	if err != nil {
+		return 0, err
+	}
+	toUnit, err := Parse(to)
+	if err != nil {
+		return 0, err
+	}
+	if !fromUnit.commensurable(toUnit) {
+		return 0, fmt.Errorf("%w: %s and %s", ErrIncompatible, from, to)
+	}
+	return value * fromUnit.factor / toUnit.factor, nil
+}
+
+// Parse parses a UCUM unit code
+func Parse(code string) (*Unit, error) {
+	if code == "" {
+		return nil, errors.New("empty unit")
+	}
+	p := &parser{code: code}
+	unit, err := p.term()
+	if err == nil && p.pos < len(code) {
+		err = fmt.Errorf("unexpected %q", code[p.pos:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid UCUM unit %q: %w", code, err)
+	}
+	return unit, nil
+}
+
+func (u *Unit) commensurable(other *Unit) bool {
+	if len(u.dims) != len(other.dims) {
+		return false
+	}
+	for dim, exponent := range u.dims {
+		if other.dims[dim] != exponent {
+			return false
+		}
+	}
+	return true
+}
+
+func (u *Unit) multiply(other *Unit, sign int) {
+	if sign > 0 {
+		u.factor *= other.factor
+	} else {
+		u.factor /= other.factor
+	}
+	for dim, exponent := range other.dims {
+		u.dims[dim] += sign * exponent
+		if u.dims[dim] == 0 {
+			delete(u.dims, dim)
+		}
+	}
+}
+
+// parser reads a unit term: components joined by "." and "/", the whole
+// optionally led by "/"
+type parser struct {
+	code string
+	pos  int
+}
+
+func (p *parser) term() (*Unit, error) {
+	unit := &Unit{factor: 1, dims: map[string]int{}}
+	sign := 1
+	if p.peek() == '/' {
+		p.pos++
+		sign = -1
+	}
+	for {
+		component, err := p.component()
+		if err != nil {
+			return nil, err
+		}
+		unit.multiply(component, sign)
+
+		switch p.peek() {
+		case '.':
+			sign = 1
+		case '/':
+			sign = -1
+		default:
+			return unit, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.code) {
+		return p.code[p.pos]
+	}
+	return 0
+}
+
+// component reads a parenthesized term, a number such as 1000, a power of ten
+// such as 10*9, an annotation or a unit symbol with its exponent
+func (p *parser) component() (*Unit, error) {
+	switch c := p.peek(); {
+	case c == '(':
+		p.pos++
+		unit, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, errors.New("unbalanced parentheses")
+		}
+		p.pos++
+		p.annotation()
+		return unit, nil
+	case c == '{':
+		if !p.annotation() {
+			return nil, errors.New("unterminated annotation")
+		}
+		return &Unit{factor: 1, dims: map[string]int{}}, nil
+	case c >= '0' && c <= '9':
+		return p.number()
+	case c == 0:
+		return nil, errors.New("missing unit")
+	}
+
+	symbol := p.symbol()
+	if symbol == "" {
+		return nil, fmt.Errorf("unexpected %q", p.code[p.pos:])
+	}
+	a, ok := lookup(symbol)
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %s", symbol)
+	}
+	exponent, err := p.exponent(1)
+	if err != nil {
+		return nil, err
+	}
+	p.annotation()
+
+	unit := &Unit{factor: math.Pow(a.factor, float64(exponent)), dims: map[string]int{}}
+	for dim, e := range a.dims {
+		unit.dims[dim] = e * exponent
+	}
+	return unit, nil
+}
+
+// number reads an integer factor, or 10*n and 10^n powers of ten
+func (p *parser) number() (*Unit, error) {
+	start := p.pos
+	for p.pos < len(p.code) && p.code[p.pos] >= '0' && p.code[p.pos] <= '9' {
+		p.pos++
+	}
+	digits := p.code[start:p.pos]
+	if digits == "10" && (p.peek() == '*' || p.peek() == '^') {
+		p.pos++
+		exponent, err := p.exponent(0)
+		if err != nil {
+			return nil, err
+		}
+		p.annotation()
+		return &Unit{factor: math.Pow(10, float64(exponent)), dims: map[string]int{}}, nil
+	}
+	factor, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return nil, err
+	}
+	p.annotation()
+	return &Unit{factor: factor, dims: map[string]int{}}, nil
+}
+
+// symbol reads a unit symbol, taking bracketed parts such as [Hg] whole
+func (p *parser) symbol() string {
+	start := p.pos
+	for p.pos < len(p.code) {
+		c := p.code[p.pos]
+		switch {
+		case c == '[':
+			end := strings.IndexByte(p.code[p.pos:], ']')
+			if end < 0 {
+				return p.code[start:p.pos]
+			}
+			p.pos += end + 1
+			continue
+		case c == '.' || c == '/' || c == '(' || c == ')' || c == '{' || c == '+' || c == '-' || (c >= '0' && c <= '9'):
+			return p.code[start:p.pos]
+		}
+		p.pos++
+	}
+	return p.code[start:]
+}
+
+// exponent reads a signed integer exponent, returning def when there is none
+func (p *parser) exponent(def int) (int, error) {
+	start := p.pos
+	if c := p.peek(); c == '+' || c == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.code) && p.code[p.pos] >= '0' && p.code[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		if def == 0 {
+			return 0, errors.New("missing exponent")
+		}
+		return def, nil
+	}
+	return strconv.Atoi(p.code[start:p.pos])
+}
+
+// annotation skips an annotation such as {cells}, which does not change the unit
+func (p *parser) annotation() bool {
+	if p.peek() != '{' {
+		return true
+	}
+	end := strings.IndexByte(p.code[p.pos:], '}')
+	if end < 0 {
+		return false
+	}
+	p.pos += end + 1
+	return true
+}
+
+// lookup finds a unit symbol, whole or as a prefix of a metric atom
+func lookup(symbol string) (atom, bool) {
+	if a, ok := atoms[symbol]; ok {
+		return a, true
+	}
+	for prefix, factor := range prefixes {
+		if !strings.HasPrefix(symbol, prefix) {
+			continue
+		}
+		if a, ok := atoms[symbol[len(prefix):]]; ok && a.metric {
+			a.factor *= factor
+			return a, true
+		}
+	}
+	return atom{}, false
+}
@@ -5,14 +5,18 @@ import (
 	"reflect"
 	"strings"
 
+	"healthcare-api/internal/identifier"
 	"healthcare-api/internal/models"
+	"healthcare-api/internal/terminology"
 
 	"github.com/go-playground/validator/v10"
 )
 
 // Validator wraps the go-playground validator
 type Validator struct {
-	validate *validator.Validate
+	validate    *validator.Validate
+	snomed      *terminology.SNOMEDValidator
+	identifiers *identifier.Validator
 }
 
 // NewValidator creates a new validator instance
@@ -75,6 +79,8 @@ func getValidationMessage(err validator.FieldError) string {
 		return fmt.Sprintf("%s must be one of: %s", err.Field(), err.Param())
 	case "uri":
 		return fmt.Sprintf("%s must be a valid URI", err.Field())
+	case "base64":
+		return fmt.Sprintf("%s must be base64 encoded", err.Field())
 	case "fhir_status":
 		return fmt.Sprintf("%s must be a valid FHIR status", err.Field())
 	case "fhir_gender":
@@ -152,22 +158,85 @@ func validateFHIRAddressUse(fl validator.FieldLevel) bool {
 	return false
 }
 
+// SetSNOMED makes the validator check the SNOMED CT codings of observations
+func (v *Validator) SetSNOMED(snomed *terminology.SNOMEDValidator) {
+	v.snomed = snomed
+}
+
+// SetIdentifiers makes the validator check patient identifiers with identifiers
+func (v *Validator) SetIdentifiers(identifiers *identifier.Validator) {
+	v.identifiers = identifiers
+}
+
 // ValidatePatientCreate validates patient creation request
 func (v *Validator) ValidatePatientCreate(req *models.PatientCreateRequest) *models.ValidationErrors {
-	return v.ValidateStruct(req)
+	return v.checkIdentifiers(v.ValidateStruct(req), req.Identifier)
 }
 
 // ValidatePatientUpdate validates patient update request
 func (v *Validator) ValidatePatientUpdate(req *models.PatientUpdateRequest) *models.ValidationErrors {
-	return v.ValidateStruct(req)
+	return v.checkIdentifiers(v.ValidateStruct(req), req.Identifier)
+}
+
+// checkIdentifiers adds the issues of a patient's identifiers whose system has
+// a plugin
+func (v *Validator) checkIdentifiers(errs *models.ValidationErrors, identifiers []models.Identifier) *models.ValidationErrors {
+	if v.identifiers == nil {
+		return errs
+	}
+
+	issues := v.identifiers.CheckIdentifiers("identifier", identifiers)
+	if len(issues) == 0 {
+		return errs
+	}
+	if errs == nil {
+		errs = &models.ValidationErrors{}
+	}
+	errs.Errors = append(errs.Errors, issues...)
+	return errs
 }
 
 // ValidateObservationCreate validates observation creation request
 func (v *Validator) ValidateObservationCreate(req *models.ObservationCreateRequest) *models.ValidationErrors {
-	return v.ValidateStruct(req)
+	return v.checkObservationCodings(v.ValidateStruct(req), req.Interpretation, req.BodySite, req.Component)
 }
 
 // ValidateObservationUpdate validates observation update request
 func (v *Validator) ValidateObservationUpdate(req *models.ObservationUpdateRequest) *models.ValidationErrors {
+	return v.checkObservationCodings(v.ValidateStruct(req), req.Interpretation, req.BodySite, req.Component)
+}
+
+// checkObservationCodings adds the issues of the SNOMED CT codings in an
+// observation's interpretations, including its components', and body site
+func (v *Validator) checkObservationCodings(errs *models.ValidationErrors, interpretation []models.CodeableConcept, bodySite *models.CodeableConcept, components []models.ObservationComponent) *models.ValidationErrors {
+	if v.snomed == nil {
+		return errs
+	}
+
+	var issues []models.ValidationError
+	for i := range interpretation {
+		path := fmt.Sprintf("interpretation[%d]", i)
+		issues = append(issues, v.snomed.CheckConcept(terminology.ElementInterpretation, path, &interpretation[i])...)
+	}
+	for i, component := range components {
+		for j := range component.Interpretation {
+			path := fmt.Sprintf("component[%d].interpretation[%d]", i, j)
+			issues = append(issues, v.snomed.CheckConcept(terminology.ElementInterpretation, path, &component.Interpretation[j])...)
+		}
+	}
+	issues = append(issues, v.snomed.CheckConcept(terminology.ElementBodySite, "bodySite", bodySite)...)
+
+	if len(issues) == 0 {
+		return errs
+	}
+	if errs == nil {
+		errs = &models.ValidationErrors{}
+	}
+	errs.Errors = append(errs.Errors, issues...)
+	return errs
+}
+
+// ValidateDiagnosticReportCreate validates diagnostic report creation request
+func (v *Validator) ValidateDiagnosticReportCreate(req *models.DiagnosticReportCreateRequest) *models.ValidationErrors {
 	return v.ValidateStruct(req)
 }
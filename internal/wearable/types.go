@@ -0,0 +1,174 @@
+package wearable
+
+// FHIR code systems used when converting samples
+const (
+	observationCategorySystem = "http://terminology.hl7.org/CodeSystem/observation-category"
+	loincSystem               = "http://loinc.org"
+	ucumSystem                = "http://unitsofmeasure.org"
+)
+
+var categoryDisplays = map[string]string{
+	"vital-signs": "Vital Signs",
+	"activity":    "Activity",
+	"laboratory":  "Laboratory",
+}
+
+// sampleType is a kind of reading: its LOINC code, the UCUM unit it is stored
+// in and the units samples may give, each with its conversion to that unit
+type sampleType struct {
+	name     string
+	aliases  []string
+	loinc    string
+	display  string
+	category string
+	unit     string
+	units    map[string]func(float64) float64
+}
+
+func same(v float64) float64 { return v }
+
+func scale(factor float64) func(float64) float64 {
+	return func(v float64) float64 { return v * factor }
+}
+
+var (
+	perMinute = map[string]func(float64) float64{"/min": same, "{beats}/min": same, "count/min": same, "bpm": same}
+	mmHg      = map[string]func(float64) float64{"mm[Hg]": same, "mmHg": same}
+)
+
+// types lists the supported kinds of reading. Aliases are the HealthKit
+// quantity type and Google Fit data type identifiers of the same reading.
+var types = []sampleType{
+	{
+		name:     "heart_rate",
+		aliases:  []string{"HKQuantityTypeIdentifierHeartRate", "com.google.heart_rate.bpm"},
+		loinc:    "8867-4",
+		display:  "Heart rate",
+		category: "vital-signs",
+		unit:     "/min",
+		units:    perMinute,
+	},
+	{
+		name:     "resting_heart_rate",
+		aliases:  []string{"HKQuantityTypeIdentifierRestingHeartRate"},
+		loinc:    "40443-4",
+		display:  "Heart rate --resting",
+		category: "vital-signs",
+		unit:     "/min",
+		units:    perMinute,
+	},
+	{
+		name:     "heart_rate_variability",
+		aliases:  []string{"HKQuantityTypeIdentifierHeartRateVariabilitySDNN"},
+		loinc:    "80404-7",
+		display:  "R-R interval.standard deviation (Heart rate variability)",
+		category: "vital-signs",
+		unit:     "ms",
+		units:    map[string]func(float64) float64{"ms": same, "s": scale(1000)},
+	},
+	{
+		name:     "respiratory_rate",
+		aliases:  []string{"HKQuantityTypeIdentifierRespiratoryRate"},
+		loinc:    "9279-1",
+		display:  "Respiratory rate",
+		category: "vital-signs",
+		unit:     "/min",
+		units:    perMinute,
+	},
+	{
+		name:     "oxygen_saturation",
+		aliases:  []string{"HKQuantityTypeIdentifierOxygenSaturation", "com.google.oxygen_saturation"},
+		loinc:    "59408-5",
+		display:  "Oxygen saturation in Arterial blood by Pulse oximetry",
+		category: "vital-signs",
+		unit:     "%",
+		// HealthKit reports saturation as a fraction
+		units: map[string]func(float64) float64{"%": same, "1": scale(100)},
+	},
+	{
+		name:     "body_temperature",
+		aliases:  []string{"HKQuantityTypeIdentifierBodyTemperature", "com.google.body.temperature"},
+		loinc:    "8310-5",
+		display:  "Body temperature",
+		category: "vital-signs",
+		unit:     "Cel",
+		units: map[string]func(float64) float64{
+			"Cel":    same,
+			"degC":   same,
+			"[degF]": func(v float64) float64 { return (v - 32) * 5 / 9 },
+			"degF":   func(v float64) float64 { return (v - 32) * 5 / 9 },
+		},
+	},
+	{
+		name:     "body_weight",
+		aliases:  []string{"HKQuantityTypeIdentifierBodyMass", "com.google.weight"},
+		loinc:    "29463-7",
+		display:  "Body weight",
+		category: "vital-signs",
+		unit:     "kg",
+		units:    map[string]func(float64) float64{"kg": same, "g": scale(0.001), "[lb_av]": scale(0.45359237), "lb": scale(0.45359237)},
+	},
+	{
+		name:     "body_height",
+		aliases:  []string{"HKQuantityTypeIdentifierHeight", "com.google.height"},
+		loinc:    "8302-2",
+		display:  "Body height",
+		category: "vital-signs",
+		unit:     "cm",
+		units:    map[string]func(float64) float64{"cm": same, "m": scale(100), "[in_i]": scale(2.54), "in": scale(2.54)},
+	},
+	{
+		name:     "blood_pressure_systolic",
+		aliases:  []string{"HKQuantityTypeIdentifierBloodPressureSystolic"},
+		loinc:    "8480-6",
+		display:  "Systolic blood pressure",
+		category: "vital-signs",
+		unit:     "mm[Hg]",
+		units:    mmHg,
+	},
+	{
+		name:     "blood_pressure_diastolic",
+		aliases:  []string{"HKQuantityTypeIdentifierBloodPressureDiastolic"},
+		loinc:    "8462-4",
+		display:  "Diastolic blood pressure",
+		category: "vital-signs",
+		unit:     "mm[Hg]",
+		units:    mmHg,
+	},
+	{
+		name:     "blood_glucose",
+		aliases:  []string{"HKQuantityTypeIdentifierBloodGlucose", "com.google.blood_glucose"},
+		loinc:    "2339-0",
+		display:  "Glucose [Mass/volume] in Blood",
+		category: "laboratory",
+		unit:     "mg/dL",
+		units:    map[string]func(float64) float64{"mg/dL": same, "mmol/L": scale(18.0182)},
+	},
+	{
+		name:     "step_count",
+		aliases:  []string{"HKQuantityTypeIdentifierStepCount", "com.google.step_count.delta"},
+		loinc:    "55423-8",
+		display:  "Number of steps in unspecified time Pedometer",
+		category: "activity",
+		unit:     "{steps}",
+		units:    map[string]func(float64) float64{"{steps}": same, "count": same, "steps": same},
+	},
+}
+
+var typesByName = func() map[string]*sampleType {
+	byName := make(map[string]*sampleType)
+	for i := range types {
+		t := &types[i]
+		byName[t.name] = t
+		for _, alias := range t.aliases {
+			byName[alias] = t
+		}
+	}
+	return byName
+}()
+
+// lookup returns the type of a sample by its name or an alias
+func lookup(name string) (*sampleType, bool) {
+	t, ok := typesByName[name]
+	return t, ok
+}
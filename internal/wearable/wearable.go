@@ -0,0 +1,175 @@
+package wearable
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+)
+
+// MaxSamples is the most samples a batch may hold
+const MaxSamples = 10000
+
+// MaxBatchSize is the largest batch body accepted, in bytes
+const MaxBatchSize = 8 << 20
+
+// DeviceSystem is the identifier system of device IDs without one of their own
+const DeviceSystem = "urn:healthcare-api:wearable:device"
+
+// ErrInvalidBatch is wrapped by errors for batches that cannot be converted
+var ErrInvalidBatch = errors.New("invalid wearable batch")
+
+// Batch is a batch of samples recorded by one device, in the shape HealthKit
+// and Google Fit exports flatten to
+type Batch struct {
+	Device  Device   `json:"device"`
+	Samples []Sample `json:"samples"`
+}
+
+// Device identifies the device that recorded the samples
+type Device struct {
+	// ID is the device's identifier, unique within System
+	ID           string `json:"id"`
+	System       string `json:"system,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty"`
+}
+
+// Sample is a single reading. Type is one of the names in types or a HealthKit
+// or Google Fit data type identifier; End is set for readings taken
+// over an interval, such as step counts.
+type Sample struct {
+	Type  string     `json:"type"`
+	Value *float64   `json:"value"`
+	Unit  string     `json:"unit"`
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// Reading is a sample converted to an observation
+type Reading struct {
+	// Key identifies the reading by device, code and start time, so a sample
+	// sent again has the same key
+	Key         string
+	Observation *models.Observation
+}
+
+// Readings converts the batch's samples to observations about subject. A batch
+// with a sample that cannot be converted is rejected whole, naming the sample.
+func Readings(batch *Batch, subject models.Reference) ([]Reading, error) {
+	if strings.TrimSpace(batch.Device.ID) == "" {
+		return nil, fmt.Errorf("%w: device.id is required", ErrInvalidBatch)
+	}
+	if len(batch.Samples) == 0 {
+		return nil, fmt.Errorf("%w: no samples", ErrInvalidBatch)
+	}
+	if len(batch.Samples) > MaxSamples {
+		return nil, fmt.Errorf("%w: %d samples is more than the %d allowed", ErrInvalidBatch, len(batch.Samples), MaxSamples)
+	}
+
+	device := deviceReference(batch.Device)
+	readings := make([]Reading, len(batch.Samples))
+	for i, sample := range batch.Samples {
+		reading, err := convert(sample, subject, device)
+		if err != nil {
+			return nil, fmt.Errorf("%w: sample %d: %s", ErrInvalidBatch, i, err)
+		}
+		reading.Key = strings.Join([]string{
+			*device.Identifier.System,
+			batch.Device.ID,
+			*reading.Observation.Code.Coding[0].Code,
+			sample.Start.UTC().Format(time.RFC3339Nano),
+		}, "\x00")
+		readings[i] = reading
+	}
+	return readings, nil
+}
+
+// convert builds the observation of a sample
+func convert(sample Sample, subject models.Reference, device *models.Reference) (Reading, error) {
+	t, ok := lookup(sample.Type)
+	if !ok {
+		return Reading{}, fmt.Errorf("unsupported type %q", sample.Type)
+	}
+	if sample.Value == nil {
+		return Reading{}, errors.New("value is required")
+	}
+	if sample.Start.IsZero() {
+		return Reading{}, errors.New("start is required")
+	}
+	if sample.End != nil && sample.End.Before(sample.Start) {
+		return Reading{}, errors.New("end is before start")
+	}
+
+	convertUnit, ok := t.units[sample.Unit]
+	if !ok {
+		return Reading{}, fmt.Errorf("unsupported unit %q for %s", sample.Unit, t.name)
+	}
+	value := convertUnit(*sample.Value)
+	if math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+		return Reading{}, fmt.Errorf("invalid value %v", *sample.Value)
+	}
+
+	start := sample.Start.UTC()
+	observation := &models.Observation{
+		Status: "final",
+		Category: []models.CodeableConcept{{
+			Coding: []models.Coding{{
+				System:  ptr(observationCategorySystem),
+				Code:    ptr(t.category),
+				Display: ptr(categoryDisplays[t.category]),
+			}},
+		}},
+		Code: models.CodeableConcept{
+			Coding: []models.Coding{{
+				System:  ptr(loincSystem),
+				Code:    ptr(t.loinc),
+				Display: ptr(t.display),
+			}},
+			Text: ptr(t.display),
+		},
+		Subject: subject,
+		ValueQuantity: &models.Quantity{
+			Value:  &value,
+			Unit:   ptr(t.unit),
+			System: ptr(ucumSystem),
+			Code:   ptr(t.unit),
+		},
+		Device: device,
+	}
+	if sample.End != nil && !sample.End.Equal(sample.Start) {
+		end := sample.End.UTC()
+		observation.EffectivePeriod = &models.Period{Start: &start, End: &end}
+	} else {
+		observation.EffectiveDateTime = &start
+	}
+
+	return Reading{Observation: observation}, nil
+}
+
+// deviceReference refers to the device by its identifier, displaying its
+// manufacturer and model
+func deviceReference(device Device) *models.Reference {
+	system := device.System
+	if system == "" {
+		system = DeviceSystem
+	}
+	reference := &models.Reference{
+		Type: ptr("Device"),
+		Identifier: &models.Identifier{
+			System: ptr(system),
+			Value:  ptr(device.ID),
+		},
+	}
+	if display := strings.TrimSpace(device.Manufacturer + " " + device.Model); display != "" {
+		reference.Display = &display
+	}
+	return reference
+}
+
+func ptr(s string) *string {
+	return &s
+}
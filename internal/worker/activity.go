@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// JobTypeActivity is the recent outcome of the jobs of a type run by the pool
+type JobTypeActivity struct {
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	// ConsecutiveFailures counts the failed attempts since the last success
+	ConsecutiveFailures int `json:"consecutive_failures"`
+}
+
+// activity records the outcome of the pool's job attempts by type
+type activity struct {
+	mu    sync.Mutex
+	types map[string]*JobTypeActivity
+}
+
+func (a *activity) record(result *JobResult) {
+	// A cancelled job says nothing about whether its type works
+	if errors.Is(result.Error, ErrJobCancelled) {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.types == nil {
+		a.types = make(map[string]*JobTypeActivity)
+	}
+	jobType, ok := a.types[result.JobType]
+	if !ok {
+		jobType = &JobTypeActivity{}
+		a.types[result.JobType] = jobType
+	}
+	completedAt := result.CompletedAt.UTC()
+	if result.Success {
+		jobType.LastSuccess = &completedAt
+		jobType.ConsecutiveFailures = 0
+	} else {
+		jobType.LastFailure = &completedAt
+		jobType.ConsecutiveFailures++
+	}
+}
+
+// Activity returns the recent outcome of each job type the pool has run since
+// it started. A pool without workers runs none.
+func (wp *WorkerPool) Activity() map[string]JobTypeActivity {
+	wp.activity.mu.Lock()
+	defer wp.activity.mu.Unlock()
+
+	types := make(map[string]JobTypeActivity, len(wp.activity.types))
+	for name, jobType := range wp.activity.types {
+		types[name] = *jobType
+	}
+	return types
+}
+
+// QueueLen returns the number of jobs waiting in the pool's queue, failing
+// when a shared queue cannot be reached
+func (wp *WorkerPool) QueueLen(ctx context.Context) (int, error) {
+	return wp.queue.Len(ctx)
+}
+
+// QueueCapacity returns the maximum number of waiting jobs, 0 meaning unbounded
+func (wp *WorkerPool) QueueCapacity() int {
+	return wp.queue.Capacity()
+}
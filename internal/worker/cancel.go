@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// cancelPollInterval is how often running jobs are checked for cancellation
+// requested through another process
+const cancelPollInterval = 2 * time.Second
+
+// runningJob is a job attempt in progress in this process
+type runningJob struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// CancelJob stops a job in this process: a running attempt has its context
+// cancelled, and a job waiting in an in-process queue is removed. It reports
+// whether the job was found. Jobs elsewhere are stopped once their processes see
+// the cancelled status in the job store.
+func (wp *WorkerPool) CancelJob(id string) bool {
+	if wp.cancelRunning(id) {
+		return true
+	}
+
+	queue, ok := wp.queue.(interface{ Remove(id string) *Job })
+	if !ok {
+		return false
+	}
+	job := queue.Remove(id)
+	if job == nil {
+		return false
+	}
+	wp.logger.WithFields(logrus.Fields{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+	}).Info("Cancelled job removed from queue")
+	wp.notifyFinished(job, ErrJobCancelled)
+	return true
+}
+
+// cancelRunning cancels a running attempt, reporting whether there was one
+func (wp *WorkerPool) cancelRunning(id string) bool {
+	wp.runningMu.Lock()
+	defer wp.runningMu.Unlock()
+
+	running, ok := wp.running[id]
+	if !ok {
+		return false
+	}
+	running.cancelled = true
+	running.cancel()
+	return true
+}
+
+// startRunning tracks an attempt so it can be cancelled
+func (wp *WorkerPool) startRunning(id string, cancel context.CancelFunc) {
+	wp.runningMu.Lock()
+	defer wp.runningMu.Unlock()
+	wp.running[id] = &runningJob{cancel: cancel}
+}
+
+// finishRunning stops tracking an attempt, reporting whether it was cancelled
+func (wp *WorkerPool) finishRunning(id string) bool {
+	wp.runningMu.Lock()
+	defer wp.runningMu.Unlock()
+
+	running, ok := wp.running[id]
+	delete(wp.running, id)
+	return ok && running.cancelled
+}
+
+// isCancelled reports whether the job store has the job as cancelled. If the
+// store cannot tell, the job runs.
+func (wp *WorkerPool) isCancelled(logger *logrus.Entry, job *Job) bool {
+	if wp.jobStore == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	defer cancel()
+	cancelled, err := wp.jobStore.Cancelled(ctx, []string{job.ID})
+	if err != nil {
+		logger.WithError(err).Warn("Failed to check whether job is cancelled")
+		return false
+	}
+	return len(cancelled) > 0
+}
+
+// watchCancellations cancels running attempts whose jobs were cancelled through
+// another process, until the pool stops
+func (wp *WorkerPool) watchCancellations() {
+	ticker := time.NewTicker(cancelPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-wp.ctx.Done():
+			return
+		}
+
+		wp.runningMu.Lock()
+		ids := make([]string, 0, len(wp.running))
+		for id := range wp.running {
+			ids = append(ids, id)
+		}
+		wp.runningMu.Unlock()
+		if len(ids) == 0 {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+		cancelled, err := wp.jobStore.Cancelled(ctx, ids)
+		cancel()
+		if err != nil {
+			wp.logger.WithError(err).Warn("Failed to check running jobs for cancellation")
+			continue
+		}
+		for _, id := range cancelled {
+			wp.cancelRunning(id)
+		}
+	}
+}
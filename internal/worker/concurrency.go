@@ -0,0 +1,89 @@
+package worker
+
+import "sync"
+
+// typeLimiter caps how many jobs of each type run at once in a pool. A job that
+// arrives while its type is at the limit is parked rather than blocking the
+// worker, which moves on to other jobs; the worker that frees a slot runs the
+// parked job next. Limits are per process.
+type typeLimiter struct {
+	mu      sync.Mutex
+	limits  map[string]int
+	running map[string]int
+	parked  map[string][]*Job
+}
+
+func newTypeLimiter(limits map[string]int) *typeLimiter {
+	return &typeLimiter{
+		limits:  limits,
+		running: make(map[string]int),
+		parked:  make(map[string][]*Job),
+	}
+}
+
+// acquire takes a slot for job, or parks it and returns false
+func (l *typeLimiter) acquire(job *Job) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, limited := l.limits[job.Type]
+	if !limited || limit <= 0 {
+		return true
+	}
+	if l.running[job.Type] >= limit {
+		l.parked[job.Type] = append(l.parked[job.Type], job)
+		return false
+	}
+	l.running[job.Type]++
+	return true
+}
+
+// release frees a slot of jobType. If a job of that type is parked, the slot
+// passes to it and it is returned for the caller to run.
+func (l *typeLimiter) release(jobType string) *Job {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit, limited := l.limits[jobType]; !limited || limit <= 0 {
+		return nil
+	}
+
+	if parked := l.parked[jobType]; len(parked) > 0 {
+		next := parked[0]
+		parked[0] = nil
+		if len(parked) == 1 {
+			delete(l.parked, jobType)
+		} else {
+			l.parked[jobType] = parked[1:]
+		}
+		return next
+	}
+
+	l.running[jobType]--
+	return nil
+}
+
+// drain removes and returns all parked jobs
+func (l *typeLimiter) drain() []*Job {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var jobs []*Job
+	for jobType, parked := range l.parked {
+		jobs = append(jobs, parked...)
+		delete(l.parked, jobType)
+	}
+	return jobs
+}
+
+// parkedCount returns the number of parked jobs
+func (l *typeLimiter) parkedCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := 0
+	for _, parked := range l.parked {
+		count += len(parked)
+	}
+	return count
+}
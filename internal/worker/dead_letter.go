@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"healthcare-api/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// deadLetterTimeout bounds recording a dead job, independent of the failed job's context
+const deadLetterTimeout = 5 * time.Second
+
+// DeadLetterStore keeps jobs that failed permanently so they can be inspected and
+// requeued. repository.DeadJobRepository is the PostgreSQL implementation.
+type DeadLetterStore interface {
+	Add(ctx context.Context, job *models.DeadJob) error
+}
+
+// SetDeadLetterStore makes the pool record jobs that exhaust their retries, or have
+// no handler, in store. Without a store such jobs are only logged.
+func (wp *WorkerPool) SetDeadLetterStore(store DeadLetterStore) {
+	wp.deadLetters = store
+}
+
+// deadLetter records a permanently failed job
+func (wp *WorkerPool) deadLetter(logger *logrus.Entry, job *Job, jobErr error) {
+	if wp.deadLetters == nil {
+		return
+	}
+
+	dead := &models.DeadJob{
+		ID:         job.ID,
+		JobType:    job.Type,
+		Payload:    deadJobPayload(job.Payload),
+		Retries:    job.Retries,
+		MaxRetries: job.MaxRetries,
+		Timeout:    job.Timeout,
+		Priority:   int(job.Priority),
+		Error:      jobErr.Error(),
+		CreatedAt:  job.CreatedAt,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deadLetterTimeout)
+	defer cancel()
+	if err := wp.deadLetters.Add(ctx, dead); err != nil {
+		logger.WithError(err).Error("Failed to record dead job")
+		return
+	}
+	logger.Warn("Job moved to dead letter queue")
+}
+
+// Requeue submits a dead job again with a fresh retry budget
+func (wp *WorkerPool) Requeue(dead *models.DeadJob) error {
+	job := &Job{
+		ID:         dead.ID,
+		Type:       dead.JobType,
+		MaxRetries: dead.MaxRetries,
+		CreatedAt:  time.Now().UTC(),
+		Timeout:    dead.Timeout,
+		Priority:   Priority(dead.Priority),
+	}
+	if len(dead.Payload) > 0 {
+		job.Payload = []byte(dead.Payload)
+	}
+	return wp.SubmitJob(job)
+}
+
+// deadJobPayload stores JSON payloads as-is; anything else is JSON-encoded
+func deadJobPayload(payload interface{}) json.RawMessage {
+	if payload == nil {
+		return nil
+	}
+	if data, ok := payload.([]byte); ok && json.Valid(data) {
+		return data
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -2,13 +2,19 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"healthcare-api/internal/empi"
+	"healthcare-api/internal/events"
+	"healthcare-api/internal/hl7v2"
 	"healthcare-api/internal/models"
+	"healthcare-api/internal/notify"
+	"healthcare-api/internal/requestctx"
 	"healthcare-api/internal/service"
 
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -28,18 +34,26 @@ func NewPatientIndexHandler(patientService *service.PatientService, logger *logr
 
 // Handle processes patient indexing jobs
 func (h *PatientIndexHandler) Handle(ctx context.Context, job *Job) error {
-	h.logger.WithField("job_id", job.ID).Info("Processing patient index job")
+	h.logger.WithContext(ctx).WithField("job_id", job.ID).Info("Processing patient index job")
 	
 	// Parse job payload
-	var payload PatientIndexPayload
-	if err := json.Unmarshal(job.Payload.([]byte), &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	payload, err := DecodePayload[PatientIndexPayload](job)
+	if err != nil {
+		return err
 	}
 	
-	// Simulate indexing work (in real implementation, this would update search indices)
-	time.Sleep(100 * time.Millisecond)
+	patientID, err := uuid.Parse(payload.PatientID)
+	if err != nil {
+		return fmt.Errorf("invalid patient id %q: %w", payload.PatientID, err)
+	}
+
+	// Jobs run outside the request, so restore its tenant
+	ctx = requestctx.WithTenantID(ctx, job.TenantID)
+	if err := h.patientService.IndexPatient(ctx, patientID); err != nil {
+		return fmt.Errorf("failed to index patient: %w", err)
+	}
 	
-	h.logger.WithFields(logrus.Fields{
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
 		"job_id":     job.ID,
 		"patient_id": payload.PatientID,
 		"action":     payload.Action,
@@ -54,10 +68,7 @@ func (h *PatientIndexHandler) GetJobType() string {
 }
 
 // PatientIndexPayload represents the payload for patient indexing jobs
-type PatientIndexPayload struct {
-	PatientID string `json:"patient_id"`
-	Action    string `json:"action"` // create, update, delete
-}
+type PatientIndexPayload = models.PatientIndexPayload
 
 // ObservationProcessHandler handles observation processing jobs
 type ObservationProcessHandler struct {
@@ -75,18 +86,34 @@ func NewObservationProcessHandler(observationService *service.ObservationService
 
 // Handle processes observation processing jobs
 func (h *ObservationProcessHandler) Handle(ctx context.Context, job *Job) error {
-	h.logger.WithField("job_id", job.ID).Info("Processing observation job")
+	h.logger.WithContext(ctx).WithField("job_id", job.ID).Info("Processing observation job")
 	
 	// Parse job payload
-	var payload ObservationProcessPayload
-	if err := json.Unmarshal(job.Payload.([]byte), &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	payload, err := DecodePayload[ObservationProcessPayload](job)
+	if err != nil {
+		return err
 	}
 	
+	observationID, err := uuid.Parse(payload.ObservationID)
+	if err != nil {
+		return fmt.Errorf("invalid observation id %q: %w", payload.ObservationID, err)
+	}
+
+	// Jobs run outside the request, so restore its tenant
+	ctx = requestctx.WithTenantID(ctx, job.TenantID)
+	if err := h.observationService.IndexObservation(ctx, observationID); err != nil {
+		return fmt.Errorf("failed to index observation: %w", err)
+	}
+	if payload.Action == service.ActionCreate || payload.Action == service.ActionUpdate {
+		if err := h.observationService.AlertCriticalResult(ctx, observationID); err != nil {
+			return fmt.Errorf("failed to alert critical result: %w", err)
+		}
+	}
+
 	// Simulate processing work (analytics, alerts, etc.)
 	time.Sleep(200 * time.Millisecond)
 	
-	h.logger.WithFields(logrus.Fields{
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
 		"job_id":        job.ID,
 		"observation_id": payload.ObservationID,
 		"action":        payload.Action,
@@ -101,11 +128,68 @@ func (h *ObservationProcessHandler) GetJobType() string {
 }
 
 // ObservationProcessPayload represents the payload for observation processing jobs
-type ObservationProcessPayload struct {
-	ObservationID string `json:"observation_id"`
-	Action        string `json:"action"` // create, update, delete
+type ObservationProcessPayload = models.ObservationProcessPayload
+
+// ObservationDeriveHandler calculates the observations derived from the
+// measurements of an encounter
+type ObservationDeriveHandler struct {
+	derivedService *service.DerivedObservationService
+	logger         *logrus.Logger
+}
+
+// NewObservationDeriveHandler creates a new derived observation handler
+func NewObservationDeriveHandler(derivedService *service.DerivedObservationService, logger *logrus.Logger) *ObservationDeriveHandler {
+	return &ObservationDeriveHandler{
+		derivedService: derivedService,
+		logger:         logger,
+	}
 }
 
+// Handle recalculates the derived observations of an encounter whose
+// measurements changed
+func (h *ObservationDeriveHandler) Handle(ctx context.Context, job *Job) error {
+	payload, err := DecodePayload[ObservationDerivePayload](job)
+	if err != nil {
+		return err
+	}
+
+	// Jobs run outside the request, so restore its tenant
+	ctx = requestctx.WithTenantID(ctx, job.TenantID)
+	written, err := h.derivedService.Derive(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":    job.ID,
+		"encounter": payload.Encounter,
+		"written":   written,
+	}).Info("Derived observations calculated")
+
+	return nil
+}
+
+// GetJobType returns the job type this handler processes
+func (h *ObservationDeriveHandler) GetJobType() string {
+	return service.DeriveObservationsJobType
+}
+
+// RetryPolicy retries calculations that fail on a transient error; one the
+// server no longer runs is not retried
+func (h *ObservationDeriveHandler) RetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 5,
+		Backoff:    ExponentialBackoff(5*time.Second, 5*time.Minute),
+		Jitter:     0.2,
+		Retryable: func(err error) bool {
+			return !errors.Is(err, models.ErrUnsupported)
+		},
+	}
+}
+
+// ObservationDerivePayload represents the payload for derived observation jobs
+type ObservationDerivePayload = models.ObservationDerivePayload
+
 // AuditLogHandler handles audit log processing jobs
 type AuditLogHandler struct {
 	logger *logrus.Logger
@@ -120,18 +204,18 @@ func NewAuditLogHandler(logger *logrus.Logger) *AuditLogHandler {
 
 // Handle processes audit log jobs
 func (h *AuditLogHandler) Handle(ctx context.Context, job *Job) error {
-	h.logger.WithField("job_id", job.ID).Info("Processing audit log job")
+	h.logger.WithContext(ctx).WithField("job_id", job.ID).Info("Processing audit log job")
 	
 	// Parse job payload
-	var payload AuditLogPayload
-	if err := json.Unmarshal(job.Payload.([]byte), &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	payload, err := DecodePayload[AuditLogPayload](job)
+	if err != nil {
+		return err
 	}
 	
 	// Process audit log (store in long-term storage, send to SIEM, etc.)
 	time.Sleep(50 * time.Millisecond)
 	
-	h.logger.WithFields(logrus.Fields{
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
 		"job_id":        job.ID,
 		"resource_type": payload.ResourceType,
 		"resource_id":   payload.ResourceID,
@@ -147,10 +231,741 @@ func (h *AuditLogHandler) GetJobType() string {
 }
 
 // AuditLogPayload represents the payload for audit log jobs
-type AuditLogPayload struct {
-	ResourceType string `json:"resource_type"`
-	ResourceID   string `json:"resource_id"`
-	Action       string `json:"action"`
-	UserID       string `json:"user_id"`
-	Timestamp    time.Time `json:"timestamp"`
+type AuditLogPayload = models.AuditLogPayload
+
+// RetentionHandler handles scheduled retention jobs
+type RetentionHandler struct {
+	retentionService *service.RetentionService
+	logger           *logrus.Logger
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(retentionService *service.RetentionService, logger *logrus.Logger) *RetentionHandler {
+	return &RetentionHandler{
+		retentionService: retentionService,
+		logger:           logger,
+	}
+}
+
+// Handle archives and purges data past its retention period
+func (h *RetentionHandler) Handle(ctx context.Context, job *Job) error {
+	h.logger.WithContext(ctx).WithField("job_id", job.ID).Info("Processing retention job")
+
+	// Parse job payload
+	payload, err := DecodePayload[RetentionPayload](job)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.retentionService.Run(ctx, payload.DryRun)
+	if err != nil {
+		return fmt.Errorf("failed to run retention policies: %w", err)
+	}
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":   job.ID,
+		"dry_run":  report.DryRun,
+		"policies": len(report.Items),
+	}).Info("Retention job completed")
+
+	return nil
+}
+
+// GetJobType returns the job type this handler processes
+func (h *RetentionHandler) GetJobType() string {
+	return "retention"
+}
+
+// RetryPolicy retries a failed run a few times, backing off for minutes so a
+// database outage has time to clear
+func (h *RetentionHandler) RetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		Backoff:    ExponentialBackoff(time.Minute, 15*time.Minute),
+		Jitter:     0.2,
+	}
+}
+
+// RetentionPayload represents the payload for retention jobs
+type RetentionPayload struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// CacheWarmupHandler preloads in-process caches. It warms the caches of the process
+// that runs it, so schedule it where API requests are served.
+type CacheWarmupHandler struct {
+	tenantService *service.TenantService
+	logger        *logrus.Logger
+}
+
+// NewCacheWarmupHandler creates a new cache warmup handler
+func NewCacheWarmupHandler(tenantService *service.TenantService, logger *logrus.Logger) *CacheWarmupHandler {
+	return &CacheWarmupHandler{
+		tenantService: tenantService,
+		logger:        logger,
+	}
+}
+
+// Handle loads all tenants into the tenant resolution cache
+func (h *CacheWarmupHandler) Handle(ctx context.Context, job *Job) error {
+	tenants, err := h.tenantService.WarmCache(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to warm tenant cache: %w", err)
+	}
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":  job.ID,
+		"tenants": tenants,
+	}).Info("Cache warmup job completed")
+
+	return nil
+}
+
+// GetJobType returns the job type this handler processes
+func (h *CacheWarmupHandler) GetJobType() string {
+	return "cache-warmup"
+}
+
+// JobHistoryCleanupHandler prunes finished jobs, attempt results, webhook
+// deliveries and finished notifications
+type JobHistoryCleanupHandler struct {
+	jobService          *service.JobService
+	webhookService      *service.WebhookService
+	notificationService *service.NotificationService
+	logger              *logrus.Logger
+}
+
+// NewJobHistoryCleanupHandler creates a new job history cleanup handler
+func NewJobHistoryCleanupHandler(jobService *service.JobService, webhookService *service.WebhookService, notificationService *service.NotificationService, logger *logrus.Logger) *JobHistoryCleanupHandler {
+	return &JobHistoryCleanupHandler{
+		jobService:          jobService,
+		webhookService:      webhookService,
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+// Handle deletes job history older than the payload's retention
+func (h *JobHistoryCleanupHandler) Handle(ctx context.Context, job *Job) error {
+	payload, err := DecodePayload[JobHistoryCleanupPayload](job)
+	if err != nil {
+		return err
+	}
+	if payload.Days < 1 {
+		return Permanent(fmt.Errorf("job history retention must be at least 1 day, got %d", payload.Days))
+	}
+
+	retention := time.Duration(payload.Days) * 24 * time.Hour
+	if err := h.jobService.PruneHistory(ctx, retention); err != nil {
+		return err
+	}
+	if err := h.webhookService.PruneDeliveries(ctx, retention); err != nil {
+		return err
+	}
+	return h.notificationService.Prune(ctx, retention)
+}
+
+// GetJobType returns the job type this handler processes
+func (h *JobHistoryCleanupHandler) GetJobType() string {
+	return "job-history-cleanup"
+}
+
+// JobHistoryCleanupPayload represents the payload for job history cleanup jobs
+type JobHistoryCleanupPayload struct {
+	Days int `json:"days"`
+}
+
+// ReindexHandler rebuilds extracted search columns, e.g. after the extraction rules change
+type ReindexHandler struct {
+	reindexService *service.ReindexService
+	logger         *logrus.Logger
+}
+
+// NewReindexHandler creates a new reindex handler
+func NewReindexHandler(reindexService *service.ReindexService, logger *logrus.Logger) *ReindexHandler {
+	return &ReindexHandler{
+		reindexService: reindexService,
+		logger:         logger,
+	}
+}
+
+// Handle reindexes the payload's resource types, reporting progress as it goes
+func (h *ReindexHandler) Handle(ctx context.Context, job *Job) error {
+	h.logger.WithContext(ctx).WithField("job_id", job.ID).Info("Processing reindex job")
+
+	payload, err := DecodePayload[ReindexPayload](job)
+	if err != nil {
+		return err
+	}
+
+	report, err := h.reindexService.Reindex(ctx, payload.TenantID, payload.ResourceTypes, func(done, total int) {
+		if total > 0 {
+			ReportProgress(ctx, done*100/total, fmt.Sprintf("Reindexed %d of %d resources", done, total))
+		}
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrUnsupported) {
+			return Permanent(err)
+		}
+		return err
+	}
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":       job.ID,
+		"tenants":      report.Tenants,
+		"patients":     report.Patients,
+		"observations": report.Observations,
+	}).Info("Reindex job completed")
+
+	return nil
+}
+
+// GetJobType returns the job type this handler processes
+func (h *ReindexHandler) GetJobType() string {
+	return "reindex"
+}
+
+// Timeout allows a whole deployment to be reindexed in one attempt
+func (h *ReindexHandler) Timeout() time.Duration {
+	return 2 * time.Hour
+}
+
+// ReindexPayload represents the payload for reindex jobs
+type ReindexPayload struct {
+	// TenantID limits the run to one tenant; empty reindexes every tenant
+	TenantID string `json:"tenant_id,omitempty"`
+	// ResourceTypes limits the run, e.g. ["Patient"]; empty reindexes all
+	ResourceTypes []string `json:"resource_types,omitempty"`
+}
+
+// BackupHandler handles tenant backup and restore jobs
+type BackupHandler struct {
+	backupService *service.BackupService
+	logger        *logrus.Logger
+}
+
+// NewBackupHandler creates a new backup handler
+func NewBackupHandler(backupService *service.BackupService, logger *logrus.Logger) *BackupHandler {
+	return &BackupHandler{
+		backupService: backupService,
+		logger:        logger,
+	}
+}
+
+// Handle creates a snapshot of a tenant, or rebuilds a tenant from one
+func (h *BackupHandler) Handle(ctx context.Context, job *Job) error {
+	h.logger.WithContext(ctx).WithField("job_id", job.ID).Info("Processing backup job")
+
+	// Parse job payload
+	payload, err := DecodePayload[BackupPayload](job)
+	if err != nil {
+		return err
+	}
+
+	// Jobs run outside the request, so restore its tenant and actor
+	ctx = requestctx.WithTenantID(ctx, payload.TenantID)
+	if payload.UserID != "" {
+		ctx = requestctx.WithUserID(ctx, payload.UserID)
+	}
+
+	switch payload.Action {
+	case BackupActionCreate:
+		if _, err := h.backupService.CreateBackup(ctx, payload.BackupID, payload.Profile); err != nil {
+			return err
+		}
+	case BackupActionRestore:
+		if _, err := h.backupService.RestoreBackup(ctx, payload.BackupID); err != nil {
+			return err
+		}
+	default:
+		return Permanent(fmt.Errorf("unknown backup action %q", payload.Action))
+	}
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":    job.ID,
+		"action":    payload.Action,
+		"backup_id": payload.BackupID,
+		"tenant_id": payload.TenantID,
+	}).Info("Backup job completed")
+
+	return nil
+}
+
+// GetJobType returns the job type this handler processes
+func (h *BackupHandler) GetJobType() string {
+	return "backup"
+}
+
+// RetryPolicy retries backups and restores that fail on a transient error. A
+// restore runs in one transaction, so a retry starts clean; a missing,
+// unsupported or de-identified backup is not retried.
+func (h *BackupHandler) RetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 2,
+		Backoff:    ExponentialBackoff(30*time.Second, 5*time.Minute),
+		Jitter:     0.2,
+		Retryable: func(err error) bool {
+			return !errors.Is(err, models.ErrBackupNotFound) && !errors.Is(err, models.ErrUnsupported) &&
+				!errors.Is(err, models.ErrBackupDeidentified)
+		},
+	}
+}
+
+// Backup job actions
+const (
+	BackupActionCreate  = "create"
+	BackupActionRestore = "restore"
+)
+
+// BackupPayload represents the payload for backup jobs
+type BackupPayload struct {
+	Action   string `json:"action"`
+	BackupID string `json:"backup_id"`
+	TenantID string `json:"tenant_id"`
+	UserID   string `json:"user_id,omitempty"`
+	// Profile de-identifies a created snapshot, e.g. "safe-harbor"
+	Profile string `json:"profile,omitempty"`
+}
+
+// HL7ResultsHandler stores the results of ORU messages accepted by the HL7 service
+type HL7ResultsHandler struct {
+	hl7Service *service.HL7Service
+	logger     *logrus.Logger
+}
+
+// NewHL7ResultsHandler creates a new HL7 results handler
+func NewHL7ResultsHandler(hl7Service *service.HL7Service, logger *logrus.Logger) *HL7ResultsHandler {
+	return &HL7ResultsHandler{
+		hl7Service: hl7Service,
+		logger:     logger,
+	}
+}
+
+// Handle stores the diagnostic reports and observations of a queued ORU message
+func (h *HL7ResultsHandler) Handle(ctx context.Context, job *Job) error {
+	h.logger.WithContext(ctx).WithField("job_id", job.ID).Info("Processing HL7 results job")
+
+	payload, err := DecodePayload[HL7ResultsPayload](job)
+	if err != nil {
+		return err
+	}
+
+	// Jobs run outside the request, so restore its tenant and actor
+	ctx = requestctx.WithTenantID(ctx, job.TenantID)
+	if payload.UserID != "" {
+		ctx = requestctx.WithUserID(ctx, payload.UserID)
+	}
+
+	reports, err := h.hl7Service.ApplyResults(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":     job.ID,
+		"patient_id": payload.PatientID,
+		"reports":    len(reports),
+	}).Info("HL7 results stored successfully")
+
+	return nil
+}
+
+// GetJobType returns the job type this handler processes
+func (h *HL7ResultsHandler) GetJobType() string {
+	return service.HL7ResultsJobType
+}
+
+// RetryPolicy retries results that fail to store on a transient error. The
+// message was validated when it was accepted, so one that no longer converts
+// is not retried.
+func (h *HL7ResultsHandler) RetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 5,
+		Backoff:    ExponentialBackoff(5*time.Second, 5*time.Minute),
+		Jitter:     0.2,
+		Retryable: func(err error) bool {
+			var validationErrors *models.ValidationErrors
+			return !errors.As(err, &validationErrors) &&
+				!errors.Is(err, hl7v2.ErrMalformed) &&
+				!errors.Is(err, hl7v2.ErrInvalidValue) &&
+				!errors.Is(err, models.ErrUnsupported)
+		},
+	}
+}
+
+// HL7ResultsPayload represents the payload for HL7 results jobs
+type HL7ResultsPayload = models.HL7ResultsPayload
+
+// ResourceEventHandler publishes the resource change events recorded with each change
+type ResourceEventHandler struct {
+	publisher events.Publisher
+	logger    *logrus.Logger
+}
+
+// NewResourceEventHandler creates a new resource event handler
+func NewResourceEventHandler(publisher events.Publisher, logger *logrus.Logger) *ResourceEventHandler {
+	return &ResourceEventHandler{
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Handle publishes one resource change event
+func (h *ResourceEventHandler) Handle(ctx context.Context, job *Job) error {
+	payload, err := DecodePayload[ResourceEventPayload](job)
+	if err != nil {
+		return err
+	}
+
+	event := events.Event{
+		ID:           payload.EventID,
+		TenantID:     job.TenantID,
+		ResourceType: payload.ResourceType,
+		ResourceID:   payload.ResourceID,
+		Action:       payload.Action,
+		UserID:       payload.UserID,
+		RequestID:    job.RequestID,
+		Time:         payload.Timestamp,
+	}
+	if err := h.publisher.Publish(ctx, event); err != nil {
+		return err
+	}
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":        job.ID,
+		"event_id":      event.ID,
+		"resource_type": event.ResourceType,
+		"resource_id":   event.ResourceID,
+		"action":        event.Action,
+	}).Debug("Resource event published")
+
+	return nil
+}
+
+// GetJobType returns the job type this handler processes
+func (h *ResourceEventHandler) GetJobType() string {
+	return service.ResourceEventJobType
+}
+
+// RetryPolicy keeps retrying through a transport outage of up to about an
+// hour. Republishing is safe: the event id lets the transport drop duplicates.
+func (h *ResourceEventHandler) RetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 15,
+		Backoff:    ExponentialBackoff(time.Second, 10*time.Minute),
+		Jitter:     0.2,
+	}
+}
+
+// ResourceEventPayload represents the payload for resource event jobs
+type ResourceEventPayload = models.ResourceEventPayload
+
+// WebhookDispatchHandler queues the deliveries of a resource change event to the
+// tenant's webhooks
+type WebhookDispatchHandler struct {
+	webhookService *service.WebhookService
+	logger         *logrus.Logger
+}
+
+// NewWebhookDispatchHandler creates a new webhook dispatch handler
+func NewWebhookDispatchHandler(webhookService *service.WebhookService, logger *logrus.Logger) *WebhookDispatchHandler {
+	return &WebhookDispatchHandler{
+		webhookService: webhookService,
+		logger:         logger,
+	}
+}
+
+// Handle records a delivery job for each webhook receiving the event
+func (h *WebhookDispatchHandler) Handle(ctx context.Context, job *Job) error {
+	payload, err := DecodePayload[ResourceEventPayload](job)
+	if err != nil {
+		return err
+	}
+
+	ctx = requestctx.WithTenantID(ctx, job.TenantID)
+	dispatched, err := h.webhookService.Dispatch(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	if dispatched > 0 {
+		h.logger.WithContext(ctx).WithFields(logrus.Fields{
+			"job_id":     job.ID,
+			"event_id":   payload.EventID,
+			"deliveries": dispatched,
+		}).Info("Webhook deliveries queued")
+	}
+	return nil
+}
+
+// GetJobType returns the job type this handler processes
+func (h *WebhookDispatchHandler) GetJobType() string {
+	return service.WebhookDispatchJobType
+}
+
+// WebhookDeliveryHandler delivers one event to one webhook
+type WebhookDeliveryHandler struct {
+	webhookService *service.WebhookService
+	logger         *logrus.Logger
+}
+
+// NewWebhookDeliveryHandler creates a new webhook delivery handler
+func NewWebhookDeliveryHandler(webhookService *service.WebhookService, logger *logrus.Logger) *WebhookDeliveryHandler {
+	return &WebhookDeliveryHandler{
+		webhookService: webhookService,
+		logger:         logger,
+	}
+}
+
+// Handle posts the event to the webhook; a failed attempt is retried
+func (h *WebhookDeliveryHandler) Handle(ctx context.Context, job *Job) error {
+	payload, err := DecodePayload[WebhookDeliveryPayload](job)
+	if err != nil {
+		return err
+	}
+
+	ctx = requestctx.WithTenantID(ctx, job.TenantID)
+	return h.webhookService.Deliver(ctx, payload, job.Retries+1)
+}
+
+// GetJobType returns the job type this handler processes
+func (h *WebhookDeliveryHandler) GetJobType() string {
+	return service.WebhookDeliveryJobType
+}
+
+// RetryPolicy retries a failed delivery for about four hours, backing off from
+// 30 seconds to an hour between attempts, before it is dead-lettered
+func (h *WebhookDeliveryHandler) RetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 10,
+		Backoff:    ExponentialBackoff(30*time.Second, time.Hour),
+		Jitter:     0.2,
+		Retryable: func(err error) bool {
+			return !errors.Is(err, models.ErrUnsupported)
+		},
+	}
+}
+
+// WebhookDeliveryPayload represents the payload for webhook delivery jobs
+type WebhookDeliveryPayload = models.WebhookDeliveryPayload
+
+// NotificationDeliveryHandler sends one queued notification
+type NotificationDeliveryHandler struct {
+	notificationService *service.NotificationService
+	logger              *logrus.Logger
+}
+
+// NewNotificationDeliveryHandler creates a new notification delivery handler
+func NewNotificationDeliveryHandler(notificationService *service.NotificationService, logger *logrus.Logger) *NotificationDeliveryHandler {
+	return &NotificationDeliveryHandler{
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+// Handle sends the notification; a failed attempt is retried, and the
+// notification is marked failed once its last attempt fails
+func (h *NotificationDeliveryHandler) Handle(ctx context.Context, job *Job) error {
+	payload, err := DecodePayload[NotificationDeliveryPayload](job)
+	if err != nil {
+		return err
+	}
+	notificationID, err := uuid.Parse(payload.NotificationID)
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid notification id %q: %w", payload.NotificationID, err))
+	}
+
+	maxRetries := job.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = h.RetryPolicy().MaxRetries
+	}
+	ctx = requestctx.WithTenantID(ctx, job.TenantID)
+	return h.notificationService.Deliver(ctx, notificationID, job.Retries >= maxRetries)
+}
+
+// GetJobType returns the job type this handler processes
+func (h *NotificationDeliveryHandler) GetJobType() string {
+	return service.NotificationDeliveryJobType
+}
+
+// RetryPolicy retries a failed send for about two hours, backing off from 30
+// seconds to 30 minutes between attempts. Sends the mail server rejected for
+// good are not retried.
+func (h *NotificationDeliveryHandler) RetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 8,
+		Backoff:    ExponentialBackoff(30*time.Second, 30*time.Minute),
+		Jitter:     0.2,
+		Retryable: func(err error) bool {
+			return !errors.Is(err, models.ErrUnsupported) && !notify.Permanent(err)
+		},
+	}
+}
+
+// NotificationEscalationHandler releases SMS pages held over quiet hours and
+// escalates unacknowledged ones, in every tenant
+type NotificationEscalationHandler struct {
+	notificationService *service.NotificationService
+	logger              *logrus.Logger
+}
+
+// NewNotificationEscalationHandler creates a new notification escalation handler
+func NewNotificationEscalationHandler(notificationService *service.NotificationService, logger *logrus.Logger) *NotificationEscalationHandler {
+	return &NotificationEscalationHandler{
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+// Handle releases due pages and escalates overdue ones; a failed run leaves
+// the rest for the next one
+func (h *NotificationEscalationHandler) Handle(ctx context.Context, job *Job) error {
+	released, escalated, err := h.notificationService.RunEscalations(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run notification escalations: %w", err)
+	}
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":    job.ID,
+		"released":  released,
+		"escalated": escalated,
+	}).Info("Notification escalation job completed")
+
+	return nil
+}
+
+// GetJobType returns the job type this handler processes
+func (h *NotificationEscalationHandler) GetJobType() string {
+	return "notification-escalation"
+}
+
+// NotificationDeliveryPayload represents the payload for notification delivery jobs
+type NotificationDeliveryPayload = models.NotificationDeliveryPayload
+
+// EMPISyncHandler looks patients up in the EMPI when they are created, updated
+// or restored, storing the enterprise identifier found
+type EMPISyncHandler struct {
+	empiService *service.EMPIService
+	logger      *logrus.Logger
+}
+
+// NewEMPISyncHandler creates a new EMPI sync handler
+func NewEMPISyncHandler(empiService *service.EMPIService, logger *logrus.Logger) *EMPISyncHandler {
+	return &EMPISyncHandler{
+		empiService: empiService,
+		logger:      logger,
+	}
+}
+
+// Handle looks up the patient of a resource change event; events of other
+// resources and of patients deleted since are skipped
+func (h *EMPISyncHandler) Handle(ctx context.Context, job *Job) error {
+	payload, err := DecodePayload[ResourceEventPayload](job)
+	if err != nil {
+		return err
+	}
+
+	ctx = requestctx.WithTenantID(ctx, job.TenantID)
+	err = h.empiService.HandleEvent(ctx, payload)
+	if errors.Is(err, models.ErrPatientNotFound) {
+		return nil
+	}
+	return err
+}
+
+// GetJobType returns the job type this handler processes
+func (h *EMPISyncHandler) GetJobType() string {
+	return service.EMPISyncJobType
+}
+
+// RetryPolicy retries a failed lookup for about an hour, backing off from a
+// minute to 15 minutes between attempts. Lookups the EMPI rejected are not
+// retried; the empi-reconciliation job tries every failed one again later.
+func (h *EMPISyncHandler) RetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 6,
+		Backoff:    ExponentialBackoff(time.Minute, 15*time.Minute),
+		Jitter:     0.2,
+		Retryable: func(err error) bool {
+			return !empi.Permanent(err)
+		},
+	}
+}
+
+// EMPIReconciliationHandler looks up again the patients the EMPI disagreed on
+// or could not be asked about, in every tenant
+type EMPIReconciliationHandler struct {
+	empiService *service.EMPIService
+	logger      *logrus.Logger
+}
+
+// NewEMPIReconciliationHandler creates a new EMPI reconciliation handler
+func NewEMPIReconciliationHandler(empiService *service.EMPIService, logger *logrus.Logger) *EMPIReconciliationHandler {
+	return &EMPIReconciliationHandler{
+		empiService: empiService,
+		logger:      logger,
+	}
+}
+
+// Handle reconciles a batch of links; a failed run leaves the rest for the next one
+func (h *EMPIReconciliationHandler) Handle(ctx context.Context, job *Job) error {
+	checked, linked, err := h.empiService.Reconcile(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile EMPI links: %w", err)
+	}
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":  job.ID,
+		"checked": checked,
+		"linked":  linked,
+	}).Info("EMPI reconciliation job completed")
+
+	return nil
+}
+
+// GetJobType returns the job type this handler processes
+func (h *EMPIReconciliationHandler) GetJobType() string {
+	return "empi-reconciliation"
+}
+
+// DuplicateDetectionHandler scans the patients of every tenant for probable duplicates
+type DuplicateDetectionHandler struct {
+	duplicateService *service.DuplicateService
+	logger           *logrus.Logger
+}
+
+// NewDuplicateDetectionHandler creates a new duplicate detection handler
+func NewDuplicateDetectionHandler(duplicateService *service.DuplicateService, logger *logrus.Logger) *DuplicateDetectionHandler {
+	return &DuplicateDetectionHandler{
+		duplicateService: duplicateService,
+		logger:           logger,
+	}
+}
+
+// Handle runs a scan; a failed run keeps the pairs found so far, and the next
+// one starts over
+func (h *DuplicateDetectionHandler) Handle(ctx context.Context, job *Job) error {
+	scanned, found, created, err := h.duplicateService.Detect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to detect duplicate patients: %w", err)
+	}
+
+	h.logger.WithContext(ctx).WithFields(logrus.Fields{
+		"job_id":  job.ID,
+		"scanned": scanned,
+		"found":   found,
+		"created": created,
+	}).Info("Duplicate detection job completed")
+
+	return nil
+}
+
+// GetJobType returns the job type this handler processes
+func (h *DuplicateDetectionHandler) GetJobType() string {
+	return "duplicate-detection"
+}
+
+// Timeout allows a scan of every tenant's patients an hour
+func (h *DuplicateDetectionHandler) Timeout() time.Duration {
+	return time.Hour
 }
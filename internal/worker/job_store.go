@@ -0,0 +1,158 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"healthcare-api/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// jobStoreTimeout bounds a single job status update
+const jobStoreTimeout = 5 * time.Second
+
+// JobStore tracks job status for the jobs API. repository.JobRepository is the
+// PostgreSQL implementation; every process that submits or runs jobs should use it.
+type JobStore interface {
+	Queued(ctx context.Context, job *models.BackgroundJob) error
+	Transition(ctx context.Context, id, status string, jobErr error) error
+	Progress(ctx context.Context, id string, percent int, message string) error
+	// Discard removes the record of a job that was never queued
+	Discard(ctx context.Context, id string) error
+	// AddResult records the outcome of one attempt
+	AddResult(ctx context.Context, result *models.JobResult) error
+	// Cancelled returns which of ids belong to cancelled jobs
+	Cancelled(ctx context.Context, ids []string) ([]string, error)
+}
+
+// SetJobStore makes the pool record the status of every job it submits or runs
+func (wp *WorkerPool) SetJobStore(store JobStore) {
+	wp.jobStore = store
+}
+
+// recordQueued records a submitted job as waiting
+func (wp *WorkerPool) recordQueued(job *Job) {
+	if wp.jobStore == nil {
+		return
+	}
+
+	record := &models.BackgroundJob{
+		ID:         job.ID,
+		Status:     models.JobStatusQueued,
+		JobType:    job.Type,
+		Priority:   int(job.Priority),
+		Payload:    deadJobPayload(job.Payload),
+		Retries:    job.Retries,
+		MaxRetries: job.MaxRetries,
+		CreatedAt:  job.CreatedAt,
+	}
+	if job.TenantID != "" {
+		record.TenantID = &job.TenantID
+	}
+	if job.Retries > 0 {
+		record.Status = models.JobStatusRetrying
+	}
+	if !job.RunAt.IsZero() {
+		runAt := job.RunAt.UTC()
+		record.RunAt = &runAt
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	defer cancel()
+	if err := wp.jobStore.Queued(ctx, record); err != nil {
+		wp.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to record queued job")
+	}
+}
+
+// recordDiscarded removes the record of a job that collapsed into a pending duplicate
+func (wp *WorkerPool) recordDiscarded(job *Job) {
+	if wp.jobStore == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	defer cancel()
+	if err := wp.jobStore.Discard(ctx, job.ID); err != nil {
+		wp.logger.WithError(err).WithField("job_id", job.ID).Warn("Failed to discard duplicate job")
+	}
+}
+
+// recordResult records the outcome of one attempt
+func (wp *WorkerPool) recordResult(result *JobResult) {
+	if wp.jobStore == nil {
+		return
+	}
+
+	record := &models.JobResult{
+		JobID:       result.JobID,
+		JobType:     result.JobType,
+		Attempt:     result.Attempt,
+		Success:     result.Success,
+		DurationMS:  result.Duration.Milliseconds(),
+		CompletedAt: result.CompletedAt.UTC(),
+	}
+	if result.TenantID != "" {
+		record.TenantID = &result.TenantID
+	}
+	if result.Error != nil {
+		text := result.Error.Error()
+		record.Error = &text
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	defer cancel()
+	if err := wp.jobStore.AddResult(ctx, record); err != nil {
+		wp.logger.WithError(err).WithField("job_id", result.JobID).Warn("Failed to record job result")
+	}
+}
+
+// recordStatus records a job status change. Like recordQueued it only logs
+// failures: status tracking must not stop work from being done.
+func (wp *WorkerPool) recordStatus(logger *logrus.Entry, job *Job, status string, jobErr error) {
+	if wp.jobStore == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+	defer cancel()
+	if err := wp.jobStore.Transition(ctx, job.ID, status, jobErr); err != nil {
+		logger.WithError(err).WithField("status", status).Warn("Failed to record job status")
+	}
+}
+
+type progressKey struct{}
+
+// progressReporter is carried in a job's context so handlers can report progress
+type progressReporter func(percent int, message string)
+
+// ReportProgress records how far a running job has got, as a percentage with an
+// optional message. It is a no-op when the pool does not track job status.
+func ReportProgress(ctx context.Context, percent int, message string) {
+	report, ok := ctx.Value(progressKey{}).(progressReporter)
+	if !ok {
+		return
+	}
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	report(percent, message)
+}
+
+// withProgress returns a job context whose progress reports go to the job store
+func (wp *WorkerPool) withProgress(ctx context.Context, logger *logrus.Entry, job *Job) context.Context {
+	if wp.jobStore == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, progressKey{}, progressReporter(func(percent int, message string) {
+		storeCtx, cancel := context.WithTimeout(context.Background(), jobStoreTimeout)
+		defer cancel()
+		if err := wp.jobStore.Progress(storeCtx, job.ID, percent, message); err != nil {
+			logger.WithError(err).Warn("Failed to record job progress")
+		}
+	}))
+}
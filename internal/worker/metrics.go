@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"time"
+
+	"healthcare-api/internal/monitoring"
+)
+
+// metricsInterval is how often the pool's queue statistics are sampled
+const metricsInterval = 15 * time.Second
+
+// SetMetrics makes the pool report its statistics and every job attempt to
+// metrics under name. Must be called before Start.
+func (wp *WorkerPool) SetMetrics(metrics *monitoring.Metrics, name string) {
+	wp.metrics = metrics
+	wp.metricsName = name
+}
+
+// recordMetrics counts a finished attempt
+func (wp *WorkerPool) recordMetrics(result *JobResult) {
+	if wp.metrics == nil {
+		return
+	}
+	wp.metrics.RecordJob(wp.metricsName, result.JobType, result.Success, result.Duration)
+}
+
+// reportMetrics samples the pool's statistics until Stop begins
+func (wp *WorkerPool) reportMetrics() {
+	ticker := time.NewTicker(metricsInterval)
+	defer ticker.Stop()
+
+	for {
+		stats := wp.GetStats()
+		wp.metrics.UpdateWorkerPoolStats(wp.metricsName, monitoring.WorkerPoolMetrics{
+			QueueSize:     stats.QueuedJobs,
+			QueueCapacity: stats.QueueCapacity,
+			Workers:       stats.Workers,
+			ParkedJobs:    stats.ParkedJobs,
+			UpdatedAt:     time.Now().UTC(),
+		})
+
+		select {
+		case <-ticker.C:
+		case <-wp.intake.Done():
+			return
+		}
+	}
+}
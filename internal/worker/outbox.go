@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"healthcare-api/internal/models"
+)
+
+const (
+	// outboxPollInterval is how often the outbox is checked for new entries
+	outboxPollInterval = time.Second
+	// outboxBatchSize is how many entries are submitted per outbox transaction
+	outboxBatchSize = 100
+)
+
+// OutboxStore hands out jobs recorded alongside resource changes.
+// repository.OutboxRepository is the PostgreSQL implementation.
+type OutboxStore interface {
+	Dispatch(ctx context.Context, limit int, submit func(*models.OutboxEntry) error) (int, error)
+}
+
+// SetOutbox makes the pool submit the jobs waiting in outbox. Several processes
+// may relay the same outbox. Must be called before Start.
+func (wp *WorkerPool) SetOutbox(outbox OutboxStore) {
+	wp.outbox = outbox
+}
+
+// relayOutbox submits outbox entries as jobs until Stop begins. An entry that
+// cannot be submitted, e.g. because the queue is full, stays in the outbox and
+// is tried again on the next poll.
+func (wp *WorkerPool) relayOutbox() {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-wp.intake.Done():
+			return
+		}
+
+		// Keep going while batches come back full
+		for {
+			ctx, cancel := context.WithTimeout(wp.intake, jobStoreTimeout)
+			dispatched, err := wp.outbox.Dispatch(ctx, outboxBatchSize, wp.submitOutboxEntry)
+			cancel()
+			if err != nil {
+				if wp.intake.Err() == nil {
+					wp.logger.WithError(err).WithField("dispatched", dispatched).Warn("Failed to relay job outbox")
+				}
+				break
+			}
+			if dispatched < outboxBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// submitOutboxEntry submits an outbox entry as a job. The job takes the entry's
+// ID, and its dedup key collapses a resubmission while the job is still waiting.
+func (wp *WorkerPool) submitOutboxEntry(entry *models.OutboxEntry) error {
+	job := &Job{
+		ID:        entry.ID,
+		Type:      entry.JobType,
+		CreatedAt: entry.CreatedAt,
+		DedupKey:  "outbox:" + entry.ID,
+	}
+	if len(entry.Payload) > 0 {
+		job.Payload = []byte(entry.Payload)
+	}
+	if entry.TenantID != nil {
+		job.TenantID = *entry.TenantID
+	}
+	if entry.RequestID != nil {
+		job.RequestID = *entry.RequestID
+	}
+	return wp.SubmitJob(job)
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PanicError is the error of a job attempt whose handler panicked
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("job handler panicked: %v", e.Value)
+}
+
+// runHandler runs one attempt of a job, recovering a panic in the handler so the
+// worker survives it. A panic is a bug rather than a passing fault, so it fails
+// the job without retries; it can be requeued from the dead letter queue once
+// fixed. Goroutines started by the handler are not covered.
+func (wp *WorkerPool) runHandler(ctx context.Context, logger *logrus.Entry, handler JobHandler, job *Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			panicErr := &PanicError{Value: recovered, Stack: debug.Stack()}
+			logger.WithFields(logrus.Fields{
+				"panic": fmt.Sprint(recovered),
+				"stack": string(panicErr.Stack),
+			}).Error("Job handler panicked")
+			err = Permanent(panicErr)
+		}
+	}()
+
+	return handler.Handle(ctx, job)
+}
@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// pauseGate holds workers back from taking jobs while the pool is paused. Its
+// zero value is an open gate.
+type pauseGate struct {
+	mu sync.Mutex
+	// taking is cancelled when the pool is paused, ending waits for a job
+	taking context.Context
+	pause  context.CancelFunc
+	// resumed is closed when the pool is resumed
+	resumed chan struct{}
+}
+
+func (g *pauseGate) init() {
+	if g.taking == nil {
+		g.taking, g.pause = context.WithCancel(context.Background())
+	}
+}
+
+// Pause stops workers from taking jobs. Jobs already running finish, jobs
+// parked for their type's concurrency limit go back to the queue, and jobs
+// submitted meanwhile wait in it. A worker of another process sharing the
+// queue is not paused.
+func (wp *WorkerPool) Pause() {
+	g := &wp.pause
+	g.mu.Lock()
+	g.init()
+	if g.resumed != nil {
+		g.mu.Unlock()
+		return
+	}
+	g.pause()
+	g.resumed = make(chan struct{})
+	g.mu.Unlock()
+
+	for _, job := range wp.limiter.drain() {
+		wp.requeue(job)
+	}
+	wp.logger.Info("Worker pool paused")
+}
+
+// Resume lets workers take jobs again after Pause
+func (wp *WorkerPool) Resume() {
+	g := &wp.pause
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.resumed == nil {
+		return
+	}
+	g.taking, g.pause = context.WithCancel(context.Background())
+	close(g.resumed)
+	g.resumed = nil
+	wp.logger.Info("Worker pool resumed")
+}
+
+// Paused reports whether the pool is paused
+func (wp *WorkerPool) Paused() bool {
+	wp.pause.mu.Lock()
+	defer wp.pause.mu.Unlock()
+	return wp.pause.resumed != nil
+}
+
+// takeContext waits while the pool is paused, then returns the context of
+// waiting for a job: done when stop is or the pool is paused. It returns a nil
+// context once stop is done.
+func (wp *WorkerPool) takeContext(stop context.Context) (context.Context, context.CancelFunc) {
+	for {
+		g := &wp.pause
+		g.mu.Lock()
+		g.init()
+		taking, resumed := g.taking, g.resumed
+		g.mu.Unlock()
+
+		if resumed != nil {
+			select {
+			case <-resumed:
+				continue
+			case <-stop.Done():
+				return nil, nil
+			}
+		}
+
+		ctx, cancel := context.WithCancel(stop)
+		stopAfter := context.AfterFunc(taking, cancel)
+		return ctx, func() {
+			stopAfter()
+			cancel()
+		}
+	}
+}
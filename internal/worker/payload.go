@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// encodePayload returns a job payload in the JSON form jobs carry through queues
+// and stores. []byte and json.RawMessage are taken as already encoded; any other
+// value, typically a payload struct, is marshalled.
+func encodePayload(payload interface{}) ([]byte, error) {
+	switch p := payload.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return p, nil
+	case json.RawMessage:
+		return p, nil
+	default:
+		data, err := json.Marshal(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %T job payload: %w", p, err)
+		}
+		return data, nil
+	}
+}
+
+// DecodePayload returns a job's payload as T, typically the handler's payload
+// struct. A job without a payload yields the zero T. A payload that does not
+// decode fails the job without retries, since running it again cannot help.
+func DecodePayload[T any](job *Job) (T, error) {
+	var payload T
+	if typed, ok := job.Payload.(T); ok {
+		return typed, nil
+	}
+
+	data, err := encodePayload(job.Payload)
+	if err != nil {
+		return payload, Permanent(err)
+	}
+	if len(data) == 0 {
+		return payload, nil
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return payload, Permanent(fmt.Errorf("failed to unmarshal payload: %w", err))
+	}
+	return payload, nil
+}
@@ -2,9 +2,14 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 
+	"healthcare-api/internal/models"
+	"healthcare-api/internal/monitoring"
+	"healthcare-api/internal/requestctx"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -12,15 +17,48 @@ import (
 type Job struct {
 	ID       string
 	Type     string
+	// Payload is the handler's input: a payload struct, or JSON as []byte.
+	// SubmitJob encodes it to JSON; handlers read it with DecodePayload.
 	Payload  interface{}
 	Retries  int
 	MaxRetries int
 	CreatedAt time.Time
+	// Timeout overrides defaultJobTimeout for long-running jobs such as backups
+	Timeout   time.Duration
+	// Schedule names the recurring schedule that created the job, if any
+	Schedule  string
+	// Priority orders waiting jobs; the zero value is PriorityNormal
+	Priority  Priority
+	// TenantID is the tenant the job works on, if any, for the jobs API
+	TenantID  string
+	// RequestID is the request that submitted the job, if any; it is carried by
+	// the job's context and logs for correlation
+	RequestID string
+	// DedupKey identifies the logical work; while a job with the same key is
+	// waiting, submitting another collapses into it
+	DedupKey  string
+	// RunAt delays the job until then; the zero value runs it as soon as possible
+	RunAt     time.Time
+	// lease identifies the queue lease held while the job runs, if any
+	lease     string
+}
+
+// defaultJobTimeout bounds a single job attempt unless the job or its handler sets its own timeout
+const defaultJobTimeout = 30 * time.Second
+
+// TimeoutProvider is implemented by job handlers whose jobs need a different
+// default timeout; a job's own Timeout still takes precedence
+type TimeoutProvider interface {
+	Timeout() time.Duration
 }
 
 // JobResult represents the result of a job execution
 type JobResult struct {
 	JobID     string
+	JobType   string
+	TenantID  string
+	// Attempt numbers the attempt, starting at 1
+	Attempt   int
 	Success   bool
 	Error     error
 	Duration  time.Duration
@@ -36,98 +74,355 @@ type JobHandler interface {
 // WorkerPool manages a pool of workers for concurrent job processing
 type WorkerPool struct {
 	workers     int
-	jobQueue    chan *Job
+	queue       Queue
 	resultQueue chan *JobResult
-	quit        chan bool
+	resultsDone chan struct{}
 	wg          sync.WaitGroup
 	handlers    map[string]JobHandler
+	deadLetters DeadLetterStore
+	jobStore    JobStore
+	outbox      OutboxStore
+	limiter     *typeLimiter
+	finished    []func(job *Job, err error)
 	logger      *logrus.Logger
+	// intake is cancelled when Stop begins, so workers take no more jobs; ctx
+	// is cancelled when running jobs must give up
+	intake      context.Context
+	stopIntake  context.CancelFunc
 	ctx         context.Context
 	cancel      context.CancelFunc
+	drainTimeout time.Duration
+	closeMu     sync.RWMutex
+	closed      bool
+	metrics     *monitoring.Metrics
+	metricsName string
+	runningMu   sync.Mutex
+	running     map[string]*runningJob
+	// workersMu guards workers and the stop functions of running workers
+	workersMu   sync.Mutex
+	workerStops []context.CancelFunc
+	nextWorker  int
+	started     bool
+	activity    activity
+	pause       pauseGate
 }
 
-// NewWorkerPool creates a new worker pool
-func NewWorkerPool(workers int, queueSize int, logger *logrus.Logger) *WorkerPool {
+const (
+	// defaultDrainTimeout is how long Stop waits for running jobs by default
+	defaultDrainTimeout = 30 * time.Second
+	// cancelGrace is how long Stop waits for cancelled jobs to return
+	cancelGrace = 10 * time.Second
+	// submitTimeout bounds enqueueing a single job
+	submitTimeout = 5 * time.Second
+)
+
+// NewWorkerPool creates a worker pool consuming from queue. A pool with zero
+// workers only submits jobs, for API instances whose jobs run elsewhere.
+func NewWorkerPool(workers int, queue Queue, logger *logrus.Logger) *WorkerPool {
 	ctx, cancel := context.WithCancel(context.Background())
+	intake, stopIntake := context.WithCancel(ctx)
 	
 	return &WorkerPool{
 		workers:     workers,
-		jobQueue:    make(chan *Job, queueSize),
-		resultQueue: make(chan *JobResult, queueSize),
-		quit:        make(chan bool),
+		queue:       queue,
+		resultQueue: make(chan *JobResult, 1000),
+		resultsDone: make(chan struct{}),
 		handlers:    make(map[string]JobHandler),
+		limiter:     newTypeLimiter(nil),
 		logger:      logger,
+		intake:      intake,
+		stopIntake:  stopIntake,
 		ctx:         ctx,
 		cancel:      cancel,
+		drainTimeout: defaultDrainTimeout,
+		running:     make(map[string]*runningJob),
 	}
 }
 
+// SetDrainTimeout sets how long Stop waits for running jobs to finish before
+// cancelling them
+func (wp *WorkerPool) SetDrainTimeout(timeout time.Duration) {
+	wp.drainTimeout = timeout
+}
+
 // RegisterHandler registers a job handler for a specific job type
 func (wp *WorkerPool) RegisterHandler(handler JobHandler) {
 	wp.handlers[handler.GetJobType()] = handler
 }
 
+// SetTypeConcurrency caps how many jobs of each type run at once in this pool,
+// e.g. {"backup": 1}. Types without a limit use any free worker. Must be called
+// before Start.
+func (wp *WorkerPool) SetTypeConcurrency(limits map[string]int) {
+	wp.limiter = newTypeLimiter(limits)
+}
+
+// HasHandler reports whether a handler is registered for jobType
+func (wp *WorkerPool) HasHandler(jobType string) bool {
+	_, exists := wp.handlers[jobType]
+	return exists
+}
+
 // Start starts the worker pool
 func (wp *WorkerPool) Start() {
 	wp.logger.Infof("Starting worker pool with %d workers", wp.workers)
 	
 	// Start workers
-	for i := 0; i < wp.workers; i++ {
-		wp.wg.Add(1)
-		go wp.worker(i)
-	}
+	wp.workersMu.Lock()
+	wp.started = true
+	wp.scale(wp.workers)
+	wp.workersMu.Unlock()
 	
 	// Start result processor
 	go wp.processResults()
+	
+	if wp.metrics != nil {
+		go wp.reportMetrics()
+	}
+	if wp.jobStore != nil {
+		go wp.watchCancellations()
+	}
+	if wp.outbox != nil {
+		go wp.relayOutbox()
+	}
 }
 
-// Stop gracefully stops the worker pool
+// Stop drains the worker pool: workers stop taking jobs, running jobs get until
+// the drain timeout to finish, and jobs still running then are cancelled and put
+// back on the queue. Jobs that cannot outlive the process, such as those waiting
+// in an in-memory queue, are moved to the dead letter store.
 func (wp *WorkerPool) Stop() {
 	wp.logger.Info("Stopping worker pool...")
 	
-	close(wp.quit)
+	// Under workersMu so Resize cannot start a worker once draining has begun
+	wp.workersMu.Lock()
+	wp.stopIntake()
+	wp.workersMu.Unlock()
+	
+	done := make(chan struct{})
+	go func() {
+		wp.wg.Wait()
+		close(done)
+	}()
+	
+	workersStopped := true
+	select {
+	case <-done:
+	case <-time.After(wp.drainTimeout):
+		wp.logger.WithField("drain_timeout", wp.drainTimeout).Warn("Jobs still running at drain deadline, cancelling them")
+		wp.cancel()
+		select {
+		case <-done:
+		case <-time.After(cancelGrace):
+			wp.logger.Error("Jobs ignored cancellation, abandoning them")
+			workersStopped = false
+		}
+	}
 	wp.cancel()
-	wp.wg.Wait()
 	
-	close(wp.jobQueue)
-	close(wp.resultQueue)
+	// Jobs taken from the queue but never started go back to it
+	for _, job := range wp.limiter.drain() {
+		wp.requeue(job)
+	}
+	wp.persistWaiting()
+	
+	wp.closeMu.Lock()
+	wp.closed = true
+	wp.closeMu.Unlock()
+	
+	if err := wp.queue.Close(); err != nil {
+		wp.logger.WithError(err).Warn("Failed to close job queue")
+	}
+	// Abandoned workers may still send results
+	if workersStopped {
+		close(wp.resultQueue)
+		<-wp.resultsDone
+	}
 	
 	wp.logger.Info("Worker pool stopped")
 }
 
-// SubmitJob submits a job to the worker pool
-func (wp *WorkerPool) SubmitJob(job *Job) error {
-	select {
-	case wp.jobQueue <- job:
-		wp.logger.WithFields(logrus.Fields{
+// StopTimeout is about the longest Stop takes: the drain timeout, the grace
+// cancelled jobs get to return, and the time to put unfinished jobs back on the
+// queue
+func (wp *WorkerPool) StopTimeout() time.Duration {
+	return wp.drainTimeout + cancelGrace + submitTimeout
+}
+
+// requeue puts a job that was taken from the queue but did not finish back on it,
+// without counting an attempt. If that fails the job is dead-lettered.
+func (wp *WorkerPool) requeue(job *Job) {
+	logger := wp.logger.WithFields(logrus.Fields{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+	})
+	
+	defer wp.ack(logger, job)
+	
+	job.RunAt = time.Time{}
+	if err := wp.SubmitJob(job); err != nil {
+		logger.WithError(err).Error("Failed to requeue unfinished job")
+		wp.recordStatus(logger, job, models.JobStatusFailed, ErrPoolStopped)
+		wp.deadLetter(logger, job, ErrPoolStopped)
+		return
+	}
+	logger.Info("Unfinished job requeued")
+}
+
+// ack ends a leasing queue's lease on a job whose outcome has been recorded. If
+// that fails the lease expires and the job runs again, so it is only logged.
+func (wp *WorkerPool) ack(logger *logrus.Entry, job *Job) {
+	queue, ok := wp.queue.(LeasingQueue)
+	if !ok {
+		return
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), submitTimeout)
+	defer cancel()
+	if err := queue.Ack(ctx, job); err != nil {
+		logger.WithError(err).Warn("Failed to acknowledge job")
+	}
+}
+
+// persistWaiting moves jobs waiting in a queue that lives in this process to the
+// dead letter store, from which they can be requeued after a restart
+func (wp *WorkerPool) persistWaiting() {
+	queue, ok := wp.queue.(interface{ Drain() []*Job })
+	if !ok {
+		return
+	}
+	
+	jobs := queue.Drain()
+	for _, job := range jobs {
+		logger := wp.logger.WithFields(logrus.Fields{
 			"job_id":   job.ID,
 			"job_type": job.Type,
-		}).Debug("Job submitted to queue")
-		return nil
-	case <-wp.ctx.Done():
-		return wp.ctx.Err()
-	default:
-		return ErrQueueFull
+		})
+		wp.recordStatus(logger, job, models.JobStatusFailed, ErrPoolStopped)
+		wp.deadLetter(logger, job, ErrPoolStopped)
+	}
+	if len(jobs) > 0 {
+		wp.logger.WithField("jobs", len(jobs)).Warn("Moved waiting jobs to the dead letter queue on shutdown")
+	}
+}
+
+// SubmitJob submits a job to the worker pool. When a job with the same DedupKey
+// is already waiting, nothing is queued and job.ID is set to the waiting job's id.
+func (wp *WorkerPool) SubmitJob(job *Job) error {
+	wp.closeMu.RLock()
+	defer wp.closeMu.RUnlock()
+	if wp.closed {
+		return ErrPoolStopped
+	}
+	
+	payload, err := encodePayload(job.Payload)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		job.Payload = payload
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), submitTimeout)
+	defer cancel()
+	
+	wp.recordQueued(job)
+	if err := wp.queue.Enqueue(ctx, job); err != nil {
+		var duplicate *DuplicateJobError
+		if errors.As(err, &duplicate) {
+			wp.recordDiscarded(job)
+			wp.logger.WithFields(logrus.Fields{
+				"job_id":      job.ID,
+				"job_type":    job.Type,
+				"dedup_key":   job.DedupKey,
+				"pending_job": duplicate.PendingID,
+			}).Debug("Job collapsed into pending duplicate")
+			job.ID = duplicate.PendingID
+			return nil
+		}
+		wp.recordStatus(wp.logger.WithField("job_id", job.ID), job, models.JobStatusFailed, err)
+		return err
 	}
+
+	wp.logger.WithFields(logrus.Fields{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+	}).Debug("Job submitted to queue")
+	return nil
+}
+
+// OnFinished registers fn to be called once a job has succeeded or failed for good,
+// with the job's final error. Must be called before Start.
+func (wp *WorkerPool) OnFinished(fn func(job *Job, err error)) {
+	wp.finished = append(wp.finished, fn)
 }
 
-// worker processes jobs from the job queue
-func (wp *WorkerPool) worker(id int) {
+// notifyFinished runs the OnFinished callbacks for a job
+func (wp *WorkerPool) notifyFinished(job *Job, err error) {
+	for _, fn := range wp.finished {
+		fn(job, err)
+	}
+}
+
+// worker processes jobs from the job queue until stop is cancelled, either by
+// Stop or by Resize removing it
+func (wp *WorkerPool) worker(id int, stop context.Context) {
 	defer wp.wg.Done()
 	
 	wp.logger.WithField("worker_id", id).Debug("Worker started")
 	
 	for {
-		select {
-		case job := <-wp.jobQueue:
-			if job == nil {
+		// A paused pool takes no jobs, and pausing ends the wait for one
+		take, done := wp.takeContext(stop)
+		if take == nil {
+			wp.logger.WithField("worker_id", id).Debug("Worker stopping")
+			return
+		}
+		job, err := wp.queue.Dequeue(take)
+		interrupted := take.Err() != nil
+		done()
+		if err != nil {
+			if stop.Err() != nil {
+				wp.logger.WithField("worker_id", id).Debug("Worker stopping")
+				return
+			}
+			if interrupted {
+				continue
+			}
+
+			// Back off while the queue backend is unavailable
+			wp.logger.WithError(err).WithField("worker_id", id).Error("Failed to dequeue job")
+			select {
+			case <-time.After(time.Second):
+			case <-stop.Done():
 				return
 			}
+			continue
+		}
+		if wp.Paused() {
+			// Taken as the pool was paused
+			wp.requeue(job)
+			continue
+		}
+
+		// Jobs of a type at its concurrency limit are parked and run by the
+		// worker that frees a slot
+		if !wp.limiter.acquire(job) {
+			wp.logger.WithFields(logrus.Fields{
+				"worker_id": id,
+				"job_id":    job.ID,
+				"job_type":  job.Type,
+			}).Debug("Job type at concurrency limit, parking job")
+			continue
+		}
+		for job != nil {
 			wp.processJob(id, job)
-			
-		case <-wp.quit:
-			wp.logger.WithField("worker_id", id).Debug("Worker stopping")
-			return
+			job = wp.limiter.release(job.Type)
+			if job != nil && wp.intake.Err() != nil {
+				// Draining: the parked job goes back to the queue rather than starting
+				wp.requeue(job)
+				job = nil
+			}
 		}
 	}
 }
@@ -135,66 +430,145 @@ func (wp *WorkerPool) worker(id int) {
 // processJob processes a single job
 func (wp *WorkerPool) processJob(workerID int, job *Job) {
 	start := time.Now()
+	attempt := job.Retries + 1
 	
 	logger := wp.logger.WithFields(logrus.Fields{
 		"worker_id": workerID,
 		"job_id":    job.ID,
 		"job_type":  job.Type,
 	})
+	if job.RequestID != "" {
+		logger = logger.WithField("request_id", job.RequestID)
+	}
 	
 	logger.Debug("Processing job")
+	// Whatever the outcome, it is recorded before the lease ends
+	defer wp.ack(logger, job)
+	
+	// Cancelled while waiting in a shared queue
+	if wp.isCancelled(logger, job) {
+		logger.Info("Skipping cancelled job")
+		wp.notifyFinished(job, ErrJobCancelled)
+		return
+	}
 	
 	// Get handler for job type
 	handler, exists := wp.handlers[job.Type]
 	if !exists {
 		logger.Error("No handler found for job type")
-		wp.resultQueue <- &JobResult{
+		wp.recordStatus(logger, job, models.JobStatusFailed, ErrNoHandler)
+		wp.deadLetter(logger, job, ErrNoHandler)
+		wp.notifyFinished(job, ErrNoHandler)
+		wp.sendResult(logger, &JobResult{
 			JobID:       job.ID,
+			JobType:     job.Type,
+			TenantID:    job.TenantID,
+			Attempt:     attempt,
 			Success:     false,
 			Error:       ErrNoHandler,
 			Duration:    time.Since(start),
 			CompletedAt: time.Now(),
-		}
+		})
 		return
 	}
 	
 	// Execute job with timeout
-	ctx, cancel := context.WithTimeout(wp.ctx, 30*time.Second)
+	timeout := defaultJobTimeout
+	if provider, ok := handler.(TimeoutProvider); ok {
+		timeout = provider.Timeout()
+	}
+	if job.Timeout > 0 {
+		timeout = job.Timeout
+	}
+	ctx, cancel := context.WithTimeout(wp.ctx, timeout)
 	defer cancel()
+	if job.RequestID != "" {
+		ctx = requestctx.WithRequestID(ctx, job.RequestID)
+	}
 	
-	err := handler.Handle(ctx, job)
+	wp.startRunning(job.ID, cancel)
+	wp.recordStatus(logger, job, models.JobStatusRunning, nil)
+	err := wp.runHandler(wp.withProgress(ctx, logger, job), logger, handler, job)
+	cancelled := wp.finishRunning(job.ID)
 	duration := time.Since(start)
 	
 	result := &JobResult{
 		JobID:       job.ID,
+		JobType:     job.Type,
+		TenantID:    job.TenantID,
+		Attempt:     attempt,
 		Success:     err == nil,
 		Error:       err,
 		Duration:    duration,
 		CompletedAt: time.Now(),
 	}
 	
+	if cancelled {
+		// The job store already has the job as cancelled, whatever the handler returned
+		logger.Info("Job cancelled while running")
+		result.Success = false
+		result.Error = ErrJobCancelled
+		wp.sendResult(logger, result)
+		wp.notifyFinished(job, ErrJobCancelled)
+		return
+	}
+	
+	if err != nil && wp.ctx.Err() != nil {
+		// Cancelled by Stop at the drain deadline: not the job's fault, so no attempt is counted
+		logger.WithError(err).Warn("Job interrupted by shutdown")
+		wp.requeue(job)
+		return
+	}
+	
 	if err != nil {
 		logger.WithError(err).Error("Job failed")
+		wp.sendResult(logger, result)
 		
-		// Retry logic
-		if job.Retries < job.MaxRetries {
+		// Retry according to the handler's policy; jobs without their own limit take the policy's
+		policy := retryPolicy(handler)
+		if job.MaxRetries == 0 {
+			job.MaxRetries = policy.MaxRetries
+		}
+		retryable := policy.retryable(err)
+		if retryable && job.Retries < job.MaxRetries {
 			job.Retries++
-			logger.WithField("retry_count", job.Retries).Info("Retrying job")
+			backoff := policy.delay(job.Retries)
+			logger.WithFields(logrus.Fields{
+				"retry_count": job.Retries,
+				"backoff":     backoff,
+			}).Info("Retrying job")
+			wp.recordStatus(logger, job, models.JobStatusRetrying, err)
 			
-			// Exponential backoff
-			backoff := time.Duration(job.Retries*job.Retries) * time.Second
-			time.AfterFunc(backoff, func() {
-				wp.SubmitJob(job)
-			})
+			// The delay is held by the queue, so a shared queue keeps the retry
+			job.RunAt = time.Now().Add(backoff)
+			if err := wp.SubmitJob(job); err != nil {
+				logger.WithError(err).Error("Failed to resubmit job for retry")
+				wp.deadLetter(logger, job, err)
+				wp.notifyFinished(job, err)
+			}
 			return
 		}
 		
-		logger.Error("Job failed after max retries")
+		if retryable {
+			logger.Error("Job failed after max retries")
+		} else {
+			logger.Error("Job failed with an error that is not retried")
+		}
+		wp.recordStatus(logger, job, models.JobStatusFailed, err)
+		wp.deadLetter(logger, job, err)
 	} else {
 		logger.WithField("duration", duration).Debug("Job completed successfully")
+		wp.recordStatus(logger, job, models.JobStatusSucceeded, nil)
+		wp.sendResult(logger, result)
 	}
+	wp.notifyFinished(job, err)
+}
+
+// sendResult hands an attempt's result to processResults without blocking the worker
+func (wp *WorkerPool) sendResult(logger *logrus.Entry, result *JobResult) {
+	wp.recordMetrics(result)
+	wp.activity.record(result)
 	
-	// Send result
 	select {
 	case wp.resultQueue <- result:
 	default:
@@ -202,25 +576,38 @@ func (wp *WorkerPool) processJob(workerID int, job *Job) {
 	}
 }
 
-// processResults processes job results
+// processResults logs job results and records them in the job store
 func (wp *WorkerPool) processResults() {
+	defer close(wp.resultsDone)
+	
 	for result := range wp.resultQueue {
 		wp.logger.WithFields(logrus.Fields{
 			"job_id":   result.JobID,
+			"attempt":  result.Attempt,
 			"success":  result.Success,
 			"duration": result.Duration,
 		}).Info("Job result processed")
 		
-		// Here you could store results in database, send notifications, etc.
+		wp.recordResult(result)
 	}
 }
 
 // GetStats returns worker pool statistics
 func (wp *WorkerPool) GetStats() WorkerPoolStats {
+	queued, err := wp.queue.Len(wp.ctx)
+	if err != nil {
+		wp.logger.WithError(err).Warn("Failed to get job queue length")
+	}
+
+	wp.workersMu.Lock()
+	workers := wp.workers
+	wp.workersMu.Unlock()
+
 	return WorkerPoolStats{
-		Workers:        wp.workers,
-		QueuedJobs:     len(wp.jobQueue),
-		QueueCapacity:  cap(wp.jobQueue),
+		Workers:        workers,
+		QueuedJobs:     queued,
+		QueueCapacity:  wp.queue.Capacity(),
+		ParkedJobs:     wp.limiter.parkedCount(),
 		PendingResults: len(wp.resultQueue),
 	}
 }
@@ -230,11 +617,26 @@ type WorkerPoolStats struct {
 	Workers        int `json:"workers"`
 	QueuedJobs     int `json:"queued_jobs"`
 	QueueCapacity  int `json:"queue_capacity"`
+	// ParkedJobs have been taken from the queue but wait for their type's concurrency limit
+	ParkedJobs     int `json:"parked_jobs"`
 	PendingResults int `json:"pending_results"`
 }
 
+// DuplicateJobError is returned by Queue.Enqueue when a job with the same DedupKey
+// is already waiting
+type DuplicateJobError struct {
+	DedupKey  string
+	PendingID string
+}
+
+func (e *DuplicateJobError) Error() string {
+	return fmt.Sprintf("job with dedup key %q is already queued as %s", e.DedupKey, e.PendingID)
+}
+
 // Custom errors
 var (
 	ErrQueueFull  = fmt.Errorf("job queue is full")
 	ErrNoHandler  = fmt.Errorf("no handler found for job type")
+	ErrPoolStopped = fmt.Errorf("worker pool stopped before the job could run")
+	ErrJobCancelled = fmt.Errorf("job cancelled")
 )
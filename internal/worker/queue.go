@@ -0,0 +1,398 @@
+package worker
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"healthcare-api/internal/config"
+)
+
+// Queue carries jobs from producers to workers. The in-memory queue is local to
+// one process; the Redis queue is shared, so several API instances can submit
+// jobs and separately scaled worker processes consume them.
+type Queue interface {
+	// Enqueue adds a job without blocking, returning ErrQueueFull when at capacity
+	// and a *DuplicateJobError when a job with the same DedupKey is waiting. A job
+	// with a future RunAt is held back until then.
+	Enqueue(ctx context.Context, job *Job) error
+	// Dequeue blocks until a job is due or ctx is done
+	Dequeue(ctx context.Context) (*Job, error)
+	// Len returns the number of waiting jobs, including delayed ones
+	Len(ctx context.Context) (int, error)
+	// Capacity returns the maximum number of waiting jobs, 0 meaning unbounded
+	Capacity() int
+	Close() error
+}
+
+// LeasingQueue is a Queue that leases each dequeued job to the process that took
+// it instead of removing it. The lease is renewed while the process runs, and a
+// job whose lease expires, because its process died, is queued again.
+type LeasingQueue interface {
+	Queue
+	// Ack ends the lease on a job once its outcome is recorded: it succeeded,
+	// failed for good, or was resubmitted for a retry or requeue
+	Ack(ctx context.Context, job *Job) error
+}
+
+// NewQueue returns the queue backend selected by cfg
+func NewQueue(cfg config.WorkerConfig) (Queue, error) {
+	switch cfg.QueueBackend {
+	case "", "memory":
+		return NewMemoryQueue(cfg.QueueSize), nil
+	case "redis":
+		return NewRedisQueue(cfg.RedisURL, cfg.QueueName, cfg.QueueSize, time.Duration(cfg.LeaseTimeout)*time.Second)
+	default:
+		return nil, fmt.Errorf("unsupported worker queue backend %q", cfg.QueueBackend)
+	}
+}
+
+// Priority orders waiting jobs: higher priorities are dequeued first, and jobs of
+// equal priority in submission order
+type Priority int
+
+const (
+	// PriorityLow is for bulk work such as backups and reindexing
+	PriorityLow Priority = -1
+	// PriorityNormal is the default
+	PriorityNormal Priority = 0
+	// PriorityHigh is for work a user is waiting on
+	PriorityHigh Priority = 1
+	// PriorityCritical is for time-sensitive clinical work such as critical value alerts
+	PriorityCritical Priority = 2
+)
+
+// priorities lists the supported priorities, highest first
+var priorities = []Priority{PriorityCritical, PriorityHigh, PriorityNormal, PriorityLow}
+
+// clamp maps out-of-range priorities to the nearest supported one
+func (p Priority) clamp() Priority {
+	if p > PriorityCritical {
+		return PriorityCritical
+	}
+	if p < PriorityLow {
+		return PriorityLow
+	}
+	return p
+}
+
+func (p Priority) String() string {
+	switch p.clamp() {
+	case PriorityCritical:
+		return "critical"
+	case PriorityHigh:
+		return "high"
+	case PriorityLow:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// ParsePriority parses a priority name as returned by Priority.String
+func ParsePriority(name string) (Priority, error) {
+	for _, priority := range priorities {
+		if priority.String() == name {
+			return priority, nil
+		}
+	}
+	return PriorityNormal, fmt.Errorf("unknown job priority %q", name)
+}
+
+// MemoryQueue is a bounded in-process priority queue. Delayed jobs wait in a
+// separate heap and move to the main one when due.
+type MemoryQueue struct {
+	mu      sync.Mutex
+	jobs    jobHeap
+	delayed delayHeap
+	timer   *time.Timer       // fires when the earliest delayed job is due
+	pending map[string]string // dedup key to waiting job id
+	seq     uint64
+	size    int
+	ready   chan struct{} // one token per job in jobs
+}
+
+func NewMemoryQueue(size int) *MemoryQueue {
+	return &MemoryQueue{
+		pending: make(map[string]string),
+		size:    size,
+		ready:   make(chan struct{}, size),
+	}
+}
+
+func (q *MemoryQueue) Enqueue(ctx context.Context, job *Job) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	if pendingID, ok := q.pending[job.DedupKey]; ok && job.DedupKey != "" {
+		q.mu.Unlock()
+		return &DuplicateJobError{DedupKey: job.DedupKey, PendingID: pendingID}
+	}
+	if len(q.jobs)+len(q.delayed.jobHeap) >= q.size {
+		q.mu.Unlock()
+		return ErrQueueFull
+	}
+	if job.DedupKey != "" {
+		q.pending[job.DedupKey] = job.ID
+	}
+	q.seq++
+	entry := queuedJob{job: job, priority: job.Priority.clamp(), seq: q.seq}
+	if time.Until(job.RunAt) > 0 {
+		heap.Push(&q.delayed, entry)
+		q.resetTimer()
+		q.mu.Unlock()
+		return nil
+	}
+	heap.Push(&q.jobs, entry)
+	q.mu.Unlock()
+
+	// Never blocks: there are never more tokens than waiting jobs, and at most size jobs
+	q.ready <- struct{}{}
+	return nil
+}
+
+func (q *MemoryQueue) Dequeue(ctx context.Context) (*Job, error) {
+	for {
+		select {
+		case <-q.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		q.mu.Lock()
+		// Drain or Remove may have taken the job after the token was taken
+		if len(q.jobs) == 0 {
+			q.mu.Unlock()
+			continue
+		}
+		job := heap.Pop(&q.jobs).(queuedJob).job
+		q.releaseDedup(job)
+		q.mu.Unlock()
+		return job, nil
+	}
+}
+
+// releaseDedup frees a job's dedup key once the job has left the queue, so later
+// duplicates are new work; q.mu must be held
+func (q *MemoryQueue) releaseDedup(job *Job) {
+	if job.DedupKey != "" && q.pending[job.DedupKey] == job.ID {
+		delete(q.pending, job.DedupKey)
+	}
+}
+
+// Remove takes a waiting job, delayed or not, out of the queue, returning nil if
+// it is not there
+func (q *MemoryQueue) Remove(id string) *Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, entry := range q.jobs {
+		if entry.job.ID == id {
+			heap.Remove(&q.jobs, i)
+			q.releaseDedup(entry.job)
+			// Discard its token unless a Dequeue already holds it
+			select {
+			case <-q.ready:
+			default:
+			}
+			return entry.job
+		}
+	}
+	for i, entry := range q.delayed.jobHeap {
+		if entry.job.ID == id {
+			heap.Remove(&q.delayed, i)
+			q.releaseDedup(entry.job)
+			q.resetTimer()
+			return entry.job
+		}
+	}
+	return nil
+}
+
+// promote moves due delayed jobs to the main heap
+func (q *MemoryQueue) promote() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	for len(q.delayed.jobHeap) > 0 && !q.delayed.jobHeap[0].job.RunAt.After(now) {
+		heap.Push(&q.jobs, heap.Pop(&q.delayed))
+		// Never blocks, as in Enqueue
+		q.ready <- struct{}{}
+	}
+	q.resetTimer()
+}
+
+// resetTimer arms the timer for the earliest delayed job; q.mu must be held
+func (q *MemoryQueue) resetTimer() {
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	if len(q.delayed.jobHeap) > 0 {
+		q.timer = time.AfterFunc(time.Until(q.delayed.jobHeap[0].job.RunAt), q.promote)
+	}
+}
+
+func (q *MemoryQueue) Len(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs) + len(q.delayed.jobHeap), nil
+}
+
+func (q *MemoryQueue) Capacity() int {
+	return q.size
+}
+
+// Drain removes and returns every waiting job, delayed or not, so the pool can
+// persist them when shutting down
+func (q *MemoryQueue) Drain() []*Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]*Job, 0, len(q.jobs)+len(q.delayed.jobHeap))
+	for _, entry := range q.jobs {
+		jobs = append(jobs, entry.job)
+	}
+	for _, entry := range q.delayed.jobHeap {
+		jobs = append(jobs, entry.job)
+	}
+
+	q.jobs = nil
+	q.delayed = delayHeap{}
+	q.pending = make(map[string]string)
+	q.resetTimer()
+	// Discard the tokens of the removed jobs
+	for {
+		select {
+		case <-q.ready:
+		default:
+			return jobs
+		}
+	}
+}
+
+// Close stops promoting delayed jobs; waiting jobs are dropped with the process
+func (q *MemoryQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	return nil
+}
+
+// queuedJob is a heap entry; seq keeps equal priorities first-in, first-out
+type queuedJob struct {
+	job      *Job
+	priority Priority
+	seq      uint64
+}
+
+// jobHeap implements heap.Interface, highest priority first
+type jobHeap []queuedJob
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *jobHeap) Push(x interface{}) { *h = append(*h, x.(queuedJob)) }
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = queuedJob{}
+	*h = old[:n-1]
+	return item
+}
+
+// delayHeap orders delayed jobs by when they are due
+type delayHeap struct {
+	jobHeap
+}
+
+func (h delayHeap) Less(i, j int) bool {
+	if !h.jobHeap[i].job.RunAt.Equal(h.jobHeap[j].job.RunAt) {
+		return h.jobHeap[i].job.RunAt.Before(h.jobHeap[j].job.RunAt)
+	}
+	return h.jobHeap[i].seq < h.jobHeap[j].seq
+}
+
+// wireJob is the serialized form of a Job for queues that leave the process.
+// Payloads are carried as JSON and come back as []byte.
+type wireJob struct {
+	ID         string        `json:"id"`
+	Type       string        `json:"type"`
+	Payload    []byte        `json:"payload,omitempty"`
+	Retries    int           `json:"retries"`
+	MaxRetries int           `json:"max_retries"`
+	CreatedAt  time.Time     `json:"created_at"`
+	Timeout    time.Duration `json:"timeout,omitempty"`
+	Schedule   string        `json:"schedule,omitempty"`
+	Priority   Priority      `json:"priority,omitempty"`
+	TenantID   string        `json:"tenant_id,omitempty"`
+	RequestID  string        `json:"request_id,omitempty"`
+	DedupKey   string        `json:"dedup_key,omitempty"`
+	RunAt      time.Time     `json:"run_at,omitempty"`
+}
+
+// encodeJob serializes a job for an out-of-process queue
+func encodeJob(job *Job) ([]byte, error) {
+	wire := wireJob{
+		ID:         job.ID,
+		Type:       job.Type,
+		Retries:    job.Retries,
+		MaxRetries: job.MaxRetries,
+		CreatedAt:  job.CreatedAt,
+		Timeout:    job.Timeout,
+		Schedule:   job.Schedule,
+		Priority:   job.Priority,
+		TenantID:   job.TenantID,
+		RequestID:  job.RequestID,
+		DedupKey:   job.DedupKey,
+		RunAt:      job.RunAt,
+	}
+	payload, err := encodePayload(job.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %w", job.ID, err)
+	}
+	wire.Payload = payload
+	return json.Marshal(wire)
+}
+
+// decodeJob reverses encodeJob
+func decodeJob(data []byte) (*Job, error) {
+	var wire wireJob
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return &Job{
+		ID:         wire.ID,
+		Type:       wire.Type,
+		Payload:    wire.Payload,
+		Retries:    wire.Retries,
+		MaxRetries: wire.MaxRetries,
+		CreatedAt:  wire.CreatedAt,
+		Timeout:    wire.Timeout,
+		Schedule:   wire.Schedule,
+		Priority:   wire.Priority,
+		TenantID:   wire.TenantID,
+		RequestID:  wire.RequestID,
+		DedupKey:   wire.DedupKey,
+		RunAt:      wire.RunAt,
+	}, nil
+}
@@ -0,0 +1,381 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisPollInterval is how often due delayed jobs are moved onto their lists
+	redisPollInterval = time.Second
+	// redisIdleInterval is how long Dequeue waits before looking again when every list is empty
+	redisIdleInterval = 250 * time.Millisecond
+	// defaultLeaseTimeout applies when the queue is created without a lease timeout
+	defaultLeaseTimeout = time.Minute
+	// redisDedupTTL expires a dedup entry left behind by a worker that stopped
+	// between popping a job and releasing its key
+	redisDedupTTL = 24 * time.Hour
+)
+
+// enqueueScript pushes a job, or adds it to the delayed set scored by its due time
+// in milliseconds, unless its dedup key is held by a waiting job, in which case it
+// returns that job's id. KEYS: list, delayed set, dedup key. ARGV: job, dedup key,
+// job id, ttl, due time (0 for now).
+var enqueueScript = redis.NewScript(`
+if ARGV[2] ~= '' then
+	local pending = redis.call('GET', KEYS[3])
+	if pending then
+		return pending
+	end
+	redis.call('SET', KEYS[3], ARGV[3], 'EX', ARGV[4])
+end
+if tonumber(ARGV[5]) > 0 then
+	redis.call('ZADD', KEYS[2], ARGV[5], ARGV[1])
+else
+	redis.call('LPUSH', KEYS[1], ARGV[1])
+end
+return ''
+`)
+
+// promoteScript moves delayed jobs that are due onto their list. KEYS: delayed set,
+// list. ARGV: now in milliseconds, batch size.
+var promoteScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, job in ipairs(due) do
+	redis.call('ZREM', KEYS[1], job)
+	redis.call('LPUSH', KEYS[2], job)
+end
+return #due
+`)
+
+// promoteBatch bounds the delayed jobs moved per list by one promotion
+const promoteBatch = 500
+
+// leaseScript pops a job from the first non-empty list and leases it: the job is
+// kept in the leased hash, prefixed with its list, until acked, and the lease
+// expires at the given time unless renewed. KEYS: lists highest priority first,
+// lease set, leased hash. ARGV: expiry in milliseconds, lease token.
+var leaseScript = redis.NewScript(`
+local lists = #KEYS - 2
+for i = 1, lists do
+	local job = redis.call('RPOP', KEYS[i])
+	if job then
+		redis.call('ZADD', KEYS[lists + 1], ARGV[1], ARGV[2])
+		redis.call('HSET', KEYS[lists + 2], ARGV[2], KEYS[i] .. '\n' .. job)
+		return job
+	end
+end
+return false
+`)
+
+// reclaimScript puts jobs whose lease expired back at the front of their list.
+// KEYS: lease set, leased hash. ARGV: now in milliseconds, batch size.
+var reclaimScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, token in ipairs(expired) do
+	redis.call('ZREM', KEYS[1], token)
+	local entry = redis.call('HGET', KEYS[2], token)
+	if entry then
+		redis.call('HDEL', KEYS[2], token)
+		local sep = string.find(entry, '\n', 1, true)
+		redis.call('RPUSH', string.sub(entry, 1, sep - 1), string.sub(entry, sep + 1))
+	end
+end
+return #expired
+`)
+
+// releaseScript frees a dedup key if it is still held by the given job. KEYS: dedup key. ARGV: job id.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// RedisQueue is a priority queue shared by every process using the same key, with
+// one Redis list per priority. Delayed jobs wait in a sorted set per priority, and
+// every process moves them onto the lists once due.
+//
+// Dequeued jobs are leased rather than removed. Each process renews the leases it
+// holds until the jobs are acked; when a process dies its leases expire and any
+// other process puts the jobs back on their lists, so they run again elsewhere.
+type RedisQueue struct {
+	client *redis.Client
+	key    string
+	keys   []string // one list per priority, highest first
+	size   int
+
+	leaseTimeout time.Duration
+	leaseKeys    []string // the lists, lease set and leased hash, as leaseScript takes them
+	mu           sync.Mutex
+	held         map[string]struct{} // lease tokens held by this process
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// NewRedisQueue connects to url (redis://[:password@]host:port/db) and checks the
+// connection. Jobs stay leased for leaseTimeout without a renewal.
+func NewRedisQueue(url, key string, size int, leaseTimeout time.Duration) (*RedisQueue, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	keys := make([]string, len(priorities))
+	for i, priority := range priorities {
+		keys[i] = priorityKey(key, priority)
+	}
+
+	if leaseTimeout <= 0 {
+		leaseTimeout = defaultLeaseTimeout
+	}
+
+	q := &RedisQueue{
+		client:       client,
+		key:          key,
+		keys:         keys,
+		size:         size,
+		leaseTimeout: leaseTimeout,
+		leaseKeys:    append(append([]string{}, keys...), key+":leases", key+":leased"),
+		held:         make(map[string]struct{}),
+		quit:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go q.maintain()
+	return q, nil
+}
+
+// delayedKey names the sorted set holding delayed jobs bound for list
+func delayedKey(list string) string {
+	return list + ":delayed"
+}
+
+// leaseSetKey and leasedKey name the lease set and leased hash
+func (q *RedisQueue) leaseSetKey() string { return q.leaseKeys[len(q.keys)] }
+
+func (q *RedisQueue) leasedKey() string { return q.leaseKeys[len(q.keys)+1] }
+
+// maintain moves due delayed jobs onto their lists, renews this process's leases
+// and reclaims expired ones until Close. The scripts are atomic, so every process
+// running them at once is safe; failures are retried on the next tick.
+func (q *RedisQueue) maintain() {
+	defer close(q.done)
+
+	promote := time.NewTicker(redisPollInterval)
+	defer promote.Stop()
+	heartbeat := time.NewTicker(q.leaseTimeout / 3)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-promote.C:
+			q.promote()
+		case <-heartbeat.C:
+			q.renewLeases()
+			q.reclaimExpired()
+		case <-q.quit:
+			return
+		}
+	}
+}
+
+// promote moves due delayed jobs onto their lists
+func (q *RedisQueue) promote() {
+	ctx, cancel := context.WithTimeout(context.Background(), redisPollInterval)
+	defer cancel()
+
+	now := time.Now().UnixMilli()
+	for _, list := range q.keys {
+		promoteScript.Run(ctx, q.client, []string{delayedKey(list), list}, now, promoteBatch)
+	}
+}
+
+// renewLeases extends the leases this process holds. XX leaves alone leases that
+// have already been reclaimed.
+func (q *RedisQueue) renewLeases() {
+	q.mu.Lock()
+	tokens := make([]string, 0, len(q.held))
+	for token := range q.held {
+		tokens = append(tokens, token)
+	}
+	q.mu.Unlock()
+	if len(tokens) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), q.leaseTimeout/3)
+	defer cancel()
+
+	expiry := float64(time.Now().Add(q.leaseTimeout).UnixMilli())
+	members := make([]redis.Z, len(tokens))
+	for i, token := range tokens {
+		members[i] = redis.Z{Score: expiry, Member: token}
+	}
+	q.client.ZAddXX(ctx, q.leaseSetKey(), members...)
+}
+
+// reclaimExpired puts jobs whose lease expired back on the queue
+func (q *RedisQueue) reclaimExpired() {
+	ctx, cancel := context.WithTimeout(context.Background(), q.leaseTimeout/3)
+	defer cancel()
+
+	reclaimScript.Run(ctx, q.client, []string{q.leaseSetKey(), q.leasedKey()}, time.Now().UnixMilli(), promoteBatch)
+}
+
+// priorityKey names the list holding jobs of one priority. Normal jobs keep the
+// bare key, so jobs queued before priorities existed are still consumed.
+func priorityKey(key string, priority Priority) string {
+	if priority == PriorityNormal {
+		return key
+	}
+	return key + ":" + priority.String()
+}
+
+// Enqueue pushes a job. The capacity check is advisory: concurrent producers may
+// briefly overshoot it.
+func (q *RedisQueue) Enqueue(ctx context.Context, job *Job) error {
+	data, err := encodeJob(job)
+	if err != nil {
+		return err
+	}
+
+	if q.size > 0 {
+		length, err := q.Len(ctx)
+		if err != nil {
+			return err
+		}
+		if length >= q.size {
+			return ErrQueueFull
+		}
+	}
+
+	var due int64
+	if time.Until(job.RunAt) > 0 {
+		due = job.RunAt.UnixMilli()
+	}
+
+	list := priorityKey(q.key, job.Priority.clamp())
+	keys := []string{list, delayedKey(list), q.dedupKey(job.DedupKey)}
+	pendingID, err := enqueueScript.Run(ctx, q.client, keys, data, job.DedupKey, job.ID, int(redisDedupTTL.Seconds()), due).Text()
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	if pendingID != "" {
+		return &DuplicateJobError{DedupKey: job.DedupKey, PendingID: pendingID}
+	}
+	return nil
+}
+
+// dedupKey names the Redis key holding the waiting job id for a dedup key
+func (q *RedisQueue) dedupKey(dedupKey string) string {
+	return q.key + ":dedup:" + dedupKey
+}
+
+// Dequeue leases a job from the highest-priority non-empty list
+func (q *RedisQueue) Dequeue(ctx context.Context) (*Job, error) {
+	for {
+		token := uuid.New().String()
+		expiry := time.Now().Add(q.leaseTimeout).UnixMilli()
+		data, err := leaseScript.Run(ctx, q.client, q.leaseKeys, expiry, token).Text()
+		if err == nil {
+			q.mu.Lock()
+			q.held[token] = struct{}{}
+			q.mu.Unlock()
+
+			job, err := decodeJob([]byte(data))
+			if err != nil {
+				// Leasing an undecodable job again would not help
+				q.release(ctx, token)
+				return nil, err
+			}
+			job.lease = token
+
+			// Once a job is taken, later duplicates are new work. A failure here only
+			// delays that until the key expires, so the job is still returned.
+			if job.DedupKey != "" {
+				releaseScript.Run(ctx, q.client, []string{q.dedupKey(job.DedupKey)}, job.ID)
+			}
+			return job, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("failed to dequeue job: %w", err)
+		}
+
+		select {
+		case <-time.After(redisIdleInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Ack ends the lease on a job taken by Dequeue
+func (q *RedisQueue) Ack(ctx context.Context, job *Job) error {
+	if job.lease == "" {
+		return nil
+	}
+	return q.release(ctx, job.lease)
+}
+
+// release deletes a lease and stops renewing it
+func (q *RedisQueue) release(ctx context.Context, token string) error {
+	q.mu.Lock()
+	delete(q.held, token)
+	q.mu.Unlock()
+
+	pipe := q.client.TxPipeline()
+	pipe.ZRem(ctx, q.leaseSetKey(), token)
+	pipe.HDel(ctx, q.leasedKey(), token)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to release job lease: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of waiting jobs across all priorities, delayed or not
+func (q *RedisQueue) Len(ctx context.Context) (int, error) {
+	pipe := q.client.Pipeline()
+	lengths := make([]*redis.IntCmd, 0, 2*len(q.keys))
+	for _, key := range q.keys {
+		lengths = append(lengths, pipe.LLen(ctx, key), pipe.ZCard(ctx, delayedKey(key)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to get queue length: %w", err)
+	}
+
+	total := 0
+	for _, length := range lengths {
+		total += int(length.Val())
+	}
+	return total, nil
+}
+
+func (q *RedisQueue) Capacity() int {
+	return q.size
+}
+
+// Close stops renewing leases. Leases still held, on jobs that ignored
+// cancellation, expire and those jobs run again elsewhere.
+func (q *RedisQueue) Close() error {
+	close(q.quit)
+	<-q.done
+	return q.client.Close()
+}
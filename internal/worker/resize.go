@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Resize grows or shrinks the pool to n workers, e.g. to work through a backlog.
+// Added workers start taking jobs at once; removed workers finish the job they
+// are running first. Before Start it only sets the number Start runs.
+func (wp *WorkerPool) Resize(n int) error {
+	if n < 0 {
+		return fmt.Errorf("worker count must not be negative, got %d", n)
+	}
+
+	wp.workersMu.Lock()
+	defer wp.workersMu.Unlock()
+
+	if wp.intake.Err() != nil {
+		return ErrPoolStopped
+	}
+	from := wp.workers
+	if wp.started {
+		wp.scale(n)
+	} else {
+		wp.workers = n
+	}
+
+	if n != from {
+		wp.logger.WithFields(logrus.Fields{
+			"from": from,
+			"to":   n,
+		}).Info("Worker pool resized")
+	}
+	return nil
+}
+
+// scale starts or stops workers until n are running. The caller holds workersMu.
+func (wp *WorkerPool) scale(n int) {
+	for len(wp.workerStops) < n {
+		stop, cancel := context.WithCancel(wp.intake)
+		wp.workerStops = append(wp.workerStops, cancel)
+		wp.wg.Add(1)
+		go wp.worker(wp.nextWorker, stop)
+		wp.nextWorker++
+	}
+	for len(wp.workerStops) > n {
+		last := len(wp.workerStops) - 1
+		wp.workerStops[last]()
+		wp.workerStops = wp.workerStops[:last]
+	}
+	wp.workers = n
+}
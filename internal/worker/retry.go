@@ -0,0 +1,121 @@
+package worker
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Backoff returns the delay before the given retry, counting from 1
+type Backoff func(retry int) time.Duration
+
+// QuadraticBackoff waits base × retry², the pool's historical behaviour
+func QuadraticBackoff(base time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		return base * time.Duration(retry*retry)
+	}
+}
+
+// ExponentialBackoff doubles the delay from base with each retry, up to max
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		delay := base
+		for i := 1; i < retry && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			return max
+		}
+		return delay
+	}
+}
+
+// ConstantBackoff waits the same delay before every retry
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(int) time.Duration {
+		return delay
+	}
+}
+
+// RetryPolicy controls how failed attempts of a job type are retried
+type RetryPolicy struct {
+	// MaxRetries applies to jobs submitted without their own MaxRetries
+	MaxRetries int
+	Backoff    Backoff
+	// Jitter spreads each delay randomly by up to this fraction either way, so
+	// jobs that failed together do not all retry at the same moment
+	Jitter float64
+	// Retryable reports whether an error is worth retrying; nil retries every
+	// error. Errors wrapped with Permanent are never retried.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy applies to handlers that do not implement RetryPolicyProvider
+var DefaultRetryPolicy = RetryPolicy{
+	Backoff: QuadraticBackoff(time.Second),
+	Jitter:  0.2,
+}
+
+// RetryPolicyProvider is implemented by job handlers with their own retry policy
+type RetryPolicyProvider interface {
+	RetryPolicy() RetryPolicy
+}
+
+// retryPolicy returns the policy for a handler
+func retryPolicy(handler JobHandler) RetryPolicy {
+	provider, ok := handler.(RetryPolicyProvider)
+	if !ok {
+		return DefaultRetryPolicy
+	}
+
+	policy := provider.RetryPolicy()
+	if policy.Backoff == nil {
+		policy.Backoff = DefaultRetryPolicy.Backoff
+	}
+	return policy
+}
+
+// retryable reports whether err should be retried under the policy
+func (p RetryPolicy) retryable(err error) bool {
+	if IsPermanent(err) {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// delay returns the jittered delay before the given retry
+func (p RetryPolicy) delay(retry int) time.Duration {
+	delay := p.Backoff(retry)
+	if p.Jitter > 0 && delay > 0 {
+		spread := float64(delay) * p.Jitter
+		delay += time.Duration((rand.Float64()*2 - 1) * spread)
+	}
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// permanentError marks an error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job fails without further retries, e.g. for an
+// invalid payload
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
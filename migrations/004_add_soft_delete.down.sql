@@ -0,0 +1,11 @@
+-- Remove soft delete support
+UPDATE audit_logs SET action = 'UPDATE' WHERE action = 'RESTORE';
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_action_check;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_action_check
+    CHECK (action IN ('CREATE', 'READ', 'UPDATE', 'DELETE'));
+
+DROP INDEX IF EXISTS idx_observations_deleted_at;
+DROP INDEX IF EXISTS idx_patients_deleted_at;
+
+ALTER TABLE observations DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE patients DROP COLUMN IF EXISTS deleted_at;
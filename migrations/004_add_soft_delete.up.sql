@@ -0,0 +1,12 @@
+-- Add soft delete support to FHIR resource tables
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE observations ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Partial indexes keep lookups on live rows fast
+CREATE INDEX idx_patients_deleted_at ON patients (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_observations_deleted_at ON observations (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Allow RESTORE actions in the audit log
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_action_check;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_action_check
+    CHECK (action IN ('CREATE', 'READ', 'UPDATE', 'DELETE', 'RESTORE'));
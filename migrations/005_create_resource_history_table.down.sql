@@ -0,0 +1,2 @@
+-- Drop resource history table
+DROP TABLE IF EXISTS resource_history;
@@ -0,0 +1,15 @@
+-- Create resource history table holding every version of every resource
+CREATE TABLE IF NOT EXISTS resource_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id UUID NOT NULL,
+    version INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('CREATE', 'UPDATE', 'DELETE', 'RESTORE')),
+    payload JSONB NOT NULL,
+    actor VARCHAR(255),
+    recorded_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- One row per resource version
+CREATE UNIQUE INDEX idx_resource_history_resource_version ON resource_history (resource_type, resource_id, version);
+CREATE INDEX idx_resource_history_recorded_at ON resource_history (recorded_at);
@@ -0,0 +1,15 @@
+-- Drop extracted search columns and their indexes
+DROP INDEX IF EXISTS idx_observations_interpretation;
+DROP INDEX IF EXISTS idx_patients_address;
+DROP INDEX IF EXISTS idx_patients_telecom;
+DROP INDEX IF EXISTS idx_observations_subject_effective;
+DROP INDEX IF EXISTS idx_observations_code_values;
+DROP INDEX IF EXISTS idx_patients_identifier_values;
+DROP INDEX IF EXISTS idx_patients_family_name;
+
+ALTER TABLE observations DROP COLUMN IF EXISTS effective_date;
+ALTER TABLE observations DROP COLUMN IF EXISTS subject_reference;
+ALTER TABLE observations DROP COLUMN IF EXISTS code_values;
+
+ALTER TABLE patients DROP COLUMN IF EXISTS identifier_values;
+ALTER TABLE patients DROP COLUMN IF EXISTS family_name;
@@ -0,0 +1,40 @@
+-- Extracted search columns maintained by the application on write
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS family_name TEXT;
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS identifier_values TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE observations ADD COLUMN IF NOT EXISTS code_values TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE observations ADD COLUMN IF NOT EXISTS subject_reference TEXT;
+ALTER TABLE observations ADD COLUMN IF NOT EXISTS effective_date TIMESTAMP WITH TIME ZONE;
+
+-- Backfill existing rows
+UPDATE patients SET
+    family_name = lower(COALESCE(
+        (SELECT n->>'family' FROM jsonb_array_elements(CASE WHEN jsonb_typeof(name) = 'array' THEN name ELSE '[]'::jsonb END) n
+         WHERE n->>'use' = 'official' AND n ? 'family' LIMIT 1),
+        (SELECT n->>'family' FROM jsonb_array_elements(CASE WHEN jsonb_typeof(name) = 'array' THEN name ELSE '[]'::jsonb END) n
+         WHERE n ? 'family' LIMIT 1)
+    )),
+    identifier_values = ARRAY(
+        SELECT i->>'value' FROM jsonb_array_elements(CASE WHEN jsonb_typeof(identifier) = 'array' THEN identifier ELSE '[]'::jsonb END) i
+        WHERE i ? 'value'
+    );
+
+UPDATE observations SET
+    code_values = ARRAY(
+        SELECT DISTINCT v FROM jsonb_array_elements(CASE WHEN jsonb_typeof(code->'coding') = 'array' THEN code->'coding' ELSE '[]'::jsonb END) c,
+            LATERAL (VALUES (c->>'code'), (c->>'system' || '|' || (c->>'code'))) AS codes(v)
+        WHERE c ? 'code' AND v IS NOT NULL
+    ),
+    subject_reference = subject->>'reference',
+    effective_date = COALESCE(effective_date_time, (effective_period->>'start')::timestamptz, effective_instant);
+
+-- Indexes on extracted columns
+CREATE INDEX idx_patients_family_name ON patients (family_name text_pattern_ops);
+CREATE INDEX idx_patients_identifier_values ON patients USING GIN (identifier_values);
+CREATE INDEX idx_observations_code_values ON observations USING GIN (code_values);
+CREATE INDEX idx_observations_subject_effective ON observations (subject_reference, effective_date DESC);
+
+-- Additional GIN indexes for containment queries on JSONB blobs
+CREATE INDEX idx_patients_telecom ON patients USING GIN (telecom jsonb_path_ops);
+CREATE INDEX idx_patients_address ON patients USING GIN (address jsonb_path_ops);
+CREATE INDEX idx_observations_interpretation ON observations USING GIN (interpretation jsonb_path_ops);
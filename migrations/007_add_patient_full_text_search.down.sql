@@ -0,0 +1,6 @@
+-- Drop patient full-text search
+DROP INDEX IF EXISTS idx_patients_search_text_trgm;
+DROP INDEX IF EXISTS idx_patients_search_vector;
+
+ALTER TABLE patients DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE patients DROP COLUMN IF EXISTS search_text;
@@ -0,0 +1,21 @@
+-- Full-text and trigram search over patient demographics
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Lowercased names, identifiers and addresses maintained by the application on write
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS search_text TEXT NOT NULL DEFAULT '';
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS search_vector TSVECTOR
+    GENERATED ALWAYS AS (to_tsvector('simple', search_text)) STORED;
+
+-- Backfill existing rows
+UPDATE patients SET search_text = lower(concat_ws(' ',
+    (SELECT string_agg(concat_ws(' ', n->>'text', n->>'family',
+            (SELECT string_agg(g, ' ') FROM jsonb_array_elements_text(COALESCE(n->'given', '[]'::jsonb)) g)), ' ')
+     FROM jsonb_array_elements(CASE WHEN jsonb_typeof(name) = 'array' THEN name ELSE '[]'::jsonb END) n),
+    array_to_string(identifier_values, ' '),
+    (SELECT string_agg(concat_ws(' ', a->>'city', a->>'postalCode',
+            (SELECT string_agg(l, ' ') FROM jsonb_array_elements_text(COALESCE(a->'line', '[]'::jsonb)) l)), ' ')
+     FROM jsonb_array_elements(CASE WHEN jsonb_typeof(address) = 'array' THEN address ELSE '[]'::jsonb END) a)
+));
+
+CREATE INDEX idx_patients_search_vector ON patients USING GIN (search_vector);
+CREATE INDEX idx_patients_search_text_trgm ON patients USING GIN (search_text gin_trgm_ops);
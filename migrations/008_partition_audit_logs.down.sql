@@ -0,0 +1,35 @@
+-- Convert audit_logs back to a regular table
+ALTER TABLE audit_logs RENAME TO audit_logs_partitioned;
+
+CREATE TABLE audit_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('CREATE', 'READ', 'UPDATE', 'DELETE', 'RESTORE')),
+    user_id VARCHAR(255),
+    user_agent TEXT,
+    ip_address INET,
+    request_id VARCHAR(255),
+    old_values JSONB,
+    new_values JSONB,
+    timestamp TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO audit_logs
+SELECT id, resource_type, resource_id, action, user_id, user_agent, ip_address,
+       request_id, old_values, new_values, timestamp
+FROM audit_logs_partitioned
+ON CONFLICT (id) DO NOTHING;
+
+DROP TABLE audit_logs_partitioned CASCADE;
+
+DROP FUNCTION IF EXISTS ensure_audit_log_partitions(INTEGER);
+DROP FUNCTION IF EXISTS create_audit_log_partition(DATE);
+
+CREATE INDEX idx_audit_logs_resource_type ON audit_logs (resource_type);
+CREATE INDEX idx_audit_logs_resource_id ON audit_logs (resource_id);
+CREATE INDEX idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX idx_audit_logs_user_id ON audit_logs (user_id);
+CREATE INDEX idx_audit_logs_timestamp ON audit_logs (timestamp);
+CREATE INDEX idx_audit_logs_request_id ON audit_logs (request_id);
+CREATE INDEX idx_audit_logs_resource_action_timestamp ON audit_logs (resource_type, action, timestamp DESC);
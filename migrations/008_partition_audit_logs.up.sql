@@ -0,0 +1,86 @@
+-- Partition audit_logs by month so old data can be detached instead of deleted row by row
+ALTER TABLE audit_logs RENAME TO audit_logs_unpartitioned;
+
+CREATE TABLE audit_logs (
+    id UUID NOT NULL DEFAULT gen_random_uuid(),
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    user_id VARCHAR(255),
+    user_agent TEXT,
+    ip_address INET,
+    request_id VARCHAR(255),
+    old_values JSONB,
+    new_values JSONB,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, timestamp),
+    CONSTRAINT audit_logs_action_check CHECK (action IN ('CREATE', 'READ', 'UPDATE', 'DELETE', 'RESTORE'))
+) PARTITION BY RANGE (timestamp);
+
+-- Catch-all for rows outside the pre-created monthly partitions
+CREATE TABLE audit_logs_default PARTITION OF audit_logs DEFAULT;
+
+-- Creates the partition for the month containing month_start, if missing
+CREATE OR REPLACE FUNCTION create_audit_log_partition(month_start DATE) RETURNS TEXT AS $$
+DECLARE
+    range_start DATE := date_trunc('month', month_start)::DATE;
+    range_end DATE := (date_trunc('month', month_start) + INTERVAL '1 month')::DATE;
+    partition_name TEXT := 'audit_logs_' || to_char(range_start, 'YYYY_MM');
+BEGIN
+    IF to_regclass(partition_name) IS NULL THEN
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF audit_logs FOR VALUES FROM (%L) TO (%L)',
+            partition_name, range_start, range_end
+        );
+    END IF;
+    RETURN partition_name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Ensures partitions exist for the current month and the given number of months ahead
+CREATE OR REPLACE FUNCTION ensure_audit_log_partitions(months_ahead INTEGER) RETURNS VOID AS $$
+DECLARE
+    i INTEGER;
+BEGIN
+    FOR i IN 0..months_ahead LOOP
+        PERFORM create_audit_log_partition((date_trunc('month', NOW()) + make_interval(months => i))::DATE);
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Create partitions covering existing rows, then move them across
+DO $$
+DECLARE
+    month DATE;
+BEGIN
+    FOR month IN
+        SELECT DISTINCT date_trunc('month', timestamp)::DATE
+        FROM audit_logs_unpartitioned
+        WHERE timestamp IS NOT NULL
+    LOOP
+        PERFORM create_audit_log_partition(month);
+    END LOOP;
+END;
+$$;
+
+SELECT ensure_audit_log_partitions(3);
+
+INSERT INTO audit_logs (
+    id, resource_type, resource_id, action, user_id, user_agent, ip_address,
+    request_id, old_values, new_values, timestamp
+)
+SELECT
+    id, resource_type, resource_id, action, user_id, user_agent, ip_address,
+    request_id, old_values, new_values, COALESCE(timestamp, NOW())
+FROM audit_logs_unpartitioned;
+
+DROP TABLE audit_logs_unpartitioned;
+
+-- Indexes are created on every partition
+CREATE INDEX idx_audit_logs_resource_type ON audit_logs (resource_type);
+CREATE INDEX idx_audit_logs_resource_id ON audit_logs (resource_id);
+CREATE INDEX idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX idx_audit_logs_user_id ON audit_logs (user_id);
+CREATE INDEX idx_audit_logs_timestamp ON audit_logs (timestamp);
+CREATE INDEX idx_audit_logs_request_id ON audit_logs (request_id);
+CREATE INDEX idx_audit_logs_resource_action_timestamp ON audit_logs (resource_type, action, timestamp DESC);
@@ -0,0 +1,3 @@
+-- Drop retention archive tables
+DROP TABLE IF EXISTS archived_resources;
+DROP TABLE IF EXISTS archived_audit_logs;
@@ -0,0 +1,29 @@
+-- Archive tables for the retention subsystem; rows are moved here before being purged
+CREATE TABLE IF NOT EXISTS archived_audit_logs (
+    id UUID NOT NULL,
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    user_id VARCHAR(255),
+    user_agent TEXT,
+    ip_address INET,
+    request_id VARCHAR(255),
+    old_values JSONB,
+    new_values JSONB,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, timestamp)
+);
+
+CREATE TABLE IF NOT EXISTS archived_resources (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    deleted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_archived_audit_logs_resource ON archived_audit_logs (resource_type, resource_id);
+CREATE INDEX idx_archived_audit_logs_timestamp ON archived_audit_logs (timestamp);
+CREATE INDEX idx_archived_resources_resource ON archived_resources (resource_type, resource_id);
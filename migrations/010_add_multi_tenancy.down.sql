@@ -0,0 +1,14 @@
+-- Remove multi-tenancy
+DROP INDEX IF EXISTS idx_resource_history_tenant;
+DROP INDEX IF EXISTS idx_audit_logs_tenant_timestamp;
+DROP INDEX IF EXISTS idx_observations_tenant_created_at;
+DROP INDEX IF EXISTS idx_patients_tenant_created_at;
+
+ALTER TABLE archived_resources DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE archived_audit_logs DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE resource_history DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE observations DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE patients DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,34 @@
+-- Tenants hosted on this deployment
+CREATE TABLE IF NOT EXISTS tenants (
+    id VARCHAR(63) PRIMARY KEY CHECK (id ~ '^[a-z0-9][a-z0-9-]*$'),
+    name VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended')),
+    rate_limit_per_minute INTEGER NOT NULL DEFAULT 6000,
+    rate_limit_burst INTEGER NOT NULL DEFAULT 100,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Existing data belongs to the default tenant
+INSERT INTO tenants (id, name) VALUES ('default', 'Default') ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE observations ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE resource_history ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE archived_audit_logs ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE archived_resources ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+-- New rows must name their tenant explicitly
+ALTER TABLE patients ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE observations ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE audit_logs ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE resource_history ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE archived_audit_logs ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE archived_resources ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- Tenant-leading indexes for scoped queries
+CREATE INDEX idx_patients_tenant_created_at ON patients (tenant_id, created_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX idx_observations_tenant_created_at ON observations (tenant_id, created_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX idx_audit_logs_tenant_timestamp ON audit_logs (tenant_id, timestamp DESC);
+CREATE INDEX idx_resource_history_tenant ON resource_history (tenant_id, resource_type, resource_id);
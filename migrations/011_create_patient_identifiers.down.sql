@@ -0,0 +1,4 @@
+-- Remove patient identifier uniqueness
+DROP TRIGGER IF EXISTS sync_patients_identifiers ON patients;
+DROP FUNCTION IF EXISTS sync_patient_identifiers();
+DROP TABLE IF EXISTS patient_identifiers;
@@ -0,0 +1,44 @@
+-- Business identifiers (e.g. MRNs) claimed by patients, unique per tenant.
+-- Identifiers without a system are stored with an empty system so they still collide.
+CREATE TABLE IF NOT EXISTS patient_identifiers (
+    tenant_id VARCHAR(63) NOT NULL,
+    system TEXT NOT NULL DEFAULT '',
+    value TEXT NOT NULL,
+    patient_id UUID NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    CONSTRAINT uq_patient_identifiers_system_value UNIQUE (tenant_id, system, value)
+);
+
+CREATE INDEX idx_patient_identifiers_patient_id ON patient_identifiers (patient_id);
+
+-- Kept in sync by trigger so that single writes, COPY loads and restores are all covered.
+-- Soft-deleted patients keep their identifiers; purging the row releases them.
+CREATE OR REPLACE FUNCTION sync_patient_identifiers()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' THEN
+        DELETE FROM patient_identifiers WHERE patient_id = NEW.id;
+    END IF;
+
+    INSERT INTO patient_identifiers (tenant_id, system, value, patient_id)
+    SELECT DISTINCT NEW.tenant_id, COALESCE(i->>'system', ''), i->>'value', NEW.id
+    FROM jsonb_array_elements(CASE WHEN jsonb_typeof(NEW.identifier) = 'array' THEN NEW.identifier ELSE '[]'::jsonb END) i
+    WHERE i->>'value' IS NOT NULL AND i->>'value' <> '';
+
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- Backfill existing rows, oldest first. Pre-existing duplicates keep the first
+-- registration; later holders get a conflict on their next update.
+INSERT INTO patient_identifiers (tenant_id, system, value, patient_id)
+SELECT DISTINCT ON (p.tenant_id, COALESCE(i->>'system', ''), i->>'value')
+    p.tenant_id, COALESCE(i->>'system', ''), i->>'value', p.id
+FROM patients p,
+    jsonb_array_elements(CASE WHEN jsonb_typeof(p.identifier) = 'array' THEN p.identifier ELSE '[]'::jsonb END) i
+WHERE i->>'value' IS NOT NULL AND i->>'value' <> ''
+ORDER BY p.tenant_id, COALESCE(i->>'system', ''), i->>'value', p.created_at, p.id;
+
+CREATE TRIGGER sync_patients_identifiers
+    AFTER INSERT OR UPDATE OF identifier ON patients
+    FOR EACH ROW
+    EXECUTE FUNCTION sync_patient_identifiers();
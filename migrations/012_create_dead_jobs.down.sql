@@ -0,0 +1,2 @@
+-- Remove the dead letter table
+DROP TABLE IF EXISTS dead_jobs;
@@ -0,0 +1,15 @@
+-- Background jobs that failed permanently, kept for inspection and requeueing
+CREATE TABLE IF NOT EXISTS dead_jobs (
+    id VARCHAR(64) PRIMARY KEY,
+    job_type VARCHAR(100) NOT NULL,
+    payload JSONB,
+    retries INTEGER NOT NULL DEFAULT 0,
+    max_retries INTEGER NOT NULL DEFAULT 0,
+    timeout_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_dead_jobs_failed_at ON dead_jobs (failed_at DESC);
+CREATE INDEX idx_dead_jobs_job_type ON dead_jobs (job_type, failed_at DESC);
@@ -0,0 +1,2 @@
+-- Remove recurring job schedules
+DROP TABLE IF EXISTS job_schedules;
@@ -0,0 +1,23 @@
+-- Recurring background jobs. Rows with source 'config' are synced from the
+-- server configuration at startup; rows with source 'db' are managed directly.
+CREATE TABLE IF NOT EXISTS job_schedules (
+    name VARCHAR(100) PRIMARY KEY,
+    job_type VARCHAR(100) NOT NULL,
+    spec VARCHAR(100) NOT NULL,
+    payload JSONB,
+    timeout_ms BIGINT NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    source VARCHAR(20) NOT NULL DEFAULT 'db' CHECK (source IN ('config', 'db')),
+
+    -- Run tracking; running_job_id is set while a run is in flight
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    running_job_id VARCHAR(64),
+    running_since TIMESTAMP WITH TIME ZONE,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_finished_at TIMESTAMP WITH TIME ZONE,
+    last_status VARCHAR(20),
+    last_error TEXT,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
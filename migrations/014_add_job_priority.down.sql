@@ -0,0 +1,3 @@
+-- Remove job priority
+ALTER TABLE job_schedules DROP COLUMN IF EXISTS priority;
+ALTER TABLE dead_jobs DROP COLUMN IF EXISTS priority;
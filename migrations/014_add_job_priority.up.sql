@@ -0,0 +1,3 @@
+-- Job priority (higher runs first, 0 is normal) for requeued dead jobs and scheduled runs
+ALTER TABLE dead_jobs ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE job_schedules ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0;
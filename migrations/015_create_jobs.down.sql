@@ -0,0 +1,2 @@
+-- Remove background job status tracking
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,23 @@
+-- Status of background jobs, maintained by the worker pool for the jobs API
+CREATE TABLE IF NOT EXISTS jobs (
+    id VARCHAR(64) PRIMARY KEY,
+    tenant_id VARCHAR(63),
+    job_type VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL
+        CHECK (status IN ('queued', 'running', 'retrying', 'succeeded', 'failed')),
+    priority INTEGER NOT NULL DEFAULT 0,
+    payload JSONB,
+    progress INTEGER NOT NULL DEFAULT 0 CHECK (progress BETWEEN 0 AND 100),
+    progress_message TEXT,
+    retries INTEGER NOT NULL DEFAULT 0,
+    max_retries INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_jobs_created_at ON jobs (created_at DESC);
+CREATE INDEX idx_jobs_tenant ON jobs (tenant_id, created_at DESC);
+CREATE INDEX idx_jobs_status ON jobs (status, created_at DESC);
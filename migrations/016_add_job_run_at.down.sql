@@ -0,0 +1,2 @@
+-- Remove job due times
+ALTER TABLE jobs DROP COLUMN IF EXISTS run_at;
@@ -0,0 +1,2 @@
+-- When delayed jobs and retries become due
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS run_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,2 @@
+-- Remove job result history
+DROP TABLE IF EXISTS job_results;
@@ -0,0 +1,17 @@
+-- Outcome of every background job attempt, kept for debugging and pruned after
+-- JOB_HISTORY_DAYS by the job-history-cleanup job
+CREATE TABLE IF NOT EXISTS job_results (
+    id BIGSERIAL PRIMARY KEY,
+    job_id VARCHAR(64) NOT NULL,
+    tenant_id VARCHAR(63),
+    job_type VARCHAR(100) NOT NULL,
+    attempt INTEGER NOT NULL,
+    success BOOLEAN NOT NULL,
+    error TEXT,
+    duration_ms BIGINT NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_job_results_job ON job_results (job_id, attempt);
+CREATE INDEX idx_job_results_completed_at ON job_results (completed_at DESC);
+CREATE INDEX idx_job_results_tenant ON job_results (tenant_id, completed_at DESC);
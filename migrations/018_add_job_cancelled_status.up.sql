@@ -0,0 +1,4 @@
+-- Allow background jobs to be cancelled
+ALTER TABLE jobs DROP CONSTRAINT IF EXISTS jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check
+    CHECK (status IN ('queued', 'running', 'retrying', 'succeeded', 'failed', 'cancelled'));
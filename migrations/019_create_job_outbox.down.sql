@@ -0,0 +1,2 @@
+-- Remove the job outbox
+DROP TABLE IF EXISTS job_outbox;
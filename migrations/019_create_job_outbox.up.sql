@@ -0,0 +1,11 @@
+-- Background jobs recorded alongside resource changes, waiting to be submitted
+-- to the worker pool. Rows are removed once their job is submitted.
+CREATE TABLE IF NOT EXISTS job_outbox (
+    id VARCHAR(64) PRIMARY KEY,
+    tenant_id VARCHAR(63),
+    job_type VARCHAR(100) NOT NULL,
+    payload JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_job_outbox_created_at ON job_outbox (created_at);
@@ -0,0 +1,17 @@
+-- Responses to POST requests sent with an Idempotency-Key header, replayed when
+-- the client retries with the same key. A row without status_code is a request
+-- still in progress.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    tenant_id VARCHAR(63) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER,
+    headers JSONB,
+    body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (tenant_id, subject, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,2 @@
+-- Remove the request ID of outbox entries
+ALTER TABLE job_outbox DROP COLUMN IF EXISTS request_id;
@@ -0,0 +1,2 @@
+-- Carry the ID of the request that recorded an outbox entry on to its job
+ALTER TABLE job_outbox ADD COLUMN IF NOT EXISTS request_id VARCHAR(255);
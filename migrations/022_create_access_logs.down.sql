@@ -0,0 +1,2 @@
+-- Remove the access log
+DROP TABLE IF EXISTS access_logs;
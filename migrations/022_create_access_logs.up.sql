@@ -0,0 +1,21 @@
+-- HTTP access log of API requests, kept for HIPAA access auditing. Request
+-- bodies and query values are stored with PHI fields redacted.
+CREATE TABLE IF NOT EXISTS access_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63),
+    request_id VARCHAR(255),
+    user_id VARCHAR(255),
+    ip_address INET,
+    user_agent TEXT,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    query TEXT,
+    status_code INTEGER NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    request_body TEXT,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_access_logs_tenant_timestamp ON access_logs (tenant_id, timestamp);
+CREATE INDEX idx_access_logs_user_id ON access_logs (user_id);
+CREATE INDEX idx_access_logs_request_id ON access_logs (request_id);
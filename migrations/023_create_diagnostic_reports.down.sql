@@ -0,0 +1,3 @@
+-- Drop diagnostic_reports table and related objects
+DROP TRIGGER IF EXISTS update_diagnostic_reports_updated_at ON diagnostic_reports;
+DROP TABLE IF EXISTS diagnostic_reports;
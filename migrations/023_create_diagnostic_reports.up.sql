@@ -0,0 +1,46 @@
+-- Diagnostic reports following the FHIR DiagnosticReport resource, grouping the
+-- result observations of an order such as a lab panel
+CREATE TABLE IF NOT EXISTS diagnostic_reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants (id),
+    identifier JSONB DEFAULT '[]'::jsonb,
+    based_on JSONB DEFAULT '[]'::jsonb,
+    status VARCHAR(50) NOT NULL CHECK (status IN ('registered', 'partial', 'preliminary', 'final', 'amended', 'corrected', 'appended', 'cancelled', 'entered-in-error', 'unknown')),
+    category JSONB DEFAULT '[]'::jsonb,
+    code JSONB NOT NULL,
+    subject JSONB NOT NULL,
+    encounter JSONB,
+    effective_date_time TIMESTAMP WITH TIME ZONE,
+    effective_period JSONB,
+    issued TIMESTAMP WITH TIME ZONE,
+    performer JSONB DEFAULT '[]'::jsonb,
+    results_interpreter JSONB DEFAULT '[]'::jsonb,
+    specimen JSONB DEFAULT '[]'::jsonb,
+    result JSONB DEFAULT '[]'::jsonb,
+    conclusion TEXT,
+    conclusion_code JSONB DEFAULT '[]'::jsonb,
+    meta JSONB DEFAULT '{}'::jsonb,
+    implicit_rules TEXT,
+    language VARCHAR(10),
+    text JSONB,
+    contained JSONB DEFAULT '[]'::jsonb,
+    extension JSONB DEFAULT '[]'::jsonb,
+    modifier_extension JSONB DEFAULT '[]'::jsonb,
+    -- Extracted search columns maintained by the application on write
+    code_values TEXT[] NOT NULL DEFAULT '{}',
+    subject_reference TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    version INTEGER DEFAULT 1,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_diagnostic_reports_tenant_created_at ON diagnostic_reports (tenant_id, created_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX idx_diagnostic_reports_subject ON diagnostic_reports (tenant_id, subject_reference, created_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX idx_diagnostic_reports_code_values ON diagnostic_reports USING GIN (code_values);
+CREATE INDEX idx_diagnostic_reports_identifier ON diagnostic_reports USING GIN (identifier jsonb_path_ops);
+
+CREATE TRIGGER update_diagnostic_reports_updated_at
+    BEFORE UPDATE ON diagnostic_reports
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,4 @@
+-- Drop webhook tables and related objects
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TRIGGER IF EXISTS update_webhooks_updated_at ON webhooks;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,44 @@
+-- Webhook endpoints registered by tenant admins to receive resource change
+-- events. The secret signs deliveries and must be readable to do so.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants (id),
+    url TEXT NOT NULL,
+    description VARCHAR(255),
+    -- Event patterns such as 'Patient.create' or 'Observation.*'; empty matches every event
+    events TEXT[] NOT NULL DEFAULT '{}',
+    secret TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhooks_tenant ON webhooks (tenant_id) WHERE active;
+
+CREATE TRIGGER update_webhooks_updated_at
+    BEFORE UPDATE ON webhooks
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Every attempt to deliver an event to a webhook, kept for debugging and pruned
+-- after JOB_HISTORY_DAYS by the job-history-cleanup job
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id UUID NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    tenant_id VARCHAR(63) NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    attempt INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    success BOOLEAN NOT NULL,
+    status_code INTEGER,
+    response_body TEXT,
+    error TEXT,
+    duration_ms BIGINT NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, delivered_at DESC);
+CREATE INDEX idx_webhook_deliveries_event ON webhook_deliveries (webhook_id, event_id) WHERE success;
+CREATE INDEX idx_webhook_deliveries_delivered_at ON webhook_deliveries (delivered_at);
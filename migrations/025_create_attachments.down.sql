@@ -0,0 +1,2 @@
+-- Drop the attachments table; objects already stored are left in the object store
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,16 @@
+-- Attachment contents are kept in the object store; this table holds what is
+-- needed to serve them. Resources refer to attachments by URL.
+CREATE TABLE IF NOT EXISTS attachments (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants (id),
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    -- Base64 SHA-1 of the content, as FHIR Attachment.hash carries it
+    hash VARCHAR(64) NOT NULL,
+    title VARCHAR(255),
+    object_key TEXT NOT NULL,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_attachments_tenant ON attachments (tenant_id, created_at);
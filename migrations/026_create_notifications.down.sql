@@ -0,0 +1,5 @@
+-- Drop notification tables and related objects
+DROP TRIGGER IF EXISTS update_notifications_updated_at ON notifications;
+DROP TABLE IF EXISTS notifications;
+DROP TRIGGER IF EXISTS update_notification_recipients_updated_at ON notification_recipients;
+DROP TABLE IF EXISTS notification_recipients;
@@ -0,0 +1,51 @@
+-- People the tenant's notifications, such as critical result emails, are sent to
+CREATE TABLE IF NOT EXISTS notification_recipients (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants (id),
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(320) NOT NULL,
+    -- Notification kinds such as 'critical_result'; empty receives every kind
+    kinds TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_notification_recipients_tenant ON notification_recipients (tenant_id) WHERE active;
+
+CREATE TRIGGER update_notification_recipients_updated_at
+    BEFORE UPDATE ON notification_recipients
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Every notification queued, with its rendered content and delivery status.
+-- Finished ones are pruned after JOB_HISTORY_DAYS by the job-history-cleanup job.
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants (id),
+    kind VARCHAR(50) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    recipient_id UUID REFERENCES notification_recipients (id) ON DELETE SET NULL,
+    address VARCHAR(320) NOT NULL,
+    -- What the notification is about, e.g. 'Observation/<id>'
+    reference VARCHAR(255) NOT NULL,
+    subject TEXT NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+-- An event notifies each address once, however often it is processed
+CREATE UNIQUE INDEX idx_notifications_event ON notifications (tenant_id, kind, reference, channel, address);
+CREATE INDEX idx_notifications_tenant ON notifications (tenant_id, created_at DESC);
+CREATE INDEX idx_notifications_created_at ON notifications (created_at) WHERE status <> 'queued';
+
+CREATE TRIGGER update_notifications_updated_at
+    BEFORE UPDATE ON notifications
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,24 @@
+-- Remove SMS paging
+DROP INDEX IF EXISTS idx_notifications_escalate_at;
+DROP INDEX IF EXISTS idx_notifications_held;
+DELETE FROM notifications WHERE channel = 'sms';
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS acknowledged_by;
+ALTER TABLE notifications DROP COLUMN IF EXISTS acknowledged_at;
+ALTER TABLE notifications DROP COLUMN IF EXISTS escalated_at;
+ALTER TABLE notifications DROP COLUMN IF EXISTS escalate_at;
+ALTER TABLE notifications DROP COLUMN IF EXISTS deliver_after;
+ALTER TABLE notifications DROP COLUMN IF EXISTS provider_message_id;
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_status_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_status_check
+    CHECK (status IN ('queued', 'sent', 'failed'));
+
+DELETE FROM notification_recipients WHERE email IS NULL;
+ALTER TABLE notification_recipients DROP CONSTRAINT IF EXISTS notification_recipients_address_check;
+ALTER TABLE notification_recipients DROP COLUMN IF EXISTS escalate_after_minutes;
+ALTER TABLE notification_recipients DROP COLUMN IF EXISTS escalate_to;
+ALTER TABLE notification_recipients DROP COLUMN IF EXISTS quiet_hours_time_zone;
+ALTER TABLE notification_recipients DROP COLUMN IF EXISTS quiet_hours_end;
+ALTER TABLE notification_recipients DROP COLUMN IF EXISTS quiet_hours_start;
+ALTER TABLE notification_recipients DROP COLUMN IF EXISTS phone;
+ALTER TABLE notification_recipients ALTER COLUMN email SET NOT NULL;
@@ -0,0 +1,30 @@
+-- SMS paging of critical results: recipients may have a phone number, quiet
+-- hours and an escalation target, and pages track acknowledgement and escalation
+ALTER TABLE notification_recipients ALTER COLUMN email DROP NOT NULL;
+ALTER TABLE notification_recipients ADD COLUMN IF NOT EXISTS phone VARCHAR(16);
+-- Local times 'HH:MM' in quiet_hours_time_zone; a period ending before it starts spans midnight
+ALTER TABLE notification_recipients ADD COLUMN IF NOT EXISTS quiet_hours_start VARCHAR(5);
+ALTER TABLE notification_recipients ADD COLUMN IF NOT EXISTS quiet_hours_end VARCHAR(5);
+ALTER TABLE notification_recipients ADD COLUMN IF NOT EXISTS quiet_hours_time_zone VARCHAR(64);
+ALTER TABLE notification_recipients ADD COLUMN IF NOT EXISTS escalate_to UUID
+    REFERENCES notification_recipients (id) ON DELETE SET NULL;
+ALTER TABLE notification_recipients ADD COLUMN IF NOT EXISTS escalate_after_minutes INTEGER;
+ALTER TABLE notification_recipients ADD CONSTRAINT notification_recipients_address_check
+    CHECK (email IS NOT NULL OR phone IS NOT NULL);
+
+-- Pages held over the recipient's quiet hours wait in 'held' until deliver_after
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_status_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_status_check
+    CHECK (status IN ('held', 'queued', 'sent', 'failed'));
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS provider_message_id VARCHAR(64);
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS deliver_after TIMESTAMP WITH TIME ZONE;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS escalate_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS acknowledged_by VARCHAR(255);
+
+-- Held pages acknowledged before their release are never sent and are pruned
+-- with finished notifications
+CREATE INDEX idx_notifications_held ON notifications (deliver_after) WHERE status = 'held';
+CREATE INDEX idx_notifications_escalate_at ON notifications (escalate_at)
+    WHERE escalated_at IS NULL AND acknowledged_at IS NULL;
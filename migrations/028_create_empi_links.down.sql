@@ -0,0 +1,3 @@
+-- Drop the EMPI link table and related objects
+DROP TRIGGER IF EXISTS update_empi_links_updated_at ON empi_links;
+DROP TABLE IF EXISTS empi_links;
@@ -0,0 +1,28 @@
+-- The outcome of the last EMPI lookup of each patient. Patients the EMPI
+-- disagrees on, or could not be asked about, are looked up again by the
+-- empi-reconciliation job.
+CREATE TABLE IF NOT EXISTS empi_links (
+    patient_id UUID PRIMARY KEY REFERENCES patients (id) ON DELETE CASCADE,
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants (id),
+    status VARCHAR(20) NOT NULL
+        CHECK (status IN ('linked', 'unmatched', 'mismatch', 'conflict', 'error')),
+    -- The enterprise identifier stored on the patient, if any
+    enterprise_id TEXT,
+    -- The enterprise identifier the EMPI returned, when it is not the stored one
+    candidate_id TEXT,
+    -- The patient already holding candidate_id, for a conflict
+    conflicting_patient_id UUID,
+    last_error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    checked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_empi_links_tenant_status ON empi_links (tenant_id, status);
+CREATE INDEX idx_empi_links_reconcile ON empi_links (checked_at) WHERE status <> 'linked';
+
+CREATE TRIGGER update_empi_links_updated_at
+    BEFORE UPDATE ON empi_links
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,4 @@
+-- Drop the imaging_studies table and related objects
+ALTER TABLE diagnostic_reports DROP COLUMN IF EXISTS imaging_study;
+DROP TRIGGER IF EXISTS update_imaging_studies_updated_at ON imaging_studies;
+DROP TABLE IF EXISTS imaging_studies;
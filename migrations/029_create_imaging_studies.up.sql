@@ -0,0 +1,45 @@
+-- Imaging studies following the FHIR ImagingStudy resource, holding the DICOM
+-- metadata of studies in a PACS so reports can link to their images
+CREATE TABLE IF NOT EXISTS imaging_studies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants (id),
+    identifier JSONB DEFAULT '[]'::jsonb,
+    status VARCHAR(50) NOT NULL CHECK (status IN ('registered', 'available', 'cancelled', 'entered-in-error', 'unknown')),
+    modality JSONB DEFAULT '[]'::jsonb,
+    subject JSONB NOT NULL,
+    started TIMESTAMP WITH TIME ZONE,
+    number_of_series INTEGER NOT NULL DEFAULT 0,
+    number_of_instances INTEGER NOT NULL DEFAULT 0,
+    description TEXT,
+    series JSONB DEFAULT '[]'::jsonb,
+    meta JSONB DEFAULT '{}'::jsonb,
+    implicit_rules TEXT,
+    language VARCHAR(10),
+    text JSONB,
+    contained JSONB DEFAULT '[]'::jsonb,
+    extension JSONB DEFAULT '[]'::jsonb,
+    modifier_extension JSONB DEFAULT '[]'::jsonb,
+    -- Extracted search columns maintained by the application on write
+    study_instance_uid VARCHAR(64) NOT NULL,
+    accession_number VARCHAR(64),
+    modality_codes TEXT[] NOT NULL DEFAULT '{}',
+    subject_reference TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    version INTEGER DEFAULT 1,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX idx_imaging_studies_study_instance_uid ON imaging_studies (tenant_id, study_instance_uid);
+CREATE INDEX idx_imaging_studies_tenant_created_at ON imaging_studies (tenant_id, created_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX idx_imaging_studies_subject ON imaging_studies (tenant_id, subject_reference, created_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX idx_imaging_studies_accession_number ON imaging_studies (tenant_id, accession_number) WHERE deleted_at IS NULL;
+CREATE INDEX idx_imaging_studies_modality_codes ON imaging_studies USING GIN (modality_codes);
+
+CREATE TRIGGER update_imaging_studies_updated_at
+    BEFORE UPDATE ON imaging_studies
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Reports reference the studies whose images they interpret
+ALTER TABLE diagnostic_reports ADD COLUMN IF NOT EXISTS imaging_study JSONB DEFAULT '[]'::jsonb;
@@ -0,0 +1,5 @@
+-- Drop the critical value rules and tasks
+DROP TRIGGER IF EXISTS update_critical_value_tasks_updated_at ON critical_value_tasks;
+DROP TABLE IF EXISTS critical_value_tasks;
+DROP TRIGGER IF EXISTS update_critical_value_rules_updated_at ON critical_value_rules;
+DROP TABLE IF EXISTS critical_value_rules;
@@ -0,0 +1,50 @@
+-- Critical value rules: per code thresholds beyond which an observation is
+-- flagged critical when it is written
+CREATE TABLE IF NOT EXISTS critical_value_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants (id),
+    name VARCHAR(255) NOT NULL,
+    code_system VARCHAR(255) NOT NULL,
+    code VARCHAR(100) NOT NULL,
+    -- UCUM code of the thresholds; values in other units are converted
+    unit VARCHAR(50) NOT NULL,
+    low DOUBLE PRECISION,
+    high DOUBLE PRECISION,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT critical_value_rules_threshold_check CHECK (low IS NOT NULL OR high IS NOT NULL),
+    CONSTRAINT critical_value_rules_range_check CHECK (low IS NULL OR high IS NULL OR low < high)
+);
+
+CREATE INDEX idx_critical_value_rules_code ON critical_value_rules (tenant_id, code) WHERE active;
+
+CREATE TRIGGER update_critical_value_rules_updated_at
+    BEFORE UPDATE ON critical_value_rules
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Acknowledgement tasks of critical results, served as FHIR Task resources.
+-- An observation opens one task, however often it is processed.
+CREATE TABLE IF NOT EXISTS critical_value_tasks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants (id),
+    observation_id UUID NOT NULL,
+    -- The observation's subject, e.g. 'Patient/<id>'
+    subject VARCHAR(255) NOT NULL,
+    interpretation VARCHAR(10) NOT NULL,
+    description TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'requested' CHECK (status IN ('requested', 'completed')),
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    acknowledged_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_critical_value_tasks_observation ON critical_value_tasks (tenant_id, observation_id);
+CREATE INDEX idx_critical_value_tasks_tenant ON critical_value_tasks (tenant_id, status, created_at DESC);
+
+CREATE TRIGGER update_critical_value_tasks_updated_at
+    BEFORE UPDATE ON critical_value_tasks
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,3 @@
+-- Drop the patient duplicate candidates
+DROP TRIGGER IF EXISTS update_patient_duplicates_updated_at ON patient_duplicates;
+DROP TABLE IF EXISTS patient_duplicates;
@@ -0,0 +1,29 @@
+-- Probable duplicate patients found by the duplicate-detection job, awaiting
+-- or after review by a registrar. A pair is stored once, lowest id first.
+CREATE TABLE IF NOT EXISTS patient_duplicates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants (id),
+    patient_id UUID NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    duplicate_id UUID NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    score DOUBLE PRECISION NOT NULL,
+    -- What matched, e.g. {name,birth-date}
+    reasons TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'dismissed')),
+    -- When the pair was last found; pending pairs not found by a later scan are removed
+    detected_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    reviewed_by VARCHAR(255),
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT patient_duplicates_order_check CHECK (patient_id < duplicate_id)
+);
+
+CREATE UNIQUE INDEX idx_patient_duplicates_pair ON patient_duplicates (patient_id, duplicate_id);
+CREATE INDEX idx_patient_duplicates_duplicate_id ON patient_duplicates (duplicate_id);
+CREATE INDEX idx_patient_duplicates_tenant ON patient_duplicates (tenant_id, status, score DESC);
+
+CREATE TRIGGER update_patient_duplicates_updated_at
+    BEFORE UPDATE ON patient_duplicates
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,10 @@
+-- Remove MERGE actions
+UPDATE audit_logs SET action = 'UPDATE' WHERE action = 'MERGE';
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_action_check;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_action_check
+    CHECK (action IN ('CREATE', 'READ', 'UPDATE', 'DELETE', 'RESTORE'));
+
+UPDATE resource_history SET action = 'UPDATE' WHERE action = 'MERGE';
+ALTER TABLE resource_history DROP CONSTRAINT IF EXISTS resource_history_action_check;
+ALTER TABLE resource_history ADD CONSTRAINT resource_history_action_check
+    CHECK (action IN ('CREATE', 'UPDATE', 'DELETE', 'RESTORE'));
@@ -0,0 +1,9 @@
+-- Allow MERGE actions in the audit log and resource history, recorded for the
+-- patients of a patient merge and the resources it moves between them
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_action_check;
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_action_check
+    CHECK (action IN ('CREATE', 'READ', 'UPDATE', 'DELETE', 'RESTORE', 'MERGE'));
+
+ALTER TABLE resource_history DROP CONSTRAINT IF EXISTS resource_history_action_check;
+ALTER TABLE resource_history ADD CONSTRAINT resource_history_action_check
+    CHECK (action IN ('CREATE', 'UPDATE', 'DELETE', 'RESTORE', 'MERGE'));
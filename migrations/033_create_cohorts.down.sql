@@ -0,0 +1,3 @@
+-- Drop the cohorts
+DROP TRIGGER IF EXISTS update_cohorts_updated_at ON cohorts;
+DROP TABLE IF EXISTS cohorts;
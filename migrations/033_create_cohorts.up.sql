@@ -0,0 +1,18 @@
+-- Cohorts: stored patient selection criteria, evaluated on request
+CREATE TABLE IF NOT EXISTS cohorts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(63) NOT NULL REFERENCES tenants (id),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    criteria JSONB NOT NULL,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_cohorts_tenant ON cohorts (tenant_id, name);
+
+CREATE TRIGGER update_cohorts_updated_at
+    BEFORE UPDATE ON cohorts
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
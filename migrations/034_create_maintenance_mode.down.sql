@@ -0,0 +1,2 @@
+-- Drop maintenance mode
+DROP TABLE IF EXISTS maintenance_mode;
@@ -0,0 +1,11 @@
+-- Maintenance mode: a single row, read by every API and worker instance, that
+-- switches writes and background jobs off, e.g. during schema migrations
+CREATE TABLE IF NOT EXISTS maintenance_mode (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    message TEXT,
+    updated_by VARCHAR(255),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO maintenance_mode (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
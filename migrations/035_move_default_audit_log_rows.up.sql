@@ -0,0 +1,47 @@
+-- Rows written for a month without a partition land in audit_logs_default, and
+-- creating that month's partition then fails because the default partition
+-- already holds rows in its range. Move them into the new partition first.
+CREATE OR REPLACE FUNCTION create_audit_log_partition(month_start DATE) RETURNS TEXT AS $$
+DECLARE
+    range_start DATE := date_trunc('month', month_start)::DATE;
+    range_end DATE := (date_trunc('month', month_start) + INTERVAL '1 month')::DATE;
+    partition_name TEXT := 'audit_logs_' || to_char(range_start, 'YYYY_MM');
+    stranded BOOLEAN := FALSE;
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN partition_name;
+    END IF;
+
+    IF to_regclass('audit_logs_default') IS NOT NULL THEN
+        SELECT EXISTS (
+            SELECT 1 FROM audit_logs_default
+            WHERE timestamp >= range_start AND timestamp < range_end
+        ) INTO stranded;
+    END IF;
+
+    IF NOT stranded THEN
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF audit_logs FOR VALUES FROM (%L) TO (%L)',
+            partition_name, range_start, range_end
+        );
+        RETURN partition_name;
+    END IF;
+
+    -- Detaching the default partition locks audit_logs until the transaction
+    -- commits, so no row is written while the default is detached
+    ALTER TABLE audit_logs DETACH PARTITION audit_logs_default;
+    EXECUTE format(
+        'CREATE TABLE %I PARTITION OF audit_logs FOR VALUES FROM (%L) TO (%L)',
+        partition_name, range_start, range_end
+    );
+    WITH moved AS (
+        DELETE FROM audit_logs_default
+        WHERE timestamp >= range_start AND timestamp < range_end
+        RETURNING *
+    )
+    INSERT INTO audit_logs SELECT * FROM moved;
+    ALTER TABLE audit_logs ATTACH PARTITION audit_logs_default DEFAULT;
+
+    RETURN partition_name;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryMargin is how long before it expires a client credentials token is renewed
+const tokenExpiryMargin = 30 * time.Second
+
+// TokenSource supplies the bearer token sent with each request. The API takes
+// JWTs issued by the deployment's authentication provider, carrying the
+// caller's tenant, roles and scopes.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a fixed bearer token
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// ClientCredentials obtains tokens from an OAuth 2.0 token endpoint with the
+// client credentials grant, authenticating with HTTP Basic, and reuses each
+// until shortly before it expires
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// Space-separated scopes requested, e.g. "patient:read patient:write";
+	// empty requests none
+	Scope string
+	// HTTPClient reaches the token endpoint; nil uses http.DefaultClient
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	current string
+	expires time.Time
+}
+
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current != "" && time.Now().Before(c.expires) {
+		return c.current, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if c.Scope != "" {
+		form.Set("scope", c.Scope)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint responded %d", resp.StatusCode)
+	}
+
+	var granted struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &granted); err != nil || granted.AccessToken == "" {
+		return "", errors.New("token endpoint returned no access token")
+	}
+
+	lifetime := time.Duration(granted.ExpiresIn)*time.Second - tokenExpiryMargin
+	if lifetime < tokenExpiryMargin {
+		lifetime = tokenExpiryMargin
+	}
+	c.current = granted.AccessToken
+	c.expires = time.Now().Add(lifetime)
+	return c.current, nil
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"healthcare-api/internal/models"
+)
+
+// defaultBulkConcurrency is the number of creates a bulk call sends at once
+// when BulkOptions.Concurrency is unset
+const defaultBulkConcurrency = 4
+
+// BulkOptions configures a bulk create
+type BulkOptions struct {
+	// Creates in flight at once; 0 uses 4. Keep it within the tenant's rate
+	// limit, as 429 responses are only retried up to Config.Retries times.
+	Concurrency int
+}
+
+// BulkResult is the outcome of one create of a bulk call, at the same index
+// as its request
+type BulkResult[T any] struct {
+	Resource *T
+	Err      error
+}
+
+// CreatePatients creates each patient of reqs, returning a result per
+// request. One failed create does not stop the others; only a cancelled ctx
+// does, leaving the creates not yet sent with ctx's error.
+func (c *Client) CreatePatients(ctx context.Context, reqs []*models.PatientCreateRequest, opts BulkOptions) []BulkResult[models.Patient] {
+	return bulk(ctx, reqs, opts, c.CreatePatient)
+}
+
+// CreateObservations creates each observation of reqs, returning a result
+// per request, as CreatePatients does
+func (c *Client) CreateObservations(ctx context.Context, reqs []*models.ObservationCreateRequest, opts BulkOptions) []BulkResult[models.Observation] {
+	return bulk(ctx, reqs, opts, c.CreateObservation)
+}
+
+// BulkErrors returns the errors of results, or nil when every create succeeded
+func BulkErrors[T any](results []BulkResult[T]) []error {
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return errs
+}
+
+func bulk[R, T any](ctx context.Context, reqs []R, opts BulkOptions, create func(context.Context, R) (*T, error)) []BulkResult[T] {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+
+	results := make([]BulkResult[T], len(reqs))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			for j := i; j < len(reqs); j++ {
+				results[j].Err = ctx.Err()
+			}
+			wg.Wait()
+			return results
+		}
+
+		wg.Add(1)
+		go func(i int, req R) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i].Resource, results[i].Err = create(ctx, req)
+		}(i, req)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,276 @@
+// Package client is the Go client of the Healthcare API. It sends the API's
+// own request and resource types, so a client built from the same release as
+// the server always agrees with it on the wire.
+//
+//	c, err := client.New(client.Config{
+//		BaseURL: "https://api.healthcare.example.com",
+//		Tokens:  client.StaticToken(os.Getenv("HEALTHCARE_API_TOKEN")),
+//		Retries: 3,
+//	})
+//	patient, err := c.CreatePatient(ctx, &models.PatientCreateRequest{...})
+//
+// Every POST carries a fresh Idempotency-Key, so requests that failed with a
+// network error, 429 or 503 are retried without creating a resource twice.
+// Errors returned by the API are *Error, carrying its OperationOutcome.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// maxRetryDelay caps the wait before a retry, whatever Retry-After asks for
+const maxRetryDelay = 30 * time.Second
+
+// Config configures a Client
+type Config struct {
+	// BaseURL is the root of the API, e.g. https://api.healthcare.example.com,
+	// without the /api/v1 path
+	BaseURL string
+	// Tokens supplies the bearer token of each request
+	Tokens TokenSource
+	// HTTPClient sends the requests; nil uses a client with a 30 second timeout
+	HTTPClient *http.Client
+	// Retries of a request that failed with a network error, 429 Too Many
+	// Requests or 503 Service Unavailable; 0 does not retry
+	Retries int
+	// UserAgent is sent with every request; empty sends "healthcare-api-go"
+	UserAgent string
+}
+
+// Client calls the Healthcare API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	tokens     TokenSource
+	httpClient *http.Client
+	retries    int
+	userAgent  string
+}
+
+// New creates a client of the API at cfg.BaseURL
+func New(cfg Config) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+	if err != nil || (base.Scheme != "https" && base.Scheme != "http") || base.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", cfg.BaseURL)
+	}
+	if cfg.Tokens == nil {
+		return nil, errors.New("a token source is required")
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = "healthcare-api-go"
+	}
+	return &Client{
+		baseURL:    base,
+		tokens:     cfg.Tokens,
+		httpClient: httpClient,
+		retries:    cfg.Retries,
+		userAgent:  userAgent,
+	}, nil
+}
+
+// Error is an error response of the API
+type Error struct {
+	StatusCode int
+	// Code is the stable error code of the response, e.g. PATIENT_NOT_FOUND;
+	// empty when the response carried no OperationOutcome
+	Code string
+	// Outcome is the OperationOutcome of the response, if it had one
+	Outcome *models.OperationOutcome
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("healthcare API responded %d", e.StatusCode)
+	if e.Code != "" {
+		message += " " + e.Code
+	}
+	if e.Outcome != nil && len(e.Outcome.Issue) > 0 && e.Outcome.Issue[0].Diagnostics != nil {
+		message += ": " + *e.Outcome.Issue[0].Diagnostics
+	}
+	return message
+}
+
+// ErrorCode returns the API error code of err, e.g. IDENTIFIER_CONFLICT, or ""
+// when err is not an error response of the API
+func ErrorCode(err error) string {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// IsNotFound reports whether err is a 404 Not Found or 410 Gone response, the
+// latter for a deleted resource
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusGone)
+}
+
+// do sends a request with body encoded as JSON, retrying it as configured,
+// and decodes a successful response into out unless it is nil
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+	// The same key on every attempt makes a retried POST run only once
+	idempotencyKey := ""
+	if method == http.MethodPost {
+		idempotencyKey = uuid.NewString()
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, payload, idempotencyKey)
+		if err != nil {
+			if attempt < c.retries && ctx.Err() == nil {
+				if err := wait(ctx, retryDelay(attempt, "")); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+
+		if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && attempt < c.retries {
+			delay := retryDelay(attempt, resp.Header.Get("Retry-After"))
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if err := wait(ctx, delay); err != nil {
+				return err
+			}
+			continue
+		}
+		return decodeResponse(resp, out)
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, payload []byte, idempotencyKey string) (*http.Response, error) {
+	target, err := c.baseURL.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request path %q: %w", path, err)
+	}
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/fhir+json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/fhir+json")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call healthcare API: %w", err)
+	}
+	return resp, nil
+}
+
+// decodeResponse decodes a successful response into out, or returns the
+// *Error of an unsuccessful one
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		var outcome models.OperationOutcome
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&outcome); err == nil && outcome.ResourceType == "OperationOutcome" {
+			apiErr.Outcome = &outcome
+			if len(outcome.Issue) > 0 && outcome.Issue[0].Details != nil {
+				for _, coding := range outcome.Issue[0].Details.Coding {
+					if coding.System != nil && *coding.System == models.ErrorCodeSystem && coding.Code != nil {
+						apiErr.Code = *coding.Code
+					}
+				}
+			}
+		}
+		return apiErr
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// retryDelay is the wait before retry attempt+1: the Retry-After seconds when
+// the server sent them, otherwise a doubling backoff from half a second
+func retryDelay(attempt int, retryAfter string) time.Duration {
+	delay := time.Duration(500<<attempt) * time.Millisecond
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		delay = time.Duration(seconds) * time.Second
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+func wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// nextLink returns the next page link of a search bundle, or ""
+func nextLink(links []models.BundleLink) string {
+	for _, link := range links {
+		if link.Relation == "next" {
+			return link.URL
+		}
+	}
+	return ""
+}
+
+// pageQuery adds the paging parameters of a search to query
+func pageQuery(query url.Values, limit, offset int) {
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+}
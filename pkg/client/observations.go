@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ObservationSearch are the parameters of an observation search. Text runs
+// the fuzzy search, optionally limited by Subject, instead of the Subject and
+// Code filters.
+type ObservationSearch struct {
+	// Subject reference, e.g. Patient/123e4567-e89b-12d3-a456-426614174000
+	Subject string
+	// Code, either code or system|code, e.g. http://loinc.org|8867-4
+	Code string
+	// Free text matched against code, category, value, interpretation and
+	// note text; needs the server's Elasticsearch search backend
+	Text string
+	// Page size (the API's default is 20, at most 100) and observations skipped
+	Limit  int
+	Offset int
+}
+
+func (s ObservationSearch) query() url.Values {
+	query := url.Values{}
+	if s.Text != "" {
+		query.Set("_query", "smart")
+		query.Set("text", s.Text)
+	} else if s.Code != "" {
+		query.Set("code", s.Code)
+	}
+	if s.Subject != "" {
+		query.Set("subject", s.Subject)
+	}
+	pageQuery(query, s.Limit, s.Offset)
+	return query
+}
+
+// ObservationRead are the options of reading an observation. Either
+// downsamples waveform SampledData in the response.
+type ObservationRead struct {
+	// At most this many points per waveform; 0 for all
+	MaxPoints int
+	// At least this time between points; 0 for all
+	Resolution time.Duration
+}
+
+// CreateObservation creates an observation. Requires the observation:write scope.
+func (c *Client) CreateObservation(ctx context.Context, req *models.ObservationCreateRequest) (*models.Observation, error) {
+	var observation models.Observation
+	if err := c.do(ctx, "POST", "/api/v1/observations", req, &observation); err != nil {
+		return nil, err
+	}
+	return &observation, nil
+}
+
+// GetObservation reads an observation. A deleted observation is a 410 Gone *Error.
+func (c *Client) GetObservation(ctx context.Context, id uuid.UUID, opts ObservationRead) (*models.Observation, error) {
+	query := url.Values{}
+	if opts.MaxPoints > 0 {
+		query.Set("max-points", strconv.Itoa(opts.MaxPoints))
+	}
+	if opts.Resolution > 0 {
+		query.Set("resolution", opts.Resolution.String())
+	}
+	path := "/api/v1/observations/" + id.String()
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var observation models.Observation
+	if err := c.do(ctx, "GET", path, nil, &observation); err != nil {
+		return nil, err
+	}
+	return &observation, nil
+}
+
+// UpdateObservation updates the elements of an observation set in req
+func (c *Client) UpdateObservation(ctx context.Context, id uuid.UUID, req *models.ObservationUpdateRequest) (*models.Observation, error) {
+	var observation models.Observation
+	if err := c.do(ctx, "PUT", "/api/v1/observations/"+id.String(), req, &observation); err != nil {
+		return nil, err
+	}
+	return &observation, nil
+}
+
+// DeleteObservation soft-deletes an observation. Requires the observation:delete scope.
+func (c *Client) DeleteObservation(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, "DELETE", "/api/v1/observations/"+id.String(), nil, nil)
+}
+
+// SearchObservations returns a page of the observations matching search,
+// newest first
+func (c *Client) SearchObservations(ctx context.Context, search ObservationSearch) (*models.ObservationListResponse, error) {
+	var bundle models.ObservationListResponse
+	if err := c.do(ctx, "GET", "/api/v1/observations?"+search.query().Encode(), nil, &bundle); err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+// EachObservation calls fn with every observation matching search, following
+// the bundles' next links from search.Offset on. An error from fn stops the
+// search and is returned.
+func (c *Client) EachObservation(ctx context.Context, search ObservationSearch, fn func(*models.Observation) error) error {
+	path := "/api/v1/observations?" + search.query().Encode()
+	for path != "" {
+		var bundle models.ObservationListResponse
+		if err := c.do(ctx, "GET", path, nil, &bundle); err != nil {
+			return err
+		}
+		for _, entry := range bundle.Entry {
+			if err := fn(entry.Resource); err != nil {
+				return err
+			}
+		}
+		path = nextLink(bundle.Link)
+	}
+	return nil
+}
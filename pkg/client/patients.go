@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"healthcare-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// PatientSearch are the parameters of a patient search. Text runs the fuzzy
+// demographic search instead of the family and identifier filters.
+type PatientSearch struct {
+	// Family name prefix, matched case-insensitively
+	Family string
+	// Exact identifier value
+	Identifier string
+	// Free text matched against names, identifiers and addresses, ranked by
+	// relevance
+	Text string
+	// Page size (the API's default is 20, at most 100) and patients skipped
+	Limit  int
+	Offset int
+}
+
+func (s PatientSearch) query() url.Values {
+	query := url.Values{}
+	if s.Text != "" {
+		query.Set("_query", "smart")
+		query.Set("text", s.Text)
+	}
+	if s.Family != "" {
+		query.Set("family", s.Family)
+	}
+	if s.Identifier != "" {
+		query.Set("identifier", s.Identifier)
+	}
+	pageQuery(query, s.Limit, s.Offset)
+	return query
+}
+
+// CreatePatient creates a patient. Requires the patient:write scope.
+func (c *Client) CreatePatient(ctx context.Context, req *models.PatientCreateRequest) (*models.Patient, error) {
+	var patient models.Patient
+	if err := c.do(ctx, "POST", "/api/v1/patients", req, &patient); err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
+
+// GetPatient reads a patient. A deleted patient is a 410 Gone *Error.
+func (c *Client) GetPatient(ctx context.Context, id uuid.UUID) (*models.Patient, error) {
+	var patient models.Patient
+	if err := c.do(ctx, "GET", "/api/v1/patients/"+id.String(), nil, &patient); err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
+
+// UpdatePatient updates the elements of a patient set in req
+func (c *Client) UpdatePatient(ctx context.Context, id uuid.UUID, req *models.PatientUpdateRequest) (*models.Patient, error) {
+	var patient models.Patient
+	if err := c.do(ctx, "PUT", "/api/v1/patients/"+id.String(), req, &patient); err != nil {
+		return nil, err
+	}
+	return &patient, nil
+}
+
+// DeletePatient soft-deletes a patient. Requires the patient:delete scope.
+func (c *Client) DeletePatient(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, "DELETE", "/api/v1/patients/"+id.String(), nil, nil)
+}
+
+// SearchPatients returns a page of the patients matching search
+func (c *Client) SearchPatients(ctx context.Context, search PatientSearch) (*models.PatientListResponse, error) {
+	var bundle models.PatientListResponse
+	if err := c.do(ctx, "GET", "/api/v1/patients?"+search.query().Encode(), nil, &bundle); err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+// EachPatient calls fn with every patient matching search, following the
+// bundles' next links from search.Offset on. An error from fn stops the
+// search and is returned.
+func (c *Client) EachPatient(ctx context.Context, search PatientSearch, fn func(*models.Patient) error) error {
+	path := "/api/v1/patients?" + search.query().Encode()
+	for path != "" {
+		var bundle models.PatientListResponse
+		if err := c.do(ctx, "GET", path, nil, &bundle); err != nil {
+			return err
+		}
+		for _, entry := range bundle.Entry {
+			if err := fn(entry.Resource); err != nil {
+				return err
+			}
+		}
+		path = nextLink(bundle.Link)
+	}
+	return nil
+}
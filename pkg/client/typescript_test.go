@@ -0,0 +1,152 @@
+package client
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"unicode"
+
+	"healthcare-api/internal/models"
+)
+
+// The TypeScript client in clients/typescript mirrors this package and the
+// models it sends and receives. These tests fail when the two drift apart;
+// `make client-typecheck` checks the TypeScript itself.
+
+const typescriptSource = "../../clients/typescript/src/"
+
+var (
+	interfacePattern = regexp.MustCompile(`(?ms)^export interface (\w+)(?:<\w+>)?(?: extends ([^{]+))? \{\n(.*?)^\}`)
+	fieldPattern     = regexp.MustCompile(`(?m)^  (\w+)\??:`)
+	methodPattern    = regexp.MustCompile(`(?m)^  (?:async )?(\w+)\(`)
+)
+
+// typescriptInterfaces returns the fields of each interface of a source file,
+// with the fields of the interfaces it extends. Extended types other than
+// interfaces of the file, such as Partial<T>, are left out.
+func typescriptInterfaces(t *testing.T, file string) map[string][]string {
+	t.Helper()
+	source, err := os.ReadFile(typescriptSource + file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	own := map[string][]string{}
+	extends := map[string][]string{}
+	for _, match := range interfacePattern.FindAllStringSubmatch(string(source), -1) {
+		for _, field := range fieldPattern.FindAllStringSubmatch(match[3], -1) {
+			own[match[1]] = append(own[match[1]], field[1])
+		}
+		for _, parent := range strings.Split(match[2], ",") {
+			if parent = strings.TrimSpace(parent); parent != "" {
+				extends[match[1]] = append(extends[match[1]], parent)
+			}
+		}
+	}
+
+	var fields func(name string) []string
+	fields = func(name string) []string {
+		result := append([]string(nil), own[name]...)
+		for _, parent := range extends[name] {
+			result = append(result, fields(parent)...)
+		}
+		return result
+	}
+	interfaces := map[string][]string{}
+	for name := range own {
+		interfaces[name] = fields(name)
+	}
+	return interfaces
+}
+
+// jsonFields returns the JSON names of the fields of a struct, with those of
+// embedded structs
+func jsonFields(typ reflect.Type) []string {
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" {
+			names = append(names, jsonFields(field.Type)...)
+			continue
+		}
+		if name != "-" && name != "" && field.IsExported() {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestTypeScriptTypesMatchModels(t *testing.T) {
+	interfaces := typescriptInterfaces(t, "types.ts")
+
+	// Interfaces of types.ts and the models they mirror
+	mirrored := map[string]interface{}{
+		"Coding":                    models.Coding{},
+		"CodeableConcept":           models.CodeableConcept{},
+		"Period":                    models.Period{},
+		"Reference":                 models.Reference{},
+		"Identifier":                models.Identifier{},
+		"HumanName":                 models.HumanName{},
+		"ContactPoint":              models.ContactPoint{},
+		"Address":                   models.Address{},
+		"Attachment":                models.Attachment{},
+		"Quantity":                  models.Quantity{},
+		"Range":                     models.Range{},
+		"Ratio":                     models.Ratio{},
+		"SampledData":               models.SampledData{},
+		"Annotation":                models.Annotation{},
+		"Meta":                      models.Meta{},
+		"Narrative":                 models.Narrative{},
+		"Extension":                 models.Extension{},
+		"Resource":                  models.Resource{},
+		"PatientContact":            models.PatientContact{},
+		"PatientCommunication":      models.PatientCommunication{},
+		"PatientLink":               models.PatientLink{},
+		"PatientCreateRequest":      models.PatientCreateRequest{},
+		"ObservationReferenceRange": models.ObservationReferenceRange{},
+		"ObservationComponent":      models.ObservationComponent{},
+		"ObservationCreateRequest":  models.ObservationCreateRequest{},
+		"BundleLink":                models.BundleLink{},
+		"OperationOutcomeIssue":     models.OperationOutcomeIssue{},
+		"OperationOutcome":          models.OperationOutcome{},
+	}
+
+	for name, model := range mirrored {
+		t.Run(name, func(t *testing.T) {
+			got, ok := interfaces[name]
+			if !ok {
+				t.Fatalf("types.ts has no interface %s", name)
+			}
+			want := jsonFields(reflect.TypeOf(model))
+			sort.Strings(got)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("fields of %s\n  types.ts: %v\n  models:   %v", name, got, want)
+			}
+		})
+	}
+}
+
+func TestTypeScriptClientMatchesClient(t *testing.T) {
+	source, err := os.ReadFile(typescriptSource + "client.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	methods := map[string]bool{}
+	for _, match := range methodPattern.FindAllStringSubmatch(string(source), -1) {
+		methods[match[1]] = true
+	}
+
+	typ := reflect.TypeOf(&Client{})
+	for i := 0; i < typ.NumMethod(); i++ {
+		name := []rune(typ.Method(i).Name)
+		name[0] = unicode.ToLower(name[0])
+		if !methods[string(name)] {
+			t.Errorf("client.ts has no method %s, the TypeScript name of Client.%s", string(name), typ.Method(i).Name)
+		}
+	}
+}